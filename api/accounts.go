@@ -0,0 +1,337 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+)
+
+const (
+	principalContextKey = "uiPrincipal"
+
+	auditBodyReadLimit = 64 * 1024
+	auditDetailLimit   = 2000
+)
+
+// 请求体中这些字段的值不写入操作记录
+var auditSecretKeys = []string{"password", "token", "secret", "passphrase", "accesskey"}
+
+func requestToken(c echo.Context) string {
+	token := c.Request().Header.Get("token") //nolint:canonicalheader // private header
+	if token == "" {
+		token = c.QueryParam("token")
+	}
+	return token
+}
+
+// principalOf 返回当前请求的调用者，未登录时为 nil
+func principalOf(c echo.Context) *dice.UIPrincipal {
+	if p, ok := c.Get(principalContextKey).(*dice.UIPrincipal); ok {
+		return p
+	}
+	p, ok := myDice.Parent.UIAuthenticate(requestToken(c))
+	if !ok {
+		return nil
+	}
+	c.Set(principalContextKey, p)
+	return p
+}
+
+func uiRouteScope(method, path string) dice.UIScope {
+	for _, r := range uiRouteScopes {
+		if strings.HasPrefix(path, r.prefix) {
+			if method == http.MethodGet {
+				return r.read
+			}
+			return r.write
+		}
+	}
+	if method == http.MethodGet {
+		return dice.UIScopeRead
+	}
+	return dice.UIScopeOperate
+}
+
+// permissionMiddleware 按 uiRouteScopes 检查调用者的权限，并记录已登录用户的写操作。
+// 未登录的请求交给各接口自己的 doAuth 处理
+func permissionMiddleware(prefix string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := c.Path()
+			if !strings.HasPrefix(path, prefix+"/") {
+				return next(c)
+			}
+			p := principalOf(c)
+			if p == nil {
+				return next(c)
+			}
+			method := c.Request().Method
+			if scope := uiRouteScope(method, strings.TrimPrefix(path, prefix)); scope != "" && !p.Can(scope) {
+				return c.JSON(http.StatusForbidden, Response{"result": false, "err": "当前账户没有权限进行该操作", "scope": scope})
+			}
+			if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+				return next(c)
+			}
+
+			detail := auditRequestDetail(c)
+			err := next(c)
+			status := c.Response().Status
+			if err != nil {
+				var he *echo.HTTPError
+				if errors.As(err, &he) {
+					status = he.Code
+				} else {
+					status = http.StatusInternalServerError
+				}
+			}
+			auditRecord(c, p, detail, status)
+			return err
+		}
+	}
+}
+
+// auditRequestDetail 读取 JSON 请求体的摘要并放回原处，供处理函数继续读取
+func auditRequestDetail(c echo.Context) string {
+	req := c.Request()
+	if req.Body == nil || !strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		return ""
+	}
+	buf, _ := io.ReadAll(io.LimitReader(req.Body, auditBodyReadLimit))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+
+	var v any
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return ""
+	}
+	data, _ := json.Marshal(maskAuditSecrets(v))
+	detail := string(data)
+	if len(detail) > auditDetailLimit {
+		detail = detail[:auditDetailLimit] + "..."
+	}
+	return detail
+}
+
+func maskAuditSecrets(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, item := range x {
+			lower := strings.ToLower(k)
+			masked := false
+			for _, s := range auditSecretKeys {
+				if strings.Contains(lower, s) {
+					masked = true
+					break
+				}
+			}
+			if masked {
+				x[k] = "******"
+			} else {
+				x[k] = maskAuditSecrets(item)
+			}
+		}
+	case []any:
+		for i, item := range x {
+			x[i] = maskAuditSecrets(item)
+		}
+	}
+	return v
+}
+
+func auditRecord(c echo.Context, p *dice.UIPrincipal, detail string, status int) {
+	if myDice.DBOperator == nil {
+		return
+	}
+	item := &model.UIAuditLog{
+		User:      p.User,
+		TokenID:   p.TokenID,
+		Method:    c.Request().Method,
+		Path:      c.Path(),
+		Query:     c.QueryString(),
+		Detail:    detail,
+		Status:    status,
+		IP:        c.RealIP(),
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := service.UIAuditAppend(myDice.DBOperator, item); err != nil {
+		myDice.Logger.Warnf("写入操作记录失败: %v", err)
+	}
+}
+
+// 当前登录的账户
+func accountMe(c echo.Context) error {
+	p := principalOf(c)
+	if p == nil {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	return Success(&c, Response{"data": p})
+}
+
+func accountList(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	return Success(&c, Response{"data": myDice.Parent.UIAccounts.ListUsers()})
+}
+
+// 新建或修改账户，password 为前端加盐哈希后的值，留空则不修改
+func accountSave(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	v := struct {
+		Name     string      `json:"name"`
+		Role     dice.UIRole `json:"role"`
+		Password string      `json:"password"` //nolint:gosec
+		Disabled bool        `json:"disabled"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if err := myDice.Parent.UIAccounts.SaveUser(v.Name, v.Role, v.Password, v.Disabled); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	myDice.Parent.Save()
+	return Success(&c, Response{})
+}
+
+func accountDelete(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	v := struct {
+		Name string `json:"name"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if err := myDice.Parent.UIAccounts.DeleteUser(v.Name); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	myDice.Parent.Save()
+	return Success(&c, Response{})
+}
+
+// 列出自己的令牌，管理员传入 all=true 时列出全部
+func accountTokenList(c echo.Context) error {
+	p := principalOf(c)
+	if p == nil {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	user := p.User
+	if c.QueryParam("all") == "true" && p.Can(dice.UIScopeAdmin) {
+		user = ""
+	}
+	return Success(&c, Response{"data": myDice.Parent.UIAccounts.ListTokens(user)})
+}
+
+// 创建 API 令牌，明文只在此处返回一次。管理员可通过 user 为其他账户创建
+func accountTokenCreate(c echo.Context) error {
+	p := principalOf(c)
+	if p == nil {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	v := struct {
+		User      string         `json:"user"`
+		Name      string         `json:"name"`
+		Scopes    []dice.UIScope `json:"scopes"`
+		ExpiresIn int64          `json:"expiresIn"` // 有效期，单位秒，0 为永不过期
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.User == "" {
+		v.User = p.User
+	}
+	if v.User != p.User && !p.Can(dice.UIScopeAdmin) {
+		return c.JSON(http.StatusForbidden, Response{"result": false, "err": "只能为自己创建令牌"})
+	}
+	if v.ExpiresIn < 0 {
+		return Error(&c, "有效期不能为负数", Response{})
+	}
+	// 令牌不能拥有创建者自己没有的权限
+	for _, s := range v.Scopes {
+		if !p.Can(s) {
+			return Error(&c, dice.ErrUIScopeNotAllowed.Error(), Response{})
+		}
+	}
+	token, item, err := myDice.Parent.UIAccounts.CreateToken(v.User, v.Name, v.Scopes, time.Duration(v.ExpiresIn)*time.Second)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	myDice.Parent.Save()
+	return Success(&c, Response{"token": token, "data": item})
+}
+
+func accountTokenRevoke(c echo.Context) error {
+	p := principalOf(c)
+	if p == nil {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	v := struct {
+		ID string `json:"id"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	user := p.User
+	if p.Can(dice.UIScopeAdmin) {
+		user = ""
+	}
+	if err := myDice.Parent.UIAccounts.RevokeToken(v.ID, user); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	myDice.Parent.Save()
+	return Success(&c, Response{})
+}
+
+// 分页查询操作记录
+func accountAuditPage(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	v := service.QueryUIAudit{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.PageNum < 1 {
+		v.PageNum = 1
+	}
+	if v.PageSize < 1 {
+		v.PageSize = 20
+	}
+	total, page, err := service.UIAuditGetPage(myDice.DBOperator, v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"data":     page,
+		"total":    total,
+		"pageNum":  v.PageNum,
+		"pageSize": len(page),
+	})
+}
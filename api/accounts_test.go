@@ -0,0 +1,123 @@
+package api //nolint:testpackage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"sealdice-core/dice"
+)
+
+func TestPermissionMiddleware(t *testing.T) {
+	previousDice := myDice
+	previousDM := dm
+	t.Cleanup(func() {
+		myDice = previousDice
+		dm = previousDM
+	})
+
+	testDice := &dice.Dice{Logger: zap.NewNop().Sugar()}
+	manager := &dice.DiceManager{Dice: []*dice.Dice{testDice}}
+	manager.AccessTokens.Store("owner-token", true)
+	testDice.Parent = manager
+	myDice = testDice
+	dm = manager
+
+	if err := manager.UIAccounts.SaveUser("mod", dice.UIRoleModerator, "pw", false); err != nil {
+		t.Fatal(err)
+	}
+	modToken, err := manager.UIAccounts.SignIn("mod", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = manager.UIAccounts.SaveUser("op", dice.UIRoleOperator, "pw", false); err != nil {
+		t.Fatal(err)
+	}
+	opToken, err := manager.UIAccounts.SignIn("op", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := func(c echo.Context) error {
+		if !doAuth(c) {
+			return c.JSON(http.StatusForbidden, nil)
+		}
+		return c.NoContent(http.StatusOK)
+	}
+	e := echo.New()
+	e.Use(permissionMiddleware("/sd-api"))
+	e.GET("/sd-api/banconfig/list", ok)
+	e.POST("/sd-api/banconfig/map_add_one", ok)
+	e.POST("/sd-api/js/execute", ok)
+	e.POST("/sd-api/force_stop", ok)
+	e.POST("/sd-api/dice/config/set", ok)
+	e.GET("/sd-api/accounts/me", accountMe)
+	e.GET("/sd-api/im_connections/list", ok)
+	e.POST("/sd-api/store/download", ok)
+	e.GET("/sd-api/dice/messages/search", ok)
+	e.GET("/sd-api/log/query", ok)
+	e.GET("/sd-api/store/page", ok)
+	e.GET("/sd-api/backup/download", ok)
+	e.POST("/sd-api/backup/remote/export", ok)
+	e.POST("/sd-api/js/check_update", ok)
+	e.GET("/sd-api/story/items/page", ok)
+	e.GET("/sd-api/censor/logs/page", ok)
+	e.GET("/sd-api/dice/recentMessage", ok)
+
+	cases := []struct {
+		method, path, token string
+		want                int
+	}{
+		{http.MethodGet, "/sd-api/banconfig/list", modToken, http.StatusOK},
+		{http.MethodPost, "/sd-api/banconfig/map_add_one", modToken, http.StatusOK},
+		{http.MethodPost, "/sd-api/js/execute", modToken, http.StatusForbidden},
+		{http.MethodPost, "/sd-api/force_stop", modToken, http.StatusForbidden},
+		{http.MethodPost, "/sd-api/dice/config/set", modToken, http.StatusForbidden},
+		{http.MethodGet, "/sd-api/accounts/me", modToken, http.StatusOK},
+		{http.MethodGet, "/sd-api/im_connections/list", modToken, http.StatusForbidden},
+		{http.MethodPost, "/sd-api/store/download", modToken, http.StatusForbidden},
+		{http.MethodGet, "/sd-api/dice/messages/search", modToken, http.StatusForbidden},
+		{http.MethodGet, "/sd-api/log/query", modToken, http.StatusForbidden},
+		{http.MethodGet, "/sd-api/store/page", modToken, http.StatusOK},
+		{http.MethodGet, "/sd-api/story/items/page", modToken, http.StatusForbidden},
+		{http.MethodGet, "/sd-api/censor/logs/page", modToken, http.StatusForbidden},
+		{http.MethodGet, "/sd-api/dice/recentMessage", modToken, http.StatusForbidden},
+		{http.MethodGet, "/sd-api/story/items/page", opToken, http.StatusOK},
+		{http.MethodGet, "/sd-api/backup/download", opToken, http.StatusForbidden},
+		{http.MethodPost, "/sd-api/backup/remote/export", opToken, http.StatusForbidden},
+		{http.MethodPost, "/sd-api/js/check_update", opToken, http.StatusForbidden},
+		{http.MethodGet, "/sd-api/backup/download", "owner-token", http.StatusOK},
+		{http.MethodPost, "/sd-api/js/execute", "owner-token", http.StatusOK},
+		{http.MethodPost, "/sd-api/force_stop", "", http.StatusForbidden},
+		{http.MethodPost, "/sd-api/force_stop", "bogus", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{"password":"x"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if tc.token != "" {
+			req.Header.Set("Token", tc.token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s with %q = %d, want %d", tc.method, tc.path, tc.token, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestMaskAuditSecrets(t *testing.T) {
+	v := maskAuditSecrets(map[string]any{
+		"name": "mod",
+		"s3":   map[string]any{"secretKey": "abc", "bucket": "b"},
+		"list": []any{map[string]any{"uiPassword": "x"}},
+	})
+	m := v.(map[string]any)
+	if m["name"] != "mod" || m["s3"].(map[string]any)["secretKey"] != "******" ||
+		m["s3"].(map[string]any)["bucket"] != "b" || m["list"].([]any)[0].(map[string]any)["uiPassword"] != "******" {
+		t.Fatalf("maskAuditSecrets() = %v", v)
+	}
+}
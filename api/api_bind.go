@@ -234,6 +234,7 @@ func checkSecurity(c echo.Context) error {
 
 func doSignIn(c echo.Context) error {
 	v := struct {
+		Username string `json:"username"` // 为空或 admin 时以骰主身份登录
 		Password string `json:"password"` //nolint:gosec
 	}{}
 
@@ -242,6 +243,17 @@ func doSignIn(c echo.Context) error {
 		return c.JSON(400, nil)
 	}
 
+	if v.Username != "" && v.Username != dice.UIOwnerName {
+		token, signInErr := myDice.Parent.UIAccounts.SignIn(v.Username, v.Password)
+		if signInErr != nil {
+			return c.JSON(400, nil)
+		}
+		myDice.Parent.Save()
+		return c.JSON(http.StatusOK, map[string]string{
+			"token": token,
+		})
+	}
+
 	generateToken := func() error {
 		now := time.Now().Unix()
 		head := hex.EncodeToString(Int64ToBytes(now))
@@ -270,7 +282,7 @@ func logFetchAndClear(c echo.Context) error {
 	if !doAuth(c) {
 		return c.JSON(http.StatusForbidden, nil)
 	}
-	info := c.JSON(http.StatusOK, myDice.LogWriter.Items)
+	info := c.JSON(http.StatusOK, myDice.LogWriter.Snapshot())
 	// myDice.LogWriter.Items = myDice.LogWriter.Items[:0]
 	return info
 }
@@ -585,10 +597,15 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	// 挂载 humaecho 到 echo 实例
 	_ = humaecho.New(e, huma.DefaultConfig("Sealdiciapi", "1.0.0"))
 
+	e.Use(permissionMiddleware(prefix))
+
 	e.GET(prefix+"/preInfo", preInfo)
 	e.GET(prefix+"/baseInfo", baseInfo)
 	e.GET(prefix+"/hello", hello2)
 	e.GET(prefix+"/log/fetchAndClear", logFetchAndClear)
+	e.GET(prefix+"/log/stream", logStream)
+	e.GET(prefix+"/log/query", logQuery)
+	e.GET(prefix+"/log/modules", logModules)
 	e.GET(prefix+"/im_connections/list", ImConnections)
 	e.GET(prefix+"/im_connections/get", ImConnectionsGet)
 
@@ -650,6 +667,9 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	e.GET(prefix+"/dice/exec/split_options", DiceExecSplitOptions)
 	e.POST(prefix+"/dice/exec", DiceExec)
 	e.GET(prefix+"/dice/recentMessage", DiceRecentMessage)
+	e.GET(prefix+"/dice/messages/search", messageSearch)
+	e.GET(prefix+"/dice/messages/index", messageIndexStatus)
+	e.POST(prefix+"/dice/messages/index", messageIndexSet)
 	e.GET(prefix+"/dice/cmdList", DiceAllCommand)
 	e.POST(prefix+"/dice/upload_to_upgrade", DiceNewVersionUpload)
 
@@ -669,11 +689,35 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	e.GET(prefix+"/backup/download", backupDownload)
 	e.POST(prefix+"/backup/delete", backupDelete)
 	e.POST(prefix+"/backup/batch_delete", backupBatchDelete)
+	e.GET(prefix+"/backup/inspect", backupInspect)
+	e.POST(prefix+"/backup/restore", backupRestore)
+	e.GET(prefix+"/backup/restore/pending", backupRestorePending)
+	e.POST(prefix+"/backup/restore/cancel", backupRestoreCancel)
+	e.GET(prefix+"/backup/remote/config_get", backupRemoteConfigGet)
+	e.POST(prefix+"/backup/remote/config_set", backupRemoteConfigSet)
+	e.POST(prefix+"/backup/remote/run", backupRemoteRun)
+	e.GET(prefix+"/backup/remote/status", backupRemoteStatus)
+	e.GET(prefix+"/backup/remote/snapshots", backupRemoteSnapshots)
+	e.POST(prefix+"/backup/remote/export", backupRemoteExport)
 
 	e.GET(prefix+"/group/list", groupList)
 	e.POST(prefix+"/group/set_one", groupSetOne)
 	e.POST(prefix+"/group/quit_one", groupQuit)
 
+	e.GET(prefix+"/schedule/list", scheduleList)
+	e.POST(prefix+"/schedule/set_paused", scheduleSetPaused)
+	e.POST(prefix+"/schedule/delete", scheduleDelete)
+
+	e.GET(prefix+"/character/list", characterList)
+	e.GET(prefix+"/character/get", characterGet)
+	e.GET(prefix+"/character/attr", characterAttrGet)
+	e.POST(prefix+"/character/attrs/set", characterAttrsSet)
+	e.POST(prefix+"/character/attrs/delete", characterAttrsDelete)
+	e.POST(prefix+"/character/bind", characterBind)
+	e.GET(prefix+"/character/export", characterExport)
+	e.POST(prefix+"/character/import", characterImport)
+	e.POST(prefix+"/character/import_external", characterImportExternal)
+
 	e.GET(prefix+"/banconfig/list", banMapList)
 	e.GET(prefix+"/banconfig/get", banConfigGet)
 	e.POST(prefix+"/banconfig/set", banConfigSet)
@@ -683,6 +727,13 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	// e.POST(prefix+"/banconfig/map_set", banMapSet)
 	e.GET(prefix+"/banconfig/export", banExport)
 	e.POST(prefix+"/banconfig/import", banImport)
+	e.GET(prefix+"/banconfig/events", banEventPage)
+	e.GET(prefix+"/banconfig/appeals", banAppealPage)
+	e.POST(prefix+"/banconfig/appeal_resolve", banAppealResolve)
+	e.GET(prefix+"/banconfig/federation/export", banFederationExport)
+	e.GET(prefix+"/banconfig/federation/get", banFederationGet)
+	e.POST(prefix+"/banconfig/federation/set", banFederationSet)
+	e.POST(prefix+"/banconfig/federation/sync", banFederationSync)
 
 	e.GET(prefix+"/deck/list", deckList)
 	e.POST(prefix+"/deck/reload", deckReload)
@@ -704,6 +755,7 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	e.GET(prefix+"/js/get_record", jsGetRecord)
 	e.POST(prefix+"/js/shutdown", jsShutdown)
 	e.GET(prefix+"/js/status", jsStatus)
+	e.POST(prefix+"/js/runtime_config", jsRuntimeConfig)
 	e.POST(prefix+"/js/enable", jsEnable)
 	e.POST(prefix+"/js/disable", jsDisable)
 	e.POST(prefix+"/js/check_update", jsCheckUpdate)
@@ -731,6 +783,7 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	e.POST(prefix+"/story/uploadLog", storyUploadLog)
 	e.GET(prefix+"/story/backup/list", storyGetLogBackupList)
 	e.GET(prefix+"/story/backup/download", storyDownloadLogBackup)
+	e.GET(prefix+"/story/export/formats", storyGetExportFormats)
 	e.POST(prefix+"/story/backup/batch_delete", storyBatchDeleteLogBackup)
 
 	e.POST(prefix+"/tool/onebot", onebotTool)
@@ -753,6 +806,8 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	e.GET(prefix+"/censor/files/template/toml", censorGetTomlFileTemplate)
 	e.GET(prefix+"/censor/files/template/txt", censorGetTxtFileTemplate)
 	e.GET(prefix+"/censor/logs/page", censorGetLogPage)
+	e.GET(prefix+"/censor/group_profile", censorGetGroupProfile)
+	e.POST(prefix+"/censor/group_profile", censorSetGroupProfile)
 
 	e.GET(prefix+"/resource/page", resourceGetList)
 	e.GET(prefix+"/resource/download", resourceDownload)
@@ -800,4 +855,78 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	e.GET(prefix+"/package/:id/config-schema", packageGetConfigSchema)
 
 	bindPProfAPIs(e, prefix)
+
+	e.GET("/metrics", metricsExport)
+	e.GET(prefix+"/metrics/config_get", metricsConfigGet)
+	e.POST(prefix+"/metrics/config_set", metricsConfigSet)
+
+	// WebUI 账户与令牌
+	e.GET(prefix+"/accounts/me", accountMe)
+	e.GET(prefix+"/accounts/list", accountList)
+	e.POST(prefix+"/accounts/save", accountSave)
+	e.POST(prefix+"/accounts/delete", accountDelete)
+	e.GET(prefix+"/accounts/tokens/list", accountTokenList)
+	e.POST(prefix+"/accounts/tokens/create", accountTokenCreate)
+	e.POST(prefix+"/accounts/tokens/revoke", accountTokenRevoke)
+	e.GET(prefix+"/accounts/audit", accountAuditPage)
+}
+
+// uiRouteScopes 各路由所需的权限，按前缀匹配，先匹配者优先。
+// 未列出的路由 GET 需要 read，其余方法需要 operate；scope 为空表示不检查
+var uiRouteScopes = []struct {
+	prefix string
+	read   dice.UIScope // GET 请求
+	write  dice.UIScope // 其余请求
+}{
+	{"/signin", "", ""},
+
+	// 账户：自己的信息与令牌人人可管，其余仅管理员
+	{"/accounts/me", dice.UIScopeRead, dice.UIScopeRead},
+	{"/accounts/tokens/", dice.UIScopeRead, dice.UIScopeRead},
+	{"/accounts/", dice.UIScopeAdmin, dice.UIScopeAdmin},
+
+	// 可执行任意代码、替换程序或覆盖数据的操作
+	{"/js/execute", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/js/upload", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/js/update", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/js/check_update", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/package/install-", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/package/upload-install", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/force_stop", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/dice/upgrade", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/dice/upload_to_upgrade", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/tool/onebot", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/backup/restore", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/backup/remote/config_", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	// 备份中含 dice.yaml 里的 WebUI 密码哈希与访问令牌，拿到即可登录为管理员
+	{"/backup/download", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/backup/remote/export", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/metrics/config_", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/debug/pprof", dice.UIScopeAdmin, dice.UIScopeAdmin},
+
+	// 商店下载和安装扩展同样是执行任意代码
+	{"/store/download", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/store/install-list", dice.UIScopeAdmin, dice.UIScopeAdmin},
+	{"/store/backend/", dice.UIScopeRead, dice.UIScopeAdmin},
+
+	// 读取时会带出账号凭据、聊天内容或完整数据。连接列表里有 gocq 的 accessToken 和 satori 的 token
+	{"/im_connections/", dice.UIScopeOperate, dice.UIScopeOperate},
+	{"/dice/messages/", dice.UIScopeOperate, dice.UIScopeAdmin},
+	{"/dice/recentMessage", dice.UIScopeOperate, dice.UIScopeOperate},
+	{"/log/", dice.UIScopeOperate, dice.UIScopeOperate},
+	{"/story/items", dice.UIScopeOperate, dice.UIScopeOperate},
+	{"/censor/logs/", dice.UIScopeOperate, dice.UIScopeOperate},
+	{"/story/backup/download", dice.UIScopeOperate, dice.UIScopeOperate},
+	{"/utils/get_token", dice.UIScopeOperate, dice.UIScopeOperate},
+
+	// 黑名单、群组与日程
+	{"/banconfig/", dice.UIScopeRead, dice.UIScopeModerate},
+	{"/group/", dice.UIScopeRead, dice.UIScopeModerate},
+	{"/schedule/", dice.UIScopeRead, dice.UIScopeModerate},
+	{"/censor/group_profile", dice.UIScopeRead, dice.UIScopeModerate},
+
+	// 以 POST 提交的查询
+	{"/helpdoc/textitem/get_page", dice.UIScopeRead, dice.UIScopeRead},
+	{"/store/package-info-list", dice.UIScopeRead, dice.UIScopeRead},
+	{"/utils/check_", dice.UIScopeRead, dice.UIScopeRead},
 }
@@ -36,7 +36,7 @@ func backupGetList(c echo.Context) error {
 		return c.JSON(http.StatusForbidden, nil)
 	}
 
-	reFn := regexp.MustCompile(`^(bak_\d{6}_\d{6}(?:_auto)?_r([0-9a-f]+))_([0-9a-f]{8})\.zip$`)
+	reFn := regexp.MustCompile(`^(bak_\d{6}_\d{6}(?:_auto|_prerestore)?_r([0-9a-f]+))_([0-9a-f]{8})\.zip$`)
 
 	var items []*backupFileItem
 	_ = filepath.Walk(dice.BackupDir, func(path string, info fs.FileInfo, err error) error {
@@ -235,3 +235,79 @@ func backupConfigSave(c echo.Context) error {
 	dm.Save()
 	return c.String(http.StatusOK, "")
 }
+
+func backupNameOK(name string) bool {
+	return name != "" && !strings.Contains(name, "/") && !strings.Contains(name, "\\")
+}
+
+// 查看备份中包含的内容
+func backupInspect(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+
+	name := c.QueryParam("name")
+	if !backupNameOK(name) {
+		return Error(&c, "无效的备份文件名", Response{})
+	}
+	info, err := dice.InspectBackup(filepath.Join(dice.BackupDir, name))
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"info": info})
+}
+
+// 安排从备份恢复。数据库文件无法在运行中替换，恢复在下次启动时、打开数据库之前执行
+func backupRestore(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+
+	v := struct {
+		Name      string                      `json:"name"`
+		Selection dice.BackupRestoreSelection `json:"selection"`
+		Reboot    bool                        `json:"reboot"` // 立即重启以执行恢复
+	}{}
+	err := c.Bind(&v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if !backupNameOK(v.Name) {
+		return Error(&c, "无效的备份文件名", Response{})
+	}
+
+	info, err := dm.ScheduleRestore(v.Name, v.Selection)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.Reboot {
+		select {
+		case dm.RebootRequestChan <- 1:
+		default:
+		}
+	}
+	return Success(&c, Response{"info": info})
+}
+
+func backupRestorePending(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	return Success(&c, Response{"pending": dice.PendingRestore()})
+}
+
+func backupRestoreCancel(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	if err := dice.CancelScheduledRestore(); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{})
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+)
+
+// 密码类字段不回传给前端，提交时仍为该值则视为未修改
+const remoteBackupSecretMask = "******"
+
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return remoteBackupSecretMask
+}
+
+func keepSecret(s, old string) string {
+	if s == remoteBackupSecretMask {
+		return old
+	}
+	return s
+}
+
+func backupRemoteConfigGet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	cfg := dm.RemoteBackup
+	cfg.Passphrase = maskSecret(cfg.Passphrase)
+	cfg.Target.Password = maskSecret(cfg.Target.Password)
+	cfg.Target.SecretKey = maskSecret(cfg.Target.SecretKey)
+	return Success(&c, Response{"config": cfg})
+}
+
+func backupRemoteConfigSet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+
+	v := dice.RemoteBackupConfig{}
+	err := c.Bind(&v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	old := dm.RemoteBackup
+	v.Passphrase = keepSecret(v.Passphrase, old.Passphrase)
+	v.Target.Password = keepSecret(v.Target.Password, old.Target.Password)
+	v.Target.SecretKey = keepSecret(v.Target.SecretKey, old.Target.SecretKey)
+	if v.KeepCount < 0 {
+		v.KeepCount = 0
+	}
+
+	dm.RemoteBackup = v
+	dm.Save()
+	return Success(&c, Response{})
+}
+
+// 立即执行一次增量备份，在后台进行，结果通过 status 查询
+func backupRemoteRun(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+
+	v := struct {
+		Selection uint64 `json:"selection"`
+	}{}
+	err := c.Bind(&v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if dm.RemoteBackupStatus().Running {
+		return Error(&c, dice.ErrRemoteBackupRunning.Error(), Response{})
+	}
+	go func() {
+		_, _ = dm.BackupRemote(context.Background(), dice.BackupSelection(v.Selection))
+	}()
+	return Success(&c, Response{})
+}
+
+func backupRemoteStatus(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	return Success(&c, Response{"status": dm.RemoteBackupStatus()})
+}
+
+func backupRemoteSnapshots(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	items, err := dm.RemoteBackupSnapshots(c.Request().Context())
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"items": items})
+}
+
+// 把快照导出为普通备份文件，之后可以下载或通过 /backup/restore 恢复
+func backupRemoteExport(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+
+	v := struct {
+		ID string `json:"id"`
+	}{}
+	err := c.Bind(&v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if !backupNameOK(v.ID) {
+		return Error(&c, "无效的快照 ID", Response{})
+	}
+	fn, err := dm.RemoteBackupExport(c.Request().Context(), v.ID)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"name": filepath.Base(fn)})
+}
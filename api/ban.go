@@ -13,6 +13,7 @@ import (
 	"github.com/labstack/echo/v4"
 
 	"sealdice-core/dice"
+	"sealdice-core/dice/service"
 )
 
 func banConfigGet(c echo.Context) error {
@@ -59,6 +60,8 @@ func banConfigSet(c echo.Context) error {
 	config.BanList.JointScorePercentOfGroup = v.JointScorePercentOfGroup
 	config.BanList.JointScorePercentOfInviter = v.JointScorePercentOfInviter
 	config.BanList.BanNotifyIntervalMinutes = v.BanNotifyIntervalMinutes
+	config.BanList.AutoBanMinutes = v.AutoBanMinutes
+	config.BanList.AutoBanExpire = v.AutoBanExpire
 	myDice.MarkModified()
 
 	return c.JSON(http.StatusOK, myDice.Config.BanList)
@@ -78,7 +81,7 @@ func banMapDeleteOne(c echo.Context) error {
 	if err != nil {
 		return c.String(430, err.Error())
 	}
-	(&myDice.Config).BanList.DeleteByID(myDice, v.ID)
+	(&myDice.Config).BanList.DeleteWithSource(myDice, v.ID, dice.BanSource{Handler: dice.BanHandlerWebUI})
 	return c.JSON(http.StatusOK, nil)
 }
 
@@ -105,7 +108,11 @@ func banMapAddOne(c echo.Context) error {
 		platform := strings.Replace(prefix, "-Group", "", 1)
 		for _, i := range myDice.ImSession.EndPoints {
 			if i.Platform == platform && i.Enable {
-				v2 := (&myDice.Config).BanList.AddScoreBase(v.ID, score, "海豹后台", reason, &dice.MsgContext{Dice: myDice, EndPoint: i})
+				src := dice.BanSource{Handler: dice.BanHandlerWebUI, Minutes: -1}
+				if v.ExpireAt > time.Now().Unix() {
+					src.Minutes = (v.ExpireAt - time.Now().Unix() + 59) / 60
+				}
+				v2 := (&myDice.Config).BanList.AddScoreWithSource(v.ID, score, "海豹后台", reason, src, &dice.MsgContext{Dice: myDice, EndPoint: i})
 				if v2 != nil {
 					if v.Name != "" {
 						v2.Name = v.Name
@@ -115,7 +122,7 @@ func banMapAddOne(c echo.Context) error {
 		}
 	}
 	if v.Rank == dice.BanRankTrusted {
-		(&myDice.Config).BanList.SetTrustByID(v.ID, "海豹后台", "骰主后台设置")
+		(&myDice.Config).BanList.SetTrustWithSource(v.ID, "海豹后台", "骰主后台设置", dice.BanSource{Handler: dice.BanHandlerWebUI})
 	}
 
 	return c.JSON(http.StatusOK, nil)
@@ -187,6 +194,18 @@ func banImport(c echo.Context) error {
 	if err != nil {
 		return Error(&c, err.Error(), Response{})
 	}
+	// 带签名的共享名单，校验后按来源合并
+	var ex dice.BanListExchange
+	if json.Unmarshal(data, &ex) == nil && ex.Sign != "" {
+		payload, errVerify := ex.Verify("")
+		if errVerify != nil {
+			return Error(&c, errVerify.Error(), Response{})
+		}
+		banList := (&myDice.Config).BanList
+		changed := banList.MergeFederation("导入:"+payload.Source, payload, 1, time.Now().Unix())
+		banList.SaveChanged(myDice)
+		return Success(&c, Response{"source": payload.Source, "count": len(payload.Items), "changed": changed})
+	}
 	err = json.Unmarshal(data, &lst)
 	if err != nil {
 		return Error(&c, err.Error(), Response{})
@@ -208,3 +227,194 @@ func banImport(c echo.Context) error {
 
 	return Success(&c, Response{})
 }
+
+// banEventPage 分页查询黑名单事件
+func banEventPage(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+
+	v := service.QueryBanEvent{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.PageNum < 1 {
+		v.PageNum = 1
+	}
+	if v.PageSize < 1 {
+		v.PageSize = 20
+	}
+
+	total, page, err := service.BanEventGetPage(myDice.DBOperator, v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"data":     page,
+		"total":    total,
+		"pageNum":  v.PageNum,
+		"pageSize": len(page),
+	})
+}
+
+// banAppealPage 分页查询申诉
+func banAppealPage(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+
+	v := service.QueryBanAppeal{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.PageNum < 1 {
+		v.PageNum = 1
+	}
+	if v.PageSize < 1 {
+		v.PageSize = 20
+	}
+
+	total, page, err := service.BanAppealGetPage(myDice.DBOperator, v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"data":     page,
+		"total":    total,
+		"pageNum":  v.PageNum,
+		"pageSize": len(page),
+	})
+}
+
+// banAppealResolve 通过或驳回申诉
+func banAppealResolve(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+
+	v := struct {
+		ID     uint64 `json:"id"`
+		Accept bool   `json:"accept"`
+		Reply  string `json:"reply"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+
+	appeal, err := (&myDice.Config).BanList.ResolveAppeal(v.ID, v.Accept, "海豹后台", v.Reply)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	dice.NotifyBanAppealResult(&dice.MsgContext{Dice: myDice}, appeal)
+	return Success(&c, Response{"data": appeal})
+}
+
+// banFederationExport 导出带签名的本地名单，供其他骰子订阅，无需登录
+func banFederationExport(c echo.Context) error {
+	if !myDice.Config.BanList.FederationPublish {
+		return c.NoContent(http.StatusForbidden)
+	}
+	ex, err := myDice.FederationExport()
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	c.Response().Header().Add("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, ex)
+}
+
+type banFederationConfig struct {
+	Publish       bool                              `json:"publish"`
+	Name          string                            `json:"name"`
+	SyncMinutes   int64                             `json:"syncMinutes"`
+	Subscriptions []*dice.BanFederationSubscription `json:"subscriptions"`
+	PublicKey     string                            `json:"publicKey"`
+}
+
+func banFederationGet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+
+	banList := myDice.Config.BanList
+	publicKey, err := myDice.FederationPublicKey()
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"data": banFederationConfig{
+		Publish:       banList.FederationPublish,
+		Name:          banList.FederationName,
+		SyncMinutes:   banList.FederationSyncMinutes,
+		Subscriptions: banList.FederationSubscriptions,
+		PublicKey:     publicKey,
+	}})
+}
+
+func banFederationSet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+
+	v := banFederationConfig{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	names := map[string]bool{}
+	var subs []*dice.BanFederationSubscription
+	for _, sub := range v.Subscriptions {
+		if sub == nil {
+			continue
+		}
+		sub.Name = strings.TrimSpace(sub.Name)
+		sub.URL = strings.TrimSpace(sub.URL)
+		if sub.Name == "" || sub.URL == "" {
+			return Error(&c, "订阅名称与地址不能为空", Response{})
+		}
+		if names[sub.Name] {
+			return Error(&c, "订阅名称重复: "+sub.Name, Response{})
+		}
+		if sub.Weight < 0 || sub.Weight > 1 {
+			return Error(&c, "信任权重应在 0~1 之间", Response{})
+		}
+		names[sub.Name] = true
+		subs = append(subs, sub)
+	}
+
+	banList := (&myDice.Config).BanList
+	var removed []string
+	for _, sub := range banList.FederationSubscriptions {
+		if !names[sub.Name] {
+			removed = append(removed, sub.Name)
+		}
+	}
+	banList.FederationPublish = v.Publish
+	banList.FederationName = v.Name
+	banList.FederationSyncMinutes = v.SyncMinutes
+	banList.SetFederationSubscriptions(subs)
+	for _, name := range removed {
+		banList.RemoveFederationSource(name)
+	}
+	banList.SaveChanged(myDice)
+	myDice.MarkModified()
+
+	return Success(&c, Response{})
+}
+
+// banFederationSync 立即同步全部订阅
+func banFederationSync(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+
+	banList := (&myDice.Config).BanList
+	banList.SyncFederation(true)
+	return Success(&c, Response{"subscriptions": banList.FederationSubscriptions})
+}
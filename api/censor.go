@@ -101,6 +101,7 @@ func censorGetConfig(c echo.Context) error {
 		"caseSensitive": config.CensorCaseSensitive,
 		"matchPinyin":   config.CensorMatchPinyin,
 		"filterRegex":   config.CensorFilterRegexStr,
+		"normalize":     config.CensorNormalize,
 		"levelConfig":   levelConfig,
 	})
 }
@@ -196,6 +197,12 @@ func censorSetConfig(c echo.Context) error {
 			config.CensorMatchPinyin = matchPinyin
 		}
 	}
+	if val, ok := jsonMap["normalize"]; ok {
+		normalize, ok := val.(bool)
+		if ok {
+			config.CensorNormalize = normalize
+		}
+	}
 	if val, ok := jsonMap["levelConfig"]; ok { //nolint:nestif
 		levelConfig, ok := val.(map[string]interface{})
 
@@ -339,9 +346,7 @@ func censorGetWords(c echo.Context) error {
 					Level: info.Level,
 				}
 			}
-		case censor.IgnoreCase:
-			fallthrough
-		case censor.PinYin:
+		case censor.IgnoreCase, censor.PinYin, censor.Regex, censor.Fuzzy:
 			sensitiveWord, ok := temp[info.Origin]
 			if !ok {
 				temp[info.Origin] = &SensitiveWord{
@@ -479,6 +484,13 @@ func censorGetTomlFileTemplate(c echo.Context) error {
 			Warning: []string{"警告级词汇1", "警告级词汇2"},
 			Danger:  []string{"危险级词汇1", "危险级词汇2"},
 		},
+		Regex: censor.TomlWords{
+			Warning: []string{`警\s*告\s*级`},
+		},
+		Fuzzy: censor.TomlFuzzyWords{
+			Distance: 1,
+			Danger:   []string{"危险级模糊词汇"},
+		},
 	}
 	temp, _ := os.CreateTemp("", "词库模板-*.toml")
 	writer := bufio.NewWriter(temp)
@@ -548,3 +560,56 @@ func censorGetLogPage(c echo.Context) error {
 		"pageSize": len(page),
 	})
 }
+
+// censorGetGroupProfile 查看群拦截策略及合并后生效的策略
+func censorGetGroupProfile(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+
+	groupID := c.QueryParam("groupId")
+	groupInfo, ok := myDice.ImSession.ServiceAtNew.Load(groupID)
+	if !ok {
+		return Error(&c, "群组不存在", Response{})
+	}
+	return Success(&c, Response{
+		"profile": groupInfo.CensorProfile,
+		"policy":  myDice.Config.CensorPolicy(groupInfo.CensorProfile),
+	})
+}
+
+// censorSetGroupProfile 设置群拦截策略，profile 为 null 时恢复使用全局配置
+func censorSetGroupProfile(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+
+	v := struct {
+		GroupID string                   `json:"groupId"`
+		Profile *dice.GroupCensorProfile `json:"profile"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	groupInfo, ok := myDice.ImSession.ServiceAtNew.Load(v.GroupID)
+	if !ok {
+		return Error(&c, "群组不存在", Response{})
+	}
+	if p := v.Profile; p != nil {
+		if p.Mode != nil && (*p.Mode < dice.OnlyOutputReply || *p.Mode > dice.AllInput) {
+			return Error(&c, dice.ErrCensorUnknownMode.Error(), Response{})
+		}
+		if p.MinLevel < censor.Ignore || p.MinLevel > censor.Danger {
+			return Error(&c, dice.ErrCensorUnknownLevel.Error(), Response{})
+		}
+	}
+	groupInfo.CensorProfile = v.Profile
+	groupInfo.MarkDirty(myDice)
+
+	return Success(&c, Response{
+		"policy": myDice.Config.CensorPolicy(groupInfo.CensorProfile),
+	})
+}
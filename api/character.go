@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+)
+
+// characterList 列出用户名下的角色卡
+func characterList(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	userId := c.QueryParam("userId")
+	if userId == "" {
+		return Error(&c, "缺少用户ID", Response{})
+	}
+	lst, err := myDice.AttrsManager.GetCharacterList(userId)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"userId": myDice.AttrsManager.UIDConvert(userId),
+		"data":   lst,
+	})
+}
+
+// characterGet 读取角色卡，defaults=true 时附带模板默认值
+func characterGet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	withDefaults, _ := strconv.ParseBool(c.QueryParam("defaults"))
+	sheet, err := myDice.CharacterGet(c.QueryParam("id"), withDefaults)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"data": sheet})
+}
+
+// characterAttrGet 读取单个属性，支持别名
+func characterAttrGet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	v, err := myDice.CharacterAttrGet(c.QueryParam("id"), c.QueryParam("key"))
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"data": v})
+}
+
+// characterAttrsSet 写入属性
+func characterAttrsSet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	v := struct {
+		ID    string         `json:"id"`
+		Attrs map[string]any `json:"attrs"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	sheet, err := myDice.CharacterAttrsSet(v.ID, v.Attrs)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"data": sheet})
+}
+
+// characterAttrsDelete 删除属性
+func characterAttrsDelete(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	v := struct {
+		ID   string   `json:"id"`
+		Keys []string `json:"keys"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	deleted, err := myDice.CharacterAttrsDelete(v.ID, v.Keys)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"deleted": deleted})
+}
+
+// characterBind 绑定或解除绑定角色卡到群
+func characterBind(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	v := struct {
+		ID      string `json:"id"`
+		GroupID string `json:"groupId"`
+		Unbind  bool   `json:"unbind"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	var err error
+	if v.Unbind {
+		err = myDice.CharacterUnbind(v.ID, v.GroupID)
+	} else {
+		err = myDice.CharacterBind(v.ID, v.GroupID)
+	}
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{})
+}
+
+// characterExport 导出角色卡为 JSON 文件
+func characterExport(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	sheet, err := myDice.CharacterGet(c.QueryParam("id"), false)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	// 导出的卡不带归属信息，导入时由目标用户决定
+	sheet.ID, sheet.OwnerID, sheet.Groups = "", "", nil
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename*=UTF-8''"+url.PathEscape(sheet.Name+".json"))
+	return c.JSON(http.StatusOK, sheet)
+}
+
+// characterImport 从 JSON 文件导入角色卡到指定用户名下
+func characterImport(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	userId := c.FormValue("userId")
+	if userId == "" {
+		return Error(&c, "缺少用户ID", Response{})
+	}
+	overwrite, _ := strconv.ParseBool(c.FormValue("overwrite"))
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	src, err := file.Open()
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	defer func(src multipart.File) {
+		_ = src.Close()
+	}(src)
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+
+	var sheet dice.CharacterSheet
+	if err = json.Unmarshal(data, &sheet); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	ret, err := myDice.CharacterImport(userId, &sheet, overwrite)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"data": ret})
+}
+
+// characterImportExternal 导入外部车卡文件（Foundry VTT、D&D Beyond、JSON、CSV、Excel）
+func characterImportExternal(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	userId := c.FormValue("userId")
+	if userId == "" {
+		return Error(&c, "缺少用户ID", Response{})
+	}
+	overwrite, _ := strconv.ParseBool(c.FormValue("overwrite"))
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if file.Size > 5<<20 {
+		return Error(&c, dice.ErrSheetFileTooLarge.Error(), Response{})
+	}
+	src, err := file.Open()
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	defer func(src multipart.File) {
+		_ = src.Close()
+	}(src)
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+
+	ret, err := myDice.CharacterImportExternal(userId, file.Filename, data, dice.SheetImportOptions{
+		Name:      c.FormValue("name"),
+		SheetType: c.FormValue("sheetType"),
+		Overwrite: overwrite,
+	})
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"data": ret})
+}
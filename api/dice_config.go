@@ -116,6 +116,12 @@ func DiceConfigSet(c echo.Context) error {
 		return c.JSON(http.StatusOK, nil)
 	}
 
+	if _, ok := jsonMap["uiPassword"]; ok {
+		if p := principalOf(c); p == nil || !p.Can(dice.UIScopeAdmin) {
+			return Error(&c, "修改 UI 密码需要管理员权限", Response{})
+		}
+	}
+
 	trayTooltip := ""
 	trayTooltipModified := false
 	if val, ok := jsonMap["trayTooltip"]; ok {
@@ -244,9 +244,67 @@ func jsShutdown(c echo.Context) error {
 }
 
 func jsStatus(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	resp := map[string]interface{}{
+		"result":        true,
+		"status":        myDice.Config.JsEnable,
+		"isolation":     myDice.Config.JsIsolation,
+		"hookTimeLimit": myDice.Config.JsHookTimeLimit,
+		"runtimes":      []dice.JsRuntimeStatus{},
+		"extensions":    map[string]dice.JsHookStats{},
+	}
+	if m := myDice.ExtLoopManager; m != nil {
+		resp["runtimes"] = m.RuntimeStatus()
+		resp["extensions"] = m.HookStats()
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// jsRuntimeConfig 修改运行时隔离设置，隔离模式变化时重载JS环境
+func jsRuntimeConfig(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"testMode": true,
+		})
+	}
+	v := struct {
+		Isolation     bool  `json:"isolation"`
+		HookTimeLimit int64 `json:"hookTimeLimit"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return c.JSON(http.StatusBadRequest, nil)
+	}
+	if v.HookTimeLimit < 0 {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"result": false,
+			"err":    "时间限制不能为负数",
+		})
+	}
+
+	config := &myDice.Config
+	reload := config.JsEnable && config.JsIsolation != v.Isolation
+	if reload {
+		// 与 jsReload 相同，重载进行中时拒绝修改
+		if !myDice.JsReloadLock.TryLock() {
+			return c.NoContent(400)
+		}
+		defer myDice.JsReloadLock.Unlock()
+	}
+	config.JsIsolation = v.Isolation
+	config.JsHookTimeLimit = v.HookTimeLimit
+	myDice.MarkModified()
+	myDice.Save(false)
+	if reload {
+		myDice.JsReload()
+	}
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"result": true,
-		"status": myDice.Config.JsEnable,
+		"reload": reload,
 	})
 }
 
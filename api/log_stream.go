@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/logger"
+)
+
+const (
+	logStreamBacklog   = 100
+	logStreamHeartbeat = 25 * time.Second
+)
+
+func logFilterFromQuery(c echo.Context) (logger.LogFilter, error) {
+	return logger.ParseLogFilter(c.QueryParam("level"), c.QueryParam("module"), c.QueryParam("q"))
+}
+
+// 以 SSE 推送实时日志，支持多个页面同时订阅。
+// 浏览器的 EventSource 无法设置请求头，因此 token 通过查询参数传递；断线重连时根据 Last-Event-ID 补发
+func logStream(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	filter, err := logFilterFromQuery(c)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	lastID := c.Request().Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.QueryParam("after")
+	}
+	afterID, _ := strconv.ParseUint(lastID, 10, 64)
+	backlog := logStreamBacklog
+	if v, convErr := strconv.Atoi(c.QueryParam("backlog")); convErr == nil && v >= 0 {
+		backlog = v
+	}
+
+	sub, history := myDice.LogWriter.Subscribe(filter, afterID, backlog)
+	defer myDice.LogWriter.Unsubscribe(sub)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	send := func(item *logger.LogItem) error {
+		data, _ := json.Marshal(item)
+		_, err := fmt.Fprintf(res, "id: %d\nevent: log\ndata: %s\n\n", item.ID, data)
+		return err
+	}
+	for _, item := range history {
+		if err = send(item); err != nil {
+			return nil
+		}
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+	var reported uint64
+	ctx := c.Request().Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case item := <-sub.C:
+			if dropped := sub.Dropped(); dropped != reported {
+				// 告知前端有日志因来不及接收被丢弃
+				_, _ = fmt.Fprintf(res, "event: dropped\ndata: %d\n\n", dropped)
+				reported = dropped
+			}
+			if err = send(item); err != nil {
+				return nil
+			}
+			res.Flush()
+		case <-heartbeat.C:
+			if _, err = fmt.Fprint(res, ": ping\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+// parseLogTime 接受 Unix 秒数或 RFC3339 格式的时间
+func parseLogTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if sec, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.UnixMilli(int64(sec * 1000)), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// 检索保存在磁盘上的系统日志
+func logQuery(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if logger.DefaultLogStore == nil {
+		return Error(&c, "日志存储未启用", Response{})
+	}
+	filter, err := logFilterFromQuery(c)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	q := logger.LogQuery{Filter: filter}
+	if q.From, err = parseLogTime(c.QueryParam("from")); err != nil {
+		return Error(&c, "无效的开始时间", Response{})
+	}
+	if q.To, err = parseLogTime(c.QueryParam("to")); err != nil {
+		return Error(&c, "无效的结束时间", Response{})
+	}
+	q.Limit, _ = strconv.Atoi(c.QueryParam("limit"))
+
+	result, err := logger.DefaultLogStore.Query(q)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"items": result.Items, "more": result.More})
+}
+
+// 可用于过滤的模块名
+func logModules(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	return Success(&c, Response{"modules": []string{
+		logger.LogKeyMain,
+		logger.LogKeyAdapter,
+		logger.LogKeyDatabase,
+		logger.LogKeyWeb,
+	}})
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice/service"
+)
+
+// 单页最多返回的消息数
+const messageSearchMaxPageSize = 200
+
+func messageSearch(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+
+	v := service.QueryMessageSearch{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.PageNum < 1 {
+		v.PageNum = 1
+	}
+	if v.PageSize < 1 {
+		v.PageSize = 20
+	}
+	v.PageSize = min(v.PageSize, messageSearchMaxPageSize)
+
+	result, err := myDice.MessageSearch(v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{
+		"data":      result.Items,
+		"total":     result.Total,
+		"truncated": result.Truncated,
+		"pageNum":   v.PageNum,
+		"pageSize":  v.PageSize,
+	})
+}
+
+func messageIndexStatus(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	return Success(&c, Response{"status": myDice.MessageIndex.Status()})
+}
+
+func messageIndexSet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+
+	v := struct {
+		Enable bool `json:"enable"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	var err error
+	if v.Enable {
+		err = myDice.MessageIndex.Enable()
+	} else {
+		err = myDice.MessageIndex.Disable()
+	}
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"status": myDice.MessageIndex.Status()})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+)
+
+// metricsAuth 抓取使用单独的令牌，与 UI 登录令牌无关
+func metricsAuth(c echo.Context) bool {
+	token := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = c.QueryParam("token")
+	}
+	want := dm.Metrics.Token
+	return want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+// Prometheus 指标，未开启时视为不存在
+func metricsExport(c echo.Context) error {
+	if !dm.Metrics.Enable {
+		return echo.ErrNotFound
+	}
+	if !metricsAuth(c) {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	dm.ObserveEndpointStates()
+	c.Response().Header().Set(echo.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	c.Response().WriteHeader(http.StatusOK)
+	_, err := dice.Metrics.WriteTo(c.Response())
+	return err
+}
+
+func metricsConfigGet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	return Success(&c, Response{"config": dm.Metrics})
+}
+
+func metricsConfigSet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+
+	v := struct {
+		Enable     bool `json:"enable"`
+		ResetToken bool `json:"resetToken"` // 重新生成抓取令牌
+	}{}
+	err := c.Bind(&v)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	dm.Metrics.Enable = v.Enable
+	if v.ResetToken || (v.Enable && dm.Metrics.Token == "") {
+		buf := make([]byte, 24)
+		if _, err = rand.Read(buf); err != nil {
+			return Error(&c, err.Error(), Response{})
+		}
+		dm.Metrics.Token = hex.EncodeToString(buf)
+	}
+	dm.Save()
+	return Success(&c, Response{"config": dm.Metrics})
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// scheduleList 列出日程提醒，groupId 为空时列出全部
+func scheduleList(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if myDice.Schedules == nil {
+		return Error(&c, "日程提醒尚未初始化", Response{})
+	}
+	return Success(&c, Response{
+		"data": myDice.Schedules.List(c.QueryParam("groupId")),
+	})
+}
+
+// scheduleSetPaused 暂停或恢复日程提醒
+func scheduleSetPaused(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	v := struct {
+		ID     uint64 `json:"id"`
+		Paused bool   `json:"paused"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if myDice.Schedules == nil {
+		return Error(&c, "日程提醒尚未初始化", Response{})
+	}
+	if err := myDice.Schedules.SetPaused(v.ID, v.Paused); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"data": myDice.Schedules.Info(v.ID)})
+}
+
+// scheduleDelete 删除日程提醒
+func scheduleDelete(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	v := struct {
+		ID uint64 `json:"id"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if myDice.Schedules == nil {
+		return Error(&c, "日程提醒尚未初始化", Response{})
+	}
+	if err := myDice.Schedules.Remove(v.ID); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{})
+}
@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
 	"sealdice-core/dice"
 	"sealdice-core/dice/service"
+	"sealdice-core/dice/storylog"
 	"sealdice-core/model"
 )
 
@@ -114,7 +117,7 @@ func storyDelLog(c echo.Context) error {
 		myDice.Logger.Error("storyDelLog", err)
 		return c.JSON(http.StatusInternalServerError, err)
 	}
-	err = service.LogDelete(myDice.DBOperator, v.GroupID, v.Name)
+	err = myDice.LogDelete(v.GroupID, v.Name)
 	if err != nil {
 		if errors.Is(err, service.ErrLogNotFound) {
 			return c.JSON(http.StatusNotFound, false)
@@ -163,6 +166,9 @@ func storyDownloadLogBackup(c echo.Context) error {
 	if !doAuth(c) {
 		return c.JSON(http.StatusForbidden, nil)
 	}
+	if format := c.QueryParam("format"); format != "" {
+		return storyExportLog(c, c.QueryParam("groupId"), c.QueryParam("logName"), format)
+	}
 	name := c.QueryParam("name")
 	path, err := dice.StoryLogBackupDownloadPath(myDice, name)
 	if err != nil {
@@ -171,6 +177,39 @@ func storyDownloadLogBackup(c echo.Context) error {
 	return c.Attachment(path, name)
 }
 
+// storyExportLog 在本地按指定格式渲染日志并作为附件返回，不经过染色器后端
+func storyExportLog(c echo.Context, groupID string, logName string, format string) error {
+	if groupID == "" || logName == "" {
+		return Error(&c, "需要指定 groupId 和 logName", Response{})
+	}
+	info, ok := storylog.GetLogExporter(format)
+	if !ok {
+		return Error(&c, fmt.Sprintf("未知的日志导出格式: %s，可用格式: %s", format, strings.Join(storylog.LogExporterNames(), "/")), Response{})
+	}
+	path, _, err := storylog.ExportLogToTempFile(storylog.ExportEnv{
+		Db:      myDice.DBOperator,
+		GroupID: groupID,
+		LogName: logName,
+		Format:  info.Name,
+	}, logName)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	defer func() { _ = os.Remove(path) }()
+	filename, _ := storylog.BuildExportFilename(groupID, logName, info.Ext, time.Now())
+	c.Response().Header().Set(echo.HeaderContentType, info.ContentType)
+	return c.Attachment(path, filename)
+}
+
+func storyGetExportFormats(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	return Success(&c, Response{
+		"data": storylog.LogExporterList(),
+	})
+}
+
 func storyBatchDeleteLogBackup(c echo.Context) error {
 	if !doAuth(c) {
 		return c.JSON(http.StatusForbidden, nil)
@@ -43,11 +43,7 @@ func Int64ToBytes(i int64) []byte {
 }
 
 func doAuth(c echo.Context) bool {
-	token := c.Request().Header.Get("token") //nolint:canonicalheader // private header
-	if token == "" {
-		token = c.QueryParam("token")
-	}
-	return myDice.Parent.AccessTokens.Exists(token)
+	return principalOf(c) != nil
 }
 
 func GetHexData(c echo.Context, method string, name string) (value []byte, finished bool) {
@@ -0,0 +1,150 @@
+package backuprepo //nolint:testpackage
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+func TestWebDAVTarget(t *testing.T) {
+	handler := &webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "seal" || pass != "pw" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	target, err := NewWebDAVTarget(server.URL+"/dav/backup", "seal", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 基础目录本身也需要自动创建
+	testTarget(t, target)
+
+	target, _ = NewWebDAVTarget(server.URL+"/dav/repo/", "seal", "pw")
+	if _, err = Open(context.Background(), target, "secret"); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err = Open(context.Background(), target, "secret"); err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+}
+
+// fakeS3 最简单的 S3 兼容服务，校验签名并支持 PUT/GET/DELETE/ListObjectsV2
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	secret  string
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if !s.checkSignature(r, body) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "bucket" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		s.list(w, r.URL.Query())
+	case r.Method == http.MethodPut:
+		s.objects[key] = body
+	case r.Method == http.MethodGet:
+		data, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// list 每页只返回一个对象，以覆盖分页
+func (s *fakeS3) list(w http.ResponseWriter, q map[string][]string) {
+	prefix := q["prefix"][0]
+	var keys []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	start := 0
+	if token := q["continuation-token"]; len(token) > 0 {
+		start = sort.SearchStrings(keys, token[0])
+	}
+	type content struct {
+		Key string `xml:"Key"`
+	}
+	result := struct {
+		XMLName               xml.Name  `xml:"ListBucketResult"`
+		Contents              []content `xml:"Contents"`
+		IsTruncated           bool      `xml:"IsTruncated"`
+		NextContinuationToken string    `xml:"NextContinuationToken,omitempty"`
+	}{}
+	if start < len(keys) {
+		result.Contents = []content{{Key: keys[start]}}
+	}
+	if start+1 < len(keys) {
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[start+1]
+	}
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func (s *fakeS3) checkSignature(r *http.Request, body []byte) bool {
+	date, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	u := *r.URL
+	u.Host = r.Host
+	req := &http.Request{Method: r.Method, URL: &u, Header: http.Header{}}
+	signV4(req, body, "test-region", "AKID", s.secret, date)
+	return req.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func TestS3Target(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, secret: "SECRET"}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	target, err := NewS3Target(server.URL, "test-region", "bucket", "/seal/", "AKID", "SECRET")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTarget(t, target)
+	if _, ok := fake.objects["seal/config"]; !ok {
+		t.Fatal("objects should be stored under the prefix")
+	}
+
+	bad, _ := NewS3Target(server.URL, "test-region", "bucket", "", "AKID", "WRONG")
+	if err = bad.Put(context.Background(), "x", []byte("x")); err == nil {
+		t.Fatal("request with a wrong secret should be rejected")
+	}
+}
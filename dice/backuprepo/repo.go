@@ -0,0 +1,431 @@
+// Package backuprepo 增量备份仓库。文件按固定大小切块，以内容哈希为 key 存储，
+// 未变化的文件和 SQLite 数据库中未变化的页在各个快照间只保存一份。
+// 数据块和快照经 zstd 压缩，设置口令时再用 AES-256-GCM 加密。
+package backuprepo
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	repoVersion = 1
+	// DefaultChunkSize 切块大小，是 SQLite 页大小的整数倍，数据库中改动的页只影响所在的块
+	DefaultChunkSize = 256 << 10
+
+	configKey      = "config"
+	snapshotPrefix = "snapshots/"
+	dataPrefix     = "data/"
+
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var (
+	ErrPassphraseRequired = errors.New("备份仓库已加密，需要口令")
+	ErrWrongPassphrase    = errors.New("备份仓库口令错误")
+	ErrNotEncrypted       = errors.New("备份仓库未加密，不能使用口令。如需加密请换一个新的存储位置")
+	ErrSnapshotNotFound   = errors.New("快照不存在")
+)
+
+var passphraseCheck = []byte("sealdice-backup")
+
+type repoConfig struct {
+	Version   int    `json:"version"`
+	ChunkSize int    `json:"chunkSize"`
+	Encrypted bool   `json:"encrypted"`
+	Salt      []byte `json:"salt,omitempty"`
+	N         int    `json:"n,omitempty"`
+	R         int    `json:"r,omitempty"`
+	P         int    `json:"p,omitempty"`
+	Check     []byte `json:"check,omitempty"` // 用密钥加密的固定内容，用于验证口令
+}
+
+// Repository 一个备份仓库
+type Repository struct {
+	target Target
+	cfg    repoConfig
+	aead   cipher.AEAD // 为空表示不加密
+	idKey  []byte      // 加密时数据块 ID 用 HMAC 计算，避免通过 ID 推测内容
+}
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// Open 打开仓库，仓库不存在时新建。passphrase 为空时新建的仓库不加密
+func Open(ctx context.Context, target Target, passphrase string) (*Repository, error) {
+	r := &Repository{target: target}
+	data, err := target.Get(ctx, configKey)
+	if errors.Is(err, ErrNotFound) {
+		return r, r.init(ctx, passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &r.cfg); err != nil {
+		return nil, fmt.Errorf("备份仓库配置损坏: %w", err)
+	}
+	if r.cfg.Version > repoVersion {
+		return nil, fmt.Errorf("备份仓库版本 %d 高于当前支持的版本 %d", r.cfg.Version, repoVersion)
+	}
+	if !r.cfg.Encrypted {
+		if passphrase != "" {
+			return nil, ErrNotEncrypted
+		}
+		return r, nil
+	}
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	if err = r.deriveKeys(passphrase); err != nil {
+		return nil, err
+	}
+	check, err := r.open(r.cfg.Check)
+	if err != nil || !hmac.Equal(check, passphraseCheck) {
+		return nil, ErrWrongPassphrase
+	}
+	return r, nil
+}
+
+func (r *Repository) init(ctx context.Context, passphrase string) error {
+	r.cfg = repoConfig{Version: repoVersion, ChunkSize: DefaultChunkSize}
+	if passphrase != "" {
+		r.cfg.Encrypted = true
+		r.cfg.Salt = make([]byte, 32)
+		if _, err := rand.Read(r.cfg.Salt); err != nil {
+			return err
+		}
+		r.cfg.N, r.cfg.R, r.cfg.P = scryptN, scryptR, scryptP
+		if err := r.deriveKeys(passphrase); err != nil {
+			return err
+		}
+		var err error
+		if r.cfg.Check, err = r.seal(passphraseCheck); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(r.cfg)
+	if err != nil {
+		return err
+	}
+	return r.target.Put(ctx, configKey, data)
+}
+
+func (r *Repository) deriveKeys(passphrase string) error {
+	key, err := scrypt.Key([]byte(passphrase), r.cfg.Salt, r.cfg.N, r.cfg.R, r.cfg.P, 64)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return err
+	}
+	if r.aead, err = cipher.NewGCM(block); err != nil {
+		return err
+	}
+	r.idKey = key[32:]
+	return nil
+}
+
+// Encrypted 仓库是否加密
+func (r *Repository) Encrypted() bool {
+	return r.cfg.Encrypted
+}
+
+func (r *Repository) seal(plain []byte) ([]byte, error) {
+	if r.aead == nil {
+		return plain, nil
+	}
+	nonce := make([]byte, r.aead.NonceSize(), r.aead.NonceSize()+len(plain)+r.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return r.aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func (r *Repository) open(data []byte) ([]byte, error) {
+	if r.aead == nil {
+		return data, nil
+	}
+	n := r.aead.NonceSize()
+	if len(data) < n {
+		return nil, errors.New("数据长度不足")
+	}
+	return r.aead.Open(nil, data[:n], data[n:], nil)
+}
+
+// encode 压缩后加密
+func (r *Repository) encode(plain []byte) ([]byte, error) {
+	return r.seal(zstdEncoder.EncodeAll(plain, nil))
+}
+
+func (r *Repository) decode(data []byte) ([]byte, error) {
+	compressed, err := r.open(data)
+	if err != nil {
+		return nil, err
+	}
+	return zstdDecoder.DecodeAll(compressed, nil)
+}
+
+func (r *Repository) chunkID(data []byte) string {
+	if r.idKey != nil {
+		return hex.EncodeToString(hmacSHA256(r.idKey, string(data)))
+	}
+	return sha256Hex(data)
+}
+
+func dataKey(id string) string {
+	return dataPrefix + id[:2] + "/" + id
+}
+
+// Snapshot 一次备份
+type Snapshot struct {
+	ID    string            `json:"id"`
+	Time  int64             `json:"time"`
+	Meta  map[string]string `json:"meta,omitempty"` // 调用方附加的信息，如版本号
+	Size  int64             `json:"size"`           // 文件总大小
+	Added int64             `json:"added"`          // 本次新上传的数据量（压缩前）
+	Files []SnapshotFile    `json:"files,omitempty"`
+}
+
+type SnapshotFile struct {
+	Path    string   `json:"path"` // / 分隔的相对路径
+	Size    int64    `json:"size"`
+	ModTime int64    `json:"modTime"`
+	Chunks  []string `json:"chunks"`
+}
+
+// BackupResult 备份结果
+type BackupResult struct {
+	Snapshot  *Snapshot `json:"snapshot"`
+	NewChunks int       `json:"newChunks"`
+	Skipped   []string  `json:"skipped"` // 无法读取的文件
+}
+
+// Snapshots 按时间升序列出全部快照
+func (r *Repository) Snapshots(ctx context.Context) ([]*Snapshot, error) {
+	keys, err := r.target.List(ctx, snapshotPrefix)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make([]*Snapshot, 0, len(keys))
+	for _, key := range keys {
+		snap, loadErr := r.loadSnapshot(ctx, strings.TrimPrefix(key, snapshotPrefix))
+		if loadErr != nil {
+			return nil, fmt.Errorf("读取快照 %s 失败: %w", key, loadErr)
+		}
+		snapshots = append(snapshots, snap)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Time != snapshots[j].Time {
+			return snapshots[i].Time < snapshots[j].Time
+		}
+		return snapshots[i].ID < snapshots[j].ID
+	})
+	return snapshots, nil
+}
+
+func (r *Repository) loadSnapshot(ctx context.Context, id string) (*Snapshot, error) {
+	data, err := r.target.Get(ctx, snapshotPrefix+id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data, err = r.decode(data); err != nil {
+		return nil, err
+	}
+	var snap Snapshot
+	if err = json.Unmarshal(data, &snap); err != nil {
+		return nil, err
+	}
+	snap.ID = id
+	return &snap, nil
+}
+
+// referencedChunks 已有快照引用的全部数据块
+func referencedChunks(snapshots []*Snapshot) map[string]bool {
+	known := map[string]bool{}
+	for _, snap := range snapshots {
+		for _, f := range snap.Files {
+			for _, id := range f.Chunks {
+				known[id] = true
+			}
+		}
+	}
+	return known
+}
+
+// Backup 备份 files 中列出的文件（相对当前目录、/ 分隔），只上传已有快照中没有的数据块。
+// 快照在全部数据块上传后才写入，中途失败不会留下不完整的快照。
+func (r *Repository) Backup(ctx context.Context, files []string, meta map[string]string) (*BackupResult, error) {
+	existing, err := r.Snapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := referencedChunks(existing)
+
+	now := time.Now()
+	snap := &Snapshot{Time: now.Unix(), Meta: meta}
+	result := &BackupResult{Snapshot: snap}
+	buf := make([]byte, r.cfg.ChunkSize)
+	for _, fn := range files {
+		entry, backupErr := r.backupFile(ctx, fn, buf, known, snap, result)
+		if backupErr != nil {
+			if ctx.Err() != nil || !isReadError(backupErr) {
+				return nil, fmt.Errorf("备份 %s 失败: %w", fn, backupErr)
+			}
+			result.Skipped = append(result.Skipped, fn)
+			continue
+		}
+		snap.Files = append(snap.Files, *entry)
+		snap.Size += entry.Size
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = r.encode(data); err != nil {
+		return nil, err
+	}
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	snap.ID = now.UTC().Format("20060102T150405.000000Z") + "-" + hex.EncodeToString(suffix)
+	if err = r.target.Put(ctx, snapshotPrefix+snap.ID, data); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readError 读取本地文件时出错，这类文件跳过而不是中止整个备份
+type readError struct{ err error }
+
+func (e *readError) Error() string { return e.err.Error() }
+func (e *readError) Unwrap() error { return e.err }
+
+func isReadError(err error) bool {
+	var re *readError
+	return errors.As(err, &re)
+}
+
+func (r *Repository) backupFile(ctx context.Context, fn string, buf []byte, known map[string]bool, snap *Snapshot, result *BackupResult) (*SnapshotFile, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, &readError{err}
+	}
+	defer func() { _ = f.Close() }()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, &readError{err}
+	}
+	entry := &SnapshotFile{Path: fn, ModTime: stat.ModTime().Unix(), Chunks: []string{}}
+	for {
+		n, readErr := io.ReadFull(f, buf)
+		if n > 0 {
+			chunk := buf[:n]
+			id := r.chunkID(chunk)
+			if !known[id] {
+				data, encErr := r.encode(chunk)
+				if encErr != nil {
+					return nil, encErr
+				}
+				if err = r.target.Put(ctx, dataKey(id), data); err != nil {
+					return nil, err
+				}
+				known[id] = true
+				result.NewChunks++
+				snap.Added += int64(n)
+			}
+			entry.Chunks = append(entry.Chunks, id)
+			entry.Size += int64(n)
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			return entry, nil
+		}
+		if readErr != nil {
+			return nil, &readError{readErr}
+		}
+	}
+}
+
+// Snapshot 读取指定快照
+func (r *Repository) Snapshot(ctx context.Context, id string) (*Snapshot, error) {
+	if id == "" || strings.ContainsAny(id, "/\\") {
+		return nil, ErrSnapshotNotFound
+	}
+	return r.loadSnapshot(ctx, id)
+}
+
+// WriteFile 把快照中的一个文件写入 w
+func (r *Repository) WriteFile(ctx context.Context, f *SnapshotFile, w io.Writer) error {
+	for _, id := range f.Chunks {
+		data, err := r.target.Get(ctx, dataKey(id))
+		if err != nil {
+			return fmt.Errorf("读取数据块 %s 失败: %w", id, err)
+		}
+		if data, err = r.decode(data); err != nil {
+			return fmt.Errorf("解码数据块 %s 失败: %w", id, err)
+		}
+		if r.chunkID(data) != id {
+			return fmt.Errorf("数据块 %s 校验失败", id)
+		}
+		if _, err = w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prune 只保留最近 keep 个快照，并删除不再被引用的数据块。不能与 Backup 同时执行
+func (r *Repository) Prune(ctx context.Context, keep int) (removedSnapshots, removedChunks int, err error) {
+	snapshots, err := r.Snapshots(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	if keep < 1 || len(snapshots) <= keep {
+		return 0, 0, nil
+	}
+	for _, snap := range snapshots[:len(snapshots)-keep] {
+		if err = r.target.Delete(ctx, snapshotPrefix+snap.ID); err != nil {
+			return removedSnapshots, 0, err
+		}
+		removedSnapshots++
+	}
+
+	used := referencedChunks(snapshots[len(snapshots)-keep:])
+	keys, err := r.target.List(ctx, dataPrefix)
+	if err != nil {
+		return removedSnapshots, 0, err
+	}
+	for _, key := range keys {
+		id := key[strings.LastIndex(key, "/")+1:]
+		if used[id] {
+			continue
+		}
+		if err = r.target.Delete(ctx, key); err != nil {
+			return removedSnapshots, removedChunks, err
+		}
+		removedChunks++
+	}
+	return removedSnapshots, removedChunks, nil
+}
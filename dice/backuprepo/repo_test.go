@@ -0,0 +1,166 @@
+package backuprepo //nolint:testpackage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func writeFile(t *testing.T, fn string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fn, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func restoreFile(t *testing.T, repo *Repository, snap *Snapshot, path string) []byte {
+	t.Helper()
+	for i := range snap.Files {
+		if snap.Files[i].Path == path {
+			var buf bytes.Buffer
+			if err := repo.WriteFile(context.Background(), &snap.Files[i], &buf); err != nil {
+				t.Fatalf("WriteFile(%s) error = %v", path, err)
+			}
+			return buf.Bytes()
+		}
+	}
+	t.Fatalf("%s not in snapshot", path)
+	return nil
+}
+
+func TestBackupDeduplicatesChunks(t *testing.T) {
+	t.Chdir(t.TempDir())
+	ctx := context.Background()
+	target := &LocalTarget{Dir: t.TempDir()}
+
+	// 模拟一个 3 块大小的数据库，首尾两块内容相同，第二次备份只改动中间一页
+	db := make([]byte, DefaultChunkSize*3)
+	for i := range db {
+		db[i] = byte(i / DefaultChunkSize % 2)
+	}
+	writeFile(t, "data/default/data.db", db)
+	writeFile(t, "data/dice.yaml", []byte("a: 1"))
+
+	repo, err := Open(ctx, target, "secret")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	files := []string{"data/default/data.db", "data/dice.yaml", "data/missing.yaml"}
+	first, err := repo.Backup(ctx, files, map[string]string{"version": "1"})
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	// 相同的块只保存一次
+	if first.NewChunks != 3 || len(first.Skipped) != 1 {
+		t.Fatalf("first backup = %+v", first)
+	}
+
+	db[DefaultChunkSize+4096] = 7
+	writeFile(t, "data/default/data.db", db)
+	second, err := repo.Backup(ctx, files, nil)
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if second.NewChunks != 1 || second.Snapshot.Added != DefaultChunkSize {
+		t.Fatalf("second backup uploaded %d chunks (%d bytes), want 1", second.NewChunks, second.Snapshot.Added)
+	}
+
+	// 重新打开仓库后能读出两个快照的内容
+	repo, err = Open(ctx, target, "secret")
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	snapshots, err := repo.Snapshots(ctx)
+	if err != nil || len(snapshots) != 2 {
+		t.Fatalf("Snapshots() = %d, %v", len(snapshots), err)
+	}
+	if snapshots[0].Meta["version"] != "1" {
+		t.Fatalf("snapshot meta = %v", snapshots[0].Meta)
+	}
+	if got := restoreFile(t, repo, snapshots[0], "data/default/data.db"); got[DefaultChunkSize+4096] != 1 || len(got) != len(db) {
+		t.Fatal("first snapshot content changed")
+	}
+	if got := restoreFile(t, repo, snapshots[1], "data/default/data.db"); !bytes.Equal(got, db) {
+		t.Fatal("second snapshot content mismatch")
+	}
+
+	removed, chunks, err := repo.Prune(ctx, 1)
+	if err != nil || removed != 1 || chunks != 1 {
+		t.Fatalf("Prune() = %d, %d, %v", removed, chunks, err)
+	}
+	snapshots, _ = repo.Snapshots(ctx)
+	if got := restoreFile(t, repo, snapshots[0], "data/default/data.db"); !bytes.Equal(got, db) {
+		t.Fatal("snapshot content mismatch after prune")
+	}
+}
+
+func TestOpenChecksPassphrase(t *testing.T) {
+	ctx := context.Background()
+	target := &LocalTarget{Dir: t.TempDir()}
+	if _, err := Open(ctx, target, "secret"); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := Open(ctx, target, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("Open(wrong) error = %v", err)
+	}
+	if _, err := Open(ctx, target, ""); !errors.Is(err, ErrPassphraseRequired) {
+		t.Fatalf("Open(empty) error = %v", err)
+	}
+
+	plain := &LocalTarget{Dir: t.TempDir()}
+	if _, err := Open(ctx, plain, ""); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := Open(ctx, plain, "secret"); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("Open(unencrypted, passphrase) error = %v", err)
+	}
+}
+
+// testTarget 对各个存储实现做相同的读写检查
+func testTarget(t *testing.T, target Target) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := target.Get(ctx, "data/ab/missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(missing) error = %v", err)
+	}
+	for _, key := range []string{"config", "data/ab/ab01", "data/cd/cd02", "snapshots/s1"} {
+		if err := target.Put(ctx, key, []byte(key)); err != nil {
+			t.Fatalf("Put(%s) error = %v", key, err)
+		}
+	}
+	data, err := target.Get(ctx, "data/cd/cd02")
+	if err != nil || string(data) != "data/cd/cd02" {
+		t.Fatalf("Get() = %q, %v", data, err)
+	}
+	keys, err := target.List(ctx, "data/")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "data/ab/ab01" || keys[1] != "data/cd/cd02" {
+		t.Fatalf("List(data/) = %v", keys)
+	}
+	if err = target.Delete(ctx, "data/ab/ab01"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err = target.Delete(ctx, "data/ab/ab01"); err != nil {
+		t.Fatalf("Delete(missing) error = %v", err)
+	}
+	if keys, _ = target.List(ctx, "data/"); len(keys) != 1 {
+		t.Fatalf("List() after delete = %v", keys)
+	}
+	if keys, _ = target.List(ctx, "nothing/"); len(keys) != 0 {
+		t.Fatalf("List(nothing/) = %v", keys)
+	}
+}
+
+func TestLocalTarget(t *testing.T) {
+	testTarget(t, &LocalTarget{Dir: t.TempDir()})
+}
@@ -0,0 +1,125 @@
+package backuprepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNotFound = errors.New("对象不存在")
+
+// Target 备份仓库的存储位置。key 以 / 分隔，如 data/ab/abcd...
+type Target interface {
+	Put(ctx context.Context, key string, data []byte) error
+	// Get 对象不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List 列出以 prefix 开头的全部 key，prefix 以 / 结尾
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete 删除不存在的对象不报错
+	Delete(ctx context.Context, key string) error
+}
+
+const (
+	TargetLocal  = "local"
+	TargetWebDAV = "webdav"
+	TargetS3     = "s3"
+)
+
+// TargetConfig 存储位置的配置，按 Type 使用对应的字段
+type TargetConfig struct {
+	Type string `json:"type" yaml:"type"`
+
+	Dir string `json:"dir" yaml:"dir"` // local
+
+	URL      string `json:"url"      yaml:"url"` // webdav，如 https://dav.example.com/sealdice/
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+
+	Endpoint  string `json:"endpoint"  yaml:"endpoint"` // s3，如 https://s3.us-east-1.amazonaws.com
+	Region    string `json:"region"    yaml:"region"`
+	Bucket    string `json:"bucket"    yaml:"bucket"`
+	Prefix    string `json:"prefix"    yaml:"prefix"`
+	AccessKey string `json:"accessKey" yaml:"accessKey"`
+	SecretKey string `json:"secretKey" yaml:"secretKey"`
+}
+
+// NewTarget 按配置创建存储位置
+func NewTarget(cfg TargetConfig) (Target, error) {
+	switch cfg.Type {
+	case TargetLocal:
+		if cfg.Dir == "" {
+			return nil, errors.New("请填写备份目录")
+		}
+		return &LocalTarget{Dir: cfg.Dir}, nil
+	case TargetWebDAV:
+		return NewWebDAVTarget(cfg.URL, cfg.Username, cfg.Password)
+	case TargetS3:
+		return NewS3Target(cfg.Endpoint, cfg.Region, cfg.Bucket, cfg.Prefix, cfg.AccessKey, cfg.SecretKey)
+	default:
+		return nil, fmt.Errorf("未知的备份存储类型: %q", cfg.Type)
+	}
+}
+
+// LocalTarget 本地目录，也可以是挂载的网络盘
+type LocalTarget struct {
+	Dir string
+}
+
+func (t *LocalTarget) path(key string) string {
+	return filepath.Join(t.Dir, filepath.FromSlash(key))
+}
+
+func (t *LocalTarget) Put(_ context.Context, key string, data []byte) error {
+	fn := t.path(key)
+	if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+		return err
+	}
+	tmp := fn + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+func (t *LocalTarget) Get(_ context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(t.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (t *LocalTarget) List(_ context.Context, prefix string) ([]string, error) {
+	var keys []string
+	root := t.path(prefix)
+	err := filepath.WalkDir(root, func(fn string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(fn, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(t.Dir, fn)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(rel))
+		return nil
+	})
+	return keys, err
+}
+
+func (t *LocalTarget) Delete(_ context.Context, key string) error {
+	err := os.Remove(t.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,210 @@
+package backuprepo
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// WebDAVTarget 通过 WebDAV 存储，目录按需用 MKCOL 创建
+type WebDAVTarget struct {
+	base     *url.URL
+	username string
+	password string
+	client   *http.Client
+}
+
+func NewWebDAVTarget(rawURL, username, password string) (*WebDAVTarget, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("无效的 WebDAV 地址: %q", rawURL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return &WebDAVTarget{
+		base:     u,
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (t *WebDAVTarget) url(key string) string {
+	return t.urlOfPath(t.base.Path + key)
+}
+
+func (t *WebDAVTarget) urlOfPath(p string) string {
+	u := *t.base
+	u.Path = p
+	return u.String()
+}
+
+func (t *WebDAVTarget) do(ctx context.Context, method, key string, body []byte, header map[string]string) (*http.Response, error) {
+	return t.doURL(ctx, method, t.url(key), body, header)
+}
+
+func (t *WebDAVTarget) doURL(ctx context.Context, method, rawURL string, body []byte, header map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if t.username != "" || t.password != "" {
+		req.SetBasicAuth(t.username, t.password)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	return t.client.Do(req)
+}
+
+func webdavStatusError(method, key string, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("WebDAV %s %s: %s %s", method, key, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+func (t *WebDAVTarget) Put(ctx context.Context, key string, data []byte) error {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.do(ctx, http.MethodPut, key, data, nil)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return nil
+		case attempt == 0 && (resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusNotFound):
+			// 上级目录不存在
+			if err = t.mkdirs(ctx, path.Dir(t.base.Path+key)); err != nil {
+				return err
+			}
+		default:
+			return webdavStatusError(http.MethodPut, key, resp)
+		}
+	}
+}
+
+// mkdirs 创建目录，上级目录不存在（409）时先创建上级。已存在的目录返回 405，忽略即可
+func (t *WebDAVTarget) mkdirs(ctx context.Context, dir string) error {
+	dir = strings.TrimSuffix(dir, "/") + "/"
+	if dir == "/" {
+		return nil
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := t.doURL(ctx, "MKCOL", t.urlOfPath(dir), nil, nil)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		switch {
+		case resp.StatusCode < 300 || resp.StatusCode == http.StatusMethodNotAllowed:
+			return nil
+		case attempt == 0 && resp.StatusCode == http.StatusConflict:
+			if err = t.mkdirs(ctx, path.Dir(strings.TrimSuffix(dir, "/"))); err != nil {
+				return err
+			}
+		default:
+			return webdavStatusError("MKCOL", dir, resp)
+		}
+	}
+}
+
+func (t *WebDAVTarget) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := t.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, webdavStatusError(http.MethodGet, key, resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (t *WebDAVTarget) Delete(ctx context.Context, key string) error {
+	resp, err := t.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return webdavStatusError(http.MethodDelete, key, resp)
+	}
+	return nil
+}
+
+type webdavMultistatus struct {
+	Responses []struct {
+		Href       string    `xml:"DAV: href"`
+		Collection *struct{} `xml:"DAV: propstat>prop>resourcetype>collection"`
+	} `xml:"DAV: response"`
+}
+
+const webdavPropfindBody = `<?xml version="1.0" encoding="utf-8"?><D:propfind xmlns:D="DAV:"><D:prop><D:resourcetype/></D:prop></D:propfind>`
+
+// List 不是所有服务都支持 Depth: infinity，这里逐层列出
+func (t *WebDAVTarget) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	dirs := []string{prefix}
+	for len(dirs) > 0 {
+		dir := dirs[0]
+		dirs = dirs[1:]
+		resp, err := t.do(ctx, "PROPFIND", dir, []byte(webdavPropfindBody), map[string]string{
+			"Depth":        "1",
+			"Content-Type": "application/xml",
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			_ = resp.Body.Close()
+			continue
+		}
+		if resp.StatusCode != http.StatusMultiStatus {
+			err = webdavStatusError("PROPFIND", dir, resp)
+			_ = resp.Body.Close()
+			return nil, err
+		}
+		var ms webdavMultistatus
+		err = xml.NewDecoder(resp.Body).Decode(&ms)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range ms.Responses {
+			key, ok := t.keyOf(r.Href)
+			if !ok || key == "" || strings.TrimSuffix(key, "/") == strings.TrimSuffix(dir, "/") {
+				continue
+			}
+			if r.Collection != nil {
+				dirs = append(dirs, strings.TrimSuffix(key, "/")+"/")
+			} else {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// keyOf 把 PROPFIND 返回的 href 转为 key，href 可能是完整URL或绝对路径
+func (t *WebDAVTarget) keyOf(href string) (string, bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	p := u.Path
+	if !strings.HasPrefix(p, t.base.Path) {
+		return "", false
+	}
+	return strings.TrimPrefix(p, t.base.Path), true
+}
@@ -21,6 +21,7 @@ import (
 	ds "github.com/sealdice/dicescript"
 
 	"sealdice-core/dice/docengine"
+	"sealdice-core/dice/service"
 )
 
 type dismissConfirmState struct {
@@ -255,6 +256,11 @@ func (d *Dice) executeDismissOperation(ctx *MsgContext, msg *Message, targetGrou
 }
 
 func (d *Dice) executeDismissWithConfirm(ctx *MsgContext, msg *Message, targetGroupID string, targetGroup *GroupInfo, inputCode string, hasExtraArgs bool, confirmCommand string) CmdExecuteResult {
+	if !ctx.EndPoint.Capabilities().QuitGroup {
+		ReplyToSender(ctx, msg, "当前平台不支持骰子主动退群，请由管理员手动将骰子移出群组")
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+
 	processDismissConfirmation := func(roleDetail string, issueLogTpl string, issueReplyTpl string, successLogTpl string) CmdExecuteResult {
 		confirmKey := getDismissConfirmKeyForGroup(ctx, msg.Sender.UserID, targetGroupID)
 		if inputCode == "" || hasExtraArgs {
@@ -323,6 +329,7 @@ func (d *Dice) registerCoreCommands() {
 	helpForBlack := ".ban add user <帐号> [<原因>] //添加个人\n" +
 		".ban add group <群号> [<原因>] //添加群组\n" +
 		".ban add <统一ID>\n" +
+		".ban add <统一ID> [<原因>] --time=<分钟> //限时拉黑，到期自动解除\n" +
 		".ban rm user <帐号> //解黑/移出信任\n" +
 		".ban rm group <群号>\n" +
 		".ban rm <统一ID> //同上\n" +
@@ -330,6 +337,9 @@ func (d *Dice) registerCoreCommands() {
 		".ban list ban/warn/trust //只显示被禁用/被警告/信任用户\n" +
 		".ban trust <统一ID> //添加信任\n" +
 		".ban query <统一ID> //查看指定用户拉黑情况\n" +
+		".ban appeal <申诉内容> //被拉黑/警告的用户向骰主申诉\n" +
+		".ban appeal list //查看待处理的申诉\n" +
+		".ban appeal accept/reject <编号> [<回复>] //通过/驳回申诉\n" +
 		".ban help //查看帮助\n" +
 		"// 统一ID示例: QQ:12345、QQ-Group:12345"
 	cmdBlack := &CmdItemInfo{
@@ -337,7 +347,10 @@ func (d *Dice) registerCoreCommands() {
 		ShortHelp: helpForBlack,
 		Help:      "黑名单指令:\n" + helpForBlack,
 		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
-			cmdArgs.ChopPrefixToArgsWith("add", "rm", "del", "list", "show", "find", "trust")
+			cmdArgs.ChopPrefixToArgsWith("add", "rm", "del", "list", "show", "find", "trust", "appeal")
+			if cmdArgs.IsArgEqual(1, "appeal") {
+				return banAppealSolve(ctx, msg, cmdArgs)
+			}
 			if ctx.PrivilegeLevel < 100 {
 				ReplyToSender(ctx, msg, "你不具备Master权限")
 				return CmdExecuteResult{Matched: true, Solved: true}
@@ -370,11 +383,33 @@ func (d *Dice) registerCoreCommands() {
 					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
 				}
 				reason := cmdArgs.GetArgN(4)
+				if !cmdArgs.IsArgEqual(2, "user") && !cmdArgs.IsArgEqual(2, "group") {
+					reason = cmdArgs.GetArgN(3)
+				}
 				if reason == "" {
 					reason = "骰主指令"
 				}
-				(&d.Config).BanList.AddScoreBase(uid, (&d.Config).BanList.ThresholdBan, "骰主指令", reason, ctx)
-				ReplyToSender(ctx, msg, fmt.Sprintf("已将用户/群组 %s 加入黑名单，原因: %s", uid, reason))
+				// 默认永久，--time 指定分钟数
+				src := BanSource{Handler: BanHandlerCommand, Operator: msg.Sender.UserID, Minutes: -1}
+				if kw := cmdArgs.GetKwarg("time"); kw != nil {
+					minutes, err := strconv.ParseInt(kw.Value, 10, 64)
+					if err != nil || minutes <= 0 {
+						ReplyToSender(ctx, msg, "拉黑时长需为正整数(分钟)")
+						break
+					}
+					src.Minutes = minutes
+				}
+				item, _ := (&d.Config).BanList.GetByID(uid)
+				if item != nil && item.Rank == BanRankBanned {
+					// 已在黑名单中时先解除，使新的时长生效
+					(&d.Config).BanList.Unban(uid, "重新拉黑", src)
+				}
+				item = (&d.Config).BanList.AddScoreWithSource(uid, (&d.Config).BanList.ThresholdBan, "骰主指令", reason, src, ctx)
+				text := fmt.Sprintf("已将用户/群组 %s 加入黑名单，原因: %s", uid, reason)
+				if item.ExpireAt > 0 {
+					text += "，到期时间: " + carbon.CreateFromTimestamp(item.ExpireAt).ToDateTimeString()
+				}
+				ReplyToSender(ctx, msg, text)
 			case "rm", "del":
 				uid = getID()
 				if uid == "" {
@@ -388,8 +423,7 @@ func (d *Dice) registerCoreCommands() {
 				}
 
 				ReplyToSender(ctx, msg, fmt.Sprintf("已将用户/群组 %s 移出%s列表", uid, BanRankText[item.Rank]))
-				item.Score = 0
-				item.Rank = BanRankNormal
+				(&d.Config).BanList.Unban(uid, "骰主指令", BanSource{Handler: BanHandlerCommand, Operator: msg.Sender.UserID})
 			case "trust":
 				uid = cmdArgs.GetArgN(2)
 				if !strings.Contains(uid, ":") {
@@ -397,7 +431,7 @@ func (d *Dice) registerCoreCommands() {
 					return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
 				}
 
-				(&d.Config).BanList.SetTrustByID(uid, "骰主指令", "骰主指令")
+				(&d.Config).BanList.SetTrustWithSource(uid, "骰主指令", "骰主指令", BanSource{Handler: BanHandlerCommand, Operator: msg.Sender.UserID})
 				ReplyToSender(ctx, msg, fmt.Sprintf("已将用户/群组 %s 加入信任列表", uid))
 			case "list", "show":
 				// ban/warn/trust
@@ -449,6 +483,9 @@ func (d *Dice) registerCoreCommands() {
 				default:
 					text.WriteString("正常(0)")
 				}
+				if v.Rank == BanRankBanned && v.ExpireAt > 0 {
+					fmt.Fprintf(&text, "，到期时间：%s", carbon.CreateFromTimestamp(v.ExpireAt).ToDateTimeString())
+				}
 				for i, reason := range v.Reasons {
 					fmt.Fprintf(
 						&text,
@@ -458,6 +495,12 @@ func (d *Dice) registerCoreCommands() {
 						reason,
 					)
 				}
+				if d.DBOperator != nil {
+					total, _, err := service.BanEventGetPage(d.DBOperator, service.QueryBanEvent{PageNum: 1, PageSize: 1, TargetID: targetID})
+					if err == nil && total > 0 {
+						fmt.Fprintf(&text, "\n共有%d条记录，完整记录请在后台查看", total)
+					}
+				}
 				ReplyToSender(ctx, msg, text.String())
 			default:
 				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
@@ -2344,6 +2387,7 @@ func (d *Dice) registerCoreCommands() {
 		".pc rename <角色名|序号> <新角色名> // 将指定角色改名 \n" +
 		// ".ch group // 列出各群当前绑卡\n" +
 		".pc save [<角色名>] // [不绑卡]保存角色，角色名可省略\n" +
+		".pc import [<角色名>] // 从同一条消息附带的文件导入角色并绑卡，支持 JSON/CSV/Excel 车卡\n" +
 		".pc load (<角色名> | <角色序号>) // [不绑卡]加载角色\n" +
 		".pc del/rm (<角色名> | <角色序号>) // 删除角色 角色序号可用pc list查询\n" +
 		"> 注: 海豹各群数据独立(多张空白卡)，单群游戏不需要存角色。"
@@ -2353,7 +2397,7 @@ func (d *Dice) registerCoreCommands() {
 		ShortHelp: helpCh,
 		Help:      "角色管理:\n" + helpCh,
 		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) (result CmdExecuteResult) {
-			cmdArgs.ChopPrefixToArgsWith("list", "lst", "load", "save", "del", "rm", "new", "tag", "untagAll", "rename")
+			cmdArgs.ChopPrefixToArgsWith("list", "lst", "load", "save", "del", "rm", "new", "tag", "untagAll", "rename", "import")
 			val1 := cmdArgs.GetArgN(1)
 			am := d.AttrsManager
 
@@ -2456,6 +2500,37 @@ func (d *Dice) registerCoreCommands() {
 					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:角色管理_新建_已存在"))
 				}
 
+				if ctx.Player.AutoSetNameTemplate != "" {
+					_, _ = SetPlayerGroupCardByTemplate(ctx, ctx.Player.AutoSetNameTemplate)
+				}
+				return CmdExecuteResult{Matched: true, Solved: true}
+			case "import":
+				importFail := func(err error) CmdExecuteResult {
+					VarSetValueStr(ctx, "$t原因", err.Error())
+					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:角色管理_导入失败"))
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				filename, data, err := sheetFileFromMessage(msg)
+				if err != nil {
+					return importFail(err)
+				}
+				ret, err := d.CharacterImportExternal(ctx.Player.UserID, filename, data, SheetImportOptions{
+					Name:             getNicknameRaw(false, false),
+					DefaultSheetType: ctx.Group.System,
+				})
+				if err != nil {
+					return importFail(err)
+				}
+				lo.Must0(am.CharBind(ret.Sheet.ID, ctx.Group.GroupID, ctx.Player.UserID))
+				setCurPlayerName(ret.Sheet.Name)
+
+				VarSetValueStr(ctx, "$t角色名", ret.Sheet.Name)
+				VarSetValueStr(ctx, "$t导入格式", ret.Format)
+				VarSetValueInt64(ctx, "$t导入数量", int64(len(ret.Imported)))
+				VarSetValueInt64(ctx, "$t未识别数量", int64(len(ret.Unmapped)))
+				VarSetValueStr(ctx, "$t未识别列表", strings.Join(ret.Unmapped, "、"))
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:角色管理_导入成功"))
+
 				if ctx.Player.AutoSetNameTemplate != "" {
 					_, _ = SetPlayerGroupCardByTemplate(ctx, ctx.Player.AutoSetNameTemplate)
 				}
@@ -2731,6 +2806,32 @@ func (d *Dice) registerCoreCommands() {
 		},
 	}
 	d.CmdMap["reply"] = cmdReply
+
+	cmdCensor := &CmdItemInfo{
+		Name:              "censor",
+		ShortHelp:         helpForCensor,
+		Help:              "群内拦截策略:\n" + helpForCensor,
+		DisabledInPrivate: true,
+		Solve:             cmdCensorSolve,
+	}
+	d.CmdMap["censor"] = cmdCensor
+
+	cmdLink := &CmdItemInfo{
+		Name:      "link",
+		ShortHelp: helpForLink,
+		Help:      "跨平台账号关联:\n" + helpForLink,
+		Solve:     cmdLinkSolve,
+	}
+	d.CmdMap["link"] = cmdLink
+
+	cmdSchedule := &CmdItemInfo{
+		Name:              "schedule",
+		ShortHelp:         helpForSchedule,
+		Help:              "群日程提醒:\n" + helpForSchedule,
+		DisabledInPrivate: true,
+		Solve:             cmdScheduleSolve,
+	}
+	d.CmdMap["schedule"] = cmdSchedule
 }
 
 func getDefaultDicePoints(ctx *MsgContext) int64 {
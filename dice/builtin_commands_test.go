@@ -9,6 +9,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	milky "github.com/Szzrain/Milky-go-sdk"
 	ds "github.com/sealdice/dicescript"
@@ -267,6 +268,26 @@ func TestDismissAcceptsFourDigitConfirmationCode(t *testing.T) {
 	}
 }
 
+func TestDismissUnsupportedPlatformRepliesInsteadOfQuitting(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	adapter.cannotQuit = true
+	d.Config.BotExitWithoutAt = true
+	ctx, msg := newQuitCommandTestContext(t, d, ep, "TG:9005", "TG-Group:2005", "DismissGroup")
+
+	result := d.CmdMap["dismiss"].Solve(ctx, msg, &CmdArgs{})
+	if !result.Matched || !result.Solved {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+	if len(adapter.quitGroups) != 0 {
+		t.Fatalf("unsupported platform should not quit, got %#v", adapter.quitGroups)
+	}
+	if reply, ok := adapter.waitForMsg(time.Second); !ok || !strings.Contains(reply, "不支持") {
+		t.Fatalf("expected an unsupported notice, got %q", reply)
+	}
+}
+
 func TestShouldDismissRequireOwnerConfirmMilkyRoleNormalization(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -2,10 +2,12 @@ package censor
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -51,10 +53,28 @@ type Censor struct {
 	CaseSensitive  bool   // 大小写敏感
 	MatchPinyin    bool   // 匹配拼音
 	FilterRegexStr string // 过滤字符正则
+	Normalize      bool   // 匹配前规范化文本（全角、形近字、分隔符、重复字符）
 
 	SensitiveKeys map[string]WordInfo
 	t             *trie
 	filterRegex   *regexp.Regexp
+	trieOrigin    map[string]string // 规范化后的词 -> SensitiveKeys 中的键
+	regexRules    []regexRule
+	fuzzyRules    []fuzzyRule
+	curFile       string // 正在读取的词库文件名，用于标注词汇来源
+}
+
+type regexRule struct {
+	key   string
+	re    *regexp.Regexp
+	level Level
+}
+
+type fuzzyRule struct {
+	key      string
+	word     []rune
+	distance int
+	level    Level
 }
 
 type Reason int
@@ -63,12 +83,33 @@ const (
 	Origin Reason = iota
 	IgnoreCase
 	PinYin
+	Normalized // 规范化后命中
+	Regex      // 正则
+	Fuzzy      // 编辑距离
+)
+
+// 正则与编辑距离条目在 SensitiveKeys 中的键前缀，避免与普通词冲突
+const (
+	regexKeyPrefix = "regex:"
+	fuzzyKeyPrefix = "fuzzy:"
 )
 
 type WordInfo struct {
-	Level  Level  // 级别
-	Origin string // 附加词对应的原始词，如大小写不敏感指向原单词，拼音为原词汇
-	Reason Reason // 添加原因
+	Level    Level    // 级别
+	Origin   string   // 附加词对应的原始词，如大小写不敏感指向原单词，拼音为原词汇
+	Reason   Reason   // 添加原因
+	Distance int      // 编辑距离条目允许的最大距离
+	Files    []string // 来源词库文件名
+}
+
+// InFiles 词汇是否来自给定的词库之一
+func (w WordInfo) InFiles(files map[string]bool) bool {
+	for _, f := range w.Files {
+		if files[f] {
+			return true
+		}
+	}
+	return false
 }
 
 type WordFile struct {
@@ -89,6 +130,8 @@ type WordFile struct {
 type FileCounter [5]int
 
 func (c *Censor) PreloadFile(path string) (*WordFile, error) {
+	c.curFile = filepath.Base(path)
+	defer func() { c.curFile = "" }()
 	if strings.ToLower(filepath.Ext(path)) == ".toml" {
 		return c.tryPreloadTomlFile(path)
 	}
@@ -166,9 +209,20 @@ type TomlWords struct {
 	Danger  []string `comment:"危险级词表"                                toml:"danger"`
 }
 
+type TomlFuzzyWords struct {
+	Distance int      `comment:"允许的最大编辑距离，默认为 1，且不超过词长的一半" toml:"distance"`
+	Ignore   []string `comment:"忽略级词表，没有实际作用"                toml:"ignore"`
+	Notice   []string `comment:"提醒级词表"                       toml:"notice"`
+	Caution  []string `comment:"注意级词表"                       toml:"caution"`
+	Warning  []string `comment:"警告级词表"                       toml:"warning"`
+	Danger   []string `comment:"危险级词表"                       toml:"danger"`
+}
+
 type TomlCensorWordFile struct {
-	Meta  TomlMeta  `comment:"元信息，用于填写一些额外的展示内容"                                   toml:"meta"`
-	Words TomlWords `comment:"词表，出现相同词汇时按最高级别判断"                                   toml:"words"`
+	Meta  TomlMeta       `comment:"元信息，用于填写一些额外的展示内容"                                   toml:"meta"`
+	Words TomlWords      `comment:"词表，出现相同词汇时按最高级别判断"                                   toml:"words"`
+	Regex TomlWords      `comment:"正则词表，使用 Go 正则语法，同时匹配原文与规范化后的文本"                      toml:"regex"`
+	Fuzzy TomlFuzzyWords `comment:"模糊词表，与规范化后的文本按编辑距离匹配"                              toml:"fuzzy"`
 }
 
 func (c *Censor) tryPreloadTomlFile(path string) (*WordFile, error) {
@@ -186,7 +240,28 @@ func (c *Censor) tryPreloadTomlFile(path string) (*WordFile, error) {
 		return nil, err
 	}
 
+	// 先检查正则，避免词库只加载一半
+	regexLists := [...][]string{tomlFile.Regex.Ignore, tomlFile.Regex.Notice, tomlFile.Regex.Caution, tomlFile.Regex.Warning, tomlFile.Regex.Danger}
+	for _, patterns := range regexLists {
+		for _, pattern := range patterns {
+			if _, err = regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+			}
+		}
+	}
+
 	var counter FileCounter
+	for level, patterns := range regexLists {
+		for _, pattern := range patterns {
+			c.addRegex(pattern, Level(level), &counter)
+		}
+	}
+	fuzzyLists := [...][]string{tomlFile.Fuzzy.Ignore, tomlFile.Fuzzy.Notice, tomlFile.Fuzzy.Caution, tomlFile.Fuzzy.Warning, tomlFile.Fuzzy.Danger}
+	for level, words := range fuzzyLists {
+		for _, word := range words {
+			c.addFuzzy(word, Level(level), tomlFile.Fuzzy.Distance, &counter)
+		}
+	}
 	for _, word := range tomlFile.Words.Ignore {
 		c.addWord(word, Ignore, &counter)
 	}
@@ -230,12 +305,12 @@ func (c *Censor) addWord(word string, level Level, counter *FileCounter) {
 	key := strings.ToLower(strings.TrimSpace(word))
 	counter[level]++
 	if c.CaseSensitive {
-		c.SensitiveKeys[key] = WordInfo{Level: level}
+		c.putKey(key, WordInfo{Level: level})
 	} else {
 		if c.MatchPinyin {
 			// 拼音必须大小写不敏感
 			w := strings.ToLower(key)
-			c.SensitiveKeys[w] = WordInfo{Level: level, Origin: key, Reason: IgnoreCase}
+			c.putKey(w, WordInfo{Level: level, Origin: key, Reason: IgnoreCase})
 
 			pys := pinyin.LazyPinyin(w, pinyin.Args{
 				Style: pinyin.Normal,
@@ -244,13 +319,51 @@ func (c *Censor) addWord(word string, level Level, counter *FileCounter) {
 				},
 			})
 			pyStr := strings.Join(pys, "")
-			c.SensitiveKeys[strings.ToLower(pyStr)] = WordInfo{Level: level, Origin: key, Reason: PinYin}
+			c.putKey(strings.ToLower(pyStr), WordInfo{Level: level, Origin: key, Reason: PinYin})
 		} else {
-			c.SensitiveKeys[strings.ToLower(key)] = WordInfo{Level: level, Origin: key, Reason: IgnoreCase}
+			c.putKey(strings.ToLower(key), WordInfo{Level: level, Origin: key, Reason: IgnoreCase})
 		}
 	}
 }
 
+// putKey 写入词汇，同一词汇出现在多个词库时取最高级别并合并来源
+func (c *Censor) putKey(key string, info WordInfo) {
+	if old, ok := c.SensitiveKeys[key]; ok {
+		info.Level = HigherLevel(info.Level, old.Level)
+		info.Files = old.Files
+	}
+	if c.curFile != "" && !slices.Contains(info.Files, c.curFile) {
+		info.Files = append(slices.Clone(info.Files), c.curFile)
+	}
+	c.SensitiveKeys[key] = info
+}
+
+// addRegex 正则条目不区分大小写时自动加上 (?i)
+func (c *Censor) addRegex(pattern string, level Level, counter *FileCounter) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return
+	}
+	counter[level]++
+	c.putKey(regexKeyPrefix+pattern, WordInfo{Level: level, Origin: pattern, Reason: Regex})
+}
+
+func (c *Censor) addFuzzy(word string, level Level, distance int, counter *FileCounter) {
+	word = strings.TrimSpace(word)
+	if word == "" {
+		return
+	}
+	counter[level]++
+	c.putKey(fuzzyKeyPrefix+strings.ToLower(word), WordInfo{Level: level, Origin: word, Reason: Fuzzy, Distance: distance})
+}
+
+func (c *Censor) normalize(text string) string {
+	if !c.Normalize {
+		return text
+	}
+	return Normalize(text, c.CaseSensitive)
+}
+
 func (c *Censor) Load() (err error) {
 	if c.FilterRegexStr != "" {
 		c.filterRegex = regexp.MustCompile(c.FilterRegexStr)
@@ -259,38 +372,120 @@ func (c *Censor) Load() (err error) {
 	}
 
 	c.t = newTire()
-	if c.SensitiveKeys != nil {
-		for key, wordInfo := range c.SensitiveKeys {
-			c.t.Insert(key, wordInfo.Level)
+	c.trieOrigin = map[string]string{}
+	c.regexRules = nil
+	c.fuzzyRules = nil
+	for key, wordInfo := range c.SensitiveKeys {
+		switch wordInfo.Reason {
+		case Regex:
+			pattern := wordInfo.Origin
+			if !c.CaseSensitive {
+				pattern = "(?i)" + pattern
+			}
+			re, errCompile := regexp.Compile(pattern)
+			if errCompile != nil {
+				err = errCompile
+				continue
+			}
+			c.regexRules = append(c.regexRules, regexRule{key: key, re: re, level: wordInfo.Level})
+		case Fuzzy:
+			word := []rune(Normalize(wordInfo.Origin, c.CaseSensitive))
+			c.fuzzyRules = append(c.fuzzyRules, fuzzyRule{
+				key:      key,
+				word:     word,
+				distance: fuzzyDistance(word, wordInfo.Distance),
+				level:    wordInfo.Level,
+			})
+		default:
+			normalized := c.normalize(key)
+			if normalized == "" {
+				continue
+			}
+			if old, ok := c.trieOrigin[normalized]; ok && c.SensitiveKeys[old].Level > wordInfo.Level {
+				continue
+			}
+			c.trieOrigin[normalized] = key
+			c.t.Insert(normalized, wordInfo.Level)
 		}
 	}
-	return nil
+	return err
 }
 
 type CheckResult struct {
 	HighestLevel   Level
 	SensitiveWords map[string]Level
+	// Hits 命中详情，键同 SensitiveWords，Reason 标明命中方式
+	Hits map[string]WordInfo
 }
 
 func (c *Censor) Check(content string) CheckResult {
 	if c.filterRegex != nil {
 		content = c.filterRegex.ReplaceAllString(content, "")
 	}
-	sensitiveKeys := c.t.Match(content)
-	sensitiveWords := make(map[string]Level)
-	highestLevel := Ignore
-	for key, level := range sensitiveKeys {
-		highestLevel = HigherLevel(highestLevel, level)
+	text := c.normalize(content)
+	result := CheckResult{
+		HighestLevel:   Ignore,
+		SensitiveWords: map[string]Level{},
+		Hits:           map[string]WordInfo{},
+	}
+	hit := func(key string, reason Reason) {
 		wordInfo := c.SensitiveKeys[key]
-		sensitiveWords[wordInfo.Origin] = wordInfo.Level
+		origin := wordInfo.Origin
+		if origin == "" {
+			origin = key
+		}
+		result.HighestLevel = HigherLevel(result.HighestLevel, wordInfo.Level)
+		result.SensitiveWords[origin] = wordInfo.Level
+		wordInfo.Reason = reason
+		result.Hits[origin] = wordInfo
+	}
+
+	for normalized := range c.t.Match(text) {
+		key, ok := c.trieOrigin[normalized]
+		if !ok {
+			continue
+		}
+		reason := c.SensitiveKeys[key].Reason
+		if c.Normalize && !strings.Contains(strings.ToLower(content), key) {
+			reason = Normalized
+		}
+		hit(key, reason)
+	}
+	for _, rule := range c.regexRules {
+		if rule.re.MatchString(content) || rule.re.MatchString(text) {
+			hit(rule.key, Regex)
+		}
 	}
-	return CheckResult{
-		HighestLevel:   highestLevel,
-		SensitiveWords: sensitiveWords,
+	if len(c.fuzzyRules) > 0 {
+		fuzzyText := []rune(Normalize(content, c.CaseSensitive))
+		for _, rule := range c.fuzzyRules {
+			if withinEditDistance(rule.word, fuzzyText, rule.distance) {
+				hit(rule.key, Fuzzy)
+			}
+		}
 	}
+	return result
 }
 
 func generateFileKey() string {
 	key, _ := nanoid.Generate("0123456789abcdef", 16)
 	return key
 }
+
+// Filter 仅保留满足条件的命中，并重新计算最高级别
+func (r CheckResult) Filter(keep func(word string, info WordInfo) bool) CheckResult {
+	result := CheckResult{
+		HighestLevel:   Ignore,
+		SensitiveWords: map[string]Level{},
+		Hits:           map[string]WordInfo{},
+	}
+	for word, info := range r.Hits {
+		if !keep(word, info) {
+			continue
+		}
+		result.HighestLevel = HigherLevel(result.HighestLevel, info.Level)
+		result.SensitiveWords[word] = info.Level
+		result.Hits[word] = info
+	}
+	return result
+}
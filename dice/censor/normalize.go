@@ -0,0 +1,101 @@
+package censor
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// homoglyphs 形近字符折叠表，仅收录常被用来规避审查的字符
+var homoglyphs = map[rune]rune{
+	// 西里尔字母
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's',
+	'А': 'a', 'В': 'b', 'Е': 'e', 'К': 'k', 'М': 'm', 'Н': 'h', 'О': 'o', 'Р': 'p',
+	'С': 'c', 'Т': 't', 'Х': 'x', 'І': 'i', 'Ј': 'j', 'Ѕ': 's',
+	// 希腊字母
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x', 'Α': 'a', 'Β': 'b', 'Ε': 'e', 'Ζ': 'z', 'Η': 'h',
+	'Ι': 'i', 'Κ': 'k', 'Μ': 'm', 'Ν': 'n', 'Ο': 'o', 'Ρ': 'p', 'Τ': 't', 'Υ': 'y',
+	'Χ': 'x',
+	// 其他拉丁变体
+	'ı': 'i', 'ł': 'l', 'ø': 'o', 'đ': 'd', 'ß': 's',
+}
+
+// Normalize 规范化文本：NFKC、全角转半角、形近字折叠、去除分隔字符、合并连续重复字符。
+// caseSensitive 为 false 时同时转为小写。敏感词与待检查文本需经过同样的处理再匹配。
+func Normalize(text string, caseSensitive bool) string {
+	text = norm.NFKC.String(text)
+	text = width.Fold.String(text)
+
+	var sb strings.Builder
+	sb.Grow(len(text))
+	var last rune = -1
+	for _, r := range text {
+		if h, ok := homoglyphs[r]; ok {
+			r = h
+		}
+		if !caseSensitive {
+			r = unicode.ToLower(r)
+		}
+		if isSeparator(r) {
+			continue
+		}
+		if r == last {
+			continue
+		}
+		last = r
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// isSeparator 空白、标点、符号、零宽字符与组合附加符号，插在词中间常用于规避审查
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) ||
+		unicode.IsPunct(r) ||
+		unicode.IsSymbol(r) ||
+		unicode.In(r, unicode.Cf, unicode.Mn, unicode.Me)
+}
+
+// withinEditDistance 判断 text 中是否存在与 word 编辑距离不超过 k 的子串
+func withinEditDistance(word []rune, text []rune, k int) bool {
+	m := len(word)
+	if m == 0 {
+		return false
+	}
+	// prev[i] 为 word[:i] 与以当前位置结尾的某个子串的最小编辑距离
+	prev := make([]int, m+1)
+	cur := make([]int, m+1)
+	for i := range prev {
+		prev[i] = i
+	}
+	if prev[m] <= k {
+		return true
+	}
+	for _, c := range text {
+		cur[0] = 0
+		for i := 1; i <= m; i++ {
+			cost := 1
+			if word[i-1] == c {
+				cost = 0
+			}
+			cur[i] = min(prev[i-1]+cost, prev[i]+1, cur[i-1]+1)
+		}
+		if cur[m] <= k {
+			return true
+		}
+		prev, cur = cur, prev
+	}
+	return false
+}
+
+// fuzzyDistance 限制编辑距离不超过词长的一半，避免短词误伤
+func fuzzyDistance(word []rune, distance int) int {
+	if distance <= 0 {
+		distance = 1
+	}
+	return min(distance, (len(word)-1)/2)
+}
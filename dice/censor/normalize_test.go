@@ -0,0 +1,123 @@
+//nolint:testpackage
+package censor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"ＢＡＤ　ｗｏｒｄ", "badword"},
+		{"b.a.d-w o r d", "badword"},
+		{"bааd", "bad"}, // 西里尔 а
+		{"baaaaad", "bad"},
+		{"坏​话", "坏话"},
+		{"坏 坏 话", "坏话"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in, false); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWithinEditDistance(t *testing.T) {
+	tests := []struct {
+		word, text string
+		k          int
+		want       bool
+	}{
+		{"badword", "this is badword", 0, true},
+		{"badword", "this is badwrd", 1, true},
+		{"badword", "this is bsdw0rd", 1, false},
+		{"badword", "this is bsdw0rd", 2, true},
+		{"badword", "clean", 1, false},
+	}
+	for _, tt := range tests {
+		if got := withinEditDistance([]rune(tt.word), []rune(tt.text), tt.k); got != tt.want {
+			t.Errorf("withinEditDistance(%q, %q, %d) = %v, want %v", tt.word, tt.text, tt.k, got, tt.want)
+		}
+	}
+	if fuzzyDistance([]rune("坏话"), 1) != 0 {
+		t.Error("two-rune words should only match exactly")
+	}
+}
+
+func TestCensor_Check_Normalized(t *testing.T) {
+	c := newTestCensor(map[string]Level{"badword": Danger})
+	c.Normalize = true
+	_ = c.Load()
+
+	result := c.Check("ｂａｄ ｗｏｒｄ")
+	if result.HighestLevel != Danger {
+		t.Fatalf("expected Danger, got %v", result.HighestLevel)
+	}
+	if result.Hits["badword"].Reason != Normalized {
+		t.Errorf("expected Normalized reason, got %v", result.Hits["badword"].Reason)
+	}
+
+	result = c.Check("badword")
+	if result.Hits["badword"].Reason != Origin {
+		t.Errorf("exact hit should keep its own reason, got %v", result.Hits["badword"].Reason)
+	}
+}
+
+func TestCensor_PreloadTomlRegexAndFuzzy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.toml")
+	content := `
+[words]
+notice = ["spam"]
+
+[regex]
+warning = ["b[a@]d\\s*guy"]
+
+[fuzzy]
+distance = 1
+danger = ["forbidden"]
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Censor{SensitiveKeys: make(map[string]WordInfo), Normalize: true}
+	file, err := c.PreloadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.FileCounter[Notice] != 1 || file.FileCounter[Warning] != 1 || file.FileCounter[Danger] != 1 {
+		t.Fatalf("unexpected counter %v", *file.FileCounter)
+	}
+	if err = c.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	result := c.Check("you B@D  GUY")
+	if result.Hits["b[a@]d\\s*guy"].Reason != Regex || result.HighestLevel != Warning {
+		t.Errorf("regex should hit, got %+v", result.Hits)
+	}
+	result = c.Check("this is forbiden")
+	if result.Hits["forbidden"].Reason != Fuzzy || result.HighestLevel != Danger {
+		t.Errorf("fuzzy should hit, got %+v", result.Hits)
+	}
+	if result = c.Check("a perfectly clean message"); result.HighestLevel != Ignore {
+		t.Errorf("clean text should not hit, got %+v", result.Hits)
+	}
+}
+
+func TestCensor_PreloadTomlInvalidRegex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.toml")
+	if err := os.WriteFile(path, []byte("[words]\nnotice = [\"spam\"]\n[regex]\ndanger = [\"(\"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := &Censor{SensitiveKeys: make(map[string]WordInfo)}
+	if _, err := c.PreloadFile(path); err == nil {
+		t.Fatal("invalid regex should be rejected")
+	}
+	if len(c.SensitiveKeys) != 0 {
+		t.Fatal("no words should be added from a rejected file")
+	}
+}
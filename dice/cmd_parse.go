@@ -595,6 +595,9 @@ func findMatchingCommand(restText string, d *Dice, group *GroupInfo) (string, bo
 				cmdLst = append(cmdLst, k)
 			}
 		}
+		for k := range group.checkCmdMap(d) {
+			cmdLst = append(cmdLst, k)
+		}
 	}
 
 	// 按长度排序，优先匹配长命令
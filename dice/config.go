@@ -702,6 +702,35 @@ func setupBaseTextTemplate(d *Dice) {
    $tWinFlag == 0 ? '平手！(请自行根据场景，如属性比较、攻击对反击，攻击对闪避)做出判断'
 %}`, 1},
 			},
+			// -------------------- chase combat --------------------------
+			"追逐_开始": {
+				{"追逐开始，共{$t数量}个地点。使用.chase join加入，.chase round开始新的一轮", 1},
+			},
+			"追逐_结束": {
+				{"追逐结束", 1},
+			},
+			"追逐_未开始": {
+				{"当前没有进行中的追逐，请先使用.chase new", 1},
+			},
+			"追逐_单位不存在": {
+				{"没有找到单位: {$t目标}", 1},
+			},
+			"追逐_速度检定": {
+				{"{$t单位}的速度检定(体质): D100={$t骰子出目}/{$t判定值} {$t判定结果}\n本场追逐MOV: {$t旧移动力}->{$t新移动力}", 1},
+			},
+			"追逐_危险检定": {
+				{"{$t单位}在{$t地点}号地点遭遇危险{$t危险描述}，{$t技能}检定: D100={$t骰子出目}/{$t判定值} {$t判定结果}{$t附加语}", 1},
+			},
+			"追逐_移动": {
+				{"{$t单位}到达{$t地点}号地点，剩余{$t剩余行动}次移动行动", 1},
+			},
+			"战斗轮_为空": {
+				{"战斗轮中还没有单位，使用.combat join加入", 1},
+			},
+			"战斗轮_轮到": {
+				{"战斗 第{$t轮数}轮，轮到{$t单位}行动(DEX{$t敏捷})", 1},
+			},
+			// -------------------- chase combat end --------------------------
 			// -------------------- ti li --------------------------
 			"疯狂发作_即时症状": {
 				{"{$t玩家}的疯狂发作-即时症状:\n{$t表达式文本}\n{$t疯狂描述}", 1},
@@ -758,6 +787,27 @@ func setupBaseTextTemplate(d *Dice) {
 			"先攻_新轮开始提示": {
 				{"新的一轮开始了！\n", 1},
 			},
+			"战斗_单位更新": {
+				{`{$t目标}{$t单位状态}`, 1},
+			},
+			"战斗_单位不存在": {
+				{`先攻列表中没有单位: {$t目标}`, 1},
+			},
+			"战斗_专注_检定提示": {
+				{`{$t专注单位}正在专注于{$t专注法术}，需进行体质豁免 DC{$t专注DC}`, 1},
+			},
+			"战斗_专注_中断": {
+				{`{$t专注单位}倒下了，对{$t专注法术}的专注中断！`, 1},
+			},
+			"战斗_遭遇_保存": {
+				{`已保存遭遇“{$t遭遇名}”，共{$t数量}个单位`, 1},
+			},
+			"战斗_遭遇_读取": {
+				{`已读取遭遇“{$t遭遇名}”，共{$t数量}个单位`, 1},
+			},
+			"战斗_遭遇_不存在": {
+				{`没有找到遭遇“{$t遭遇名}”`, 1},
+			},
 			"死亡豁免_D20_附加语": {
 				{`你觉得你还可以抢救一下！HP回复1点！`, 1},
 			},
@@ -947,6 +997,12 @@ func setupBaseTextTemplate(d *Dice) {
 			"角色管理_删除成功_当前卡": {
 				{"由于你删除的角色是当前角色，昵称和属性将被一同清空", 1},
 			},
+			"角色管理_导入成功": {
+				{"已从{$t导入格式}导入角色\"{$t角色名}\"并绑定，共{$t导入数量}项属性{$t未识别列表 ? '\n未能对应到规则模板的项目已忽略: ' + $t未识别列表}", 1},
+			},
+			"角色管理_导入失败": {
+				{"角色卡导入失败: {$t原因}", 1},
+			},
 			// -------------------- pc end --------------------------
 			"提示_私聊不可用": {
 				{"该指令只在群组中可用", 1},
@@ -1073,6 +1129,24 @@ func setupBaseTextTemplate(d *Dice) {
 			"抽牌_结果前缀": {
 				{``, 1},
 			},
+			"抽牌_实体牌组_洗牌": {
+				{"已将{$t牌组}洗牌，共{$t总张数}张，抽出的牌在重新洗牌前不会放回", 1},
+			},
+			"抽牌_实体牌组_剩余": {
+				{"{$t牌组}还剩{$t剩余张数}/{$t总张数}张", 1},
+			},
+			"抽牌_实体牌组_已抽完": {
+				{"{$t牌组}已经抽完了，使用.draw reshuffle {$t牌组}重新洗牌", 1},
+			},
+			"抽牌_实体牌组_未开启": {
+				{"{$t牌组}在本群不是实体牌组，使用.draw reshuffle {$t牌组}开始", 1},
+			},
+			"抽牌_实体牌组_关闭": {
+				{"{$t牌组}已恢复为放回抽取", 1},
+			},
+			"模板检定": {
+				{`{$t玩家}的"{$t技能}"检定结果为: {$t检定过程文本} = {$t检定结果} {$t判定结果}`, 1},
+			},
 			"随机名字": {
 				{"为{$t玩家}生成以下名字：\n{$t随机名字文本}", 1},
 			},
@@ -1407,6 +1481,41 @@ func setupBaseTextTemplate(d *Dice) {
 			"对抗检定": {
 				SubType: ".rav/.rcv",
 			},
+			// -------------------- chase combat --------------------------
+			"追逐_开始": {
+				SubType: ".chase new",
+				Vars:    []string{"$t数量"},
+			},
+			"追逐_结束": {
+				SubType: ".chase end",
+			},
+			"追逐_未开始": {
+				SubType: ".chase",
+			},
+			"追逐_单位不存在": {
+				SubType: ".chase/.combat",
+				Vars:    []string{"$t目标"},
+			},
+			"追逐_速度检定": {
+				SubType: ".chase speed",
+				Vars:    []string{"$t单位", "$t骰子出目", "$t判定值", "$t判定结果", "$t旧移动力", "$t新移动力"},
+			},
+			"追逐_危险检定": {
+				SubType: ".chase move",
+				Vars:    []string{"$t单位", "$t地点", "$t危险描述", "$t技能", "$t骰子出目", "$t判定值", "$t判定结果", "$t附加语"},
+			},
+			"追逐_移动": {
+				SubType: ".chase move",
+				Vars:    []string{"$t单位", "$t地点", "$t剩余行动"},
+			},
+			"战斗轮_为空": {
+				SubType: ".combat",
+			},
+			"战斗轮_轮到": {
+				SubType: ".combat next",
+				Vars:    []string{"$t轮数", "$t单位", "$t敏捷"},
+			},
+			// -------------------- chase combat end --------------------------
 			// -------------------- ti li --------------------------
 			"疯狂发作_即时症状": {
 				Vars:    []string{"$t玩家", "$t表达式文本", "$t疯狂描述", "$t选项值", "$t附加值1", "$t附加值2"},
@@ -1461,6 +1570,34 @@ func setupBaseTextTemplate(d *Dice) {
 			"先攻_新轮开始提示": {
 				SubType: ".init ed",
 			},
+			"战斗_单位更新": {
+				SubType: ".init hp/ac/cond",
+				Vars:    []string{"$t目标", "$t单位状态"},
+			},
+			"战斗_单位不存在": {
+				SubType: ".init hp/ac/cond",
+				Vars:    []string{"$t目标"},
+			},
+			"战斗_专注_检定提示": {
+				SubType: ".st hp-",
+				Vars:    []string{"$t专注单位", "$t专注法术", "$t专注DC"},
+			},
+			"战斗_专注_中断": {
+				SubType: ".st hp-",
+				Vars:    []string{"$t专注单位", "$t专注法术"},
+			},
+			"战斗_遭遇_保存": {
+				SubType: ".init save",
+				Vars:    []string{"$t遭遇名", "$t数量"},
+			},
+			"战斗_遭遇_读取": {
+				SubType: ".init load",
+				Vars:    []string{"$t遭遇名", "$t数量"},
+			},
+			"战斗_遭遇_不存在": {
+				SubType: ".init load",
+				Vars:    []string{"$t遭遇名"},
+			},
 			"先攻_移除_前缀": {
 				SubType: ".init rm",
 			},
@@ -1686,6 +1823,14 @@ func setupBaseTextTemplate(d *Dice) {
 			"角色管理_删除成功_当前卡": {
 				SubType: ".pc rm",
 			},
+			"角色管理_导入成功": {
+				SubType: ".pc import",
+				Vars:    []string{"$t角色名", "$t导入格式", "$t导入数量", "$t未识别数量", "$t未识别列表"},
+			},
+			"角色管理_导入失败": {
+				SubType: ".pc import",
+				Vars:    []string{"$t原因"},
+			},
 			// -------------------- pc end --------------------------
 			"提示_私聊不可用": {
 				SubType: "通用",
@@ -1814,6 +1959,31 @@ func setupBaseTextTemplate(d *Dice) {
 				SubType:   ".draw",
 				ExtraText: "多个抽取结果之间的分隔符",
 			},
+			"抽牌_实体牌组_洗牌": {
+				SubType: ".draw reshuffle",
+				Vars:    []string{"$t牌组", "$t总张数"},
+			},
+			"抽牌_实体牌组_剩余": {
+				SubType: ".draw remain",
+				Vars:    []string{"$t牌组", "$t剩余张数", "$t总张数", "$t剩余列表"},
+			},
+			"抽牌_实体牌组_已抽完": {
+				SubType: ".draw",
+				Vars:    []string{"$t牌组"},
+			},
+			"抽牌_实体牌组_未开启": {
+				SubType: ".draw remain",
+				Vars:    []string{"$t牌组"},
+			},
+			"抽牌_实体牌组_关闭": {
+				SubType: ".draw close",
+				Vars:    []string{"$t牌组"},
+			},
+			"模板检定": {
+				SubType:   "模板声明的检定指令",
+				ExtraText: "游戏系统模板的 commands.check 未指定 text 时使用",
+				Vars:      []string{"$t玩家", "$t技能", "$t属性表达式文本", "$t判定值", "$t出目", "$t检定过程文本", "$t检定结果", "$t成功等级", "$t判定结果", "$tSuccessRank"},
+			},
 			"随机名字": {
 				SubType: ".name/.namednd",
 			},
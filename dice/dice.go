@@ -133,6 +133,13 @@ type JsLoopManager struct {
 	loop     *eventloop.EventLoop
 	loopLock sync.RWMutex
 	version  int64
+	// 版本号计数器，主 loop 和隔离运行时共用，保证旧版本号不会被复用
+	counter int64
+	// 隔离模式下为单个脚本或扩展包创建的运行时，按版本号索引，随主 loop 一同更替
+	runtimes map[int64]*jsRuntime
+
+	statsLock sync.Mutex
+	stats     map[string]*JsHookStats
 }
 
 func NewJsLoopManager() *JsLoopManager {
@@ -140,6 +147,8 @@ func NewJsLoopManager() *JsLoopManager {
 		loop:     nil,
 		loopLock: sync.RWMutex{},
 		version:  0,
+		runtimes: map[int64]*jsRuntime{},
+		stats:    map[string]*JsHookStats{},
 	}
 }
 
@@ -148,6 +157,12 @@ func (m *JsLoopManager) GetLoop(expectedVersion int64) (*eventloop.EventLoop, er
 	m.loopLock.RLock()
 	defer m.loopLock.RUnlock()
 
+	if rt, ok := m.runtimes[expectedVersion]; ok {
+		if rt.crashed != "" {
+			return nil, fmt.Errorf("runtime %s stopped: %s", rt.name, rt.crashed)
+		}
+		return rt.loop, nil
+	}
 	if m.version != expectedVersion {
 		return nil, fmt.Errorf("version mismatch: expected %d, current %d", expectedVersion, m.version)
 	}
@@ -171,10 +186,16 @@ func (m *JsLoopManager) SetLoop(newLoop *eventloop.EventLoop) int64 {
 	if m.loop != nil {
 		m.loop.Terminate()
 	}
+	for _, rt := range m.runtimes {
+		rt.loop.Terminate()
+	}
+	m.runtimes = map[int64]*jsRuntime{}
+	m.resetStats()
 
 	// 设置新的 loop 并递增版本号
 	m.loop = newLoop
-	m.version++
+	m.counter++
+	m.version = m.counter
 	return m.version
 }
 
@@ -237,6 +258,8 @@ type Dice struct {
 	JsScriptCronLock *sync.Mutex     `json:"-" yaml:"-"`
 	// 重载使用的互斥锁
 	JsReloadLock sync.Mutex `json:"-" yaml:"-"`
+	// 保护 JsScriptList 中脚本的状态和 ExtUpdateTime，隔离运行时被终止时会在调用扩展的协程中修改它们
+	jsScriptLock sync.Mutex
 	// 内置脚本摘要表，用于判断内置脚本是否有更新
 	JsBuiltinDigestSet map[string]bool `json:"-" yaml:"-"`
 	// 当前在加载的脚本路径，用于关联 jsScriptInfo 和 ExtInfo
@@ -255,6 +278,10 @@ type Dice struct {
 
 	AttrsManager *AttrsManager `json:"-" yaml:"-"`
 
+	Schedules *ScheduleManager `json:"-" yaml:"-"` // 群日程提醒
+
+	MessageIndex *MessageIndexManager `json:"-" yaml:"-"` // 跑团日志全文索引
+
 	Config Config `json:"-" yaml:"-"`
 
 	AdvancedConfig AdvancedConfig `json:"-" yaml:"-"`
@@ -353,6 +380,12 @@ func (d *Dice) Init(operator engine.DatabaseOperator, uiWriter *logger.UIWriter)
 	d.loadAdvanced()
 	(&d.Config).BanList.Loads()
 	(&d.Config).BanList.AfterLoads()
+	d.Schedules = NewScheduleManager(d)
+	if err = d.Schedules.Load(); err != nil {
+		loggerInstance.Errorf("读取日程提醒失败: %v", err)
+	}
+	d.MessageIndex = NewMessageIndexManager(d)
+	_, _ = d.Cron.AddFunc("@every 10m", d.MessageIndex.Sync)
 	d.IsAlreadyLoadConfig = true
 
 	if d.Config.EnableCensor {
@@ -23,6 +23,10 @@ type AttrsManager struct {
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 	m      SyncMap[string, *AttributesItem]
+
+	links     SyncMap[string, string] // 平台用户ID -> 统一ID，见 dice_identity_link.go
+	linkMu    sync.Mutex
+	linkCodes map[string]*identityLinkCode
 }
 
 func (am *AttrsManager) Stop() {
@@ -33,6 +37,9 @@ func (am *AttrsManager) Stop() {
 
 // LoadByCtx 获取当前角色，如有绑定，则获取绑定的角色，若无绑定，获取群内默认卡
 func (am *AttrsManager) LoadByCtx(ctx *MsgContext) (*AttributesItem, error) {
+	if ctx.AttrsOverride != nil {
+		return ctx.AttrsOverride, nil
+	}
 	// 如果是兼容性测试环境，跳过绑定查询以避免不必要的数据库操作
 	if ctx.IsCompatibilityTest {
 		return am.LoadByIdDirect(ctx.Group.GroupID, ctx.Player.UserID)
@@ -71,6 +78,9 @@ func (am *AttrsManager) LoadByIdDirect(groupId string, userId string) (*Attribut
 
 func (am *AttrsManager) UIDConvert(userId string) string {
 	// 如果存在一个虚拟id，那么返回虚拟id，不存在原样返回
+	if id := am.LinkedIdentity(userId); id != "" {
+		return id
+	}
 	return userId
 }
 
@@ -174,6 +184,9 @@ func (am *AttrsManager) LoadById(id string) (*AttributesItem, error) {
 func (am *AttrsManager) Init(d *Dice) {
 	am.db = d.DBOperator
 	am.logger = d.Logger
+	if err := am.initIdentityLinks(); err != nil {
+		d.Logger.Errorf("读取账号关联数据失败: %v", err)
+	}
 	// 创建一个 context 用于取消 goroutine
 	ctx, cancel := context.WithCancel(context.Background())
 	// 启动后台定时任务
@@ -232,10 +245,14 @@ func (am *AttrsManager) CheckForSave() error {
 		return nil
 	}
 
-	if err := service.AttrsPutsByIDBatch(am.db, resultList); err != nil {
+	start := time.Now()
+	err := service.AttrsPutsByIDBatch(am.db, resultList)
+	metricAttrsSaveDuration.With(metricResult(err)).ObserveSince(start)
+	if err != nil {
 		log.Errorf("定期写入用户数据出错(批量保存): %v", err)
 		return err
 	}
+	metricAttrsSaveRows.With().Add(float64(len(resultList)))
 	for key := range prepareToSave {
 		// 理应不存在这个数据没有的情况
 		v, _ := am.m.Load(key)
@@ -309,6 +326,7 @@ func (am *AttrsManager) CharGetBindingId(groupId string, userId string) (string,
 }
 
 func (am *AttrsManager) CharIdGetByName(userId string, name string) (string, error) {
+	userId = am.UIDConvert(userId)
 	return service.AttrsGetIdByUidAndName(am.db, userId, name)
 }
 
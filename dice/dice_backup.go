@@ -81,8 +81,15 @@ const (
 		BackupSelectionResources
 )
 
-func (dm *DiceManager) Backup(sel BackupSelection, fromAuto bool) (string, error) {
-	_ = os.MkdirAll(BackupDir, 0o755)
+// backupFile 一个待备份的文件，d 用于输出日志，全局文件为 nil
+type backupFile struct {
+	d    *Dice
+	path string
+}
+
+// backupFileList 按 sel 列出需要备份的文件，SQLite 数据库会先写回 WAL。
+// zip 备份和增量备份共用这份列表
+func (dm *DiceManager) backupFileList(sel BackupSelection) ([]backupFile, backupConfigGlobal) {
 	logger := dm.Dice[0].Logger
 
 	cfgGlb := backupConfigGlobal{
@@ -97,26 +104,7 @@ func (dm *DiceManager) Backup(sel BackupSelection, fromAuto bool) (string, error
 		CustomText:  true,
 	}
 
-	bakFn := "bak_" + time.Now().Format("060102_150405")
-	if fromAuto {
-		bakFn += "_auto"
-	}
-	bakFn += "_r" + strconv.FormatUint(uint64(sel), 16)
-	fnHashed := crypto.CalculateSHA512Str([]byte(bakFn))[:8]
-	bakFn += "_" + fnHashed + ".zip"
-
-	fzip, err := os.OpenFile(filepath.Join(BackupDir, bakFn),
-		os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
-	if err != nil {
-		return "", err
-	}
-	defer func() { _ = fzip.Close() }()
-
-	writer := zip.NewWriter(fzip)
-	defer func(writer *zip.Writer) {
-		_ = writer.Close()
-	}(writer)
-
+	var files []backupFile
 	fileOK := func(fn string) bool {
 		stat, err := os.Stat(fn)
 		return err == nil && !stat.IsDir()
@@ -127,36 +115,7 @@ func (dm *DiceManager) Backup(sel BackupSelection, fromAuto bool) (string, error
 	}
 
 	backup := func(d *Dice, fn string) {
-		file, err := os.Open(fn)
-		if err != nil && !strings.Contains(fn, "session.token") {
-			if d != nil {
-				d.Logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
-			} else {
-				logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
-			}
-			return
-		}
-		defer file.Close()
-
-		h := &zip.FileHeader{Name: fn, Method: zip.Deflate, Flags: 0x800}
-		fileWriter, err := writer.CreateHeader(h)
-		if err != nil {
-			if d != nil {
-				d.Logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
-			} else {
-				logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
-			}
-			return
-		}
-
-		_, err = io.Copy(fileWriter, file)
-		if err != nil {
-			if d != nil {
-				d.Logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
-			} else {
-				logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
-			}
-		}
+		files = append(files, backupFile{d: d, path: fn})
 	}
 
 	backupDir := func(path string, info fs.FileInfo, _ error) error {
@@ -325,6 +284,78 @@ func (dm *DiceManager) Backup(sel BackupSelection, fromAuto bool) (string, error
 		}
 	}
 
+	return files, cfgGlb
+}
+
+func (dm *DiceManager) Backup(sel BackupSelection, fromAuto bool) (string, error) {
+	start := time.Now()
+	fn, err := dm.backup(sel, fromAuto)
+	metricBackupDuration.With("zip", metricResult(err)).ObserveSince(start)
+	return fn, err
+}
+
+func (dm *DiceManager) backup(sel BackupSelection, fromAuto bool) (string, error) {
+	_ = os.MkdirAll(BackupDir, 0o755)
+	logger := dm.Dice[0].Logger
+
+	bakFn := "bak_" + time.Now().Format("060102_150405")
+	if fromAuto {
+		bakFn += "_auto"
+	}
+	bakFn += "_r" + strconv.FormatUint(uint64(sel), 16)
+	fnHashed := crypto.CalculateSHA512Str([]byte(bakFn))[:8]
+	bakFn += "_" + fnHashed + ".zip"
+
+	fzip, err := os.OpenFile(filepath.Join(BackupDir, bakFn),
+		os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = fzip.Close() }()
+
+	writer := zip.NewWriter(fzip)
+	defer func(writer *zip.Writer) {
+		_ = writer.Close()
+	}(writer)
+
+	backup := func(d *Dice, fn string) {
+		file, err := os.Open(fn)
+		if err != nil && !strings.Contains(fn, "session.token") {
+			if d != nil {
+				d.Logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
+			} else {
+				logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
+			}
+			return
+		}
+		defer file.Close()
+
+		h := &zip.FileHeader{Name: fn, Method: zip.Deflate, Flags: 0x800}
+		fileWriter, err := writer.CreateHeader(h)
+		if err != nil {
+			if d != nil {
+				d.Logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
+			} else {
+				logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
+			}
+			return
+		}
+
+		_, err = io.Copy(fileWriter, file)
+		if err != nil {
+			if d != nil {
+				d.Logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
+			} else {
+				logger.Errorf("备份文件失败: %s, 原因: %s", fn, err.Error())
+			}
+		}
+	}
+
+	files, cfgGlb := dm.backupFileList(sel)
+	for _, f := range files {
+		backup(f.d, f.path)
+	}
+
 	// 写入文件信息
 	data, _ := json.Marshal(map[string]interface{}{
 		"config":      cfgGlb,
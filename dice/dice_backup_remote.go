@@ -0,0 +1,258 @@
+package dice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alexmullins/zip"
+
+	"sealdice-core/dice/backuprepo"
+	"sealdice-core/logger"
+	"sealdice-core/utils/crypto"
+)
+
+var ErrRemoteBackupRunning = errors.New("增量备份正在进行中")
+
+// RemoteBackupConfig 增量备份配置，存储位置可以是本地目录、WebDAV 或 S3 兼容的对象存储
+type RemoteBackupConfig struct {
+	Enable     bool                    `json:"enable"     yaml:"enable"` // 随自动备份一起执行
+	Passphrase string                  `json:"passphrase" yaml:"passphrase"`
+	KeepCount  int                     `json:"keepCount"  yaml:"keepCount"` // 保留的快照数，0 为不清理
+	Target     backuprepo.TargetConfig `json:"target"     yaml:"target"`
+}
+
+// RemoteBackupStatus 最近一次增量备份的情况
+type RemoteBackupStatus struct {
+	Running    bool   `json:"running"`
+	LastTime   int64  `json:"lastTime"`
+	LastError  string `json:"lastError"`
+	SnapshotID string `json:"snapshotId"`
+	Size       int64  `json:"size"`
+	Added      int64  `json:"added"`
+}
+
+type remoteBackupState struct {
+	lock   sync.Mutex // 同一时间只进行一个备份或清理
+	mu     sync.Mutex
+	status RemoteBackupStatus
+}
+
+func (dm *DiceManager) openBackupRepo(ctx context.Context) (*backuprepo.Repository, error) {
+	target, err := backuprepo.NewTarget(dm.RemoteBackup.Target)
+	if err != nil {
+		return nil, err
+	}
+	return backuprepo.Open(ctx, target, dm.RemoteBackup.Passphrase)
+}
+
+// RemoteBackupStatus 返回最近一次增量备份的情况
+func (dm *DiceManager) RemoteBackupStatus() RemoteBackupStatus {
+	dm.remoteBackup.mu.Lock()
+	defer dm.remoteBackup.mu.Unlock()
+	return dm.remoteBackup.status
+}
+
+func (dm *DiceManager) setRemoteBackupStatus(fn func(s *RemoteBackupStatus)) {
+	dm.remoteBackup.mu.Lock()
+	defer dm.remoteBackup.mu.Unlock()
+	fn(&dm.remoteBackup.status)
+}
+
+// backupNotice 通过系统通知告知骰主备份结果
+func (dm *DiceManager) backupNotice(text string) {
+	if len(dm.Dice) == 0 || dm.Dice[0].ImSession == nil {
+		return
+	}
+	dm.Dice[0].NoticeForEveryEndpoint(text, false, NoticeTypeSystem)
+}
+
+// BackupRemote 执行一次增量备份，只上传有变化的数据块，完成后按 KeepCount 清理旧快照
+func (dm *DiceManager) BackupRemote(ctx context.Context, sel BackupSelection) (*backuprepo.BackupResult, error) {
+	if !dm.remoteBackup.lock.TryLock() {
+		return nil, ErrRemoteBackupRunning
+	}
+	defer dm.remoteBackup.lock.Unlock()
+
+	log := logger.M()
+	dm.setRemoteBackupStatus(func(s *RemoteBackupStatus) { s.Running = true })
+	start := time.Now()
+	result, err := dm.backupRemote(ctx, sel)
+	metricBackupDuration.With("remote", metricResult(err)).ObserveSince(start)
+	dm.setRemoteBackupStatus(func(s *RemoteBackupStatus) {
+		s.Running = false
+		s.LastTime = time.Now().Unix()
+		s.LastError = ""
+		if err != nil {
+			s.LastError = err.Error()
+			return
+		}
+		s.SnapshotID = result.Snapshot.ID
+		s.Size = result.Snapshot.Size
+		s.Added = result.Snapshot.Added
+	})
+	if err != nil {
+		log.Errorf("增量备份失败: %v", err)
+		dm.backupNotice(fmt.Sprintf("增量备份失败: %v", err))
+		return nil, err
+	}
+
+	text := fmt.Sprintf("增量备份完成: 快照 %s，共 %s，新上传 %s",
+		result.Snapshot.ID, formatBackupSize(result.Snapshot.Size), formatBackupSize(result.Snapshot.Added))
+	if len(result.Skipped) > 0 {
+		text += fmt.Sprintf("，%d 个文件无法读取已跳过", len(result.Skipped))
+	}
+	log.Info(text)
+	dm.backupNotice(text)
+	return result, nil
+}
+
+func (dm *DiceManager) backupRemote(ctx context.Context, sel BackupSelection) (*backuprepo.BackupResult, error) {
+	repo, err := dm.openBackupRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files, cfgGlb := dm.backupFileList(sel)
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		// 列表中包含可能不存在的文件，如未登录帐号的 session.token
+		if _, statErr := os.Stat(f.path); statErr != nil {
+			continue
+		}
+		paths = append(paths, filepath.ToSlash(f.path))
+	}
+	config, _ := json.Marshal(cfgGlb)
+	result, err := repo.Backup(ctx, paths, map[string]string{
+		"version":     VERSION.String(),
+		"versionCode": strconv.FormatInt(VERSION_CODE, 10),
+		"selection":   strconv.FormatUint(uint64(sel), 16),
+		"config":      string(config),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dm.RemoteBackup.KeepCount > 0 {
+		if _, _, err = repo.Prune(ctx, dm.RemoteBackup.KeepCount); err != nil {
+			logger.M().Errorf("清理旧的增量备份失败: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// RemoteBackupSnapshot 快照列表中的一项，不含文件明细
+type RemoteBackupSnapshot struct {
+	ID        string `json:"id"`
+	Time      int64  `json:"time"`
+	Version   string `json:"version"`
+	Size      int64  `json:"size"`
+	Added     int64  `json:"added"`
+	FileCount int    `json:"fileCount"`
+}
+
+// RemoteBackupSnapshots 按时间倒序列出增量备份的快照
+func (dm *DiceManager) RemoteBackupSnapshots(ctx context.Context) ([]RemoteBackupSnapshot, error) {
+	repo, err := dm.openBackupRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := repo.Snapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]RemoteBackupSnapshot, 0, len(snapshots))
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		s := snapshots[i]
+		items = append(items, RemoteBackupSnapshot{
+			ID:        s.ID,
+			Time:      s.Time,
+			Version:   s.Meta["version"],
+			Size:      s.Size,
+			Added:     s.Added,
+			FileCount: len(s.Files),
+		})
+	}
+	return items, nil
+}
+
+// RemoteBackupExport 把快照还原为普通的 zip 备份放入备份目录，之后可按常规方式下载或恢复
+func (dm *DiceManager) RemoteBackupExport(ctx context.Context, id string) (string, error) {
+	repo, err := dm.openBackupRepo(ctx)
+	if err != nil {
+		return "", err
+	}
+	snap, err := repo.Snapshot(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	sel, _ := strconv.ParseUint(snap.Meta["selection"], 16, 64)
+
+	_ = os.MkdirAll(BackupDir, 0o755)
+	bakFn := "bak_" + time.Unix(snap.Time, 0).Format("060102_150405") + "_r" + strconv.FormatUint(sel, 16)
+	bakFn += "_" + crypto.CalculateSHA512Str([]byte(bakFn))[:8] + ".zip"
+	fn := filepath.Join(BackupDir, bakFn)
+	fzip, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	err = writeSnapshotZip(ctx, repo, snap, fzip)
+	if closeErr := fzip.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(fn)
+		return "", err
+	}
+	return fn, nil
+}
+
+func writeSnapshotZip(ctx context.Context, repo *backuprepo.Repository, snap *backuprepo.Snapshot, out *os.File) error {
+	writer := zip.NewWriter(out)
+	for i := range snap.Files {
+		f := &snap.Files[i]
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: f.Path, Method: zip.Deflate, Flags: 0x800})
+		if err != nil {
+			return err
+		}
+		if err = repo.WriteFile(ctx, f, w); err != nil {
+			return err
+		}
+	}
+
+	versionCode, _ := strconv.ParseInt(snap.Meta["versionCode"], 10, 64)
+	info := map[string]interface{}{
+		"version":     snap.Meta["version"],
+		"versionCode": versionCode,
+		"snapshot":    snap.ID,
+	}
+	if cfg := snap.Meta["config"]; cfg != "" {
+		info["config"] = json.RawMessage(cfg)
+	}
+	data, _ := json.Marshal(info)
+	w, err := writer.CreateHeader(&zip.FileHeader{Name: "backup_info.json", Method: zip.Deflate, Flags: 0x800})
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func formatBackupSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + "B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,423 @@
+package dice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexmullins/zip"
+
+	"sealdice-core/logger"
+	"sealdice-core/utils/crypto"
+)
+
+// BackupRestorePendingFile 通过UI发起的恢复先记录在这里，下次启动、数据库打开前再执行
+const BackupRestorePendingFile = BackupDir + "/restore-pending.json"
+
+var (
+	ErrBackupNoInfo      = errors.New("不是有效的海豹备份：缺少 backup_info.json")
+	ErrBackupBadEntry    = errors.New("备份中含有不安全的文件路径")
+	ErrBackupNothingToDo = errors.New("备份中没有选中的内容")
+)
+
+// BackupArchiveInfo 备份文件中包含的内容
+type BackupArchiveInfo struct {
+	Name        string          `json:"name"`
+	Version     string          `json:"version"`
+	VersionCode int64           `json:"versionCode"`
+	Global      bool            `json:"global"`   // data/dice.yaml
+	Sections    BackupSelection `json:"sections"` // 包含的可选部分
+	Dices       []string        `json:"dices"`    // 包含数据的骰子
+	FileCount   int             `json:"fileCount"`
+	Size        uint64          `json:"size"` // 解压后的大小
+}
+
+// BackupRestoreSelection 要恢复的部分
+type BackupRestoreSelection struct {
+	Global   bool            `json:"global"`
+	Sections BackupSelection `json:"sections"` // 与备份时的位掩码相同
+	AllDices bool            `json:"allDices"`
+	Dices    []string        `json:"dices"` // 恢复这些骰子的帐号、设置和数据库
+}
+
+// BackupRestoreSelectionAll 恢复备份中的全部内容
+var BackupRestoreSelectionAll = BackupRestoreSelection{Global: true, Sections: BackupSelectionAll, AllDices: true}
+
+// ParseBackupRestoreSelection 解析逗号分隔的恢复范围，如 "decks,helpdoc,dice:default"，空串为全部
+func ParseBackupRestoreSelection(text string) (BackupRestoreSelection, error) {
+	if strings.TrimSpace(text) == "" {
+		return BackupRestoreSelectionAll, nil
+	}
+	sections := map[string]BackupSelection{
+		"js":      BackupSelectionJS,
+		"decks":   BackupSelectionDecks,
+		"helpdoc": BackupSelectionHelpDoc,
+		"censor":  BackupSelectionCensor,
+		"names":   BackupSelectionNames,
+		"images":  BackupSelectionImages,
+	}
+	var sel BackupRestoreSelection
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+		case item == "global":
+			sel.Global = true
+		case item == "dice":
+			sel.AllDices = true
+		case strings.HasPrefix(item, "dice:") && len(item) > len("dice:"):
+			sel.Dices = append(sel.Dices, item[len("dice:"):])
+		case sections[item] != 0:
+			sel.Sections |= sections[item]
+		default:
+			return sel, fmt.Errorf("未知的恢复范围: %s", item)
+		}
+	}
+	return sel, nil
+}
+
+// backupEntryKind 备份中一个文件所属的部分
+type backupEntryKind struct {
+	global  bool
+	section BackupSelection
+	dice    string // 属于该骰子的数据；section 为 JS 时表示该骰子的脚本
+}
+
+// classifyBackupEntry 按路径判断文件属于哪一部分，路径不在 data 目录下时返回 false
+func classifyBackupEntry(name string) (backupEntryKind, bool) {
+	var kind backupEntryKind
+	parts := strings.Split(name, "/")
+	if len(parts) < 2 || parts[0] != "data" || slices.Contains(parts, "..") || slices.Contains(parts, "") {
+		return kind, false
+	}
+	if len(parts) == 2 {
+		kind.global = parts[1] == "dice.yaml"
+		return kind, kind.global
+	}
+	switch parts[1] {
+	case "decks":
+		kind.section = BackupSelectionDecks
+	case "helpdoc":
+		kind.section = BackupSelectionHelpDoc
+	case "censor":
+		kind.section = BackupSelectionCensor
+	case "names":
+		kind.section = BackupSelectionNames
+	case "images":
+		kind.section = BackupSelectionImages
+	default:
+		kind.dice = parts[1]
+		if parts[2] == "scripts" || (parts[2] == "extensions" && len(parts) > 3 && parts[3] != "reply") {
+			kind.section = BackupSelectionJS
+		}
+	}
+	return kind, true
+}
+
+func (sel *BackupRestoreSelection) match(kind backupEntryKind) bool {
+	switch {
+	case kind.global:
+		return sel.Global
+	case kind.section != 0:
+		return sel.Sections&kind.section != 0
+	default:
+		return sel.AllDices || slices.Contains(sel.Dices, kind.dice)
+	}
+}
+
+// backupEntryName 统一为 / 分隔，Windows 上生成的备份中路径分隔符为 \
+func backupEntryName(f *zip.File) string {
+	return path.Clean(strings.ReplaceAll(f.Name, "\\", "/"))
+}
+
+// InspectBackup 校验备份文件并列出其中包含的部分
+func InspectBackup(fn string) (*BackupArchiveInfo, error) {
+	r, err := zip.OpenReader(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	return inspectBackup(&r.Reader, filepath.Base(fn))
+}
+
+func inspectBackup(r *zip.Reader, name string) (*BackupArchiveInfo, error) {
+	info := &BackupArchiveInfo{Name: name, Dices: []string{}}
+	hasInfo := false
+	dices := map[string]bool{}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		entry := backupEntryName(f)
+		if entry == "backup_info.json" {
+			meta, err := readBackupInfo(f)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrBackupNoInfo, err)
+			}
+			info.Version, info.VersionCode = meta.Version, meta.VersionCode
+			hasInfo = true
+			continue
+		}
+		kind, ok := classifyBackupEntry(entry)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrBackupBadEntry, f.Name)
+		}
+		info.Global = info.Global || kind.global
+		info.Sections |= kind.section
+		if kind.section == 0 && kind.dice != "" && !dices[kind.dice] {
+			dices[kind.dice] = true
+			info.Dices = append(info.Dices, kind.dice)
+		}
+		info.FileCount++
+		info.Size += f.UncompressedSize64
+	}
+	if !hasInfo {
+		return nil, ErrBackupNoInfo
+	}
+	sort.Strings(info.Dices)
+	return info, nil
+}
+
+type backupInfoMeta struct {
+	Version     string `json:"version"`
+	VersionCode int64  `json:"versionCode"`
+}
+
+func readBackupInfo(f *zip.File) (*backupInfoMeta, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	var meta backupInfoMeta
+	if err = json.NewDecoder(rc).Decode(&meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// BackupRestoreResult 恢复结果
+type BackupRestoreResult struct {
+	Snapshot string   `json:"snapshot"` // 恢复前自动备份的文件
+	Files    []string `json:"files"`
+}
+
+// RestoreBackup 从备份中恢复选中的部分，已有文件会被覆盖，备份中没有的文件保持不变。
+// 必须在数据库打开之前调用：SQLite 数据库文件会被直接替换。
+// 覆盖前会把将被替换的文件打包为一个新的备份，可用它撤销本次恢复。
+func RestoreBackup(fn string, sel BackupRestoreSelection) (*BackupRestoreResult, error) {
+	r, err := zip.OpenReader(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	if _, err = inspectBackup(&r.Reader, filepath.Base(fn)); err != nil {
+		return nil, err
+	}
+
+	var files []*zip.File
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if kind, ok := classifyBackupEntry(backupEntryName(f)); ok && sel.match(kind) {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return nil, ErrBackupNothingToDo
+	}
+
+	result := &BackupRestoreResult{}
+	if result.Snapshot, err = snapshotBeforeRestore(files); err != nil {
+		return nil, fmt.Errorf("恢复前备份失败，已取消恢复: %w", err)
+	}
+	for _, f := range files {
+		name := backupEntryName(f)
+		if err = restoreBackupFile(f, filepath.FromSlash(name)); err != nil {
+			return result, fmt.Errorf("恢复 %s 失败: %w", name, err)
+		}
+		result.Files = append(result.Files, name)
+	}
+	return result, nil
+}
+
+// sqliteSidecars SQLite 数据库的 WAL 文件，替换数据库后旧的 WAL 必须一并移除
+func sqliteSidecars(fn string) []string {
+	if filepath.Ext(fn) != ".db" {
+		return nil
+	}
+	return []string{fn + "-wal", fn + "-shm"}
+}
+
+func restoreBackupFile(f *zip.File, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	tmp := dest + ".restoring"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, rc) //nolint:gosec // 备份大小由用户自行控制
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	for _, side := range sqliteSidecars(dest) {
+		if err = os.Remove(side); err != nil && !os.IsNotExist(err) {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, dest)
+}
+
+// snapshotBeforeRestore 打包即将被覆盖的现有文件，文件名与普通备份一致，可直接用于恢复
+func snapshotBeforeRestore(files []*zip.File) (string, error) {
+	if err := os.MkdirAll(BackupDir, 0o755); err != nil {
+		return "", err
+	}
+	// 文件名精确到秒，同一秒内多次恢复时顺延
+	var fzip *os.File
+	var err error
+	now := time.Now()
+	for i := range 60 {
+		bakFn := "bak_" + now.Add(time.Duration(i)*time.Second).Format("060102_150405") + "_prerestore_r0"
+		bakFn += "_" + crypto.CalculateSHA512Str([]byte(bakFn))[:8] + ".zip"
+		fzip, err = os.OpenFile(filepath.Join(BackupDir, bakFn), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+		if !os.IsExist(err) {
+			break
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = fzip.Close() }()
+	writer := zip.NewWriter(fzip)
+
+	add := func(name string) error {
+		src, openErr := os.Open(filepath.FromSlash(name))
+		if os.IsNotExist(openErr) {
+			return nil
+		} else if openErr != nil {
+			return openErr
+		}
+		defer func() { _ = src.Close() }()
+		w, createErr := writer.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Flags: 0x800})
+		if createErr != nil {
+			return createErr
+		}
+		_, copyErr := io.Copy(w, src)
+		return copyErr
+	}
+	for _, f := range files {
+		name := backupEntryName(f)
+		for _, n := range append([]string{name}, sqliteSidecars(name)...) {
+			if err = add(n); err != nil {
+				_ = writer.Close()
+				return "", err
+			}
+		}
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"version":     VERSION.String(),
+		"versionCode": VERSION_CODE,
+		"preRestore":  true,
+	})
+	w, err := writer.CreateHeader(&zip.FileHeader{Name: "backup_info.json", Method: zip.Deflate, Flags: 0x800})
+	if err != nil {
+		_ = writer.Close()
+		return "", err
+	}
+	if _, err = w.Write(data); err != nil {
+		_ = writer.Close()
+		return "", err
+	}
+	return fzip.Name(), writer.Close()
+}
+
+// BackupRestorePending 待执行的恢复
+type BackupRestorePending struct {
+	Archive   string                 `json:"archive"`
+	Selection BackupRestoreSelection `json:"selection"`
+}
+
+// ScheduleRestore 校验备份后记录待恢复的内容，在下次启动时由 ApplyPendingRestore 执行
+func (dm *DiceManager) ScheduleRestore(name string, sel BackupRestoreSelection) (*BackupArchiveInfo, error) {
+	fn := filepath.Join(BackupDir, name)
+	info, err := InspectBackup(fn)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(BackupRestorePending{Archive: fn, Selection: sel})
+	if err != nil {
+		return nil, err
+	}
+	return info, os.WriteFile(BackupRestorePendingFile, data, 0o644)
+}
+
+// CancelScheduledRestore 取消尚未执行的恢复
+func CancelScheduledRestore() error {
+	err := os.Remove(BackupRestorePendingFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// PendingRestore 返回尚未执行的恢复，没有时返回 nil
+func PendingRestore() *BackupRestorePending {
+	data, err := os.ReadFile(BackupRestorePendingFile)
+	if err != nil {
+		return nil
+	}
+	var p BackupRestorePending
+	if json.Unmarshal(data, &p) != nil {
+		return nil
+	}
+	return &p
+}
+
+// ApplyPendingRestore 执行UI中安排的恢复，需在打开数据库之前调用。
+// 记录文件会先被删除，恢复失败时不会在每次启动时反复重试。
+func ApplyPendingRestore() {
+	p := PendingRestore()
+	if p == nil {
+		return
+	}
+	log := logger.M()
+	if err := CancelScheduledRestore(); err != nil {
+		log.Errorf("无法移除待恢复记录，跳过本次恢复: %v", err)
+		return
+	}
+	log.Infof("开始从备份 %s 恢复数据", p.Archive)
+	result, err := RestoreBackup(p.Archive, p.Selection)
+	if err != nil {
+		if result != nil && result.Snapshot != "" {
+			log.Errorf("恢复失败: %v，恢复前的文件已备份至 %s", err, result.Snapshot)
+		} else {
+			log.Errorf("恢复失败: %v", err)
+		}
+		return
+	}
+	log.Infof("已恢复 %d 个文件，恢复前的文件已备份至 %s", len(result.Files), result.Snapshot)
+}
@@ -0,0 +1,125 @@
+package dice //nolint:testpackage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alexmullins/zip"
+)
+
+func writeTestBackup(t *testing.T, fn string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, createErr := w.Create(name)
+		if createErr != nil {
+			t.Fatal(createErr)
+		}
+		_, _ = fw.Write([]byte(content))
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, fn string) string {
+	t.Helper()
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRestoreBackupSelection(t *testing.T) {
+	t.Chdir(t.TempDir())
+	_ = os.MkdirAll("data/default", 0o755)
+	_ = os.MkdirAll("data/decks", 0o755)
+	_ = os.WriteFile("data/dice.yaml", []byte("old-global"), 0o644)
+	_ = os.WriteFile("data/default/data.db", []byte("old-db"), 0o644)
+	_ = os.WriteFile("data/default/data.db-wal", []byte("old-wal"), 0o644)
+	_ = os.WriteFile("data/decks/keep.json", []byte("keep"), 0o644)
+
+	archive := filepath.Join(t.TempDir(), "bak.zip")
+	writeTestBackup(t, archive, map[string]string{
+		"backup_info.json":                     `{"version":"1.0.0","versionCode":1}`,
+		"data/dice.yaml":                       "new-global",
+		`data\default\data.db`:                 "new-db",
+		"data/default/scripts/a.js":            "js",
+		"data/decks/new.json":                  "deck",
+		"data/other/serve.yaml":                "other",
+		"data/default/extensions/reply/r.yaml": "reply",
+	})
+
+	info, err := InspectBackup(archive)
+	if err != nil {
+		t.Fatalf("InspectBackup() error = %v", err)
+	}
+	if !info.Global || info.Sections != BackupSelectionJS|BackupSelectionDecks || len(info.Dices) != 2 || info.FileCount != 6 {
+		t.Fatalf("InspectBackup() = %+v", info)
+	}
+
+	sel, err := ParseBackupRestoreSelection("decks,dice:default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := RestoreBackup(archive, sel)
+	if err != nil {
+		t.Fatalf("RestoreBackup() error = %v", err)
+	}
+	if len(result.Files) != 3 {
+		t.Fatalf("restored files = %v", result.Files)
+	}
+	if got := readTestFile(t, "data/default/data.db"); got != "new-db" {
+		t.Fatalf("data.db = %q", got)
+	}
+	if _, statErr := os.Stat("data/default/data.db-wal"); !os.IsNotExist(statErr) {
+		t.Fatal("stale WAL file should be removed")
+	}
+	if got := readTestFile(t, "data/dice.yaml"); got != "old-global" {
+		t.Fatalf("dice.yaml should not be restored, got %q", got)
+	}
+	if readTestFile(t, "data/decks/keep.json") != "keep" || readTestFile(t, "data/decks/new.json") != "deck" {
+		t.Fatal("decks not merged")
+	}
+	for _, fn := range []string{"data/default/scripts/a.js", "data/other/serve.yaml"} {
+		if _, statErr := os.Stat(fn); !os.IsNotExist(statErr) {
+			t.Fatalf("%s should not be restored", fn)
+		}
+	}
+
+	// 恢复前的快照本身也是有效的备份，可以撤销这次恢复
+	if _, err = RestoreBackup(result.Snapshot, BackupRestoreSelectionAll); err != nil {
+		t.Fatalf("restore snapshot error = %v", err)
+	}
+	if readTestFile(t, "data/default/data.db") != "old-db" || readTestFile(t, "data/default/data.db-wal") != "old-wal" {
+		t.Fatal("snapshot did not bring back the old database")
+	}
+}
+
+func TestInspectBackupRejectsUnsafePaths(t *testing.T) {
+	dir := t.TempDir()
+	for name, files := range map[string]map[string]string{
+		"escape.zip":   {"backup_info.json": "{}", "data/../../evil": "x"},
+		"absolute.zip": {"backup_info.json": "{}", "/etc/evil": "x"},
+	} {
+		fn := filepath.Join(dir, name)
+		writeTestBackup(t, fn, files)
+		if _, err := InspectBackup(fn); !errors.Is(err, ErrBackupBadEntry) {
+			t.Fatalf("%s: InspectBackup() error = %v, want ErrBackupBadEntry", name, err)
+		}
+	}
+
+	fn := filepath.Join(dir, "noinfo.zip")
+	writeTestBackup(t, fn, map[string]string{"data/dice.yaml": "x"})
+	if _, err := InspectBackup(fn); !errors.Is(err, ErrBackupNoInfo) {
+		t.Fatalf("InspectBackup() error = %v, want ErrBackupNoInfo", err)
+	}
+}
@@ -2,14 +2,18 @@ package dice
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/golang-module/carbon"
 	"github.com/robfig/cron/v3"
 
 	"sealdice-core/dice/service"
+	"sealdice-core/model"
 )
 
 type BanRankType int
@@ -25,6 +29,42 @@ const defaultBlacklistedHelpMasterCooldown = time.Hour
 
 const defaultBlacklistedUserNoticeCooldown = 20 * time.Minute
 
+// 申诉被驳回后，需等待此时长才能再次申诉
+const banAppealCooldown = 24 * time.Hour
+
+// Times/Reasons/Places 仅保留最近的记录用于展示，完整记录见 ban_events 表
+const banHistoryKeep = 20
+
+// 黑名单事件的触发来源
+const (
+	BanHandlerGroupMuted  = "group_muted"
+	BanHandlerGroupKicked = "group_kicked"
+	BanHandlerCommandSpam = "command_spam"
+	BanHandlerCensor      = "censor"
+	BanHandlerJoint       = "joint" // 连带责任
+	BanHandlerCommand     = "command"
+	BanHandlerWebUI       = "webui"
+	BanHandlerAppeal      = "appeal"
+	BanHandlerCron        = "cron"
+	BanHandlerOther       = "other"
+)
+
+var (
+	ErrBanAppealNotBanned = errors.New("当前不在黑名单或警告名单中，无需申诉")
+	ErrBanAppealPending   = errors.New("已有待处理的申诉，请耐心等待骰主处理")
+	ErrBanAppealCooldown  = errors.New("申诉已被驳回，请稍后再试")
+	ErrBanAppealNotFound  = errors.New("申诉不存在或已处理")
+	ErrBanNoDatabase      = errors.New("数据库未就绪")
+)
+
+// BanSource 黑名单变动的来源，写入审计记录
+type BanSource struct {
+	Handler  string // 触发来源，见 BanHandler 系列常量
+	Operator string // 操作者，自动触发时为空
+	// Minutes 进入黑名单时的时长(分钟)，0 在开启 AutoBanExpire 时使用 AutoBanMinutes，否则与负数一样为永久
+	Minutes int64
+}
+
 type blacklistedUserNoticeKey struct {
 	GroupID string
 	UserID  string
@@ -39,6 +79,10 @@ type BanListInfoItem struct {
 	Reasons []string    `jsbind:"reasons" json:"reasons"` // 拉黑原因
 	Places  []string    `jsbind:"places"  json:"places"`  // 发生地点
 	BanTime int64       `jsbind:"banTime" json:"banTime"` // 上黑名单时间
+	// ExpireAt 黑名单到期时间，0 为永久，到期后由定时任务解除
+	ExpireAt int64 `jsbind:"expireAt" json:"expireAt"`
+	// Sources 来自各订阅名单的分数(已乘信任权重)，键为订阅名
+	Sources map[string]int64 `jsbind:"sources" json:"sources,omitempty"`
 
 	BanUpdatedAt int64 `json:"-"` // 排序依据，不过可能和bantime重复？
 	UpdatedAt    int64 `json:"-"` // 数据更新时间
@@ -53,6 +97,10 @@ var BanRankText = map[BanRankType]string{
 
 func (i *BanListInfoItem) toText(_ *Dice) string {
 	prefix := BanRankText[i.Rank]
+	if i.Rank == -30 && i.ExpireAt > 0 {
+		return fmt.Sprintf("[%s] <%s>(%s) 原因: %s 到期: %s", prefix, i.Name, i.ID, strings.Join(i.Reasons, ","),
+			carbon.CreateFromTimestamp(i.ExpireAt).ToDateTimeString())
+	}
 	if i.Rank == -10 || i.Rank == -30 {
 		return fmt.Sprintf("[%s] <%s>(%s) 原因: %s", prefix, i.Name, i.ID, strings.Join(i.Reasons, ","))
 	}
@@ -74,6 +122,7 @@ type BanListInfo struct {
 	ThresholdWarn                          int64                              `json:"thresholdWarn"                          yaml:"thresholdWarn"`                          // 警告阈值
 	ThresholdBan                           int64                              `json:"thresholdBan"                           yaml:"thresholdBan"`                           // 错误阈值
 	AutoBanMinutes                         int64                              `json:"autoBanMinutes"                         yaml:"autoBanMinutes"`                         // 自动禁止时长
+	AutoBanExpire                          bool                               `json:"autoBanExpire"                          yaml:"autoBanExpire"`                          // 自动拉黑按 AutoBanMinutes 到期解除，关闭时为永久
 
 	ScoreReducePerMinute      int64 `json:"scoreReducePerMinute" yaml:"scoreReducePerMinute"`           // 每分钟下降
 	ScoreGroupMuted           int64 `json:"scoreGroupMuted"      yaml:"scoreGroupMuted"`                // 群组禁言
@@ -85,11 +134,18 @@ type BanListInfo struct {
 	JointScorePercentOfGroup   float64 `json:"jointScorePercentOfGroup"   yaml:"jointScorePercentOfGroup"`   // 群组连带责任
 	JointScorePercentOfInviter float64 `json:"jointScorePercentOfInviter" yaml:"jointScorePercentOfInviter"` // 邀请人连带责任
 
+	FederationPublish       bool                         `json:"federationPublish"       yaml:"federationPublish"`       // 允许其他骰子拉取本地名单
+	FederationName          string                       `json:"federationName"          yaml:"federationName"`          // 对外发布的名称
+	FederationSyncMinutes   int64                        `json:"federationSyncMinutes"   yaml:"federationSyncMinutes"`   // 订阅同步间隔，0 为默认 60 分钟
+	FederationSubscriptions []*BanFederationSubscription `json:"federationSubscriptions" yaml:"federationSubscriptions"` // 黑名单订阅
+
 	helpMasterReplyMu sync.Mutex                             `json:"-" yaml:"-"`
 	helpMasterReplyAt map[string]time.Time                   `json:"-" yaml:"-"`
 	banNoticeMu       sync.Mutex                             `json:"-" yaml:"-"`
 	banNoticeAt       map[blacklistedUserNoticeKey]time.Time `json:"-" yaml:"-"`
 	cronID            cron.EntryID                           `json:"-" yaml:"-"`
+	federationMu      sync.Mutex                             `json:"-" yaml:"-"`
+	federationSyncing bool                                   `json:"-" yaml:"-"`
 }
 
 func (i *BanListInfo) Init() {
@@ -188,6 +244,77 @@ func (i *BanListInfo) CanNotifyBlacklistedUser(groupID string, userID string, no
 func (i *BanListInfo) Loads() {
 }
 
+// appendHistory 追加一条简要记录，只保留最近 banHistoryKeep 条
+func (v *BanListInfoItem) appendHistory(place string, reason string, now int64) {
+	v.Places = append(v.Places, place)
+	v.Reasons = append(v.Reasons, reason)
+	v.Times = append(v.Times, now)
+	if n := len(v.Reasons); n > banHistoryKeep && len(v.Places) == n && len(v.Times) == n {
+		v.Places = v.Places[n-banHistoryKeep:]
+		v.Reasons = v.Reasons[n-banHistoryKeep:]
+		v.Times = v.Times[n-banHistoryKeep:]
+	}
+}
+
+// recordEvent 写入审计记录，失败时只记日志
+func (i *BanListInfo) recordEvent(event *model.BanEvent) {
+	d := i.Parent
+	if d == nil || d.DBOperator == nil {
+		return
+	}
+	if event.CreatedAt == 0 {
+		event.CreatedAt = time.Now().Unix()
+	}
+	if err := service.BanEventAppend(d.DBOperator, event); err != nil {
+		d.Logger.Errorf("写入黑名单记录失败: %v", err)
+	}
+}
+
+// expireBanned 解除已到期的黑名单，由定时任务调用
+func (i *BanListInfo) expireBanned(now int64) {
+	var expired []*BanListInfoItem
+	i.Map.Range(func(_ string, v *BanListInfoItem) bool {
+		if v.Rank == BanRankBanned && v.ExpireAt > 0 && v.ExpireAt <= now {
+			expired = append(expired, v)
+		}
+		return true
+	})
+	for _, v := range expired {
+		i.unban(v, model.BanEventActionExpire, "黑名单到期", BanSource{Handler: BanHandlerCron}, now)
+	}
+}
+
+// unban 将条目恢复为常规，并写入审计记录
+func (i *BanListInfo) unban(v *BanListInfoItem, action string, reason string, src BanSource, now int64) {
+	oldRank := v.Rank
+	v.Rank = BanRankNormal
+	v.Score = 0
+	v.ExpireAt = 0
+	v.BanUpdatedAt = now
+	v.UpdatedAt = now
+	i.recordEvent(&model.BanEvent{
+		TargetID:   v.ID,
+		TargetName: v.Name,
+		Action:     action,
+		Handler:    src.Handler,
+		Operator:   src.Operator,
+		Reason:     reason,
+		RankBefore: int(oldRank),
+		RankAfter:  int(BanRankNormal),
+		CreatedAt:  now,
+	})
+}
+
+// Unban 解除黑名单/警告/信任，返回原条目
+func (i *BanListInfo) Unban(uid string, reason string, src BanSource) (*BanListInfoItem, bool) {
+	v, ok := i.GetByID(uid)
+	if !ok || v.Rank == BanRankNormal {
+		return v, false
+	}
+	i.unban(v, model.BanEventActionUnban, reason, src, time.Now().Unix())
+	return v, true
+}
+
 func (i *BanListInfo) AfterLoads() {
 	// 加载完成了
 	d := i.Parent
@@ -206,11 +333,17 @@ func (i *BanListInfo) AfterLoads() {
 		if d.DBOperator == nil {
 			return
 		}
+		i.expireBanned(time.Now().Unix())
+		go i.SyncFederation(false)
+
 		var toDelete []string
 		(&d.Config).BanList.Map.Range(func(k string, v *BanListInfoItem) bool {
 			if v.Rank == BanRankNormal || v.Rank == BanRankWarn {
 				v.Score -= i.ScoreReducePerMinute
-				if v.Score <= 0 {
+				if len(v.Sources) > 0 {
+					// 仍在订阅名单中，只衰减本地分数
+					v.Score = max(v.Score, 0)
+				} else if v.Score <= 0 {
 					// 小于0之后就移除掉
 					toDelete = append(toDelete, k)
 				}
@@ -230,6 +363,11 @@ func (i *BanListInfo) AfterLoads() {
 // AddScoreBase
 // 这一份ctx有endpoint就行
 func (i *BanListInfo) AddScoreBase(uid string, score int64, place string, reason string, ctx *MsgContext) *BanListInfoItem {
+	return i.AddScoreWithSource(uid, score, place, reason, BanSource{Handler: BanHandlerOther}, ctx)
+}
+
+// AddScoreWithSource 同 AddScoreBase，额外记录来源与拉黑时长
+func (i *BanListInfo) AddScoreWithSource(uid string, score int64, place string, reason string, src BanSource, ctx *MsgContext) *BanListInfoItem {
 	log := i.Parent.Logger
 	v, _ := i.Map.Load(uid)
 	if v == nil {
@@ -240,14 +378,13 @@ func (i *BanListInfo) AddScoreBase(uid string, score int64, place string, reason
 		}
 	}
 
+	now := time.Now().Unix()
 	v.Score += score
 	v.Name = i.Parent.Parent.TryGetUserName(uid)
 	if strings.Contains(uid, "-Group:") {
 		v.Name = i.Parent.Parent.TryGetGroupName(uid)
 	}
-	v.Places = append(v.Places, place)
-	v.Reasons = append(v.Reasons, reason)
-	v.Times = append(v.Times, time.Now().Unix())
+	v.appendHistory(place, reason, now)
 	oldRank := v.Rank
 
 	switch v.Rank {
@@ -262,32 +399,58 @@ func (i *BanListInfo) AddScoreBase(uid string, score int64, place string, reason
 		}
 		if v.Score >= i.ThresholdBan {
 			v.Rank = BanRankBanned
-			v.BanTime = time.Now().Unix()
+			v.BanTime = now
+			v.ExpireAt = 0
+			minutes := src.Minutes
+			if minutes == 0 && i.AutoBanExpire {
+				minutes = i.AutoBanMinutes
+			}
+			if minutes > 0 {
+				v.ExpireAt = now + minutes*60
+			}
 
 			if ctx.EndPoint.Platform == "QQ" {
-				switch adapter := ctx.EndPoint.Adapter.(type) {
-				case *PlatformAdapterGocq:
+				adapter, ok := ctx.EndPoint.Adapter.(friendDeleter)
+				if ok && ctx.EndPoint.Capabilities().DeleteFriend {
 					adapter.DeleteFriend(ctx, place)
-				case *PlatformAdapterWalleQ:
-					adapter.DeleteFriend(ctx, place)
-				case *PlatformAdapterRed:
-					log.Warn("qq red 适配器不支持删除好友")
-				case *PlatformAdapterOfficialQQ:
-					log.Warn("official qq 适配器不支持删除好友")
-				default:
-					log.Error("unknown qq adapter")
+				} else {
+					log.Warnf("qq %s 适配器不支持删除好友", ctx.EndPoint.ProtocolType)
 				}
 			}
 		}
 
 		if oldRank != v.Rank {
-			v.BanUpdatedAt = time.Now().Unix()
+			v.BanUpdatedAt = now
 		}
 	}
 
-	v.UpdatedAt = time.Now().Unix()
+	v.UpdatedAt = now
 	i.Map.Store(uid, v)
 
+	action := model.BanEventActionScore
+	if oldRank != v.Rank {
+		switch v.Rank {
+		case BanRankBanned:
+			action = model.BanEventActionBan
+		case BanRankWarn:
+			action = model.BanEventActionWarn
+		}
+	}
+	i.recordEvent(&model.BanEvent{
+		TargetID:   uid,
+		TargetName: v.Name,
+		Action:     action,
+		Handler:    src.Handler,
+		Operator:   src.Operator,
+		Place:      place,
+		Reason:     reason,
+		Score:      score,
+		RankBefore: int(oldRank),
+		RankAfter:  int(v.Rank),
+		ExpireAt:   v.ExpireAt,
+		CreatedAt:  now,
+	})
+
 	// 发送通知
 	if ctx != nil {
 		// 警告: XXX 因为等行为，进入警告列表
@@ -303,16 +466,17 @@ func (i *BanListInfo) AddScoreBase(uid string, score int64, place string, reason
 // 返回连带责任人
 func (i *BanListInfo) addJointScore(_ string, score int64, place string, reason string, ctx *MsgContext) (string, BanRankType) {
 	d := i.Parent
+	src := BanSource{Handler: BanHandlerJoint}
 	if i.JointScorePercentOfGroup > 0 {
 		score := i.JointScorePercentOfGroup * float64(score)
-		i.AddScoreBase(place, int64(score), place, reason, ctx)
+		i.AddScoreWithSource(place, int64(score), place, reason, src, ctx)
 	}
 	if i.JointScorePercentOfInviter > 0 {
 		groupInfo, ok := d.ImSession.ServiceAtNew.Load(place)
 		if ok && groupInfo.InviteUserID != "" {
 			rank := i.NoticeCheckPrepare(groupInfo.InviteUserID)
 			score := i.JointScorePercentOfInviter * float64(score)
-			i.AddScoreBase(groupInfo.InviteUserID, int64(score), place, reason, ctx)
+			i.AddScoreWithSource(groupInfo.InviteUserID, int64(score), place, reason, src, ctx)
 
 			// text := fmt.Sprintf("提醒: 你邀请的骰子在群组<%s>中被禁言/踢出/指令刷屏了", groupInfo.GroupName)
 			// ReplyPersonRaw(ctx, &Message{Sender: SenderBase{UserId: groupInfo.InviteUserId}}, text, "")
@@ -410,7 +574,7 @@ func (i *BanListInfo) NoticeCheck(uid string, place string, oldRank BanRankType,
 func (i *BanListInfo) AddScoreByGroupMuted(uid string, place string, ctx *MsgContext) {
 	rank := i.NoticeCheckPrepare(uid)
 
-	i.AddScoreBase(uid, i.ScoreGroupMuted, place, "禁言骰子", ctx)
+	i.AddScoreWithSource(uid, i.ScoreGroupMuted, place, "禁言骰子", BanSource{Handler: BanHandlerGroupMuted}, ctx)
 	inviterID, inviterRank := i.addJointScore(uid, i.ScoreGroupMuted, place, "连带责任:禁言骰子", ctx)
 
 	i.NoticeCheck(uid, place, rank, ctx)
@@ -424,7 +588,7 @@ func (i *BanListInfo) AddScoreByGroupMuted(uid string, place string, ctx *MsgCon
 func (i *BanListInfo) AddScoreByGroupKicked(uid string, place string, ctx *MsgContext) {
 	rank := i.NoticeCheckPrepare(uid)
 
-	i.AddScoreBase(uid, i.ScoreGroupKicked, place, "踢出骰子", ctx)
+	i.AddScoreWithSource(uid, i.ScoreGroupKicked, place, "踢出骰子", BanSource{Handler: BanHandlerGroupKicked}, ctx)
 	inviterID, inviterRank := i.addJointScore(uid, i.ScoreGroupKicked, place, "连带责任:踢出骰子", ctx)
 
 	i.NoticeCheck(uid, place, rank, ctx)
@@ -438,7 +602,7 @@ func (i *BanListInfo) AddScoreByGroupKicked(uid string, place string, ctx *MsgCo
 func (i *BanListInfo) AddScoreByCommandSpam(uid string, place string, ctx *MsgContext) {
 	rank := i.NoticeCheckPrepare(uid)
 
-	i.AddScoreBase(uid, i.ScoreTooManyCommand, place, "指令刷屏", ctx)
+	i.AddScoreWithSource(uid, i.ScoreTooManyCommand, place, "指令刷屏", BanSource{Handler: BanHandlerCommandSpam}, ctx)
 	inviterID, inviterRank := i.addJointScore(uid, i.ScoreTooManyCommand, place, "连带责任:指令刷屏", ctx)
 
 	i.NoticeCheck(uid, place, rank, ctx)
@@ -452,7 +616,7 @@ func (i *BanListInfo) AddScoreByCommandSpam(uid string, place string, ctx *MsgCo
 func (i *BanListInfo) AddScoreByCensor(uid string, score int64, place string, level string, ctx *MsgContext) {
 	rank := i.NoticeCheckPrepare(uid)
 
-	i.AddScoreBase(uid, score, place, "触发<"+level+">敏感词", ctx)
+	i.AddScoreWithSource(uid, score, place, "触发<"+level+">敏感词", BanSource{Handler: BanHandlerCensor}, ctx)
 	inviterID, inviterRank := i.addJointScore(uid, score, place, "连带责任:触发<"+level+">敏感词", ctx)
 
 	i.NoticeCheck(uid, place, rank, ctx)
@@ -469,7 +633,30 @@ func (i *BanListInfo) GetByID(uid string) (*BanListInfoItem, bool) {
 	return i.Map.Load(uid)
 }
 
+// GetByIDLinked 查询用户的黑名单记录，账号已关联时取各关联账号中最严重的一条。
+// 本账号被信任时以本账号为准
+func (i *BanListInfo) GetByIDLinked(uid string) (*BanListInfoItem, bool) {
+	v, ok := i.GetByID(uid)
+	if (ok && v.Rank == BanRankTrusted) || i.Parent == nil || i.Parent.AttrsManager == nil {
+		return v, ok
+	}
+	for _, other := range i.Parent.AttrsManager.LinkedUsers(uid) {
+		if other == uid {
+			continue
+		}
+		if o, exists := i.GetByID(other); exists && o.Rank < BanRankNormal && (v == nil || o.Rank < v.Rank) {
+			v, ok = o, true
+		}
+	}
+	return v, ok
+}
+
 func (i *BanListInfo) SetTrustByID(uid string, place string, reason string) {
+	i.SetTrustWithSource(uid, place, reason, BanSource{Handler: BanHandlerOther})
+}
+
+// SetTrustWithSource 同 SetTrustByID，额外记录来源
+func (i *BanListInfo) SetTrustWithSource(uid string, place string, reason string, src BanSource) {
 	v, ok := i.GetByID(uid)
 	if !ok {
 		v = &BanListInfoItem{
@@ -478,17 +665,30 @@ func (i *BanListInfo) SetTrustByID(uid string, place string, reason string) {
 			Places:  []string{},
 		}
 	}
+	now := time.Now().Unix()
+	oldRank := v.Rank
 	v.Rank = BanRankTrusted
+	v.ExpireAt = 0
 	v.Name = i.Parent.Parent.TryGetUserName(uid)
 	if strings.Contains(uid, "-Group:") {
 		v.Name = i.Parent.Parent.TryGetGroupName(uid)
 	}
-	v.Places = append(v.Places, place)
-	v.Reasons = append(v.Reasons, reason)
-	v.Times = append(v.Times, time.Now().Unix())
+	v.appendHistory(place, reason, now)
 
-	v.UpdatedAt = time.Now().Unix()
+	v.UpdatedAt = now
 	i.Map.Store(uid, v)
+	i.recordEvent(&model.BanEvent{
+		TargetID:   uid,
+		TargetName: v.Name,
+		Action:     model.BanEventActionTrust,
+		Handler:    src.Handler,
+		Operator:   src.Operator,
+		Place:      place,
+		Reason:     reason,
+		RankBefore: int(oldRank),
+		RankAfter:  int(BanRankTrusted),
+		CreatedAt:  now,
+	})
 }
 
 func (d *Dice) GetBanList() []*BanListInfoItem {
@@ -518,6 +718,235 @@ func (i *BanListInfo) SaveChanged(d *Dice) {
 }
 
 func (i *BanListInfo) DeleteByID(d *Dice, id string) {
+	i.DeleteWithSource(d, id, BanSource{Handler: BanHandlerOther})
+}
+
+// DeleteWithSource 同 DeleteByID，额外记录来源
+func (i *BanListInfo) DeleteWithSource(d *Dice, id string, src BanSource) {
+	if v, ok := i.Map.Load(id); ok {
+		i.recordEvent(&model.BanEvent{
+			TargetID:   id,
+			TargetName: v.Name,
+			Action:     model.BanEventActionDelete,
+			Handler:    src.Handler,
+			Operator:   src.Operator,
+			RankBefore: int(v.Rank),
+			RankAfter:  int(BanRankNormal),
+		})
+	}
 	i.Map.Delete(id)
 	_ = service.BanItemDel(d.DBOperator, id)
 }
+
+// SubmitAppeal 提交申诉，仅黑名单与警告名单中的对象可以申诉
+func (i *BanListInfo) SubmitAppeal(uid string, name string, place string, content string) (*model.BanAppeal, error) {
+	d := i.Parent
+	if d.DBOperator == nil {
+		return nil, ErrBanNoDatabase
+	}
+	v, ok := i.GetByID(uid)
+	if !ok || (v.Rank != BanRankBanned && v.Rank != BanRankWarn) {
+		return nil, ErrBanAppealNotBanned
+	}
+
+	latest, err := service.BanAppealLatest(d.DBOperator, uid)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if latest != nil {
+		if latest.Status == model.BanAppealStatusPending {
+			return latest, ErrBanAppealPending
+		}
+		// 被驳回后需等待一段时间才能再次申诉
+		if latest.Status == model.BanAppealStatusRejected && now.Sub(time.Unix(latest.HandledAt, 0)) < banAppealCooldown {
+			return latest, ErrBanAppealCooldown
+		}
+	}
+
+	appeal := &model.BanAppeal{
+		TargetID:   uid,
+		TargetName: name,
+		Place:      place,
+		Content:    content,
+		Status:     model.BanAppealStatusPending,
+		CreatedAt:  now.Unix(),
+	}
+	if err = service.BanAppealCreate(d.DBOperator, appeal); err != nil {
+		return nil, err
+	}
+	return appeal, nil
+}
+
+// ResolveAppeal 处理申诉，接受时解除对象的黑名单/警告
+func (i *BanListInfo) ResolveAppeal(id uint64, accept bool, operator string, reply string) (*model.BanAppeal, error) {
+	d := i.Parent
+	if d.DBOperator == nil {
+		return nil, ErrBanNoDatabase
+	}
+	appeal, err := service.BanAppealGet(d.DBOperator, id)
+	if err != nil {
+		return nil, err
+	}
+	if appeal == nil || appeal.Status != model.BanAppealStatusPending {
+		return nil, ErrBanAppealNotFound
+	}
+
+	status := model.BanAppealStatusRejected
+	if accept {
+		status = model.BanAppealStatusAccepted
+	}
+	now := time.Now().Unix()
+	updated, err := service.BanAppealResolve(d.DBOperator, id, status, operator, reply, now)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrBanAppealNotFound
+	}
+	appeal.Status = status
+	appeal.HandledBy = operator
+	appeal.HandledAt = now
+	appeal.Reply = reply
+
+	if accept {
+		reason := fmt.Sprintf("申诉#%d通过", id)
+		if reply != "" {
+			reason += ": " + reply
+		}
+		i.Unban(appeal.TargetID, reason, BanSource{Handler: BanHandlerAppeal, Operator: operator})
+		i.SaveChanged(d)
+	}
+	return appeal, nil
+}
+
+// BanAppealStatusText 申诉状态的展示文本
+var BanAppealStatusText = map[string]string{
+	model.BanAppealStatusPending:  "待处理",
+	model.BanAppealStatusAccepted: "已通过",
+	model.BanAppealStatusRejected: "已驳回",
+}
+
+// banAppealSolve 处理 .ban appeal：骰主查看/处理申诉，其他人提交申诉
+func banAppealSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+	d := ctx.Dice
+	banList := d.Config.BanList
+	sub := strings.ToLower(cmdArgs.GetArgN(2))
+
+	if ctx.PrivilegeLevel >= 100 && (sub == "list" || sub == "accept" || sub == "reject") {
+		if d.DBOperator == nil {
+			ReplyToSender(ctx, msg, ErrBanNoDatabase.Error())
+			return CmdExecuteResult{Matched: true, Solved: true}
+		}
+		if sub == "list" {
+			_, appeals, err := service.BanAppealGetPage(d.DBOperator, service.QueryBanAppeal{
+				PageNum: 1, PageSize: 10, Status: model.BanAppealStatusPending,
+			})
+			if err != nil {
+				ReplyToSender(ctx, msg, "读取申诉失败: "+err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			if len(appeals) == 0 {
+				ReplyToSender(ctx, msg, "当前没有待处理的申诉")
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			var sb strings.Builder
+			sb.WriteString("待处理的申诉:")
+			for _, a := range appeals {
+				fmt.Fprintf(&sb, "\n#%d <%s>(%s) %s: %s", a.ID, a.TargetName, a.TargetID,
+					carbon.CreateFromTimestamp(a.CreatedAt).ToDateTimeString(), a.Content)
+			}
+			ReplyToSender(ctx, msg, sb.String())
+			return CmdExecuteResult{Matched: true, Solved: true}
+		}
+
+		id, err := strconv.ParseUint(strings.TrimPrefix(cmdArgs.GetArgN(3), "#"), 10, 64)
+		if err != nil {
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		reply := strings.Join(cmdArgs.Args[min(3, len(cmdArgs.Args)):], " ")
+		appeal, err := banList.ResolveAppeal(id, sub == "accept", msg.Sender.UserID, reply)
+		if err != nil {
+			ReplyToSender(ctx, msg, err.Error())
+			return CmdExecuteResult{Matched: true, Solved: true}
+		}
+
+		ReplyToSender(ctx, msg, fmt.Sprintf("申诉#%d %s", appeal.ID, BanAppealStatusText[appeal.Status]))
+		NotifyBanAppealResult(ctx, appeal)
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+
+	content := strings.TrimSpace(strings.Join(cmdArgs.Args[min(1, len(cmdArgs.Args)):], " "))
+	if content == "" {
+		ReplyToSender(ctx, msg, "请填写申诉内容，如: .ban appeal 误操作，已与群主沟通")
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+	appeal, err := banList.SubmitAppeal(msg.Sender.UserID, msg.Sender.Nickname, msg.GroupID, content)
+	switch {
+	case errors.Is(err, ErrBanAppealPending):
+		ReplyToSender(ctx, msg, fmt.Sprintf("%s(#%d)", err.Error(), appeal.ID))
+		return CmdExecuteResult{Matched: true, Solved: true}
+	case err != nil:
+		ReplyToSender(ctx, msg, err.Error())
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+
+	rankText := "未知"
+	if v, ok := banList.GetByID(appeal.TargetID); ok {
+		rankText = BanRankText[v.Rank]
+	}
+	place := appeal.Place
+	if place == "" {
+		place = "私聊"
+	}
+	ctx.Notice(fmt.Sprintf("收到黑名单申诉#%d: <%s>(%s) 当前: %s，来自: %s\n申诉内容: %s\n使用 .ban appeal accept/reject %d [<回复>] 处理",
+		appeal.ID, appeal.TargetName, appeal.TargetID, rankText, place, appeal.Content, appeal.ID), NoticeTypeBan)
+	ReplyToSender(ctx, msg, fmt.Sprintf("申诉#%d已提交，骰主处理后会私聊通知你", appeal.ID))
+	return CmdExecuteResult{Matched: true, Solved: true}
+}
+
+// NotifyBanAppealResult 私聊通知申诉人处理结果。ctx 的平台与申诉人不一致时，改用对应平台的账号发送
+func NotifyBanAppealResult(ctx *MsgContext, appeal *model.BanAppeal) {
+	text := fmt.Sprintf("你的黑名单申诉#%d%s", appeal.ID, BanAppealStatusText[appeal.Status])
+	if appeal.Reply != "" {
+		text += "，骰主回复: " + appeal.Reply
+	}
+	platform := strings.SplitN(appeal.TargetID, ":", 2)[0]
+	if ctx.EndPoint == nil || ctx.EndPoint.Platform != platform {
+		var ep *EndPointInfo
+		for _, i := range ctx.Dice.ImSession.EndPoints {
+			if i.Platform == platform && i.Enable {
+				ep = i
+				break
+			}
+		}
+		if ep == nil {
+			return
+		}
+		ctx = &MsgContext{Dice: ctx.Dice, EndPoint: ep, Session: ctx.Dice.ImSession}
+	}
+	ReplyPersonRaw(ctx, &Message{Sender: SenderBase{UserID: appeal.TargetID}}, text, "")
+}
+
+// tryHandleBlacklistedAppealRequest 黑名单用户被拒绝回复时，仍允许其使用 .ban appeal
+func tryHandleBlacklistedAppealRequest(ctx *MsgContext, msg *Message, now time.Time) bool {
+	var cmdArgs *CmdArgs
+	if len(msg.Segment) > 0 {
+		cmdArgs = CommandParseNew(ctx, msg)
+	} else {
+		cmdArgs = CommandParse(msg.Message, []string{"ban", "black"}, ctx.Dice.CommandPrefix, msg.Platform, false)
+	}
+	if cmdArgs == nil || (!strings.EqualFold(cmdArgs.Command, "ban") && !strings.EqualFold(cmdArgs.Command, "black")) {
+		return false
+	}
+	cmdArgs.ChopPrefixToArgsWith("appeal")
+	if !cmdArgs.IsArgEqual(1, "appeal") {
+		return false
+	}
+	// 避免黑名单用户借申诉刷屏
+	if !ctx.Dice.Config.BanList.CanNotifyBlacklistedUser(msg.GroupID, msg.Sender.UserID, now) {
+		return true
+	}
+	banAppealSolve(ctx, msg, cmdArgs)
+	return true
+}
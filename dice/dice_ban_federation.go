@@ -0,0 +1,558 @@
+package dice
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/crypto"
+)
+
+// 黑名单共享格式版本
+const banExchangeVersion = 1
+
+const (
+	BanExchangeAlgorithmECDSA = "ecdsa"
+	BanExchangeAlgorithmRSA   = "rsa"
+)
+
+// 来自订阅的变动，handler 记为 federation
+const BanHandlerFederation = "federation"
+
+// 订阅同步默认间隔
+const defaultBanFederationSyncMinutes = 60
+
+// 单个订阅名单的大小上限
+const banExchangeMaxSize = 16 << 20
+
+var (
+	ErrBanExchangeBadSign    = errors.New("黑名单签名校验失败")
+	ErrBanExchangeBadKey     = errors.New("黑名单公钥格式错误")
+	ErrBanExchangeKeyChanged = errors.New("黑名单公钥与订阅记录的不一致")
+	ErrBanExchangeVersion    = errors.New("不支持的黑名单格式版本")
+)
+
+var banFederationHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// BanListExchange 带签名的黑名单交换格式，签名覆盖 payload 压缩后的 JSON
+type BanListExchange struct {
+	Algorithm string          `json:"algorithm"`
+	PublicKey string          `json:"publicKey"`
+	Payload   json.RawMessage `json:"payload"`
+	Sign      string          `json:"sign"`
+}
+
+type BanListExchangePayload struct {
+	Version   int                    `json:"version"`
+	Source    string                 `json:"source"` // 发布者名称
+	CreatedAt int64                  `json:"createdAt"`
+	Items     []*BanListExchangeItem `json:"items"`
+}
+
+type BanListExchangeItem struct {
+	ID       string      `json:"id"`
+	Name     string      `json:"name"`
+	Rank     BanRankType `json:"rank"`
+	Score    int64       `json:"score"`
+	Reasons  []string    `json:"reasons"`
+	BanTime  int64       `json:"banTime"`
+	ExpireAt int64       `json:"expireAt"`
+}
+
+// BanFederationSubscription 黑名单订阅
+type BanFederationSubscription struct {
+	Name string `json:"name" yaml:"name"` // 订阅名，用于标注来源
+	URL  string `json:"url"  yaml:"url"`
+	// PublicKey 对方公钥，为空时首次同步记录对方公钥，之后不允许变化
+	PublicKey string `json:"publicKey" yaml:"publicKey"`
+	// Weight 信任权重，远端分数乘以该值后计入本地
+	Weight float64 `json:"weight" yaml:"weight"`
+	Enable bool    `json:"enable" yaml:"enable"`
+
+	LastSyncAt int64  `json:"lastSyncAt" yaml:"lastSyncAt"`
+	LastError  string `json:"lastError"  yaml:"lastError"`
+	LastCount  int    `json:"lastCount"  yaml:"lastCount"`
+}
+
+func (s *BanFederationSubscription) weight() float64 {
+	if s.Weight <= 0 {
+		return 1
+	}
+	return s.Weight
+}
+
+func (i *BanListInfo) FederationSyncInterval() time.Duration {
+	if i.FederationSyncMinutes <= 0 {
+		return defaultBanFederationSyncMinutes * time.Minute
+	}
+	return time.Duration(i.FederationSyncMinutes) * time.Minute
+}
+
+// banExchangeCheckKey 确认公钥类型与算法匹配，避免 crypto 包中类型断言失败
+func banExchangeCheckKey(algorithm string, publicKey string) error {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return ErrBanExchangeBadKey
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return ErrBanExchangeBadKey
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey:
+		if algorithm == BanExchangeAlgorithmECDSA {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if algorithm == BanExchangeAlgorithmRSA {
+			return nil
+		}
+	}
+	return ErrBanExchangeBadKey
+}
+
+// SignBanListExchange 使用 PKCS8 格式的 ECDSA 或 RSA 私钥签名
+func SignBanListExchange(payload *BanListExchangePayload, privateKey string) (*BanListExchange, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, ErrBanExchangeBadKey
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.Version = banExchangeVersion
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	ex := &BanListExchange{Payload: data}
+	var pub any
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		ex.Algorithm = BanExchangeAlgorithmECDSA
+		ex.Sign, err = crypto.EcdsaSign(data, privateKey)
+		pub = &k.PublicKey
+	case *rsa.PrivateKey:
+		ex.Algorithm = BanExchangeAlgorithmRSA
+		ex.Sign, err = crypto.RSASign256(data, privateKey)
+		pub = &k.PublicKey
+	default:
+		return nil, ErrBanExchangeBadKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pubDer, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	ex.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer}))
+	return ex, nil
+}
+
+// Verify 校验签名并解出内容。pinnedKey 不为空时要求公钥一致
+func (ex *BanListExchange) Verify(pinnedKey string) (*BanListExchangePayload, error) {
+	if pinnedKey != "" && strings.TrimSpace(pinnedKey) != strings.TrimSpace(ex.PublicKey) {
+		return nil, ErrBanExchangeKeyChanged
+	}
+	if err := banExchangeCheckKey(ex.Algorithm, ex.PublicKey); err != nil {
+		return nil, err
+	}
+
+	// 文件可能被重新格式化过，签名针对的是压缩后的内容
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, ex.Payload); err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+
+	var err error
+	switch ex.Algorithm {
+	case BanExchangeAlgorithmECDSA:
+		err = crypto.EcdsaVerify(data, ex.Sign, ex.PublicKey)
+	case BanExchangeAlgorithmRSA:
+		err = crypto.RSAVerify256(data, ex.Sign, ex.PublicKey)
+	}
+	if err != nil {
+		return nil, ErrBanExchangeBadSign
+	}
+
+	var payload BanListExchangePayload
+	if err = json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	if payload.Version != banExchangeVersion {
+		return nil, ErrBanExchangeVersion
+	}
+	return &payload, nil
+}
+
+// FederationKey 读取用于签名的私钥，不存在时生成一份
+func (d *Dice) FederationKey() (string, error) {
+	fn := filepath.Join(d.BaseConfig.DataDir, "extra", "ban_federation_key.pem")
+	data, err := os.ReadFile(fn)
+	if err == nil {
+		return string(data), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	data = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	_ = os.MkdirAll(filepath.Dir(fn), 0o755)
+	if err = os.WriteFile(fn, data, 0o600); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FederationExportPayload 导出本地产生的黑名单与警告，单纯来自订阅的条目不会被转发
+func (i *BanListInfo) FederationExportPayload(source string) *BanListExchangePayload {
+	payload := &BanListExchangePayload{
+		Source:    source,
+		CreatedAt: time.Now().Unix(),
+		Items:     []*BanListExchangeItem{},
+	}
+	i.Map.Range(func(_ string, v *BanListInfoItem) bool {
+		rank := v.Rank
+		if rank != BanRankBanned && rank != BanRankWarn {
+			return true
+		}
+		if len(v.Sources) > 0 {
+			rank = i.rankOfScore(v.Score)
+			if rank == BanRankNormal {
+				return true
+			}
+		}
+		payload.Items = append(payload.Items, &BanListExchangeItem{
+			ID:       v.ID,
+			Name:     v.Name,
+			Rank:     rank,
+			Score:    v.Score,
+			Reasons:  v.Reasons,
+			BanTime:  v.BanTime,
+			ExpireAt: v.ExpireAt,
+		})
+		return true
+	})
+	return payload
+}
+
+// FederationExport 导出签名后的本地名单
+func (d *Dice) FederationExport() (*BanListExchange, error) {
+	key, err := d.FederationKey()
+	if err != nil {
+		return nil, err
+	}
+	banList := d.Config.BanList
+	source := banList.FederationName
+	if source == "" {
+		source = "SealDice"
+	}
+	return SignBanListExchange(banList.FederationExportPayload(source), key)
+}
+
+// FederationPublicKey 本地签名公钥，供其他骰子订阅时校验
+func (d *Dice) FederationPublicKey() (string, error) {
+	ex, err := d.FederationExport()
+	if err != nil {
+		return "", err
+	}
+	return ex.PublicKey, nil
+}
+
+func (i *BanListInfo) rankOfScore(score int64) BanRankType {
+	switch {
+	case score >= i.ThresholdBan:
+		return BanRankBanned
+	case score >= i.ThresholdWarn:
+		return BanRankWarn
+	default:
+		return BanRankNormal
+	}
+}
+
+// federationScore 远端条目折算到本地的分数
+func (i *BanListInfo) federationScore(item *BanListExchangeItem, weight float64) int64 {
+	score := item.Score
+	switch item.Rank {
+	case BanRankBanned:
+		score = max(score, i.ThresholdBan)
+	case BanRankWarn:
+		score = max(score, i.ThresholdWarn)
+	default:
+		return 0
+	}
+	return int64(float64(score) * weight)
+}
+
+// applyFederationRank 按本地分数与各订阅分数之和重新计算等级，信任条目不受影响
+func (i *BanListInfo) applyFederationRank(v *BanListInfoItem, source string, item *BanListExchangeItem, now int64) bool {
+	if v.Rank == BanRankTrusted {
+		return false
+	}
+	total := v.Score
+	for _, s := range v.Sources {
+		total += s
+	}
+	newRank := i.rankOfScore(total)
+	if newRank == v.Rank {
+		return false
+	}
+	// 等级数值越小越严重；降级只在本地分数不足以维持当前等级时发生
+	if newRank > v.Rank && i.rankOfScore(v.Score) <= v.Rank {
+		return false
+	}
+
+	oldRank := v.Rank
+	v.Rank = newRank
+	v.BanUpdatedAt = now
+	v.UpdatedAt = now
+	action := model.BanEventActionWarn
+	reason := "订阅名单更新"
+	switch newRank {
+	case BanRankBanned:
+		action = model.BanEventActionBan
+		v.BanTime = now
+		v.ExpireAt = 0
+		if item != nil {
+			v.ExpireAt = item.ExpireAt
+			reason = strings.Join(item.Reasons, ",")
+		}
+	case BanRankNormal:
+		action = model.BanEventActionUnban
+		v.ExpireAt = 0
+		reason = "订阅名单已移除"
+	}
+	i.recordEvent(&model.BanEvent{
+		TargetID:   v.ID,
+		TargetName: v.Name,
+		Action:     action,
+		Handler:    BanHandlerFederation,
+		Operator:   source,
+		Reason:     reason,
+		Score:      total,
+		RankBefore: int(oldRank),
+		RankAfter:  int(newRank),
+		ExpireAt:   v.ExpireAt,
+		CreatedAt:  now,
+	})
+	return true
+}
+
+// MergeFederation 将订阅名单合并到本地，返回等级发生变化的条目数。
+// 远端条目的分数乘以 weight 记在 Sources[source] 中，远端已移除的条目会撤销其分数。
+func (i *BanListInfo) MergeFederation(source string, payload *BanListExchangePayload, weight float64, now int64) int {
+	changed := 0
+	seen := map[string]bool{}
+	for _, item := range payload.Items {
+		if item == nil || item.ID == "" {
+			continue
+		}
+		if item.ExpireAt > 0 && item.ExpireAt <= now {
+			continue
+		}
+		score := i.federationScore(item, weight)
+		if score <= 0 {
+			continue
+		}
+		seen[item.ID] = true
+
+		v, ok := i.Map.Load(item.ID)
+		if !ok {
+			v = &BanListInfoItem{
+				ID:      item.ID,
+				Name:    item.Name,
+				Reasons: []string{},
+				Places:  []string{},
+			}
+		}
+		if v.Rank == BanRankTrusted {
+			continue
+		}
+		if v.Sources == nil {
+			v.Sources = map[string]int64{}
+		}
+		old, existed := v.Sources[source]
+		if existed && old == score {
+			continue
+		}
+		v.Sources[source] = score
+		if !existed {
+			v.appendHistory("订阅:"+source, strings.Join(item.Reasons, ",")+"（来自订阅）", now)
+		}
+		v.UpdatedAt = now
+		if i.applyFederationRank(v, source, item, now) {
+			changed++
+		}
+		i.Map.Store(item.ID, v)
+	}
+
+	i.Map.Range(func(k string, v *BanListInfoItem) bool {
+		if _, ok := v.Sources[source]; !ok || seen[k] {
+			return true
+		}
+		delete(v.Sources, source)
+		v.UpdatedAt = now
+		if i.applyFederationRank(v, source, nil, now) {
+			changed++
+		}
+		return true
+	})
+	return changed
+}
+
+// FetchBanListExchange 拉取并校验远端名单
+func FetchBanListExchange(ctx context.Context, client *http.Client, url string, pinnedKey string) (*BanListExchange, *BanListExchangePayload, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("远端返回状态码 %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, banExchangeMaxSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	var ex BanListExchange
+	if err = json.Unmarshal(data, &ex); err != nil {
+		return nil, nil, err
+	}
+	payload, err := ex.Verify(pinnedKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &ex, payload, nil
+}
+
+// SyncSubscription 同步单个订阅，首次同步时记录对方公钥
+func (i *BanListInfo) SyncSubscription(ctx context.Context, sub *BanFederationSubscription) (int, error) {
+	i.federationMu.Lock()
+	url, pinnedKey, weight := sub.URL, sub.PublicKey, sub.weight()
+	i.federationMu.Unlock()
+
+	ex, payload, err := FetchBanListExchange(ctx, banFederationHTTPClient, url, pinnedKey)
+	now := time.Now().Unix()
+
+	i.federationMu.Lock()
+	sub.LastSyncAt = now
+	if err != nil {
+		sub.LastError = err.Error()
+		i.federationMu.Unlock()
+		return 0, err
+	}
+	if sub.PublicKey == "" {
+		sub.PublicKey = ex.PublicKey
+	}
+	sub.LastError = ""
+	sub.LastCount = len(payload.Items)
+	i.federationMu.Unlock()
+
+	return i.MergeFederation(sub.Name, payload, weight, now), nil
+}
+
+// SyncFederation 同步订阅。force 为 false 时只同步到期的订阅
+func (i *BanListInfo) SyncFederation(force bool) {
+	d := i.Parent
+	now := time.Now()
+	interval := i.FederationSyncInterval()
+
+	var due []*BanFederationSubscription
+	i.federationMu.Lock()
+	if i.federationSyncing {
+		i.federationMu.Unlock()
+		return
+	}
+	for _, sub := range i.FederationSubscriptions {
+		if !sub.Enable || sub.URL == "" || sub.Name == "" {
+			continue
+		}
+		if force || now.Sub(time.Unix(sub.LastSyncAt, 0)) >= interval {
+			due = append(due, sub)
+		}
+	}
+	if len(due) == 0 {
+		i.federationMu.Unlock()
+		return
+	}
+	i.federationSyncing = true
+	i.federationMu.Unlock()
+
+	defer func() {
+		i.federationMu.Lock()
+		i.federationSyncing = false
+		i.federationMu.Unlock()
+	}()
+
+	for _, sub := range due {
+		changed, err := i.SyncSubscription(context.Background(), sub)
+		if err != nil {
+			d.Logger.Warnf("同步黑名单订阅<%s>失败: %v", sub.Name, err)
+			continue
+		}
+		d.Logger.Infof("同步黑名单订阅<%s>完成，%d 个条目等级变化", sub.Name, changed)
+	}
+	i.SaveChanged(d)
+	d.MarkModified()
+}
+
+// SetFederationSubscriptions 替换订阅列表，保留同名订阅的同步状态
+func (i *BanListInfo) SetFederationSubscriptions(subs []*BanFederationSubscription) {
+	i.federationMu.Lock()
+	defer i.federationMu.Unlock()
+
+	old := map[string]*BanFederationSubscription{}
+	for _, sub := range i.FederationSubscriptions {
+		old[sub.Name] = sub
+	}
+	for _, sub := range subs {
+		if o, ok := old[sub.Name]; ok && o.URL == sub.URL {
+			sub.LastSyncAt = o.LastSyncAt
+			sub.LastError = o.LastError
+			sub.LastCount = o.LastCount
+			if sub.PublicKey == "" {
+				sub.PublicKey = o.PublicKey
+			}
+		}
+	}
+	i.FederationSubscriptions = subs
+}
+
+// RemoveFederationSource 撤销某个订阅的全部分数，用于删除订阅后清理
+func (i *BanListInfo) RemoveFederationSource(source string) int {
+	return i.MergeFederation(source, &BanListExchangePayload{}, 1, time.Now().Unix())
+}
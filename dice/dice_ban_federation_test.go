@@ -0,0 +1,160 @@
+package dice_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"sealdice-core/dice"
+)
+
+func newFederationKey(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// federationServer 模拟另一台骰子的导出接口
+type federationServer struct {
+	mu    sync.Mutex
+	key   string
+	items []*dice.BanListExchangeItem
+}
+
+func (s *federationServer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ex, err := dice.SignBanListExchange(&dice.BanListExchangePayload{Source: "remote", Items: s.items}, s.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(ex)
+}
+
+func newFederationBanList() *dice.BanListInfo {
+	banList := &dice.BanListInfo{}
+	banList.Init()
+	return banList
+}
+
+func TestBanListExchangeVerify(t *testing.T) {
+	ex, err := dice.SignBanListExchange(&dice.BanListExchangePayload{
+		Source: "remote",
+		Items:  []*dice.BanListExchangeItem{{ID: "QQ:1", Rank: dice.BanRankBanned, Score: 200}},
+	}, newFederationKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新格式化后签名仍然有效
+	pretty, _ := json.MarshalIndent(ex, "", "  ")
+	var loaded dice.BanListExchange
+	if err = json.Unmarshal(pretty, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := loaded.Verify(ex.PublicKey)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if payload.Source != "remote" || len(payload.Items) != 1 {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+
+	tampered := loaded
+	tampered.Payload = []byte(`{"version":1,"source":"remote","createdAt":0,"items":[]}`)
+	if _, err = tampered.Verify(""); !errors.Is(err, dice.ErrBanExchangeBadSign) {
+		t.Fatalf("tampered payload should fail verification, got %v", err)
+	}
+
+	other, _ := dice.SignBanListExchange(&dice.BanListExchangePayload{Source: "other"}, newFederationKey(t))
+	if _, err = loaded.Verify(other.PublicKey); !errors.Is(err, dice.ErrBanExchangeKeyChanged) {
+		t.Fatalf("pinned key mismatch should fail, got %v", err)
+	}
+}
+
+func TestBanListSyncSubscription(t *testing.T) {
+	remote := &federationServer{key: newFederationKey(t), items: []*dice.BanListExchangeItem{
+		{ID: "QQ:1", Name: "甲", Rank: dice.BanRankBanned, Score: 200, Reasons: []string{"踢出骰子"}},
+		{ID: "QQ:2", Name: "乙", Rank: dice.BanRankBanned, Score: 200},
+		{ID: "QQ:3", Name: "丙", Rank: dice.BanRankBanned, Score: 200},
+	}}
+	server := httptest.NewServer(remote)
+	defer server.Close()
+
+	banList := newFederationBanList()
+	banList.Map.Store("QQ:3", &dice.BanListInfoItem{ID: "QQ:3", Rank: dice.BanRankTrusted})
+	sub := &dice.BanFederationSubscription{Name: "community", URL: server.URL, Weight: 0.5, Enable: true}
+
+	if _, err := banList.SyncSubscription(context.Background(), sub); err != nil {
+		t.Fatal(err)
+	}
+	if sub.PublicKey == "" || sub.LastCount != 3 {
+		t.Fatalf("subscription state not recorded: %+v", sub)
+	}
+	v, _ := banList.GetByID("QQ:1")
+	if v.Rank != dice.BanRankWarn || v.Sources["community"] != 100 {
+		t.Fatalf("half weight should only warn, got rank %d sources %v", v.Rank, v.Sources)
+	}
+	if v, _ := banList.GetByID("QQ:3"); v.Rank != dice.BanRankTrusted || len(v.Sources) != 0 {
+		t.Fatal("trusted entry should not be affected by subscriptions")
+	}
+
+	// 本地分数与订阅分数叠加后进入黑名单
+	v.Score = 100
+	remote.mu.Lock()
+	remote.items = remote.items[:1]
+	remote.items[0].Score = 300
+	remote.mu.Unlock()
+	if _, err := banList.SyncSubscription(context.Background(), sub); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := banList.GetByID("QQ:1"); v.Rank != dice.BanRankBanned || v.Sources["community"] != 150 {
+		t.Fatalf("combined score should ban, got rank %d sources %v", v.Rank, v.Sources)
+	}
+	if v, _ := banList.GetByID("QQ:2"); v.Rank != dice.BanRankNormal || len(v.Sources) != 0 {
+		t.Fatalf("entry removed upstream should be lifted, got rank %d sources %v", v.Rank, v.Sources)
+	}
+
+	// 对方更换密钥后拒绝同步
+	remote.mu.Lock()
+	remote.key = newFederationKey(t)
+	remote.mu.Unlock()
+	if _, err := banList.SyncSubscription(context.Background(), sub); !errors.Is(err, dice.ErrBanExchangeKeyChanged) {
+		t.Fatalf("key change should be rejected, got %v", err)
+	}
+	if sub.LastError == "" {
+		t.Fatal("sync error should be recorded")
+	}
+}
+
+func TestBanListFederationExportSkipsForwarded(t *testing.T) {
+	banList := newFederationBanList()
+	now := time.Now().Unix()
+	banList.MergeFederation("community", &dice.BanListExchangePayload{Items: []*dice.BanListExchangeItem{
+		{ID: "QQ:1", Rank: dice.BanRankBanned, Score: 200},
+	}}, 1, now)
+	banList.Map.Store("QQ:2", &dice.BanListInfoItem{ID: "QQ:2", Rank: dice.BanRankBanned, Score: 200})
+
+	payload := banList.FederationExportPayload("local")
+	if len(payload.Items) != 1 || payload.Items[0].ID != "QQ:2" {
+		t.Fatalf("only local entries should be exported, got %+v", payload.Items)
+	}
+}
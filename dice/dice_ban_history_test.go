@@ -0,0 +1,40 @@
+//nolint:testpackage
+package dice
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBanListItemAppendHistoryKeepsLatest(t *testing.T) {
+	v := &BanListInfoItem{ID: "QQ:1"}
+	for index := range banHistoryKeep + 5 {
+		v.appendHistory("QQ-Group:1", fmt.Sprintf("reason-%d", index), int64(index))
+	}
+	if len(v.Reasons) != banHistoryKeep || len(v.Places) != banHistoryKeep || len(v.Times) != banHistoryKeep {
+		t.Fatalf("history should be capped to %d, got %d/%d/%d", banHistoryKeep, len(v.Reasons), len(v.Places), len(v.Times))
+	}
+	if v.Reasons[0] != "reason-5" || v.Times[banHistoryKeep-1] != int64(banHistoryKeep+4) {
+		t.Fatalf("history should keep the latest entries, got %s ... %d", v.Reasons[0], v.Times[banHistoryKeep-1])
+	}
+}
+
+func TestBanListExpireBanned(t *testing.T) {
+	var banList BanListInfo
+	banList.Init()
+	banList.Map.Store("QQ:1", &BanListInfoItem{ID: "QQ:1", Rank: BanRankBanned, Score: 300, ExpireAt: 100})
+	banList.Map.Store("QQ:2", &BanListInfoItem{ID: "QQ:2", Rank: BanRankBanned, Score: 300, ExpireAt: 0})
+	banList.Map.Store("QQ:3", &BanListInfoItem{ID: "QQ:3", Rank: BanRankBanned, Score: 300, ExpireAt: 500})
+
+	banList.expireBanned(200)
+
+	if v, _ := banList.GetByID("QQ:1"); v.Rank != BanRankNormal || v.Score != 0 || v.ExpireAt != 0 || v.UpdatedAt != 200 {
+		t.Fatalf("expired entry should be reset, got %+v", v)
+	}
+	if v, _ := banList.GetByID("QQ:2"); v.Rank != BanRankBanned {
+		t.Fatal("permanent entry should not expire")
+	}
+	if v, _ := banList.GetByID("QQ:3"); v.Rank != BanRankBanned {
+		t.Fatal("entry before its expiry should stay banned")
+	}
+}
@@ -68,6 +68,7 @@ func (d *Dice) NewCensorManager() {
 			CaseSensitive:  d.Config.CensorCaseSensitive,
 			MatchPinyin:    d.Config.CensorMatchPinyin,
 			FilterRegexStr: d.Config.CensorFilterRegexStr,
+			Normalize:      d.Config.CensorNormalize,
 		},
 		DB: d.DBOperator,
 	}
@@ -98,6 +99,7 @@ func (cm *CensorManager) Load(d *Dice) {
 			fileInfo, e := cm.Censor.PreloadFile(path)
 			if e != nil {
 				log.Errorf("censor: unable to read %s, %v", path, e)
+				return nil
 			}
 			if cm.SensitiveWordsFiles == nil {
 				cm.SensitiveWordsFiles = make(map[string]*censor.WordFile)
@@ -117,8 +119,9 @@ func (cm *CensorManager) Check(ctx *MsgContext, msg *Message, checkContent strin
 	if cm.IsLoading {
 		return nil, errors.New("censor is loading")
 	}
-	res := cm.Censor.Check(checkContent)
+	res := cm.Parent.CensorPolicyOf(msg).Apply(cm.Censor.Check(checkContent))
 	if !ctx.Censored && res.HighestLevel > censor.Ignore {
+		metricCensorHits.With(censorLevelNames[res.HighestLevel]).Inc()
 		// 敏感词命中记录保存
 		service.CensorAppend(cm.DB, ctx.MessageType, msg.Sender.UserID, msg.GroupID, msg.Message, res.SensitiveWords, int(res.HighestLevel))
 	}
@@ -234,7 +237,8 @@ func (d *Dice) CensorMsg(mctx *MsgContext, msg *Message, checkContent string, se
 	if !ok {
 		d.Logger.Warn("Dice CenSor获取GroupInfo失败")
 	}
-	thresholds := d.Config.CensorThresholds
+	policy := d.CensorPolicyOf(msg)
+	thresholds := policy.Thresholds
 
 	// 保证按程度依次降低来处理
 	var tempLevels censor.Levels
@@ -252,7 +256,7 @@ func (d *Dice) CensorMsg(mctx *MsgContext, msg *Message, checkContent string, se
 			// 清空此用户该等级计数
 			service.CensorClearLevelCount(d.CensorManager.DB, msg.Sender.UserID, level)
 			// 该等级敏感词超过阈值，执行操作
-			handler := d.Config.CensorHandlers[level]
+			handler := policy.Handlers[level]
 			levelText := censor.LevelText[level]
 			if handler&(1<<SendWarning) != 0 {
 				tmplText := fmt.Sprintf("核心:拦截_警告内容_%s级", censor.LevelText[level])
@@ -285,22 +289,24 @@ func (d *Dice) CensorMsg(mctx *MsgContext, msg *Message, checkContent string, se
 			}
 			if handler&(1<<BanUser) != 0 {
 				// 拉黑用户
-				(&d.Config).BanList.AddScoreBase(
+				(&d.Config).BanList.AddScoreWithSource(
 					msg.Sender.UserID,
 					d.Config.BanList.ThresholdBan,
 					"敏感词审查",
 					"触发<"+levelText+">敏感词",
+					BanSource{Handler: BanHandlerCensor},
 					mctx,
 				)
 			}
 			if handler&(1<<BanGroup) != 0 {
 				// 拉黑群
 				if msg.MessageType == "group" {
-					(&d.Config).BanList.AddScoreBase(
+					(&d.Config).BanList.AddScoreWithSource(
 						msg.GroupID,
 						d.Config.BanList.ThresholdBan,
 						"敏感词审查",
 						"触发<"+levelText+">敏感词",
+						BanSource{Handler: BanHandlerCensor},
 						mctx,
 					)
 				}
@@ -308,17 +314,18 @@ func (d *Dice) CensorMsg(mctx *MsgContext, msg *Message, checkContent string, se
 			if handler&(1<<BanInviter) != 0 {
 				// 拉黑邀请人
 				if msg.MessageType == "group" {
-					(&d.Config).BanList.AddScoreBase(
+					(&d.Config).BanList.AddScoreWithSource(
 						groupInfo.InviteUserID,
 						d.Config.BanList.ThresholdBan,
 						"敏感词审查",
 						"触发<"+levelText+">敏感词",
+						BanSource{Handler: BanHandlerCensor},
 						mctx,
 					)
 				}
 			}
 			if handler&(1<<AddScore) != 0 {
-				score, ok := d.Config.CensorScores[level]
+				score, ok := policy.Scores[level]
 				if !ok {
 					score = 100
 				}
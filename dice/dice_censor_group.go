@@ -0,0 +1,404 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sealdice-core/dice/censor"
+)
+
+// GroupCensorProfile 群内的拦截策略，未设置的项沿用全局配置
+type GroupCensorProfile struct {
+	Disabled     bool                   `json:"disabled"`               // 本群关闭拦截
+	Mode         *CensorMode            `json:"mode,omitempty"`         // 输入拦截模式
+	FilterOutput *bool                  `json:"filterOutput,omitempty"` // 是否审查骰子的回复
+	MinLevel     censor.Level           `json:"minLevel"`               // 低于此级别的命中忽略
+	WordFiles    []string               `json:"wordFiles,omitempty"`    // 仅使用这些词库(文件名)，为空时使用全部
+	Thresholds   map[censor.Level]int   `json:"thresholds,omitempty"`
+	Handlers     map[censor.Level]uint8 `json:"handlers,omitempty"`
+	Scores       map[censor.Level]int   `json:"scores,omitempty"`
+}
+
+// CensorPolicy 合并全局配置与群策略后生效的拦截策略
+type CensorPolicy struct {
+	Enable       bool                   `json:"enable"`
+	Mode         CensorMode             `json:"mode"`
+	FilterOutput bool                   `json:"filterOutput"`
+	MinLevel     censor.Level           `json:"minLevel"`
+	WordFiles    []string               `json:"wordFiles"`
+	Thresholds   map[censor.Level]int   `json:"thresholds"`
+	Handlers     map[censor.Level]uint8 `json:"handlers"`
+	Scores       map[censor.Level]int   `json:"scores"`
+	FromGroup    bool                   `json:"fromGroup"` // 是否应用了群策略
+}
+
+var (
+	ErrCensorUnknownLevel   = errors.New("未知的拦截级别")
+	ErrCensorUnknownMode    = errors.New("未知的拦截模式")
+	ErrCensorUnknownHandler = errors.New("未知的处理方式")
+	ErrCensorBanMasterOnly  = errors.New("拉黑、怒气值和次数阈值会影响全局黑名单，仅 master 可以修改")
+)
+
+// censorBanHandlers 会写入全局黑名单的处理方式
+const censorBanHandlers uint8 = 1<<BanUser | 1<<BanGroup | 1<<BanInviter | 1<<AddScore
+
+var censorModeNames = map[string]CensorMode{
+	"output":  OnlyOutputReply,
+	"回复":      OnlyOutputReply,
+	"command": OnlyInputCommand,
+	"指令":      OnlyInputCommand,
+	"all":     AllInput,
+	"全部":      AllInput,
+}
+
+var CensorModeText = map[CensorMode]string{
+	OnlyOutputReply:  "仅审查回复",
+	OnlyInputCommand: "审查收到的指令",
+	AllInput:         "审查收到的所有消息",
+}
+
+// ParseCensorLevel 支持中文级别名与 notice/caution/warning/danger
+func ParseCensorLevel(s string) (censor.Level, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for level, text := range censor.LevelText {
+		if s == text {
+			return level, nil
+		}
+	}
+	switch s {
+	case "ignore":
+		return censor.Ignore, nil
+	case "notice":
+		return censor.Notice, nil
+	case "caution":
+		return censor.Caution, nil
+	case "warning":
+		return censor.Warning, nil
+	case "danger":
+		return censor.Danger, nil
+	}
+	return censor.Ignore, ErrCensorUnknownLevel
+}
+
+// ParseCensorHandlers 将处理方式名称转为位掩码，名称见 CensorHandlerText
+func ParseCensorHandlers(names []string) (uint8, error) {
+	var val uint8
+	for _, name := range names {
+		found := false
+		for handler, text := range CensorHandlerText {
+			if strings.EqualFold(name, text) {
+				val |= 1 << handler
+				found = true
+				break
+			}
+		}
+		if !found && name != "none" {
+			return 0, fmt.Errorf("%w: %s", ErrCensorUnknownHandler, name)
+		}
+	}
+	return val, nil
+}
+
+func censorHandlerNames(val uint8) []string {
+	var names []string
+	for handler := SendWarning; handler <= SendEncodedDetails; handler++ {
+		if val&(1<<handler) != 0 {
+			names = append(names, CensorHandlerText[handler])
+		}
+	}
+	return names
+}
+
+// CensorPolicyOf 计算消息所在会话生效的拦截策略，私聊使用全局配置
+func (d *Dice) CensorPolicyOf(msg *Message) *CensorPolicy {
+	var profile *GroupCensorProfile
+	if msg != nil && msg.GroupID != "" && d.ImSession != nil && d.ImSession.ServiceAtNew != nil {
+		if groupInfo, ok := d.ImSession.ServiceAtNew.Load(msg.GroupID); ok {
+			profile = groupInfo.CensorProfile
+		}
+	}
+	return d.Config.CensorPolicy(profile)
+}
+
+// CensorPolicy 以全局配置为基础，叠加群策略
+func (c *Config) CensorPolicy(profile *GroupCensorProfile) *CensorPolicy {
+	p := &CensorPolicy{
+		Enable:       c.EnableCensor,
+		Mode:         c.CensorMode,
+		FilterOutput: c.CensorMode == OnlyOutputReply,
+		Thresholds:   maps.Clone(c.CensorThresholds),
+		Handlers:     maps.Clone(c.CensorHandlers),
+		Scores:       maps.Clone(c.CensorScores),
+	}
+	if p.Thresholds == nil {
+		p.Thresholds = map[censor.Level]int{}
+	}
+	if p.Handlers == nil {
+		p.Handlers = map[censor.Level]uint8{}
+	}
+	if p.Scores == nil {
+		p.Scores = map[censor.Level]int{}
+	}
+	if profile == nil {
+		return p
+	}
+
+	p.FromGroup = true
+	if profile.Disabled {
+		p.Enable = false
+	}
+	if profile.Mode != nil {
+		p.Mode = *profile.Mode
+		p.FilterOutput = p.Mode == OnlyOutputReply
+	}
+	if profile.FilterOutput != nil {
+		p.FilterOutput = *profile.FilterOutput
+	}
+	p.MinLevel = profile.MinLevel
+	p.WordFiles = profile.WordFiles
+	maps.Copy(p.Thresholds, profile.Thresholds)
+	maps.Copy(p.Handlers, profile.Handlers)
+	maps.Copy(p.Scores, profile.Scores)
+	return p
+}
+
+// Active 判断某一环节是否需要审查，stage 为 OnlyOutputReply 时表示骰子的回复
+func (p *CensorPolicy) Active(stage CensorMode) bool {
+	if !p.Enable {
+		return false
+	}
+	if stage == OnlyOutputReply {
+		return p.FilterOutput
+	}
+	return p.Mode == stage
+}
+
+// Apply 按级别与词库过滤命中结果
+func (p *CensorPolicy) Apply(res censor.CheckResult) censor.CheckResult {
+	if p.MinLevel <= censor.Ignore && len(p.WordFiles) == 0 {
+		return res
+	}
+	files := map[string]bool{}
+	for _, f := range p.WordFiles {
+		files[f] = true
+	}
+	return res.Filter(func(_ string, info censor.WordInfo) bool {
+		if info.Level < p.MinLevel {
+			return false
+		}
+		return len(files) == 0 || info.InFiles(files)
+	})
+}
+
+// CensorActive 当前消息是否需要在该环节审查
+func (d *Dice) CensorActive(msg *Message, stage CensorMode) bool {
+	if !d.Config.EnableCensor {
+		return false
+	}
+	return d.CensorPolicyOf(msg).Active(stage)
+}
+
+func (p *CensorPolicy) toText() string {
+	if !p.Enable {
+		return "本群已关闭敏感词拦截"
+	}
+	var sb strings.Builder
+	source := "全局配置"
+	if p.FromGroup {
+		source = "本群策略"
+	}
+	output := "否"
+	if p.FilterOutput {
+		output = "是"
+	}
+	fmt.Fprintf(&sb, "当前拦截策略(%s):\n模式: %s\n审查回复: %s", source, CensorModeText[p.Mode], output)
+	if p.MinLevel > censor.Ignore {
+		fmt.Fprintf(&sb, "\n忽略低于<%s>级的命中", censor.LevelText[p.MinLevel])
+	}
+	if len(p.WordFiles) > 0 {
+		fmt.Fprintf(&sb, "\n词库: %s", strings.Join(p.WordFiles, ", "))
+	}
+	for level := censor.Notice; level <= censor.Danger; level++ {
+		handlers := censorHandlerNames(p.Handlers[level])
+		if len(handlers) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n<%s>级: 阈值%d 怒气值%d 处理%s", censor.LevelText[level], p.Thresholds[level], p.Scores[level], strings.Join(handlers, "/"))
+	}
+	return sb.String()
+}
+
+const helpForCensor = ".censor // 查看本群生效的拦截策略\n" +
+	".censor on/off // 本群开启/关闭拦截\n" +
+	".censor mode <回复|指令|全部> // 设置输入拦截模式\n" +
+	".censor output on/off // 是否审查骰子的回复\n" +
+	".censor level <提醒|注意|警告|危险> // 忽略低于该级别的命中\n" +
+	".censor files <词库文件名>... // 仅使用指定词库，all 为全部\n" +
+	".censor handler <级别> <处理方式>... // 如 SendWarning BanUser，none 为不处理\n" +
+	".censor threshold <级别> <次数> // 触发处理的次数阈值\n" +
+	".censor score <级别> <怒气值> // AddScore 增加的怒气值\n" +
+	".censor reset // 恢复使用全局配置\n" +
+	"修改需要群管理以上权限，其中拉黑类处理方式、threshold 和 score 仅 master 可修改"
+
+// censorCheckBanPrivilege 群管理只能设置警告、通知之类的处理，会影响全局黑名单的设置需要 master
+func censorCheckBanPrivilege(ctx *MsgContext, sub string, handlers uint8) error {
+	if ctx.PrivilegeLevel >= 100 {
+		return nil
+	}
+	if sub == "threshold" || sub == "score" || handlers&censorBanHandlers != 0 {
+		return ErrCensorBanMasterOnly
+	}
+	return nil
+}
+
+// censorModifyProfile 修改群策略，不存在时新建
+func censorModifyProfile(ctx *MsgContext, fn func(p *GroupCensorProfile) error) error {
+	p := ctx.Group.CensorProfile
+	if p == nil {
+		p = &GroupCensorProfile{}
+	} else {
+		cp := *p
+		cp.WordFiles = slices.Clone(p.WordFiles)
+		cp.Thresholds = maps.Clone(p.Thresholds)
+		cp.Handlers = maps.Clone(p.Handlers)
+		cp.Scores = maps.Clone(p.Scores)
+		p = &cp
+	}
+	if err := fn(p); err != nil {
+		return err
+	}
+	ctx.Group.CensorProfile = p
+	ctx.Group.MarkDirty(ctx.Dice)
+	return nil
+}
+
+func cmdCensorSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+	if ctx.IsPrivate {
+		ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_私聊不可用"))
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+
+	sub := strings.ToLower(cmdArgs.GetArgN(1))
+	if sub == "" || sub == "show" {
+		ReplyToSender(ctx, msg, ctx.Dice.CensorPolicyOf(msg).toText())
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+	if sub == "help" {
+		return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+	}
+	if ctx.PrivilegeLevel < 50 {
+		ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理"))
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+
+	arg2, arg3 := cmdArgs.GetArgN(2), cmdArgs.GetArgN(3)
+	var err error
+	switch sub {
+	case "on", "off":
+		err = censorModifyProfile(ctx, func(p *GroupCensorProfile) error {
+			p.Disabled = sub == "off"
+			return nil
+		})
+	case "mode":
+		mode, ok := censorModeNames[strings.ToLower(arg2)]
+		if !ok {
+			err = ErrCensorUnknownMode
+			break
+		}
+		err = censorModifyProfile(ctx, func(p *GroupCensorProfile) error {
+			p.Mode = &mode
+			return nil
+		})
+	case "output":
+		if arg2 != "on" && arg2 != "off" {
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		on := arg2 == "on"
+		err = censorModifyProfile(ctx, func(p *GroupCensorProfile) error {
+			p.FilterOutput = &on
+			return nil
+		})
+	case "level":
+		var level censor.Level
+		if level, err = ParseCensorLevel(arg2); err != nil {
+			break
+		}
+		err = censorModifyProfile(ctx, func(p *GroupCensorProfile) error {
+			p.MinLevel = level
+			return nil
+		})
+	case "files":
+		files := cmdArgs.Args[1:]
+		if len(files) == 0 {
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		if len(files) == 1 && strings.EqualFold(files[0], "all") {
+			files = nil
+		}
+		err = censorModifyProfile(ctx, func(p *GroupCensorProfile) error {
+			p.WordFiles = slices.Clone(files)
+			return nil
+		})
+	case "handler":
+		var level censor.Level
+		var val uint8
+		if level, err = ParseCensorLevel(arg2); err != nil {
+			break
+		}
+		if len(cmdArgs.Args) < 3 {
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		if val, err = ParseCensorHandlers(cmdArgs.Args[2:]); err != nil {
+			break
+		}
+		if err = censorCheckBanPrivilege(ctx, sub, val); err != nil {
+			break
+		}
+		err = censorModifyProfile(ctx, func(p *GroupCensorProfile) error {
+			if p.Handlers == nil {
+				p.Handlers = map[censor.Level]uint8{}
+			}
+			p.Handlers[level] = val
+			return nil
+		})
+	case "threshold", "score":
+		var level censor.Level
+		var n int
+		if level, err = ParseCensorLevel(arg2); err != nil {
+			break
+		}
+		if n, err = strconv.Atoi(arg3); err != nil || n < 0 {
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		if err = censorCheckBanPrivilege(ctx, sub, 0); err != nil {
+			break
+		}
+		err = censorModifyProfile(ctx, func(p *GroupCensorProfile) error {
+			target := &p.Thresholds
+			if sub == "score" {
+				target = &p.Scores
+			}
+			if *target == nil {
+				*target = map[censor.Level]int{}
+			}
+			(*target)[level] = n
+			return nil
+		})
+	case "reset":
+		ctx.Group.CensorProfile = nil
+		ctx.Group.MarkDirty(ctx.Dice)
+	default:
+		return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+	}
+
+	if err != nil {
+		ReplyToSender(ctx, msg, "设置失败: "+err.Error())
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+	ReplyToSender(ctx, msg, "已更新。"+ctx.Dice.CensorPolicyOf(msg).toText())
+	return CmdExecuteResult{Matched: true, Solved: true}
+}
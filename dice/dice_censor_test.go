@@ -2,9 +2,12 @@ package dice //nolint:testpackage // Tests the unexported message formatter dire
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+
+	"sealdice-core/dice/censor"
 )
 
 func TestFormatCensorHitDetailsEncodesWordsAndContext(t *testing.T) {
@@ -67,3 +70,101 @@ func TestCensorHitContextOmitsLongContentWithoutDirectHit(t *testing.T) {
 		t.Fatalf("context without a direct hit = %q, want omission marker", got)
 	}
 }
+
+func TestCensorPolicyMergesGroupProfile(t *testing.T) {
+	config := &Config{}
+	config.EnableCensor = true
+	config.CensorMode = OnlyInputCommand
+	config.CensorThresholds = map[censor.Level]int{censor.Warning: 3, censor.Danger: 1}
+	config.CensorHandlers = map[censor.Level]uint8{censor.Danger: 1 << BanUser}
+
+	global := config.CensorPolicy(nil)
+	if !global.Active(OnlyInputCommand) || global.Active(OnlyOutputReply) || global.FromGroup {
+		t.Fatalf("unexpected global policy: %+v", global)
+	}
+
+	mode := AllInput
+	output := true
+	profile := &GroupCensorProfile{
+		Mode:         &mode,
+		FilterOutput: &output,
+		Thresholds:   map[censor.Level]int{censor.Warning: 10},
+		Handlers:     map[censor.Level]uint8{censor.Danger: 1 << SendWarning},
+	}
+	p := config.CensorPolicy(profile)
+	if !p.Active(AllInput) || !p.Active(OnlyOutputReply) || p.Active(OnlyInputCommand) {
+		t.Fatalf("group mode not applied: %+v", p)
+	}
+	if p.Thresholds[censor.Warning] != 10 || p.Thresholds[censor.Danger] != 1 {
+		t.Fatalf("thresholds should be merged per level, got %v", p.Thresholds)
+	}
+	if p.Handlers[censor.Danger] != 1<<SendWarning {
+		t.Fatalf("group handler should override global, got %v", p.Handlers)
+	}
+	if config.CensorThresholds[censor.Warning] != 3 {
+		t.Fatal("global config must not be modified")
+	}
+
+	profile.Disabled = true
+	if config.CensorPolicy(profile).Active(AllInput) {
+		t.Fatal("disabled profile should turn censor off")
+	}
+}
+
+func TestCensorPolicyApplyFiltersHits(t *testing.T) {
+	res := censor.CheckResult{
+		HighestLevel:   censor.Danger,
+		SensitiveWords: map[string]censor.Level{"a": censor.Notice, "b": censor.Danger, "c": censor.Warning},
+		Hits: map[string]censor.WordInfo{
+			"a": {Level: censor.Notice, Files: []string{"common.txt"}},
+			"b": {Level: censor.Danger, Files: []string{"adult.toml"}},
+			"c": {Level: censor.Warning, Files: []string{"common.txt"}},
+		},
+	}
+
+	p := &CensorPolicy{MinLevel: censor.Caution, WordFiles: []string{"common.txt"}}
+	got := p.Apply(res)
+	if len(got.SensitiveWords) != 1 || got.HighestLevel != censor.Warning {
+		t.Fatalf("expected only c to remain, got %+v", got.SensitiveWords)
+	}
+
+	if got = (&CensorPolicy{}).Apply(res); len(got.SensitiveWords) != 3 {
+		t.Fatal("empty policy should keep all hits")
+	}
+}
+
+func TestParseCensorHandlers(t *testing.T) {
+	val, err := ParseCensorHandlers([]string{"sendwarning", "BanUser"})
+	if err != nil || val != 1<<SendWarning|1<<BanUser {
+		t.Fatalf("unexpected result %b %v", val, err)
+	}
+	if _, err = ParseCensorHandlers([]string{"explode"}); err == nil {
+		t.Fatal("unknown handler should fail")
+	}
+	if level, err := ParseCensorLevel("危险"); err != nil || level != censor.Danger {
+		t.Fatalf("unexpected level %v %v", level, err)
+	}
+}
+
+func TestCensorCheckBanPrivilege(t *testing.T) {
+	admin := &MsgContext{PrivilegeLevel: 50}
+	if err := censorCheckBanPrivilege(admin, "handler", 1<<SendWarning|1<<SendEncodedDetails); err != nil {
+		t.Fatalf("group admin should set warning handlers, got %v", err)
+	}
+	for _, tc := range []struct {
+		sub      string
+		handlers uint8
+	}{
+		{"handler", 1 << BanUser},
+		{"handler", 1<<SendWarning | 1<<AddScore},
+		{"threshold", 0},
+		{"score", 0},
+	} {
+		if err := censorCheckBanPrivilege(admin, tc.sub, tc.handlers); !errors.Is(err, ErrCensorBanMasterOnly) {
+			t.Fatalf("group admin should not set %s %b, got %v", tc.sub, tc.handlers, err)
+		}
+	}
+	if err := censorCheckBanPrivilege(&MsgContext{PrivilegeLevel: 100}, "handler", 1<<BanUser); err != nil {
+		t.Fatalf("master should set ban handlers, got %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package dice
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	ds "github.com/sealdice/dicescript"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+)
+
+var (
+	ErrCharacterNotFound   = errors.New("角色卡不存在")
+	ErrCharacterNameEmpty  = errors.New("角色名不能为空")
+	ErrCharacterNameExists = errors.New("同名角色卡已存在")
+	ErrCharacterNotBound   = errors.New("角色卡并未绑定到该群")
+)
+
+// 网页编辑时使用的虚拟群，只用于求值，不会写入任何数据
+const characterEditorGroupID = "UI-Character"
+
+// CharacterSheet 角色卡内容，同时也是导入导出的 JSON 格式
+type CharacterSheet struct {
+	ID        string         `json:"id,omitempty"`
+	Name      string         `json:"name"`
+	SheetType string         `json:"sheetType"`
+	OwnerID   string         `json:"ownerId,omitempty"`
+	Groups    []string       `json:"groups,omitempty"`   // 绑定的群
+	Attrs     map[string]any `json:"attrs"`              // 卡上的属性，已按模板归并别名
+	Defaults  map[string]any `json:"defaults,omitempty"` // 卡上没有的属性在模板中的默认值
+}
+
+// CharacterAttrValue 单个属性的读取结果
+type CharacterAttrValue struct {
+	Key       string `json:"key"` // 模板中的标准名
+	Value     any    `json:"value"`
+	Detail    string `json:"detail,omitempty"`
+	IsDefault bool   `json:"isDefault"` // 卡上没有该属性，取自模板默认值
+	Computed  bool   `json:"computed"`  // 默认值由表达式计算得出
+}
+
+// characterLoad 按ID载入角色卡，只接受 character 类型，返回的对象与指令共用同一份缓存
+func (d *Dice) characterLoad(id string) (*AttributesItem, *model.AttributesItemModel, error) {
+	if id == "" {
+		return nil, nil, ErrCharacterNotFound
+	}
+	item, err := service.AttrsGetById(d.DBOperator, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !item.IsDataExists() || item.AttrsType != service.AttrsTypeCharacter {
+		return nil, nil, ErrCharacterNotFound
+	}
+	attrs, err := d.AttrsManager.LoadById(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attrs, item, nil
+}
+
+// characterCtx 构造一个以该角色卡为当前卡的上下文，用于计算模板中的默认值
+func (d *Dice) characterCtx(attrs *AttributesItem, ownerId string) *MsgContext {
+	system := attrs.SheetType
+	if system == "" {
+		system = "coc7"
+	}
+	group := &GroupInfo{GroupID: characterEditorGroupID, System: system}
+	ctx := &MsgContext{
+		MessageType:   "group",
+		Dice:          d,
+		Session:       d.ImSession,
+		EndPoint:      d.UIEndpoint,
+		Group:         group,
+		Player:        &GroupPlayerInfo{UserID: ownerId, Name: attrs.Name},
+		AttrsOverride: attrs,
+	}
+	ctx.SystemTemplate = group.GetCharTemplate(d)
+	return ctx
+}
+
+func (d *Dice) characterSheetOf(attrs *AttributesItem, item *model.AttributesItemModel, withDefaults bool) *CharacterSheet {
+	ctx := d.characterCtx(attrs, item.OwnerId)
+	sheet := &CharacterSheet{
+		ID:        item.Id,
+		Name:      attrs.Name,
+		SheetType: attrs.SheetType,
+		OwnerID:   item.OwnerId,
+		Groups:    d.AttrsManager.CharGetBindingGroupIdList(item.Id),
+		Attrs: buildSealChatCharacterOutputAttrs(&sealChatCharacterTarget{
+			Attrs:    attrs,
+			Ctx:      ctx,
+			Template: ctx.SystemTemplate,
+		}),
+	}
+	if !withDefaults || ctx.SystemTemplate.GameSystemTemplateV2 == nil {
+		return sheet
+	}
+
+	tmplAttrs := ctx.SystemTemplate.Attrs
+	keys := make([]string, 0, len(tmplAttrs.Defaults)+len(tmplAttrs.DefaultsComputed))
+	for k := range tmplAttrs.Defaults {
+		keys = append(keys, k)
+	}
+	for k := range tmplAttrs.DefaultsComputed {
+		if _, exists := tmplAttrs.Defaults[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	sheet.Defaults = map[string]any{}
+	for _, k := range keys {
+		if _, exists := sheet.Attrs[k]; exists {
+			continue
+		}
+		v, _, _, exists := ctx.SystemTemplate.GetDefaultValueEx0(ctx, k)
+		if exists {
+			sheet.Defaults[k] = vmValueToAny(v)
+		}
+	}
+	return sheet
+}
+
+// CharacterGet 读取角色卡，withDefaults 为真时附带模板默认值
+func (d *Dice) CharacterGet(id string, withDefaults bool) (*CharacterSheet, error) {
+	attrs, item, err := d.characterLoad(id)
+	if err != nil {
+		return nil, err
+	}
+	return d.characterSheetOf(attrs, item, withDefaults), nil
+}
+
+// CharacterAttrGet 读取单个属性，key 可以是模板中的任意别名，卡上没有时返回模板默认值
+func (d *Dice) CharacterAttrGet(id string, key string) (*CharacterAttrValue, error) {
+	attrs, item, err := d.characterLoad(id)
+	if err != nil {
+		return nil, err
+	}
+	ctx := d.characterCtx(attrs, item.OwnerId)
+	tmpl := ctx.SystemTemplate
+	canonical, err := resolveSealChatCharacterAttrKey(key, tmpl)
+	if err != nil {
+		return nil, err
+	}
+	if canonical == "" {
+		return nil, errors.New("属性名不能为空")
+	}
+
+	if v, exists := findSealChatExistingCanonicalAttrValue(attrs, canonical, tmpl); exists {
+		return &CharacterAttrValue{Key: canonical, Value: vmValueToAny(v)}, nil
+	}
+	v, detail, computed, exists := tmpl.GetDefaultValueEx0(ctx, canonical)
+	if !exists {
+		return &CharacterAttrValue{Key: canonical, IsDefault: true}, nil
+	}
+	return &CharacterAttrValue{Key: canonical, Value: vmValueToAny(v), Detail: detail, IsDefault: true, Computed: computed}, nil
+}
+
+// CharacterAttrsSet 写入属性，别名会归并到模板中的标准名，卡上残留的别名键一并清理
+func (d *Dice) CharacterAttrsSet(id string, data map[string]any) (*CharacterSheet, error) {
+	attrs, item, err := d.characterLoad(id)
+	if err != nil {
+		return nil, err
+	}
+	ctx := d.characterCtx(attrs, item.OwnerId)
+	normalized, err := normalizeSealChatCharacterAttrs(data, ctx.SystemTemplate, attrs)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range normalized {
+		attrs.Store(k, v)
+	}
+	cleanupSealChatCharacterAliasKeys(attrs, ctx.SystemTemplate, normalized)
+	return d.characterSheetOf(attrs, item, false), nil
+}
+
+// CharacterAttrsDelete 删除属性，返回实际删除的键
+func (d *Dice) CharacterAttrsDelete(id string, keys []string) ([]string, error) {
+	attrs, item, err := d.characterLoad(id)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := d.characterCtx(attrs, item.OwnerId).SystemTemplate
+	targets := map[string]struct{}{}
+	for _, key := range keys {
+		canonical, resolveErr := resolveSealChatCharacterAttrKey(key, tmpl)
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+		if canonical != "" {
+			targets[canonical] = struct{}{}
+		}
+	}
+
+	var deleted []string
+	attrs.Range(func(key string, _ *ds.VMValue) bool {
+		canonical, resolveErr := resolveSealChatCharacterAttrKey(key, tmpl)
+		if resolveErr != nil {
+			canonical = key
+		}
+		if _, ok := targets[canonical]; ok {
+			deleted = append(deleted, key)
+		}
+		return true
+	})
+	for _, key := range deleted {
+		attrs.Delete(key)
+	}
+	if len(deleted) > 0 {
+		attrs.SetModified()
+	}
+	sort.Strings(deleted)
+	return deleted, nil
+}
+
+// CharacterBind 将角色卡绑定到群，之后角色卡主人在该群使用这张卡
+func (d *Dice) CharacterBind(id string, groupId string) error {
+	_, item, err := d.characterLoad(id)
+	if err != nil {
+		return err
+	}
+	if groupId == "" {
+		return errors.New("群号不能为空")
+	}
+	return d.AttrsManager.CharBind(id, groupId, item.OwnerId)
+}
+
+// CharacterUnbind 解除角色卡在群内的绑定
+func (d *Dice) CharacterUnbind(id string, groupId string) error {
+	_, item, err := d.characterLoad(id)
+	if err != nil {
+		return err
+	}
+	am := d.AttrsManager
+	if cur, _ := am.CharGetBindingId(groupId, item.OwnerId); cur != id {
+		return ErrCharacterNotBound
+	}
+	return am.CharBind("", groupId, item.OwnerId)
+}
+
+// CharacterImport 为 userId 导入角色卡。同名卡存在时，overwrite 为真则清空后写入，否则报错
+func (d *Dice) CharacterImport(userId string, sheet *CharacterSheet, overwrite bool) (*CharacterSheet, error) {
+	name := strings.TrimSpace(sheet.Name)
+	if name == "" {
+		return nil, ErrCharacterNameEmpty
+	}
+	am := d.AttrsManager
+	id, err := am.CharIdGetByName(userId, name)
+	if err != nil {
+		return nil, err
+	}
+	if id != "" && !overwrite {
+		return nil, ErrCharacterNameExists
+	}
+	if id == "" {
+		item, newErr := am.CharNew(userId, name, sheet.SheetType)
+		if newErr != nil {
+			return nil, newErr
+		}
+		id = item.Id
+	}
+
+	attrs, item, err := d.characterLoad(id)
+	if err != nil {
+		return nil, err
+	}
+	if sheet.SheetType != "" {
+		attrs.SetSheetType(sheet.SheetType)
+	}
+	ctx := d.characterCtx(attrs, item.OwnerId)
+	normalized, err := normalizeSealChatCharacterAttrs(sheet.Attrs, ctx.SystemTemplate, nil)
+	if err != nil {
+		return nil, err
+	}
+	attrs.Clear()
+	for k, v := range normalized {
+		attrs.Store(k, v)
+	}
+	return d.characterSheetOf(attrs, item, false), nil
+}
@@ -0,0 +1,88 @@
+//nolint:testpackage
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"sealdice-core/model"
+)
+
+func TestCharacterEditor(t *testing.T) {
+	d, _, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	am := d.AttrsManager
+	if err := d.DBOperator.(*mockDatabaseOperator).db.AutoMigrate(&model.AttributesItemModel{}); err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := am.CharNew("QQ:1", "阿尔", "coc7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 通过别名写入，归并为标准名
+	sheet, err := d.CharacterAttrsSet(item.Id, map[string]any{"dex": float64(60), "力量": float64(50)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(sheet.Attrs["敏捷"]) != "60" || sheet.Attrs["dex"] != nil {
+		t.Fatalf("alias should be stored as canonical key, got %v", sheet.Attrs)
+	}
+	// 修改直接反映在指令使用的缓存中
+	attrs, _ := am.LoadById(item.Id)
+	if v, ok := attrs.LoadX("敏捷"); !ok || v.ToString() != "60" {
+		t.Fatal("edit should go through the shared cache")
+	}
+
+	v, err := d.CharacterAttrGet(item.Id, "DEX")
+	if err != nil || v.Key != "敏捷" || v.IsDefault {
+		t.Fatalf("unexpected attr %+v, err %v", v, err)
+	}
+	v, err = d.CharacterAttrGet(item.Id, "躲闪")
+	if err != nil || v.Key != "闪避" || !v.IsDefault || !v.Computed || fmt.Sprint(v.Value) != "30" {
+		t.Fatalf("computed default should be evaluated against the card, got %+v, err %v", v, err)
+	}
+
+	sheet, _ = d.CharacterGet(item.Id, true)
+	if fmt.Sprint(sheet.Defaults["闪避"]) != "30" || sheet.Defaults["敏捷"] != nil {
+		t.Fatalf("unexpected defaults %v", sheet.Defaults)
+	}
+
+	deleted, err := d.CharacterAttrsDelete(item.Id, []string{"STR"})
+	if err != nil || len(deleted) != 1 || deleted[0] != "力量" {
+		t.Fatalf("unexpected deleted %v, err %v", deleted, err)
+	}
+
+	if err = d.CharacterBind(item.Id, "QQ-Group:2"); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := am.CharGetBindingId("QQ-Group:2", "QQ:1"); id != item.Id {
+		t.Fatal("character should be bound")
+	}
+	if err = d.CharacterUnbind(item.Id, "QQ-Group:3"); !errors.Is(err, ErrCharacterNotBound) {
+		t.Fatalf("unbinding an unbound group should fail, got %v", err)
+	}
+	if err = d.CharacterUnbind(item.Id, "QQ-Group:2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 导出后导入到另一个用户
+	exported, _ := d.CharacterGet(item.Id, false)
+	imported, err := d.CharacterImport("QQ:2", exported, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.ID == item.Id || imported.OwnerID != "QQ:2" || fmt.Sprint(imported.Attrs["敏捷"]) != "60" {
+		t.Fatalf("unexpected imported sheet %+v", imported)
+	}
+	if _, err = d.CharacterImport("QQ:2", exported, false); !errors.Is(err, ErrCharacterNameExists) {
+		t.Fatalf("duplicate import should fail, got %v", err)
+	}
+
+	// 非角色卡不能通过编辑器访问
+	if _, err = d.CharacterGet("QQ-Group:2-QQ:1", false); !errors.Is(err, ErrCharacterNotFound) {
+		t.Fatalf("group card should not be editable, got %v", err)
+	}
+}
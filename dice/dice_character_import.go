@@ -0,0 +1,251 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"sealdice-core/message"
+)
+
+var (
+	ErrSheetFormatUnknown   = errors.New("无法识别的角色卡格式")
+	ErrSheetNoFields        = errors.New("角色卡中没有可导入的属性")
+	ErrSheetTemplateMissing = errors.New("找不到对应的规则模板")
+	ErrSheetFileMissing     = errors.New("没有找到附带的文件，请在同一条消息中发送角色卡文件")
+	ErrSheetFileTooLarge    = errors.New("角色卡文件过大")
+)
+
+// 角色卡文件的大小上限
+const maxSheetFileSize = 5 << 20
+
+// ExternalSheetField 外部角色卡中的一项，Value 为 int64、float64 或 string
+type ExternalSheetField struct {
+	Key   string
+	Value any
+}
+
+// ExternalSheet 解析器的输出，字段保持原始顺序
+type ExternalSheet struct {
+	Name      string
+	SheetType string // 能从文件中判断出规则时填写
+	Fields    []ExternalSheetField
+}
+
+// Add 添加一项，空键忽略
+func (s *ExternalSheet) Add(key string, value any) {
+	key = strings.TrimSpace(key)
+	if key == "" || value == nil {
+		return
+	}
+	s.Fields = append(s.Fields, ExternalSheetField{Key: key, Value: value})
+}
+
+// SheetParser 外部角色卡格式的解析器
+type SheetParser interface {
+	Name() string
+	// Detect 判断数据是否为该格式，filename 可能为空
+	Detect(filename string, data []byte) bool
+	Parse(data []byte) (*ExternalSheet, error)
+}
+
+var (
+	sheetParsersLock sync.RWMutex
+	sheetParsers     = []SheetParser{
+		foundrySheetParser{},
+		ddbSheetParser{},
+		jsonSheetParser{},
+		xlsxSheetParser{},
+		csvSheetParser{},
+	}
+)
+
+// RegisterSheetParser 注册角色卡解析器，后注册的优先尝试
+func RegisterSheetParser(p SheetParser) {
+	sheetParsersLock.Lock()
+	defer sheetParsersLock.Unlock()
+	sheetParsers = append([]SheetParser{p}, sheetParsers...)
+}
+
+// ParseExternalSheet 依次尝试各解析器，返回第一个识别成功的结果
+func ParseExternalSheet(filename string, data []byte) (*ExternalSheet, string, error) {
+	sheetParsersLock.RLock()
+	parsers := append([]SheetParser(nil), sheetParsers...)
+	sheetParsersLock.RUnlock()
+
+	var lastErr error
+	for _, p := range parsers {
+		if !p.Detect(filename, data) {
+			continue
+		}
+		sheet, err := p.Parse(data)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", p.Name(), err)
+			continue
+		}
+		if len(sheet.Fields) == 0 {
+			lastErr = fmt.Errorf("%s: %w", p.Name(), ErrSheetNoFields)
+			continue
+		}
+		return sheet, p.Name(), nil
+	}
+	if lastErr != nil {
+		return nil, "", lastErr
+	}
+	return nil, "", ErrSheetFormatUnknown
+}
+
+// SheetImportOptions 导入选项，留空的项从文件中推断
+type SheetImportOptions struct {
+	Name      string `json:"name"`
+	SheetType string `json:"sheetType"` // 指定规则，优先于文件中判断出的规则
+	Overwrite bool   `json:"overwrite"`
+	// DefaultSheetType 文件中判断不出规则时使用，仍为空则为 coc7
+	DefaultSheetType string `json:"defaultSheetType"`
+}
+
+// SheetImportResult 导入结果
+type SheetImportResult struct {
+	Sheet    *CharacterSheet `json:"sheet"`
+	Format   string          `json:"format"`
+	Imported []string        `json:"imported"` // 导入的属性，为模板中的标准名
+	Unmapped []string        `json:"unmapped"` // 模板中没有对应属性而忽略的项
+}
+
+// mapExternalSheet 通过模板的别名将外部字段对应到模板属性，对应不上的字段原样返回
+func mapExternalSheet(sheet *ExternalSheet, tmpl *GameSystemTemplate) (map[string]any, []string) {
+	attrs := map[string]any{}
+	var unmapped []string
+	for _, field := range sheet.Fields {
+		key, ok := sheetTemplateKey(tmpl, field.Key)
+		if !ok {
+			unmapped = append(unmapped, field.Key)
+			continue
+		}
+		// 同一属性出现多次时以先出现的为准
+		if _, exists := attrs[key]; exists {
+			continue
+		}
+		attrs[key] = normalizeSheetValue(field.Value)
+	}
+	return attrs, unmapped
+}
+
+// sheetTemplateKey 返回 key 在模板中的标准名，别名和默认值中都没有时 ok 为 false
+func sheetTemplateKey(tmpl *GameSystemTemplate, key string) (string, bool) {
+	if canonical, ok := sealChatLookupCanonicalAttrKey(tmpl, key); ok {
+		return canonical, true
+	}
+	if tmpl.GameSystemTemplateV2 == nil {
+		return key, false
+	}
+	if _, ok := tmpl.Attrs.Defaults[key]; ok {
+		return key, true
+	}
+	if _, ok := tmpl.Attrs.DefaultsComputed[key]; ok {
+		return key, true
+	}
+	return key, false
+}
+
+// normalizeSheetValue 数字字符串转为整数，小数向下取整
+func normalizeSheetValue(v any) any {
+	switch val := v.(type) {
+	case float64:
+		return int64(math.Floor(val))
+	case string:
+		s := strings.TrimSpace(val)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return int64(math.Floor(f))
+		}
+		return s
+	}
+	return v
+}
+
+// CharacterImportExternal 解析外部角色卡并导入到 userId 名下
+func (d *Dice) CharacterImportExternal(userId string, filename string, data []byte, opts SheetImportOptions) (*SheetImportResult, error) {
+	sheet, format, err := ParseExternalSheet(filename, data)
+	if err != nil {
+		return nil, err
+	}
+
+	sheetType := sheet.SheetType
+	if opts.SheetType != "" {
+		sheetType = opts.SheetType
+	}
+	if sheetType == "" {
+		sheetType = opts.DefaultSheetType
+	}
+	if sheetType == "" {
+		sheetType = "coc7"
+	}
+	tmpl, ok := d.GameSystemMap.Load(sheetType)
+	if !ok || tmpl == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSheetTemplateMissing, sheetType)
+	}
+
+	attrs, unmapped := mapExternalSheet(sheet, tmpl)
+	if len(attrs) == 0 {
+		return nil, ErrSheetNoFields
+	}
+	name := opts.Name
+	if name == "" {
+		name = sheet.Name
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+
+	imported, err := d.CharacterImport(userId, &CharacterSheet{Name: name, SheetType: sheetType, Attrs: attrs}, opts.Overwrite)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return &SheetImportResult{Sheet: imported, Format: format, Imported: keys, Unmapped: unmapped}, nil
+}
+
+// sheetFileFromMessage 读取消息中附带的第一个文件，只接受已下载的内容或 http(s) 地址
+func sheetFileFromMessage(msg *Message) (string, []byte, error) {
+	for _, seg := range msg.Segment {
+		f, ok := seg.(*message.FileElement)
+		if !ok {
+			continue
+		}
+		if f.Stream == nil {
+			if !strings.HasPrefix(f.URL, "http://") && !strings.HasPrefix(f.URL, "https://") {
+				continue
+			}
+			fetched, err := message.FilepathToFileElement(f.URL)
+			if err != nil {
+				return "", nil, err
+			}
+			if f.File == "" {
+				f.File = fetched.File
+			}
+			f.Stream = fetched.Stream
+		}
+		data, err := io.ReadAll(io.LimitReader(f.Stream, maxSheetFileSize+1))
+		if err != nil {
+			return "", nil, err
+		}
+		if len(data) > maxSheetFileSize {
+			return "", nil, ErrSheetFileTooLarge
+		}
+		return f.File, data, nil
+	}
+	return "", nil, ErrSheetFileMissing
+}
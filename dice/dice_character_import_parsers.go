@@ -0,0 +1,393 @@
+package dice
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 表格和通用 JSON 中表示角色名的键
+var sheetNameKeys = map[string]bool{
+	"name": true, "姓名": true, "名字": true, "角色名": true, "调查员": true, "调查员姓名": true, "角色": true,
+}
+
+func sheetIsExt(filename string, exts ...string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, e := range exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func sheetLooksLikeJSON(filename string, data []byte) bool {
+	if sheetIsExt(filename, ".json") {
+		return true
+	}
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	return len(data) > 0 && data[0] == '{'
+}
+
+func sheetDecodeJSON(data []byte) (map[string]any, error) {
+	var root map[string]any
+	err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &root)
+	return root, err
+}
+
+func sheetJSONObject(m map[string]any, path ...string) map[string]any {
+	cur := m
+	for _, p := range path {
+		next, ok := cur[p].(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = next
+	}
+	return cur
+}
+
+func sheetJSONNumber(v any) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// sortedKeys 使输出顺序稳定
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// foundrySheetParser Foundry VTT 的角色导出（dnd5e 和 CoC7 系统）
+type foundrySheetParser struct{}
+
+func (foundrySheetParser) Name() string { return "Foundry VTT" }
+
+func (foundrySheetParser) Detect(filename string, data []byte) bool {
+	if !sheetLooksLikeJSON(filename, data) {
+		return false
+	}
+	root, err := sheetDecodeJSON(data)
+	if err != nil {
+		return false
+	}
+	_, hasItems := root["items"].([]any)
+	return hasItems && (sheetJSONObject(root, "system") != nil || sheetJSONObject(root, "data") != nil)
+}
+
+func (foundrySheetParser) Parse(data []byte) (*ExternalSheet, error) {
+	root, err := sheetDecodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	sheet := &ExternalSheet{}
+	sheet.Name, _ = root["name"].(string)
+	system := sheetJSONObject(root, "system")
+	if system == nil {
+		// v10 之前的导出
+		system = sheetJSONObject(root, "data")
+	}
+
+	// dnd5e: system.abilities.str.value
+	if abilities := sheetJSONObject(system, "abilities"); abilities != nil {
+		sheet.SheetType = "dnd5e"
+		for _, k := range sortedKeys(abilities) {
+			if v, ok := sheetJSONNumber(sheetJSONObject(abilities, k)["value"]); ok {
+				sheet.Add(k, v)
+			}
+		}
+		if hp := sheetJSONObject(system, "attributes", "hp"); hp != nil {
+			if v, ok := sheetJSONNumber(hp["value"]); ok {
+				sheet.Add("hp", v)
+			}
+			if v, ok := sheetJSONNumber(hp["max"]); ok {
+				sheet.Add("hpmax", v)
+			}
+		}
+		if ac := sheetJSONObject(system, "attributes", "ac"); ac != nil {
+			if v, ok := sheetJSONNumber(ac["value"]); ok {
+				sheet.Add("ac", v)
+			} else if v, ok := sheetJSONNumber(ac["flat"]); ok {
+				sheet.Add("ac", v)
+			}
+		}
+	}
+
+	// CoC7: system.characteristics.str.value, system.attribs.hp.value
+	if characteristics := sheetJSONObject(system, "characteristics"); characteristics != nil {
+		sheet.SheetType = "coc7"
+		for _, k := range sortedKeys(characteristics) {
+			if v, ok := sheetJSONNumber(sheetJSONObject(characteristics, k)["value"]); ok {
+				sheet.Add(k, v)
+			}
+		}
+		attribs := sheetJSONObject(system, "attribs")
+		for _, k := range sortedKeys(attribs) {
+			attr := sheetJSONObject(attribs, k)
+			key := k
+			if key == "lck" {
+				key = "luck"
+			}
+			if v, ok := sheetJSONNumber(attr["value"]); ok {
+				sheet.Add(key, v)
+			}
+			if v, ok := sheetJSONNumber(attr["max"]); ok && (k == "hp" || k == "mp" || k == "san") {
+				sheet.Add(key+"上限", v)
+			}
+		}
+	}
+
+	// CoC7 的技能是 skill 类型的物品
+	items, _ := root["items"].([]any)
+	for _, it := range items {
+		item, ok := it.(map[string]any)
+		if !ok || item["type"] != "skill" {
+			continue
+		}
+		name, _ := item["name"].(string)
+		itemSystem := sheetJSONObject(item, "system")
+		if itemSystem == nil {
+			itemSystem = sheetJSONObject(item, "data")
+		}
+		if v, ok := sheetJSONNumber(itemSystem["value"]); ok {
+			sheet.Add(name, v)
+		} else if v, ok := sheetJSONNumber(itemSystem["base"]); ok {
+			sheet.Add(name, v)
+		}
+	}
+	return sheet, nil
+}
+
+// ddbSheetParser D&D Beyond 的角色 JSON。属性值不含种族和专长带来的加值
+type ddbSheetParser struct{}
+
+func (ddbSheetParser) Name() string { return "D&D Beyond" }
+
+func ddbCharacter(root map[string]any) map[string]any {
+	if inner := sheetJSONObject(root, "data"); inner != nil {
+		return inner
+	}
+	return root
+}
+
+func (ddbSheetParser) Detect(filename string, data []byte) bool {
+	if !sheetLooksLikeJSON(filename, data) {
+		return false
+	}
+	root, err := sheetDecodeJSON(data)
+	if err != nil {
+		return false
+	}
+	c := ddbCharacter(root)
+	_, hasStats := c["stats"].([]any)
+	_, hasClasses := c["classes"].([]any)
+	return hasStats && hasClasses
+}
+
+func (ddbSheetParser) Parse(data []byte) (*ExternalSheet, error) {
+	root, err := sheetDecodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	c := ddbCharacter(root)
+	sheet := &ExternalSheet{SheetType: "dnd5e"}
+	sheet.Name, _ = c["name"].(string)
+
+	statValues := func(key string) map[int]float64 {
+		ret := map[int]float64{}
+		list, _ := c[key].([]any)
+		for _, s := range list {
+			stat, ok := s.(map[string]any)
+			if !ok {
+				continue
+			}
+			id, ok1 := sheetJSONNumber(stat["id"])
+			v, ok2 := sheetJSONNumber(stat["value"])
+			if ok1 && ok2 {
+				ret[int(id)] = v
+			}
+		}
+		return ret
+	}
+	base, bonus, override := statValues("stats"), statValues("bonusStats"), statValues("overrideStats")
+	for i, key := range []string{"str", "dex", "con", "int", "wis", "cha"} {
+		id := i + 1
+		if v, ok := override[id]; ok {
+			sheet.Add(key, v)
+		} else if v, ok := base[id]; ok {
+			sheet.Add(key, v+bonus[id])
+		}
+	}
+
+	if hp, ok := sheetJSONNumber(c["baseHitPoints"]); ok {
+		if v, ok := sheetJSONNumber(c["bonusHitPoints"]); ok {
+			hp += v
+		}
+		if v, ok := sheetJSONNumber(c["overrideHitPoints"]); ok {
+			hp = v
+		}
+		sheet.Add("hpmax", hp)
+		if v, ok := sheetJSONNumber(c["removedHitPoints"]); ok {
+			hp -= v
+		}
+		sheet.Add("hp", hp)
+	}
+	return sheet, nil
+}
+
+// jsonSheetParser 通用 JSON，收集所有数字叶子节点。
+// {"侦查": {"value": 60}} 取 value，[{"name": "侦查", "value": 60}] 按 name 取值
+type jsonSheetParser struct{}
+
+func (jsonSheetParser) Name() string { return "JSON" }
+
+func (jsonSheetParser) Detect(filename string, data []byte) bool {
+	return sheetLooksLikeJSON(filename, data)
+}
+
+func (jsonSheetParser) Parse(data []byte) (*ExternalSheet, error) {
+	root, err := sheetDecodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	sheet := &ExternalSheet{}
+	var walk func(key string, v any, depth int)
+	walk = func(key string, v any, depth int) {
+		if depth > 8 {
+			return
+		}
+		switch val := v.(type) {
+		case map[string]any:
+			if n, ok := sheetJSONNumber(val["value"]); ok && key != "" {
+				sheet.Add(key, n)
+				return
+			}
+			if name, ok := val["name"].(string); ok && key != "" {
+				if n, ok := sheetJSONNumber(val["value"]); ok {
+					sheet.Add(name, n)
+					return
+				}
+			}
+			for _, k := range sortedKeys(val) {
+				walk(k, val[k], depth+1)
+			}
+		case []any:
+			for _, item := range val {
+				obj, ok := item.(map[string]any)
+				if !ok {
+					continue
+				}
+				name, _ := obj["name"].(string)
+				if n, ok := sheetJSONNumber(obj["value"]); ok && name != "" {
+					sheet.Add(name, n)
+				} else {
+					walk("", obj, depth+1)
+				}
+			}
+		case string:
+			if sheetNameKeys[strings.ToLower(key)] && sheet.Name == "" {
+				sheet.Name = strings.TrimSpace(val)
+			} else if n, ok := sheetJSONNumber(val); ok {
+				sheet.Add(key, n)
+			}
+		case float64:
+			sheet.Add(key, val)
+		}
+	}
+	walk("", root, 0)
+	return sheet, nil
+}
+
+// sheetFromRows 从表格中取出“名称, 数值”相邻的单元格对
+func sheetFromRows(rows [][]string) *ExternalSheet {
+	sheet := &ExternalSheet{}
+	for _, row := range rows {
+		for i := 0; i+1 < len(row); i++ {
+			label := strings.TrimSpace(row[i])
+			next := strings.TrimSpace(row[i+1])
+			if label == "" || next == "" {
+				continue
+			}
+			if _, err := strconv.ParseFloat(label, 64); err == nil {
+				continue
+			}
+			if sheetNameKeys[strings.ToLower(label)] {
+				if sheet.Name == "" {
+					sheet.Name = next
+				}
+				i++
+				continue
+			}
+			if n, err := strconv.ParseFloat(next, 64); err == nil {
+				sheet.Add(label, n)
+				i++
+			}
+		}
+	}
+	return sheet
+}
+
+// csvSheetParser CSV 表格，每行中的“名称, 数值”相邻单元格视为一项
+type csvSheetParser struct{}
+
+func (csvSheetParser) Name() string { return "CSV" }
+
+func (csvSheetParser) Detect(filename string, data []byte) bool {
+	return sheetIsExt(filename, ".csv", ".txt")
+}
+
+func (csvSheetParser) Parse(data []byte) (*ExternalSheet, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return sheetFromRows(rows), nil
+}
+
+// xlsxSheetParser Excel 车卡，规则与 CSV 相同，读取所有工作表
+type xlsxSheetParser struct{}
+
+func (xlsxSheetParser) Name() string { return "Excel" }
+
+func (xlsxSheetParser) Detect(filename string, data []byte) bool {
+	return sheetIsExt(filename, ".xlsx", ".xlsm") || (filename == "" && bytes.HasPrefix(data, []byte("PK\x03\x04")))
+}
+
+func (xlsxSheetParser) Parse(data []byte) (*ExternalSheet, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var rows [][]string
+	for _, name := range f.GetSheetList() {
+		sheetRows, err := f.GetRows(name)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, sheetRows...)
+	}
+	return sheetFromRows(rows), nil
+}
@@ -0,0 +1,130 @@
+package dice //nolint:testpackage
+
+import (
+	"slices"
+	"testing"
+)
+
+const testImportTemplate = `
+name: import-test
+fullName: 导入测试
+templateVer: '2.0'
+attrs:
+  defaults:
+    侦查: 25
+alias:
+  力量: [str]
+  敏捷: [dex]
+  生命值: [hp]
+  幸运: [luck]
+`
+
+func TestParseExternalSheet(t *testing.T) {
+	cases := []struct {
+		name      string
+		filename  string
+		data      string
+		format    string
+		sheetName string
+		sheetType string
+		want      map[string]any
+	}{
+		{
+			name:     "foundry coc7",
+			filename: "fvtt-Actor-bob.json",
+			data: `{"name": "Bob", "type": "character", "system": {
+				"characteristics": {"str": {"value": 60}, "dex": {"value": 55}},
+				"attribs": {"hp": {"value": 11, "max": 12}, "lck": {"value": 40}}},
+				"items": [{"type": "skill", "name": "侦查", "system": {"value": 70}}, {"type": "weapon", "name": "刀"}]}`,
+			format:    "Foundry VTT",
+			sheetName: "Bob",
+			sheetType: "coc7",
+			want:      map[string]any{"str": 60.0, "dex": 55.0, "hp": 11.0, "hp上限": 12.0, "luck": 40.0, "侦查": 70.0},
+		},
+		{
+			name:     "dndbeyond",
+			filename: "character.json",
+			data: `{"data": {"name": "Ann", "classes": [],
+				"stats": [{"id": 1, "value": 15}, {"id": 2, "value": 12}],
+				"bonusStats": [{"id": 1, "value": 1}, {"id": 2, "value": null}],
+				"overrideStats": [{"id": 2, "value": 18}],
+				"baseHitPoints": 20, "bonusHitPoints": 2, "removedHitPoints": 5}}`,
+			format:    "D&D Beyond",
+			sheetName: "Ann",
+			sheetType: "dnd5e",
+			want:      map[string]any{"str": 16.0, "dex": 18.0, "hpmax": 22.0, "hp": 17.0},
+		},
+		{
+			name:      "generic json",
+			filename:  "",
+			data:      `{"姓名": "张三", "属性": {"力量": {"value": 50}, "敏捷": "45"}, "技能": [{"name": "侦查", "value": 60}]}`,
+			format:    "JSON",
+			sheetName: "张三",
+			want:      map[string]any{"力量": 50.0, "敏捷": 45.0, "侦查": 60.0},
+		},
+		{
+			name:      "csv",
+			filename:  "卡.csv",
+			data:      "姓名,张三,,\n力量,50,敏捷,45\n备注,无,侦查,60\n",
+			format:    "CSV",
+			sheetName: "张三",
+			want:      map[string]any{"力量": 50.0, "敏捷": 45.0, "侦查": 60.0},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sheet, format, err := ParseExternalSheet(c.filename, []byte(c.data))
+			if err != nil {
+				t.Fatalf("ParseExternalSheet() error = %v", err)
+			}
+			if format != c.format || sheet.Name != c.sheetName || sheet.SheetType != c.sheetType {
+				t.Fatalf("got format=%q name=%q type=%q", format, sheet.Name, sheet.SheetType)
+			}
+			got := map[string]any{}
+			for _, f := range sheet.Fields {
+				got[f.Key] = f.Value
+			}
+			if len(got) != len(c.want) {
+				t.Fatalf("fields = %v, want %v", got, c.want)
+			}
+			for k, v := range c.want {
+				if got[k] != v {
+					t.Fatalf("field %s = %v, want %v (all: %v)", k, got[k], v, got)
+				}
+			}
+		})
+	}
+
+	if _, _, err := ParseExternalSheet("a.png", []byte{0x89, 'P', 'N', 'G'}); err == nil {
+		t.Fatal("unknown format should fail")
+	}
+}
+
+func TestMapExternalSheet(t *testing.T) {
+	tmpl, err := LoadGameSystemTemplateFromBytes([]byte(testImportTemplate), "yaml")
+	if err != nil {
+		t.Fatalf("LoadGameSystemTemplateFromBytes() error = %v", err)
+	}
+	tmpl.Init()
+
+	sheet := &ExternalSheet{}
+	sheet.Add("STR", 60.0)
+	sheet.Add("dex", "55")
+	sheet.Add("侦查", 70.9)
+	sheet.Add("力量", 10.0) // 与 STR 重复，以先出现的为准
+	sheet.Add("克苏鲁神话", 5.0)
+
+	attrs, unmapped := mapExternalSheet(sheet, tmpl)
+	want := map[string]any{"力量": int64(60), "敏捷": int64(55), "侦查": int64(70)}
+	if len(attrs) != len(want) {
+		t.Fatalf("attrs = %v, want %v", attrs, want)
+	}
+	for k, v := range want {
+		if attrs[k] != v {
+			t.Fatalf("attr %s = %v, want %v", k, attrs[k], v)
+		}
+	}
+	if !slices.Equal(unmapped, []string{"克苏鲁神话"}) {
+		t.Fatalf("unmapped = %v", unmapped)
+	}
+}
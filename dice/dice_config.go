@@ -218,6 +218,8 @@ type BanConfig struct {
 type JsConfig struct {
 	JsEnable          bool            `json:"jsEnable"          yaml:"jsEnable"`
 	DisabledJsScripts map[string]bool `json:"disabledJsScripts" yaml:"disabledJsScripts"` // 作为set
+	JsIsolation       bool            `json:"jsIsolation"       yaml:"jsIsolation"`       // 每个脚本使用独立的运行时，扩展包不论此项总是独立运行
+	JsHookTimeLimit   int64           `json:"jsHookTimeLimit"   yaml:"jsHookTimeLimit"`   // 隔离模式或扩展包中单次调用的时间上限（毫秒），0为不限制
 }
 
 type StoryLogConfig struct {
@@ -254,6 +256,7 @@ type CensorConfig struct {
 	CensorCaseSensitive  bool                   `json:"censorCaseSensitive"  yaml:"censorCaseSensitive"`  // 敏感词大小写敏感
 	CensorMatchPinyin    bool                   `json:"censorMatchPinyin"    yaml:"censorMatchPinyin"`    // 敏感词匹配拼音
 	CensorFilterRegexStr string                 `json:"censorFilterRegexStr" yaml:"censorFilterRegexStr"` // 敏感词过滤字符正则
+	CensorNormalize      bool                   `json:"censorNormalize"      yaml:"censorNormalize"`      // 匹配前规范化文本
 }
 
 type DirtyConfig struct {
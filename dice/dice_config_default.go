@@ -81,6 +81,8 @@ var DefaultConfig = Config{
 	JsConfig{
 		JsEnable:          true,
 		DisabledJsScripts: make(map[string]bool),
+		JsIsolation:       false,
+		JsHookTimeLimit:   5000,
 	},
 	StoryLogConfig{
 		LogSizeNoticeEnable: true,
@@ -104,6 +106,7 @@ var DefaultConfig = Config{
 		CensorCaseSensitive:  false,
 		CensorMatchPinyin:    false,
 		CensorFilterRegexStr: "",
+		CensorNormalize:      false,
 	},
 	PublicDiceConfig{
 		Enable: false,
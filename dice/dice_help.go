@@ -224,6 +224,7 @@ func (m *HelpManager) Load(dice *Dice, internalCmdMap CmdMapCls, extList []*ExtI
 .pc load <角色名> // 加载角色[不绑卡]，无角色名则为当前
 .pc list //列出当前角色
 .pc del <角色名> //删除角色
+.pc import <角色名> //从附带的车卡文件导入角色
 .setcoc 2 //设置为coc2版房规
 .nn 张三 //将自己的角色名设置为张三
 `,
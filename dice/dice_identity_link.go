@@ -0,0 +1,225 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+
+	"sealdice-core/dice/service"
+)
+
+// 关联码有效期
+const identityLinkCodeTTL = 10 * time.Minute
+
+const identityLinkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+var (
+	ErrIdentityLinkCodeInvalid = errors.New("关联码无效或已过期")
+	ErrIdentityLinkSelf        = errors.New("不能使用自己生成的关联码")
+	ErrIdentityLinkOther       = errors.New("当前账号已关联到其他身份，请先使用 .link unlink 解除")
+	ErrIdentityLinkAlready     = errors.New("两个账号已经关联过了")
+	ErrIdentityLinkNotLinked   = errors.New("当前账号没有关联其他账号")
+)
+
+type identityLinkCode struct {
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// initIdentityLinks 载入账号关联表
+func (am *AttrsManager) initIdentityLinks() error {
+	if am.db == nil {
+		return nil
+	}
+	items, err := service.PlatformMappingList(am.db)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		am.links.Store(item.IMUserID, item.Id)
+	}
+	return nil
+}
+
+// LinkedIdentity 返回平台用户ID关联到的统一ID，未关联时返回空
+func (am *AttrsManager) LinkedIdentity(userId string) string {
+	id, _ := am.links.Load(userId)
+	return id
+}
+
+// LinkedUsers 返回与 userId 关联到同一身份的全部平台用户ID（含自身），未关联时返回 nil
+func (am *AttrsManager) LinkedUsers(userId string) []string {
+	identity := am.LinkedIdentity(userId)
+	if identity == "" {
+		return nil
+	}
+	var users []string
+	am.links.Range(func(uid string, id string) bool {
+		if id == identity {
+			users = append(users, uid)
+		}
+		return true
+	})
+	sort.Strings(users)
+	return users
+}
+
+// LinkCodeIssue 为 userId 生成一次性关联码，同一用户重复生成时旧码作废
+func (am *AttrsManager) LinkCodeIssue(userId string) (string, error) {
+	code, err := gonanoid.Generate(identityLinkCodeAlphabet, 8)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	am.linkMu.Lock()
+	defer am.linkMu.Unlock()
+	if am.linkCodes == nil {
+		am.linkCodes = map[string]*identityLinkCode{}
+	}
+	for k, v := range am.linkCodes {
+		if v.UserID == userId || now.After(v.ExpiresAt) {
+			delete(am.linkCodes, k)
+		}
+	}
+	am.linkCodes[code] = &identityLinkCode{UserID: userId, ExpiresAt: now.Add(identityLinkCodeTTL)}
+	return code, nil
+}
+
+// LinkCodeRedeem 使用关联码将 userId 关联到签发者的身份，返回统一ID。
+// 签发者尚无身份时新建一个，双方名下的角色卡都会转移到统一ID名下。
+func (am *AttrsManager) LinkCodeRedeem(userId string, code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	am.linkMu.Lock()
+	defer am.linkMu.Unlock()
+
+	item, ok := am.linkCodes[code]
+	if !ok || time.Now().After(item.ExpiresAt) {
+		delete(am.linkCodes, code)
+		return "", ErrIdentityLinkCodeInvalid
+	}
+	if item.UserID == userId {
+		return "", ErrIdentityLinkSelf
+	}
+	identity := am.LinkedIdentity(item.UserID)
+	if current := am.LinkedIdentity(userId); current != "" {
+		if current == identity {
+			return "", ErrIdentityLinkAlready
+		}
+		return "", ErrIdentityLinkOther
+	}
+
+	users := []string{userId}
+	if identity == "" {
+		identity = "U:" + gonanoid.Must()
+		users = []string{item.UserID, userId}
+	}
+	if err := am.linkUsers(identity, users...); err != nil {
+		return "", err
+	}
+	delete(am.linkCodes, code)
+	return identity, nil
+}
+
+// linkUsers 在同一事务中合并 userIds 的数据到 identity 并写入映射，提交后才更新内存中的关联
+func (am *AttrsManager) linkUsers(identity string, userIds ...string) error {
+	// 先落盘，保证数据库中的卡是最新的
+	if err := am.CheckForSave(); err != nil {
+		return err
+	}
+	results, err := service.PlatformMappingLink(am.db, userIds, identity, migratedCharacterName)
+	if err != nil {
+		return err
+	}
+
+	for i, userId := range userIds {
+		result := results[i]
+		am.links.Store(userId, identity)
+		for _, id := range result.MovedIds {
+			am.m.Delete(id)
+		}
+		for id, name := range result.RenamedIds {
+			if item, ok := am.m.Load(id); ok && item != nil {
+				item.Name = name
+			}
+		}
+		// 关联后 userId 对应的群内卡ID改变，清掉可能残留的空卡缓存
+		am.m.Range(func(id string, _ *AttributesItem) bool {
+			if strings.HasSuffix(id, "-"+userId) {
+				am.m.Delete(id)
+			}
+			return true
+		})
+		if am.logger != nil {
+			am.logger.Infof("账号关联: %s -> %s，转移角色卡 %d 张，群内卡 %d 张", userId, identity, result.Characters, len(result.MovedIds))
+		}
+	}
+	return nil
+}
+
+// Unlink 解除 userId 的关联，已合并的角色卡保留在统一ID名下
+func (am *AttrsManager) Unlink(userId string) error {
+	if am.LinkedIdentity(userId) == "" {
+		return ErrIdentityLinkNotLinked
+	}
+	if err := am.CheckForSave(); err != nil {
+		return err
+	}
+	if err := service.PlatformMappingDelete(am.db, userId); err != nil {
+		return err
+	}
+	am.links.Delete(userId)
+	return nil
+}
+
+const helpForLink = ".link // (仅私聊)生成关联码，在另一个平台向骰子发送 .link <关联码> 完成关联\n" +
+	".link <关联码> // 将当前账号关联到关联码的签发账号\n" +
+	".link list // 查看已关联的账号\n" +
+	".link unlink // 解除当前账号的关联\n" +
+	"关联后角色卡在各账号间共享，黑名单状态也会同步；解除关联后已合并的角色卡留在其余账号名下"
+
+func cmdLinkSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+	am := ctx.Dice.AttrsManager
+	uid := ctx.Player.UserID
+	arg := cmdArgs.GetArgN(1)
+	switch strings.ToLower(arg) {
+	case "help":
+		return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+	case "":
+		// 关联码谁先使用就关联到谁，在群内公开会被他人抢先使用
+		if !ctx.IsPrivate {
+			ReplyToSender(ctx, msg, "关联码只能私聊生成，请私聊骰子发送 .link")
+			break
+		}
+		code, err := am.LinkCodeIssue(uid)
+		if err != nil {
+			ReplyToSender(ctx, msg, "生成关联码失败: "+err.Error())
+			break
+		}
+		ReplyToSender(ctx, msg, fmt.Sprintf("关联码: %s\n请在%d分钟内，用另一个平台的账号向骰子发送 .link %s", code, int(identityLinkCodeTTL.Minutes()), code))
+	case "list":
+		users := am.LinkedUsers(uid)
+		if len(users) == 0 {
+			ReplyToSender(ctx, msg, ErrIdentityLinkNotLinked.Error())
+			break
+		}
+		ReplyToSender(ctx, msg, fmt.Sprintf("统一ID: %s\n已关联账号:\n%s", am.LinkedIdentity(uid), strings.Join(users, "\n")))
+	case "unlink":
+		if err := am.Unlink(uid); err != nil {
+			ReplyToSender(ctx, msg, "解除关联失败: "+err.Error())
+			break
+		}
+		ReplyToSender(ctx, msg, "已解除关联，当前账号将不再共享角色卡")
+	default:
+		identity, err := am.LinkCodeRedeem(uid, arg)
+		if err != nil {
+			ReplyToSender(ctx, msg, "关联失败: "+err.Error())
+			break
+		}
+		ReplyToSender(ctx, msg, fmt.Sprintf("关联成功，统一ID: %s\n已关联账号:\n%s", identity, strings.Join(am.LinkedUsers(uid), "\n")))
+	}
+	return CmdExecuteResult{Matched: true, Solved: true}
+}
@@ -0,0 +1,167 @@
+//nolint:testpackage
+package dice
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"sealdice-core/model"
+)
+
+func newIdentityLinkTestManager(t *testing.T) (*AttrsManager, *mockDatabaseOperator) {
+	t.Helper()
+	dbOperator, err := newMockDatabaseOperator(filepath.Join(t.TempDir(), "identity-link.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(dbOperator.Close)
+	if err = dbOperator.db.AutoMigrate(&model.AttributesItemModel{}, &model.PlatformMapping{}); err != nil {
+		t.Fatal(err)
+	}
+	return &AttrsManager{db: dbOperator}, dbOperator
+}
+
+func TestIdentityLinkRedeem(t *testing.T) {
+	am, dbOperator := newIdentityLinkTestManager(t)
+	db := dbOperator.db
+
+	qqChar, err := am.CharNew("QQ:1", "阿尔", "coc7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	discordChar, err := am.CharNew("DISCORD:2", "阿尔", "coc7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = am.CharBind(discordChar.Id, "DISCORD-CH-Group:9", "DISCORD:2"); err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := am.LinkCodeIssue("QQ:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = am.LinkCodeRedeem("QQ:1", code); !errors.Is(err, ErrIdentityLinkSelf) {
+		t.Fatalf("redeeming own code should fail, got %v", err)
+	}
+	identity, err := am.LinkCodeRedeem("DISCORD:2", code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = am.LinkCodeRedeem("DISCORD:2", code); !errors.Is(err, ErrIdentityLinkCodeInvalid) {
+		t.Fatalf("code should be one-time, got %v", err)
+	}
+
+	if am.UIDConvert("QQ:1") != identity || am.UIDConvert("DISCORD:2") != identity {
+		t.Fatal("both accounts should resolve to the identity")
+	}
+	for _, uid := range []string{"QQ:1", "DISCORD:2"} {
+		lst, listErr := am.GetCharacterList(uid)
+		if listErr != nil {
+			t.Fatal(listErr)
+		}
+		if len(lst) != 2 {
+			t.Fatalf("%s should see both characters, got %d", uid, len(lst))
+		}
+	}
+	if id, _ := am.CharIdGetByName("QQ:1", "阿尔"); id != qqChar.Id {
+		t.Fatalf("first character should keep its name, got %q", id)
+	}
+	if id, _ := am.CharIdGetByName("QQ:1", migratedCharacterName("阿尔", 2)); id != discordChar.Id {
+		t.Fatalf("duplicate character should be renamed, got %q", id)
+	}
+	if id, _ := am.CharGetBindingId("DISCORD-CH-Group:9", "DISCORD:2"); id != discordChar.Id {
+		t.Fatalf("group binding should follow the identity, got %q", id)
+	}
+
+	var count int64
+	db.Model(&model.PlatformMapping{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("expected 2 mappings, got %d", count)
+	}
+
+	// 重新载入后仍能解析
+	reloaded := &AttrsManager{db: dbOperator}
+	if err = reloaded.initIdentityLinks(); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.UIDConvert("DISCORD:2") != identity {
+		t.Fatal("links should be loaded from database")
+	}
+
+	if err = am.Unlink("DISCORD:2"); err != nil {
+		t.Fatal(err)
+	}
+	if am.UIDConvert("DISCORD:2") != "DISCORD:2" || len(am.LinkedUsers("QQ:1")) != 1 {
+		t.Fatal("unlinked account should resolve to itself")
+	}
+	if err = am.Unlink("DISCORD:2"); !errors.Is(err, ErrIdentityLinkNotLinked) {
+		t.Fatalf("unlink twice should fail, got %v", err)
+	}
+}
+
+func TestBanListGetByIDLinked(t *testing.T) {
+	am, _ := newIdentityLinkTestManager(t)
+	d := &Dice{AttrsManager: am}
+	banList := &BanListInfo{Parent: d}
+	banList.Init()
+
+	code, _ := am.LinkCodeIssue("QQ:1")
+	if _, err := am.LinkCodeRedeem("KOOK:3", code); err != nil {
+		t.Fatal(err)
+	}
+	banList.Map.Store("QQ:1", &BanListInfoItem{ID: "QQ:1", Rank: BanRankBanned})
+
+	if v, ok := banList.GetByIDLinked("KOOK:3"); !ok || v.Rank != BanRankBanned {
+		t.Fatal("ban should follow linked accounts")
+	}
+	if _, ok := banList.GetByID("KOOK:3"); ok {
+		t.Fatal("GetByID should not resolve links")
+	}
+
+	banList.Map.Store("KOOK:3", &BanListInfoItem{ID: "KOOK:3", Rank: BanRankTrusted})
+	if v, _ := banList.GetByIDLinked("KOOK:3"); v.Rank != BanRankTrusted {
+		t.Fatal("trust on the account itself should take precedence")
+	}
+}
+
+func TestIdentityLinkRedeemRollsBack(t *testing.T) {
+	am, dbOperator := newIdentityLinkTestManager(t)
+	db := dbOperator.db
+
+	// 第二条映射写入失败时，签发者的映射也不能留下
+	errWrite := errors.New("write failed")
+	creates := 0
+	err := db.Callback().Create().Before("gorm:create").Register("test:fail_second_mapping", func(tx *gorm.DB) {
+		if tx.Statement.Table == "platform_mappings" {
+			creates++
+			if creates == 2 {
+				_ = tx.AddError(errWrite)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := am.LinkCodeIssue("QQ:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = am.LinkCodeRedeem("DISCORD:2", code); !errors.Is(err, errWrite) {
+		t.Fatalf("redeem should fail, got %v", err)
+	}
+	var count int64
+	if err = db.Model(&model.PlatformMapping{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 || am.LinkedIdentity("QQ:1") != "" || am.LinkedIdentity("DISCORD:2") != "" {
+		t.Fatalf("failed redeem should not leave a link, got %d rows", count)
+	}
+	if _, err = am.LinkCodeRedeem("DISCORD:2", code); err != nil {
+		t.Fatalf("code should stay usable after a failed redeem, got %v", err)
+	}
+}
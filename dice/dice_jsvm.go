@@ -11,7 +11,6 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
@@ -87,19 +86,12 @@ func (d *Dice) JsInit() {
 	// 清理目前的js相关
 	d.jsClear()
 
-	// 重建js vm
-	reg := new(require.Registry)
-
-	loop := eventloop.NewEventLoop(eventloop.EnableConsole(false),
-		eventloop.WithRegistry(reg),
-		eventloop.WithDebugLog(true),
-		eventloop.WithLogger(d.Logger))
-	_ = fetch.Enable(loop, goproxy.NewProxyHttpServer())
-	versionID := d.ExtLoopManager.SetLoop(loop)
-
 	printer := &PrinterFunc{d, false, []string{}}
 	d.JsPrinter = printer
-	reg.RegisterNativeModule("console", console.RequireWithPrinter(printer))
+
+	// 重建js vm
+	loop, reg := d.jsNewLoop()
+	versionID := d.ExtLoopManager.SetLoop(loop)
 
 	d.JsScriptCron = cron.New()
 	d.JsScriptCronLock = &sync.Mutex{}
@@ -110,577 +102,611 @@ func (d *Dice) JsInit() {
 	sealws.GlobalConnManager.CloseAll()
 	// 初始化
 	loop.Run(func(vm *goja.Runtime) {
-		vm.SetFieldNameMapper(goja.TagFieldNameMapper("jsbind", true))
-		// 直接绑定进程内唯一全局随机源，避免再包一层无意义的适配。
-		vm.SetRandSource(func() float64 {
-			return float64(globalRandSource.Uint64()>>11) / (1 << 53)
-		})
+		d.jsInitRuntime(vm, loop, reg, versionID)
+	})
+	d.jsStartLoop(loop)
+	(&d.Config).JsEnable = true
+	d.Logger.Info("已加载JS环境")
+	d.MarkModified()
+	d.Save(false)
+}
 
-		// console 模块
-		console.Enable(vm)
-
-		sealws.Enable(vm, loop)
-		// require 模块
-		reg.Enable(vm)
-
-		seal := vm.NewObject()
-
-		vars := vm.NewObject()
-		_ = seal.Set("vars", vars)
-		_ = vars.Set("intGet", VarGetValueInt64)
-		_ = vars.Set("intSet", VarSetValueInt64)
-		_ = vars.Set("strGet", VarGetValueStr)
-		_ = vars.Set("strSet", VarSetValueStr)
-		_ = vars.Set("computedSet", VarSetValueComputed)
-		_ = vars.Set("computedGet", VarGetValueComputed)
-
-		ban := vm.NewObject()
-		_ = seal.Set("ban", ban)
-		_ = ban.Set("addBan", func(ctx *MsgContext, id string, place string, reason string) {
-			(&d.Config).BanList.AddScoreBase(id, d.Config.BanList.ThresholdBan, place, reason, ctx)
-			(&d.Config).BanList.SaveChanged(d)
-		})
-		_ = ban.Set("addTrust", func(ctx *MsgContext, id string, place string, reason string) {
-			(&d.Config).BanList.SetTrustByID(id, place, reason)
-			(&d.Config).BanList.SaveChanged(d)
-		})
-		_ = ban.Set("remove", func(ctx *MsgContext, id string) {
-			_, ok := (&d.Config).BanList.GetByID(id)
-			if !ok {
-				return
-			}
-			(&d.Config).BanList.DeleteByID(d, id)
-		})
-		_ = ban.Set("getList", func() []BanListInfoItem {
-			var list []BanListInfoItem
-			(&d.Config).BanList.Map.Range(func(key string, value *BanListInfoItem) bool {
-				list = append(list, *value)
-				return true
-			})
-			return list
-		})
-		_ = ban.Set("getUser", func(id string) *BanListInfoItem {
-			i, ok := (&d.Config).BanList.GetByID(id)
-			if !ok {
-				return nil
-			}
-			cp := *i
-			return &cp
-		})
+// jsNewLoop 创建新的事件循环及其模块注册表
+func (d *Dice) jsNewLoop() (*eventloop.EventLoop, *require.Registry) {
+	reg := require.NewRegistry(require.WithLoader(d.jsSourceLoader))
+	loop := eventloop.NewEventLoop(eventloop.EnableConsole(false),
+		eventloop.WithRegistry(reg),
+		eventloop.WithDebugLog(true),
+		eventloop.WithLogger(d.Logger))
+	_ = fetch.Enable(loop, goproxy.NewProxyHttpServer())
+	reg.RegisterNativeModule("console", console.RequireWithPrinter(d.JsPrinter))
+	return loop, reg
+}
 
-		ext := vm.NewObject()
-		_ = seal.Set("ext", ext)
-		_ = ext.Set("newCmdItemInfo", func() *CmdItemInfo {
-			return &CmdItemInfo{IsJsSolveFunc: true, JSLoopVersion: versionID}
-		})
-		_ = ext.Set("newCmdExecuteResult", func(solved bool) CmdExecuteResult {
-			return CmdExecuteResult{
-				Matched: true,
-				Solved:  solved,
-			}
+// jsInitRuntime 向运行时注入 seal 等全局对象，其中注册的扩展和指令都绑定到 versionID 对应的 loop
+func (d *Dice) jsInitRuntime(vm *goja.Runtime, loop *eventloop.EventLoop, reg *require.Registry, versionID int64) {
+	vm.SetFieldNameMapper(goja.TagFieldNameMapper("jsbind", true))
+	// 直接绑定进程内唯一全局随机源，避免再包一层无意义的适配。
+	vm.SetRandSource(func() float64 {
+		return float64(globalRandSource.Uint64()>>11) / (1 << 53)
+	})
+
+	// console 模块
+	console.Enable(vm)
+
+	sealws.Enable(vm, loop)
+	// require 模块
+	reg.Enable(vm)
+
+	seal := vm.NewObject()
+
+	vars := vm.NewObject()
+	_ = seal.Set("vars", vars)
+	_ = vars.Set("intGet", VarGetValueInt64)
+	_ = vars.Set("intSet", VarSetValueInt64)
+	_ = vars.Set("strGet", VarGetValueStr)
+	_ = vars.Set("strSet", VarSetValueStr)
+	_ = vars.Set("computedSet", VarSetValueComputed)
+	_ = vars.Set("computedGet", VarGetValueComputed)
+
+	ban := vm.NewObject()
+	_ = seal.Set("ban", ban)
+	_ = ban.Set("addBan", func(ctx *MsgContext, id string, place string, reason string) {
+		(&d.Config).BanList.AddScoreBase(id, d.Config.BanList.ThresholdBan, place, reason, ctx)
+		(&d.Config).BanList.SaveChanged(d)
+	})
+	_ = ban.Set("addTrust", func(ctx *MsgContext, id string, place string, reason string) {
+		(&d.Config).BanList.SetTrustByID(id, place, reason)
+		(&d.Config).BanList.SaveChanged(d)
+	})
+	_ = ban.Set("remove", func(ctx *MsgContext, id string) {
+		_, ok := (&d.Config).BanList.GetByID(id)
+		if !ok {
+			return
+		}
+		(&d.Config).BanList.DeleteByID(d, id)
+	})
+	_ = ban.Set("getList", func() []BanListInfoItem {
+		var list []BanListInfoItem
+		(&d.Config).BanList.Map.Range(func(key string, value *BanListInfoItem) bool {
+			list = append(list, *value)
+			return true
 		})
-		_ = ext.Set("new", func(name, author, version string) *ExtInfo {
-			var official bool
-			if d.JsLoadingScript != nil {
-				official = d.JsLoadingScript.Official
+		return list
+	})
+	_ = ban.Set("getUser", func(id string) *BanListInfoItem {
+		i, ok := (&d.Config).BanList.GetByID(id)
+		if !ok {
+			return nil
+		}
+		cp := *i
+		return &cp
+	})
+
+	ext := vm.NewObject()
+	_ = seal.Set("ext", ext)
+	_ = ext.Set("newCmdItemInfo", func() *CmdItemInfo {
+		return &CmdItemInfo{IsJsSolveFunc: true, JSLoopVersion: versionID}
+	})
+	_ = ext.Set("newCmdExecuteResult", func(solved bool) CmdExecuteResult {
+		return CmdExecuteResult{
+			Matched: true,
+			Solved:  solved,
+		}
+	})
+	_ = ext.Set("new", func(name, author, version string) *ExtInfo {
+		var official bool
+		if d.JsLoadingScript != nil {
+			official = d.JsLoadingScript.Official
+		}
+		return &ExtInfo{
+			Name: name, Author: author, Version: version,
+			GetDescText:   GetExtensionDesc,
+			AutoActive:    true,
+			IsJsExt:       true,
+			Brief:         "一个JS自定义扩展",
+			Official:      official,
+			CmdMap:        CmdMapCls{},
+			Source:        d.JsLoadingScript,
+			JSLoopVersion: versionID,
+		}
+	})
+	_ = ext.Set("find", func(name string) *ExtInfo {
+		return d.ExtFind(name, true)
+	})
+	_ = ext.Set("register", func(realExt *ExtInfo) {
+		defer func() {
+			// 增加recover, 以免在scripts目录中存在名字冲突扩展时导致启动崩溃
+			if e := recover(); e != nil {
+				d.Logger.Error(e)
 			}
-			return &ExtInfo{
-				Name: name, Author: author, Version: version,
+		}()
+
+		if strings.ToLower(realExt.Name) == "help" || strings.ToLower(realExt.Name) == "all" {
+			panic("help 和 all 为保留关键字，无法作为插件名使用")
+		}
+
+		extName := realExt.Name
+
+		// 1. 查找或创建 wrapper
+		var wrapper *ExtInfo
+		if existingWrapper, ok := d.ExtRegistry.Load(extName); ok && existingWrapper != nil && existingWrapper.IsWrapper {
+			// 重载：复用已有 wrapper
+			wrapper = existingWrapper
+			wrapper.Author = realExt.Author
+			wrapper.Version = realExt.Version
+			wrapper.IsDeleted = false         // 重新激活（清除删除标记）
+			wrapper.dice = d                  // 确保 dice 引用正确（可能从配置恢复时为 nil）
+			wrapper.JSLoopVersion = versionID // 同步新的 loop 版本号，避免 callWithJsCheck 时版本不匹配
+			d.ActiveWithGraphMu.Lock()
+			wrapper.ActiveWith = append([]string(nil), realExt.ActiveWith...)
+			d.ActiveWithGraph = nil
+			d.ActiveWithGraphMu.Unlock()
+		} else {
+			// 首次加载：创建新 wrapper
+			wrapper = &ExtInfo{
+				Name:          extName,
+				Author:        realExt.Author,
+				Version:       realExt.Version,
+				IsWrapper:     true,
+				TargetName:    extName,
+				IsDeleted:     false,
 				GetDescText:   GetExtensionDesc,
-				AutoActive:    true,
-				IsJsExt:       true,
+				AutoActive:    realExt.AutoActive, // 复制真实扩展的 AutoActive 设置
+				IsJsExt:       true,               // 标记为 JS 扩展
 				Brief:         "一个JS自定义扩展",
-				Official:      official,
+				Official:      realExt.Official,
+				ActiveWith:    append([]string(nil), realExt.ActiveWith...),
 				CmdMap:        CmdMapCls{},
-				Source:        d.JsLoadingScript,
 				JSLoopVersion: versionID,
+				dice:          d,
 			}
-		})
-		_ = ext.Set("find", func(name string) *ExtInfo {
-			return d.ExtFind(name, true)
-		})
-		_ = ext.Set("register", func(realExt *ExtInfo) {
-			defer func() {
-				// 增加recover, 以免在scripts目录中存在名字冲突扩展时导致启动崩溃
-				if e := recover(); e != nil {
-					d.Logger.Error(e)
-				}
-			}()
+			// 注册 wrapper 到 ExtRegistry 和 ExtList
+			d.RegisterExtension(wrapper)
+		}
 
-			if strings.ToLower(realExt.Name) == "help" || strings.ToLower(realExt.Name) == "all" {
-				panic("help 和 all 为保留关键字，无法作为插件名使用")
-			}
+		// 2. 注册真实 ExtInfo 到 JsExtRegistry
+		if d.JsExtRegistry == nil {
+			d.JsExtRegistry = new(SyncMap[string, *ExtInfo])
+		}
+		d.JsExtRegistry.Store(extName, realExt)
 
-			extName := realExt.Name
-
-			// 1. 查找或创建 wrapper
-			var wrapper *ExtInfo
-			if existingWrapper, ok := d.ExtRegistry.Load(extName); ok && existingWrapper != nil && existingWrapper.IsWrapper {
-				// 重载：复用已有 wrapper
-				wrapper = existingWrapper
-				wrapper.Author = realExt.Author
-				wrapper.Version = realExt.Version
-				wrapper.IsDeleted = false         // 重新激活（清除删除标记）
-				wrapper.dice = d                  // 确保 dice 引用正确（可能从配置恢复时为 nil）
-				wrapper.JSLoopVersion = versionID // 同步新的 loop 版本号，避免 callWithJsCheck 时版本不匹配
-				d.ActiveWithGraphMu.Lock()
-				wrapper.ActiveWith = append([]string(nil), realExt.ActiveWith...)
-				d.ActiveWithGraph = nil
-				d.ActiveWithGraphMu.Unlock()
-			} else {
-				// 首次加载：创建新 wrapper
-				wrapper = &ExtInfo{
-					Name:          extName,
-					Author:        realExt.Author,
-					Version:       realExt.Version,
-					IsWrapper:     true,
-					TargetName:    extName,
-					IsDeleted:     false,
-					GetDescText:   GetExtensionDesc,
-					AutoActive:    realExt.AutoActive, // 复制真实扩展的 AutoActive 设置
-					IsJsExt:       true,               // 标记为 JS 扩展
-					Brief:         "一个JS自定义扩展",
-					Official:      realExt.Official,
-					ActiveWith:    append([]string(nil), realExt.ActiveWith...),
-					CmdMap:        CmdMapCls{},
-					JSLoopVersion: versionID,
-					dice:          d,
-				}
-				// 注册 wrapper 到 ExtRegistry 和 ExtList
-				d.RegisterExtension(wrapper)
-			}
+		// 3. 设置真实 ExtInfo 的属性
+		realExt.dice = d
+		realExt.JSLoopVersion = versionID
 
-			// 2. 注册真实 ExtInfo 到 JsExtRegistry
-			if d.JsExtRegistry == nil {
-				d.JsExtRegistry = new(SyncMap[string, *ExtInfo])
-			}
-			d.JsExtRegistry.Store(extName, realExt)
+		// 4. 更新全局扩展变更时间戳
+		d.ExtUpdateTime = time.Now().Unix()
 
-			// 3. 设置真实 ExtInfo 的属性
-			realExt.dice = d
-			realExt.JSLoopVersion = versionID
+		// 5. 触发 OnLoad 回调
+		if realExt.OnLoad != nil {
+			realExt.OnLoad()
+		}
+	})
+	_ = ext.Set("registerStringConfig", func(ei *ExtInfo, key string, defaultValue string, description string, group string) error {
+		if ei.dice == nil {
+			return errors.New("请先完成此扩展的注册")
+		}
+		config := &ConfigItem{
+			Key:          key,
+			Type:         "string",
+			Group:        group,
+			Value:        defaultValue,
+			DefaultValue: defaultValue,
+			Description:  description,
+		}
+		d.ConfigManager.RegisterPluginConfig(ei.Name, config)
+		return nil
+	})
+	_ = ext.Set("registerIntConfig", func(ei *ExtInfo, key string, defaultValue int64, description string, group string) error {
+		if ei.dice == nil {
+			return errors.New("请先完成此扩展的注册")
+		}
+		config := &ConfigItem{
+			Key:          key,
+			Type:         "int",
+			Group:        group,
+			Value:        defaultValue,
+			DefaultValue: defaultValue,
+			Description:  description,
+		}
+		d.ConfigManager.RegisterPluginConfig(ei.Name, config)
+		return nil
+	})
+	_ = ext.Set("registerBoolConfig", func(ei *ExtInfo, key string, defaultValue bool, description string, group string) error {
+		if ei.dice == nil {
+			return errors.New("请先完成此扩展的注册")
+		}
+		config := &ConfigItem{
+			Key:          key,
+			Type:         "bool",
+			Group:        group,
+			Value:        defaultValue,
+			DefaultValue: defaultValue,
+			Description:  description,
+		}
+		d.ConfigManager.RegisterPluginConfig(ei.Name, config)
+		return nil
+	})
+	_ = ext.Set("registerFloatConfig", func(ei *ExtInfo, key string, defaultValue float64, description string, group string) error {
+		if ei.dice == nil {
+			return errors.New("请先完成此扩展的注册")
+		}
+		config := &ConfigItem{
+			Key:          key,
+			Type:         "float",
+			Group:        group,
+			Value:        defaultValue,
+			DefaultValue: defaultValue,
+			Description:  description,
+		}
+		d.ConfigManager.RegisterPluginConfig(ei.Name, config)
+		return nil
+	})
+	_ = ext.Set("registerTemplateConfig", func(ei *ExtInfo, key string, defaultValue []string, description string, group string) error {
+		if ei.dice == nil {
+			return errors.New("请先完成此扩展的注册")
+		}
+		config := &ConfigItem{
+			Key:          key,
+			Type:         "template",
+			Group:        group,
+			Value:        defaultValue,
+			DefaultValue: defaultValue,
+			Description:  description,
+		}
+		d.ConfigManager.RegisterPluginConfig(ei.Name, config)
+		return nil
+	})
+	_ = ext.Set("registerOptionConfig", func(ei *ExtInfo, key string, defaultValue string, option []string, description string, group string) error {
+		if ei.dice == nil {
+			return errors.New("请先完成此扩展的注册")
+		}
+		config := &ConfigItem{
+			Key:          key,
+			Type:         "option",
+			Group:        group,
+			Value:        defaultValue,
+			DefaultValue: defaultValue,
+			Option:       option,
+			Description:  description,
+		}
+		d.ConfigManager.RegisterPluginConfig(ei.Name, config)
+		return nil
+	})
+	_ = ext.Set("newConfigItem", func(ei *ExtInfo, key string, defaultValue interface{}, description string) *ConfigItem {
+		if ei.dice == nil {
+			panic(errors.New("请先完成此扩展的注册"))
+		}
+		return d.ConfigManager.NewConfigItem(key, defaultValue, description)
+	})
+	_ = ext.Set("registerConfig", func(ei *ExtInfo, config ...*ConfigItem) error {
+		if ei.dice == nil {
+			return errors.New("请先完成此扩展的注册")
+		}
+		d.ConfigManager.RegisterPluginConfig(ei.Name, config...)
+		return nil
+	})
+	_ = ext.Set("getConfig", func(ei *ExtInfo, key string) *ConfigItem {
+		if ei.dice == nil {
+			return nil
+		}
+		return d.ConfigManager.getConfig(ei.Name, key)
+	})
+	_ = ext.Set("getStringConfig", func(ei *ExtInfo, key string) string {
+		if ei.dice == nil || d.ConfigManager.getConfig(ei.Name, key).Type != "string" {
+			panic("配置不存在或类型不匹配")
+		}
+		return d.ConfigManager.getConfig(ei.Name, key).Value.(string)
+	})
+	_ = ext.Set("getIntConfig", func(ei *ExtInfo, key string) int64 {
+		if ei.dice == nil || d.ConfigManager.getConfig(ei.Name, key).Type != "int" {
+			panic("配置不存在或类型不匹配")
+		}
+		return d.ConfigManager.getConfig(ei.Name, key).Value.(int64)
+	})
+	_ = ext.Set("getBoolConfig", func(ei *ExtInfo, key string) bool {
+		if ei.dice == nil || d.ConfigManager.getConfig(ei.Name, key).Type != "bool" {
+			panic("配置不存在或类型不匹配")
+		}
+		return d.ConfigManager.getConfig(ei.Name, key).Value.(bool)
+	})
+	_ = ext.Set("getFloatConfig", func(ei *ExtInfo, key string) float64 {
+		if ei.dice == nil || d.ConfigManager.getConfig(ei.Name, key).Type != "float" {
+			panic("配置不存在或类型不匹配")
+		}
+		return d.ConfigManager.getConfig(ei.Name, key).Value.(float64)
+	})
+	_ = ext.Set("getTemplateConfig", func(ei *ExtInfo, key string) []string {
+		if ei.dice == nil || d.ConfigManager.getConfig(ei.Name, key).Type != "template" {
+			panic("配置不存在或类型不匹配")
+		}
+		return d.ConfigManager.getConfig(ei.Name, key).Value.([]string)
+	})
+	_ = ext.Set("getOptionConfig", func(ei *ExtInfo, key string) string {
+		if ei.dice == nil || d.ConfigManager.getConfig(ei.Name, key).Type != "option" {
+			panic("配置不存在或类型不匹配")
+		}
+		return d.ConfigManager.getConfig(ei.Name, key).Value.(string)
+	})
+	_ = ext.Set("unregisterConfig", func(ei *ExtInfo, key ...string) {
+		if ei.dice == nil {
+			return
+		}
+		d.ConfigManager.UnregisterConfig(ei.Name, key...)
+	})
 
-			// 4. 更新全局扩展变更时间戳
-			d.ExtUpdateTime = time.Now().Unix()
+	_ = ext.Set("registerTask", func(ei *ExtInfo, taskType string, value string, fn func(taskCtx JsScriptTaskCtx), key string, desc string, group string) *JsScriptTask {
+		if ei.dice == nil {
+			panic(errors.New("请先完成此扩展的注册"))
+		}
+		scriptCron := ei.dice.JsScriptCron
+		if scriptCron == nil {
+			panic(errors.New("插件cron未成功初始化")) // 按理是不会发生的
+		}
 
-			// 5. 触发 OnLoad 回调
-			if realExt.OnLoad != nil {
-				realExt.OnLoad()
-			}
-		})
-		_ = ext.Set("registerStringConfig", func(ei *ExtInfo, key string, defaultValue string, description string, group string) error {
-			if ei.dice == nil {
-				return errors.New("请先完成此扩展的注册")
-			}
-			config := &ConfigItem{
-				Key:          key,
-				Type:         "string",
-				Group:        group,
-				Value:        defaultValue,
-				DefaultValue: defaultValue,
-				Description:  description,
-			}
-			d.ConfigManager.RegisterPluginConfig(ei.Name, config)
-			return nil
-		})
-		_ = ext.Set("registerIntConfig", func(ei *ExtInfo, key string, defaultValue int64, description string, group string) error {
-			if ei.dice == nil {
-				return errors.New("请先完成此扩展的注册")
-			}
-			config := &ConfigItem{
-				Key:          key,
-				Type:         "int",
-				Group:        group,
-				Value:        defaultValue,
-				DefaultValue: defaultValue,
-				Description:  description,
-			}
-			d.ConfigManager.RegisterPluginConfig(ei.Name, config)
-			return nil
-		})
-		_ = ext.Set("registerBoolConfig", func(ei *ExtInfo, key string, defaultValue bool, description string, group string) error {
-			if ei.dice == nil {
-				return errors.New("请先完成此扩展的注册")
-			}
-			config := &ConfigItem{
-				Key:          key,
-				Type:         "bool",
-				Group:        group,
-				Value:        defaultValue,
-				DefaultValue: defaultValue,
-				Description:  description,
-			}
-			d.ConfigManager.RegisterPluginConfig(ei.Name, config)
-			return nil
-		})
-		_ = ext.Set("registerFloatConfig", func(ei *ExtInfo, key string, defaultValue float64, description string, group string) error {
-			if ei.dice == nil {
-				return errors.New("请先完成此扩展的注册")
-			}
-			config := &ConfigItem{
-				Key:          key,
-				Type:         "float",
-				Group:        group,
-				Value:        defaultValue,
-				DefaultValue: defaultValue,
-				Description:  description,
-			}
-			d.ConfigManager.RegisterPluginConfig(ei.Name, config)
-			return nil
-		})
-		_ = ext.Set("registerTemplateConfig", func(ei *ExtInfo, key string, defaultValue []string, description string, group string) error {
-			if ei.dice == nil {
-				return errors.New("请先完成此扩展的注册")
-			}
-			config := &ConfigItem{
-				Key:          key,
-				Type:         "template",
-				Group:        group,
-				Value:        defaultValue,
-				DefaultValue: defaultValue,
-				Description:  description,
-			}
-			d.ConfigManager.RegisterPluginConfig(ei.Name, config)
-			return nil
-		})
-		_ = ext.Set("registerOptionConfig", func(ei *ExtInfo, key string, defaultValue string, option []string, description string, group string) error {
-			if ei.dice == nil {
-				return errors.New("请先完成此扩展的注册")
-			}
-			config := &ConfigItem{
-				Key:          key,
-				Type:         "option",
-				Group:        group,
-				Value:        defaultValue,
-				DefaultValue: defaultValue,
-				Option:       option,
-				Description:  description,
-			}
-			d.ConfigManager.RegisterPluginConfig(ei.Name, config)
-			return nil
-		})
-		_ = ext.Set("newConfigItem", func(ei *ExtInfo, key string, defaultValue interface{}, description string) *ConfigItem {
-			if ei.dice == nil {
-				panic(errors.New("请先完成此扩展的注册"))
-			}
-			return d.ConfigManager.NewConfigItem(key, defaultValue, description)
-		})
-		_ = ext.Set("registerConfig", func(ei *ExtInfo, config ...*ConfigItem) error {
-			if ei.dice == nil {
-				return errors.New("请先完成此扩展的注册")
-			}
-			d.ConfigManager.RegisterPluginConfig(ei.Name, config...)
-			return nil
-		})
-		_ = ext.Set("getConfig", func(ei *ExtInfo, key string) *ConfigItem {
-			if ei.dice == nil {
-				return nil
-			}
-			return d.ConfigManager.getConfig(ei.Name, key)
-		})
-		_ = ext.Set("getStringConfig", func(ei *ExtInfo, key string) string {
-			if ei.dice == nil || d.ConfigManager.getConfig(ei.Name, key).Type != "string" {
-				panic("配置不存在或类型不匹配")
-			}
-			return d.ConfigManager.getConfig(ei.Name, key).Value.(string)
-		})
-		_ = ext.Set("getIntConfig", func(ei *ExtInfo, key string) int64 {
-			if ei.dice == nil || d.ConfigManager.getConfig(ei.Name, key).Type != "int" {
-				panic("配置不存在或类型不匹配")
-			}
-			return d.ConfigManager.getConfig(ei.Name, key).Value.(int64)
-		})
-		_ = ext.Set("getBoolConfig", func(ei *ExtInfo, key string) bool {
-			if ei.dice == nil || d.ConfigManager.getConfig(ei.Name, key).Type != "bool" {
-				panic("配置不存在或类型不匹配")
-			}
-			return d.ConfigManager.getConfig(ei.Name, key).Value.(bool)
-		})
-		_ = ext.Set("getFloatConfig", func(ei *ExtInfo, key string) float64 {
-			if ei.dice == nil || d.ConfigManager.getConfig(ei.Name, key).Type != "float" {
-				panic("配置不存在或类型不匹配")
-			}
-			return d.ConfigManager.getConfig(ei.Name, key).Value.(float64)
-		})
-		_ = ext.Set("getTemplateConfig", func(ei *ExtInfo, key string) []string {
-			if ei.dice == nil || d.ConfigManager.getConfig(ei.Name, key).Type != "template" {
-				panic("配置不存在或类型不匹配")
-			}
-			return d.ConfigManager.getConfig(ei.Name, key).Value.([]string)
-		})
-		_ = ext.Set("getOptionConfig", func(ei *ExtInfo, key string) string {
-			if ei.dice == nil || d.ConfigManager.getConfig(ei.Name, key).Type != "option" {
-				panic("配置不存在或类型不匹配")
-			}
-			return d.ConfigManager.getConfig(ei.Name, key).Value.(string)
-		})
-		_ = ext.Set("unregisterConfig", func(ei *ExtInfo, key ...string) {
-			if ei.dice == nil {
-				return
+		task := JsScriptTask{cron: scriptCron, key: key, task: fn, lock: ei.dice.JsScriptCronLock, logger: ei.dice.Logger}
+		expr := value
+		if key != "" {
+			if config := d.ConfigManager.getConfig(ei.Name, key); config != nil {
+				expr = config.Value.(string)
+				// Stop old task
+				if config.task != nil {
+					config.task.Off()
+				}
 			}
-			d.ConfigManager.UnregisterConfig(ei.Name, key...)
-		})
+		}
 
-		_ = ext.Set("registerTask", func(ei *ExtInfo, taskType string, value string, fn func(taskCtx JsScriptTaskCtx), key string, desc string, group string) *JsScriptTask {
-			if ei.dice == nil {
-				panic(errors.New("请先完成此扩展的注册"))
-			}
-			scriptCron := ei.dice.JsScriptCron
-			if scriptCron == nil {
-				panic(errors.New("插件cron未成功初始化")) // 按理是不会发生的
+		switch taskType {
+		case "cron":
+			entryID, err := scriptCron.AddFunc(expr, func() {
+				task.run()
+			})
+			if err != nil {
+				panic("插件注册定时任务失败：" + err.Error())
+			}
+			task.taskType = taskType
+			task.rawValue = expr
+			task.cronExpr = expr
+			task.entryID = &entryID
+			ei.dice.Logger.Infof("插件注册定时任务：cron=%s", expr)
+		case "daily":
+			// 支持每天定时触发，24 小时表示
+			cronExpr, err := parseTaskTime(expr)
+			if err != nil {
+				panic("插件注册定时任务失败：" + err.Error())
 			}
 
-			task := JsScriptTask{cron: scriptCron, key: key, task: fn, lock: ei.dice.JsScriptCronLock, logger: ei.dice.Logger}
-			expr := value
-			if key != "" {
-				if config := d.ConfigManager.getConfig(ei.Name, key); config != nil {
-					expr = config.Value.(string)
-					// Stop old task
-					if config.task != nil {
-						config.task.Off()
-					}
-				}
-			}
+			entryID, err := scriptCron.AddFunc(cronExpr, func() {
+				task.run()
+			})
+			if err != nil {
+				panic("插件注册定时任务失败：" + err.Error())
+			}
+			task.taskType = taskType
+			task.rawValue = expr
+			task.cronExpr = cronExpr
+			task.entryID = &entryID
+			ei.dice.Logger.Infof("插件注册定时任务：daily=%s", expr)
+		default:
+			panic(fmt.Sprintf("错误的任务类型：%s，当前仅支持 cron|daily", taskType))
+		}
+
+		if key != "" {
+			config := d.ConfigManager.getConfig(ei.Name, key)
 
 			switch taskType {
 			case "cron":
-				entryID, err := scriptCron.AddFunc(expr, func() {
-					task.run()
-				})
-				if err != nil {
-					panic("插件注册定时任务失败：" + err.Error())
+				config = &ConfigItem{
+					Key:          key,
+					Type:         "task:cron",
+					Group:        group,
+					Value:        expr,
+					DefaultValue: value,
+					Description:  desc,
+					task:         &task,
 				}
-				task.taskType = taskType
-				task.rawValue = expr
-				task.cronExpr = expr
-				task.entryID = &entryID
-				ei.dice.Logger.Infof("插件注册定时任务：cron=%s", expr)
 			case "daily":
-				// 支持每天定时触发，24 小时表示
-				cronExpr, err := parseTaskTime(expr)
-				if err != nil {
-					panic("插件注册定时任务失败：" + err.Error())
-				}
-
-				entryID, err := scriptCron.AddFunc(cronExpr, func() {
-					task.run()
-				})
-				if err != nil {
-					panic("插件注册定时任务失败：" + err.Error())
+				config = &ConfigItem{
+					Key:          key,
+					Type:         "task:daily",
+					Group:        group,
+					Value:        expr,
+					DefaultValue: value,
+					Description:  desc,
+					task:         &task,
 				}
-				task.taskType = taskType
-				task.rawValue = expr
-				task.cronExpr = cronExpr
-				task.entryID = &entryID
-				ei.dice.Logger.Infof("插件注册定时任务：daily=%s", expr)
-			default:
-				panic(fmt.Sprintf("错误的任务类型：%s，当前仅支持 cron|daily", taskType))
-			}
-
-			if key != "" {
-				config := d.ConfigManager.getConfig(ei.Name, key)
-
-				switch taskType {
-				case "cron":
-					config = &ConfigItem{
-						Key:          key,
-						Type:         "task:cron",
-						Group:        group,
-						Value:        expr,
-						DefaultValue: value,
-						Description:  desc,
-						task:         &task,
-					}
-				case "daily":
-					config = &ConfigItem{
-						Key:          key,
-						Type:         "task:daily",
-						Group:        group,
-						Value:        expr,
-						DefaultValue: value,
-						Description:  desc,
-						task:         &task,
-					}
-				}
-				d.ConfigManager.RegisterPluginConfig(ei.Name, config)
 			}
+			d.ConfigManager.RegisterPluginConfig(ei.Name, config)
+		}
 
-			if key == "" {
-				// 如果不提供 key，手动避免 task 失去引用
-				if ei.taskList == nil {
-					ei.taskList = make([]*JsScriptTask, 0)
-					ei.taskList = append(ei.taskList, &task)
-				} else {
-					ei.taskList = append(ei.taskList, &task)
-				}
+		if key == "" {
+			// 如果不提供 key，手动避免 task 失去引用
+			if ei.taskList == nil {
+				ei.taskList = make([]*JsScriptTask, 0)
+				ei.taskList = append(ei.taskList, &task)
+			} else {
+				ei.taskList = append(ei.taskList, &task)
 			}
+		}
 
-			return &task
-		})
+		return &task
+	})
 
-		// COC规则自定义
-		coc := vm.NewObject()
-		_ = coc.Set("newRule", func() *CocRuleInfo {
-			return &CocRuleInfo{}
-		})
-		_ = coc.Set("newRuleCheckResult", func() *CocRuleCheckRet {
-			return &CocRuleCheckRet{}
-		})
-		_ = coc.Set("registerRule", func(rule *CocRuleInfo) bool {
-			return d.CocExtraRulesAdd(rule)
-		})
-		_ = seal.Set("coc", coc)
+	// COC规则自定义
+	coc := vm.NewObject()
+	_ = coc.Set("newRule", func() *CocRuleInfo {
+		return &CocRuleInfo{}
+	})
+	_ = coc.Set("newRuleCheckResult", func() *CocRuleCheckRet {
+		return &CocRuleCheckRet{}
+	})
+	_ = coc.Set("registerRule", func(rule *CocRuleInfo) bool {
+		return d.CocExtraRulesAdd(rule)
+	})
+	_ = seal.Set("coc", coc)
 
-		deck := vm.NewObject()
-		_ = deck.Set("draw", func(ctx *MsgContext, deckName string, isShuffle bool) map[string]interface{} {
-			exists, result, err := deckDraw(ctx, deckName, isShuffle)
-			var errText string
-			if err != nil {
-				errText = err.Error()
-			}
-			return map[string]interface{}{
-				"exists": exists,
-				"err":    errText,
-				"result": result,
-			}
-		})
-		_ = deck.Set("reload", func() {
-			DeckReload(d)
-		})
-		_ = seal.Set("deck", deck)
-
-		_ = seal.Set("replyGroup", ReplyGroup)
-		_ = seal.Set("replyPerson", ReplyPerson)
-		_ = seal.Set("replyToSender", ReplyToSender)
-		_ = seal.Set("memberBan", MemberBan)
-		_ = seal.Set("memberKick", MemberKick)
-		_ = seal.Set("format", DiceFormat)
-		_ = seal.Set("formatTmpl", DiceFormatTmpl)
-		_ = seal.Set("getCtxProxyFirst", GetCtxProxyFirst)
-
-		// 1.2新增
-		_ = seal.Set("newMessage", func() *Message {
-			return &Message{}
-		})
-		_ = seal.Set("createTempCtx", CreateTempCtx)
-		_ = seal.Set("applyPlayerGroupCardByTemplate", func(ctx *MsgContext, tmpl string) string {
-			if tmpl != "" {
-				ctx.Player.AutoSetNameTemplate = tmpl
-			}
-			if ctx.Player.AutoSetNameTemplate != "" {
-				text, _ := SetPlayerGroupCardByTemplate(ctx, ctx.Player.AutoSetNameTemplate)
-				return text
-			}
-			return ""
-		})
-		gameSystem := vm.NewObject()
-		_ = gameSystem.Set("newTemplate", func(data string) error {
-			tmpl, err := loadGameSystemTemplateFromData([]byte(data), "json")
-			if err != nil {
-				return errors.New("解析失败:" + err.Error())
-			}
-			ret := d.GameSystemTemplateAddEx(tmpl, true)
-			if !ret {
-				return errors.New("已存在同名模板")
-			}
-			return nil
-		})
-		_ = gameSystem.Set("newTemplateByYaml", func(data string) error {
-			tmpl, err := loadGameSystemTemplateFromData([]byte(data), "yaml")
-			if err != nil {
-				return errors.New("解析失败:" + err.Error())
-			}
-			ret := d.GameSystemTemplateAddEx(tmpl, true)
-			if !ret {
-				return errors.New("已存在同名模板")
-			}
-			return nil
-		})
-		_ = seal.Set("gameSystem", gameSystem)
-		_ = seal.Set("getCtxProxyAtPos", GetCtxProxyAtPos)
-		_ = seal.Set("getVersion", func() map[string]interface{} {
-			return map[string]interface{}{
-				"versionCode":   VERSION_CODE,
-				"version":       VERSION.String(),
-				"versionSimple": VERSION_MAIN + VERSION_PRERELEASE,
-				"versionDetail": map[string]interface{}{
-					"major":         VERSION.Major(),
-					"minor":         VERSION.Minor(),
-					"patch":         VERSION.Patch(),
-					"prerelease":    VERSION.Prerelease(),
-					"buildMetaData": VERSION.Metadata(),
-				},
-			}
-		})
-		_ = seal.Set("getEndPoints", func() []*EndPointInfo {
-			src := d.ImSession.EndPoints
-			dst := make([]*EndPointInfo, len(src))
-			copy(dst, src)
-			return dst
-		})
+	deck := vm.NewObject()
+	_ = deck.Set("draw", func(ctx *MsgContext, deckName string, isShuffle bool) map[string]interface{} {
+		exists, result, err := deckDraw(ctx, deckName, isShuffle)
+		var errText string
+		if err != nil {
+			errText = err.Error()
+		}
+		return map[string]interface{}{
+			"exists": exists,
+			"err":    errText,
+			"result": result,
+		}
+	})
+	// 群内实体牌组，开启后 draw 的不放回抽取会从中抽牌
+	_ = deck.Set("reshuffle", func(ctx *MsgContext, deckName string) (int, error) {
+		if ctx == nil || ctx.Group == nil {
+			return 0, errors.New("当前上下文没有群组")
+		}
+		return groupDeckReshuffle(ctx, deckName)
+	})
+	_ = deck.Set("remain", func(ctx *MsgContext, deckName string) map[string]interface{} {
+		if ctx == nil || ctx.Group == nil {
+			return map[string]interface{}{"enabled": false}
+		}
+		remaining, total, ok := groupDeckRemain(ctx, deckName)
+		return map[string]interface{}{
+			"enabled":   ok,
+			"remaining": remaining,
+			"total":     total,
+		}
+	})
+	_ = deck.Set("close", func(ctx *MsgContext, deckName string) bool {
+		if ctx == nil || ctx.Group == nil {
+			return false
+		}
+		return groupDeckClose(ctx, deckName)
+	})
+	_ = deck.Set("reload", func() {
+		DeckReload(d)
+	})
+	_ = seal.Set("deck", deck)
+
+	_ = seal.Set("replyGroup", ReplyGroup)
+	_ = seal.Set("replyPerson", ReplyPerson)
+	_ = seal.Set("replyToSender", ReplyToSender)
+	_ = seal.Set("memberBan", MemberBan)
+	_ = seal.Set("memberKick", MemberKick)
+	_ = seal.Set("format", DiceFormat)
+	_ = seal.Set("formatTmpl", DiceFormatTmpl)
+	_ = seal.Set("getCtxProxyFirst", GetCtxProxyFirst)
+
+	// 1.2新增
+	_ = seal.Set("newMessage", func() *Message {
+		return &Message{}
+	})
+	_ = seal.Set("createTempCtx", CreateTempCtx)
+	_ = seal.Set("applyPlayerGroupCardByTemplate", func(ctx *MsgContext, tmpl string) string {
+		if tmpl != "" {
+			ctx.Player.AutoSetNameTemplate = tmpl
+		}
+		if ctx.Player.AutoSetNameTemplate != "" {
+			text, _ := SetPlayerGroupCardByTemplate(ctx, ctx.Player.AutoSetNameTemplate)
+			return text
+		}
+		return ""
+	})
+	gameSystem := vm.NewObject()
+	_ = gameSystem.Set("newTemplate", func(data string) error {
+		tmpl, err := loadGameSystemTemplateFromData([]byte(data), "json")
+		if err != nil {
+			return errors.New("解析失败:" + err.Error())
+		}
+		ret := d.GameSystemTemplateAddEx(tmpl, true)
+		if !ret {
+			return errors.New("已存在同名模板")
+		}
+		return nil
+	})
+	_ = gameSystem.Set("newTemplateByYaml", func(data string) error {
+		tmpl, err := loadGameSystemTemplateFromData([]byte(data), "yaml")
+		if err != nil {
+			return errors.New("解析失败:" + err.Error())
+		}
+		ret := d.GameSystemTemplateAddEx(tmpl, true)
+		if !ret {
+			return errors.New("已存在同名模板")
+		}
+		return nil
+	})
+	_ = seal.Set("gameSystem", gameSystem)
+	_ = seal.Set("getCtxProxyAtPos", GetCtxProxyAtPos)
+	_ = seal.Set("getVersion", func() map[string]interface{} {
+		return map[string]interface{}{
+			"versionCode":   VERSION_CODE,
+			"version":       VERSION.String(),
+			"versionSimple": VERSION_MAIN + VERSION_PRERELEASE,
+			"versionDetail": map[string]interface{}{
+				"major":         VERSION.Major(),
+				"minor":         VERSION.Minor(),
+				"patch":         VERSION.Patch(),
+				"prerelease":    VERSION.Prerelease(),
+				"buildMetaData": VERSION.Metadata(),
+			},
+		}
+	})
+	_ = seal.Set("ipc", jsIPCUnavailable(vm))
+	_ = seal.Set("getEndPoints", func() []*EndPointInfo {
+		src := d.ImSession.EndPoints
+		dst := make([]*EndPointInfo, len(src))
+		copy(dst, src)
+		return dst
+	})
 
-		_ = vm.Set("atob", func(s string) (string, error) {
-			// Remove data URI scheme and any whitespace from the string.
-			s = strings.ReplaceAll(s, "data:text/plain;base64,", "")
-			s = strings.ReplaceAll(s, " ", "")
+	_ = vm.Set("atob", func(s string) (string, error) {
+		// Remove data URI scheme and any whitespace from the string.
+		s = strings.ReplaceAll(s, "data:text/plain;base64,", "")
+		s = strings.ReplaceAll(s, " ", "")
 
-			// Decode the base64-encoded string.
-			b, err := base64.StdEncoding.DecodeString(s)
-			if err != nil {
-				return "", errors.New("atob: 不合法的base64字串")
-			}
-			return string(b), nil
-		})
-		_ = vm.Set("btoa", func(s string) string {
-			// 编码
-			return base64.StdEncoding.EncodeToString([]byte(s))
-		})
-		// 1.2新增结束
-		_ = seal.Set("setPlayerGroupCard", SetPlayerGroupCardByTemplate)
-		_ = seal.Set("base64ToImage", Base64ToImageFunc())
-
-		// Note: Szzrain 暴露dice对象给js会导致js可以调用dice的所有Export的方法
-		// 这是不安全的, 所有需要用到dice实例的函数都可以以传入ctx作为替代
-		// _ = seal.Set("inst", d)
-		_ = vm.Set("__dirname", "")
-		_ = vm.Set("seal", seal)
-
-		// Note(Szzrain): 不要修改原型链, 会导致一些奇怪的问题，比如无法使用某些 TS 库
-		//		_, _ = vm.RunString(`
-		// let e = seal.ext.new('_', '', '');
-		// e.__proto__.storageSet = function(k, v) {
-		//  try {
-		//    // 这里goja会强行抛出异常，等于是将返回error的函数转写成throw形式
-		//    this.storageSetRaw(k, v)
-		//  } catch (error) {
-		//    throw error;
-		//  }
-		// }
-		// e.__proto__.storageGet = function(k, v) {
-		//  try {
-		//    return this.storageGetRaw(k, v);
-		//  } catch (error) {
-		//    if (error.value.toString() !== 'not found') {
-		//      throw error;
-		//    }
-		//  }
-		// }
-		// `)
-		_, _ = vm.RunString(`Object.freeze(seal);Object.freeze(seal.deck);Object.freeze(seal.coc);Object.freeze(seal.ext);Object.freeze(seal.vars);`)
+		// Decode the base64-encoded string.
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", errors.New("atob: 不合法的base64字串")
+		}
+		return string(b), nil
 	})
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				d.Logger.Errorf("JS核心执行异常: %v 堆栈: %v", r, string(debug.Stack()))
-			}
-		}()
-		loop.StartInForeground()
-	}()
-	// loop.Start()
-	(&d.Config).JsEnable = true
-	d.Logger.Info("已加载JS环境")
-	d.MarkModified()
-	d.Save(false)
+	_ = vm.Set("btoa", func(s string) string {
+		// 编码
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	})
+	// 1.2新增结束
+	_ = seal.Set("setPlayerGroupCard", SetPlayerGroupCardByTemplate)
+	_ = seal.Set("base64ToImage", Base64ToImageFunc())
+
+	// Note: Szzrain 暴露dice对象给js会导致js可以调用dice的所有Export的方法
+	// 这是不安全的, 所有需要用到dice实例的函数都可以以传入ctx作为替代
+	// _ = seal.Set("inst", d)
+	_ = vm.Set("__dirname", "")
+	_ = vm.Set("seal", seal)
+
+	// Note(Szzrain): 不要修改原型链, 会导致一些奇怪的问题，比如无法使用某些 TS 库
+	//		_, _ = vm.RunString(`
+	// let e = seal.ext.new('_', '', '');
+	// e.__proto__.storageSet = function(k, v) {
+	//  try {
+	//    // 这里goja会强行抛出异常，等于是将返回error的函数转写成throw形式
+	//    this.storageSetRaw(k, v)
+	//  } catch (error) {
+	//    throw error;
+	//  }
+	// }
+	// e.__proto__.storageGet = function(k, v) {
+	//  try {
+	//    return this.storageGetRaw(k, v);
+	//  } catch (error) {
+	//    if (error.value.toString() !== 'not found') {
+	//      throw error;
+	//    }
+	//  }
+	// }
+	// `)
+	_, _ = vm.RunString(`Object.freeze(seal);Object.freeze(seal.deck);Object.freeze(seal.coc);Object.freeze(seal.ext);Object.freeze(seal.vars);`)
 }
 
 func (d *Dice) JsShutdown() {
@@ -710,7 +736,9 @@ func (d *Dice) jsClear() {
 	// 清理coc扩展规则
 	d.CocExtraRules = map[int]*CocRuleInfo{}
 	// 清理脚本列表
+	d.jsScriptLock.Lock()
 	d.JsScriptList = []*JsScriptInfo{}
+	d.jsScriptLock.Unlock()
 	// 清理 JS 注册的规则模板，同时恢复内置、用户和已启用扩展包模板。
 	if d.PackageManager == nil {
 		d.resetGameSystemTemplates()
@@ -720,6 +748,10 @@ func (d *Dice) jsClear() {
 			d.Logger.Errorf("JS 环境清理后恢复用户及 sealpack 规则模板失败，当前仅保留内置模板: %v", err)
 		}
 	}
+	// 旧运行时中注册的扩展包间通信回调随之失效
+	if d.PackageManager != nil {
+		d.PackageManager.IPC().Reset()
+	}
 	// JsEnable=false 的启动状态下 ExtLoopManager 可能尚未初始化
 	if d.ExtLoopManager != nil {
 		d.ExtLoopManager.SetLoop(nil)
@@ -732,6 +764,8 @@ func isScriptFile(filename string) bool {
 }
 
 func (d *Dice) JsLoadScripts() {
+	d.jsScriptLock.Lock()
+	defer d.jsScriptLock.Unlock()
 	d.JsScriptList = []*JsScriptInfo{}
 
 	path := filepath.Join(d.BaseConfig.DataDir, "scripts")
@@ -866,6 +900,8 @@ func (d *Dice) JsLoadScripts() {
 		d.Logger.Warnf("插件「%s」拒绝加载：\n%s", strings.Join(keys, "、"), strings.Join(unloadInfos, "\n"))
 	}
 
+	jsAssignRuntimes(sortedJsInfos, d.Config.JsIsolation)
+
 	// 按顺序加载
 	for _, jsInfo := range sortedJsInfos {
 		if len(jsInfo.Depends) == 0 {
@@ -910,7 +946,9 @@ func (d *Dice) JsReload() {
 	d.JsLoadScripts()
 
 	// 更新扩展变更时间戳，触发延迟更新
+	d.jsScriptLock.Lock()
 	d.ExtUpdateTime = time.Now().Unix()
+	d.jsScriptLock.Unlock()
 
 	d.MarkModified()
 	d.Save(false)
@@ -1013,6 +1051,9 @@ type JsScriptInfo struct {
 	Depends []JsScriptDepends `json:"depends"`
 	/** 需要被编译 */
 	needCompiled bool
+	/** 隔离模式下所在的运行时 */
+	runtimeName string
+	loopVersion int64
 	/** 扩展商店唯一 ID */
 	StoreID string `json:"storeID"`
 	/** Owning package ID */
@@ -1170,7 +1211,12 @@ func (d *Dice) JsLoadScriptRaw(jsInfo *JsScriptInfo) {
 		var targetPath string
 		if jsInfo.needCompiled {
 			d.Logger.Infof("脚本<%s>正在经过编译处理……", jsInfo.Name)
-			targetPath, err = tsScriptCompile(jsInfo.Filename)
+			// 扩展包脚本编译到包目录下，使编译结果仍按包内脚本加载
+			var outDir string
+			if jsInfo.PackageID != "" {
+				outDir = filepath.Dir(jsInfo.Filename)
+			}
+			targetPath, err = tsScriptCompile(jsInfo.Filename, outDir)
 			defer func(name string) {
 				_ = os.Remove(name)
 			}(targetPath)
@@ -1178,7 +1224,7 @@ func (d *Dice) JsLoadScriptRaw(jsInfo *JsScriptInfo) {
 			targetPath = jsInfo.Filename
 		}
 		if err == nil {
-			_, err = d.ExtLoopManager.GetWebLoop().RequireModule(targetPath)
+			err = d.jsRequire(jsInfo, targetPath)
 		}
 		d.JsLoadingScript = nil
 	} else {
@@ -1193,7 +1239,33 @@ func (d *Dice) JsLoadScriptRaw(jsInfo *JsScriptInfo) {
 	}
 }
 
-func tsScriptCompile(path string) (string, error) {
+// jsRequire 在脚本所属的运行时中加载脚本，加载过程同样受时间限制
+func (d *Dice) jsRequire(jsInfo *JsScriptInfo, path string) error {
+	if jsInfo.runtimeName == "" {
+		_, err := d.ExtLoopManager.GetWebLoop().RequireModule(path)
+		return err
+	}
+	version := d.jsIsolatedRuntime(jsInfo.runtimeName)
+	jsInfo.loopVersion = version
+	loop, err := d.ExtLoopManager.GetLoop(version)
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := loop.RequireModule(path)
+		done <- err
+	}()
+	err = d.jsWait(version, d.ExtLoopManager.runtime(version), done)
+	if errors.Is(err, ErrJsHookTimeout) {
+		// JsLoadScripts 已持有 jsScriptLock
+		d.jsDisableRuntimeLocked(version, err)
+	}
+	return err
+}
+
+// tsScriptCompile 将 ts 脚本编译为 js 临时文件，outDir 为空时写入系统临时目录
+func tsScriptCompile(path string, outDir string) (string, error) {
 	script, err := os.ReadFile(path)
 	if err != nil {
 		return "", err
@@ -1208,7 +1280,7 @@ func tsScriptCompile(path string) (string, error) {
 		}
 		return "", errors.New(msg.String())
 	}
-	compiledPath, err := os.CreateTemp("", "compiled-*-"+filepath.Base(path))
+	compiledPath, err := os.CreateTemp(outDir, "compiled-*-"+filepath.Base(path))
 	if err != nil {
 		return "", err
 	}
@@ -1280,28 +1352,34 @@ func JsDelete(d *Dice, jsInfo *JsScriptInfo) {
 		}
 
 		// 更新时间戳
+		d.jsScriptLock.Lock()
 		d.ExtUpdateTime = time.Now().Unix()
+		d.jsScriptLock.Unlock()
 	}
 }
 
 func JsEnable(d *Dice, jsInfoName string) {
 	delete((&d.Config).DisabledJsScripts, jsInfoName)
+	d.jsScriptLock.Lock()
 	for _, jsInfo := range d.JsScriptList {
 		if jsInfo.Name == jsInfoName {
 			jsInfo.Enable = true
 		}
 	}
+	d.jsScriptLock.Unlock()
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
 }
 
 func JsDisable(d *Dice, jsInfoName string) {
 	(&d.Config).DisabledJsScripts[jsInfoName] = true
+	d.jsScriptLock.Lock()
 	for _, jsInfo := range d.JsScriptList {
 		if jsInfo.Name == jsInfoName {
 			jsInfo.Enable = false
 		}
 	}
+	d.jsScriptLock.Unlock()
 	d.LastUpdatedTime = time.Now().Unix()
 	d.Save(false)
 }
@@ -42,11 +42,8 @@ func TestJsInit_WhenExtLoopManagerNil_DoesNotPanic(t *testing.T) {
 	d.Config.JsEnable = false
 	d.ExtLoopManager = nil
 
-	defer func() {
-		if r := recover(); r != nil {
-			t.Fatalf("JsInit should not panic when ExtLoopManager is nil, got: %v", r)
-		}
-		// 清理后台任务，避免测试进程残留 goroutine
+	// 清理后台任务，避免测试进程残留 goroutine
+	t.Cleanup(func() {
 		if d.JsScriptCron != nil {
 			d.JsScriptCron.Stop()
 			d.JsScriptCron = nil
@@ -54,6 +51,11 @@ func TestJsInit_WhenExtLoopManagerNil_DoesNotPanic(t *testing.T) {
 		if d.ExtLoopManager != nil {
 			d.ExtLoopManager.SetLoop(nil)
 		}
+	})
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("JsInit should not panic when ExtLoopManager is nil, got: %v", r)
+		}
 	}()
 
 	d.JsInit()
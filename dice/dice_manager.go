@@ -1,6 +1,7 @@
 package dice
 
 import (
+	"context"
 	"errors"
 	"os"
 	"strings"
@@ -55,6 +56,7 @@ type DiceManager struct { //nolint:revive
 	UIPasswordHash string
 	UIPasswordSalt string
 	AccessTokens   SyncMap[string, bool]
+	UIAccounts     UIAccounts // 骰主之外的 WebUI 账户与 API 令牌
 	IsReady        bool
 
 	AutoBackupEnable    bool
@@ -70,6 +72,10 @@ type DiceManager struct { //nolint:revive
 	BackupCleanCron      string              // 如果使用cron触发, 表达式
 	backupCleanCronID    cron.EntryID
 
+	RemoteBackup RemoteBackupConfig // 增量备份
+	Metrics      MetricsConfig      // Prometheus 指标接口
+	remoteBackup remoteBackupState
+
 	AppBootTime      int64
 	AppVersionCode   int64
 	AppVersionOnline *VersionInfo
@@ -100,9 +106,11 @@ type Configs struct { //nolint:revive
 	WebUIAddress      string       `yaml:"webUIAddress"`
 	HelpDocEngineType int          `yaml:"helpDocEngineType"`
 
-	UIPasswordSalt string   `yaml:"UIPasswordFrontendSalt"`
-	UIPasswordHash string   `yaml:"uiPasswordHash"`
-	AccessTokens   []string `yaml:"accessTokens"` //nolint:gosec
+	UIPasswordSalt string     `yaml:"UIPasswordFrontendSalt"`
+	UIPasswordHash string     `yaml:"uiPasswordHash"`
+	AccessTokens   []string   `yaml:"accessTokens"` //nolint:gosec
+	UIUsers        []*UIUser  `yaml:"uiUsers"`
+	UITokens       []*UIToken `yaml:"uiTokens"`
 
 	AutoBackupEnable    bool   `yaml:"autoBackupEnable"`
 	AutoBackupTime      string `yaml:"autoBackupTime"`
@@ -116,6 +124,9 @@ type Configs struct { //nolint:revive
 		Cron      string `yaml:"cron"`
 	} `yaml:"backupClean"`
 
+	RemoteBackup RemoteBackupConfig `yaml:"remoteBackup"`
+	Metrics      MetricsConfig      `yaml:"metrics"`
+
 	ServiceName string `yaml:"serviceName"`
 
 	ConfigVersion int `yaml:"configVersion"`
@@ -167,6 +178,7 @@ func (dm *DiceManager) LoadDice() {
 	log := logger.M()
 	dm.AppVersionCode = VERSION_CODE
 	dm.AppBootTime = time.Now().Unix()
+	metricsSource.Store(dm)
 
 	_ = os.MkdirAll(BackupDir, 0755)
 	_ = os.MkdirAll("./data/images", 0755)
@@ -227,10 +239,13 @@ func (dm *DiceManager) LoadDice() {
 	dm.BackupCleanKeepDur = time.Duration(dc.BackupClean.KeepDur)
 	dm.BackupCleanTrigger = BackupCleanTrigger(dc.BackupClean.Trigger)
 	dm.BackupCleanCron = dc.BackupClean.Cron
+	dm.RemoteBackup = dc.RemoteBackup
+	dm.Metrics = dc.Metrics
 
 	for _, i := range dc.AccessTokens {
 		dm.AccessTokens.Store(i, true)
 	}
+	dm.UIAccounts.load(dc.UIUsers, dc.UITokens)
 
 	for _, i := range dc.DiceConfigs {
 		newDice := new(Dice)
@@ -256,6 +271,8 @@ func (dm *DiceManager) Save() {
 	dc.BackupClean.KeepDur = int64(dm.BackupCleanKeepDur)
 	dc.BackupClean.Trigger = int(dm.BackupCleanTrigger)
 	dc.BackupClean.Cron = dm.BackupCleanCron
+	dc.RemoteBackup = dm.RemoteBackup
+	dc.Metrics = dm.Metrics
 	dc.ServiceName = dm.ServiceName
 	dc.ConfigVersion = 9914
 
@@ -263,6 +280,7 @@ func (dm *DiceManager) Save() {
 		dc.AccessTokens = append(dc.AccessTokens, k)
 		return true
 	})
+	dc.UIUsers, dc.UITokens = dm.UIAccounts.dump()
 
 	for _, i := range dm.Dice {
 		dc.DiceConfigs = append(dc.DiceConfigs, i.BaseConfig)
@@ -345,6 +363,7 @@ func (dm *DiceManager) InitDice(writer *logger.UIWriter) {
 
 	dm.ResetAutoBackup()
 	dm.ResetBackupClean()
+	_, _ = dm.Cron.AddFunc("@every 5s", dm.ObserveEndpointStates)
 }
 
 func (dm *DiceManager) ResetAutoBackup() {
@@ -364,6 +383,10 @@ func (dm *DiceManager) ResetAutoBackup() {
 			if errBackup = dm.BackupClean(true); errBackup != nil {
 				log.Errorf("滚动清理备份失败: %v", errBackup)
 			}
+			if dm.RemoteBackup.Enable {
+				// 结果和错误已通过通知和日志报告
+				_, _ = dm.BackupRemote(context.Background(), dm.AutoBackupSelection)
+			}
 		})
 		if err != nil {
 			log.Errorf("设定的自动备份间隔有误: %v", err)
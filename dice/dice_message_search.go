@@ -0,0 +1,227 @@
+package dice
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"sealdice-core/dice/docengine"
+	"sealdice-core/dice/service"
+)
+
+// 建立全文索引时每批读取的消息数
+const messageIndexBatchSize = 2000
+
+// 搜索结果中有已删除的消息时，最多重新搜索的次数
+const messageIndexSearchRetry = 3
+
+var (
+	ErrMessageIndexDisabled = errors.New("全文索引未启用")
+	ErrMessageFullTextRegex = errors.New("全文搜索仅支持跑团日志，且不能与正则同时使用")
+)
+
+// MessageIndexManager 跑团日志的全文索引，可选功能，索引目录存在即视为启用
+type MessageIndexManager struct {
+	parent   *Dice
+	mu       sync.Mutex
+	dir      string
+	index    *docengine.MessageIndex
+	building atomic.Bool
+}
+
+// MessageIndexStatus 全文索引状态
+type MessageIndexStatus struct {
+	Enabled  bool   `json:"enabled"`
+	Building bool   `json:"building"`
+	LastID   uint64 `json:"lastId"`
+}
+
+func NewMessageIndexManager(d *Dice) *MessageIndexManager {
+	m := &MessageIndexManager{parent: d, dir: docengine.DefaultMessageIndexDir}
+	if docengine.MessageIndexExists(m.dir) {
+		if err := m.Enable(); err != nil {
+			d.Logger.Errorf("打开消息全文索引失败: %v", err)
+		}
+	}
+	return m
+}
+
+func (m *MessageIndexManager) Enabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.index != nil
+}
+
+// Enable 打开索引并在后台补齐未索引的消息
+func (m *MessageIndexManager) Enable() error {
+	m.mu.Lock()
+	if m.index == nil {
+		index, err := docengine.OpenMessageIndex(m.dir)
+		if err != nil {
+			m.mu.Unlock()
+			return err
+		}
+		m.index = index
+	}
+	m.mu.Unlock()
+	go m.Sync()
+	return nil
+}
+
+// Disable 关闭并删除索引
+func (m *MessageIndexManager) Disable() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.index != nil {
+		m.index.Close()
+		m.index = nil
+	}
+	return os.RemoveAll(m.dir)
+}
+
+func (m *MessageIndexManager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.index != nil {
+		m.index.Close()
+		m.index = nil
+	}
+}
+
+func (m *MessageIndexManager) current() *docengine.MessageIndex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.index
+}
+
+// Sync 增量索引上次之后写入的日志消息，同一时间只有一个在运行
+func (m *MessageIndexManager) Sync() {
+	index := m.current()
+	if index == nil || !m.building.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.building.Store(false)
+
+	for {
+		items, err := service.MessageListAfter(m.parent.DBOperator, index.LastID(), messageIndexBatchSize)
+		if err != nil {
+			m.parent.Logger.Errorf("读取待索引的消息失败: %v", err)
+			return
+		}
+		docs := make([]docengine.MessageDoc, 0, len(items))
+		for _, item := range items {
+			docs = append(docs, docengine.MessageDoc{
+				ID:      item.ID,
+				GroupID: item.GroupID,
+				UserIDs: []string{item.UserID, item.UniformID},
+				Time:    item.Time,
+				Message: item.Message,
+			})
+		}
+		if err = index.Add(docs); err != nil {
+			// 索引被关闭时也会走到这里
+			if m.current() == index {
+				m.parent.Logger.Errorf("写入消息全文索引失败: %v", err)
+			}
+			return
+		}
+		if len(items) < messageIndexBatchSize {
+			return
+		}
+	}
+}
+
+// Remove 从索引中移除已删除的消息
+func (m *MessageIndexManager) Remove(ids []uint64) {
+	index := m.current()
+	if index == nil || len(ids) == 0 {
+		return
+	}
+	if err := index.Delete(ids); err != nil {
+		m.parent.Logger.Errorf("从消息全文索引中移除消息失败: %v", err)
+	}
+}
+
+func (m *MessageIndexManager) Status() MessageIndexStatus {
+	index := m.current()
+	status := MessageIndexStatus{Enabled: index != nil, Building: m.building.Load()}
+	if index != nil {
+		status.LastID = index.LastID()
+	}
+	return status
+}
+
+// MessageSearch 跨群搜索消息。FullText 时使用全文索引，否则直接查询数据库
+func (d *Dice) MessageSearch(q service.QueryMessageSearch) (*service.MessageSearchResult, error) {
+	if !q.FullText {
+		return service.MessageSearch(d.DBOperator, q)
+	}
+	if (q.Source != "" && q.Source != service.MessageSourceLog) || q.Regex != "" {
+		return nil, ErrMessageFullTextRegex
+	}
+	index := d.MessageIndex.current()
+	if index == nil {
+		return nil, ErrMessageIndexDisabled
+	}
+	// 先补上新写入的消息，本次搜索可能还看不到它们
+	go d.MessageIndex.Sync()
+
+	query := docengine.MessageQuery{
+		Text:      q.Keyword,
+		GroupID:   q.GroupID,
+		UserID:    q.UserID,
+		TimeBegin: q.TimeBegin,
+		TimeEnd:   q.TimeEnd,
+		PageSize:  q.PageSize,
+		PageNum:   q.PageNum,
+	}
+	var items []*service.MessageSearchItem
+	var total uint64
+	for range messageIndexSearchRetry {
+		var ids []uint64
+		var err error
+		if ids, total, err = index.Search(query); err != nil {
+			return nil, err
+		}
+		if items, err = service.MessageGetByIDs(d.DBOperator, ids); err != nil {
+			return nil, err
+		}
+		if len(items) == len(ids) {
+			break
+		}
+		// 撤回等操作删掉的消息仍留在索引里，移除后重新搜索，使总数和分页准确
+		found := make(map[uint64]bool, len(items))
+		for _, item := range items {
+			found[item.ID] = true
+		}
+		var missing []uint64
+		for _, id := range ids {
+			if !found[id] {
+				missing = append(missing, id)
+			}
+		}
+		if err = index.Delete(missing); err != nil {
+			return nil, err
+		}
+	}
+	return &service.MessageSearchResult{Total: int64(total), Items: items}, nil
+}
+
+// LogDelete 删除日志，并从全文索引中移除其中的消息
+func (d *Dice) LogDelete(groupID string, logName string) error {
+	var ids []uint64
+	if d.MessageIndex != nil && d.MessageIndex.Enabled() {
+		var err error
+		if ids, err = service.LogItemIDs(d.DBOperator, groupID, logName); err != nil {
+			return err
+		}
+	}
+	if err := service.LogDelete(d.DBOperator, groupID, logName); err != nil {
+		return err
+	}
+	if d.MessageIndex != nil {
+		d.MessageIndex.Remove(ids)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package dice //nolint:testpackage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"sealdice-core/dice/docengine"
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func TestMessageSearchFullTextSkipsDeletedLogs(t *testing.T) {
+	mockDB := newLogAliasTestDB(t)
+	d := &Dice{DBOperator: mockDB, Logger: zap.NewNop().Sugar()}
+	index, err := docengine.OpenMessageIndex(filepath.Join(t.TempDir(), "messages"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.MessageIndex = &MessageIndexManager{parent: d, index: index}
+	t.Cleanup(d.MessageIndex.Close)
+
+	groupID := "QQ-Group:1"
+	for _, line := range []struct{ log, msg string }{
+		{"a", "地下室的门开了"}, {"a", "地下室里很黑"}, {"b", "地下室有脚步声"},
+	} {
+		if !LogAppend(&MsgContext{Dice: d}, groupID, 0, line.log, &model.LogOneItem{IMUserID: "QQ:1", Message: line.msg}) {
+			t.Fatalf("LogAppend(%q) failed", line.log)
+		}
+	}
+	d.MessageIndex.Sync()
+
+	search := func() *service.MessageSearchResult {
+		t.Helper()
+		got, searchErr := d.MessageSearch(service.QueryMessageSearch{FullText: true, Keyword: "地下室", PageNum: 1, PageSize: 2})
+		if searchErr != nil {
+			t.Fatal(searchErr)
+		}
+		return got
+	}
+	if got := search(); got.Total != 3 || len(got.Items) != 2 {
+		t.Fatalf("MessageSearch() = %d items (total %d), want 2 (total 3)", len(got.Items), got.Total)
+	}
+
+	if err = d.LogDelete(groupID, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := search(); got.Total != 1 || len(got.Items) != 1 || got.Items[0].LogName != "b" {
+		t.Fatalf("MessageSearch() after LogDelete = %+v (total %d)", got.Items, got.Total)
+	}
+
+	// 绕过 LogDelete 删除的消息在搜索时被清理
+	if err = mockDB.GetLogDB(constant.WRITE).Where("1 = 1").Delete(&model.LogOneItem{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := search(); got.Total != 0 || len(got.Items) != 0 {
+		t.Fatalf("MessageSearch() after recall = %+v (total %d)", got.Items, got.Total)
+	}
+}
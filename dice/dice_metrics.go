@@ -0,0 +1,143 @@
+package dice
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"sealdice-core/dice/censor"
+	"sealdice-core/utils/metrics"
+)
+
+// MetricsConfig Prometheus 指标接口的配置，默认关闭
+type MetricsConfig struct {
+	Enable bool   `json:"enable" yaml:"enable"`
+	Token  string `json:"token"  yaml:"token"` // 抓取时通过 Authorization: Bearer 或 ?token= 提供
+}
+
+// Metrics 进程内的全部指标，多个 Dice 实例共用
+var Metrics = metrics.NewRegistry()
+
+var (
+	metricMessagesReceived = Metrics.NewCounterVec("sealdice_messages_received_total",
+		"Messages received from the platform.", "endpoint", "platform")
+	metricMessagesSent = Metrics.NewCounterVec("sealdice_messages_sent_total",
+		"Messages sent to the platform.", "endpoint", "platform")
+	metricCommandExecutions = Metrics.NewCounterVec("sealdice_command_executions_total",
+		"Commands executed, labelled by whether the command handled the message.", "command", "extension", "solved")
+	metricCommandDuration = Metrics.NewHistogramVec("sealdice_command_duration_seconds",
+		"Command execution latency.", nil, "command", "extension")
+	metricJsHookDuration = Metrics.NewHistogramVec("sealdice_js_hook_duration_seconds",
+		"Time spent in JS extension hooks and commands.", nil, "extension", "runtime")
+	metricCensorHits = Metrics.NewCounterVec("sealdice_censor_hits_total",
+		"Messages hitting the sensitive word filter, by highest level.", "level")
+	metricRateLimitTriggers = Metrics.NewCounterVec("sealdice_rate_limit_triggers_total",
+		"Messages rejected by the spam rate limiter.", "scope")
+	metricAdapterTransitions = Metrics.NewCounterVec("sealdice_adapter_state_transitions_total",
+		"Adapter connection state changes.", "endpoint", "platform", "from", "to")
+	metricAttrsSaveDuration = Metrics.NewHistogramVec("sealdice_attrs_save_duration_seconds",
+		"Latency of the periodic attribute batch write.", nil, "result")
+	metricAttrsSaveRows = Metrics.NewCounterVec("sealdice_attrs_saved_rows_total",
+		"Attribute rows written by the periodic batch write.")
+	metricBackupDuration = Metrics.NewHistogramVec("sealdice_backup_duration_seconds",
+		"Backup duration.", []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800}, "kind", "result")
+)
+
+var endpointStateNames = map[EndpointState]string{
+	StateDisconnected:     "disconnected",
+	StateConnected:        "connected",
+	StateConnecting:       "connecting",
+	StateConnectionFailed: "failed",
+}
+
+func endpointStateName(s EndpointState) string {
+	if name, ok := endpointStateNames[s]; ok {
+		return name
+	}
+	return strconv.Itoa(int(s))
+}
+
+var censorLevelNames = map[censor.Level]string{
+	censor.Notice:  "notice",
+	censor.Caution: "caution",
+	censor.Warning: "warning",
+	censor.Danger:  "danger",
+}
+
+func metricResult(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// endpointLastState 上次采样到的账号状态，用于统计状态变化
+var endpointLastState SyncMap[string, EndpointState]
+
+// ObserveEndpointStates 对比各账号的连接状态并记录变化。
+// 各适配器直接修改 State 字段，因此这里定期采样，抓取时也会调用一次
+func (dm *DiceManager) ObserveEndpointStates() {
+	if !dm.Metrics.Enable {
+		return
+	}
+	for _, d := range dm.Dice {
+		if d.ImSession == nil {
+			continue
+		}
+		for _, ep := range d.ImSession.EndPoints {
+			last, ok := endpointLastState.Load(ep.ID)
+			if ok && last != ep.State {
+				metricAdapterTransitions.With(ep.ID, ep.Platform, endpointStateName(last), endpointStateName(ep.State)).Inc()
+			}
+			endpointLastState.Store(ep.ID, ep.State)
+		}
+	}
+}
+
+// metricsSource 抓取时读取账号统计的 DiceManager，在 LoadDice 时设置
+var metricsSource atomic.Pointer[DiceManager]
+
+func eachMetricsEndpoint(fn func(ep *EndPointInfo)) {
+	dm := metricsSource.Load()
+	if dm == nil {
+		return
+	}
+	for _, d := range dm.Dice {
+		if d.ImSession == nil {
+			continue
+		}
+		for _, ep := range d.ImSession.EndPoints {
+			fn(ep)
+		}
+	}
+}
+
+func init() {
+	labels := []string{"endpoint", "platform", "user_id"}
+	Metrics.NewGaugeFunc("sealdice_adapter_state",
+		"Adapter connection state: 0 disconnected, 1 connected, 2 connecting, 3 failed.", labels,
+		func(emit func(float64, ...string)) {
+			eachMetricsEndpoint(func(ep *EndPointInfo) {
+				emit(float64(ep.State), ep.ID, ep.Platform, ep.UserID)
+			})
+		})
+	Metrics.NewCounterFunc("sealdice_endpoint_commands_executed_total",
+		"Commands executed by the account, persisted across restarts.", labels,
+		func(emit func(float64, ...string)) {
+			eachMetricsEndpoint(func(ep *EndPointInfo) {
+				emit(float64(ep.CmdExecutedNum), ep.ID, ep.Platform, ep.UserID)
+			})
+		})
+	Metrics.NewGaugeFunc("sealdice_endpoint_groups", "Groups the account is in.", labels,
+		func(emit func(float64, ...string)) {
+			eachMetricsEndpoint(func(ep *EndPointInfo) {
+				emit(float64(ep.GroupNum), ep.ID, ep.Platform, ep.UserID)
+			})
+		})
+	Metrics.NewGaugeFunc("sealdice_uptime_seconds", "Seconds since the process started.", nil,
+		func(emit func(float64, ...string)) {
+			if dm := metricsSource.Load(); dm != nil {
+				emit(float64(time.Now().Unix() - dm.AppBootTime))
+			}
+		})
+}
@@ -0,0 +1,33 @@
+package dice //nolint:testpackage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObserveEndpointStates(t *testing.T) {
+	ep := &EndPointInfo{EndPointInfoBase: EndPointInfoBase{ID: "metrics-test-ep", Platform: "QQ", State: StateConnecting}}
+	d := &Dice{ImSession: &IMSession{EndPoints: []*EndPointInfo{ep}}}
+	dm := &DiceManager{Dice: []*Dice{d}, Metrics: MetricsConfig{Enable: true}}
+	metricsSource.Store(dm)
+	defer metricsSource.Store(nil)
+
+	dm.ObserveEndpointStates()
+	ep.State = StateConnected
+	dm.ObserveEndpointStates()
+	dm.ObserveEndpointStates()
+
+	var b strings.Builder
+	if _, err := Metrics.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, line := range []string{
+		`sealdice_adapter_state_transitions_total{endpoint="metrics-test-ep",platform="QQ",from="connecting",to="connected"} 1`,
+		`sealdice_adapter_state{endpoint="metrics-test-ep",platform="QQ",user_id=""} 1`,
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("metrics output missing %q", line)
+		}
+	}
+}
@@ -0,0 +1,620 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/robfig/cron/v3"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+)
+
+// 每个群最多保存的日程数
+const scheduleMaxPerGroup = 10
+
+var (
+	ErrScheduleTimeFormat = errors.New("无法识别的时间，可用格式如: 每周五20:00 每天21:30 每月1日9:00 明天20:00 2026-10-20 20:00 cron 0 20 * * 5")
+	ErrSchedulePast       = errors.New("提醒时间已经过去了")
+	ErrScheduleTooMany    = fmt.Errorf("每个群最多设置%d条日程", scheduleMaxPerGroup)
+	ErrScheduleNotFound   = errors.New("没有找到这条日程")
+	ErrScheduleNoContent  = errors.New("请输入提醒内容")
+)
+
+var (
+	scheduleTimeRe = regexp.MustCompile(`^(?:(每天|每日)|每(?:周|星期)([一二三四五六日天1-7])|(?:周|星期)([一二三四五六日天1-7])|每月(\d{1,2})[日号]|(今天|明天|后天)|(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})|(\d{1,2})[-/.月](\d{1,2})[日号]?)\s*(\d{1,2})(?:[:：](\d{2})|点(?:(半)|(\d{1,2})分?)?)`)
+	scheduleLeadRe = regexp.MustCompile(`^提前(\d+)(分钟|分|小时|时|天)$`)
+)
+
+var scheduleWeekdays = map[string]time.Weekday{
+	"一": time.Monday, "二": time.Tuesday, "三": time.Wednesday, "四": time.Thursday,
+	"五": time.Friday, "六": time.Saturday, "日": time.Sunday, "天": time.Sunday,
+	"1": time.Monday, "2": time.Tuesday, "3": time.Wednesday, "4": time.Thursday,
+	"5": time.Friday, "6": time.Saturday, "7": time.Sunday,
+}
+
+// ScheduleSpec 解析后的时间描述，CronExpr 与 RunAt 二者取一
+type ScheduleSpec struct {
+	Text     string
+	CronExpr string
+	RunAt    time.Time
+}
+
+// ParseScheduleSpec 解析 text 开头的时间描述，返回剩余部分。
+// 支持“每天/每周X/每月N日 HH:MM”的周期时间、“今天/明天/后天/周X/日期 HH:MM”的一次性时间，以及“cron <五段表达式>”。
+func ParseScheduleSpec(text string, now time.Time) (*ScheduleSpec, string, error) {
+	text = strings.TrimSpace(text)
+	if fields := strings.Fields(text); len(fields) > 0 && strings.EqualFold(fields[0], "cron") {
+		if len(fields) < 6 {
+			return nil, "", ErrScheduleTimeFormat
+		}
+		expr := strings.Join(fields[1:6], " ")
+		if _, err := cron.ParseStandard(expr); err != nil {
+			return nil, "", fmt.Errorf("cron 表达式有误: %w", err)
+		}
+		rest := text
+		for range 6 {
+			_, rest = cutScheduleField(rest)
+		}
+		return &ScheduleSpec{Text: "cron " + expr, CronExpr: expr}, rest, nil
+	}
+
+	m := scheduleTimeRe.FindStringSubmatch(text)
+	if m == nil {
+		return nil, "", ErrScheduleTimeFormat
+	}
+	hour, _ := strconv.Atoi(m[11])
+	minute := 0
+	switch {
+	case m[12] != "":
+		minute, _ = strconv.Atoi(m[12])
+	case m[13] != "":
+		minute = 30
+	case m[14] != "":
+		minute, _ = strconv.Atoi(m[14])
+	}
+	if hour > 23 || minute > 59 {
+		return nil, "", ErrScheduleTimeFormat
+	}
+	spec := &ScheduleSpec{Text: strings.TrimSpace(m[0])}
+	rest := strings.TrimSpace(text[len(m[0]):])
+
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, now.Location())
+	}
+	today := at(now.Year(), now.Month(), now.Day())
+	switch {
+	case m[1] != "":
+		spec.CronExpr = fmt.Sprintf("%d %d * * *", minute, hour)
+	case m[2] != "":
+		spec.CronExpr = fmt.Sprintf("%d %d * * %d", minute, hour, scheduleWeekdays[m[2]])
+	case m[4] != "":
+		day, _ := strconv.Atoi(m[4])
+		if day < 1 || day > 31 {
+			return nil, "", ErrScheduleTimeFormat
+		}
+		spec.CronExpr = fmt.Sprintf("%d %d %d * *", minute, hour, day)
+	case m[3] != "":
+		days := (int(scheduleWeekdays[m[3]]) - int(now.Weekday()) + 7) % 7
+		spec.RunAt = today.AddDate(0, 0, days)
+		if !spec.RunAt.After(now) {
+			spec.RunAt = spec.RunAt.AddDate(0, 0, 7)
+		}
+	case m[5] != "":
+		spec.RunAt = today.AddDate(0, 0, map[string]int{"今天": 0, "明天": 1, "后天": 2}[m[5]])
+	case m[6] != "":
+		year, _ := strconv.Atoi(m[6])
+		month, _ := strconv.Atoi(m[7])
+		day, _ := strconv.Atoi(m[8])
+		spec.RunAt = at(year, time.Month(month), day)
+		if spec.RunAt.Month() != time.Month(month) || spec.RunAt.Day() != day {
+			return nil, "", ErrScheduleTimeFormat
+		}
+	case m[9] != "":
+		month, _ := strconv.Atoi(m[9])
+		day, _ := strconv.Atoi(m[10])
+		spec.RunAt = at(now.Year(), time.Month(month), day)
+		if spec.RunAt.Month() != time.Month(month) || spec.RunAt.Day() != day {
+			return nil, "", ErrScheduleTimeFormat
+		}
+		if !spec.RunAt.After(now) {
+			spec.RunAt = spec.RunAt.AddDate(1, 0, 0)
+		}
+	}
+	if spec.CronExpr == "" && !spec.RunAt.After(now) {
+		return nil, "", ErrSchedulePast
+	}
+	return spec, rest, nil
+}
+
+// cutScheduleField 切出第一个以空白分隔的字段
+func cutScheduleField(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+		return text[:i], strings.TrimSpace(text[i:])
+	}
+	return text, ""
+}
+
+// parseScheduleLead 解析“提前N分钟/小时/天”
+func parseScheduleLead(token string) (int64, bool) {
+	m := scheduleLeadRe.FindStringSubmatch(token)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	switch m[2] {
+	case "小时", "时":
+		n *= 60
+	case "天":
+		n *= 24 * 60
+	}
+	return n, true
+}
+
+func scheduleLeadText(minutes int64) string {
+	switch {
+	case minutes%(24*60) == 0:
+		return fmt.Sprintf("%d天", minutes/(24*60))
+	case minutes%60 == 0:
+		return fmt.Sprintf("%d小时", minutes/60)
+	default:
+		return fmt.Sprintf("%d分钟", minutes)
+	}
+}
+
+// leadSchedule 将周期时间整体提前 lead 触发
+type leadSchedule struct {
+	inner cron.Schedule
+	lead  time.Duration
+}
+
+func (s leadSchedule) Next(t time.Time) time.Time {
+	next := s.inner.Next(t.Add(s.lead))
+	if next.IsZero() {
+		return next
+	}
+	return next.Add(-s.lead)
+}
+
+// onceSchedule 只触发一次，过时后返回零值，cron 不再调度
+type onceSchedule struct {
+	at time.Time
+}
+
+func (s onceSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+// onceRemindAt 一次性日程的提醒时刻，即日程时间减去提前量
+func onceRemindAt(item *model.GroupSchedule) time.Time {
+	return time.Unix(item.RunAt, 0).Add(-time.Duration(item.LeadMinutes) * time.Minute)
+}
+
+// scheduleOf 计算日程的提醒时刻
+func scheduleOf(item *model.GroupSchedule) (cron.Schedule, error) {
+	lead := time.Duration(item.LeadMinutes) * time.Minute
+	if item.CronExpr == "" {
+		return onceSchedule{at: onceRemindAt(item)}, nil
+	}
+	inner, err := cron.ParseStandard(item.CronExpr)
+	if err != nil {
+		return nil, err
+	}
+	return leadSchedule{inner: inner, lead: lead}, nil
+}
+
+// GroupScheduleInfo 日程及其下次提醒时间
+type GroupScheduleInfo struct {
+	*model.GroupSchedule
+	NextRunAt int64 `json:"nextRunAt"`
+}
+
+type scheduleEntry struct {
+	item    *model.GroupSchedule
+	entryID cron.EntryID // 0 表示未注册到 cron
+}
+
+// ScheduleManager 管理群日程提醒，数据存于 group_schedules 表，通过 Dice.Cron 调度
+type ScheduleManager struct {
+	d       *Dice
+	mu      sync.Mutex
+	entries map[uint64]*scheduleEntry
+}
+
+func NewScheduleManager(d *Dice) *ScheduleManager {
+	return &ScheduleManager{d: d, entries: map[uint64]*scheduleEntry{}}
+}
+
+// Load 从数据库载入全部日程并注册
+func (m *ScheduleManager) Load() error {
+	items, err := service.GroupScheduleList(m.d.DBOperator, "")
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, item := range items {
+		if item.CronExpr == "" && !onceRemindAt(item).After(now) {
+			// 骰子离线期间错过提醒时刻的一次性日程，cron 不会再触发，留着只会一直占用名额
+			m.d.Logger.Infof("日程#%d 已错过提醒时间，删除: %s", item.ID, item.Content)
+			_ = service.GroupScheduleDelete(m.d.DBOperator, item.ID)
+			continue
+		}
+		e := &scheduleEntry{item: item}
+		m.entries[item.ID] = e
+		if err = m.register(e); err != nil {
+			m.d.Logger.Errorf("日程#%d 注册失败: %v", item.ID, err)
+		}
+	}
+	return nil
+}
+
+func (m *ScheduleManager) register(e *scheduleEntry) error {
+	if e.item.Paused || e.entryID != 0 {
+		return nil
+	}
+	sched, err := scheduleOf(e.item)
+	if err != nil {
+		return err
+	}
+	id := e.item.ID
+	e.entryID = m.d.Cron.Schedule(sched, cron.FuncJob(func() {
+		m.fire(id)
+	}))
+	return nil
+}
+
+func (m *ScheduleManager) unregister(e *scheduleEntry) {
+	if e.entryID != 0 {
+		m.d.Cron.Remove(e.entryID)
+		e.entryID = 0
+	}
+}
+
+// Add 新建日程
+func (m *ScheduleManager) Add(item *model.GroupSchedule) error {
+	if strings.TrimSpace(item.Content) == "" {
+		return ErrScheduleNoContent
+	}
+	if _, err := scheduleOf(item); err != nil {
+		return err
+	}
+	if item.CronExpr == "" && !onceRemindAt(item).After(time.Now()) {
+		return ErrSchedulePast
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, e := range m.entries {
+		if e.item.GroupID == item.GroupID {
+			count++
+		}
+	}
+	if count >= scheduleMaxPerGroup {
+		return ErrScheduleTooMany
+	}
+	item.ID = 0
+	item.CreatedAt = time.Now().Unix()
+	if err := service.GroupScheduleCreate(m.d.DBOperator, item); err != nil {
+		return err
+	}
+	e := &scheduleEntry{item: item}
+	m.entries[item.ID] = e
+	return m.register(e)
+}
+
+// Get 获取日程副本
+func (m *ScheduleManager) Get(id uint64) (*model.GroupSchedule, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[id]
+	if !ok {
+		return nil, false
+	}
+	item := *e.item
+	return &item, true
+}
+
+// Remove 删除日程
+func (m *ScheduleManager) Remove(id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[id]
+	if !ok {
+		return ErrScheduleNotFound
+	}
+	if err := service.GroupScheduleDelete(m.d.DBOperator, id); err != nil {
+		return err
+	}
+	m.unregister(e)
+	delete(m.entries, id)
+	return nil
+}
+
+// SetPaused 暂停或恢复日程
+func (m *ScheduleManager) SetPaused(id uint64, paused bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[id]
+	if !ok {
+		return ErrScheduleNotFound
+	}
+	if !paused && e.item.CronExpr == "" && !onceRemindAt(e.item).After(time.Now()) {
+		return ErrSchedulePast
+	}
+	if err := service.GroupScheduleUpdate(m.d.DBOperator, id, map[string]any{"paused": paused}); err != nil {
+		return err
+	}
+	e.item.Paused = paused
+	if paused {
+		m.unregister(e)
+		return nil
+	}
+	return m.register(e)
+}
+
+// List 列出日程，groupID 为空时列出全部
+func (m *ScheduleManager) List(groupID string) []*GroupScheduleInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var lst []*GroupScheduleInfo
+	for _, e := range m.entries {
+		if groupID == "" || e.item.GroupID == groupID {
+			lst = append(lst, m.info(e))
+		}
+	}
+	sort.Slice(lst, func(i, j int) bool {
+		return lst[i].ID < lst[j].ID
+	})
+	return lst
+}
+
+// Info 获取日程及下次提醒时间，不存在时返回 nil
+func (m *ScheduleManager) Info(id uint64) *GroupScheduleInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[id]; ok {
+		return m.info(e)
+	}
+	return nil
+}
+
+func (m *ScheduleManager) info(e *scheduleEntry) *GroupScheduleInfo {
+	item := *e.item
+	info := &GroupScheduleInfo{GroupSchedule: &item}
+	if e.entryID != 0 {
+		if sched, err := scheduleOf(e.item); err == nil {
+			if next := sched.Next(time.Now()); !next.IsZero() {
+				info.NextRunAt = next.Unix()
+			}
+		}
+	}
+	return info
+}
+
+func (m *ScheduleManager) fire(id uint64) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.d.Logger.Errorf("日程#%d 发送异常: %v 堆栈: %v", id, r, string(debug.Stack()))
+		}
+	}()
+	item, ok := m.Get(id)
+	if !ok || item.Paused {
+		return
+	}
+	if err := m.send(item); err != nil {
+		m.d.Logger.Warnf("日程#%d 未能发送到群 %s: %v", id, item.GroupID, err)
+	}
+	if item.CronExpr == "" {
+		// 一次性日程触发后删除
+		_ = m.Remove(id)
+		return
+	}
+	now := time.Now().Unix()
+	m.mu.Lock()
+	if e, exists := m.entries[id]; exists {
+		e.item.LastRunAt = now
+	}
+	m.mu.Unlock()
+	_ = service.GroupScheduleUpdate(m.d.DBOperator, id, map[string]any{"last_run_at": now})
+}
+
+// endpointFor 优先使用创建日程的账号，离线时换用同在群内的其他在线账号
+func (m *ScheduleManager) endpointFor(item *model.GroupSchedule, group *GroupInfo) *EndPointInfo {
+	var fallback *EndPointInfo
+	for _, ep := range m.d.ImSession.EndPoints {
+		if ep == nil || !ep.Enable || ep.State != StateConnected {
+			continue
+		}
+		if ep.UserID == item.EndpointID {
+			return ep
+		}
+		if fallback == nil && group != nil && group.DiceIDExistsMap != nil {
+			if _, exists := group.DiceIDExistsMap.Load(ep.UserID); exists {
+				fallback = ep
+			}
+		}
+	}
+	return fallback
+}
+
+func (m *ScheduleManager) send(item *model.GroupSchedule) error {
+	group, _ := m.d.ImSession.ServiceAtNew.Load(item.GroupID)
+	ep := m.endpointFor(item, group)
+	if ep == nil {
+		return errors.New("没有可用的在线账号")
+	}
+	msg := &Message{
+		MessageType: "group",
+		GroupID:     item.GroupID,
+		Sender:      SenderBase{UserID: ep.UserID},
+	}
+	ctx := CreateTempCtx(ep, msg)
+	ReplyGroupRaw(ctx, msg, scheduleText(item, group), "")
+	return nil
+}
+
+// scheduleText 生成提醒文本，设置了队伍时附带@
+func scheduleText(item *model.GroupSchedule, group *GroupInfo) string {
+	text := "【日程提醒】" + item.Content
+	if item.LeadMinutes > 0 {
+		text += fmt.Sprintf("\n将于%s后开始", scheduleLeadText(item.LeadMinutes))
+	}
+	if item.Team != "" && group != nil && group.PlayerGroups != nil {
+		if members, ok := group.PlayerGroups.Load(item.Team); ok && len(members) > 0 {
+			rawUserIDs := teamExtractRawIDsFromGroup(members)
+			cqCodes := make([]string, 0, len(rawUserIDs))
+			for _, id := range rawUserIDs {
+				cqCodes = append(cqCodes, fmt.Sprintf("[CQ:at,qq=%s]", id))
+			}
+			text += "\n" + strings.Join(cqCodes, " ")
+		}
+	}
+	return text
+}
+
+func scheduleDescribe(info *GroupScheduleInfo) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#%d %s", info.ID, info.Spec)
+	if info.LeadMinutes > 0 {
+		fmt.Fprintf(&sb, " 提前%s", scheduleLeadText(info.LeadMinutes))
+	}
+	if info.Team != "" {
+		fmt.Fprintf(&sb, " @%s", info.Team)
+	}
+	fmt.Fprintf(&sb, ": %s", info.Content)
+	switch {
+	case info.Paused:
+		sb.WriteString(" [已暂停]")
+	case info.NextRunAt > 0:
+		fmt.Fprintf(&sb, " (下次提醒 %s)", time.Unix(info.NextRunAt, 0).Format("2006-01-02 15:04"))
+	}
+	return sb.String()
+}
+
+const helpForSchedule = ".schedule // 列出本群日程\n" +
+	".schedule add <时间> [提前<时长>] [team=<队伍>] <内容> // 添加日程\n" +
+	".schedule del <编号> // 删除日程\n" +
+	".schedule pause/resume <编号> // 暂停/恢复日程\n" +
+	"时间示例: 每周五20:00 每天21:30 每月1日9:00 明天20:00 周六14:00 2026-10-20 20:00 cron 0 20 * * 5\n" +
+	"时长示例: 提前30分钟 提前1小时 提前1天\n" +
+	"team 为 .team 创建的队伍，提醒时会@队伍成员；删除和暂停限创建者或群管理以上权限"
+
+func cmdScheduleSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+	if ctx.IsPrivate {
+		ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_私聊不可用"))
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+	m := ctx.Dice.Schedules
+	if m == nil {
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+
+	sub := strings.ToLower(cmdArgs.GetArgN(1))
+	switch sub {
+	case "", "list":
+		lst := m.List(ctx.Group.GroupID)
+		if len(lst) == 0 {
+			ReplyToSender(ctx, msg, "本群还没有日程")
+			break
+		}
+		lines := make([]string, 0, len(lst))
+		for _, info := range lst {
+			lines = append(lines, scheduleDescribe(info))
+		}
+		ReplyToSender(ctx, msg, "本群日程:\n"+strings.Join(lines, "\n"))
+	case "add":
+		_, text := cutScheduleField(cmdArgs.CleanArgs)
+		spec, rest, err := ParseScheduleSpec(text, time.Now())
+		if err != nil {
+			ReplyToSender(ctx, msg, "添加日程失败: "+err.Error())
+			break
+		}
+		item := &model.GroupSchedule{
+			GroupID:     ctx.Group.GroupID,
+			EndpointID:  ctx.EndPoint.UserID,
+			CreatorID:   ctx.Player.UserID,
+			CreatorName: ctx.Player.Name,
+			Spec:        spec.Text,
+			CronExpr:    spec.CronExpr,
+		}
+		if !spec.RunAt.IsZero() {
+			item.RunAt = spec.RunAt.Unix()
+		}
+		for {
+			token, after := cutScheduleField(rest)
+			if lead, ok := parseScheduleLead(token); ok {
+				item.LeadMinutes = lead
+			} else if team, ok := strings.CutPrefix(token, "team="); ok {
+				item.Team = team
+			} else if team, ok = strings.CutPrefix(token, "队伍="); ok {
+				item.Team = team
+			} else {
+				break
+			}
+			rest = after
+		}
+		item.Content = rest
+		if item.Team != "" {
+			if ctx.Group.PlayerGroups == nil || !ctx.Group.PlayerGroups.Exists(item.Team) {
+				ReplyToSender(ctx, msg, fmt.Sprintf("添加日程失败: 没有名叫%s的队伍", item.Team))
+				break
+			}
+		}
+		if err = m.Add(item); err != nil {
+			ReplyToSender(ctx, msg, "添加日程失败: "+err.Error())
+			break
+		}
+		ReplyToSender(ctx, msg, "已添加日程\n"+scheduleDescribe(m.Info(item.ID)))
+	case "del", "rm", "delete", "pause", "resume":
+		id, err := strconv.ParseUint(strings.TrimPrefix(cmdArgs.GetArgN(2), "#"), 10, 64)
+		if err != nil {
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		item, ok := m.Get(id)
+		if !ok || item.GroupID != ctx.Group.GroupID {
+			ReplyToSender(ctx, msg, ErrScheduleNotFound.Error())
+			break
+		}
+		if item.CreatorID != ctx.Player.UserID && ctx.PrivilegeLevel < 50 {
+			ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理"))
+			break
+		}
+		action := "删除"
+		switch sub {
+		case "pause":
+			action = "暂停"
+			err = m.SetPaused(id, true)
+		case "resume":
+			action = "恢复"
+			err = m.SetPaused(id, false)
+		default:
+			err = m.Remove(id)
+		}
+		if err != nil {
+			ReplyToSender(ctx, msg, action+"日程失败: "+err.Error())
+			break
+		}
+		ReplyToSender(ctx, msg, fmt.Sprintf("已%s日程#%d", action, id))
+	default:
+		return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+	}
+	return CmdExecuteResult{Matched: true, Solved: true}
+}
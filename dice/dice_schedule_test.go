@@ -0,0 +1,151 @@
+//nolint:testpackage
+package dice
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"sealdice-core/logger"
+	"sealdice-core/model"
+)
+
+func TestParseScheduleSpec(t *testing.T) {
+	// 2026-10-14 是周三
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		text, cronExpr, rest string
+		runAt                time.Time
+	}{
+		{"每周五20:00 跑团", "0 20 * * 5", "跑团", time.Time{}},
+		{"每天 21点半 日常", "30 21 * * *", "日常", time.Time{}},
+		{"每月1日9:05 月会", "5 9 1 * *", "月会", time.Time{}},
+		{"cron 0 20 * * 5 提前1小时 跑团", "0 20 * * 5", "提前1小时 跑团", time.Time{}},
+		{"明天20:00 团", "", "团", time.Date(2026, 10, 15, 20, 0, 0, 0, time.Local)},
+		{"周三11:00 团", "", "团", time.Date(2026, 10, 21, 11, 0, 0, 0, time.Local)},
+		{"周三13:00 团", "", "团", time.Date(2026, 10, 14, 13, 0, 0, 0, time.Local)},
+		{"2026-12-31 23:59 跨年", "", "跨年", time.Date(2026, 12, 31, 23, 59, 0, 0, time.Local)},
+		{"1月2日8点 新年团", "", "新年团", time.Date(2027, 1, 2, 8, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		spec, rest, err := ParseScheduleSpec(tt.text, now)
+		if err != nil {
+			t.Errorf("ParseScheduleSpec(%q) error: %v", tt.text, err)
+			continue
+		}
+		if spec.CronExpr != tt.cronExpr || !spec.RunAt.Equal(tt.runAt) || rest != tt.rest {
+			t.Errorf("ParseScheduleSpec(%q) = %q %v %q", tt.text, spec.CronExpr, spec.RunAt, rest)
+		}
+	}
+
+	for _, text := range []string{"下周五 团", "每天25:00 团", "2026-02-30 10:00 团", "cron 0 20 * *"} {
+		if _, _, err := ParseScheduleSpec(text, now); err == nil {
+			t.Errorf("ParseScheduleSpec(%q) should fail", text)
+		}
+	}
+	if _, _, err := ParseScheduleSpec("今天9:00 团", now); !errors.Is(err, ErrSchedulePast) {
+		t.Errorf("past time should be rejected, got %v", err)
+	}
+}
+
+func TestLeadSchedule(t *testing.T) {
+	item := &model.GroupSchedule{CronExpr: "0 20 * * 5", LeadMinutes: 60}
+	sched, err := scheduleOf(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 16, 19, 30, 0, 0, time.Local) // 周五
+	want := time.Date(2026, 10, 23, 19, 0, 0, 0, time.Local)
+	if next := sched.Next(now); !next.Equal(want) {
+		t.Fatalf("next = %v, want %v", next, want)
+	}
+
+	once, _ := scheduleOf(&model.GroupSchedule{RunAt: want.Unix(), LeadMinutes: 30})
+	if next := once.Next(now); !next.Equal(want.Add(-30 * time.Minute)) {
+		t.Fatalf("once next = %v", next)
+	}
+	if next := once.Next(want); !next.IsZero() {
+		t.Fatal("once schedule should not repeat")
+	}
+}
+
+func TestScheduleManager(t *testing.T) {
+	dbOperator, err := newMockDatabaseOperator(filepath.Join(t.TempDir(), "schedule.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(dbOperator.Close)
+	if err = dbOperator.db.AutoMigrate(&model.GroupSchedule{}); err != nil {
+		t.Fatal(err)
+	}
+	d := &Dice{DBOperator: dbOperator, Cron: cron.New(), Logger: logger.M()}
+	m := NewScheduleManager(d)
+
+	item := &model.GroupSchedule{GroupID: "QQ-Group:1", CronExpr: "0 20 * * 5", Spec: "每周五20:00", Content: "跑团"}
+	if err = m.Add(item); err != nil {
+		t.Fatal(err)
+	}
+	if info := m.Info(item.ID); info == nil || info.NextRunAt == 0 {
+		t.Fatalf("schedule should be registered, got %+v", info)
+	}
+	if err = m.Add(&model.GroupSchedule{GroupID: "QQ-Group:1", RunAt: time.Now().Add(30 * time.Minute).Unix(), LeadMinutes: 60, Content: "x"}); !errors.Is(err, ErrSchedulePast) {
+		t.Fatalf("reminder in the past should be rejected, got %v", err)
+	}
+
+	if err = m.SetPaused(item.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if info := m.Info(item.ID); !info.Paused || info.NextRunAt != 0 || len(d.Cron.Entries()) != 0 {
+		t.Fatal("paused schedule should be removed from cron")
+	}
+
+	// 重新载入后保持暂停状态
+	reloaded := NewScheduleManager(d)
+	if err = reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if lst := reloaded.List("QQ-Group:1"); len(lst) != 1 || !lst[0].Paused {
+		t.Fatalf("unexpected reloaded list %+v", lst)
+	}
+
+	// 离线期间错过提醒时刻的一次性日程在载入时删除，即使日程本身还没到
+	missed := &model.GroupSchedule{GroupID: "QQ-Group:2", RunAt: time.Now().Add(2 * time.Hour).Unix(), LeadMinutes: 60, Content: "团"}
+	if err = m.Add(missed); err != nil {
+		t.Fatal(err)
+	}
+	if err = dbOperator.db.Model(&model.GroupSchedule{}).Where("id = ?", missed.ID).
+		Update("run_at", time.Now().Add(30*time.Minute).Unix()).Error; err != nil {
+		t.Fatal(err)
+	}
+	reloaded = NewScheduleManager(d)
+	if err = reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if lst := reloaded.List("QQ-Group:2"); len(lst) != 0 {
+		t.Fatalf("missed one-shot schedule should be dropped, got %+v", lst)
+	}
+	_ = m.Remove(missed.ID)
+
+	if err = m.Remove(item.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.List("")) != 0 {
+		t.Fatal("schedule should be removed")
+	}
+	if err = m.Remove(item.ID); !errors.Is(err, ErrScheduleNotFound) {
+		t.Fatalf("removing twice should fail, got %v", err)
+	}
+}
+
+func TestScheduleText(t *testing.T) {
+	group := &GroupInfo{PlayerGroups: new(SyncMap[string, []string])}
+	group.PlayerGroups.Store("调查员", []string{"QQ:1", "QQ:2"})
+	text := scheduleText(&model.GroupSchedule{Content: "今晚跑团", LeadMinutes: 60, Team: "调查员"}, group)
+	if !strings.Contains(text, "1小时后") || !strings.Contains(text, "[CQ:at,qq=1] [CQ:at,qq=2]") {
+		t.Fatalf("unexpected text %q", text)
+	}
+}
@@ -0,0 +1,356 @@
+package dice
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UIRole WebUI 账户的角色，权限依次递增
+type UIRole string
+
+const (
+	UIRoleViewer    UIRole = "viewer"    // 只能查看
+	UIRoleModerator UIRole = "moderator" // 管理黑名单、群组与日程
+	UIRoleOperator  UIRole = "operator"  // 修改骰子配置、连接、扩展等日常运维
+	UIRoleAdmin     UIRole = "admin"     // 全部权限，含执行 JS、升级、恢复备份与账户管理
+)
+
+// UIScope 接口的权限范围，每个路由归属其中之一
+type UIScope string
+
+const (
+	UIScopeRead     UIScope = "read"
+	UIScopeModerate UIScope = "moderate"
+	UIScopeOperate  UIScope = "operate"
+	UIScopeAdmin    UIScope = "admin"
+)
+
+var uiRoleScopes = map[UIRole][]UIScope{
+	UIRoleViewer:    {UIScopeRead},
+	UIRoleModerator: {UIScopeRead, UIScopeModerate},
+	UIRoleOperator:  {UIScopeRead, UIScopeModerate, UIScopeOperate},
+	UIRoleAdmin:     {UIScopeRead, UIScopeModerate, UIScopeOperate, UIScopeAdmin},
+}
+
+// Scopes 角色拥有的权限范围，未知角色为空
+func (r UIRole) Scopes() []UIScope {
+	return uiRoleScopes[r]
+}
+
+const (
+	// UIOwnerName 使用骰子 UI 密码登录的骰主，始终拥有全部权限
+	UIOwnerName = "admin"
+
+	uiSessionTTL = 30 * 24 * time.Hour
+)
+
+var uiUserNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]{1,32}$`)
+
+var (
+	ErrUIUserNotFound     = errors.New("账户不存在")
+	ErrUIUserNameInvalid  = errors.New("账户名只能包含字母、数字、下划线、点和减号，长度不超过32")
+	ErrUIUserNameReserved = errors.New("该账户名为骰主保留")
+	ErrUIRoleInvalid      = errors.New("无效的角色")
+	ErrUIPasswordRequired = errors.New("新建账户必须设置密码")
+	ErrUISignInFailed     = errors.New("账户名或密码错误")
+	ErrUITokenNotFound    = errors.New("令牌不存在")
+	ErrUIScopeInvalid     = errors.New("无效的权限范围")
+	ErrUIScopeNotAllowed  = errors.New("令牌的权限不能超出账户角色")
+)
+
+// UIUser WebUI 账户。密码与 /signin 相同，是前端加盐哈希后的值，此处再以 bcrypt 保存
+type UIUser struct {
+	Name         string `json:"name"      yaml:"name"`
+	Role         UIRole `json:"role"      yaml:"role"`
+	PasswordHash string `json:"-"         yaml:"passwordHash"`
+	Disabled     bool   `json:"disabled"  yaml:"disabled"`
+	CreatedAt    int64  `json:"createdAt" yaml:"createdAt"`
+}
+
+// UIToken 访问令牌，只保存其 SHA-256，明文仅在创建时返回一次
+type UIToken struct {
+	ID        string    `json:"id"        yaml:"id"`
+	Hash      string    `json:"-"         yaml:"hash"`
+	User      string    `json:"user"      yaml:"user"`
+	Name      string    `json:"name"      yaml:"name"`
+	Scopes    []UIScope `json:"scopes"    yaml:"scopes"`    // 为空表示与账户角色相同
+	Session   bool      `json:"session"   yaml:"session"`   // 登录产生的会话
+	ExpiresAt int64     `json:"expiresAt" yaml:"expiresAt"` // 为 0 时永不过期
+	CreatedAt int64     `json:"createdAt" yaml:"createdAt"`
+}
+
+// UIPrincipal 一次请求的调用者
+type UIPrincipal struct {
+	User    string    `json:"user"`
+	Role    UIRole    `json:"role"`
+	TokenID string    `json:"tokenId"` // 骰主的旧式令牌为空
+	Scopes  []UIScope `json:"scopes"`
+}
+
+func (p *UIPrincipal) Can(scope UIScope) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// UIAccounts 保存 WebUI 账户与令牌，随 dice.yaml 持久化
+type UIAccounts struct {
+	mu     sync.RWMutex
+	users  map[string]*UIUser
+	tokens map[string]*UIToken // key 为令牌的 SHA-256
+}
+
+func hashUIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func (a *UIAccounts) load(users []*UIUser, tokens []*UIToken) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.users = map[string]*UIUser{}
+	a.tokens = map[string]*UIToken{}
+	for _, u := range users {
+		a.users[u.Name] = u
+	}
+	for _, t := range tokens {
+		a.tokens[t.Hash] = t
+	}
+}
+
+// dump 返回用于保存的副本，顺带清理已过期的令牌
+func (a *UIAccounts) dump() ([]*UIUser, []*UIToken) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now().Unix()
+	users := make([]*UIUser, 0, len(a.users))
+	for _, u := range a.users {
+		users = append(users, u)
+	}
+	tokens := make([]*UIToken, 0, len(a.tokens))
+	for k, t := range a.tokens {
+		if t.ExpiresAt != 0 && t.ExpiresAt < now {
+			delete(a.tokens, k)
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt < tokens[j].CreatedAt })
+	return users, tokens
+}
+
+// roleOf 返回账户的角色，骰主视为 admin
+func (a *UIAccounts) roleOf(name string) (UIRole, bool) {
+	if name == UIOwnerName {
+		return UIRoleAdmin, true
+	}
+	u, ok := a.users[name]
+	if !ok || u.Disabled {
+		return "", false
+	}
+	return u.Role, true
+}
+
+// Authenticate 校验账户令牌，令牌过期、账户停用或被删除时失败
+func (a *UIAccounts) Authenticate(token string) (*UIPrincipal, bool) {
+	if token == "" {
+		return nil, false
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	t, ok := a.tokens[hashUIToken(token)]
+	if !ok || (t.ExpiresAt != 0 && t.ExpiresAt < time.Now().Unix()) {
+		return nil, false
+	}
+	role, ok := a.roleOf(t.User)
+	if !ok {
+		return nil, false
+	}
+	scopes := role.Scopes()
+	if len(t.Scopes) > 0 {
+		// 角色降级后，令牌的权限随之收窄
+		scopes = slices.DeleteFunc(slices.Clone(t.Scopes), func(s UIScope) bool {
+			return !slices.Contains(role.Scopes(), s)
+		})
+	}
+	return &UIPrincipal{User: t.User, Role: role, TokenID: t.ID, Scopes: scopes}, true
+}
+
+// issue 生成令牌，调用方须持有写锁
+func (a *UIAccounts) issue(user, name string, scopes []UIScope, session bool, ttl time.Duration) (string, *UIToken) {
+	if a.tokens == nil {
+		a.tokens = map[string]*UIToken{}
+	}
+	now := time.Now()
+	token := "sd_" + randomHex(32)
+	t := &UIToken{
+		ID:        randomHex(8),
+		Hash:      hashUIToken(token),
+		User:      user,
+		Name:      name,
+		Scopes:    scopes,
+		Session:   session,
+		CreatedAt: now.Unix(),
+	}
+	if ttl > 0 {
+		t.ExpiresAt = now.Add(ttl).Unix()
+	}
+	a.tokens[t.Hash] = t
+	return token, t
+}
+
+// SignIn 以账户密码登录，返回会话令牌
+func (a *UIAccounts) SignIn(name, password string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u, ok := a.users[name]
+	if !ok || u.Disabled || bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return "", ErrUISignInFailed
+	}
+	token, _ := a.issue(name, "登录", nil, true, uiSessionTTL)
+	return token, nil
+}
+
+// SaveUser 新建或修改账户，password 为空时保留原密码。修改密码或停用账户会使其登录会话失效
+func (a *UIAccounts) SaveUser(name string, role UIRole, password string, disabled bool) error {
+	if name == UIOwnerName {
+		return ErrUIUserNameReserved
+	}
+	if !uiUserNamePattern.MatchString(name) {
+		return ErrUIUserNameInvalid
+	}
+	if _, ok := uiRoleScopes[role]; !ok {
+		return ErrUIRoleInvalid
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.users == nil {
+		a.users = map[string]*UIUser{}
+	}
+	u, exists := a.users[name]
+	if !exists {
+		if password == "" {
+			return ErrUIPasswordRequired
+		}
+		u = &UIUser{Name: name, CreatedAt: time.Now().Unix()}
+	}
+	revoke := disabled && !u.Disabled
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		u.PasswordHash = string(hash)
+		revoke = true
+	}
+	u.Role = role
+	u.Disabled = disabled
+	a.users[name] = u
+	if exists && revoke {
+		for k, t := range a.tokens {
+			if t.User == name && t.Session {
+				delete(a.tokens, k)
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteUser 删除账户及其全部令牌
+func (a *UIAccounts) DeleteUser(name string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.users[name]; !ok {
+		return ErrUIUserNotFound
+	}
+	delete(a.users, name)
+	for k, t := range a.tokens {
+		if t.User == name {
+			delete(a.tokens, k)
+		}
+	}
+	return nil
+}
+
+func (a *UIAccounts) ListUsers() []UIUser {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	users := make([]UIUser, 0, len(a.users))
+	for _, u := range a.users {
+		users = append(users, *u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	return users
+}
+
+// CreateToken 为账户创建 API 令牌，scopes 为空时与角色相同，ttl 为 0 时永不过期
+func (a *UIAccounts) CreateToken(user, name string, scopes []UIScope, ttl time.Duration) (string, *UIToken, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	role, ok := a.roleOf(user)
+	if !ok {
+		return "", nil, ErrUIUserNotFound
+	}
+	for _, s := range scopes {
+		if !slices.Contains(UIRoleAdmin.Scopes(), s) {
+			return "", nil, ErrUIScopeInvalid
+		}
+		if !slices.Contains(role.Scopes(), s) {
+			return "", nil, ErrUIScopeNotAllowed
+		}
+	}
+	token, t := a.issue(user, name, slices.Clone(scopes), false, ttl)
+	return token, t, nil
+}
+
+// ListTokens 列出令牌，user 为空时列出全部
+func (a *UIAccounts) ListTokens(user string) []UIToken {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	now := time.Now().Unix()
+	tokens := []UIToken{}
+	for _, t := range a.tokens {
+		if (user != "" && t.User != user) || (t.ExpiresAt != 0 && t.ExpiresAt < now) {
+			continue
+		}
+		tokens = append(tokens, *t)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt < tokens[j].CreatedAt })
+	return tokens
+}
+
+// RevokeToken 吊销令牌，user 不为空时只能吊销该账户自己的令牌
+func (a *UIAccounts) RevokeToken(id, user string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for k, t := range a.tokens {
+		if t.ID == id && (user == "" || t.User == user) {
+			delete(a.tokens, k)
+			return nil
+		}
+	}
+	return ErrUITokenNotFound
+}
+
+// UIAuthenticate 校验 WebUI/API 令牌。骰主登录得到的旧式令牌拥有全部权限
+func (dm *DiceManager) UIAuthenticate(token string) (*UIPrincipal, bool) {
+	if token != "" && dm.AccessTokens.Exists(token) {
+		return &UIPrincipal{User: UIOwnerName, Role: UIRoleAdmin, Scopes: UIRoleAdmin.Scopes()}, true
+	}
+	return dm.UIAccounts.Authenticate(token)
+}
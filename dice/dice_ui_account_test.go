@@ -0,0 +1,105 @@
+package dice //nolint:testpackage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUIAccountsRolesAndTokens(t *testing.T) {
+	var a UIAccounts
+	if err := a.SaveUser(UIOwnerName, UIRoleViewer, "pw", false); !errors.Is(err, ErrUIUserNameReserved) {
+		t.Fatalf("SaveUser(owner) error = %v", err)
+	}
+	if err := a.SaveUser("mod", UIRoleModerator, "", false); !errors.Is(err, ErrUIPasswordRequired) {
+		t.Fatalf("SaveUser(no password) error = %v", err)
+	}
+	if err := a.SaveUser("mod", UIRoleModerator, "pw", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.SignIn("mod", "wrong"); !errors.Is(err, ErrUISignInFailed) {
+		t.Fatalf("SignIn(wrong password) error = %v", err)
+	}
+	session, err := a.SignIn("mod", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := a.Authenticate(session)
+	if !ok || p.User != "mod" || !p.Can(UIScopeModerate) || p.Can(UIScopeOperate) {
+		t.Fatalf("Authenticate(session) = %+v, %v", p, ok)
+	}
+
+	// 令牌的权限不能超出角色，可以更窄
+	if _, _, err = a.CreateToken("mod", "bot", []UIScope{UIScopeAdmin}, 0); !errors.Is(err, ErrUIScopeNotAllowed) {
+		t.Fatalf("CreateToken(admin scope) error = %v", err)
+	}
+	readOnly, item, err := a.CreateToken("mod", "bot", []UIScope{UIScopeRead}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok = a.Authenticate(readOnly); !ok || p.Can(UIScopeModerate) || p.TokenID != item.ID {
+		t.Fatalf("Authenticate(read-only token) = %+v, %v", p, ok)
+	}
+
+	// 修改密码使登录会话失效，API 令牌不受影响
+	if err = a.SaveUser("mod", UIRoleModerator, "pw2", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = a.Authenticate(session); ok {
+		t.Fatal("session should be revoked after password change")
+	}
+	if _, ok = a.Authenticate(readOnly); !ok {
+		t.Fatal("API token should survive password change")
+	}
+
+	// 停用账户后其令牌全部失效
+	if err = a.SaveUser("mod", UIRoleModerator, "", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = a.Authenticate(readOnly); ok {
+		t.Fatal("token of disabled user should be rejected")
+	}
+
+	if err = a.RevokeToken(item.ID, "someone-else"); !errors.Is(err, ErrUITokenNotFound) {
+		t.Fatalf("RevokeToken(other user) error = %v", err)
+	}
+	if err = a.RevokeToken(item.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.ListTokens("")) != 0 {
+		t.Fatalf("ListTokens() = %+v", a.ListTokens(""))
+	}
+}
+
+func TestUIAccountsExpiryAndPersistence(t *testing.T) {
+	var a UIAccounts
+	if err := a.SaveUser("viewer", UIRoleViewer, "pw", false); err != nil {
+		t.Fatal(err)
+	}
+	token, item, err := a.CreateToken("viewer", "dashboard", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _, _ := a.CreateToken("viewer", "old", nil, time.Hour)
+	for _, tk := range a.tokens {
+		if tk.Name == "old" {
+			tk.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+		}
+	}
+	if _, ok := a.Authenticate(expired); ok {
+		t.Fatal("expired token should be rejected")
+	}
+
+	users, tokens := a.dump()
+	if len(users) != 1 || len(tokens) != 1 || tokens[0].ID != item.ID {
+		t.Fatalf("dump() = %+v, %+v", users, tokens)
+	}
+
+	var b UIAccounts
+	b.load(users, tokens)
+	p, ok := b.Authenticate(token)
+	if !ok || p.Role != UIRoleViewer || !p.Can(UIScopeRead) || p.Can(UIScopeModerate) {
+		t.Fatalf("Authenticate() after reload = %+v, %v", p, ok)
+	}
+}
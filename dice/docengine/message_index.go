@@ -0,0 +1,211 @@
+package docengine
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/fy0/bluge"
+)
+
+const (
+	DefaultMessageIndexDir = "./cache/_message_index"
+	messageIndexStateFile  = "last_id"
+)
+
+// MessageDoc 建立全文索引的一条消息，ID 为 log_items 的主键
+type MessageDoc struct {
+	ID      uint64
+	GroupID string
+	UserIDs []string // 平台用户ID和统一ID，任一匹配即可
+	Time    int64
+	Message string
+}
+
+// MessageQuery 全文搜索条件，留空的条件不生效
+type MessageQuery struct {
+	Text      string
+	GroupID   string
+	UserID    string
+	TimeBegin int64
+	TimeEnd   int64
+	PageSize  int
+	PageNum   int
+}
+
+// MessageIndex log_items 的全文索引，只保存ID和过滤字段，消息内容仍从数据库读取
+type MessageIndex struct {
+	mu     sync.Mutex
+	dir    string
+	writer *bluge.Writer
+	lastID uint64 // 已索引的最大 log_items ID
+}
+
+// OpenMessageIndex 打开或新建索引目录
+func OpenMessageIndex(dir string) (*MessageIndex, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	writer, err := bluge.OpenWriter(bluge.DefaultConfig(dir))
+	if err != nil {
+		return nil, err
+	}
+	idx := &MessageIndex{dir: dir, writer: writer}
+	data, err := os.ReadFile(filepath.Join(dir, messageIndexStateFile))
+	if err == nil {
+		idx.lastID, _ = strconv.ParseUint(string(data), 10, 64)
+		return idx, nil
+	}
+	if err = idx.saveStateLocked(); err != nil {
+		_ = writer.Close()
+		return nil, err
+	}
+	return idx, nil
+}
+
+// MessageIndexExists 索引目录是否存在，即全文索引是否已启用
+func MessageIndexExists(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, messageIndexStateFile))
+	return err == nil
+}
+
+func (m *MessageIndex) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.writer != nil {
+		_ = m.writer.Close()
+		m.writer = nil
+	}
+}
+
+// LastID 已索引的最大消息ID，增量索引从这里继续
+func (m *MessageIndex) LastID() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastID
+}
+
+// Add 写入一批消息并推进 LastID，docs 需按 ID 升序
+func (m *MessageIndex) Add(docs []MessageDoc) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.writer == nil {
+		return errors.New("消息索引已关闭")
+	}
+
+	batch := bluge.NewBatch()
+	for _, msg := range docs {
+		doc := bluge.NewDocument(strconv.FormatUint(msg.ID, 10)).
+			AddField(bluge.NewKeywordField("group", msg.GroupID)).
+			AddField(bluge.NewNumericField("time", float64(msg.Time)).Sortable()).
+			AddField(bluge.NewTextField("message", msg.Message).SearchTermPositions())
+		for _, userID := range msg.UserIDs {
+			if userID != "" {
+				doc.AddField(bluge.NewKeywordField("user", userID))
+			}
+		}
+		batch.Update(doc.ID(), doc)
+	}
+	if err := m.writer.Batch(batch); err != nil {
+		return err
+	}
+	last := docs[len(docs)-1].ID
+	if last > m.lastID {
+		m.lastID = last
+	}
+	return m.saveStateLocked()
+}
+
+func (m *MessageIndex) saveStateLocked() error {
+	return os.WriteFile(filepath.Join(m.dir, messageIndexStateFile), []byte(strconv.FormatUint(m.lastID, 10)), 0o600)
+}
+
+// Delete 从索引中移除消息，用于日志被删除的情形
+func (m *MessageIndex) Delete(ids []uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.writer == nil || len(ids) == 0 {
+		return nil
+	}
+	batch := bluge.NewBatch()
+	for _, id := range ids {
+		batch.Delete(bluge.Identifier(strconv.FormatUint(id, 10)))
+	}
+	return m.writer.Batch(batch)
+}
+
+// Search 返回按时间倒序的消息ID和命中总数
+func (m *MessageIndex) Search(q MessageQuery) ([]uint64, uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.writer == nil {
+		return nil, 0, errors.New("消息索引已关闭")
+	}
+
+	query := bluge.NewBooleanQuery()
+	if q.Text != "" {
+		terms := bluge.NewBooleanQuery()
+		for _, term := range reSpace.Split(q.Text, -1) {
+			if term != "" {
+				terms.AddMust(bluge.NewMatchPhraseQuery(term).SetField("message"))
+			}
+		}
+		query.AddMust(terms)
+	} else {
+		query.AddMust(bluge.NewMatchAllQuery())
+	}
+	if q.GroupID != "" {
+		query.AddMust(bluge.NewTermQuery(q.GroupID).SetField("group"))
+	}
+	if q.UserID != "" {
+		query.AddMust(bluge.NewTermQuery(q.UserID).SetField("user"))
+	}
+	if q.TimeBegin > 0 || q.TimeEnd > 0 {
+		begin, end := float64(q.TimeBegin), math.Inf(1)
+		if q.TimeEnd > 0 {
+			end = float64(q.TimeEnd)
+		}
+		query.AddMust(bluge.NewNumericRangeInclusiveQuery(begin, end, true, true).SetField("time"))
+	}
+
+	from := max((q.PageNum-1)*q.PageSize, 0)
+	reader, err := m.writer.Reader()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	request := bluge.NewTopNSearch(max(q.PageSize, 0), query).
+		SetFrom(from).
+		SortBy([]string{"-time", "-_id"}).
+		WithStandardAggregations()
+	matches, err := reader.Search(context.Background(), request)
+	if err != nil {
+		return nil, 0, err
+	}
+	var ids []uint64
+	for {
+		match, nextErr := matches.Next()
+		if nextErr != nil {
+			return nil, 0, nextErr
+		}
+		if match == nil {
+			break
+		}
+		id, _, visitErr := storedFields(match)
+		if visitErr != nil {
+			return nil, 0, visitErr
+		}
+		if n, parseErr := strconv.ParseUint(id, 10, 64); parseErr == nil {
+			ids = append(ids, n)
+		}
+	}
+	return ids, matches.Aggregations().Count(), nil
+}
@@ -0,0 +1,64 @@
+package docengine //nolint:testpackage // Tests need access to unexported index helpers.
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMessageIndexSearchAndResume(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "messages")
+	index, err := OpenMessageIndex(dir)
+	if err != nil {
+		t.Fatalf("OpenMessageIndex() error = %v", err)
+	}
+	err = index.Add([]MessageDoc{
+		{ID: 1, GroupID: "QQ-Group:1", UserIDs: []string{"QQ:10", "UI:1"}, Time: 100, Message: "调查员打开了地下室的门"},
+		{ID: 2, GroupID: "QQ-Group:1", UserIDs: []string{"QQ:11"}, Time: 200, Message: "地下室里一片漆黑"},
+		{ID: 3, GroupID: "QQ-Group:2", UserIDs: []string{"QQ:10", "UI:1"}, Time: 300, Message: "楼上传来脚步声"},
+	})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query MessageQuery
+		want  []uint64
+	}{
+		{"text", MessageQuery{Text: "地下室"}, []uint64{2, 1}},
+		{"text and user", MessageQuery{Text: "地下室", UserID: "UI:1"}, []uint64{1}},
+		{"group", MessageQuery{GroupID: "QQ-Group:2"}, []uint64{3}},
+		{"time", MessageQuery{TimeBegin: 150, TimeEnd: 300}, []uint64{3, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			q.PageNum, q.PageSize = 1, 10
+			ids, total, searchErr := index.Search(q)
+			if searchErr != nil {
+				t.Fatalf("Search() error = %v", searchErr)
+			}
+			if int(total) != len(tt.want) || len(ids) != len(tt.want) {
+				t.Fatalf("Search() = %v (total %d), want %v", ids, total, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("Search() = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+
+	index.Close()
+	if !MessageIndexExists(dir) {
+		t.Fatal("MessageIndexExists() = false after open")
+	}
+	reopened, err := OpenMessageIndex(dir)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+	if reopened.LastID() != 3 {
+		t.Fatalf("LastID() = %d, want 3", reopened.LastID())
+	}
+}
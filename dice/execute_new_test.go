@@ -48,6 +48,7 @@ type mockPlatformAdapter struct {
 	personMsgs []string
 	quitGroups []string
 	msgCh      chan string
+	cannotQuit bool
 }
 
 // compile-time check
@@ -102,6 +103,9 @@ func (m *mockPlatformAdapter) MemberKick(_ string, _ string)            {}
 func (m *mockPlatformAdapter) GetGroupInfoAsync(_ string)               {}
 func (m *mockPlatformAdapter) EditMessage(_ *MsgContext, _, _ string)   {}
 func (m *mockPlatformAdapter) RecallMessage(_ *MsgContext, _ string)    {}
+func (m *mockPlatformAdapter) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{QuitGroup: !m.cannotQuit, RichElements: richElements(message.Text)}
+}
 func (m *mockPlatformAdapter) SendSegmentToGroup(_ *MsgContext, _ string, _ []message.IMessageElement, _ string) {
 }
 func (m *mockPlatformAdapter) SendSegmentToPerson(_ *MsgContext, _ string, _ []message.IMessageElement, _ string) {
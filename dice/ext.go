@@ -10,7 +10,6 @@ import (
 	"strings"
 	"sync/atomic"
 
-	"github.com/dop251/goja"
 	"github.com/tidwall/buntdb"
 	"go.uber.org/zap"
 
@@ -324,23 +323,12 @@ func (i *ExtInfo) CallOnGroupLeave(d *Dice, ctx *MsgContext, event *events.Group
 func (i *ExtInfo) callWithJsCheck(d *Dice, f func()) {
 	if i.IsJsExt {
 		if d.Config.JsEnable {
-			loop, err := d.ExtLoopManager.GetLoop(i.JSLoopVersion)
-			if err != nil {
-				i.dice.Logger.Errorf("扩展<%s>运行环境已经过期: %v", i.Name, err)
-				return
+			err := d.jsCallHook(i.JSLoopVersion, i.Name, f)
+			if errors.Is(err, ErrJsRuntimeExpired) {
+				d.Logger.Errorf("扩展<%s>%v", i.Name, err)
+			} else if err != nil {
+				d.Logger.Error("JS脚本异常:", err)
 			}
-			waitRun := make(chan int, 1)
-			loop.RunOnLoop(func(vm *goja.Runtime) {
-				defer func() {
-					if r := recover(); r != nil {
-						d.Logger.Error("JS脚本异常:", r)
-					}
-					waitRun <- 1
-				}()
-
-				f()
-			})
-			<-waitRun
 		} else {
 			d.Logger.Infof("当前已关闭js扩展<%v>", i.Name)
 		}
@@ -1319,6 +1319,20 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 		},
 	}
 
+	cmdChase := &CmdItemInfo{
+		Name:      "chase",
+		ShortHelp: helpChase,
+		Help:      "追逐:\n" + helpChase,
+		Solve:     cocChaseSolve,
+	}
+
+	cmdCombat := &CmdItemInfo{
+		Name:      "combat",
+		ShortHelp: helpCombat,
+		Help:      "战斗轮顺序(按DEX从高到低):\n" + helpCombat,
+		Solve:     cocCombatSolve,
+	}
+
 	cmdCoc := &CmdItemInfo{
 		Name:      "coc",
 		ShortHelp: ".coc [<数量>] // 制卡指令，返回<数量>组人物属性",
@@ -1396,6 +1410,8 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 			"rcv":    cmdRcv,
 			"sc":     cmdSc,
 			"coc":    cmdCoc,
+			"chase":  cmdChase,
+			"combat": cmdCombat,
 			"st":     cmdSt,
 			"cst":    cmdSt,
 		},
@@ -0,0 +1,888 @@
+package dice
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+// CocChaseHazard 追逐中某个地点的危险，通过时需进行技能检定
+type CocChaseHazard struct {
+	Skill      string `json:"skill"      yaml:"skill"`
+	Difficulty int    `json:"difficulty" yaml:"difficulty"` // 1 常规 2 困难 3 极难
+	Desc       string `json:"desc"       yaml:"desc"`
+}
+
+// CocChaseUnit 追逐参与者。UID 非空为玩家，其属性与技能从人物卡读取
+type CocChaseUnit struct {
+	Name    string `json:"name"    yaml:"name"`
+	UID     string `json:"uid"     yaml:"uid"`
+	Pos     int    `json:"pos"     yaml:"pos"`
+	MOV     int64  `json:"mov"     yaml:"mov"`
+	DEX     int64  `json:"dex"     yaml:"dex"`
+	Actions int64  `json:"actions" yaml:"actions"` // 本轮剩余移动行动
+	// SpeedChecked 是否已进行过速度检定，每场追逐一次
+	SpeedChecked bool `json:"speedChecked" yaml:"speedChecked"`
+}
+
+// CocChase 群内的追逐，随 GroupInfo 一同存入数据库
+type CocChase struct {
+	Length  int                     `json:"length"  yaml:"length"` // 地点数量，编号从1开始
+	Hazards map[int]*CocChaseHazard `json:"hazards" yaml:"hazards"`
+	Units   []*CocChaseUnit         `json:"units"   yaml:"units"`
+	Round   int                     `json:"round"   yaml:"round"`
+}
+
+// CocCombatUnit 战斗轮中的单位，Gun 表示已准备好火器(DEX+50)
+type CocCombatUnit struct {
+	Name string `json:"name" yaml:"name"`
+	UID  string `json:"uid"  yaml:"uid"`
+	DEX  int64  `json:"dex"  yaml:"dex"`
+	Gun  bool   `json:"gun"  yaml:"gun"`
+}
+
+// CocCombat 群内的战斗轮顺序
+type CocCombat struct {
+	Units []*CocCombatUnit `json:"units" yaml:"units"`
+	Round int              `json:"round" yaml:"round"`
+	Cur   int              `json:"cur"   yaml:"cur"` // 当前行动单位的下标
+}
+
+const (
+	cocChaseDefaultLength = 10
+	cocChaseMaxLength     = 50
+	// NPC 没有人物卡，未指定技能值时按此值检定
+	cocChaseNpcSkillDefault = 50
+	// 火器已准备好时，战斗轮顺序中 DEX 的加值
+	cocCombatGunBonus = 50
+)
+
+var cocChaseLock sync.Mutex
+
+// clone 深拷贝追逐，便于在锁外序列化
+func (c *CocChase) clone() *CocChase {
+	if c == nil {
+		return nil
+	}
+	cc := *c
+	if c.Hazards != nil {
+		cc.Hazards = make(map[int]*CocChaseHazard, len(c.Hazards))
+		for pos, h := range c.Hazards {
+			v := *h
+			cc.Hazards[pos] = &v
+		}
+	}
+	cc.Units = nil
+	for _, u := range c.Units {
+		v := *u
+		cc.Units = append(cc.Units, &v)
+	}
+	return &cc
+}
+
+// clone 深拷贝战斗轮，便于在锁外序列化
+func (c *CocCombat) clone() *CocCombat {
+	if c == nil {
+		return nil
+	}
+	cc := *c
+	cc.Units = nil
+	for _, u := range c.Units {
+		v := *u
+		cc.Units = append(cc.Units, &v)
+	}
+	return &cc
+}
+
+// cocChaseCopy 在锁内复制群内的追逐与战斗轮，序列化群信息时使用
+func cocChaseCopy(g *GroupInfo) (*CocChase, *CocCombat) {
+	cocChaseLock.Lock()
+	defer cocChaseLock.Unlock()
+	return g.CocChase.clone(), g.CocCombat.clone()
+}
+
+func (u *CocCombatUnit) OrderDEX() int64 {
+	if u.Gun {
+		return u.DEX + cocCombatGunBonus
+	}
+	return u.DEX
+}
+
+func (c *CocChase) Find(name string) *CocChaseUnit {
+	for _, i := range c.Units {
+		if i.Name == name {
+			return i
+		}
+	}
+	return nil
+}
+
+func (c *CocChase) FindByUID(uid string) *CocChaseUnit {
+	for _, i := range c.Units {
+		if i.UID != "" && i.UID == uid {
+			return i
+		}
+	}
+	return nil
+}
+
+// SetUnit 添加参与者，同名则覆盖
+func (c *CocChase) SetUnit(u *CocChaseUnit) {
+	if u.Pos < 1 {
+		u.Pos = 1
+	}
+	if u.Pos > c.Length {
+		u.Pos = c.Length
+	}
+	for index, i := range c.Units {
+		if i.Name == u.Name {
+			c.Units[index] = u
+			return
+		}
+	}
+	c.Units = append(c.Units, u)
+}
+
+// SortByDEX 按 DEX 从高到低排列，即行动顺序
+func (c *CocChase) SortByDEX() {
+	sort.SliceStable(c.Units, func(i, j int) bool {
+		return c.Units[i].DEX > c.Units[j].DEX
+	})
+}
+
+// NewRound 开始新的一轮，每个参与者获得 1 + (MOV - 最慢者MOV) 次移动行动
+func (c *CocChase) NewRound() {
+	if len(c.Units) == 0 {
+		return
+	}
+	slowest := c.Units[0].MOV
+	for _, i := range c.Units {
+		if i.MOV < slowest {
+			slowest = i.MOV
+		}
+	}
+	for _, i := range c.Units {
+		i.Actions = 1 + i.MOV - slowest
+	}
+	c.Round++
+	c.SortByDEX()
+}
+
+// HazardAt 返回地点上的危险，没有时返回 nil
+func (c *CocChase) HazardAt(pos int) *CocChaseHazard {
+	if c.Hazards == nil {
+		return nil
+	}
+	return c.Hazards[pos]
+}
+
+// SortByDEX 按 DEX(含火器加值)从高到低排列，保持当前行动单位不变。
+// 按名字而非指针追踪当前单位，同名单位被替换后仍指向它
+func (c *CocCombat) SortByDEX() {
+	cur := ""
+	if c.Cur >= 0 && c.Cur < len(c.Units) {
+		cur = c.Units[c.Cur].Name
+	}
+	sort.SliceStable(c.Units, func(i, j int) bool {
+		return c.Units[i].OrderDEX() > c.Units[j].OrderDEX()
+	})
+	c.Cur = 0
+	for index, i := range c.Units {
+		if i.Name == cur {
+			c.Cur = index
+			break
+		}
+	}
+}
+
+// SetUnit 添加单位，同名则覆盖
+func (c *CocCombat) SetUnit(u *CocCombatUnit) {
+	replaced := false
+	for index, i := range c.Units {
+		if i.Name == u.Name {
+			c.Units[index] = u
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		c.Units = append(c.Units, u)
+	}
+	c.SortByDEX()
+}
+
+// Remove 移除单位，返回是否存在
+func (c *CocCombat) Remove(name string) bool {
+	for index, i := range c.Units {
+		if i.Name == name {
+			c.Units = append(c.Units[:index], c.Units[index+1:]...)
+			if index < c.Cur {
+				c.Cur--
+			}
+			if c.Cur >= len(c.Units) {
+				c.Cur = 0
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Next 轮到下一个单位，返回该单位，一轮结束时回合数加一
+func (c *CocCombat) Next() *CocCombatUnit {
+	if len(c.Units) == 0 {
+		return nil
+	}
+	if c.Round == 0 {
+		c.Round = 1
+		c.Cur = 0
+		return c.Units[0]
+	}
+	c.Cur++
+	if c.Cur >= len(c.Units) {
+		c.Cur = 0
+		c.Round++
+	}
+	return c.Units[c.Cur]
+}
+
+// cocChaseModify 在锁内修改当前群的追逐或战斗轮并标记保存
+func cocChaseModify(ctx *MsgContext, fn func(group *GroupInfo)) {
+	cocChaseLock.Lock()
+	fn(ctx.Group)
+	cocChaseLock.Unlock()
+	ctx.Group.MarkDirty(ctx.Dice)
+}
+
+// cocReadCardAttr 从当前绑定的人物卡读取属性，没有录入时使用模板默认值
+func cocReadCardAttr(ctx *MsgContext, name string) (int64, bool) {
+	tmpl := ctx.Group.GetCharTemplate(ctx.Dice)
+	if tmpl != nil {
+		name = tmpl.GetAlias(name)
+	}
+	if attrs, err := ctx.Dice.AttrsManager.LoadByCtx(ctx); err == nil {
+		if v, exists := attrs.LoadX(name); exists && v.TypeId == ds.VMTypeInt {
+			return int64(v.MustReadInt()), true
+		}
+	}
+	if tmpl == nil {
+		return 0, false
+	}
+	v, _, _, exists := tmpl.GetDefaultValueEx0(ctx, name)
+	if !exists || v.TypeId != ds.VMTypeInt {
+		return 0, false
+	}
+	return int64(v.MustReadInt()), true
+}
+
+// cocCheckSuccess 在有难度要求时，成功等级需达到难度才算成功
+func cocCheckSuccess(successRank int, difficulty int) bool {
+	if difficulty < 1 {
+		difficulty = 1
+	}
+	return successRank >= difficulty
+}
+
+func cocCheckVal(attrVal int64, difficulty int, criticalSuccessValue int64) int64 {
+	switch difficulty {
+	case 2:
+		return attrVal / 2
+	case 3:
+		return attrVal / 5
+	case 4:
+		return criticalSuccessValue
+	}
+	return attrVal
+}
+
+// cocChaseCheck 进行一次追逐中的检定，设置模板变量并返回 CommandInfo 条目
+func cocChaseCheck(ctx *MsgContext, skill string, attrVal int64, difficulty int) (bool, map[string]any) {
+	d100 := ctx.Roll64(100)
+	successRank, criticalSuccessValue := ResultCheck(ctx, ctx.Group.CocRuleIndex, d100, attrVal, difficulty)
+	checkVal := cocCheckVal(attrVal, difficulty, criticalSuccessValue)
+
+	VarSetValueStr(ctx, "$t技能", skill)
+	VarSetValueInt64(ctx, "$t骰子出目", d100)
+	VarSetValueInt64(ctx, "$t判定值", checkVal)
+	VarSetValueInt64(ctx, "$tSuccessRank", int64(successRank))
+	VarSetValueStr(ctx, "$t判定结果", GetResultTextWithRequire(ctx, successRank, difficulty, true))
+
+	return cocCheckSuccess(successRank, difficulty), map[string]any{
+		"expr1":      "D100",
+		"expr2":      skill,
+		"outcome":    d100,
+		"attrVal":    attrVal,
+		"checkVal":   checkVal,
+		"rank":       successRank,
+		"difficulty": difficulty,
+	}
+}
+
+func cocChaseSetCommandInfo(ctx *MsgContext, cmd string, pcName string, items []any) {
+	ctx.CommandInfo = map[string]any{
+		"cmd":     cmd,
+		"rule":    "coc7",
+		"pcName":  pcName,
+		"cocRule": ctx.Group.CocRuleIndex,
+		"items":   items,
+	}
+}
+
+func cocChaseAppendCommandInfo(ctx *MsgContext, cmdArgs *CmdArgs, text string) string {
+	if cmdArgs.GetKwarg("ci") == nil || ctx.CommandInfo == nil {
+		return text
+	}
+	info, err := json.Marshal(ctx.CommandInfo)
+	if err != nil {
+		return text + "\n指令信息无法序列化"
+	}
+	return text + "\n" + string(info)
+}
+
+func cocDifficultyText(difficulty int) string {
+	switch difficulty {
+	case 2:
+		return "困难"
+	case 3:
+		return "极难"
+	case 4:
+		return "大成功"
+	}
+	return ""
+}
+
+func (c *CocChase) Text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "追逐 第%d轮，共%d个地点\n", c.Round, c.Length)
+	for pos := 1; pos <= c.Length; pos++ {
+		var names []string
+		for _, u := range c.Units {
+			if u.Pos == pos {
+				names = append(names, u.Name)
+			}
+		}
+		h := c.HazardAt(pos)
+		if len(names) == 0 && h == nil {
+			continue
+		}
+		fmt.Fprintf(&sb, "[%d]", pos)
+		if h != nil {
+			fmt.Fprintf(&sb, " 危险:%s%s", cocDifficultyText(h.Difficulty), h.Skill)
+			if h.Desc != "" {
+				fmt.Fprintf(&sb, "(%s)", h.Desc)
+			}
+		}
+		if len(names) > 0 {
+			sb.WriteString(" " + strings.Join(names, "、"))
+		}
+		sb.WriteString("\n")
+	}
+	if len(c.Units) > 0 {
+		sb.WriteString("行动顺序:\n")
+		for index, u := range c.Units {
+			fmt.Fprintf(&sb, "%d. %s MOV%d DEX%d 剩余行动%d\n", index+1, u.Name, u.MOV, u.DEX, u.Actions)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func (c *CocCombat) Text() string {
+	var sb strings.Builder
+	if c.Round > 0 {
+		fmt.Fprintf(&sb, "战斗 第%d轮\n", c.Round)
+	} else {
+		sb.WriteString("战斗轮顺序(尚未开始):\n")
+	}
+	for index, u := range c.Units {
+		mark := ""
+		if c.Round > 0 && index == c.Cur {
+			mark = " <-"
+		}
+		gun := ""
+		if u.Gun {
+			gun = fmt.Sprintf("(火器+%d)", cocCombatGunBonus)
+		}
+		fmt.Fprintf(&sb, "%d. %s DEX%d%s%s\n", index+1, u.Name, u.DEX, gun, mark)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+const helpChase = `.chase new [<地点数>] // 开始新的追逐，默认10个地点
+.chase join [--pos=<位置>] // 以当前人物卡的MOV与DEX加入追逐
+.chase npc <名称> <位置> <MOV> [<DEX>] // 加入NPC
+.chase hazard <位置> <技能> [困难|极难] [<描述>] // 在地点设置危险
+.chase speed // 速度检定(体质)，极难成功MOV+1，失败MOV-1
+.chase round // 开始新的一轮，计算移动行动
+.chase move [<步数>] [<名称>] [--val=<技能值>] // 移动，途经危险时进行检定
+.chase rm <名称> // 移出追逐
+.chase show // 查看追逐状态
+.chase end // 结束追逐`
+
+const helpCombat = `.combat // 查看战斗轮顺序
+.combat join [--gun] // 以当前人物卡的DEX加入，--gun 为已准备好火器(DEX+50)
+.combat npc <名称> <DEX> [--gun] // 加入NPC
+.combat next // 轮到下一个单位
+.combat rm <名称> // 移除单位
+.combat clr // 清空战斗轮`
+
+func cocChaseSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+	reply := func(text string) CmdExecuteResult {
+		ReplyToSender(ctx, msg, text)
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+	if ctx.Group == nil {
+		return reply("追逐只能在群内使用")
+	}
+
+	sub := strings.ToLower(cmdArgs.GetArgN(1))
+	if sub == "help" {
+		return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+	}
+	if sub == "new" {
+		length := cocChaseDefaultLength
+		if v, err := strconv.Atoi(cmdArgs.GetArgN(2)); err == nil {
+			length = v
+		}
+		if length < 2 || length > cocChaseMaxLength {
+			return reply(fmt.Sprintf("地点数需要在2到%d之间", cocChaseMaxLength))
+		}
+		cocChaseModify(ctx, func(group *GroupInfo) {
+			group.CocChase = &CocChase{Length: length, Hazards: map[int]*CocChaseHazard{}}
+		})
+		VarSetValueInt64(ctx, "$t数量", int64(length))
+		return reply(DiceFormatTmpl(ctx, "COC:追逐_开始"))
+	}
+
+	cocChaseLock.Lock()
+	exists := ctx.Group.CocChase != nil
+	cocChaseLock.Unlock()
+	if !exists {
+		return reply(DiceFormatTmpl(ctx, "COC:追逐_未开始"))
+	}
+
+	switch sub {
+	case "", "show", "list":
+		var text string
+		cocChaseLock.Lock()
+		text = ctx.Group.CocChase.Text()
+		cocChaseLock.Unlock()
+		return reply(text)
+	case "end":
+		cocChaseModify(ctx, func(group *GroupInfo) {
+			group.CocChase = nil
+		})
+		return reply(DiceFormatTmpl(ctx, "COC:追逐_结束"))
+	case "join":
+		// 属性只能读自己的人物卡，其他角色用 npc 加入
+		if cmdArgs.GetArgN(2) != "" {
+			return reply("只能以自己的人物卡加入，其他角色请使用 .chase npc <名称> <位置> <MOV> [<DEX>]")
+		}
+		mov, ok := cocReadCardAttr(ctx, "移动力")
+		if !ok {
+			return reply("未能从人物卡读取移动力，请先录卡")
+		}
+		dex, _ := cocReadCardAttr(ctx, "敏捷")
+		pos := 1
+		if kw := cmdArgs.GetKwarg("pos"); kw != nil {
+			if v, err := strconv.Atoi(kw.Value); err == nil {
+				pos = v
+			}
+		}
+		var text string
+		cocChaseModify(ctx, func(group *GroupInfo) {
+			if group.CocChase == nil {
+				return
+			}
+			u := &CocChaseUnit{Name: ctx.Player.Name, UID: ctx.Player.UserID, Pos: pos, MOV: mov, DEX: dex}
+			group.CocChase.SetUnit(u)
+			group.CocChase.SortByDEX()
+			text = fmt.Sprintf("%s加入追逐，位置%d MOV%d DEX%d", u.Name, u.Pos, u.MOV, u.DEX)
+		})
+		if text == "" {
+			// 读人物卡期间追逐已结束
+			return reply(DiceFormatTmpl(ctx, "COC:追逐_未开始"))
+		}
+		return reply(text)
+	case "npc":
+		name := cmdArgs.GetArgN(2)
+		pos, err1 := strconv.Atoi(cmdArgs.GetArgN(3))
+		mov, err2 := strconv.ParseInt(cmdArgs.GetArgN(4), 10, 64)
+		if name == "" || err1 != nil || err2 != nil {
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		dex, _ := strconv.ParseInt(cmdArgs.GetArgN(5), 10, 64)
+		var text string
+		cocChaseModify(ctx, func(group *GroupInfo) {
+			u := &CocChaseUnit{Name: name, Pos: pos, MOV: mov, DEX: dex}
+			group.CocChase.SetUnit(u)
+			group.CocChase.SortByDEX()
+			text = fmt.Sprintf("%s加入追逐，位置%d MOV%d DEX%d", u.Name, u.Pos, u.MOV, u.DEX)
+		})
+		return reply(text)
+	case "rm", "del":
+		name := cmdArgs.GetArgN(2)
+		removed := false
+		cocChaseModify(ctx, func(group *GroupInfo) {
+			units := group.CocChase.Units[:0]
+			for _, u := range group.CocChase.Units {
+				if u.Name == name {
+					removed = true
+					continue
+				}
+				units = append(units, u)
+			}
+			group.CocChase.Units = units
+		})
+		if !removed {
+			VarSetValueStr(ctx, "$t目标", name)
+			return reply(DiceFormatTmpl(ctx, "COC:追逐_单位不存在"))
+		}
+		return reply(fmt.Sprintf("%s已退出追逐", name))
+	case "hazard":
+		pos, err := strconv.Atoi(cmdArgs.GetArgN(2))
+		skill := cmdArgs.GetArgN(3)
+		if err != nil || skill == "" {
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		difficulty := 1
+		descIndex := 4
+		if d, ok := difficultyPrefixMap[cmdArgs.GetArgN(4)]; ok && cmdArgs.GetArgN(4) != "" {
+			difficulty = d
+			descIndex = 5
+		}
+		desc := strings.Join(cmdArgs.Args[min(descIndex-1, len(cmdArgs.Args)):], " ")
+		if tmpl := ctx.Group.GetCharTemplate(ctx.Dice); tmpl != nil {
+			skill = tmpl.GetAlias(skill)
+		}
+		var length int
+		cocChaseModify(ctx, func(group *GroupInfo) {
+			length = group.CocChase.Length
+			if pos < 1 || pos > length {
+				return
+			}
+			if group.CocChase.Hazards == nil {
+				group.CocChase.Hazards = map[int]*CocChaseHazard{}
+			}
+			group.CocChase.Hazards[pos] = &CocChaseHazard{Skill: skill, Difficulty: difficulty, Desc: desc}
+		})
+		if pos < 1 || pos > length {
+			return reply(fmt.Sprintf("地点编号需要在1到%d之间", length))
+		}
+		return reply(fmt.Sprintf("已在%d号地点设置危险: %s%s %s", pos, cocDifficultyText(difficulty), skill, desc))
+	case "speed":
+		return cocChaseSpeed(ctx, msg, cmdArgs)
+	case "round":
+		var text string
+		cocChaseModify(ctx, func(group *GroupInfo) {
+			group.CocChase.NewRound()
+			text = group.CocChase.Text()
+		})
+		return reply(text)
+	case "move", "mv":
+		return cocChaseMove(ctx, msg, cmdArgs)
+	}
+	return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+}
+
+// cocChaseSpeed 追逐开始时的速度检定：体质检定极难成功 MOV+1，失败 MOV-1
+func cocChaseSpeed(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+	reply := func(text string) CmdExecuteResult {
+		ReplyToSender(ctx, msg, text)
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+	uid := ctx.Player.UserID
+	cocChaseLock.Lock()
+	var found bool
+	if ctx.Group.CocChase != nil {
+		found = ctx.Group.CocChase.FindByUID(uid) != nil
+	}
+	cocChaseLock.Unlock()
+	if !found {
+		VarSetValueStr(ctx, "$t目标", ctx.Player.Name)
+		return reply(DiceFormatTmpl(ctx, "COC:追逐_单位不存在"))
+	}
+
+	con, ok := cocReadCardAttr(ctx, "体质")
+	if !ok {
+		return reply("未能从人物卡读取体质，请先录卡")
+	}
+	d100 := ctx.Roll64(100)
+	successRank, _ := ResultCheck(ctx, ctx.Group.CocRuleIndex, d100, con, 0)
+
+	// 检定期间单位可能被移出或已由另一条指令完成检定，在锁内重新查找并标记
+	var name string
+	var checked bool
+	var movOld, movNew int64
+	found = false
+	cocChaseModify(ctx, func(group *GroupInfo) {
+		if group.CocChase == nil {
+			return
+		}
+		u := group.CocChase.FindByUID(uid)
+		if u == nil {
+			return
+		}
+		name, found = u.Name, true
+		if u.SpeedChecked {
+			checked = true
+			return
+		}
+		movOld = u.MOV
+		switch {
+		case successRank >= 3:
+			u.MOV++
+		case successRank < 0:
+			u.MOV--
+		}
+		u.SpeedChecked = true
+		movNew = u.MOV
+	})
+	if !found {
+		VarSetValueStr(ctx, "$t目标", ctx.Player.Name)
+		return reply(DiceFormatTmpl(ctx, "COC:追逐_单位不存在"))
+	}
+	if checked {
+		return reply(fmt.Sprintf("%s已经进行过速度检定", name))
+	}
+
+	VarSetValueStr(ctx, "$t单位", name)
+	VarSetValueInt64(ctx, "$t骰子出目", d100)
+	VarSetValueInt64(ctx, "$t判定值", con)
+	VarSetValueStr(ctx, "$t判定结果", GetResultText(ctx, successRank, true))
+	VarSetValueInt64(ctx, "$t旧移动力", movOld)
+	VarSetValueInt64(ctx, "$t新移动力", movNew)
+
+	cocChaseSetCommandInfo(ctx, "chase", name, []any{
+		map[string]any{
+			"type":     "speed",
+			"expr1":    "D100",
+			"expr2":    "体质",
+			"outcome":  d100,
+			"attrVal":  con,
+			"checkVal": con,
+			"rank":     successRank,
+			"movOld":   movOld,
+			"movNew":   movNew,
+		},
+	})
+	text := cocChaseAppendCommandInfo(ctx, cmdArgs, DiceFormatTmpl(ctx, "COC:追逐_速度检定"))
+	ReplyToSender(ctx, msg, text)
+	return CmdExecuteResult{Matched: true, Solved: true}
+}
+
+// cocChaseMove 移动若干个地点，每个地点消耗一次移动行动。
+// 进入有危险的地点时进行检定，失败则额外失去 1D3 次移动行动。
+func cocChaseMove(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+	steps := 1
+	nameIndex := 2
+	if v, err := strconv.Atoi(cmdArgs.GetArgN(2)); err == nil {
+		steps = v
+		nameIndex = 3
+	}
+	name := cmdArgs.GetArgN(nameIndex)
+
+	// 在锁内复制单位与沿途的危险，检定时读人物卡、格式化文本都在锁外进行
+	var unit CocChaseUnit
+	var length int
+	hazards := map[int]CocChaseHazard{}
+	found := false
+	cocChaseLock.Lock()
+	if chase := ctx.Group.CocChase; chase != nil {
+		var u *CocChaseUnit
+		if name != "" {
+			u = chase.Find(name)
+		} else {
+			u = chase.FindByUID(ctx.Player.UserID)
+		}
+		if u != nil {
+			found = true
+			unit = *u
+			length = chase.Length
+			for pos, h := range chase.Hazards {
+				if h != nil && pos > u.Pos {
+					hazards[pos] = *h
+				}
+			}
+		}
+	}
+	cocChaseLock.Unlock()
+	if name == "" {
+		name = ctx.Player.Name
+	}
+	if !found {
+		VarSetValueStr(ctx, "$t目标", name)
+		ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "COC:追逐_单位不存在"))
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+	if steps < 1 {
+		steps = 1
+	}
+	u := &unit
+
+	var npcSkill int64 = cocChaseNpcSkillDefault
+	if kw := cmdArgs.GetKwarg("val"); kw != nil {
+		if v, err := strconv.ParseInt(kw.Value, 10, 64); err == nil {
+			npcSkill = v
+		}
+	}
+
+	var lines []string
+	var items []any
+	for ; steps > 0 && u.Actions > 0 && u.Pos < length; steps-- {
+		u.Actions--
+		u.Pos++
+		h, ok := hazards[u.Pos]
+		if !ok {
+			continue
+		}
+		attrVal := npcSkill
+		if u.UID != "" && u.UID == ctx.Player.UserID {
+			if v, ok := cocReadCardAttr(ctx, h.Skill); ok {
+				attrVal = v
+			}
+		}
+		success, item := cocChaseCheck(ctx, h.Skill, attrVal, h.Difficulty)
+		item["type"] = "hazard"
+		item["pos"] = u.Pos
+		items = append(items, item)
+
+		VarSetValueStr(ctx, "$t单位", u.Name)
+		VarSetValueInt64(ctx, "$t地点", int64(u.Pos))
+		VarSetValueStr(ctx, "$t危险描述", h.Desc)
+		if success {
+			VarSetValueStr(ctx, "$t附加语", "")
+		} else {
+			lost := ctx.Roll64(3)
+			u.Actions -= lost
+			if u.Actions < 0 {
+				u.Actions = 0
+			}
+			VarSetValueStr(ctx, "$t附加语", fmt.Sprintf("，失去%d次移动行动", lost))
+		}
+		lines = append(lines, DiceFormatTmpl(ctx, "COC:追逐_危险检定"))
+	}
+	cocChaseModify(ctx, func(group *GroupInfo) {
+		// 期间追逐被结束或单位被移除时不再写回
+		if group.CocChase == nil {
+			return
+		}
+		if target := group.CocChase.Find(u.Name); target != nil {
+			target.Pos, target.Actions = u.Pos, u.Actions
+		}
+	})
+
+	VarSetValueStr(ctx, "$t单位", u.Name)
+	VarSetValueInt64(ctx, "$t地点", int64(u.Pos))
+	VarSetValueInt64(ctx, "$t剩余行动", u.Actions)
+	lines = append(lines, DiceFormatTmpl(ctx, "COC:追逐_移动"))
+
+	if len(items) > 0 {
+		cocChaseSetCommandInfo(ctx, "chase", u.Name, items)
+	}
+	text := cocChaseAppendCommandInfo(ctx, cmdArgs, strings.Join(lines, "\n"))
+	ReplyToSender(ctx, msg, text)
+	return CmdExecuteResult{Matched: true, Solved: true}
+}
+
+func cocCombatSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+	reply := func(text string) CmdExecuteResult {
+		ReplyToSender(ctx, msg, text)
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+	if ctx.Group == nil {
+		return reply("战斗轮只能在群内使用")
+	}
+	isGun := cmdArgs.GetKwarg("gun") != nil
+
+	switch strings.ToLower(cmdArgs.GetArgN(1)) {
+	case "help":
+		return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+	case "", "show", "list":
+		var text string
+		cocChaseLock.Lock()
+		if ctx.Group.CocCombat != nil && len(ctx.Group.CocCombat.Units) > 0 {
+			text = ctx.Group.CocCombat.Text()
+		}
+		cocChaseLock.Unlock()
+		if text == "" {
+			return reply(DiceFormatTmpl(ctx, "COC:战斗轮_为空"))
+		}
+		return reply(text)
+	case "join":
+		// 属性只能读自己的人物卡，其他角色用 npc 加入
+		if cmdArgs.GetArgN(2) != "" {
+			return reply("只能以自己的人物卡加入，其他角色请使用 .combat npc <名称> <DEX>")
+		}
+		name := ctx.Player.Name
+		dex, ok := cocReadCardAttr(ctx, "敏捷")
+		if !ok {
+			return reply("未能从人物卡读取敏捷，请先录卡")
+		}
+		u := &CocCombatUnit{Name: name, UID: ctx.Player.UserID, DEX: dex, Gun: isGun}
+		cocChaseModify(ctx, func(group *GroupInfo) {
+			if group.CocCombat == nil {
+				group.CocCombat = &CocCombat{}
+			}
+			group.CocCombat.SetUnit(u)
+		})
+		cocChaseSetCommandInfo(ctx, "combat", name, []any{
+			map[string]any{"type": "join", "dex": dex, "gun": isGun},
+		})
+		return reply(cocChaseAppendCommandInfo(ctx, cmdArgs, fmt.Sprintf("%s加入战斗轮，DEX%d", name, u.OrderDEX())))
+	case "npc":
+		name := cmdArgs.GetArgN(2)
+		dex, err := strconv.ParseInt(cmdArgs.GetArgN(3), 10, 64)
+		if name == "" || err != nil {
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		u := &CocCombatUnit{Name: name, DEX: dex, Gun: isGun}
+		cocChaseModify(ctx, func(group *GroupInfo) {
+			if group.CocCombat == nil {
+				group.CocCombat = &CocCombat{}
+			}
+			group.CocCombat.SetUnit(u)
+		})
+		return reply(fmt.Sprintf("%s加入战斗轮，DEX%d", name, u.OrderDEX()))
+	case "next":
+		var u *CocCombatUnit
+		var round int
+		cocChaseModify(ctx, func(group *GroupInfo) {
+			if group.CocCombat == nil {
+				return
+			}
+			u = group.CocCombat.Next()
+			round = group.CocCombat.Round
+		})
+		if u == nil {
+			return reply(DiceFormatTmpl(ctx, "COC:战斗轮_为空"))
+		}
+		VarSetValueInt64(ctx, "$t轮数", int64(round))
+		VarSetValueStr(ctx, "$t单位", u.Name)
+		VarSetValueInt64(ctx, "$t敏捷", u.OrderDEX())
+		return reply(DiceFormatTmpl(ctx, "COC:战斗轮_轮到"))
+	case "rm", "del":
+		name := cmdArgs.GetArgN(2)
+		removed := false
+		cocChaseModify(ctx, func(group *GroupInfo) {
+			if group.CocCombat != nil {
+				removed = group.CocCombat.Remove(name)
+			}
+		})
+		if !removed {
+			VarSetValueStr(ctx, "$t目标", name)
+			return reply(DiceFormatTmpl(ctx, "COC:追逐_单位不存在"))
+		}
+		return reply(fmt.Sprintf("%s已移出战斗轮", name))
+	case "clr", "clear":
+		cocChaseModify(ctx, func(group *GroupInfo) {
+			group.CocCombat = nil
+		})
+		return reply("战斗轮已清空")
+	}
+	return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+}
@@ -0,0 +1,165 @@
+//nolint:testpackage
+package dice
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"sealdice-core/model"
+)
+
+func TestCocChaseNewRound(t *testing.T) {
+	chase := &CocChase{Length: 10}
+	chase.SetUnit(&CocChaseUnit{Name: "调查员", Pos: 3, MOV: 9, DEX: 60})
+	chase.SetUnit(&CocChaseUnit{Name: "食尸鬼", Pos: 1, MOV: 7, DEX: 65})
+	chase.SetUnit(&CocChaseUnit{Name: "警察", Pos: 20, MOV: 8, DEX: 50})
+
+	if chase.Find("警察").Pos != 10 {
+		t.Fatalf("position should clamp to chase length, got %d", chase.Find("警察").Pos)
+	}
+
+	chase.NewRound()
+	if chase.Round != 1 {
+		t.Fatalf("round = %d, want 1", chase.Round)
+	}
+	want := map[string]int64{"调查员": 3, "食尸鬼": 1, "警察": 2}
+	for name, actions := range want {
+		if got := chase.Find(name).Actions; got != actions {
+			t.Errorf("%s actions = %d, want %d", name, got, actions)
+		}
+	}
+	if chase.Units[0].Name != "食尸鬼" || chase.Units[2].Name != "警察" {
+		t.Fatalf("units should be ordered by DEX, got %s %s %s", chase.Units[0].Name, chase.Units[1].Name, chase.Units[2].Name)
+	}
+}
+
+func TestCocCombatOrder(t *testing.T) {
+	combat := &CocCombat{}
+	combat.SetUnit(&CocCombatUnit{Name: "甲", DEX: 70})
+	combat.SetUnit(&CocCombatUnit{Name: "乙", DEX: 40, Gun: true})
+	combat.SetUnit(&CocCombatUnit{Name: "丙", DEX: 55})
+
+	order := []string{"乙", "甲", "丙"}
+	for index, name := range order {
+		if combat.Units[index].Name != name {
+			t.Fatalf("order[%d] = %s, want %s", index, combat.Units[index].Name, name)
+		}
+	}
+
+	if u := combat.Next(); u.Name != "乙" || combat.Round != 1 {
+		t.Fatalf("first turn = %s round %d", u.Name, combat.Round)
+	}
+	combat.Next()
+	// 当前行动单位不应因新单位加入而改变
+	combat.SetUnit(&CocCombatUnit{Name: "丁", DEX: 99})
+	if combat.Units[combat.Cur].Name != "甲" {
+		t.Fatalf("current unit changed to %s", combat.Units[combat.Cur].Name)
+	}
+	// 替换当前行动单位(如准备火器)后仍轮到它
+	combat.SetUnit(&CocCombatUnit{Name: "甲", DEX: 70, Gun: true})
+	if combat.Units[combat.Cur].Name != "甲" || !combat.Units[combat.Cur].Gun {
+		t.Fatalf("current unit after replacing it = %+v", combat.Units[combat.Cur])
+	}
+	combat.SetUnit(&CocCombatUnit{Name: "甲", DEX: 70})
+	combat.Next()
+	if u := combat.Next(); u.Name != "丁" || combat.Round != 2 {
+		t.Fatalf("expected new round to start with 丁, got %s round %d", u.Name, combat.Round)
+	}
+
+	if !combat.Remove("丁") || combat.Remove("丁") {
+		t.Fatal("remove should succeed exactly once")
+	}
+	if combat.Units[combat.Cur].Name != "乙" {
+		t.Fatalf("current unit after removal = %s", combat.Units[combat.Cur].Name)
+	}
+}
+
+func TestCocCheckSuccess(t *testing.T) {
+	cases := []struct {
+		rank, difficulty int
+		want             bool
+	}{
+		{1, 0, true},
+		{1, 1, true},
+		{-1, 1, false},
+		{1, 2, false},
+		{2, 2, true},
+		{2, 3, false},
+		{4, 3, true},
+		{-2, 0, false},
+	}
+	for _, c := range cases {
+		if got := cocCheckSuccess(c.rank, c.difficulty); got != c.want {
+			t.Errorf("cocCheckSuccess(%d, %d) = %v, want %v", c.rank, c.difficulty, got, c.want)
+		}
+	}
+}
+
+func TestCocChasePersistsWithGroupInfo(t *testing.T) {
+	group := &GroupInfo{GroupID: "QQ-Group:1"}
+	group.CocChase = &CocChase{Length: 6, Hazards: map[int]*CocChaseHazard{
+		4: {Skill: "攀爬", Difficulty: 2, Desc: "围墙"},
+	}}
+	group.CocChase.SetUnit(&CocChaseUnit{Name: "调查员", UID: "QQ:1", Pos: 2, MOV: 8, DEX: 50})
+	group.CocCombat = &CocCombat{}
+	group.CocCombat.SetUnit(&CocCombatUnit{Name: "调查员", DEX: 50, Gun: true})
+
+	data, err := json.Marshal(group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded GroupInfo
+	if err = json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if h := loaded.CocChase.HazardAt(4); h == nil || h.Skill != "攀爬" || h.Difficulty != 2 {
+		t.Fatalf("hazard not restored: %+v", h)
+	}
+	if u := loaded.CocChase.FindByUID("QQ:1"); u == nil || u.Pos != 2 || u.MOV != 8 {
+		t.Fatalf("chase unit not restored: %+v", u)
+	}
+	if len(loaded.CocCombat.Units) != 1 || loaded.CocCombat.Units[0].OrderDEX() != 100 {
+		t.Fatalf("combat not restored: %+v", loaded.CocCombat)
+	}
+}
+
+func TestCocChaseJoinAndSpeed(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	if err := d.DBOperator.(*mockDatabaseOperator).db.AutoMigrate(&model.AttributesItemModel{}); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(text string) string {
+		t.Helper()
+		d.ImSession.ExecuteNew(ep, newGroupMsg("QQ-Group:111", "QQ:999", text))
+		got, ok := adapter.waitForMsg(2 * time.Second)
+		if !ok {
+			t.Fatalf("timeout: expected a reply to %q", text)
+		}
+		return got
+	}
+
+	send(".chase new")
+	send(".st con50 mov8 dex60")
+	if got := send(".chase join 路人"); !strings.Contains(got, ".chase npc") {
+		t.Fatalf("joining with another name should point to npc, got %q", got)
+	}
+	if got := send(".chase join"); !strings.Contains(got, "MOV8") {
+		t.Fatalf("unexpected join reply %q", got)
+	}
+	send(".chase speed")
+	if got := send(".chase speed"); !strings.Contains(got, "已经进行过速度检定") {
+		t.Fatalf("speed check should only run once, got %q", got)
+	}
+
+	group, _ := d.ImSession.ServiceAtNew.Load("QQ-Group:111")
+	cocChaseLock.Lock()
+	units := len(group.CocChase.Units)
+	cocChaseLock.Unlock()
+	if units != 1 {
+		t.Fatalf("chase should have one unit, got %d", units)
+	}
+}
@@ -35,7 +35,7 @@ func RegisterBuiltinExtCore(dice *Dice) {
 
 				skip := false
 				skipReason := ""
-				banInfo, ok := ctx.Dice.Config.BanList.GetByID(opUID)
+				banInfo, ok := ctx.Dice.Config.BanList.GetByIDLinked(opUID)
 				if ok {
 					if banInfo.Rank == 30 {
 						skip = true
@@ -644,6 +644,17 @@ func deckDraw(ctx *MsgContext, deckName string, shufflePool bool) (bool, string,
 		if i.Enable {
 			_, deckExists := i.Command[deckName]
 			if deckExists {
+				// 不放回抽取时，本群的实体牌组优先
+				if shufflePool {
+					key, ok, err := groupDeckDraw(ctx, deckName)
+					if err != nil {
+						return true, "", err
+					}
+					if ok {
+						a, b := deckStringFormat(ctx, i, key)
+						return true, a, b
+					}
+				}
 				a, b := executeDeck(ctx, i, deckName, shufflePool)
 				return true, a, b
 			}
@@ -660,6 +671,9 @@ func RegisterBuiltinExtDeck(d *Dice) {
 		".draw search <牌组名称> // 搜索相关牌组\n" +
 		".draw reload // 从硬盘重新装载牌堆，仅Master可用\n" +
 		".draw list // 查看载入的牌堆文件\n" +
+		".draw reshuffle <牌组名称> // 将牌组作为本群的实体牌组洗牌，抽出的牌在下次洗牌前不会放回\n" +
+		".draw remain <牌组名称> // 查看实体牌组剩余的牌\n" +
+		".draw close <牌组名称> // 取消实体牌组，恢复放回抽取\n" +
 		".draw <牌组名称> // 进行抽牌"
 
 	cmdDraw := &CmdItemInfo{
@@ -673,7 +687,7 @@ func RegisterBuiltinExtDeck(d *Dice) {
 				return CmdExecuteResult{Matched: true, Solved: true}
 			}
 
-			cmdArgs.ChopPrefixToArgsWith("list", "help", "reload", "search", "keys", "desc")
+			cmdArgs.ChopPrefixToArgsWith("list", "help", "reload", "search", "keys", "desc", "reshuffle", "remain", "close")
 			deckName := cmdArgs.GetArgN(1)
 
 			if deckName == "" {
@@ -766,6 +780,8 @@ func RegisterBuiltinExtDeck(d *Dice) {
 					DeckReload(d)
 					ReplyToSender(ctx, msg, "牌堆已经重新装载")
 				}
+			} else if strings.EqualFold(deckName, "reshuffle") || strings.EqualFold(deckName, "remain") || strings.EqualFold(deckName, "close") {
+				groupDeckSolve(ctx, msg, strings.ToLower(deckName), cmdArgs.GetArgN(2))
 			} else if strings.EqualFold(deckName, "search") {
 				text := cmdArgs.GetArgN(2)
 				if text != "" {
@@ -792,10 +808,14 @@ func RegisterBuiltinExtDeck(d *Dice) {
 				}
 			} else {
 				exists, result, err := deckDraw(ctx, deckName, true)
+				VarSetValueStr(ctx, "$t牌组", deckName)
+				if errors.Is(err, ErrGroupDeckExhausted) {
+					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "其它:抽牌_实体牌组_已抽完"))
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
 				if err != nil {
 					result = fmt.Sprintf("<%s>", err.Error())
 				}
-				VarSetValueStr(ctx, "$t牌组", deckName)
 
 				if exists {
 					results := []string{result}
@@ -821,6 +841,10 @@ func RegisterBuiltinExtDeck(d *Dice) {
 
 					for i := 1; i < times; i++ {
 						_, r2, errDraw := deckDraw(ctx, deckName, true)
+						if errors.Is(errDraw, ErrGroupDeckExhausted) {
+							results = append(results, DiceFormatTmpl(ctx, "其它:抽牌_实体牌组_已抽完"))
+							break
+						}
 						if errDraw != nil {
 							r2 = fmt.Sprintf("<%s>", errDraw.Error())
 						}
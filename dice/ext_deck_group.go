@@ -0,0 +1,188 @@
+package dice
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+var (
+	ErrGroupDeckNotFound  = errors.New("找不到这个牌组")
+	ErrGroupDeckEmpty     = errors.New("牌组为空，请检查格式是否正确")
+	ErrGroupDeckExhausted = errors.New("牌组已经抽完，需要重新洗牌")
+)
+
+// GroupDeckState 群内的实体牌组，随 GroupInfo 一同存入数据库。
+// Remaining 保存尚未抽出的原始条目，保留权重前缀
+type GroupDeckState struct {
+	Remaining []string `json:"remaining" yaml:"remaining"`
+	Total     int      `json:"total"     yaml:"total"`
+}
+
+var groupDeckLock sync.Mutex
+
+// groupDeckCopy 在锁内复制群内的实体牌组，序列化群信息时使用
+func groupDeckCopy(g *GroupInfo) map[string]*GroupDeckState {
+	groupDeckLock.Lock()
+	defer groupDeckLock.Unlock()
+	if g.DeckStates == nil {
+		return nil
+	}
+	states := make(map[string]*GroupDeckState, len(g.DeckStates))
+	for name, st := range g.DeckStates {
+		states[name] = &GroupDeckState{Remaining: slices.Clone(st.Remaining), Total: st.Total}
+	}
+	return states
+}
+
+// findDeckByCommand 与 deckDraw 相同，返回第一个包含该牌组的已启用牌堆
+func findDeckByCommand(d *Dice, deckName string) *DeckInfo {
+	for _, i := range d.DeckList {
+		if i.Enable {
+			if _, ok := i.Command[deckName]; ok {
+				return i
+			}
+		}
+	}
+	return nil
+}
+
+// groupDeckReshuffle 将牌组设为当前群的实体牌组，放回所有的牌
+func groupDeckReshuffle(ctx *MsgContext, deckName string) (int, error) {
+	deckInfo := findDeckByCommand(ctx.Dice, deckName)
+	if deckInfo == nil {
+		return 0, ErrGroupDeckNotFound
+	}
+	items := getDeckGroup(deckInfo, deckName)
+	if len(items) == 0 {
+		return 0, ErrGroupDeckEmpty
+	}
+
+	groupDeckLock.Lock()
+	if ctx.Group.DeckStates == nil {
+		ctx.Group.DeckStates = map[string]*GroupDeckState{}
+	}
+	ctx.Group.DeckStates[deckName] = &GroupDeckState{
+		Remaining: append([]string(nil), items...),
+		Total:     len(items),
+	}
+	groupDeckLock.Unlock()
+	ctx.Group.MarkDirty(ctx.Dice)
+	return len(items), nil
+}
+
+// groupDeckRemain 返回实体牌组剩余的牌（去掉权重前缀）和总张数，牌组未开启时 ok 为 false
+func groupDeckRemain(ctx *MsgContext, deckName string) (remaining []string, total int, ok bool) {
+	groupDeckLock.Lock()
+	defer groupDeckLock.Unlock()
+	state := ctx.Group.DeckStates[deckName]
+	if state == nil {
+		return nil, 0, false
+	}
+	remaining = make([]string, len(state.Remaining))
+	for i, item := range state.Remaining {
+		_, remaining[i] = extractWeight(item)
+	}
+	return remaining, state.Total, true
+}
+
+// groupDeckClose 取消实体牌组，之后恢复放回抽取
+func groupDeckClose(ctx *MsgContext, deckName string) bool {
+	groupDeckLock.Lock()
+	_, ok := ctx.Group.DeckStates[deckName]
+	delete(ctx.Group.DeckStates, deckName)
+	groupDeckLock.Unlock()
+	if ok {
+		ctx.Group.MarkDirty(ctx.Dice)
+	}
+	return ok
+}
+
+// groupDeckDraw 从实体牌组中抽出一张不再放回，牌组未开启时 ok 为 false
+func groupDeckDraw(ctx *MsgContext, deckName string) (key string, ok bool, err error) {
+	if ctx.Group == nil {
+		return "", false, nil
+	}
+	groupDeckLock.Lock()
+	state := ctx.Group.DeckStates[deckName]
+	if state == nil {
+		groupDeckLock.Unlock()
+		return "", false, nil
+	}
+	if len(state.Remaining) == 0 {
+		groupDeckLock.Unlock()
+		return "", true, ErrGroupDeckExhausted
+	}
+	idx := pickWeightedIndex(ctx.getDiceSource(), state.Remaining)
+	item := state.Remaining[idx]
+	state.Remaining = slices.Delete(state.Remaining, idx, idx+1)
+	groupDeckLock.Unlock()
+
+	ctx.Group.MarkDirty(ctx.Dice)
+	_, key = extractWeight(item)
+	return key, true, nil
+}
+
+// pickWeightedIndex 按条目权重随机选取下标，权重全为0时等概率选取
+func pickWeightedIndex(src ds.DiceSource, items []string) int {
+	weights := make([]int, len(items))
+	total := 0
+	for i, item := range items {
+		w, _ := extractWeight(item)
+		weights[i] = int(w)
+		total += weights[i]
+	}
+	if total < 1 {
+		return randIntnFromSource(src, len(items))
+	}
+	r := randIntnFromSource(src, total)
+	for i, w := range weights {
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	return len(items) - 1
+}
+
+// groupDeckSolve 处理 .draw reshuffle/remain/close
+func groupDeckSolve(ctx *MsgContext, msg *Message, action string, deckName string) {
+	if deckName == "" {
+		ReplyToSender(ctx, msg, "请给出牌组名称")
+		return
+	}
+	VarSetValueStr(ctx, "$t牌组", deckName)
+	switch action {
+	case "reshuffle":
+		total, err := groupDeckReshuffle(ctx, deckName)
+		if errors.Is(err, ErrGroupDeckNotFound) {
+			ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "其它:抽牌_找不到牌组"))
+			return
+		}
+		if err != nil {
+			ReplyToSender(ctx, msg, "<"+err.Error()+">")
+			return
+		}
+		VarSetValueInt64(ctx, "$t总张数", int64(total))
+		ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "其它:抽牌_实体牌组_洗牌"))
+	case "remain":
+		remaining, total, ok := groupDeckRemain(ctx, deckName)
+		if !ok {
+			ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "其它:抽牌_实体牌组_未开启"))
+			return
+		}
+		VarSetValueInt64(ctx, "$t剩余张数", int64(len(remaining)))
+		VarSetValueInt64(ctx, "$t总张数", int64(total))
+		VarSetValueStr(ctx, "$t剩余列表", strings.Join(remaining, "/"))
+		ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "其它:抽牌_实体牌组_剩余"))
+	case "close":
+		if !groupDeckClose(ctx, deckName) {
+			ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "其它:抽牌_实体牌组_未开启"))
+			return
+		}
+		ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "其它:抽牌_实体牌组_关闭"))
+	}
+}
@@ -0,0 +1,99 @@
+package dice //nolint:testpackage
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestGroupDeckDrawWithoutReplacement(t *testing.T) {
+	d := &Dice{
+		DirtyGroups: new(SyncMap[string, int64]),
+		DeckList: []*DeckInfo{{
+			Enable:    true,
+			Command:   map[string]bool{"poker": true},
+			DeckItems: map[string][]string{"poker": {"A", "::2::B", "C"}},
+		}},
+	}
+	ctx := &MsgContext{Dice: d, Group: &GroupInfo{GroupID: "QQ-Group:1"}}
+
+	if _, ok, err := groupDeckDraw(ctx, "poker"); ok || err != nil {
+		t.Fatalf("deck should not be persistent before reshuffle, ok=%v err=%v", ok, err)
+	}
+	if _, err := groupDeckReshuffle(ctx, "missing"); !errors.Is(err, ErrGroupDeckNotFound) {
+		t.Fatalf("reshuffle of unknown deck: err = %v", err)
+	}
+	total, err := groupDeckReshuffle(ctx, "poker")
+	if err != nil || total != 3 {
+		t.Fatalf("groupDeckReshuffle() = %d, %v", total, err)
+	}
+
+	var drawn []string
+	for range 3 {
+		key, ok, err := groupDeckDraw(ctx, "poker")
+		if !ok || err != nil {
+			t.Fatalf("groupDeckDraw() ok=%v err=%v", ok, err)
+		}
+		drawn = append(drawn, key)
+	}
+	slices.Sort(drawn)
+	if !slices.Equal(drawn, []string{"A", "B", "C"}) {
+		t.Fatalf("every card should be drawn exactly once, got %v", drawn)
+	}
+	if _, ok, err := groupDeckDraw(ctx, "poker"); !ok || !errors.Is(err, ErrGroupDeckExhausted) {
+		t.Fatalf("draw from empty deck: ok=%v err=%v", ok, err)
+	}
+	if _, dirty := d.DirtyGroups.Load("QQ-Group:1"); !dirty {
+		t.Fatal("drawing should mark the group dirty so the deck is saved")
+	}
+
+	if _, err := groupDeckReshuffle(ctx, "poker"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := groupDeckDraw(ctx, "poker"); err != nil {
+		t.Fatal(err)
+	}
+	remaining, total, ok := groupDeckRemain(ctx, "poker")
+	if !ok || total != 3 || len(remaining) != 2 {
+		t.Fatalf("groupDeckRemain() = %v, %d, %v", remaining, total, ok)
+	}
+
+	if !groupDeckClose(ctx, "poker") {
+		t.Fatal("close should report the deck was persistent")
+	}
+	if _, _, ok := groupDeckRemain(ctx, "poker"); ok {
+		t.Fatal("deck should no longer be persistent after close")
+	}
+}
+
+func TestGroupDeckConcurrentMarshal(t *testing.T) {
+	d := &Dice{
+		DirtyGroups: new(SyncMap[string, int64]),
+		DeckList: []*DeckInfo{{
+			Enable:    true,
+			Command:   map[string]bool{"poker": true},
+			DeckItems: map[string][]string{"poker": {"A", "B", "C"}},
+		}},
+	}
+	ctx := &MsgContext{Dice: d, Group: &GroupInfo{GroupID: "QQ-Group:1"}}
+
+	// 定时保存与 .draw 重新洗牌同时进行，-race 下不应报告数据竞争
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 200 {
+			if _, err := json.Marshal(ctx.Group); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for range 200 {
+		if _, err := groupDeckReshuffle(ctx, "poker"); err != nil {
+			t.Fatal(err)
+		}
+		_, _, _ = groupDeckDraw(ctx, "poker")
+	}
+	<-done
+}
@@ -321,6 +321,19 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 
 				newHp, _ := theNewValue.ReadInt()
 
+				if i.op == "-" {
+					// 遭遇中正在专注的单位受伤时提示专注检定，附加在其他附加语之后
+					var oldHp ds.IntType
+					if theOldValue != nil {
+						oldHp, _ = theOldValue.ReadInt()
+					}
+					if concText := dndEncounterOnDamage(ctx, int64(oldHp-newHp), int64(newHp)); concText != "" {
+						defer func() {
+							i.appendedText += "\n" + concText
+						}()
+					}
+				}
+
 				if newHp <= 0 {
 					var oldValue ds.IntType
 					if theOldValue != nil {
@@ -1475,10 +1488,11 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 			".init del <单位1> <单位2> ... // 从先攻列表中删除\n" +
 			".init set <单位名称> <先攻表达式> // 设置单位的先攻\n" +
 			".init clr // 清除先攻列表\n" +
-			".init end // 结束一回合" +
+			".init end // 结束一回合\n" +
+			helpInitEncounter + "\n" +
 			".init help // 显示本帮助",
 		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
-			cmdArgs.ChopPrefixToArgsWith("del", "set", "rm", "ed")
+			cmdArgs.ChopPrefixToArgsWith("del", "set", "rm", "ed", "uncond", "cond", "conc", "hp", "ac")
 			n := cmdArgs.GetArgN(1)
 			switch n {
 			case "", "list":
@@ -1488,8 +1502,9 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 
 				round, _ := VarGetValueInt64(ctx, "$g回合数")
 
+				combatants := dndEncounterCombatants(ctx.Group)
 				for order, i := range riList {
-					_, _ = fmt.Fprintf(&textOut, "%2d. %s: %d\n", order+1, i.name, i.val)
+					_, _ = fmt.Fprintf(&textOut, "%2d. %s: %d%s\n", order+1, i.name, i.val, dndCombatantStatusText(ctx, i, combatants[i.name]))
 				}
 
 				if len(riList) == 0 {
@@ -1513,7 +1528,7 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 				round = (round + 1) % int64(len(lst))
 
 				setInitNextRoundVars(ctx, lst, round)
-				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "DND:先攻_下一回合"))
+				ReplyToSender(ctx, msg, initNextRoundText(ctx))
 			case "del", "rm":
 				tryDeleteMembersInInitList := func(deleteNames []string, riList RIList) (newList RIList, textOut strings.Builder, ok bool) {
 					if len(riList) == 0 {
@@ -1566,7 +1581,7 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 							// Note(Xiangze Li): 这是为了让回合结束的角色显示为被删除的角色，而不是当前角色的上一个
 							VarSetValueStr(ctx, "$t当前回合角色名", current.name)
 							VarSetValueStr(ctx, "$t当前回合at", AtBuild(current.uid))
-							textOut.WriteString(DiceFormatTmpl(ctx, "DND:先攻_下一回合"))
+							textOut.WriteString(initNextRoundText(ctx))
 						}
 					}
 					return newList, textOut, true
@@ -1632,11 +1647,14 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 				ReplyToSender(ctx, msg, textOut)
 			case "clr", "clear":
 				(RIList{}).SaveToGroup(ctx)
+				dndEncounterClear(ctx)
 				VarSetValueInt64(ctx, "$g当前回合先攻值", NULL_INIT_VAL)
 				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "DND:先攻_清除列表"))
 				VarSetValueInt64(ctx, "$g回合数", 0)
 			case "help":
 				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			default:
+				dndInitEncounterSolve(ctx, msg, cmdArgs)
 			}
 
 			return CmdExecuteResult{Matched: true, Solved: true}
@@ -1790,4 +1808,14 @@ func setInitNextRoundVars(ctx *MsgContext, lst RIList, round int64) {
 	VarSetValueStr(ctx, "$t下下一回合角色名", lst[nextRound].name)
 	VarSetValueStr(ctx, "$t下下一回合at", AtBuild(lst[nextRound].uid))
 	VarSetValueInt64(ctx, "$g回合数", round)
+	VarSetValueStr(ctx, "$t战斗状态提示", dndEncounterOnTurnStart(ctx, lst[round]))
+}
+
+// initNextRoundText 回合推进的回复文本，附带遭遇中的状态变化
+func initNextRoundText(ctx *MsgContext) string {
+	text := DiceFormatTmpl(ctx, "DND:先攻_下一回合")
+	if v, ok := VarGetValueStr(ctx, "$t战斗状态提示"); ok && v != "" {
+		text += "\n" + v
+	}
+	return text
 }
@@ -0,0 +1,669 @@
+package dice
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+// DndCondition 战斗单位身上的状态，Rounds 为剩余轮数，<=0 表示持续到手动移除
+type DndCondition struct {
+	Name   string `json:"name"   yaml:"name"`
+	Rounds int    `json:"rounds" yaml:"rounds"`
+}
+
+// DndCombatant 遭遇中的单位信息，与先攻列表按名字对应。
+// 玩家单位(UID 非空)的生命值以人物卡为准，这里的 HP 仅对 NPC 生效。
+type DndCombatant struct {
+	Name          string          `json:"name"          yaml:"name"`
+	UID           string          `json:"uid"           yaml:"uid"`
+	HP            int64           `json:"hp"            yaml:"hp"`
+	HPMax         int64           `json:"hpMax"         yaml:"hpMax"`
+	AC            int64           `json:"ac"            yaml:"ac"`
+	HasHP         bool            `json:"hasHp"         yaml:"hasHp"`
+	Conditions    []*DndCondition `json:"conditions"    yaml:"conditions"`
+	Concentration string          `json:"concentration" yaml:"concentration"` // 正在专注的法术
+}
+
+// DndEncounterUnit 先攻列表条目的可序列化形式，用于遭遇存档
+type DndEncounterUnit struct {
+	Name   string `json:"name"   yaml:"name"`
+	Val    int64  `json:"val"    yaml:"val"`
+	Detail string `json:"detail" yaml:"detail"`
+	UID    string `json:"uid"    yaml:"uid"`
+}
+
+// DndEncounter 群内的战斗遭遇，随 GroupInfo 一同存入数据库
+type DndEncounter struct {
+	Combatants map[string]*DndCombatant `json:"combatants" yaml:"combatants"`
+
+	TurnTimer     int64 `json:"turnTimer"     yaml:"turnTimer"` // 单回合时限(秒)，0为不限
+	TurnStartedAt int64 `json:"turnStartedAt" yaml:"turnStartedAt"`
+
+	// 以下字段仅在存档中使用
+	Units      []*DndEncounterUnit `json:"units,omitempty"     yaml:"units,omitempty"`
+	Round      int64               `json:"round,omitempty"     yaml:"round,omitempty"`
+	CurInitVal int64               `json:"curInitVal,omitempty" yaml:"curInitVal,omitempty"`
+	SavedAt    int64               `json:"savedAt,omitempty"   yaml:"savedAt,omitempty"`
+}
+
+const dndEncounterMaxSaved = 20
+
+var dndEncounterLock sync.Mutex
+
+// clone 深拷贝遭遇，便于在锁外序列化
+func (enc *DndEncounter) clone() *DndEncounter {
+	if enc == nil {
+		return nil
+	}
+	cc := *enc
+	if enc.Combatants != nil {
+		cc.Combatants = make(map[string]*DndCombatant, len(enc.Combatants))
+		for name, c := range enc.Combatants {
+			cc.Combatants[name] = c.clone()
+		}
+	}
+	cc.Units = nil
+	for _, u := range enc.Units {
+		v := *u
+		cc.Units = append(cc.Units, &v)
+	}
+	return &cc
+}
+
+// dndEncounterCopy 在锁内复制群内的遭遇与存档，序列化群信息时使用
+func dndEncounterCopy(g *GroupInfo) (*DndEncounter, map[string]*DndEncounter) {
+	dndEncounterLock.Lock()
+	defer dndEncounterLock.Unlock()
+	var saved map[string]*DndEncounter
+	if g.DndSavedEncounters != nil {
+		saved = make(map[string]*DndEncounter, len(g.DndSavedEncounters))
+		for name, enc := range g.DndSavedEncounters {
+			saved[name] = enc.clone()
+		}
+	}
+	return g.DndEncounter.clone(), saved
+}
+
+func (enc *DndEncounter) get(name string) *DndCombatant {
+	if enc.Combatants == nil {
+		enc.Combatants = map[string]*DndCombatant{}
+	}
+	c := enc.Combatants[name]
+	if c == nil {
+		c = &DndCombatant{Name: name}
+		enc.Combatants[name] = c
+	}
+	return c
+}
+
+// prune 移除已不在先攻列表中的单位
+func (enc *DndEncounter) prune(lst RIList) {
+	for name := range enc.Combatants {
+		if lst.GetExists(name) == nil {
+			delete(enc.Combatants, name)
+		}
+	}
+}
+
+// AddCondition 添加或刷新状态
+func (c *DndCombatant) AddCondition(name string, rounds int) {
+	for _, i := range c.Conditions {
+		if i.Name == name {
+			i.Rounds = rounds
+			return
+		}
+	}
+	c.Conditions = append(c.Conditions, &DndCondition{Name: name, Rounds: rounds})
+}
+
+// RemoveCondition 移除状态，返回是否存在
+func (c *DndCombatant) RemoveCondition(name string) bool {
+	for index, i := range c.Conditions {
+		if i.Name == name {
+			c.Conditions = append(c.Conditions[:index], c.Conditions[index+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// TickConditions 在单位的回合开始时减少状态持续轮数，返回到期的状态
+func (c *DndCombatant) TickConditions() []string {
+	var expired []string
+	kept := c.Conditions[:0]
+	for _, i := range c.Conditions {
+		if i.Rounds > 0 {
+			i.Rounds--
+			if i.Rounds == 0 {
+				expired = append(expired, i.Name)
+				continue
+			}
+		}
+		kept = append(kept, i)
+	}
+	c.Conditions = kept
+	return expired
+}
+
+// ApplyDamage 对 NPC 造成伤害或治疗(负数)，生命值限制在 0 和上限之间
+func (c *DndCombatant) ApplyDamage(damage int64) {
+	c.HP -= damage
+	if c.HP < 0 {
+		c.HP = 0
+	}
+	if c.HPMax > 0 && c.HP > c.HPMax {
+		c.HP = c.HPMax
+	}
+}
+
+// clone 深拷贝单位信息，便于在锁外读取
+func (c *DndCombatant) clone() *DndCombatant {
+	cc := *c
+	cc.Conditions = nil
+	for _, cond := range c.Conditions {
+		v := *cond
+		cc.Conditions = append(cc.Conditions, &v)
+	}
+	return &cc
+}
+
+// ConditionsText 状态的展示文本
+func (c *DndCombatant) ConditionsText() string {
+	parts := make([]string, 0, len(c.Conditions))
+	for _, i := range c.Conditions {
+		if i.Rounds > 0 {
+			parts = append(parts, fmt.Sprintf("%s(%d轮)", i.Name, i.Rounds))
+		} else {
+			parts = append(parts, i.Name)
+		}
+	}
+	return strings.Join(parts, "、")
+}
+
+// dndConcentrationDC 专注检定的 DC：10 与伤害一半中取较大者
+func dndConcentrationDC(damage int64) int64 {
+	dc := damage / 2
+	if dc < 10 {
+		dc = 10
+	}
+	return dc
+}
+
+func dndEncounterOf(group *GroupInfo) *DndEncounter {
+	if group.DndEncounter == nil {
+		group.DndEncounter = &DndEncounter{}
+	}
+	if group.DndEncounter.Combatants == nil {
+		group.DndEncounter.Combatants = map[string]*DndCombatant{}
+	}
+	return group.DndEncounter
+}
+
+// dndEncounterCombatants 在锁内复制当前群的单位信息，读人物卡、格式化文本等在锁外进行
+func dndEncounterCombatants(group *GroupInfo) map[string]*DndCombatant {
+	dndEncounterLock.Lock()
+	defer dndEncounterLock.Unlock()
+	ret := map[string]*DndCombatant{}
+	if group.DndEncounter != nil {
+		for k, v := range group.DndEncounter.Combatants {
+			ret[k] = v.clone()
+		}
+	}
+	return ret
+}
+
+// dndEncounterModify 在锁内修改当前群的遭遇并标记保存
+func dndEncounterModify(ctx *MsgContext, fn func(enc *DndEncounter)) {
+	dndEncounterLock.Lock()
+	fn(dndEncounterOf(ctx.Group))
+	dndEncounterLock.Unlock()
+	ctx.Group.MarkDirty(ctx.Dice)
+}
+
+// dndEncounterOnTurnStart 由 setInitNextRoundVars 调用：单位回合开始时结算其状态持续时间，并检查上一回合用时
+func dndEncounterOnTurnStart(ctx *MsgContext, started *RIListItem) string {
+	if ctx.Group == nil || started == nil {
+		return ""
+	}
+	var sb strings.Builder
+	now := time.Now().Unix()
+	dndEncounterModify(ctx, func(enc *DndEncounter) {
+		if enc.TurnTimer > 0 && enc.TurnStartedAt > 0 {
+			used := now - enc.TurnStartedAt
+			if used > enc.TurnTimer {
+				_, _ = fmt.Fprintf(&sb, "上一回合用时%s，超出时限%s\n",
+					time.Duration(used)*time.Second, time.Duration(enc.TurnTimer)*time.Second)
+			}
+		}
+		enc.TurnStartedAt = now
+		if c, ok := enc.Combatants[started.name]; ok {
+			if expired := c.TickConditions(); len(expired) > 0 {
+				_, _ = fmt.Fprintf(&sb, "%s的状态结束: %s\n", started.name, strings.Join(expired, "、"))
+			}
+		}
+	})
+	return strings.TrimSpace(sb.String())
+}
+
+// dndEncounterOnDamage 由 .st hp- 调用：检查受伤单位的专注
+func dndEncounterOnDamage(ctx *MsgContext, damage int64, newHp int64) string {
+	if ctx.Group == nil || ctx.Player == nil || damage <= 0 {
+		return ""
+	}
+	dndEncounterLock.Lock()
+	enc := ctx.Group.DndEncounter
+	var c *DndCombatant
+	if enc != nil {
+		c = enc.Combatants[ctx.Player.Name]
+		if c == nil {
+			for _, i := range enc.Combatants {
+				if i.UID != "" && i.UID == ctx.Player.UserID {
+					c = i
+					break
+				}
+			}
+		}
+	}
+	if c == nil || c.Concentration == "" {
+		dndEncounterLock.Unlock()
+		return ""
+	}
+	spell := c.Concentration
+	if newHp <= 0 {
+		c.Concentration = ""
+	}
+	dndEncounterLock.Unlock()
+
+	if newHp <= 0 {
+		ctx.Group.MarkDirty(ctx.Dice)
+	}
+	return dndConcentrationText(ctx, c.Name, spell, damage, newHp <= 0)
+}
+
+func dndConcentrationText(ctx *MsgContext, unit string, spell string, damage int64, broken bool) string {
+	VarSetValueStr(ctx, "$t专注单位", unit)
+	VarSetValueStr(ctx, "$t专注法术", spell)
+	if broken {
+		return DiceFormatTmpl(ctx, "DND:战斗_专注_中断")
+	}
+	VarSetValueInt64(ctx, "$t专注DC", dndConcentrationDC(damage))
+	return DiceFormatTmpl(ctx, "DND:战斗_专注_检定提示")
+}
+
+// dndEncounterClear 先攻列表清空时一并清理单位信息
+func dndEncounterClear(ctx *MsgContext) {
+	dndEncounterModify(ctx, func(enc *DndEncounter) {
+		enc.Combatants = map[string]*DndCombatant{}
+		enc.TurnStartedAt = 0
+	})
+}
+
+// dndEncounterSnapshot 将当前先攻列表与单位信息保存为存档
+func dndEncounterSnapshot(ctx *MsgContext, name string) (int, bool) {
+	lst := (RIList{}).LoadByCurGroup(ctx)
+	if len(lst) == 0 {
+		return 0, false
+	}
+	round, _ := VarGetValueInt64(ctx, "$g回合数")
+	curInitVal, _ := VarGetValueInt64(ctx, "$g当前回合先攻值")
+
+	ok := true
+	dndEncounterModify(ctx, func(enc *DndEncounter) {
+		enc.prune(lst)
+		if ctx.Group.DndSavedEncounters == nil {
+			ctx.Group.DndSavedEncounters = map[string]*DndEncounter{}
+		}
+		if _, exists := ctx.Group.DndSavedEncounters[name]; !exists && len(ctx.Group.DndSavedEncounters) >= dndEncounterMaxSaved {
+			ok = false
+			return
+		}
+		saved := &DndEncounter{
+			Combatants: map[string]*DndCombatant{},
+			TurnTimer:  enc.TurnTimer,
+			Round:      round,
+			CurInitVal: curInitVal,
+			SavedAt:    time.Now().Unix(),
+		}
+		for _, i := range lst {
+			saved.Units = append(saved.Units, &DndEncounterUnit{Name: i.name, Val: i.val, Detail: i.detail, UID: i.uid})
+		}
+		for k, v := range enc.Combatants {
+			saved.Combatants[k] = v.clone()
+		}
+		ctx.Group.DndSavedEncounters[name] = saved
+	})
+	return len(lst), ok
+}
+
+// dndEncounterRestore 读取存档，覆盖当前先攻列表
+func dndEncounterRestore(ctx *MsgContext, name string) (int, bool) {
+	var saved *DndEncounter
+	dndEncounterLock.Lock()
+	if ctx.Group.DndSavedEncounters != nil {
+		saved = ctx.Group.DndSavedEncounters[name]
+	}
+	dndEncounterLock.Unlock()
+	if saved == nil {
+		return 0, false
+	}
+
+	lst := RIList{}
+	for _, i := range saved.Units {
+		lst = append(lst, &RIListItem{name: i.Name, val: i.Val, detail: i.Detail, uid: i.UID})
+	}
+	sort.Sort(lst)
+	lst.SaveToGroup(ctx)
+	VarSetValueInt64(ctx, "$g回合数", saved.Round)
+	VarSetValueInt64(ctx, "$g当前回合先攻值", saved.CurInitVal)
+
+	dndEncounterModify(ctx, func(enc *DndEncounter) {
+		enc.Combatants = map[string]*DndCombatant{}
+		for k, v := range saved.Combatants {
+			enc.Combatants[k] = v.clone()
+		}
+		enc.TurnTimer = saved.TurnTimer
+		enc.TurnStartedAt = time.Now().Unix()
+	})
+	return len(lst), true
+}
+
+// dndCombatantStatusText 先攻列表中每个单位后附加的状态文本
+func dndCombatantStatusText(ctx *MsgContext, item *RIListItem, c *DndCombatant) string {
+	var parts []string
+	hpText := ""
+	if c != nil && c.HasHP {
+		if c.HPMax > 0 {
+			hpText = fmt.Sprintf("HP%d/%d", c.HP, c.HPMax)
+		} else {
+			hpText = fmt.Sprintf("HP%d", c.HP)
+		}
+	} else if item.uid != "" && ctx.Group != nil {
+		// 玩家单位读取人物卡
+		if attrs, err := ctx.Dice.AttrsManager.Load(ctx.Group.GroupID, item.uid); err == nil && attrs != nil {
+			if hp, ok := attrs.LoadX("hp"); ok && hp.TypeId == ds.VMTypeInt {
+				hpText = "HP" + hp.ToString()
+				if hpMax, ok2 := attrs.LoadX("hpmax"); ok2 && hpMax.TypeId == ds.VMTypeInt {
+					hpText += "/" + hpMax.ToString()
+				}
+			}
+		}
+	}
+	if hpText != "" {
+		parts = append(parts, hpText)
+	}
+	if c != nil {
+		if c.AC > 0 {
+			parts = append(parts, "AC"+strconv.FormatInt(c.AC, 10))
+		}
+		if cond := c.ConditionsText(); cond != "" {
+			parts = append(parts, cond)
+		}
+		if c.Concentration != "" {
+			parts = append(parts, "专注:"+c.Concentration)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(parts, " ") + "]"
+}
+
+const helpInitEncounter = ".init hp <单位> <数值/+x/-x> [上限] // 设置或修改NPC生命值\n" +
+	".init ac <单位> <数值> // 设置护甲等级\n" +
+	".init cond <单位> <状态> [轮数] // 添加状态，轮数在该单位回合开始时减少\n" +
+	".init uncond <单位> <状态> // 移除状态\n" +
+	".init conc <单位> [<法术>] // 设置专注，不写法术则结束专注\n" +
+	".init timer <秒> // 设置单回合时限，0为不限\n" +
+	".init save <遭遇名> // 保存当前遭遇\n" +
+	".init load <遭遇名> // 读取遭遇\n" +
+	".init saved // 查看已保存的遭遇\n" +
+	".init drop <遭遇名> // 删除保存的遭遇"
+
+// dndInitEncounterSolve 处理 .init 的遭遇相关子命令，返回 false 表示不是遭遇子命令
+func dndInitEncounterSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) bool {
+	sub := cmdArgs.GetArgN(1)
+	switch sub {
+	case "hp", "ac", "cond", "uncond", "conc":
+	case "timer", "save", "load", "saved", "drop":
+	default:
+		return false
+	}
+
+	reply := func(text string) {
+		ReplyToSender(ctx, msg, text)
+	}
+	// 单位相关的子命令需要先在先攻列表中
+	needUnit := func() (*RIListItem, bool) {
+		name := cmdArgs.GetArgN(2)
+		if name == "" {
+			reply("需要指定单位名称")
+			return nil, false
+		}
+		item := (RIList{}).LoadByCurGroup(ctx).GetExists(name)
+		if item == nil {
+			VarSetValueStr(ctx, "$t目标", name)
+			reply(DiceFormatTmpl(ctx, "DND:战斗_单位不存在"))
+			return nil, false
+		}
+		VarSetValueStr(ctx, "$t目标", name)
+		return item, true
+	}
+
+	switch sub {
+	case "hp":
+		item, ok := needUnit()
+		if !ok {
+			return true
+		}
+		expr := cmdArgs.GetArgN(3)
+		if expr == "" {
+			reply("错误的格式，应为: .init hp <单位> <数值/+x/-x> [上限]")
+			return true
+		}
+		op := ""
+		if strings.HasPrefix(expr, "+") || strings.HasPrefix(expr, "-") {
+			op = expr[:1]
+			expr = expr[1:]
+		}
+		r := ctx.Eval(expr, nil)
+		if r.vm.Error != nil || r.TypeId != ds.VMTypeInt {
+			reply("错误的格式，应为: .init hp <单位> <数值/+x/-x> [上限]")
+			return true
+		}
+		val := int64(r.MustReadInt())
+		var hpMax int64
+		if s := cmdArgs.GetArgN(4); s != "" {
+			hpMax, _ = strconv.ParseInt(s, 10, 64)
+		}
+		var concSpell string
+		var unit *DndCombatant
+		concBroken := false
+		dndEncounterModify(ctx, func(enc *DndEncounter) {
+			c := enc.get(item.name)
+			c.UID = item.uid
+			c.HasHP = true
+			if hpMax > 0 {
+				c.HPMax = hpMax
+			}
+			switch op {
+			case "-":
+				c.ApplyDamage(val)
+				concSpell = c.Concentration
+				if concSpell != "" && c.HP == 0 {
+					concBroken = true
+					c.Concentration = ""
+				}
+			case "+":
+				c.ApplyDamage(-val)
+			default:
+				c.HP = val
+				if c.HPMax > 0 && c.HP > c.HPMax {
+					c.HP = c.HPMax
+				}
+			}
+			unit = c.clone()
+		})
+		text := dndCombatantStatusText(ctx, item, unit)
+		VarSetValueStr(ctx, "$t单位状态", text)
+		out := DiceFormatTmpl(ctx, "DND:战斗_单位更新")
+		if op == "-" && concSpell != "" {
+			out += "\n" + dndConcentrationText(ctx, item.name, concSpell, val, concBroken)
+		}
+		reply(out)
+	case "ac":
+		item, ok := needUnit()
+		if !ok {
+			return true
+		}
+		ac, err := strconv.ParseInt(cmdArgs.GetArgN(3), 10, 64)
+		if err != nil {
+			reply("错误的格式，应为: .init ac <单位> <数值>")
+			return true
+		}
+		var unit *DndCombatant
+		dndEncounterModify(ctx, func(enc *DndEncounter) {
+			c := enc.get(item.name)
+			c.UID = item.uid
+			c.AC = ac
+			unit = c.clone()
+		})
+		text := dndCombatantStatusText(ctx, item, unit)
+		VarSetValueStr(ctx, "$t单位状态", text)
+		reply(DiceFormatTmpl(ctx, "DND:战斗_单位更新"))
+	case "cond":
+		item, ok := needUnit()
+		if !ok {
+			return true
+		}
+		cond := cmdArgs.GetArgN(3)
+		if cond == "" {
+			reply("错误的格式，应为: .init cond <单位> <状态> [轮数]")
+			return true
+		}
+		rounds, _ := strconv.Atoi(cmdArgs.GetArgN(4))
+		var unit *DndCombatant
+		dndEncounterModify(ctx, func(enc *DndEncounter) {
+			c := enc.get(item.name)
+			c.UID = item.uid
+			c.AddCondition(cond, rounds)
+			unit = c.clone()
+		})
+		text := dndCombatantStatusText(ctx, item, unit)
+		VarSetValueStr(ctx, "$t单位状态", text)
+		reply(DiceFormatTmpl(ctx, "DND:战斗_单位更新"))
+	case "uncond":
+		item, ok := needUnit()
+		if !ok {
+			return true
+		}
+		cond := cmdArgs.GetArgN(3)
+		removed := false
+		var unit *DndCombatant
+		dndEncounterModify(ctx, func(enc *DndEncounter) {
+			c := enc.get(item.name)
+			removed = c.RemoveCondition(cond)
+			unit = c.clone()
+		})
+		text := dndCombatantStatusText(ctx, item, unit)
+		if !removed {
+			reply(fmt.Sprintf("%s身上没有状态: %s", item.name, cond))
+			return true
+		}
+		VarSetValueStr(ctx, "$t单位状态", text)
+		reply(DiceFormatTmpl(ctx, "DND:战斗_单位更新"))
+	case "conc":
+		item, ok := needUnit()
+		if !ok {
+			return true
+		}
+		spell := strings.Join(cmdArgs.Args[2:], " ")
+		var unit *DndCombatant
+		dndEncounterModify(ctx, func(enc *DndEncounter) {
+			c := enc.get(item.name)
+			c.UID = item.uid
+			c.Concentration = spell
+			unit = c.clone()
+		})
+		text := dndCombatantStatusText(ctx, item, unit)
+		VarSetValueStr(ctx, "$t单位状态", text)
+		reply(DiceFormatTmpl(ctx, "DND:战斗_单位更新"))
+	case "timer":
+		seconds, err := strconv.ParseInt(cmdArgs.GetArgN(2), 10, 64)
+		if err != nil || seconds < 0 {
+			reply("错误的格式，应为: .init timer <秒>")
+			return true
+		}
+		dndEncounterModify(ctx, func(enc *DndEncounter) {
+			enc.TurnTimer = seconds
+			enc.TurnStartedAt = time.Now().Unix()
+		})
+		if seconds == 0 {
+			reply("已取消回合时限")
+		} else {
+			reply(fmt.Sprintf("已设置回合时限为%s", time.Duration(seconds)*time.Second))
+		}
+	case "save":
+		name := cmdArgs.GetArgN(2)
+		if name == "" {
+			reply("错误的格式，应为: .init save <遭遇名>")
+			return true
+		}
+		VarSetValueStr(ctx, "$t遭遇名", name)
+		n, ok := dndEncounterSnapshot(ctx, name)
+		switch {
+		case n == 0:
+			reply("先攻列表为空")
+		case !ok:
+			reply(fmt.Sprintf("保存的遭遇数量已达上限(%d)，请先删除一些", dndEncounterMaxSaved))
+		default:
+			VarSetValueInt64(ctx, "$t数量", int64(n))
+			reply(DiceFormatTmpl(ctx, "DND:战斗_遭遇_保存"))
+		}
+	case "load":
+		name := cmdArgs.GetArgN(2)
+		VarSetValueStr(ctx, "$t遭遇名", name)
+		n, ok := dndEncounterRestore(ctx, name)
+		if !ok {
+			reply(DiceFormatTmpl(ctx, "DND:战斗_遭遇_不存在"))
+			return true
+		}
+		VarSetValueInt64(ctx, "$t数量", int64(n))
+		reply(DiceFormatTmpl(ctx, "DND:战斗_遭遇_读取"))
+	case "saved":
+		dndEncounterLock.Lock()
+		var names []string
+		for k, v := range ctx.Group.DndSavedEncounters {
+			names = append(names, fmt.Sprintf("%s(%d个单位，%s)", k, len(v.Units), time.Unix(v.SavedAt, 0).Format("01-02 15:04")))
+		}
+		dndEncounterLock.Unlock()
+		if len(names) == 0 {
+			reply("当前群没有保存的遭遇")
+			return true
+		}
+		sort.Strings(names)
+		reply("已保存的遭遇:\n" + strings.Join(names, "\n"))
+	case "drop":
+		name := cmdArgs.GetArgN(2)
+		VarSetValueStr(ctx, "$t遭遇名", name)
+		dropped := false
+		dndEncounterLock.Lock()
+		if _, ok := ctx.Group.DndSavedEncounters[name]; ok {
+			delete(ctx.Group.DndSavedEncounters, name)
+			dropped = true
+		}
+		dndEncounterLock.Unlock()
+		if !dropped {
+			reply(DiceFormatTmpl(ctx, "DND:战斗_遭遇_不存在"))
+			return true
+		}
+		ctx.Group.MarkDirty(ctx.Dice)
+		reply(fmt.Sprintf("已删除遭遇: %s", name))
+	}
+	return true
+}
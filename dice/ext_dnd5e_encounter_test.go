@@ -0,0 +1,105 @@
+//nolint:testpackage
+package dice
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDndCombatantTickConditions(t *testing.T) {
+	c := &DndCombatant{Name: "地精"}
+	c.AddCondition("目盲", 2)
+	c.AddCondition("倒地", 0)
+	c.AddCondition("恐慌", 1)
+
+	expired := c.TickConditions()
+	if len(expired) != 1 || expired[0] != "恐慌" {
+		t.Fatalf("first tick expired = %v, want [恐慌]", expired)
+	}
+	if got := c.ConditionsText(); got != "目盲(1轮)、倒地" {
+		t.Fatalf("conditions text = %q", got)
+	}
+
+	expired = c.TickConditions()
+	if len(expired) != 1 || expired[0] != "目盲" {
+		t.Fatalf("second tick expired = %v, want [目盲]", expired)
+	}
+	if len(c.Conditions) != 1 || c.Conditions[0].Name != "倒地" {
+		t.Fatalf("indefinite condition should remain, got %+v", c.Conditions)
+	}
+
+	// 重复添加时刷新轮数
+	c.AddCondition("倒地", 3)
+	if len(c.Conditions) != 1 || c.Conditions[0].Rounds != 3 {
+		t.Fatalf("re-adding condition should refresh rounds, got %+v", c.Conditions)
+	}
+	if !c.RemoveCondition("倒地") || c.RemoveCondition("倒地") {
+		t.Fatal("remove condition should succeed exactly once")
+	}
+}
+
+func TestDndCombatantApplyDamage(t *testing.T) {
+	c := &DndCombatant{HP: 10, HPMax: 12, HasHP: true}
+	c.ApplyDamage(4)
+	if c.HP != 6 {
+		t.Fatalf("hp = %d, want 6", c.HP)
+	}
+	c.ApplyDamage(-20)
+	if c.HP != 12 {
+		t.Fatalf("healing should clamp to max, hp = %d", c.HP)
+	}
+	c.ApplyDamage(30)
+	if c.HP != 0 {
+		t.Fatalf("damage should clamp to 0, hp = %d", c.HP)
+	}
+}
+
+func TestDndConcentrationDC(t *testing.T) {
+	cases := map[int64]int64{1: 10, 19: 10, 21: 10, 22: 11, 40: 20}
+	for damage, want := range cases {
+		if got := dndConcentrationDC(damage); got != want {
+			t.Errorf("dndConcentrationDC(%d) = %d, want %d", damage, got, want)
+		}
+	}
+}
+
+func TestDndEncounterPrune(t *testing.T) {
+	enc := &DndEncounter{}
+	enc.get("甲").AC = 12
+	enc.get("乙").AC = 15
+	enc.prune(RIList{{name: "甲", val: 10}})
+	if _, ok := enc.Combatants["乙"]; ok {
+		t.Fatal("combatant not in initiative list should be pruned")
+	}
+	if enc.Combatants["甲"].AC != 12 {
+		t.Fatal("combatant in initiative list should be kept")
+	}
+}
+
+func TestDndEncounterPersistsWithGroupInfo(t *testing.T) {
+	group := &GroupInfo{GroupID: "QQ-Group:1"}
+	enc := dndEncounterOf(group)
+	c := enc.get("巨魔")
+	c.HP, c.HPMax, c.HasHP = 30, 84, true
+	c.Concentration = "祝福术"
+	c.AddCondition("束缚", 2)
+	group.DndSavedEncounters = map[string]*DndEncounter{
+		"boss": {Units: []*DndEncounterUnit{{Name: "巨魔", Val: 12}}, Round: 1},
+	}
+
+	data, err := json.Marshal(group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded GroupInfo
+	if err = json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	got := loaded.DndEncounter.Combatants["巨魔"]
+	if got == nil || got.HP != 30 || got.Concentration != "祝福术" || got.ConditionsText() != "束缚(2轮)" {
+		t.Fatalf("encounter not restored: %+v", got)
+	}
+	if saved := loaded.DndSavedEncounters["boss"]; saved == nil || len(saved.Units) != 1 || saved.Units[0].Val != 12 {
+		t.Fatalf("saved encounter not restored: %+v", saved)
+	}
+}
@@ -1,7 +1,6 @@
 package dice
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,7 +14,6 @@ import (
 	"time"
 
 	"github.com/golang-module/carbon"
-	"github.com/pilagod/gorm-cursor-paginator/v2/paginator"
 	ds "github.com/sealdice/dicescript"
 	"github.com/tidwall/gjson"
 	"go.uber.org/zap"
@@ -188,6 +186,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 .log list <群号> // 查看指定群的日志列表(无法取得日志时，找骰主做这个操作)
 .log masterget <群号> <日志名> // 重新上传日志，并获取链接(无法取得日志时，找骰主做这个操作)
 .log export <日志名> // 直接取得日志txt(服务出问题或有其他需要时使用)
+.log export <日志名> --format=html // 以指定格式导出，可选 txt/html/md/jsonl/fvtt
 .log export <日志名> <邮箱地址> // 通过邮件取得日志txt，多个邮箱用空格隔开`
 
 	// const txtLogTip = "若未出现线上日志地址，可换时间获取，或联系骰主在data/default/log-exports路径下取出日志\n文件名: 群号_日志名_随机数.zip\n注意此文件log end/get后才会生成"
@@ -365,7 +364,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 				if name == getGroupLogName(group) {
 					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "日志:记录_删除_失败_正在进行"))
 				} else {
-					err := service.LogDelete(ctx.Dice.DBOperator, group.GroupID, name)
+					err := ctx.Dice.LogDelete(group.GroupID, name)
 					if err == nil {
 						ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "日志:记录_删除_成功"))
 					} else if errors.Is(err, service.ErrLogNotFound) {
@@ -584,7 +583,11 @@ func RegisterBuiltinExtLog(self *Dice) {
 				VarSetValueStr(ctx, "$t日期", now.ToShortDateString())
 				VarSetValueStr(ctx, "$t时间", now.ToShortTimeString())
 				logFileNamePrefix := DiceFormatTmpl(ctx, "日志:记录_导出_文件名前缀")
-				logFile, notice, err := GetLogTxt(ctx, group.GroupID, logName, logFileNamePrefix)
+				format := storylog.ExportFormatDefault
+				if kw := cmdArgs.GetKwarg("format"); kw != nil && kw.Value != "" {
+					format = kw.Value
+				}
+				logFile, notice, err := GetLogExport(ctx, group.GroupID, logName, logFileNamePrefix, format)
 				if err != nil {
 					reply := err.Error()
 					if strings.Contains(reply, "此log不存在") || strings.Contains(reply, "名字是否正确") {
@@ -1157,87 +1160,17 @@ func LogEditByID(ctx *MsgContext, groupID, content string, messageID interface{}
 }
 
 func GetLogTxt(ctx *MsgContext, groupID string, logName string, fileNamePrefix string) (string, string, error) {
-	// 创建临时文件
-	tempPattern, notice := storylog.BuildTempPattern(fileNamePrefix)
-	tempLog, err := os.CreateTemp("", tempPattern)
-	if err != nil {
-		return "", notice, errors.New("log导出出现未知错误")
-	}
-	defer func() {
-		_ = tempLog.Close()
-		if err != nil {
-			_ = os.Remove(tempLog.Name()) //nolint:gosec
-		}
-	}()
-
-	counter := 0
-	currentCursor := paginator.Cursor{} // 初始游标为空
-
-	// 腾讯元宝: 创建带10秒超时的 context
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel() // 确保 context 被正确取消
-
-	// 腾讯元宝: 使用 channel 接收 goroutine 的结果
-	resultCh := make(chan error, 1)
-	go func() {
-		defer close(resultCh) // 确保 channel 被关闭
-
-		for {
-			select {
-			case <-ctxWithTimeout.Done(): // 检查是否超时或被取消
-				resultCh <- errors.New("日志导出超时（10秒限制），请尝试减少数据量或联系管理员")
-				return
-			default:
-				// 获取当前游标对应的数据
-				cursorLines, cursor, err := service.LogGetCursorLines(ctx.Dice.DBOperator, groupID, logName, currentCursor)
-				if err != nil {
-					resultCh <- err
-					return
-				}
-
-				// 写入当前批次的数据
-				for _, line := range cursorLines {
-					timeTxt := time.Unix(line.Time, 0).Format("2006-01-02 15:04:05")
-					text := fmt.Sprintf("%s(%v) %s\n%s\n\n", line.Nickname, line.IMUserID, timeTxt, line.Message)
-					if _, err = tempLog.WriteString(text); err != nil {
-						resultCh <- fmt.Errorf("写入日志导出临时文件失败: %w", err)
-						return
-					}
-					counter++
-				}
-				// ========== 新增：每批写入后强制同步 ==========
-				if err := tempLog.Sync(); err != nil { // 确保批次数据落盘
-					resultCh <- fmt.Errorf("批次同步失败: %w", err)
-					return
-				}
-
-				// 如果没有下一页，则成功完成
-				if cursor.After == nil {
-					resultCh <- nil
-					return
-				}
-
-				// 更新游标，继续获取下一页
-				currentCursor.After = cursor.After
-			}
-		}
-	}()
-
-	// 等待 goroutine 完成或超时
-	if err := <-resultCh; err != nil {
-		return "", notice, err
-	}
-	// 2. 确保文件指针回到开头
-	if _, err := tempLog.Seek(0, 0); err != nil {
-		return "", notice, fmt.Errorf("重置文件指针失败: %w", err)
-	}
-
-	// 如果没有任何数据，返回错误
-	if counter == 0 {
-		return "", notice, errors.New("此log不存在，或条目数为空，名字是否正确？")
-	}
+	return GetLogExport(ctx, groupID, logName, fileNamePrefix, storylog.ExportFormatDefault)
+}
 
-	return tempLog.Name(), notice, nil
+// GetLogExport 以指定格式导出日志到临时文件，格式见 storylog.LogExporterNames
+func GetLogExport(ctx *MsgContext, groupID string, logName string, fileNamePrefix string, format string) (string, string, error) {
+	return storylog.ExportLogToTempFile(storylog.ExportEnv{
+		Db:      ctx.Dice.DBOperator,
+		GroupID: groupID,
+		LogName: logName,
+		Format:  format,
+	}, fileNamePrefix)
 }
 
 func LogSendToBackend(ctx *MsgContext, groupID string, logName string) (bool, string, string, error) {
@@ -1364,6 +1297,31 @@ func LogRollBriefByPCV2(ctx *MsgContext, items []string, showAll bool, name stri
 					}
 				}
 				continue
+			case "chase":
+				ok2 := info.Get("items").IsArray()
+				if !ok2 {
+					continue
+				}
+				nickname := info.Get("pcName").String()
+				setupName(nickname)
+
+				for _, j := range info.Get("items").Array() {
+					// 危险检定可能带有难度要求，需达到难度才算成功
+					attr := getName(j.Get("expr2").String())
+					if cocCheckSuccess(int(j.Get("rank").Int()), int(j.Get("difficulty").Int())) {
+						pcInfo[nickname][attr+":成功"]++
+					} else {
+						pcInfo[nickname][attr+":失败"]++
+					}
+
+					if j.Get("type").String() == "speed" {
+						if pcInfo[nickname]["移动力:旧值"] == 0 {
+							pcInfo[nickname]["移动力:旧值"] = int(j.Get("movOld").Int())
+						}
+						pcInfo[nickname]["移动力:新值"] = int(j.Get("movNew").Int())
+					}
+				}
+				continue
 			case "sc":
 				ok2 := info.Get("items").IsArray()
 				if !ok2 {
@@ -58,9 +58,10 @@ func writeAttrsTemplateVersion(attrs *AttributesItem, version string) {
 
 // Commands wraps command-related configuration.
 type Commands struct {
-	Set SetConfig `yaml:"set"`
-	Sn  SnConfig  `yaml:"sn"`
-	St  StConfig  `yaml:"st"`
+	Set   SetConfig   `yaml:"set"`
+	Sn    SnConfig    `yaml:"sn"`
+	St    StConfig    `yaml:"st"`
+	Check CheckConfig `yaml:"check"`
 }
 
 // SetConfig configures the set command.
@@ -139,6 +140,7 @@ type GameSystemTemplate struct {
 
 	SetConfig    LegacySetConfig             `yaml:"-"`
 	NameTemplate map[string]NameTemplateItem `yaml:"-"`
+	CheckCmdMap  CmdMapCls                   `json:"-" yaml:"-"` // 由 Commands.Check 生成的检定指令
 
 	inited bool
 }
@@ -387,6 +389,8 @@ func (t *GameSystemTemplate) Init() {
 		}
 	}
 
+	t.CheckCmdMap = t.buildCheckCmdMap()
+
 	if t.Attrs.Defaults == nil {
 		t.Attrs.Defaults = map[string]int{}
 	}
@@ -0,0 +1,319 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrCheckNoTiers    = errors.New("检定指令没有定义成功等级")
+	ErrCheckNotNumeric = errors.New("表达式的结果不是数字")
+)
+
+// CheckConfig 模板声明的检定指令，键为指令名
+type CheckConfig map[string]CheckCommandConfig
+
+// CheckCommandConfig 描述一个检定指令。
+// 表达式中可以使用 $t出目、$t判定值、$t检定结果、$t修正数 等临时变量
+type CheckCommandConfig struct {
+	Aliases   []string              `yaml:"aliases"`
+	Help      string                `yaml:"help"`
+	Roll      string                `yaml:"roll"`      // 出目表达式，默认为 d100
+	Result    string                `yaml:"result"`    // 由出目和判定值计算检定结果，默认为出目本身
+	Modifiers []CheckModifierConfig `yaml:"modifiers"` // 写在参数开头的关键字，如优势、奖励骰
+	Tiers     []CheckTierConfig     `yaml:"tiers"`     // 成功等级，从好到坏排列，取第一个条件成立的
+	Critical  CheckCriticalConfig   `yaml:"critical"`
+	Text      string                `yaml:"text"` // 回复文本，默认为 其它:模板检定
+}
+
+// CheckModifierConfig 参数开头的关键字，后面可以跟一个数字，如 b2、dc15
+type CheckModifierConfig struct {
+	Keywords []string `yaml:"keywords"`
+	Roll     string   `yaml:"roll"`    // 替换出目表达式，其中的 {n} 会被替换为关键字后的数字
+	Var      string   `yaml:"var"`     // 保存关键字后数字的变量，默认为 $t修正数
+	Default  int64    `yaml:"default"` // 关键字后没有数字时使用的值，默认为1
+}
+
+// CheckTierConfig 一个成功等级
+type CheckTierConfig struct {
+	Name string `yaml:"name"`
+	Rank int    `yaml:"rank"` // 写入 $tSuccessRank，正数为成功，负数为失败
+	Cond string `yaml:"cond"` // 条件表达式，留空视为总是成立
+	Text string `yaml:"text"` // 结果文本，可以是文本模板的键（如 COC:判定_成功_普通）或直接的文本
+}
+
+// CheckCriticalConfig 大成功/大失败规则
+type CheckCriticalConfig struct {
+	Success string `yaml:"success"` // 大成功条件
+	Failure string `yaml:"failure"` // 大失败条件
+	// Mode 为 tier 时（默认）大成功/大失败直接取最好/最坏的等级，为 shift 时提升/降低一级
+	Mode string `yaml:"mode"`
+}
+
+// checkModifier 解析出的关键字
+type checkModifier struct {
+	config *CheckModifierConfig
+	value  int64
+}
+
+// buildCheckCmdMap 为 Commands.Check 中的每个指令生成 CmdItemInfo
+func (t *GameSystemTemplate) buildCheckCmdMap() CmdMapCls {
+	cmdMap := CmdMapCls{}
+	for name, cfg := range t.Commands.Check {
+		item := t.newCheckCmd(strings.ToLower(name), cfg)
+		cmdMap[item.Name] = item
+		for _, alias := range cfg.Aliases {
+			cmdMap[strings.ToLower(alias)] = item
+		}
+	}
+	return cmdMap
+}
+
+func (t *GameSystemTemplate) newCheckCmd(name string, cfg CheckCommandConfig) *CmdItemInfo {
+	help := cfg.Help
+	if help == "" {
+		help = fmt.Sprintf(".%s <属性表达式> [原因] // %s 检定", name, t.Name)
+	}
+	return &CmdItemInfo{
+		Name:          name,
+		ShortHelp:     help,
+		Help:          fmt.Sprintf("%s 检定:\n%s", t.Name, help),
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			switch cmdArgs.GetArgN(1) {
+			case "", "help":
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+
+			mctx := GetCtxProxyFirst(ctx, cmdArgs)
+			mctx.DelegateText = ctx.DelegateText
+			mctx.SystemTemplate = t
+			text, err := t.runCheck(mctx, &cfg, cmdArgs.CleanArgs)
+			if err != nil {
+				ReplyToSender(mctx, msg, "检定失败: "+err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			ReplyToSender(mctx, msg, text)
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+}
+
+// runCheck 执行一次检定并返回回复文本
+func (t *GameSystemTemplate) runCheck(ctx *MsgContext, cfg *CheckCommandConfig, restText string) (string, error) {
+	if len(cfg.Tiers) == 0 {
+		return "", ErrCheckNoTiers
+	}
+	ctx.CreateVmIfNotExists()
+	t.runInitScript(ctx)
+
+	mods, restText := parseCheckModifiers(cfg.Modifiers, restText)
+	rollExpr := cfg.Roll
+	if rollExpr == "" {
+		rollExpr = "d100"
+	}
+	VarSetValueInt64(ctx, "$t修正数", 0)
+	for _, mod := range mods {
+		varName := mod.config.Var
+		if varName == "" {
+			varName = "$t修正数"
+		}
+		VarSetValueInt64(ctx, varName, mod.value)
+		if mod.config.Roll != "" {
+			rollExpr = strings.ReplaceAll(mod.config.Roll, "{n}", strconv.FormatInt(mod.value, 10))
+		}
+	}
+
+	// 判定值
+	r := ctx.Eval(restText, nil)
+	if r.vm.Error != nil {
+		return "", fmt.Errorf("无法解析表达式 %s: %w", restText, r.vm.Error)
+	}
+	attrValue, ok := r.ReadInt()
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrCheckNotNumeric, restText)
+	}
+	reason := r.vm.RestInput
+	exprText := strings.TrimSpace(strings.TrimSuffix(restText, reason))
+	if reason == "" {
+		reason = restText
+	}
+	VarSetValueStr(ctx, "$t技能", LimitCommandReasonText(reason))
+	VarSetValueStr(ctx, "$t属性表达式文本", exprText)
+	VarSetValueInt64(ctx, "$t判定值", int64(attrValue))
+
+	// 出目
+	r = ctx.Eval(rollExpr, nil)
+	if r.vm.Error != nil {
+		return "", fmt.Errorf("无法解析出目表达式 %s: %w", rollExpr, r.vm.Error)
+	}
+	outcome, ok := r.ReadInt()
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrCheckNotNumeric, rollExpr)
+	}
+	detail := r.vm.GetDetailText()
+	if detail == "" || detail == strconv.FormatInt(int64(outcome), 10) {
+		detail = fmt.Sprintf("%d[%s]", outcome, rollExpr)
+	}
+	VarSetValueInt64(ctx, "$t出目", int64(outcome))
+	VarSetValueInt64(ctx, "$t骰子出目", int64(outcome))
+
+	result := int64(outcome)
+	if cfg.Result != "" {
+		v, err := evalCheckInt(ctx, cfg.Result)
+		if err != nil {
+			return "", err
+		}
+		result = v
+		detail = fmt.Sprintf("%s, %s", detail, cfg.Result)
+	}
+	VarSetValueInt64(ctx, "$t检定结果", result)
+	VarSetValueStr(ctx, "$t检定过程文本", detail)
+
+	idx, err := pickCheckTier(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	tier := cfg.Tiers[idx]
+	VarSetValueInt64(ctx, "$tSuccessRank", int64(tier.Rank))
+	VarSetValueStr(ctx, "$t成功等级", tier.Name)
+	VarSetValueStr(ctx, "$t判定结果", formatCheckText(ctx, tier.Text))
+
+	textKey := cfg.Text
+	if textKey == "" {
+		textKey = "其它:模板检定"
+	}
+	return formatCheckText(ctx, textKey), nil
+}
+
+// parseCheckModifiers 依次剥离参数开头的关键字和紧随的数字
+func parseCheckModifiers(configs []CheckModifierConfig, text string) ([]checkModifier, string) {
+	var mods []checkModifier
+	text = strings.TrimSpace(text)
+	for {
+		matched := false
+		for i := range configs {
+			cfg := &configs[i]
+			for _, kw := range cfg.Keywords {
+				if kw == "" || len(text) < len(kw) || !strings.EqualFold(text[:len(kw)], kw) {
+					continue
+				}
+				rest := text[len(kw):]
+				end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) })
+				if end == -1 {
+					end = len(rest)
+				}
+				value := cfg.Default
+				if value == 0 {
+					value = 1
+				}
+				if end > 0 {
+					value, _ = strconv.ParseInt(rest[:end], 10, 64)
+				}
+				mods = append(mods, checkModifier{config: cfg, value: value})
+				text = strings.TrimSpace(rest[end:])
+				matched = true
+				break
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			return mods, text
+		}
+	}
+}
+
+// pickCheckTier 返回成功等级的下标，并按大成功/大失败规则调整
+func pickCheckTier(ctx *MsgContext, cfg *CheckCommandConfig) (int, error) {
+	last := len(cfg.Tiers) - 1
+	idx := last
+	for i, tier := range cfg.Tiers {
+		ok, err := evalCheckCond(ctx, tier.Cond)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			idx = i
+			break
+		}
+	}
+
+	var critSuccess, critFailure bool
+	if cfg.Critical.Success != "" {
+		ok, err := evalCheckCond(ctx, cfg.Critical.Success)
+		if err != nil {
+			return 0, err
+		}
+		critSuccess = ok
+	}
+	if cfg.Critical.Failure != "" && !critSuccess {
+		ok, err := evalCheckCond(ctx, cfg.Critical.Failure)
+		if err != nil {
+			return 0, err
+		}
+		critFailure = ok
+	}
+	shift := cfg.Critical.Mode == "shift"
+	switch {
+	case critSuccess && shift:
+		idx = max(idx-1, 0)
+	case critSuccess:
+		idx = 0
+	case critFailure && shift:
+		idx = min(idx+1, last)
+	case critFailure:
+		idx = last
+	}
+	return idx, nil
+}
+
+// evalCheckCond 求值条件表达式，空表达式视为成立
+func evalCheckCond(ctx *MsgContext, expr string) (bool, error) {
+	if strings.TrimSpace(expr) == "" {
+		return true, nil
+	}
+	r := ctx.Eval(expr, nil)
+	if r.vm.Error != nil {
+		return false, fmt.Errorf("无法解析条件 %s: %w", expr, r.vm.Error)
+	}
+	return r.AsBool(), nil
+}
+
+func evalCheckInt(ctx *MsgContext, expr string) (int64, error) {
+	r := ctx.Eval(expr, nil)
+	if r.vm.Error != nil {
+		return 0, fmt.Errorf("无法解析表达式 %s: %w", expr, r.vm.Error)
+	}
+	v, ok := r.ReadInt()
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrCheckNotNumeric, expr)
+	}
+	return int64(v), nil
+}
+
+// formatCheckText 存在同名文本模板时使用模板，否则作为文本直接格式化
+func formatCheckText(ctx *MsgContext, text string) string {
+	if text == "" {
+		return ""
+	}
+	if _, exists := ctx.Dice.TextMap[text]; exists {
+		return DiceFormatTmpl(ctx, text)
+	}
+	return DiceFormat(ctx, text)
+}
+
+// checkCmdMap 返回群当前规则模板声明的检定指令
+func (group *GroupInfo) checkCmdMap(d *Dice) CmdMapCls {
+	if group == nil || d == nil || d.GameSystemMap == nil {
+		return nil
+	}
+	if tmpl := group.GetCharTemplate(d); tmpl != nil {
+		return tmpl.CheckCmdMap
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package dice //nolint:testpackage
+
+import (
+	"testing"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+const testCheckTemplate = `
+name: pf2e-test
+fullName: 检定指令测试
+templateVer: '2.0'
+attrs:
+  defaults:
+    运动: 5
+commands:
+  check:
+    pf:
+      aliases: [pfc]
+      roll: '20'
+      result: $t出目 + $t判定值
+      modifiers:
+        - keywords: [dc]
+          var: $t难度
+          default: 15
+      tiers:
+        - name: 大成功
+          cond: $t检定结果 >= $t难度 + 10
+        - name: 成功
+          rank: 1
+          cond: $t检定结果 >= $t难度
+        - name: 失败
+          rank: -1
+          cond: $t检定结果 > $t难度 - 10
+        - name: 大失败
+          rank: -2
+      critical:
+        success: $t出目 == 20
+        failure: $t出目 == 1
+        mode: shift
+      text: '{$t技能}:{$t检定结果}/{$t成功等级}'
+`
+
+func TestTemplateCheckCommand(t *testing.T) {
+	d, ep, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	tmpl, err := LoadGameSystemTemplateFromBytes([]byte(testCheckTemplate), "yaml")
+	if err != nil {
+		t.Fatalf("LoadGameSystemTemplateFromBytes() error = %v", err)
+	}
+	d.GameSystemTemplateAdd(tmpl)
+	if tmpl.CheckCmdMap["pf"] == nil || tmpl.CheckCmdMap["pfc"] != tmpl.CheckCmdMap["pf"] {
+		t.Fatalf("check command and its alias should be registered, got %v", tmpl.CheckCmdMap)
+	}
+
+	ctx := &MsgContext{
+		Dice:                d,
+		Session:             d.ImSession,
+		EndPoint:            ep,
+		IsCompatibilityTest: true,
+		Group:               &GroupInfo{GroupID: "QQ-Group:1", System: "pf2e-test"},
+		Player:              &GroupPlayerInfo{UserID: "QQ:2", Name: "Tester"},
+	}
+	ctx.SystemTemplate = ctx.Group.GetCharTemplate(d)
+	attrs := &AttributesItem{ID: ctx.Group.GroupID + "-" + ctx.Player.UserID, valueMap: &ds.ValueMap{}}
+	d.AttrsManager.m.Store(attrs.ID, attrs)
+	if _, ok := ctx.Group.checkCmdMap(d)["pfc"]; !ok {
+		t.Fatal("group using the template should see its check commands")
+	}
+
+	cfg := tmpl.Commands.Check["pf"]
+	cases := []struct {
+		args string
+		want string
+	}{
+		// 25 >= 15 + 10，大成功不能再提升
+		{"运动", "运动:25/大成功"},
+		// 25 > 30 - 10 为失败，出目20提升一级
+		{"dc30 运动", "运动:25/成功"},
+		// 25 <= 40 - 10 为大失败，出目20提升一级
+		{"dc40 运动", "运动:25/失败"},
+	}
+	for _, c := range cases {
+		got, err := tmpl.runCheck(ctx, &cfg, c.args)
+		if err != nil {
+			t.Fatalf("runCheck(%q) error = %v", c.args, err)
+		}
+		if got != c.want {
+			t.Fatalf("runCheck(%q) = %q, want %q", c.args, got, c.want)
+		}
+	}
+}
+
+func TestParseCheckModifiers(t *testing.T) {
+	configs := []CheckModifierConfig{
+		{Keywords: []string{"优势"}, Roll: "d20优势"},
+		{Keywords: []string{"b"}, Roll: "b{n}"},
+	}
+	mods, rest := parseCheckModifiers(configs, "b2 优势 力量 原因")
+	if len(mods) != 2 || rest != "力量 原因" {
+		t.Fatalf("parseCheckModifiers() = %v, %q", mods, rest)
+	}
+	if mods[0].value != 2 || mods[1].value != 1 {
+		t.Fatalf("unexpected modifier values: %d, %d", mods[0].value, mods[1].value)
+	}
+}
@@ -130,7 +130,7 @@ func TryReplyToSenderMergedForward(ctx *MsgContext, msg *Message, title string,
 		return false
 	}
 
-	if ctx.Dice.Config.EnableCensor && ctx.Dice.Config.CensorMode == OnlyOutputReply {
+	if ctx.Dice.CensorActive(msg, OnlyOutputReply) {
 		for i, content := range contents {
 			checkText := sealCodeRe.ReplaceAllString(content, "")
 			checkText = cqCodeRe.ReplaceAllString(checkText, "")
@@ -171,7 +171,7 @@ func TryReplyToSenderMergedForward(ctx *MsgContext, msg *Message, title string,
 	}
 
 	s, ok := ctx.EndPoint.Adapter.(forwardMsgSender)
-	if !ok {
+	if !ok || !ctx.EndPoint.Capabilities().ForwardMessage {
 		return false
 	}
 
@@ -416,7 +416,7 @@ func ReplyGroupRaw(ctx *MsgContext, msg *Message, text string, flag string) {
 	if d != nil {
 		d.Logger.Infof("发给(群%s): %s", msg.GroupID, text)
 		// 敏感词拦截：回复（群）
-		if d.Config.EnableCensor && d.Config.CensorMode == OnlyOutputReply {
+		if d.CensorActive(msg, OnlyOutputReply) {
 			// 先拿掉海豹码和CQ码再检查敏感词
 			checkText := sealCodeRe.ReplaceAllString(text, "")
 			checkText = cqCodeRe.ReplaceAllString(checkText, "")
@@ -488,7 +488,7 @@ func ReplyPersonRaw(ctx *MsgContext, msg *Message, text string, flag string) {
 	if d != nil {
 		d.Logger.Infof("发给(帐号%s): %s", msg.Sender.UserID, text)
 		// 敏感词拦截：回复（个人）
-		if d.Config.EnableCensor && d.Config.CensorMode == OnlyOutputReply {
+		if d.CensorActive(msg, OnlyOutputReply) {
 			// 先拿掉海豹码和CQ码再检查敏感词
 			checkText := sealCodeRe.ReplaceAllString(text, "")
 			checkText = cqCodeRe.ReplaceAllString(checkText, "")
@@ -593,12 +593,22 @@ func SendFileToGroupRaw(ctx *MsgContext, msg *Message, path string, flag string)
 	ctx.EndPoint.Adapter.SendFileToGroup(ctx, msg.GroupID, path, flag)
 }
 
-func MemberBan(ctx *MsgContext, groupID string, userID string, duration int64) {
+// MemberBan 禁言群成员，平台不支持时返回 false
+func MemberBan(ctx *MsgContext, groupID string, userID string, duration int64) bool {
+	if !ctx.EndPoint.Capabilities().MemberBan {
+		return false
+	}
 	ctx.EndPoint.Adapter.MemberBan(groupID, userID, duration)
+	return true
 }
 
-func MemberKick(ctx *MsgContext, groupID string, userID string) {
+// MemberKick 踢出群成员，平台不支持时返回 false
+func MemberKick(ctx *MsgContext, groupID string, userID string) bool {
+	if !ctx.EndPoint.Capabilities().MemberKick {
+		return false
+	}
 	ctx.EndPoint.Adapter.MemberKick(groupID, userID)
+	return true
 }
 
 type ByLength []string
@@ -690,6 +700,7 @@ func spamCheckPerson(ctx *MsgContext, msg *Message) bool {
 		ctx.Player.RateLimitWarned = false
 		return false
 	}
+	metricRateLimitTriggers.With("person").Inc()
 
 	// Check if user is already banned to avoid sending multiple warnings in concurrent scenarios
 	if banItem, exists := ctx.Dice.Config.BanList.GetByID(ctx.Player.UserID); exists && banItem.Rank == BanRankBanned {
@@ -746,6 +757,7 @@ func spamCheckGroup(ctx *MsgContext, msg *Message) bool {
 		ctx.Group.RateLimitWarned = false
 		return false
 	}
+	metricRateLimitTriggers.With("group").Inc()
 
 	// Check if group is already banned to avoid sending multiple warnings in concurrent scenarios
 	if banItem, exists := ctx.Dice.Config.BanList.GetByID(ctx.Group.GroupID); exists && banItem.Rank == BanRankBanned {
@@ -11,6 +11,7 @@ import (
 	"regexp"
 	"runtime/debug"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -25,7 +26,6 @@ import (
 	"sealdice-core/model"
 	"sealdice-core/utils/dboperator/engine"
 
-	"github.com/dop251/goja"
 	"github.com/golang-module/carbon"
 	ds "github.com/sealdice/dicescript"
 	rand2 "golang.org/x/exp/rand" //nolint:staticcheck // against my better judgment, but this was mandated due to a strongly held opinion from you know who
@@ -143,6 +143,13 @@ type GroupInfo struct {
 	PlayerGroups      *SyncMap[string, []string] `json:"playerGroups"      yaml:"playerGroups"` // 给team指令使用，和玩家、群等信息一样，都来自Players，不会重复存储
 	ExtAppliedVersion int64                      `json:"extAppliedVersion" yaml:"extAppliedVersion"`
 
+	DndEncounter       *DndEncounter              `json:"dndEncounter,omitempty"       yaml:"dndEncounter,omitempty"`       // 当前战斗遭遇，配合先攻列表使用
+	DndSavedEncounters map[string]*DndEncounter   `json:"dndSavedEncounters,omitempty" yaml:"dndSavedEncounters,omitempty"` // 已保存的战斗遭遇
+	CocChase           *CocChase                  `json:"cocChase,omitempty"           yaml:"cocChase,omitempty"`           // 当前追逐
+	CocCombat          *CocCombat                 `json:"cocCombat,omitempty"          yaml:"cocCombat,omitempty"`          // 当前战斗轮顺序
+	CensorProfile      *GroupCensorProfile        `json:"censorProfile,omitempty"      yaml:"censorProfile,omitempty"`      // 群内拦截策略，为空时使用全局配置
+	DeckStates         map[string]*GroupDeckState `json:"deckStates,omitempty"         yaml:"deckStates,omitempty"`         // 群内的实体牌组，抽出的牌在重新洗牌前不会放回
+
 	/* Wrapper 架构 */
 	ExtAppliedTime int64 `json:"-" yaml:"-"` // 群组应用扩展的时间戳，运行时使用，不序列化（强制每次启动重新初始化）
 }
@@ -271,6 +278,13 @@ type groupInfoAlias GroupInfo
 type groupInfoJSON struct {
 	*groupInfoAlias
 	ActivatedExtList []*ExtInfo `json:"activatedExtList"`
+
+	// 以下字段覆盖 groupInfoAlias 中的同名字段，填入各功能在自己的锁内复制的快照
+	DndEncounter       *DndEncounter              `json:"dndEncounter,omitempty"`
+	DndSavedEncounters map[string]*DndEncounter   `json:"dndSavedEncounters,omitempty"`
+	CocChase           *CocChase                  `json:"cocChase,omitempty"`
+	CocCombat          *CocCombat                 `json:"cocCombat,omitempty"`
+	DeckStates         map[string]*GroupDeckState `json:"deckStates,omitempty"`
 }
 
 // groupInfoDecodeJSON 仅用于反序列化：activatedExtList 为私有字段，解码时自动跳过该键，
@@ -298,9 +312,17 @@ func (g *GroupInfo) MarshalJSON() ([]byte, error) {
 	}
 	g.extInitMu.Unlock()
 
+	// 遭遇、追逐与实体牌组由指令在各自的锁内修改，这里只在各自的锁内复制一份再序列化
+	encounter, savedEncounters := dndEncounterCopy(g)
+	chase, combat := cocChaseCopy(g)
 	return json.Marshal(&groupInfoJSON{
-		groupInfoAlias:   (*groupInfoAlias)(g),
-		ActivatedExtList: filteredList,
+		groupInfoAlias:     (*groupInfoAlias)(g),
+		ActivatedExtList:   filteredList,
+		DndEncounter:       encounter,
+		DndSavedEncounters: savedEncounters,
+		CocChase:           chase,
+		CocCombat:          combat,
+		DeckStates:         groupDeckCopy(g),
 	})
 }
 
@@ -498,6 +520,23 @@ type EndPointInfo struct {
 	Adapter PlatformAdapter `json:"adapter" yaml:"adapter"`
 }
 
+// Capabilities 返回当前账号所用适配器支持的功能
+func (ep *EndPointInfo) Capabilities() *PlatformCapabilities {
+	if ep.Adapter == nil {
+		return &PlatformCapabilities{}
+	}
+	caps := ep.Adapter.Capabilities()
+	return &caps
+}
+
+func (ep *EndPointInfo) MarshalJSON() ([]byte, error) {
+	type endPointInfo EndPointInfo
+	return json.Marshal(struct {
+		*endPointInfo
+		Capabilities *PlatformCapabilities `json:"capabilities"`
+	}{(*endPointInfo)(ep), ep.Capabilities()})
+}
+
 func (ep *EndPointInfo) UnmarshalYAML(value *yaml.Node) error {
 	if ep.Adapter != nil {
 		return value.Decode(ep)
@@ -861,7 +900,8 @@ type MsgContext struct {
 	Group       *GroupInfo       `jsbind:"group"`  // 当前群信息
 	Player      *GroupPlayerInfo `jsbind:"player"` // 当前群的玩家数据
 
-	IsCompatibilityTest bool // 是否为兼容性测试环境，用于跳过不必要的数据库查询
+	IsCompatibilityTest bool            // 是否为兼容性测试环境，用于跳过不必要的数据库查询
+	AttrsOverride       *AttributesItem // 指定当前角色卡，设置后不再按群和用户查找，用于网页编辑等场景
 
 	EndPoint        *EndPointInfo `jsbind:"endPoint"` // 对应的Endpoint
 	Session         *IMSession    // 对应的IMSession
@@ -917,7 +957,7 @@ func (ctx *MsgContext) fillPrivilege(msg *Message) int {
 	}
 
 	// 加入黑名单相关权限
-	if val, exists := ctx.Dice.Config.BanList.GetByID(ctx.Player.UserID); exists {
+	if val, exists := ctx.Dice.Config.BanList.GetByIDLinked(ctx.Player.UserID); exists {
 		switch val.Rank {
 		case BanRankBanned:
 			ctx.PrivilegeLevel = -30
@@ -941,6 +981,7 @@ func (ctx *MsgContext) fillPrivilege(msg *Message) int {
 
 func (s *IMSession) Execute(ep *EndPointInfo, msg *Message, runInSync bool) {
 	d := s.Parent
+	metricMessagesReceived.With(ep.ID, ep.Platform).Inc()
 
 	mctx := &MsgContext{}
 	mctx.Dice = d
@@ -1070,6 +1111,9 @@ func (s *IMSession) Execute(ep *EndPointInfo, msg *Message, runInSync bool) {
 						cmdLst = append(cmdLst, k)
 					}
 				}
+				for k := range g.checkCmdMap(d) {
+					cmdLst = append(cmdLst, k)
+				}
 			}
 			sort.Sort(ByLength(cmdLst))
 		}
@@ -1130,7 +1174,7 @@ func (s *IMSession) Execute(ep *EndPointInfo, msg *Message, runInSync bool) {
 		}
 
 		// 敏感词拦截：全部输入
-		if mctx.IsCurGroupBotOn && d.Config.EnableCensor && d.Config.CensorMode == AllInput {
+		if mctx.IsCurGroupBotOn && d.CensorActive(msg, AllInput) {
 			hit, words, needToTerminate, _ := d.CensorMsg(mctx, msg, msg.Message, "")
 			if needToTerminate {
 				return
@@ -1180,7 +1224,7 @@ func (s *IMSession) Execute(ep *EndPointInfo, msg *Message, runInSync bool) {
 				}()
 
 				// 敏感词拦截：命令输入
-				if (msg.MessageType == "private" || mctx.IsCurGroupBotOn) && d.Config.EnableCensor && d.Config.CensorMode == OnlyInputCommand {
+				if (msg.MessageType == "private" || mctx.IsCurGroupBotOn) && d.CensorActive(msg, OnlyInputCommand) {
 					hit, words, needToTerminate, _ := d.CensorMsg(mctx, msg, msg.Message, "")
 					if needToTerminate {
 						return
@@ -1267,25 +1311,14 @@ func (s *IMSession) Execute(ep *EndPointInfo, msg *Message, runInSync bool) {
 						if i.OnNotCommandReceived != nil {
 							notCommandReceiveCall := func() {
 								if i.IsJsExt {
-									// 先判断运行环境
-									loop, err := d.ExtLoopManager.GetLoop(i.JSLoopVersion)
-									if err != nil {
-										// 打个DEBUG日志？
-										mctx.Dice.Logger.Errorf("扩展<%s>运行环境已经过期: %v", i.Name, err)
-										return
-									}
-									waitRun := make(chan int, 1)
-									loop.RunOnLoop(func(runtime *goja.Runtime) {
-										defer func() {
-											if r := recover(); r != nil {
-												mctx.Dice.Logger.Errorf("扩展<%s>处理非指令消息异常: %v 堆栈: %v", i.Name, r, string(debug.Stack()))
-											}
-											waitRun <- 1
-										}()
-
+									err := d.jsCallHook(i.JSLoopVersion, i.Name, func() {
 										i.OnNotCommandReceived(mctx, msg)
 									})
-									<-waitRun
+									if errors.Is(err, ErrJsRuntimeExpired) {
+										mctx.Dice.Logger.Errorf("扩展<%s>%v", i.Name, err)
+									} else if err != nil {
+										mctx.Dice.Logger.Errorf("扩展<%s>处理非指令消息异常: %v", i.Name, err)
+									}
 								} else {
 									i.OnNotCommandReceived(mctx, msg)
 								}
@@ -1310,6 +1343,7 @@ func (s *IMSession) Execute(ep *EndPointInfo, msg *Message, runInSync bool) {
 // 这个 ExcuteNew 方法优化了对消息段的解析，其他平台应当尽快实现消息段解析并使用这个方法
 func (s *IMSession) ExecuteNew(ep *EndPointInfo, msg *Message) {
 	d := s.Parent
+	metricMessagesReceived.With(ep.ID, ep.Platform).Inc()
 
 	mctx := &MsgContext{}
 	mctx.Dice = d
@@ -1500,7 +1534,7 @@ func (s *IMSession) ExecuteNew(ep *EndPointInfo, msg *Message) {
 	}
 
 	// 敏感词拦截：全部输入
-	if mctx.IsCurGroupBotOn && d.Config.EnableCensor && d.Config.CensorMode == AllInput {
+	if mctx.IsCurGroupBotOn && d.CensorActive(msg, AllInput) {
 		hit, words, needToTerminate, _ := d.CensorMsg(mctx, msg, msg.Message, "")
 		if needToTerminate {
 			return
@@ -1558,23 +1592,14 @@ func (s *IMSession) ExecuteNew(ep *EndPointInfo, msg *Message) {
 					if i.OnNotCommandReceived != nil {
 						notCommandReceiveCall := func() {
 							if i.IsJsExt {
-								loop, err := d.ExtLoopManager.GetLoop(i.JSLoopVersion)
-								if err != nil {
-									// 打个DEBUG日志？
-									i.dice.Logger.Errorf("扩展<%s>运行环境已经过期: %v", i.Name, err)
-									return
-								}
-								waitRun := make(chan int, 1)
-								loop.RunOnLoop(func(runtime *goja.Runtime) {
-									defer func() {
-										if r := recover(); r != nil {
-											mctx.Dice.Logger.Errorf("扩展<%s>处理非指令消息异常: %v 堆栈: %v", i.Name, r, string(debug.Stack()))
-										}
-										waitRun <- 1
-									}()
+								err := d.jsCallHook(i.JSLoopVersion, i.Name, func() {
 									i.OnNotCommandReceived(mctx, msg)
 								})
-								<-waitRun
+								if errors.Is(err, ErrJsRuntimeExpired) {
+									mctx.Dice.Logger.Errorf("扩展<%s>%v", i.Name, err)
+								} else if err != nil {
+									mctx.Dice.Logger.Errorf("扩展<%s>处理非指令消息异常: %v", i.Name, err)
+								}
 							} else {
 								i.OnNotCommandReceived(mctx, msg)
 							}
@@ -1601,7 +1626,7 @@ func (s *IMSession) PreTriggerCommand(mctx *MsgContext, msg *Message, cmdArgs *C
 	}()
 
 	// 敏感词拦截：命令输入
-	if (msg.MessageType == "private" || mctx.IsCurGroupBotOn) && d.Config.EnableCensor && d.Config.CensorMode == OnlyInputCommand {
+	if (msg.MessageType == "private" || mctx.IsCurGroupBotOn) && d.CensorActive(msg, OnlyInputCommand) {
 		hit, words, needToTerminate, _ := d.CensorMsg(mctx, msg, msg.Message, "")
 		if needToTerminate {
 			return
@@ -2056,7 +2081,7 @@ func isBlacklistedHelpMasterRequest(cmdArgs *CmdArgs) bool {
 func handleBlacklistedUserQuitIfAdmin(ctx *MsgContext, msg *Message, isWhiteGroup bool, now time.Time, banQuitGroup func()) bool {
 	d := ctx.Dice
 	log := d.Logger
-	banListInfoItem, _ := d.Config.BanList.GetByID(msg.Sender.UserID)
+	banListInfoItem, _ := d.Config.BanList.GetByIDLinked(msg.Sender.UserID)
 	reasontext := FormatBlacklistReasons(banListInfoItem)
 	groupID := msg.GroupID
 
@@ -2123,6 +2148,9 @@ func handleBlacklistedUser(ctx *MsgContext, msg *Message, isWhiteGroup bool, now
 		if tryHandleBlacklistedHelpMasterRequest(ctx, msg, now) {
 			return true
 		}
+		if tryHandleBlacklistedAppealRequest(ctx, msg, now) {
+			return true
+		}
 		log.Infof("忽略黑名单用户信息: 来自群(%s)内<%s>(%s): %s", msg.GroupID, msg.Sender.Nickname, msg.Sender.UserID, msg.Message)
 		return true
 	}
@@ -2150,7 +2178,7 @@ func checkBan(ctx *MsgContext, msg *Message) (notReply bool) {
 	}
 
 	banQuitGroup := func() {
-		banListInfoItem, _ := ctx.Dice.Config.BanList.GetByID(msg.Sender.UserID)
+		banListInfoItem, _ := ctx.Dice.Config.BanList.GetByIDLinked(msg.Sender.UserID)
 		reasontext := FormatBlacklistReasons(banListInfoItem)
 		groupID := msg.GroupID
 		noticeMsg := fmt.Sprintf("检测到群(%s)内黑名单用户<%s>(%s)，自动退群\n%s", groupID, msg.Sender.Nickname, msg.Sender.UserID, reasontext)
@@ -2285,27 +2313,34 @@ func (s *IMSession) commandSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs
 		}
 
 		var ret CmdExecuteResult
+		extName := "builtin"
+		if ext != nil {
+			extName = ext.Name
+		}
+		start := time.Now()
+		defer func() {
+			metricCommandDuration.With(item.Name, extName).ObserveSince(start)
+			metricCommandExecutions.With(item.Name, extName, strconv.FormatBool(ret.Solved)).Inc()
+		}()
 		// 如果是js命令，那么加锁
 		if item.IsJsSolveFunc {
-			loop, err := s.Parent.ExtLoopManager.GetLoop(item.JSLoopVersion)
-			if err != nil {
-				// 打个DEBUG日志？
-				s.Parent.Logger.Errorf("扩展注册的指令<%s>运行环境已经过期: %v", item.Name, err)
+			jsExtName := ""
+			if ext != nil {
+				jsExtName = ext.Name
+			}
+			// 超时返回后脚本仍可能在 loop 上写入结果，出错时不能再读取它
+			var jsRet CmdExecuteResult
+			err := s.Parent.jsCallHook(item.JSLoopVersion, jsExtName, func() {
+				jsRet = item.Solve(ctx, msg, cmdArgs)
+			})
+			if errors.Is(err, ErrJsRuntimeExpired) {
+				s.Parent.Logger.Errorf("扩展注册的指令<%s>%v", item.Name, err)
 				return false
+			} else if err != nil {
+				ReplyToSender(ctx, msg, fmt.Sprintf("JS执行异常，请反馈给该扩展的作者：\n%v", err))
+			} else {
+				ret = jsRet
 			}
-			waitRun := make(chan int, 1)
-			loop.RunOnLoop(func(vm *goja.Runtime) {
-				defer func() {
-					if r := recover(); r != nil {
-						// log.Errorf("异常: %v 堆栈: %v", r, string(debug.Stack()))
-						ReplyToSender(ctx, msg, fmt.Sprintf("JS执行异常，请反馈给该扩展的作者：\n%v", r))
-					}
-					waitRun <- 1
-				}()
-
-				ret = item.Solve(ctx, msg, cmdArgs)
-			})
-			<-waitRun
 		} else {
 			ret = item.Solve(ctx, msg, cmdArgs)
 		}
@@ -2342,6 +2377,12 @@ func (s *IMSession) commandSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs
 		}
 
 		if group != nil && (group.Active || ctx.IsCurGroupBotOn) {
+			// 规则模板声明的检定指令优先于扩展指令
+			if tmpl := ctx.SystemTemplate; tmpl != nil {
+				if tryItemSolve(nil, tmpl.CheckCmdMap[cmdArgs.Command]) {
+					return true
+				}
+			}
 			for _, wrapper := range commandExtensionOrder(group, ctx.Dice) {
 				cmdMap := wrapper.GetCmdMap()
 				item := cmdMap[cmdArgs.Command]
@@ -2404,6 +2445,9 @@ func (s *IMSession) OnMessageDeleted(mctx *MsgContext, msg *Message) {
 }
 
 func (s *IMSession) OnMessageSend(ctx *MsgContext, msg *Message, flag string) {
+	if ctx.EndPoint != nil {
+		metricMessagesSent.With(ctx.EndPoint.ID, ctx.EndPoint.Platform).Inc()
+	}
 	for _, i := range s.Parent.ExtList {
 		i.CallOnMessageSend(ctx.Dice, ctx, msg, flag)
 	}
@@ -0,0 +1,358 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/eventloop"
+)
+
+var (
+	ErrJsRuntimeExpired = errors.New("运行环境已经过期")
+	ErrJsHookTimeout    = errors.New("JS执行超时")
+)
+
+const (
+	// jsInterruptGrace 中断后等待脚本退出的时间，原生调用无法被中断，不能无限等待
+	jsInterruptGrace = time.Second
+	// jsSharedRuntimeName 非隔离模式下所有脚本共用的运行时名称
+	jsSharedRuntimeName = "shared"
+)
+
+// jsRuntime 隔离模式下单个脚本或扩展包独占的运行时
+type jsRuntime struct {
+	name    string
+	loop    *eventloop.EventLoop
+	vm      *goja.Runtime
+	crashed string // 被终止的原因，非空时不再调度
+}
+
+// JsRuntimeStatus 隔离运行时的状态
+type JsRuntimeStatus struct {
+	Name    string `json:"name"`
+	Version int64  `json:"version"`
+	Crashed string `json:"crashed"`
+}
+
+// JsHookStats 单个扩展的调用统计，耗时单位为毫秒
+type JsHookStats struct {
+	Runtime   string  `json:"runtime"`
+	Calls     int64   `json:"calls"`
+	Errors    int64   `json:"errors"`
+	Timeouts  int64   `json:"timeouts"`
+	TotalTime float64 `json:"totalTime"`
+	MaxTime   float64 `json:"maxTime"`
+	LastError string  `json:"lastError"`
+}
+
+// addRuntime 登记隔离运行时并分配版本号
+func (m *JsLoopManager) addRuntime(rt *jsRuntime) int64 {
+	m.loopLock.Lock()
+	defer m.loopLock.Unlock()
+	if m.runtimes == nil {
+		m.runtimes = map[int64]*jsRuntime{}
+	}
+	m.counter++
+	m.runtimes[m.counter] = rt
+	return m.counter
+}
+
+// runtime 返回隔离运行时，主运行时返回 nil
+func (m *JsLoopManager) runtime(version int64) *jsRuntime {
+	m.loopLock.RLock()
+	defer m.loopLock.RUnlock()
+	return m.runtimes[version]
+}
+
+func (m *JsLoopManager) runtimeByName(name string) (int64, bool) {
+	m.loopLock.RLock()
+	defer m.loopLock.RUnlock()
+	for version, rt := range m.runtimes {
+		if rt.name == name {
+			return version, true
+		}
+	}
+	return 0, false
+}
+
+// stopRuntime 终止隔离运行时，之后通过该版本号获取 loop 会失败
+func (m *JsLoopManager) stopRuntime(version int64, reason string) {
+	m.loopLock.Lock()
+	rt := m.runtimes[version]
+	if rt == nil || rt.crashed != "" {
+		m.loopLock.Unlock()
+		return
+	}
+	rt.crashed = reason
+	m.loopLock.Unlock()
+	rt.loop.Terminate()
+}
+
+// RuntimeStatus 列出当前的隔离运行时
+func (m *JsLoopManager) RuntimeStatus() []JsRuntimeStatus {
+	m.loopLock.RLock()
+	defer m.loopLock.RUnlock()
+	ret := make([]JsRuntimeStatus, 0, len(m.runtimes))
+	for version, rt := range m.runtimes {
+		ret = append(ret, JsRuntimeStatus{Name: rt.name, Version: version, Crashed: rt.crashed})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Version < ret[j].Version })
+	return ret
+}
+
+// HookStats 返回各扩展的调用统计
+func (m *JsLoopManager) HookStats() map[string]JsHookStats {
+	m.statsLock.Lock()
+	defer m.statsLock.Unlock()
+	ret := make(map[string]JsHookStats, len(m.stats))
+	for name, s := range m.stats {
+		ret[name] = *s
+	}
+	return ret
+}
+
+func (m *JsLoopManager) resetStats() {
+	m.statsLock.Lock()
+	defer m.statsLock.Unlock()
+	m.stats = map[string]*JsHookStats{}
+}
+
+func (m *JsLoopManager) record(extName, runtimeName string, elapsed time.Duration, err error) {
+	m.statsLock.Lock()
+	defer m.statsLock.Unlock()
+	if m.stats == nil {
+		m.stats = map[string]*JsHookStats{}
+	}
+	s := m.stats[extName]
+	if s == nil {
+		s = &JsHookStats{}
+		m.stats[extName] = s
+	}
+	ms := float64(elapsed.Microseconds()) / 1000
+	s.Runtime = runtimeName
+	s.Calls++
+	s.TotalTime += ms
+	if ms > s.MaxTime {
+		s.MaxTime = ms
+	}
+	if err != nil {
+		s.Errors++
+		s.LastError = err.Error()
+		if errors.Is(err, ErrJsHookTimeout) {
+			s.Timeouts++
+		}
+	}
+}
+
+// jsTimeLimit 返回 rt 中单次调用的时间上限。扩展包的运行时总是独立的，不论是否开启隔离模式都受限制
+func (d *Dice) jsTimeLimit(rt *jsRuntime) time.Duration {
+	if rt == nil || (!d.Config.JsIsolation && !strings.HasPrefix(rt.name, jsPackageRuntimePrefix)) {
+		return 0
+	}
+	return time.Duration(max(d.Config.JsHookTimeLimit, 0)) * time.Millisecond
+}
+
+// jsCallHook 在扩展所在的事件循环上同步执行 f 并记录耗时。
+// 独立运行时中的执行受时间限制，超时的运行时会被终止，其中的扩展随之停用
+func (d *Dice) jsCallHook(version int64, extName string, f func()) error {
+	m := d.ExtLoopManager
+	loop, err := m.GetLoop(version)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrJsRuntimeExpired, err)
+	}
+	rt := m.runtime(version)
+	runtimeName := jsSharedRuntimeName
+	if rt != nil {
+		runtimeName = rt.name
+	}
+	if extName == "" {
+		extName = runtimeName
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	scheduled := loop.RunOnLoop(func(vm *goja.Runtime) {
+		var callErr error
+		defer func() {
+			if r := recover(); r != nil {
+				callErr = jsRecoveredError(r)
+			}
+			done <- callErr
+		}()
+		f()
+	})
+	if !scheduled {
+		return fmt.Errorf("%w: %s", ErrJsRuntimeExpired, runtimeName)
+	}
+	err = d.jsWait(version, rt, done)
+	if errors.Is(err, ErrJsHookTimeout) {
+		d.jsDisableRuntime(version, err)
+	}
+	m.record(extName, runtimeName, time.Since(start), err)
+	metricJsHookDuration.With(extName, runtimeName).ObserveSince(start)
+	return err
+}
+
+// jsWait 等待 done 返回，独立运行时超时时将其中断并终止，调用方负责停用其中的扩展。
+// goja 无法统计单个运行时占用的内存，因此只限制时间
+func (d *Dice) jsWait(version int64, rt *jsRuntime, done <-chan error) error {
+	timeLimit := d.jsTimeLimit(rt)
+	if timeLimit <= 0 {
+		return <-done
+	}
+	timer := time.NewTimer(timeLimit)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return d.jsAbortRuntime(version, rt, fmt.Errorf("%w（%v）", ErrJsHookTimeout, timeLimit), done)
+	}
+}
+
+// jsAbortRuntime 中断正在执行的脚本，随后终止运行时
+func (d *Dice) jsAbortRuntime(version int64, rt *jsRuntime, reason error, done <-chan error) error {
+	if rt.vm != nil {
+		rt.vm.Interrupt(reason)
+	}
+	select {
+	case <-done:
+	case <-time.After(jsInterruptGrace):
+	}
+	d.ExtLoopManager.stopRuntime(version, reason.Error())
+	return reason
+}
+
+// jsDisableRuntime 终止隔离运行时并停用其中注册的扩展，其它扩展不受影响
+func (d *Dice) jsDisableRuntime(version int64, reason error) {
+	d.jsScriptLock.Lock()
+	defer d.jsScriptLock.Unlock()
+	d.jsDisableRuntimeLocked(version, reason)
+}
+
+// jsDisableRuntimeLocked 同 jsDisableRuntime，调用方需持有 jsScriptLock
+func (d *Dice) jsDisableRuntimeLocked(version int64, reason error) {
+	rt := d.ExtLoopManager.runtime(version)
+	if rt == nil {
+		return
+	}
+	d.ExtLoopManager.stopRuntime(version, reason.Error())
+	// 其它包不能再请求或推送到已终止的运行时
+	if d.PackageManager != nil {
+		d.PackageManager.IPC().RemoveLoop(rt.loop)
+	}
+
+	if d.JsExtRegistry != nil {
+		var names []string
+		d.JsExtRegistry.Range(func(name string, ext *ExtInfo) bool {
+			if ext != nil && ext.JSLoopVersion == version {
+				names = append(names, name)
+			}
+			return true
+		})
+		for _, name := range names {
+			if ext, ok := d.JsExtRegistry.Load(name); ok && ext.Storage != nil {
+				_ = ext.StorageClose()
+			}
+			d.JsExtRegistry.Delete(name)
+		}
+	}
+	for _, jsInfo := range d.JsScriptList {
+		if jsInfo.loopVersion == version {
+			jsInfo.Enable = false
+			jsInfo.ErrText = reason.Error()
+		}
+	}
+	d.ExtUpdateTime = time.Now().Unix()
+	d.Logger.Errorf("JS运行环境<%s>已终止: %v，其中的扩展已停用", rt.name, reason)
+}
+
+// jsIsolatedRuntime 返回名为 name 的隔离运行时的版本号，不存在时创建
+func (d *Dice) jsIsolatedRuntime(name string) int64 {
+	if version, ok := d.ExtLoopManager.runtimeByName(name); ok {
+		return version
+	}
+	loop, reg := d.jsNewLoop()
+	rt := &jsRuntime{name: name, loop: loop}
+	version := d.ExtLoopManager.addRuntime(rt)
+	loop.Run(func(vm *goja.Runtime) {
+		rt.vm = vm
+		d.jsInitRuntime(vm, loop, reg, version)
+		if strings.HasPrefix(name, jsPackageRuntimePrefix) {
+			d.jsEnablePackageSandbox(vm, loop, reg)
+		}
+	})
+	d.jsStartLoop(loop)
+	return version
+}
+
+// jsAssignRuntimes 分配运行时：同一扩展包的脚本共用，有依赖关系的脚本共用。
+// 含扩展包的运行时总是独立的，名为 jsPackageRuntimePrefix 加包ID，其中没有全局 fetch 和 WebSocket；
+// 其余脚本在 isolateAll 为 false 时留在共享运行时
+func jsAssignRuntimes(scripts []*JsScriptInfo, isolateAll bool) {
+	parent := map[string]string{}
+	var find func(string) string
+	find = func(k string) string {
+		p, ok := parent[k]
+		if !ok || p == k {
+			return k
+		}
+		root := find(p)
+		parent[k] = root
+		return root
+	}
+	union := func(a, b string) {
+		ra, rb := find(a), find(b)
+		if ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	for _, jsInfo := range scripts {
+		key := "script:" + jsInfo.Author + ":" + jsInfo.Name
+		if jsInfo.PackageID != "" {
+			union(jsPackageRuntimePrefix+jsInfo.PackageID, key)
+		}
+		for _, dep := range jsInfo.Depends {
+			union("script:"+dep.Author+":"+dep.Name, key)
+		}
+	}
+	// 以最先出现的包命名含扩展包的运行时
+	names := map[string]string{}
+	for _, jsInfo := range scripts {
+		root := find("script:" + jsInfo.Author + ":" + jsInfo.Name)
+		if _, ok := names[root]; !ok && jsInfo.PackageID != "" {
+			names[root] = jsPackageRuntimePrefix + jsInfo.PackageID
+		}
+	}
+	for _, jsInfo := range scripts {
+		root := find("script:" + jsInfo.Author + ":" + jsInfo.Name)
+		switch name, ok := names[root]; {
+		case ok:
+			jsInfo.runtimeName = name
+		case isolateAll:
+			jsInfo.runtimeName = root
+		default:
+			jsInfo.runtimeName = ""
+		}
+	}
+}
+
+func jsRecoveredError(r any) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
+
+// jsStartLoop 在后台运行事件循环。
+// Start 在返回前就把 loop 标记为运行中，之后的 Terminate 一定能让它退出；
+// 若在新协程里调用 StartInForeground，先于它执行的 Terminate 不起作用，loop 会一直运行下去。
+// 任务中的 panic 由 loop 自己恢复并记录
+func (d *Dice) jsStartLoop(loop *eventloop.EventLoop) {
+	loop.Start()
+}
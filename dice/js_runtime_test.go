@@ -0,0 +1,166 @@
+package dice //nolint:testpackage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dop251/goja_nodejs/eventloop"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func writeTestScript(t *testing.T, name, body string) {
+	t.Helper()
+	dir := filepath.Join("scripts")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	src := "// ==UserScript==\n// @name " + name + "\n// @author tester\n// @version 1.0.0\n// ==/UserScript==\n" + body
+	if err := os.WriteFile(filepath.Join(dir, name+".js"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJsIsolatedRuntimes(t *testing.T) {
+	d, _ := newScriptReloadTestPackageManager(t)
+	d.ExtRegistry = new(SyncMap[string, *ExtInfo])
+	d.Config.JsIsolation = true
+	d.Config.JsHookTimeLimit = 1000
+
+	ext := func(name, hook string) string {
+		return `const ext = seal.ext.new("` + name + `", "tester", "1.0.0");
+ext.onNotCommandReceived = ` + hook + `;
+seal.ext.register(ext);
+`
+	}
+	writeTestScript(t, "good", ext("good", "() => { globalThis.hits = (globalThis.hits || 0) + 1; }"))
+	writeTestScript(t, "bad", ext("bad", "() => { while (true) {} }"))
+	writeTestScript(t, "hang", "while (true) {}\n")
+
+	d.JsReload()
+
+	good, _ := d.JsExtRegistry.Load("good")
+	bad, _ := d.JsExtRegistry.Load("bad")
+	if good == nil || bad == nil {
+		t.Fatal("extensions should be registered")
+	}
+	if good.JSLoopVersion == bad.JSLoopVersion {
+		t.Fatal("each script should run in its own runtime")
+	}
+	for _, jsInfo := range d.JsScriptList {
+		if jsInfo.Name == "hang" && (jsInfo.Enable || jsInfo.ErrText == "") {
+			t.Fatalf("script hanging on load should be stopped, got %+v", jsInfo)
+		}
+	}
+
+	call := func(e *ExtInfo) error {
+		return d.jsCallHook(e.JSLoopVersion, e.Name, func() { e.OnNotCommandReceived(nil, nil) })
+	}
+	if err := call(bad); !errors.Is(err, ErrJsHookTimeout) {
+		t.Fatalf("runaway hook should time out, got %v", err)
+	}
+	if _, ok := d.JsExtRegistry.Load("bad"); ok {
+		t.Fatal("extension of the crashed runtime should be disabled")
+	}
+	if err := call(bad); !errors.Is(err, ErrJsRuntimeExpired) {
+		t.Fatalf("crashed runtime should not be scheduled again, got %v", err)
+	}
+	if err := call(good); err != nil {
+		t.Fatalf("other extensions should keep working, got %v", err)
+	}
+
+	stats := d.ExtLoopManager.HookStats()
+	if stats["good"].Calls != 1 || stats["good"].Errors != 0 {
+		t.Fatalf("unexpected stats for good: %+v", stats["good"])
+	}
+	if stats["bad"].Timeouts != 1 {
+		t.Fatalf("unexpected stats for bad: %+v", stats["bad"])
+	}
+	crashed := 0
+	for _, rt := range d.ExtLoopManager.RuntimeStatus() {
+		if rt.Crashed != "" {
+			crashed++
+		}
+	}
+	if crashed != 2 {
+		t.Fatalf("bad and hang runtimes should be stopped, got %+v", d.ExtLoopManager.RuntimeStatus())
+	}
+}
+
+func TestJsPackageRuntimeTimeLimit(t *testing.T) {
+	d, pm := newScriptReloadTestPackageManager(t)
+	d.ExtRegistry = new(SyncMap[string, *ExtInfo])
+	d.Config.JsHookTimeLimit = 200
+	if err := pm.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	archive := createTestSealPack(t, "", "slow/pack", "1.0.0", map[string][]string{
+		"scripts": {"scripts/*.js"},
+	}, map[string]string{
+		"scripts/main.js": "// ==UserScript==\n// @name slow\n// ==/UserScript==\n" +
+			`const ext = seal.ext.new("slow", "tester", "1.0.0");
+ext.onNotCommandReceived = () => { while (true) {} };
+seal.ext.register(ext);
+`,
+	})
+	if err := pm.Install(archive); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if _, err := pm.Enable("slow/pack"); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	d.JsReload()
+
+	slow, _ := d.JsExtRegistry.Load("slow")
+	if slow == nil {
+		t.Fatal("package extension should be registered")
+	}
+	// 未开启隔离模式时，扩展包的运行时同样受时间限制
+	err := d.jsCallHook(slow.JSLoopVersion, slow.Name, func() { slow.OnNotCommandReceived(nil, nil) })
+	if !errors.Is(err, ErrJsHookTimeout) {
+		t.Fatalf("runaway package hook should time out, got %v", err)
+	}
+	if _, ok := d.JsExtRegistry.Load("slow"); ok {
+		t.Fatal("extension of the stopped package runtime should be disabled")
+	}
+}
+
+func TestJsStartLoopTerminatedImmediately(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	d := &Dice{Logger: zap.NewNop().Sugar()}
+	loop := eventloop.NewEventLoop()
+	d.jsStartLoop(loop)
+	// 先于事件循环协程执行的 Terminate 也要让它退出
+	loop.Terminate()
+}
+
+func TestJsAssignRuntimes(t *testing.T) {
+	scripts := []*JsScriptInfo{
+		{Author: "a", Name: "lib"},
+		{Author: "a", Name: "app", Depends: []JsScriptDepends{{Author: "a", Name: "lib"}}},
+		{Author: "b", Name: "one", PackageID: "b/pack"},
+		{Author: "b", Name: "two", PackageID: "b/pack"},
+		{Author: "c", Name: "alone"},
+	}
+	jsAssignRuntimes(scripts, true)
+	if scripts[0].runtimeName != scripts[1].runtimeName {
+		t.Fatal("dependent scripts should share a runtime")
+	}
+	if scripts[2].runtimeName != scripts[3].runtimeName {
+		t.Fatal("scripts of one package should share a runtime")
+	}
+	if scripts[4].runtimeName == scripts[0].runtimeName || scripts[4].runtimeName == scripts[2].runtimeName {
+		t.Fatal("unrelated scripts should be isolated")
+	}
+	if scripts[2].runtimeName != jsPackageRuntimePrefix+"b/pack" {
+		t.Fatalf("package runtime name = %q", scripts[2].runtimeName)
+	}
+
+	// 不隔离时只有扩展包使用独立的运行时
+	jsAssignRuntimes(scripts, false)
+	if scripts[0].runtimeName != "" || scripts[4].runtimeName != "" || scripts[2].runtimeName != jsPackageRuntimePrefix+"b/pack" {
+		t.Fatalf("runtimes without isolation = %q, %q, %q", scripts[0].runtimeName, scripts[2].runtimeName, scripts[4].runtimeName)
+	}
+}
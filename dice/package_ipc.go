@@ -0,0 +1,342 @@
+package dice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/eventloop"
+
+	"sealdice-core/logger"
+)
+
+var (
+	ErrIPCNoHandler   = errors.New("目标扩展包没有注册该方法")
+	ErrIPCTimeout     = errors.New("扩展包间请求超时")
+	ErrIPCPackageOnly = errors.New("seal.ipc 仅可在扩展包脚本中使用")
+	ErrIPCStopped     = errors.New("目标扩展包的运行环境已终止")
+	ErrIPCUnencodable = errors.New("扩展包间传递的数据必须可以序列化为 JSON")
+)
+
+const defaultIPCRequestTimeout = 10 * time.Second
+
+// ipcReceiver 注册到 IPC 的 JS 回调，总是在注册时所在的事件循环上调用
+type ipcReceiver struct {
+	id    uint64
+	pkgID string
+	loop  *eventloop.EventLoop
+	fn    goja.Callable
+	// allow 按接收方自己的清单检查能否与对方包通信
+	allow func(pkgID string) error
+}
+
+// PackageIPC 扩展包之间的消息通道，请求按包ID和方法名路由，话题消息按发布者包ID和话题名路由
+type PackageIPC struct {
+	lock        sync.RWMutex
+	nextID      uint64
+	handlers    map[string]map[string]*ipcReceiver // 包ID -> 方法名 -> 处理函数
+	subscribers map[string][]*ipcReceiver          // 发布者包ID + 话题 -> 订阅者
+}
+
+func NewPackageIPC() *PackageIPC {
+	return &PackageIPC{
+		handlers:    map[string]map[string]*ipcReceiver{},
+		subscribers: map[string][]*ipcReceiver{},
+	}
+}
+
+func ipcTopicKey(pkgID, topic string) string {
+	return pkgID + "\x00" + topic
+}
+
+// Reset 清除所有处理函数和订阅，JS 环境重建时调用
+func (p *PackageIPC) Reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.handlers = map[string]map[string]*ipcReceiver{}
+	p.subscribers = map[string][]*ipcReceiver{}
+}
+
+// Handle 注册包的请求处理函数，同名方法会被覆盖
+func (p *PackageIPC) Handle(method string, r *ipcReceiver) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	methods := p.handlers[r.pkgID]
+	if methods == nil {
+		methods = map[string]*ipcReceiver{}
+		p.handlers[r.pkgID] = methods
+	}
+	methods[method] = r
+}
+
+// RemoveLoop 移除注册在 loop 上的处理函数和订阅，隔离运行时被终止时调用
+func (p *PackageIPC) RemoveLoop(loop *eventloop.EventLoop) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for pkgID, methods := range p.handlers {
+		for method, r := range methods {
+			if r.loop == loop {
+				delete(methods, method)
+			}
+		}
+		if len(methods) == 0 {
+			delete(p.handlers, pkgID)
+		}
+	}
+	for key, subs := range p.subscribers {
+		kept := subs[:0:0]
+		for _, sub := range subs {
+			if sub.loop != loop {
+				kept = append(kept, sub)
+			}
+		}
+		if len(kept) == 0 {
+			delete(p.subscribers, key)
+		} else {
+			p.subscribers[key] = kept
+		}
+	}
+}
+
+func (p *PackageIPC) handler(pkgID, method string) *ipcReceiver {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.handlers[pkgID][method]
+}
+
+// Subscribe 订阅 publisher 发布的话题，返回取消订阅的函数
+func (p *PackageIPC) Subscribe(publisher, topic string, r *ipcReceiver) func() {
+	key := ipcTopicKey(publisher, topic)
+	p.lock.Lock()
+	p.nextID++
+	r.id = p.nextID
+	p.subscribers[key] = append(p.subscribers[key], r)
+	p.lock.Unlock()
+
+	return func() {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		subs := p.subscribers[key]
+		for i, sub := range subs {
+			if sub.id == r.id {
+				p.subscribers[key] = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Publish 将话题消息投递给双方清单都允许通信的订阅者，allow 按发布者的清单检查，返回收到消息的订阅者数量
+func (p *PackageIPC) Publish(publisher string, allow func(pkgID string) error, topic string, data ipcPayload) int {
+	p.lock.RLock()
+	subs := append([]*ipcReceiver(nil), p.subscribers[ipcTopicKey(publisher, topic)]...)
+	p.lock.RUnlock()
+
+	delivered := 0
+	for _, sub := range subs {
+		if allow(sub.pkgID) != nil || sub.allow(publisher) != nil {
+			continue
+		}
+		scheduled := sub.loop.RunOnLoop(func(vm *goja.Runtime) {
+			meta := map[string]any{"from": publisher, "topic": topic}
+			if _, err := sub.fn(goja.Undefined(), data.value(vm), vm.ToValue(meta)); err != nil {
+				logger.M().Warnf("扩展包 %s 处理 %s 的话题 %s 出错: %v", sub.pkgID, publisher, topic, err)
+			}
+		})
+		if scheduled {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// Request 向 target 包发送请求，reply 会在处理完成或失败后调用恰好一次，通常发生在处理函数所在的事件循环上。
+// 发起方的权限由调用方检查，这里按目标包的清单检查 from
+func (p *PackageIPC) Request(from, target, method string, data ipcPayload, reply func(result ipcPayload, err error)) {
+	h := p.handler(target, method)
+	if h == nil {
+		reply(nil, fmt.Errorf("%w: %s.%s", ErrIPCNoHandler, target, method))
+		return
+	}
+	if err := h.allow(from); err != nil {
+		reply(nil, err)
+		return
+	}
+	scheduled := h.loop.RunOnLoop(func(vm *goja.Runtime) {
+		fail := func(reason goja.Value) {
+			reply(nil, fmt.Errorf("扩展包 %s 处理请求 %s 出错: %s", target, method, reason.String()))
+		}
+		ret, err := h.fn(goja.Undefined(), data.value(vm), vm.ToValue(from))
+		if err != nil {
+			var jsErr *goja.Exception
+			if errors.As(err, &jsErr) {
+				fail(jsErr.Value())
+			} else {
+				fail(vm.ToValue(err.Error()))
+			}
+			return
+		}
+
+		done := func(v goja.Value) {
+			result, err := encodeIPCValue(v)
+			if err != nil {
+				fail(vm.ToValue(err.Error()))
+				return
+			}
+			reply(result, nil)
+		}
+
+		// 处理函数返回 Promise 时等待其完成
+		if obj, ok := ret.(*goja.Object); ok {
+			if then, ok := goja.AssertFunction(obj.Get("then")); ok {
+				_, err = then(obj, vm.ToValue(done), vm.ToValue(fail))
+				if err != nil {
+					fail(vm.ToValue(err.Error()))
+				}
+				return
+			}
+		}
+		done(ret)
+	})
+	if !scheduled {
+		reply(nil, fmt.Errorf("%w: %s", ErrIPCStopped, target))
+	}
+}
+
+// ipcPayload 在运行时之间传递的数据，以 JSON 保存，每个接收方各自解码出一份，互不共享
+type ipcPayload []byte
+
+// encodeIPCValue 在发送方的事件循环上把 JS 值编码为 JSON，函数等无法序列化的值会报错
+func encodeIPCValue(v goja.Value) (ipcPayload, error) {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil, nil
+	}
+	data, err := json.Marshal(v.Export())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIPCUnencodable, err)
+	}
+	return data, nil
+}
+
+// value 在接收方的运行时上解码出新的副本
+func (p ipcPayload) value(vm *goja.Runtime) goja.Value {
+	if p == nil {
+		return goja.Null()
+	}
+	var v any
+	if err := json.Unmarshal(p, &v); err != nil {
+		// 只会解码 encodeIPCValue 的结果，不应出错
+		return goja.Null()
+	}
+	return vm.ToValue(v)
+}
+
+// IPC 返回扩展包之间的消息通道
+func (pm *PackageManager) IPC() *PackageIPC {
+	return pm.ipc
+}
+
+// ipcObject 构造绑定到当前包的 seal.ipc
+func (s *jsPackageSandbox) ipcObject() *goja.Object {
+	obj := s.vm.NewObject()
+	_ = obj.Set("handle", s.ipcHandle)
+	_ = obj.Set("request", s.ipcRequest)
+	_ = obj.Set("publish", s.ipcPublish)
+	_ = obj.Set("subscribe", s.ipcSubscribe)
+	return obj
+}
+
+// checkIPC 按本包的清单检查能否与 target 通信，与自己通信总是允许
+func (s *jsPackageSandbox) checkIPC(target string) error {
+	if target == s.sandbox.PackageID {
+		return nil
+	}
+	return s.check(s.sandbox.CheckIPCPermission(target))
+}
+
+// ipcHandle 注册请求处理函数 handle(method, (data, from) => result)，返回值可以是 Promise
+func (s *jsPackageSandbox) ipcHandle(method string, fn goja.Callable) {
+	s.d.PackageManager.IPC().Handle(method, s.receiver(fn))
+}
+
+func (s *jsPackageSandbox) receiver(fn goja.Callable) *ipcReceiver {
+	return &ipcReceiver{pkgID: s.sandbox.PackageID, loop: s.loop, fn: fn, allow: s.checkIPC}
+}
+
+// ipcRequest 向其他包发起请求 request(target, method, data, timeoutMs?)，返回 Promise
+func (s *jsPackageSandbox) ipcRequest(call goja.FunctionCall) goja.Value {
+	vm := s.vm
+	target := call.Argument(0).String()
+	method := call.Argument(1).String()
+	timeout := defaultIPCRequestTimeout
+	if ms := call.Argument(3).ToInteger(); ms > 0 {
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	promise, resolve, reject := vm.NewPromise()
+	if err := s.checkIPC(target); err != nil {
+		_ = reject(vm.NewGoError(err))
+		return vm.ToValue(promise)
+	}
+	data, err := encodeIPCValue(call.Argument(2))
+	if err != nil {
+		_ = reject(vm.NewGoError(err))
+		return vm.ToValue(promise)
+	}
+
+	// settled 只在本包的事件循环上读写
+	settled := false
+	settle := func(result ipcPayload, err error) {
+		s.loop.RunOnLoop(func(vm *goja.Runtime) {
+			if settled {
+				return
+			}
+			settled = true
+			if err != nil {
+				_ = reject(vm.NewGoError(err))
+			} else {
+				_ = resolve(result.value(vm))
+			}
+		})
+	}
+	timer := time.AfterFunc(timeout, func() {
+		settle(nil, fmt.Errorf("%w: %s.%s", ErrIPCTimeout, target, method))
+	})
+	s.d.PackageManager.IPC().Request(s.sandbox.PackageID, target, method, data, func(result ipcPayload, err error) {
+		timer.Stop()
+		settle(result, err)
+	})
+	return vm.ToValue(promise)
+}
+
+// ipcPublish 发布本包的话题消息 publish(topic, data)，返回收到消息的订阅者数量
+func (s *jsPackageSandbox) ipcPublish(topic string, data goja.Value) (int, error) {
+	payload, err := encodeIPCValue(data)
+	if err != nil {
+		return 0, err
+	}
+	return s.d.PackageManager.IPC().Publish(s.sandbox.PackageID, s.checkIPC, topic, payload), nil
+}
+
+// ipcSubscribe 订阅其他包的话题 subscribe(publisher, topic, (data, meta) => {})，返回取消订阅的函数
+func (s *jsPackageSandbox) ipcSubscribe(publisher string, topic string, fn goja.Callable) (func(), error) {
+	if err := s.checkIPC(publisher); err != nil {
+		return nil, err
+	}
+	return s.d.PackageManager.IPC().Subscribe(publisher, topic, s.receiver(fn)), nil
+}
+
+// jsIPCUnavailable 普通脚本没有包ID，无法参与扩展包间通信
+func jsIPCUnavailable(vm *goja.Runtime) *goja.Object {
+	obj := vm.NewObject()
+	unavailable := func(goja.FunctionCall) goja.Value {
+		panic(vm.NewGoError(ErrIPCPackageOnly))
+	}
+	for _, name := range []string{"handle", "request", "publish", "subscribe"} {
+		_ = obj.Set(name, unavailable)
+	}
+	return obj
+}
@@ -0,0 +1,203 @@
+package dice //nolint:testpackage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/eventloop"
+)
+
+func TestPackageIPC(t *testing.T) {
+	testDice, pm := newScriptReloadTestPackageManager(t)
+	if err := pm.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	install := func(pkgID, script string, opts ...manifestOption) {
+		archive := createTestSealPack(t, "", pkgID, "1.0.0", map[string][]string{
+			"scripts": {"scripts/*.js"},
+		}, map[string]string{
+			"scripts/main.js": "// ==UserScript==\n// @name " + pkgID + "\n// ==/UserScript==\n" + script,
+		}, opts...)
+		if err := pm.Install(archive); err != nil {
+			t.Fatalf("Install(%s) error = %v", pkgID, err)
+		}
+		if _, err := pm.Enable(pkgID); err != nil {
+			t.Fatalf("Enable(%s) error = %v", pkgID, err)
+		}
+	}
+
+	install("alice/server", `
+seal.ipc.handle("add", (d, from) => d.a + d.b + ":" + from);
+seal.ipc.handle("slow", () => new Promise(() => {}));
+seal.ipc.handle("fail", () => { throw new Error("boom"); });
+seal.ipc.handle("announce", (d) => seal.ipc.publish("news", d));
+`, func(b *strings.Builder) {
+		b.WriteString("\n[permissions]\nipc = [\"bob/client\"]\n")
+	})
+	install("bob/client", `
+const r = globalThis.__ipcResult = {};
+seal.ipc.subscribe("alice/server", "news", (d, meta) => { r.news = d + "@" + meta.from; });
+try { seal.ipc.subscribe("carol/other", "news", () => {}); } catch (e) { r.subDenied = String(e); }
+setTimeout(() => {
+  seal.ipc.request("alice/server", "add", { a: 1, b: 2 }).then((v) => { r.add = String(v); });
+  seal.ipc.request("alice/server", "slow", null, 50).catch((e) => { r.timeout = String(e); });
+  seal.ipc.request("alice/server", "fail").catch((e) => { r.fail = String(e); });
+  seal.ipc.request("alice/server", "missing").catch((e) => { r.missing = String(e); });
+  seal.ipc.request("carol/other", "add").catch((e) => { r.denied = String(e); });
+  seal.ipc.request("alice/server", "announce", "hello");
+}, 50);
+`, func(b *strings.Builder) {
+		b.WriteString("\n[permissions]\nipc = [\"alice/server\"]\n")
+	})
+
+	// dave 的清单允许访问 alice，但 alice 的清单没有允许 dave
+	install("dave/spy", `
+const r = globalThis.__ipcSpy = {};
+seal.ipc.subscribe("alice/server", "news", (d) => { r.news = d; });
+setTimeout(() => {
+  seal.ipc.request("alice/server", "add", { a: 1, b: 1 }).then((v) => { r.add = String(v); }, (e) => { r.denied = String(e); });
+}, 50);
+`, func(b *strings.Builder) {
+		b.WriteString("\n[permissions]\nipc = [\"alice/server\"]\n")
+	})
+
+	testDice.JsReload()
+
+	result := map[string]string{}
+	spy := map[string]string{}
+	var plain string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		runOnPackageRuntime(t, testDice, "bob/client", func(vm *goja.Runtime) {
+			if v := vm.Get("__ipcResult"); v != nil && !goja.IsUndefined(v) {
+				_ = vm.ExportTo(v, &result)
+			}
+		})
+		runOnPackageRuntime(t, testDice, "dave/spy", func(vm *goja.Runtime) {
+			if v := vm.Get("__ipcSpy"); v != nil && !goja.IsUndefined(v) {
+				_ = vm.ExportTo(v, &spy)
+			}
+		})
+		done := make(chan struct{})
+		testDice.ExtLoopManager.GetWebLoop().RunOnLoop(func(vm *goja.Runtime) {
+			defer close(done)
+			v, _ := vm.RunString(`try { seal.ipc.publish("x", 1); "" } catch (e) { String(e) }`)
+			plain = v.String()
+		})
+		<-done
+		if len(result) >= 7 && spy["denied"] != "" {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if result["add"] != "3:bob/client" {
+		t.Fatalf("request should reach the handler with the caller id, got %#v", result)
+	}
+	if result["news"] != "hello@alice/server" {
+		t.Fatalf("subscriber should receive published topic, got %#v", result)
+	}
+	if !strings.Contains(result["timeout"], ErrIPCTimeout.Error()) {
+		t.Fatalf("pending request should time out, got %#v", result)
+	}
+	if !strings.Contains(result["fail"], "boom") {
+		t.Fatalf("handler error should reject the request, got %#v", result)
+	}
+	if !strings.Contains(result["missing"], ErrIPCNoHandler.Error()) {
+		t.Fatalf("unknown method should be rejected, got %#v", result)
+	}
+	if !strings.Contains(result["denied"], "ipc") || !strings.Contains(result["subDenied"], "ipc") {
+		t.Fatalf("packages outside the ipc list should be denied, got %#v", result)
+	}
+	if !strings.Contains(spy["denied"], "ipc") || spy["add"] != "" || spy["news"] != "" {
+		t.Fatalf("receiver should check the caller against its own manifest, got %#v", spy)
+	}
+	if !strings.Contains(plain, ErrIPCPackageOnly.Error()) {
+		t.Fatalf("global seal.ipc should be unavailable to plain scripts, got %q", plain)
+	}
+}
+
+func TestPackageIPCRemoveLoop(t *testing.T) {
+	p := NewPackageIPC()
+	allow := func(string) error { return nil }
+	stopped, alive := eventloop.NewEventLoop(), eventloop.NewEventLoop()
+	p.Handle("m", &ipcReceiver{pkgID: "a/stopped", loop: stopped, allow: allow})
+	p.Handle("m", &ipcReceiver{pkgID: "b/alive", loop: alive, allow: allow})
+	p.Subscribe("b/alive", "t", &ipcReceiver{pkgID: "a/stopped", loop: stopped, allow: allow})
+
+	p.RemoveLoop(stopped)
+	if p.handler("a/stopped", "m") != nil || p.handler("b/alive", "m") == nil {
+		t.Fatal("only receivers on the stopped loop should be removed")
+	}
+	if n := p.Publish("b/alive", allow, "t", nil); n != 0 {
+		t.Fatalf("Publish() = %d after removing the subscriber", n)
+	}
+}
+
+func TestPackageIPCPayloadCopies(t *testing.T) {
+	p := NewPackageIPC()
+	allow := func(string) error { return nil }
+
+	type result struct {
+		seen string
+		err  error
+	}
+	results := make(chan result, 2)
+	for _, pkgID := range []string{"a/one", "b/two"} {
+		loop := eventloop.NewEventLoop()
+		loop.Start()
+		t.Cleanup(func() { loop.Stop() })
+
+		subscribed := make(chan struct{})
+		var fn goja.Callable
+		loop.RunOnLoop(func(vm *goja.Runtime) {
+			defer close(subscribed)
+			v, _ := vm.RunString(`(d) => {
+  const seen = [d.n, d.list.length, d.nested.tag].join(",");
+  for (let i = 0; i < 100; i++) { d.n++; d.list.push(i); d.nested.tag = String(i); }
+  return seen;
+}`)
+			fn, _ = goja.AssertFunction(v)
+			p.Subscribe("pub/lisher", "t", &ipcReceiver{pkgID: pkgID, loop: loop, allow: allow,
+				fn: func(this goja.Value, args ...goja.Value) (goja.Value, error) {
+					ret, err := fn(this, args[0])
+					if err == nil {
+						results <- result{seen: ret.String()}
+					} else {
+						results <- result{err: err}
+					}
+					return ret, err
+				}})
+		})
+		<-subscribed
+	}
+
+	vm := goja.New()
+	v, _ := vm.RunString(`({ n: 1, list: [], nested: { tag: "" } })`)
+	data, err := encodeIPCValue(v)
+	if err != nil {
+		t.Fatalf("encodeIPCValue() error = %v", err)
+	}
+	if n := p.Publish("pub/lisher", allow, "t", data); n != 2 {
+		t.Fatalf("Publish() = %d, want 2", n)
+	}
+	for range 2 {
+		select {
+		case r := <-results:
+			if r.err != nil || r.seen != "1,0," {
+				t.Fatalf("each subscriber should get its own copy, got %q, %v", r.seen, r.err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("subscriber was not called")
+		}
+	}
+
+	fnValue, _ := vm.RunString(`({ f: () => 1 })`)
+	if _, err = encodeIPCValue(fnValue); !errors.Is(err, ErrIPCUnencodable) {
+		t.Fatalf("functions should not be sent, got %v", err)
+	}
+}
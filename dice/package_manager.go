@@ -56,6 +56,9 @@ type PackageManager struct {
 
 	// 反向依赖图: A -> [B, C] 表示 B 和 C 依赖 A
 	reverseDependencyGraph map[string][]string
+
+	// 扩展包之间的消息通道
+	ipc *PackageIPC
 }
 
 type packageArtifactCandidate struct {
@@ -108,6 +111,7 @@ func NewPackageManager(parent *Dice) *PackageManager {
 		packages:               make(map[string]*sealpack.Instance),
 		dependencyGraph:        make(map[string][]string),
 		reverseDependencyGraph: make(map[string][]string),
+		ipc:                    NewPackageIPC(),
 	}
 	return pm
 }
@@ -1936,6 +1940,42 @@ func (pm *PackageManager) GetSandbox(pkgID string) (*sealpack.Sandbox, error) {
 	return sealpack.NewSandboxFromInstance(pkg), nil
 }
 
+// PackageIDOfPath 返回文件所属的已启用扩展包，不属于任何扩展包时返回空串
+func (pm *PackageManager) PackageIDOfPath(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+
+	pm.lock.RLock()
+	defer pm.lock.RUnlock()
+
+	for pkgID, pkg := range pm.packages {
+		if pkg.State != sealpack.PackageStateEnabled || pkg.InstallPath == "" {
+			continue
+		}
+		installPath, err := filepath.Abs(pkg.InstallPath)
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(absPath, installPath+string(os.PathSeparator)) {
+			return pkgID
+		}
+	}
+	return ""
+}
+
+// ReportPermissionError 记录扩展包的权限违规，写入包的错误信息并输出到日志
+func (pm *PackageManager) ReportPermissionError(pkgID string, err error) {
+	pm.lock.Lock()
+	if pkg, exists := pm.packages[pkgID]; exists {
+		pkg.ErrText = err.Error()
+	}
+	pm.lock.Unlock()
+
+	pm.parent.Logger.Warnf("扩展包 %s 权限违规: %v", pkgID, err)
+}
+
 // generateReloadHints 根据包的内容生成重载提示
 func (pm *PackageManager) generateReloadHints(manifest *sealpack.Manifest) *sealpack.OperationResult {
 	hints := make([]string, 0)
@@ -0,0 +1,222 @@
+package dice
+
+import (
+	"errors"
+	"os"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/eventloop"
+	"github.com/dop251/goja_nodejs/require"
+
+	"sealdice-core/dice/sealpack"
+	sealws "sealdice-core/utils/plugin/websocket"
+)
+
+const (
+	// jsPackageRuntimePrefix 扩展包所在运行时的名称前缀
+	jsPackageRuntimePrefix = "package:"
+	// jsPackageSandboxModule 扩展包运行时中的原生模块，导出取得调用方所属包沙箱 API 的函数
+	jsPackageSandboxModule = "sealpack"
+)
+
+// jsPackagePrelude 注入到扩展包脚本开头，用包自己的 seal、fetch 和 WebSocket 覆盖全局版本。
+// 不换行，以免错误信息中的行号偏移
+const jsPackagePrelude = `var sealpack = require("` + jsPackageSandboxModule + `")(), seal = sealpack.seal, fetch = sealpack.fetch, WebSocket = sealpack.WebSocket;`
+
+// jsSourceLoader 读取脚本源码，属于扩展包的脚本会注入该包的沙箱 API
+func (d *Dice) jsSourceLoader(path string) ([]byte, error) {
+	data, err := require.DefaultSourceLoader(path)
+	if err != nil || d.PackageManager == nil || !isScriptFile(path) {
+		return data, err
+	}
+	if d.PackageManager.PackageIDOfPath(path) == "" {
+		return data, nil
+	}
+	return append([]byte(jsPackagePrelude), data...), nil
+}
+
+// jsPackageSandboxes 扩展包运行时中各包的沙箱 API，同一个包在一个运行时内共用一个对象
+type jsPackageSandboxes struct {
+	d     *Dice
+	vm    *goja.Runtime
+	loop  *eventloop.EventLoop
+	fetch goja.Callable // 从全局移走的 fetch，只能经沙箱检查后调用
+	cache map[string]*goja.Object
+}
+
+// jsEnablePackageSandbox 移除扩展包运行时的全局 fetch 和 WebSocket，并注册 jsPackageSandboxModule 模块。
+// 包ID由调用方代码所在的文件决定，脚本无法取得其他包的沙箱
+func (d *Dice) jsEnablePackageSandbox(vm *goja.Runtime, loop *eventloop.EventLoop, reg *require.Registry) {
+	sb := &jsPackageSandboxes{d: d, vm: vm, loop: loop, cache: map[string]*goja.Object{}}
+	// fetch 在事件循环启动后才装载，排在它后面移除
+	loop.RunOnLoop(func(vm *goja.Runtime) {
+		sb.fetch, _ = goja.AssertFunction(vm.Get("fetch"))
+		global := vm.GlobalObject()
+		for _, name := range []string{"fetch", "WebSocket"} {
+			if err := global.Delete(name); err != nil || global.Get(name) != nil {
+				_ = global.Set(name, goja.Undefined())
+			}
+		}
+	})
+	reg.RegisterNativeModule(jsPackageSandboxModule, func(vm *goja.Runtime, module *goja.Object) {
+		_ = module.Set("exports", sb.get)
+	})
+}
+
+// get 返回调用方所属包的沙箱 API，调用方以其代码所在的文件判断
+func (sb *jsPackageSandboxes) get(goja.FunctionCall) goja.Value {
+	vm := sb.vm
+	var frames [2]goja.StackFrame
+	stack := vm.CaptureCallStack(2, frames[:0])
+	pkgID := ""
+	if len(stack) == 2 && sb.d.PackageManager != nil {
+		pkgID = sb.d.PackageManager.PackageIDOfPath(stack[1].SrcName())
+	}
+	if pkgID == "" {
+		panic(vm.NewTypeError("沙箱 API 只能由扩展包脚本获取"))
+	}
+	if obj, ok := sb.cache[pkgID]; ok {
+		return obj
+	}
+	sandbox, err := sb.d.PackageManager.GetSandbox(pkgID)
+	if err != nil {
+		panic(vm.NewGoError(err))
+	}
+	s := &jsPackageSandbox{d: sb.d, vm: vm, loop: sb.loop, globalFetch: sb.fetch, sandbox: sandbox, fs: sealpack.NewSandboxedFS(sandbox)}
+	obj := s.object()
+	sb.cache[pkgID] = obj
+	return obj
+}
+
+// jsPackageSandbox 绑定到单个扩展包权限的 JS API
+type jsPackageSandbox struct {
+	d           *Dice
+	vm          *goja.Runtime
+	loop        *eventloop.EventLoop
+	globalFetch goja.Callable
+	sandbox     *sealpack.Sandbox
+	fs          *sealpack.SandboxedFS
+}
+
+func (s *jsPackageSandbox) object() *goja.Object {
+	vm := s.vm
+	perms := s.sandbox.Permissions
+
+	fsObj := vm.NewObject()
+	_ = fsObj.Set("readFile", s.readFile)
+	_ = fsObj.Set("writeFile", s.writeFile)
+	_ = fsObj.Set("exists", s.exists)
+	_ = fsObj.Set("readDir", s.readDir)
+	_ = fsObj.Set("mkdir", s.mkdir)
+	_ = fsObj.Set("remove", s.remove)
+
+	obj := vm.NewObject()
+	_ = obj.Set("id", s.sandbox.PackageID)
+	_ = obj.Set("permissions", map[string]any{
+		"network":      perms.Network,
+		"networkHosts": perms.NetworkHosts,
+		"fileRead":     perms.FileRead,
+		"fileWrite":    perms.FileWrite,
+		"httpServer":   perms.HTTPServer,
+		"dangerous":    perms.Dangerous,
+		"ipc":          perms.IPC,
+	})
+	_ = obj.Set("fetch", s.fetch)
+	_ = obj.Set("WebSocket", sealws.NewConstructor(vm, s.loop, func(url string) error {
+		return s.check(s.sandbox.CheckNetworkPermission(url))
+	}))
+	_ = obj.Set("fs", fsObj)
+
+	// 包内的 seal 继承全局 seal，只把 ipc 换成绑定到本包的版本。
+	// 全局 seal 已被冻结，需要定义自有属性才能覆盖
+	ipc := s.ipcObject()
+	var seal *goja.Object
+	if global, ok := vm.Get("seal").(*goja.Object); ok {
+		seal = vm.CreateObject(global)
+	} else {
+		seal = vm.NewObject()
+	}
+	_ = seal.DefineDataProperty("ipc", ipc, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	_ = obj.Set("ipc", ipc)
+	_ = obj.Set("seal", seal)
+	return obj
+}
+
+// check 原样返回 err，权限错误会上报到扩展包
+func (s *jsPackageSandbox) check(err error) error {
+	var permErr *sealpack.PermissionError
+	if errors.As(err, &permErr) {
+		s.d.PackageManager.ReportPermissionError(s.sandbox.PackageID, err)
+	}
+	return err
+}
+
+// fetch 检查网络权限后交给原先的全局 fetch，没有权限时返回被拒绝的 Promise
+func (s *jsPackageSandbox) fetch(call goja.FunctionCall) goja.Value {
+	vm := s.vm
+	target := call.Argument(0)
+	url := target.String()
+	if obj, ok := target.(*goja.Object); ok {
+		// Request 对象
+		if v := obj.Get("url"); v != nil && !goja.IsUndefined(v) {
+			url = v.String()
+		}
+	}
+	if err := s.check(s.sandbox.CheckNetworkPermission(url)); err != nil {
+		p, _, reject := vm.NewPromise()
+		_ = reject(vm.NewGoError(err))
+		return vm.ToValue(p)
+	}
+
+	if s.globalFetch == nil {
+		panic(vm.NewTypeError("fetch 不可用"))
+	}
+	ret, err := s.globalFetch(goja.Undefined(), call.Arguments...)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+func (s *jsPackageSandbox) readFile(path string) (string, error) {
+	data, err := s.fs.ReadFile(path)
+	if err != nil {
+		return "", s.check(err)
+	}
+	return string(data), nil
+}
+
+func (s *jsPackageSandbox) writeFile(path string, data string) error {
+	return s.check(s.fs.WriteFile(path, []byte(data), 0o644))
+}
+
+func (s *jsPackageSandbox) exists(path string) (bool, error) {
+	_, err := s.fs.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, s.check(err)
+	}
+	return true, nil
+}
+
+func (s *jsPackageSandbox) readDir(path string) ([]string, error) {
+	entries, err := s.fs.ReadDir(path)
+	if err != nil {
+		return nil, s.check(err)
+	}
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+	return names, nil
+}
+
+func (s *jsPackageSandbox) mkdir(path string) error {
+	return s.check(s.fs.Mkdir(path, 0o755))
+}
+
+func (s *jsPackageSandbox) remove(path string) error {
+	return s.check(s.fs.Remove(path))
+}
@@ -0,0 +1,115 @@
+package dice //nolint:testpackage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+func TestPackageScriptSandbox(t *testing.T) {
+	testDice, pm := newScriptReloadTestPackageManager(t)
+	if err := pm.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	pkgID := "alice/sandboxed"
+	archive := createTestSealPack(t, "", pkgID, "1.0.0", map[string][]string{
+		"scripts": {"scripts/*.js"},
+	}, map[string]string{
+		"scripts/main.js": `// ==UserScript==
+// @name sandboxed
+// ==/UserScript==
+const r = globalThis.__sandboxResult = {};
+sealpack.fs.writeFile("_userdata/note.txt", "hi");
+r.read = sealpack.fs.readFile("_userdata/note.txt");
+try { sealpack.fs.writeFile("scripts/main.js", ""); } catch (e) { r.writeDenied = String(e); }
+try { new WebSocket("ws://evil.test/"); } catch (e) { r.wsDenied = String(e); }
+fetch("http://evil.test/").catch((e) => { r.fetchDenied = String(e); });
+r.globals = [typeof globalThis.fetch, typeof globalThis.WebSocket, typeof globalThis.__sealpackSandbox__].join(",");
+try { new Function('return require("sealpack")()')(); } catch (e) { r.evalDenied = String(e); }
+`,
+	}, func(b *strings.Builder) {
+		b.WriteString("\n[permissions]\nnetwork = true\nnetwork_hosts = [\"example.com\"]\n")
+	})
+	if err := pm.Install(archive); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if _, err := pm.Enable(pkgID); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+
+	testDice.JsReload()
+
+	result := map[string]string{}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		runOnPackageRuntime(t, testDice, pkgID, func(vm *goja.Runtime) {
+			if v := vm.Get("__sandboxResult"); v != nil && !goja.IsUndefined(v) {
+				_ = vm.ExportTo(v, &result)
+			}
+		})
+		if result["fetchDenied"] != "" {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if result["read"] != "hi" {
+		t.Fatalf("package should access its own user data, got %#v", result)
+	}
+	if !strings.Contains(result["writeDenied"], "file_write") {
+		t.Fatalf("write outside user data should be denied, got %#v", result)
+	}
+	if !strings.Contains(result["wsDenied"], "network_hosts") || !strings.Contains(result["fetchDenied"], "network_hosts") {
+		t.Fatalf("network access outside the whitelist should be denied, got %#v", result)
+	}
+
+	// 全局的 fetch 和 WebSocket 已被移除，沙箱 API 只能由包自己的代码取得
+	if result["globals"] != "undefined,undefined,undefined" {
+		t.Fatalf("package runtime globals = %q", result["globals"])
+	}
+	if !strings.Contains(result["evalDenied"], "沙箱") {
+		t.Fatalf("sandbox getter should reject dynamic code, got %#v", result)
+	}
+	var fromGo, fromShared string
+	runOnPackageRuntime(t, testDice, pkgID, func(vm *goja.Runtime) {
+		v, _ := vm.RunString(`try { require("sealpack")(); "" } catch (e) { String(e) }`)
+		fromGo = v.String()
+	})
+	done := make(chan struct{})
+	testDice.ExtLoopManager.GetWebLoop().RunOnLoop(func(vm *goja.Runtime) {
+		defer close(done)
+		v, _ := vm.RunString(`try { require("sealpack"); "" } catch (e) { String(e) }`)
+		fromShared = v.String()
+	})
+	<-done
+	if fromGo == "" || fromShared == "" {
+		t.Fatalf("sandbox getter should be unavailable outside package scripts, got %q, %q", fromGo, fromShared)
+	}
+
+	pkg, _ := pm.Get(pkgID)
+	if !strings.Contains(pkg.ErrText, "network_hosts") {
+		t.Fatalf("permission violation should be reported on the package, got %q", pkg.ErrText)
+	}
+}
+
+// runOnPackageRuntime 在扩展包所在的运行时上同步执行 fn
+func runOnPackageRuntime(t *testing.T, d *Dice, pkgID string, fn func(vm *goja.Runtime)) {
+	t.Helper()
+	version, ok := d.ExtLoopManager.runtimeByName(jsPackageRuntimePrefix + pkgID)
+	if !ok {
+		t.Fatalf("runtime of %s not found", pkgID)
+	}
+	loop, err := d.ExtLoopManager.GetLoop(version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	loop.RunOnLoop(func(vm *goja.Runtime) {
+		defer close(done)
+		fn(vm)
+	})
+	<-done
+}
@@ -1,6 +1,10 @@
 package dice
 
-import "sealdice-core/message"
+import (
+	"slices"
+
+	"sealdice-core/message"
+)
 
 type PlatformAdapter interface {
 	Serve() int
@@ -23,8 +27,8 @@ type PlatformAdapter interface {
 
 	GetGroupInfoAsync(groupID string)
 
-	// DeleteFriend 删除好友，目前只有 QQ 平台下的 gocq 和 walleq 实现有这个方法
-	// DeleteFriend(ctx *MsgContext, id string)
+	// Capabilities 返回适配器支持的功能，调用方应据此选择降级方案，而不是调用后静默失败
+	Capabilities() PlatformCapabilities
 
 	// EditMessage replace the content of the message with msgID with message.
 	// Context is retrieved from ctx.
@@ -56,3 +60,45 @@ var (
 )
 
 var _ forwardMsgSender = (*PlatformAdapterMilky)(nil)
+
+// friendDeleter 删除好友，目前只有 QQ 平台下的 gocq 和 walleq 实现有这个方法
+type friendDeleter interface {
+	DeleteFriend(ctx *MsgContext, id string)
+}
+
+var (
+	_ friendDeleter = (*PlatformAdapterGocq)(nil)
+	_ friendDeleter = (*PlatformAdapterWalleQ)(nil)
+)
+
+// PlatformCapabilities 平台适配器的功能描述
+type PlatformCapabilities struct {
+	EditMessage    bool `jsbind:"editMessage"    json:"editMessage"`    // 编辑已发送的消息
+	RecallMessage  bool `jsbind:"recallMessage"  json:"recallMessage"`  // 撤回消息
+	MemberBan      bool `jsbind:"memberBan"      json:"memberBan"`      // 禁言群成员
+	MemberKick     bool `jsbind:"memberKick"     json:"memberKick"`     // 踢出群成员
+	QuitGroup      bool `jsbind:"quitGroup"      json:"quitGroup"`      // 主动退群
+	DeleteFriend   bool `jsbind:"deleteFriend"   json:"deleteFriend"`   // 删除好友
+	ForwardMessage bool `jsbind:"forwardMessage" json:"forwardMessage"` // 合并转发
+	SendFile       bool `jsbind:"sendFile"       json:"sendFile"`       // 发送文件
+	Markdown       bool `jsbind:"markdown"       json:"markdown"`       // 消息按 markdown 渲染
+
+	// 单条消息的最大长度，超出时由适配器分段发送，0 为不限制或未知
+	MaxMessageLength int `jsbind:"maxMessageLength" json:"maxMessageLength"`
+	// 可以原生发送的消息段类型，其余类型会被降级为文本
+	RichElements []string `jsbind:"richElements" json:"richElements"`
+}
+
+// richElements 将消息段类型转为类型名列表
+func richElements(types ...message.ElementType) []string {
+	ret := make([]string, len(types))
+	for i, t := range types {
+		ret[i] = t.String()
+	}
+	return ret
+}
+
+// SupportsElement 是否可以原生发送该类型的消息段
+func (c PlatformCapabilities) SupportsElement(t message.ElementType) bool {
+	return slices.Contains(c.RichElements, t.String())
+}
@@ -65,10 +65,14 @@ func (pa *PlatformAdapterDingTalk) QuitGroup(ctx *MsgContext, id string) {
 	pa.SendToGroup(ctx, id, "不支持此功能, 请手动移除机器人", "")
 }
 
+// SendSegmentToGroup 平台只支持纯文本，消息段转为文本发送
 func (pa *PlatformAdapterDingTalk) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	pa.SendToGroup(ctx, groupID, message.ElementsToText(msg), flag)
 }
 
+// SendSegmentToPerson 平台只支持纯文本，消息段转为文本发送
 func (pa *PlatformAdapterDingTalk) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	pa.SendToPerson(ctx, userID, message.ElementsToText(msg), flag)
 }
 
 func (pa *PlatformAdapterDingTalk) SendToPerson(ctx *MsgContext, uid string, text string, flag string) {
@@ -156,6 +160,12 @@ func (pa *PlatformAdapterDingTalk) MemberBan(groupID string, userID string, dura
 func (pa *PlatformAdapterDingTalk) MemberKick(groupID string, userID string) {
 }
 
+func (pa *PlatformAdapterDingTalk) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		RichElements: richElements(message.Text),
+	}
+}
+
 func (pa *PlatformAdapterDingTalk) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterDingTalk) RecallMessage(_ *MsgContext, _ string) {}
@@ -317,9 +317,43 @@ func (pa *PlatformAdapterDiscord) SetEnable(enable bool) {
 }
 
 func (pa *PlatformAdapterDiscord) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	resp, err := pa.sendElementsToChannelRaw(groupID, msg, true)
+	if err != nil {
+		return
+	}
+	pa.EndPoint.Session.OnMessageSend(ctx, &Message{
+		Platform:    "DISCORD",
+		MessageType: "group",
+		Segment:     msg,
+		GroupID:     groupID,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: resp.ID,
+	}, flag)
 }
 
 func (pa *PlatformAdapterDiscord) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	ch, err := pa.IntentSession.UserChannelCreate(ExtractDiscordUserID(userID))
+	if err != nil {
+		pa.EndPoint.Session.Parent.Logger.Errorf("创建Discord用户#%s的私聊频道时出错:%s", userID, err)
+		return
+	}
+	resp, err := pa.sendElementsToChannelRaw(ch.ID, msg, true)
+	if err != nil {
+		return
+	}
+	pa.EndPoint.Session.OnMessageSend(ctx, &Message{
+		Platform:    "DISCORD",
+		MessageType: "private",
+		Segment:     msg,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: resp.ID,
+	}, flag)
 }
 
 // SendToPerson 这里发送的是私聊（dm）消息，私信对于discord来说也被视为一个频道
@@ -440,62 +474,60 @@ func (pa *PlatformAdapterDiscord) sendFileToChannelRaw(channelID string, path st
 }
 
 func (pa *PlatformAdapterDiscord) sendToChannelRaw(channelID string, text string) (*discordgo.Message, error) {
+	return pa.sendElementsToChannelRaw(channelID, message.ConvertStringMessage(text), false)
+}
+
+// sendElementsToChannelRaw 发送消息段，withFiles 为 false 时文件和语音只以文本形式发出
+func (pa *PlatformAdapterDiscord) sendElementsToChannelRaw(channelID string, elem []message.IMessageElement, withFiles bool) (*discordgo.Message, error) {
 	logger := pa.EndPoint.Session.Parent.Logger
-	elem := message.ConvertStringMessage(text)
 	id := ExtractDiscordChannelID(channelID)
 	var err error
 	msgSend := &discordgo.MessageSend{Content: ""}
+	appendText := func(text string) {
+		if msgSend.Embeds != nil {
+			msgSend.Embeds[len(msgSend.Embeds)-1].Description += text
+		} else {
+			msgSend.Embeds = append(msgSend.Embeds, &discordgo.MessageEmbed{
+				Description: text,
+				Type:        discordgo.EmbedTypeArticle,
+			})
+		}
+	}
+	appendFile := func(f *message.FileElement) bool {
+		if f == nil || f.Stream == nil {
+			return false
+		}
+		msgSend.Files = append(msgSend.Files, &discordgo.File{
+			Name:        f.File,
+			ContentType: f.ContentType,
+			Reader:      f.Stream,
+		})
+		return true
+	}
 	for _, element := range elem {
 		switch e := element.(type) {
 		case *message.TextElement:
 			// msgSend.Content = msgSend.Content + antiMarkdownFormat(e.Content)
-			if msgSend.Embeds != nil {
-				msgSend.Embeds[len(msgSend.Embeds)-1].Description += antiMarkdownFormat(e.Content)
-			} else {
-				msgSend.Embeds = append(msgSend.Embeds, &discordgo.MessageEmbed{
-					Description: antiMarkdownFormat(e.Content),
-					Type:        discordgo.EmbedTypeArticle,
-				})
-			}
+			appendText(antiMarkdownFormat(e.Content))
 		case *message.AtElement:
-			if msgSend.Embeds != nil {
-				if e.Target == "all" {
-					msgSend.Embeds[len(msgSend.Embeds)-1].Description += "@everyone "
-				} else {
-					msgSend.Embeds[len(msgSend.Embeds)-1].Description += fmt.Sprintf("<@%s>", e.Target)
-				}
+			if e.Target == "all" {
+				appendText("@everyone ")
 			} else {
-				if e.Target == "all" {
-					msgSend.Embeds = append(msgSend.Embeds, &discordgo.MessageEmbed{
-						Description: "@everyone ",
-						Type:        discordgo.EmbedTypeArticle,
-					})
-				} else {
-					msgSend.Embeds = append(msgSend.Embeds, &discordgo.MessageEmbed{
-						Description: fmt.Sprintf("<@%s>", e.Target),
-						Type:        discordgo.EmbedTypeArticle,
-					})
-				}
+				appendText(fmt.Sprintf("<@%s>", ExtractDiscordUserID(e.Target)))
+			}
+		// 文本中的文件 CQ 码出于安全考虑不发送，只有直接构造的消息段才允许发送文件
+		case *message.FileElement:
+			if !withFiles || !appendFile(e) {
+				appendText(antiMarkdownFormat(message.FallbackText(e)))
+			}
+		case *message.RecordElement:
+			if !withFiles || !appendFile(e.File) {
+				appendText(antiMarkdownFormat(message.FallbackText(e)))
 			}
-			// Note(Szzrain): Disabled due to Security Concerns
-		// case *message.FileElement:
-		//	msgSend.Files = append(msgSend.Files, &discordgo.File{
-		//		Name:        e.File,
-		//		ContentType: e.ContentType,
-		//		Reader:      e.Stream,
-		//	})
-		// _, err = pa.IntentSession.ChannelMessageSendComplex(id, msgSend)
-		// msgSend = &discordgo.MessageSend{Content: ""}
 		case *message.ImageElement:
-			// var files []*discordgo.File
-			f := e.File
-			msgSend.Files = append(msgSend.Files, &discordgo.File{
-				Name:        f.File,
-				ContentType: f.ContentType,
-				Reader:      f.Stream,
-			})
-			// _, err = pa.IntentSession.ChannelMessageSendComplex(id, msgSend)
-			// msgSend = &discordgo.MessageSend{Content: ""}
+			if !appendFile(e.File) {
+				appendText(antiMarkdownFormat(message.FallbackText(e)))
+			}
 		case *message.TTSElement:
 			if msgSend.Content != "" || msgSend.Files != nil || msgSend.Embeds != nil {
 				_, err = pa.IntentSession.ChannelMessageSendComplex(id, msgSend)
@@ -517,6 +549,10 @@ func (pa *PlatformAdapterDiscord) sendToChannelRaw(channelID string, text string
 			}
 			ref := &discordgo.MessageReference{MessageID: e.ReplySeq, ChannelID: id, GuildID: channel.GuildID}
 			msgSend.Reference = ref
+		default:
+			if text := message.FallbackText(e); text != "" {
+				appendText(antiMarkdownFormat(text))
+			}
 		}
 		if err != nil {
 			pa.EndPoint.Session.Parent.Logger.Errorf("向Discord频道#%s发送消息时出错:%s", id, err)
@@ -565,6 +601,18 @@ func (pa *PlatformAdapterDiscord) MemberBan(_ string, _ string, _ int64) {}
 
 func (pa *PlatformAdapterDiscord) MemberKick(_ string, _ string) {}
 
+func (pa *PlatformAdapterDiscord) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		EditMessage:      true,
+		RecallMessage:    true,
+		QuitGroup:        true,
+		SendFile:         true,
+		Markdown:         true,
+		MaxMessageLength: 2000,
+		RichElements:     richElements(message.Text, message.At, message.Image, message.File, message.Record, message.TTS, message.Reply),
+	}
+}
+
 func (pa *PlatformAdapterDiscord) EditMessage(ctx *MsgContext, msgID, message string) {
 	var envID string
 	if ctx.MessageType == "private" {
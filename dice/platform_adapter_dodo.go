@@ -324,9 +324,38 @@ func (pa *PlatformAdapterDodo) SetEnable(enable bool) {
 }
 
 func (pa *PlatformAdapterDodo) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	err := pa.sendElementsToChatRaw(ctx, groupID, msg, false)
+	if err != nil {
+		pa.EndPoint.Session.Parent.Logger.Errorf("DODO 发送消息失败：%v\n", err)
+		return
+	}
+	pa.EndPoint.Session.OnMessageSend(ctx, &Message{
+		MessageType: "group",
+		Platform:    "DODO",
+		Segment:     msg,
+		GroupID:     groupID,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+	}, flag)
 }
 
 func (pa *PlatformAdapterDodo) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	err := pa.sendElementsToPersonRaw(ctx, userID, msg, true)
+	if err != nil {
+		pa.EndPoint.Session.Parent.Logger.Errorf("DODO 发送私聊消息失败：%v\n", err)
+		return
+	}
+	pa.EndPoint.Session.OnMessageSend(ctx, &Message{
+		MessageType: "private",
+		Platform:    "DODO",
+		Segment:     msg,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+	}, flag)
 }
 
 func (pa *PlatformAdapterDodo) SendToPerson(ctx *MsgContext, uid string, text string, flag string) {
@@ -383,6 +412,13 @@ func (pa *PlatformAdapterDodo) SendFileToGroup(ctx *MsgContext, groupID string,
 	}
 }
 
+func (pa *PlatformAdapterDodo) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		QuitGroup:    true,
+		RichElements: richElements(message.Text, message.At, message.Image, message.Reply),
+	}
+}
+
 func (pa *PlatformAdapterDodo) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterDodo) RecallMessage(_ *MsgContext, _ string) {}
@@ -425,8 +461,12 @@ func convertLinksToMarkdown(text string) string {
 }
 
 func (pa *PlatformAdapterDodo) SendToPersonRaw(ctx *MsgContext, uid string, text string, isPrivate bool) error {
+	return pa.sendElementsToPersonRaw(ctx, uid, message.ConvertStringMessage(text), isPrivate)
+}
+
+// sendElementsToPersonRaw 逐段发送私信，私信只支持文字和图片，其余消息段以文本形式发出
+func (pa *PlatformAdapterDodo) sendElementsToPersonRaw(ctx *MsgContext, uid string, elem []message.IMessageElement, isPrivate bool) error {
 	instance := pa.Client
-	elem := message.ConvertStringMessage(text)
 	streamToByte := func(stream io.Reader) []byte {
 		buf := new(bytes.Buffer)
 		_, err := buf.ReadFrom(stream)
@@ -443,6 +483,13 @@ func (pa *PlatformAdapterDodo) SendToPersonRaw(ctx *MsgContext, uid string, text
 				return err
 			}
 		case *message.ImageElement:
+			if e.File == nil || e.File.Stream == nil {
+				err := pa.SendMessageRaw(ctx, &model.TextMessage{Content: message.FallbackText(e)}, uid, isPrivate, "")
+				if err != nil {
+					return err
+				}
+				continue
+			}
 			resourceResp, err := instance.UploadImageByBytes(context.Background(), &model.UploadImageByBytesReq{
 				Filename: e.File.File,
 				Bytes:    streamToByte(e.File.Stream),
@@ -460,15 +507,28 @@ func (pa *PlatformAdapterDodo) SendToPersonRaw(ctx *MsgContext, uid string, text
 			if err != nil {
 				return err
 			}
+		default:
+			text := message.FallbackText(e)
+			if text == "" {
+				continue
+			}
+			err := pa.SendMessageRaw(ctx, &model.TextMessage{Content: text}, uid, isPrivate, "")
+			if err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
 func (pa *PlatformAdapterDodo) SendToChatRaw(ctx *MsgContext, uid string, text string, isPrivate bool) error {
+	return pa.sendElementsToChatRaw(ctx, uid, message.ConvertStringMessage(text), isPrivate)
+}
+
+// sendElementsToChatRaw 将消息段组装为卡片消息发送，不支持的消息段以文本形式发出
+func (pa *PlatformAdapterDodo) sendElementsToChatRaw(ctx *MsgContext, uid string, elem []message.IMessageElement, isPrivate bool) error {
 	referenceMessageId := ""
 	instance := pa.Client
-	elem := message.ConvertStringMessage(text)
 	streamToByte := func(stream io.Reader) []byte {
 		buf := new(bytes.Buffer)
 		_, err := buf.ReadFrom(stream)
@@ -486,27 +546,34 @@ func (pa *PlatformAdapterDodo) SendToChatRaw(ctx *MsgContext, uid string, text s
 			Title:      "",
 		},
 	}
+	appendText := func(content string) {
+		if len(msgSend.Card.Components) > 0 {
+			component, ok := msgSend.Card.Components[len(msgSend.Card.Components)-1].(*DoDoTextMessageComponent)
+			if ok {
+				component.Text.Content += content
+				return
+			}
+		}
+		msgSend.Card.Components = append(msgSend.Card.Components, &DoDoTextMessageComponent{
+			Type: "section",
+			Text: struct {
+				Content string `json:"content"`
+				Type    string `json:"type"`
+			}{
+				Content: content,
+				Type:    "dodo-md",
+			},
+		})
+	}
 	for _, element := range elem {
 		switch e := element.(type) {
 		case *message.TextElement:
-			if msgSend.Card != nil && len(msgSend.Card.Components) > 0 {
-				component, ok := msgSend.Card.Components[len(msgSend.Card.Components)-1].(*DoDoTextMessageComponent)
-				if ok {
-					component.Text.Content += e.Content
-					continue
-				}
-			}
-			msgSend.Card.Components = append(msgSend.Card.Components, &DoDoTextMessageComponent{
-				Type: "section",
-				Text: struct {
-					Content string `json:"content"`
-					Type    string `json:"type"`
-				}{
-					Content: convertLinksToMarkdown(e.Content),
-					Type:    "dodo-md",
-				},
-			})
+			appendText(convertLinksToMarkdown(e.Content))
 		case *message.ImageElement:
+			if e.File == nil || e.File.Stream == nil {
+				appendText(message.FallbackText(e))
+				continue
+			}
 			resourceResp, err := instance.UploadImageByBytes(context.Background(), &model.UploadImageByBytesReq{
 				Filename: e.File.File,
 				Bytes:    streamToByte(e.File.Stream),
@@ -527,25 +594,17 @@ func (pa *PlatformAdapterDodo) SendToChatRaw(ctx *MsgContext, uid string, text s
 				},
 			})
 		case *message.AtElement:
-			if msgSend.Card != nil && len(msgSend.Card.Components) > 0 {
-				component, ok := msgSend.Card.Components[len(msgSend.Card.Components)-1].(*DoDoTextMessageComponent)
-				if ok {
-					component.Text.Content += fmt.Sprintf("<@!%s>", e.Target)
-					continue
-				}
+			if e.Target == "all" {
+				appendText(message.FallbackText(e))
+			} else {
+				appendText(fmt.Sprintf("<@!%s>", ExtractDodoUserID(e.Target)))
 			}
-			msgSend.Card.Components = append(msgSend.Card.Components, &DoDoTextMessageComponent{
-				Type: "section",
-				Text: struct {
-					Content string `json:"content"`
-					Type    string `json:"type"`
-				}{
-					Content: fmt.Sprintf("<@!%s>", e.Target),
-					Type:    "dodo-md",
-				},
-			})
 		case *message.ReplyElement:
 			referenceMessageId = e.ReplySeq
+		default:
+			if text := message.FallbackText(e); text != "" {
+				appendText(text)
+			}
 		}
 	}
 	err := pa.SendMessageRaw(ctx, msgSend, uid, isPrivate, referenceMessageId)
@@ -361,7 +361,7 @@ func OneBot11CqMessageToArrayMessage(longText string) []interface{} {
 		// 如果尾部有文本，将其拼入数组
 		endText := newText[p[1]:]
 		if len(endText) > 0 {
-			i := OneBotV11ArrMsgItem[OneBotV11MsgItemTextType]{Type: "text", Data: OneBotV11MsgItemTextType{Text: endText}}
+			i := OneBotV11ArrMsgItem[OneBotV11MsgItemTextType]{Type: "text", Data: OneBotV11MsgItemTextType{Text: message.UnescapeCQText(endText)}}
 			arr = append(arr, i)
 		}
 
@@ -403,17 +403,21 @@ func OneBot11CqMessageToArrayMessage(longText string) []interface{} {
 
 	// 如果剩余有文本，将其拼入数组
 	if len(newText) > 0 {
-		i := OneBotV11ArrMsgItem[OneBotV11MsgItemTextType]{Type: "text", Data: OneBotV11MsgItemTextType{Text: newText}}
+		i := OneBotV11ArrMsgItem[OneBotV11MsgItemTextType]{Type: "text", Data: OneBotV11MsgItemTextType{Text: message.UnescapeCQText(newText)}}
 		arr = append(arr, i)
 	}
 
 	return lo.Reverse(arr) //nolint:staticcheck // old code
 }
 
+// SendSegmentToGroup OneBot 原生支持 CQ 码，消息段还原为 CQ 码后走文本发送流程
 func (pa *PlatformAdapterGocq) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	pa.SendToGroup(ctx, groupID, message.ToCQCode(msg), flag)
 }
 
+// SendSegmentToPerson OneBot 原生支持 CQ 码，消息段还原为 CQ 码后走文本发送流程
 func (pa *PlatformAdapterGocq) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	pa.SendToPerson(ctx, userID, message.ToCQCode(msg), flag)
 }
 
 func (pa *PlatformAdapterGocq) Serve() int {
@@ -583,7 +587,7 @@ func (pa *PlatformAdapterGocq) Serve() int {
 
 					// 处理被强制拉群的情况
 					uid := groupInfo.InviteUserID
-					banInfo, ok := ctx.Dice.Config.BanList.GetByID(uid)
+					banInfo, ok := ctx.Dice.Config.BanList.GetByIDLinked(uid)
 					if ok {
 						if banInfo.Rank == BanRankBanned && ctx.Dice.Config.BanList.BanBehaviorRefuseInvite {
 							// 如果是被ban之后拉群，判定为强制拉群
@@ -647,7 +651,7 @@ func (pa *PlatformAdapterGocq) Serve() int {
 			tempInviteMap2[msg.GroupID] = uid
 
 			// 邀请人在黑名单上
-			banInfo, ok := ctx.Dice.Config.BanList.GetByID(uid)
+			banInfo, ok := ctx.Dice.Config.BanList.GetByIDLinked(uid)
 			if ok {
 				if banInfo.Rank == BanRankBanned && ctx.Dice.Config.BanList.BanBehaviorRefuseInvite {
 					pa.SetGroupAddRequest(msgQQ.Flag, msgQQ.SubType, false, "黑名单")
@@ -739,7 +743,7 @@ func (pa *PlatformAdapterGocq) Serve() int {
 			// 检查黑名单
 			extra := ""
 			uid := FormatDiceIDQQ(string(msgQQ.UserID))
-			banInfo, ok := ctx.Dice.Config.BanList.GetByID(uid)
+			banInfo, ok := ctx.Dice.Config.BanList.GetByIDLinked(uid)
 			if ok {
 				if banInfo.Rank == BanRankBanned && ctx.Dice.Config.BanList.BanBehaviorRefuseInvite {
 					if willAccept {
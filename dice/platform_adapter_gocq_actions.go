@@ -761,6 +761,16 @@ func (pa *PlatformAdapterGocq) MemberBan(_ string, _ string, _ int64) {}
 
 func (pa *PlatformAdapterGocq) MemberKick(_ string, _ string) {}
 
+func (pa *PlatformAdapterGocq) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		QuitGroup:        true,
+		DeleteFriend:     true,
+		SendFile:         true,
+		MaxMessageLength: 2000,
+		RichElements:     richElements(message.Text, message.At, message.Image, message.TTS, message.Reply, message.Record, message.Face, message.Poke),
+	}
+}
+
 func (pa *PlatformAdapterGocq) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterGocq) RecallMessage(_ *MsgContext, _ string) {}
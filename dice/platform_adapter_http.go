@@ -100,6 +100,12 @@ func (pa *PlatformAdapterHTTP) MemberBan(_ string, _ string, _ int64) {}
 
 func (pa *PlatformAdapterHTTP) MemberKick(_ string, _ string) {}
 
+func (pa *PlatformAdapterHTTP) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		RichElements: richElements(message.Text),
+	}
+}
+
 func (pa *PlatformAdapterHTTP) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterHTTP) RecallMessage(_ *MsgContext, _ string) {}
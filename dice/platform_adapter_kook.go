@@ -409,9 +409,50 @@ func (pa *PlatformAdapterKook) SetEnable(enable bool) {
 }
 
 func (pa *PlatformAdapterKook) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	if !pa.EndPoint.Enable || pa.IntentSession == nil || pa.EndPoint.State != 1 {
+		return
+	}
+	resp, err := pa.sendElementsToChannelRaw(ExtractKookChannelID(groupID), msg, false, true)
+	if err != nil {
+		return
+	}
+	pa.EndPoint.Session.OnMessageSend(ctx, &Message{
+		Platform:    "KOOK",
+		MessageType: "group",
+		Segment:     msg,
+		GroupID:     groupID,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: resp.MsgID,
+	}, flag)
 }
 
 func (pa *PlatformAdapterKook) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	log := zap.S().Named(logger.LogKeyAdapter)
+	if !pa.EndPoint.Enable || pa.IntentSession == nil || pa.EndPoint.State != 1 {
+		return
+	}
+	channel, err := pa.IntentSession.UserChatCreate(ExtractKookUserID(userID))
+	if err != nil {
+		log.Errorf("创建Kook用户#%s的私聊频道时出错:%s", userID, err)
+		return
+	}
+	resp, err := pa.sendElementsToChannelRaw(channel.Code, msg, true, true)
+	if err != nil {
+		return
+	}
+	pa.EndPoint.Session.OnMessageSend(ctx, &Message{
+		Platform:    "KOOK",
+		MessageType: "private",
+		Segment:     msg,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: resp.MsgID,
+	}, flag)
 }
 
 func (pa *PlatformAdapterKook) SendToPerson(ctx *MsgContext, userID string, text string, flag string) {
@@ -520,6 +561,17 @@ func (pa *PlatformAdapterKook) MemberBan(_ string, _ string, _ int64) {}
 
 func (pa *PlatformAdapterKook) MemberKick(_ string, _ string) {}
 
+func (pa *PlatformAdapterKook) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		EditMessage:   true,
+		RecallMessage: true,
+		QuitGroup:     true,
+		SendFile:      true,
+		Markdown:      true,
+		RichElements:  richElements(message.Text, message.At, message.Image, message.File, message.Record, message.Reply),
+	}
+}
+
 func (pa *PlatformAdapterKook) EditMessage(ctx *MsgContext, msgID, message string) {
 	log := zap.S().Named(logger.LogKeyAdapter)
 	req := kook.MessageUpdate{
@@ -594,9 +646,13 @@ func (pa *PlatformAdapterKook) SendFileToChannelRaw(id string, path string, priv
 }
 
 func (pa *PlatformAdapterKook) SendToChannelRaw(id string, text string, private bool) (*kook.MessageResp, error) {
+	return pa.sendElementsToChannelRaw(id, message.ConvertStringMessage(text), private, false)
+}
+
+// sendElementsToChannelRaw 将消息段组装为卡片消息发送，withFiles 为 false 时文件和语音只以文本形式发出
+func (pa *PlatformAdapterKook) sendElementsToChannelRaw(id string, elem []message.IMessageElement, private bool, withFiles bool) (*kook.MessageResp, error) {
 	log := zap.S().Named(logger.LogKeyAdapter)
 	bot := pa.IntentSession
-	elem := message.ConvertStringMessage(text)
 	// var err error
 	streamToByte := func(stream io.Reader) []byte {
 		buf := new(bytes.Buffer)
@@ -615,23 +671,37 @@ func (pa *PlatformAdapterKook) SendToChannelRaw(id string, text string, private
 		Theme: "primary",
 		Size:  "lg",
 	}
+	appendText := func(content string, textType string) {
+		cardModule := CardMessageModuleText{
+			Type: "section",
+			Text: struct {
+				Content string `json:"content"`
+				Type    string `json:"type"`
+			}{Content: content, Type: textType},
+		}
+		card.Modules = append(card.Modules, cardModule)
+	}
+	createAsset := func(f *message.FileElement) string {
+		if f == nil || f.Stream == nil {
+			return ""
+		}
+		assert, err := bot.AssetCreate(f.File, streamToByte(f.Stream))
+		if err != nil {
+			log.Errorf("Kook创建asserts时出错:%s", err)
+			return ""
+		}
+		return assert
+	}
 	for _, element := range elem {
 		switch e := element.(type) {
 		case *message.TextElement:
 			// goldmark.DefaultParser().Parse(txt.NewReader([]byte(e.Content)))
 			// msgb.Content += antiMarkdownFormat(e.Content)
-			cardModule := CardMessageModuleText{
-				Type: "section",
-				Text: struct {
-					Content string `json:"content"`
-					Type    string `json:"type"`
-				}{Content: e.Content, Type: "plain-text"},
-			}
-			card.Modules = append(card.Modules, cardModule)
+			appendText(e.Content, "plain-text")
 		case *message.ImageElement:
-			assert, err := bot.AssetCreate(e.File.File, streamToByte(e.File.Stream))
-			if err != nil {
-				log.Errorf("Kook创建asserts时出错:%s", err)
+			assert := createAsset(e.File)
+			if assert == "" {
+				appendText(message.FallbackText(e), "plain-text")
 				break
 			}
 			cardModule := CardMessageModuleImage{
@@ -642,33 +712,51 @@ func (pa *PlatformAdapterKook) SendToChannelRaw(id string, text string, private
 				Src  string `json:"src"`
 			}{"image", assert})
 			card.Modules = append(card.Modules, cardModule)
-		// Disabled due to Security Concerns
-		// case *message.FileElement:
-		//	assert, err := bot.AssetCreate(e.File, streamToByte(e.Stream))
-		//	if err != nil {
-		//		pa.EndPoint.Session.Parent.Logger.Errorf("Kook创建asserts时出错:%s", err)
-		//		break
-		//	}
-		//	cardModule := CardMessageModuleFile{
-		//		Type:  "file",
-		//		Title: e.File,
-		//		Src:   assert,
-		//	}
-		//	card.Modules = append(card.Modules, cardModule)
+		// 文本中的文件 CQ 码出于安全考虑不发送，只有直接构造的消息段才允许发送文件
+		case *message.FileElement:
+			assert := ""
+			if withFiles {
+				assert = createAsset(e)
+			}
+			if assert == "" {
+				appendText(message.FallbackText(e), "plain-text")
+				break
+			}
+			card.Modules = append(card.Modules, CardMessageModuleFile{
+				Type:  "file",
+				Title: e.File,
+				Src:   assert,
+			})
+		case *message.RecordElement:
+			assert := ""
+			if withFiles {
+				assert = createAsset(e.File)
+			}
+			if assert == "" {
+				appendText(message.FallbackText(e), "plain-text")
+				break
+			}
+			card.Modules = append(card.Modules, CardMessageModuleFile{
+				Type:  "audio",
+				Title: e.File.File,
+				Src:   assert,
+			})
 		case *message.AtElement:
-			cardModule := CardMessageModuleText{
-				Type: "section",
-				Text: struct {
-					Content string `json:"content"`
-					Type    string `json:"type"`
-				}{Content: "(met)" + e.Target + "(met)", Type: "kmarkdown"},
+			target := ExtractKookUserID(e.Target)
+			if target == "all" {
+				appendText("(met)all(met)", "kmarkdown")
+			} else {
+				appendText("(met)"+target+"(met)", "kmarkdown")
 			}
-			card.Modules = append(card.Modules, cardModule)
 			// msgb.Content = msgb.Content + fmt.Sprintf("(met)%s(met)", e.ReplySeq)
 		case *message.TTSElement:
 			// msgb.Content += antiMarkdownFormat(e.Content)
 		case *message.ReplyElement:
 			msgb.Quote = e.ReplySeq
+		default:
+			if text := message.FallbackText(e); text != "" {
+				appendText(text, "plain-text")
+			}
 		}
 	}
 	cardArray := []CardMessage{card}
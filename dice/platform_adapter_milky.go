@@ -364,7 +364,7 @@ func (pa *PlatformAdapterMilky) Serve() int {
 		ctx.Notice(txt, NoticeTypeInvite)
 
 		// 邀请人在黑名单上
-		banInfo, ok := ctx.Dice.Config.BanList.GetByID(uid)
+		banInfo, ok := ctx.Dice.Config.BanList.GetByIDLinked(uid)
 		if ok {
 			if banInfo.Rank == BanRankBanned && ctx.Dice.Config.BanList.BanBehaviorRefuseInvite {
 				pa.SetGroupAddRequest(m.GroupID, m.InvitationSeq, false)
@@ -497,7 +497,7 @@ func (pa *PlatformAdapterMilky) handelFriendRequest(ctx *MsgContext, event *milk
 	// 检查黑名单
 	extra := ""
 	uid := FormatDiceIDQQ(strconv.FormatInt(event.InitiatorID, 10))
-	banInfo, ok := ctx.Dice.Config.BanList.GetByID(uid)
+	banInfo, ok := ctx.Dice.Config.BanList.GetByIDLinked(uid)
 	if ok {
 		if banInfo.Rank == BanRankBanned && ctx.Dice.Config.BanList.BanBehaviorRefuseInvite {
 			if willAccept {
@@ -1056,6 +1056,15 @@ func (pa *PlatformAdapterMilky) MemberBan(_ string, _ string, _ int64) {}
 
 func (pa *PlatformAdapterMilky) MemberKick(_ string, _ string) {}
 
+func (pa *PlatformAdapterMilky) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		QuitGroup:      true,
+		ForwardMessage: true,
+		SendFile:       true,
+		RichElements:   richElements(message.Text, message.At, message.Image, message.Record, message.Reply, message.Poke),
+	}
+}
+
 func (pa *PlatformAdapterMilky) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterMilky) RecallMessage(_ *MsgContext, _ string) {}
@@ -194,10 +194,14 @@ func (pa *PlatformAdapterMinecraft) SetEnable(enable bool) {
 	}
 }
 
+// SendSegmentToGroup 平台只支持纯文本，消息段转为文本发送
 func (pa *PlatformAdapterMinecraft) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	pa.SendToGroup(ctx, groupID, message.ElementsToText(msg), flag)
 }
 
+// SendSegmentToPerson 平台只支持纯文本，消息段转为文本发送
 func (pa *PlatformAdapterMinecraft) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	pa.SendToPerson(ctx, userID, message.ElementsToText(msg), flag)
 }
 
 func (pa *PlatformAdapterMinecraft) SendToPerson(ctx *MsgContext, uid string, text string, flag string) {
@@ -263,6 +267,12 @@ func (pa *PlatformAdapterMinecraft) QuitGroup(_ *MsgContext, _ string) {}
 
 func (pa *PlatformAdapterMinecraft) SetGroupCardName(_ *MsgContext, _ string) {}
 
+func (pa *PlatformAdapterMinecraft) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		RichElements: richElements(message.Text),
+	}
+}
+
 func (pa *PlatformAdapterMinecraft) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterMinecraft) RecallMessage(_ *MsgContext, _ string) {}
@@ -1338,13 +1338,40 @@ func (pa *PlatformAdapterOfficialQQ) SetEnable(enable bool) {
 	d.LastUpdatedTime = time.Now().Unix()
 }
 
+// officialQQSupportedElements 各类会话可以原生发送的消息段
+func officialQQSupportedElements(idType OpenQQIDType) []message.ElementType {
+	switch idType {
+	case OpenQQGroupOpenid:
+		return []message.ElementType{message.Text, message.At, message.Reply, message.Image, message.Record}
+	case OpenQQUserOpenid:
+		return []message.ElementType{message.Text, message.Reply, message.Image, message.Record}
+	case OpenQQCHChannel:
+		return []message.ElementType{message.Text, message.At, message.Reply}
+	default:
+		return []message.ElementType{message.Text, message.Reply}
+	}
+}
+
+// SendSegmentToGroup 不支持的消息段转为文本后还原为 CQ 码，复用文本发送流程中的分页和主动消息限流
 func (pa *PlatformAdapterOfficialQQ) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	_, idType := pa.mustExtractID(groupID)
+	msg = message.DegradeElements(msg, officialQQSupportedElements(idType)...)
+	pa.sendToGroup(ctx, groupID, message.ToCQCode(msg), flag, message.WithCQTextUnescape())
 }
 
+// SendSegmentToPerson 同 SendSegmentToGroup
 func (pa *PlatformAdapterOfficialQQ) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	_, idType := pa.mustExtractID(userID)
+	msg = message.DegradeElements(msg, officialQQSupportedElements(idType)...)
+	pa.sendToPerson(ctx, userID, message.ToCQCode(msg), flag, message.WithCQTextUnescape())
 }
 
 func (pa *PlatformAdapterOfficialQQ) SendToPerson(ctx *MsgContext, uid string, text string, flag string) {
+	pa.sendToPerson(ctx, uid, text, flag)
+}
+
+// sendToPerson opts 用于解析文本中的 CQ 码，来自消息段时需要还原文本的转义
+func (pa *PlatformAdapterOfficialQQ) sendToPerson(ctx *MsgContext, uid string, text string, flag string, opts ...message.ConvertOption) {
 	userID, idType := pa.mustExtractID(uid)
 
 	maxLen := 900
@@ -1381,7 +1408,7 @@ func (pa *PlatformAdapterOfficialQQ) SendToPerson(ctx *MsgContext, uid string, t
 		keyboardObj := pa.buildPaginationKeyboard(cacheID, 0, len(textList))
 
 		if idType == OpenQQUserOpenid {
-			msg, err := pa.sendC2CMsgRaw(activeCtx, activeRowID, userID, textList[0], keyboardObj)
+			msg, err := pa.sendC2CMsgRaw(activeCtx, activeRowID, userID, textList[0], keyboardObj, opts...)
 			if err == nil && msg != nil {
 				pa.EndPoint.Session.OnMessageSend(ctx, &Message{
 					Platform:    "QQ",
@@ -1421,7 +1448,7 @@ func (pa *PlatformAdapterOfficialQQ) SendToPerson(ctx *MsgContext, uid string, t
 			guildID = g
 			channelID = c
 		}
-		msg, err := pa.sendQQGuildDirectMsgRaw(ctx, rowID, guildID, channelID, textList[0], keyboardObj)
+		msg, err := pa.sendQQGuildDirectMsgRaw(ctx, rowID, guildID, channelID, textList[0], keyboardObj, opts...)
 		if err == nil && msg != nil {
 			pa.EndPoint.Session.OnMessageSend(ctx, &Message{
 				Platform:    "QQ",
@@ -1439,7 +1466,7 @@ func (pa *PlatformAdapterOfficialQQ) SendToPerson(ctx *MsgContext, uid string, t
 
 	for _, t := range textList {
 		if idType == OpenQQUserOpenid {
-			msg, err := pa.sendC2CMsgRaw(activeCtx, activeRowID, userID, t, nil)
+			msg, err := pa.sendC2CMsgRaw(activeCtx, activeRowID, userID, t, nil, opts...)
 			if err == nil && msg != nil {
 				pa.EndPoint.Session.OnMessageSend(ctx, &Message{
 					Platform:    "QQ",
@@ -1480,7 +1507,7 @@ func (pa *PlatformAdapterOfficialQQ) SendToPerson(ctx *MsgContext, uid string, t
 			guildID = g
 			channelID = c
 		}
-		msg, err := pa.sendQQGuildDirectMsgRaw(ctx, rowID, guildID, channelID, t, nil)
+		msg, err := pa.sendQQGuildDirectMsgRaw(ctx, rowID, guildID, channelID, t, nil, opts...)
 		if err == nil && msg != nil {
 			pa.EndPoint.Session.OnMessageSend(ctx, &Message{
 				Platform:    "QQ",
@@ -1568,9 +1595,9 @@ func (pa *PlatformAdapterOfficialQQ) prepareMediaMessage(file *message.FileEleme
 	return url, nil, nil
 }
 
-func (pa *PlatformAdapterOfficialQQ) sendQQGuildDirectMsgRaw( /* ctx */ _ *MsgContext, rowMsgID string, guildID, channelID string, text string, keyboardObj *keyboard.MessageKeyboard) (*dto.Message, error) {
+func (pa *PlatformAdapterOfficialQQ) sendQQGuildDirectMsgRaw( /* ctx */ _ *MsgContext, rowMsgID string, guildID, channelID string, text string, keyboardObj *keyboard.MessageKeyboard, opts ...message.ConvertOption) (*dto.Message, error) {
 	qctx := context.Background()
-	elems := message.ConvertStringMessage(text)
+	elems := message.ConvertStringMessage(text, opts...)
 	var (
 		content string
 		msgRef  *dto.MessageReference
@@ -1657,9 +1684,9 @@ func (pa *PlatformAdapterOfficialQQ) uploadGroupMedia(qctx context.Context, grou
 }
 
 // sendC2CMsgRaw 发送单聊消息（使用msg_id被动回复）
-func (pa *PlatformAdapterOfficialQQ) sendC2CMsgRaw(ctx *MsgContext, rowMsgID, userOpenID string, text string, keyboardObj *keyboard.MessageKeyboard) (*dto.Message, error) {
+func (pa *PlatformAdapterOfficialQQ) sendC2CMsgRaw(ctx *MsgContext, rowMsgID, userOpenID string, text string, keyboardObj *keyboard.MessageKeyboard, opts ...message.ConvertOption) (*dto.Message, error) {
 	qctx := context.Background()
-	elems := message.ConvertStringMessage(text)
+	elems := message.ConvertStringMessage(text, opts...)
 	var (
 		content string
 		msgRef  *dto.MessageReference
@@ -1882,6 +1909,11 @@ func (pa *PlatformAdapterOfficialQQ) waitGroupActiveQuota(ctx context.Context, g
 }
 
 func (pa *PlatformAdapterOfficialQQ) SendToGroup(ctx *MsgContext, uid string, text string, flag string) {
+	pa.sendToGroup(ctx, uid, text, flag)
+}
+
+// sendToGroup 同 sendToPerson
+func (pa *PlatformAdapterOfficialQQ) sendToGroup(ctx *MsgContext, uid string, text string, flag string, opts ...message.ConvertOption) {
 	groupId, idType := pa.mustExtractID(uid)
 
 	maxLen := 900
@@ -1919,7 +1951,7 @@ func (pa *PlatformAdapterOfficialQQ) SendToGroup(ctx *MsgContext, uid string, te
 
 		switch idType {
 		case OpenQQGroupOpenid:
-			msg, err := pa.sendQQGroupMsgRaw(activeCtx, activeRowID, groupId, textList[0], keyboardObj)
+			msg, err := pa.sendQQGroupMsgRaw(activeCtx, activeRowID, groupId, textList[0], keyboardObj, opts...)
 			if err == nil && msg != nil {
 				pa.EndPoint.Session.OnMessageSend(ctx, &Message{
 					Platform:    "QQ",
@@ -1934,7 +1966,7 @@ func (pa *PlatformAdapterOfficialQQ) SendToGroup(ctx *MsgContext, uid string, te
 				}, flag)
 			}
 		case OpenQQCHChannel:
-			msg, err := pa.sendQQChannelMsgRaw(activeCtx, activeRowID, groupId, textList[0], keyboardObj)
+			msg, err := pa.sendQQChannelMsgRaw(activeCtx, activeRowID, groupId, textList[0], keyboardObj, opts...)
 			if err == nil && msg != nil {
 				pa.EndPoint.Session.OnMessageSend(ctx, &Message{
 					Platform:    "QQ",
@@ -1957,7 +1989,7 @@ func (pa *PlatformAdapterOfficialQQ) SendToGroup(ctx *MsgContext, uid string, te
 	for _, t := range textList {
 		switch idType {
 		case OpenQQGroupOpenid:
-			msg, err := pa.sendQQGroupMsgRaw(activeCtx, activeRowID, groupId, t, nil)
+			msg, err := pa.sendQQGroupMsgRaw(activeCtx, activeRowID, groupId, t, nil, opts...)
 			if err == nil && msg != nil {
 				pa.EndPoint.Session.OnMessageSend(ctx, &Message{
 					Platform:    "QQ",
@@ -1972,7 +2004,7 @@ func (pa *PlatformAdapterOfficialQQ) SendToGroup(ctx *MsgContext, uid string, te
 				}, flag)
 			}
 		case OpenQQCHChannel:
-			msg, err := pa.sendQQChannelMsgRaw(activeCtx, activeRowID, groupId, t, nil)
+			msg, err := pa.sendQQChannelMsgRaw(activeCtx, activeRowID, groupId, t, nil, opts...)
 			if err == nil && msg != nil {
 				pa.EndPoint.Session.OnMessageSend(ctx, &Message{
 					Platform:    "QQ",
@@ -1993,9 +2025,9 @@ func (pa *PlatformAdapterOfficialQQ) SendToGroup(ctx *MsgContext, uid string, te
 	}
 }
 
-func (pa *PlatformAdapterOfficialQQ) sendQQGroupMsgRaw(ctx *MsgContext, rowMsgID, groupID string, text string, keyboardObj *keyboard.MessageKeyboard) (*dto.Message, error) {
+func (pa *PlatformAdapterOfficialQQ) sendQQGroupMsgRaw(ctx *MsgContext, rowMsgID, groupID string, text string, keyboardObj *keyboard.MessageKeyboard, opts ...message.ConvertOption) (*dto.Message, error) {
 	qctx := context.Background()
-	elems := message.ConvertStringMessage(text)
+	elems := message.ConvertStringMessage(text, opts...)
 	var (
 		content string
 		msgRef  *dto.MessageReference
@@ -2082,9 +2114,9 @@ func (pa *PlatformAdapterOfficialQQ) sendQQGroupMsgRaw(ctx *MsgContext, rowMsgID
 	return lastRes, lastErr
 }
 
-func (pa *PlatformAdapterOfficialQQ) sendQQChannelMsgRaw( /* ctx */ _ *MsgContext, rowMsgID, channelID string, text string, keyboardObj *keyboard.MessageKeyboard) (*dto.Message, error) {
+func (pa *PlatformAdapterOfficialQQ) sendQQChannelMsgRaw( /* ctx */ _ *MsgContext, rowMsgID, channelID string, text string, keyboardObj *keyboard.MessageKeyboard, opts ...message.ConvertOption) (*dto.Message, error) {
 	qctx := context.Background()
-	elems := message.ConvertStringMessage(text)
+	elems := message.ConvertStringMessage(text, opts...)
 	var (
 		content string
 		msgRef  *dto.MessageReference
@@ -2296,6 +2328,13 @@ func (pa *PlatformAdapterOfficialQQ) MemberKick(_ string, _ string) {
 	pa.EndPoint.Session.Parent.Logger.Error("official qq 踢出用户失败：不支持该功能")
 }
 
+func (pa *PlatformAdapterOfficialQQ) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		MaxMessageLength: 2800,
+		RichElements:     richElements(officialQQSupportedElements(OpenQQGroupOpenid)...),
+	}
+}
+
 func (pa *PlatformAdapterOfficialQQ) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterOfficialQQ) RecallMessage(_ *MsgContext, _ string) {}
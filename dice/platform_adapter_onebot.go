@@ -517,6 +517,14 @@ func (p *PlatformAdapterOnebot) GetGroupCacheInfo(groupID string) *GroupCache {
 
 // 废弃代码
 
+func (p *PlatformAdapterOnebot) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		QuitGroup:    true,
+		SendFile:     true,
+		RichElements: richElements(message.Text, message.At, message.Image, message.File, message.Record, message.Reply, message.Face, message.Poke),
+	}
+}
+
 func (p *PlatformAdapterOnebot) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (p *PlatformAdapterOnebot) RecallMessage(_ *MsgContext, _ string) {
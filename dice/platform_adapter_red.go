@@ -532,9 +532,71 @@ func (pa *PlatformAdapterRed) QuitGroup(_ *MsgContext, id string) {
 }
 
 func (pa *PlatformAdapterRed) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	rowId, chatType := pa.mustExtractId(groupID)
+	if chatType != GroupChat {
+		return
+	}
+
+	if groupInfo, ok := ctx.Session.ServiceAtNew.Load(groupID); ok {
+		msgToSend := &Message{
+			Segment:     msg,
+			MessageType: "group",
+			Platform:    pa.EndPoint.Platform,
+			GroupID:     groupID,
+			Sender: SenderBase{
+				Nickname: pa.EndPoint.Nickname,
+				UserID:   pa.EndPoint.UserID,
+			},
+		}
+		groupInfo.TriggerExtHook(ctx.Dice, func(ext *ExtInfo) func() {
+			if ext.OnMessageSend == nil {
+				return nil
+			}
+			return func() { ext.OnMessageSend(ctx, msgToSend, flag) }
+		})
+	}
+
+	doSleepQQ(ctx)
+	pa.sendRow(&RedMessageSend{
+		Peer: &RedPeer{
+			ChatType: GroupChat,
+			PeerUin:  strconv.FormatInt(rowId, 10),
+		},
+		Elements: pa.encodeElements(ctx, msg),
+	})
 }
 
 func (pa *PlatformAdapterRed) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	rowId, chatType := pa.mustExtractId(userID)
+	if chatType != PersonChat {
+		return
+	}
+
+	for _, i := range ctx.Dice.ExtList {
+		if i.OnMessageSend != nil {
+			i.callWithJsCheck(ctx.Dice, func() {
+				i.OnMessageSend(ctx, &Message{
+					Segment:     msg,
+					MessageType: "private",
+					Platform:    pa.EndPoint.Platform,
+					Sender: SenderBase{
+						Nickname: pa.EndPoint.Nickname,
+						UserID:   pa.EndPoint.UserID,
+					},
+				},
+					flag)
+			})
+		}
+	}
+
+	doSleepQQ(ctx)
+	pa.sendRow(&RedMessageSend{
+		Peer: &RedPeer{
+			ChatType: PersonChat,
+			PeerUin:  strconv.FormatInt(rowId, 10),
+		},
+		Elements: pa.encodeElements(ctx, msg),
+	})
 }
 
 func (pa *PlatformAdapterRed) SendToPerson(ctx *MsgContext, uid string, text string, flag string) {
@@ -659,6 +721,12 @@ func (pa *PlatformAdapterRed) MemberBan(_ string, _ string, _ int64) {}
 
 func (pa *PlatformAdapterRed) MemberKick(_ string, _ string) {}
 
+func (pa *PlatformAdapterRed) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		RichElements: richElements(message.Text, message.At, message.Image, message.File),
+	}
+}
+
 func (pa *PlatformAdapterRed) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterRed) RecallMessage(_ *MsgContext, _ string) {}
@@ -881,27 +949,49 @@ func (pa *PlatformAdapterRed) httpDo(method, action string, headers map[string]s
 }
 
 // encodeMessage 将带 cq code 的内容转换为 red 所需的格式
-func (pa *PlatformAdapterRed) encodeMessage( /* ctx */ _ *MsgContext, content string) []*RedElement {
-	elems := message.ConvertStringMessage(content)
+func (pa *PlatformAdapterRed) encodeMessage(ctx *MsgContext, content string) []*RedElement {
+	return pa.encodeElements(ctx, message.ConvertStringMessage(content))
+}
+
+// encodeElements 将消息段转为 red 消息元素，不支持的消息段以文本形式发出
+func (pa *PlatformAdapterRed) encodeElements( /* ctx */ _ *MsgContext, elems []message.IMessageElement) []*RedElement {
 	var redElems []*RedElement
+	appendText := func(content string) {
+		if content == "" {
+			return
+		}
+		redElems = append(redElems, &RedElement{
+			ElementType: 1,
+			TextElement: &RedTextElement{Content: content},
+		})
+	}
 	for _, elem := range elems {
 		switch e := elem.(type) {
 		case *message.TextElement:
-			redElems = append(redElems, &RedElement{
-				ElementType: 1,
-				TextElement: &RedTextElement{Content: e.Content},
-			})
+			appendText(e.Content)
 		case *message.AtElement:
+			if e.Target == "all" {
+				redElems = append(redElems, &RedElement{
+					ElementType: 1,
+					TextElement: &RedTextElement{AtType: 1, Content: "@全体成员"},
+				})
+				continue
+			}
+			target := UserIDExtract(e.Target)
 			redElems = append(redElems, &RedElement{
 				ElementType: 1,
 				TextElement: &RedTextElement{
 					AtType:  2,
-					AtNtUin: e.Target,
-					Content: fmt.Sprintf("@%s", e.Target),
+					AtNtUin: target,
+					Content: fmt.Sprintf("@%s", target),
 				},
 			})
 		case *message.ImageElement:
 			fi := e.File
+			if fi == nil || fi.Stream == nil {
+				appendText(message.FallbackText(e))
+				continue
+			}
 			resp := pa.uploadFile(fi.File, fi.Stream)
 			redElem := RedElement{
 				ElementType: 2,
@@ -919,6 +1009,10 @@ func (pa *PlatformAdapterRed) encodeMessage( /* ctx */ _ *MsgContext, content st
 			}
 			redElems = append(redElems, &redElem)
 		case *message.FileElement:
+			if e.Stream == nil {
+				appendText(message.FallbackText(e))
+				continue
+			}
 			resp := pa.uploadFile(e.File, e.Stream)
 			redElems = append(redElems, &RedElement{
 				ElementType: 3,
@@ -938,6 +1032,8 @@ func (pa *PlatformAdapterRed) encodeMessage( /* ctx */ _ *MsgContext, content st
 					ThumbFileSize: 750,
 				},
 			})
+		default:
+			appendText(message.FallbackText(e))
 		}
 	}
 	return redElems
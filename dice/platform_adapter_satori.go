@@ -429,21 +429,22 @@ func (pa *PlatformAdapterSatori) SendToPerson(ctx *MsgContext, userID string, te
 	log := pa.EndPoint.Session.Parent.Logger
 	if pa.Platform == "QQ" {
 		id := UserIDExtract(userID)
-		pa.sendMsgRaw(ctx, "private:"+id, text, flag, "private")
+		_, _ = pa.sendMsgRaw(ctx, "private:"+id, pa.encodeMessage(text), flag, "private")
 	} else {
 		log.Errorf("satori %s 平台暂不支持私聊消息发送", pa.Platform)
 	}
 }
 
 func (pa *PlatformAdapterSatori) SendToGroup(ctx *MsgContext, groupID string, text string, flag string) {
-	pa.sendMsgRaw(ctx, UserIDExtract(groupID), text, flag, "group")
+	_, _ = pa.sendMsgRaw(ctx, UserIDExtract(groupID), pa.encodeMessage(text), flag, "group")
 }
 
-func (pa *PlatformAdapterSatori) sendMsgRaw( /* ctx */ _ *MsgContext, channelID string, text string /* flag */, _ string, msgType string) {
+// sendMsgRaw 发送已编码的消息，返回最后一条消息的ID
+func (pa *PlatformAdapterSatori) sendMsgRaw( /* ctx */ _ *MsgContext, channelID string, content string /* flag */, _ string, msgType string) (string, error) {
 	log := pa.EndPoint.Session.Parent.Logger
 	req, err := json.Marshal(map[string]interface{}{
 		"channel_id": channelID,
-		"content":    pa.encodeMessage(text),
+		"content":    content,
 	})
 	var msgTypeStr string
 	if msgType == "private" {
@@ -453,25 +454,63 @@ func (pa *PlatformAdapterSatori) sendMsgRaw( /* ctx */ _ *MsgContext, channelID
 	}
 	if err != nil {
 		log.Errorf("satori 发送%s(%s)消息失败: %s", msgTypeStr, channelID, err)
-		return
+		return "", err
 	}
 	data, err := pa.post("message.create", bytes.NewBuffer(req))
 	if err != nil {
 		log.Errorf("satori 发送%s(%s)消息失败: %s", msgTypeStr, channelID, err)
-		return
+		return "", err
 	}
 	var messages []SatoriMessage
 	err = json.Unmarshal(data, &messages)
 	if err != nil {
 		log.Errorf("satori 发送%s(%s)消息失败: %s", msgTypeStr, channelID, err)
-		return
+		return "", err
+	}
+	if len(messages) == 0 {
+		return "", nil
 	}
+	return messages[len(messages)-1].ID, nil
 }
 
 func (pa *PlatformAdapterSatori) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	rawID, err := pa.sendMsgRaw(ctx, UserIDExtract(groupID), pa.encodeElements(msg), flag, "group")
+	if err != nil {
+		return
+	}
+	pa.EndPoint.Session.OnMessageSend(ctx, &Message{
+		Platform:    pa.Platform,
+		MessageType: "group",
+		Segment:     msg,
+		GroupID:     groupID,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: rawID,
+	}, flag)
 }
 
 func (pa *PlatformAdapterSatori) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	log := pa.EndPoint.Session.Parent.Logger
+	if pa.Platform != "QQ" {
+		log.Errorf("satori %s 平台暂不支持私聊消息发送", pa.Platform)
+		return
+	}
+	rawID, err := pa.sendMsgRaw(ctx, "private:"+UserIDExtract(userID), pa.encodeElements(msg), flag, "private")
+	if err != nil {
+		return
+	}
+	pa.EndPoint.Session.OnMessageSend(ctx, &Message{
+		Platform:    pa.Platform,
+		MessageType: "private",
+		Segment:     msg,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: rawID,
+	}, flag)
 }
 
 func (pa *PlatformAdapterSatori) SetGroupCardName(ctx *MsgContext, name string) {
@@ -534,6 +573,13 @@ func (pa *PlatformAdapterSatori) GetGroupInfoAsync(groupID string) {
 	go pa.refreshGroups()
 }
 
+func (pa *PlatformAdapterSatori) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		MemberKick:   true,
+		RichElements: richElements(message.Text, message.At, message.Image, message.Record, message.Reply),
+	}
+}
+
 func (pa *PlatformAdapterSatori) EditMessage(ctx *MsgContext, msgID, message string) {
 	log := pa.EndPoint.Session.Parent.Logger
 	log.Errorf("satori %s 平台暂不支持编辑消息", pa.Platform)
@@ -649,8 +695,55 @@ func decodeMessage(text string) string {
 }
 
 func (pa *PlatformAdapterSatori) encodeMessage(content string) string {
-	elems := message.ConvertStringMessage(content)
+	return pa.encodeElements(message.ConvertStringMessage(content))
+}
+
+// satoriResourceSrc 资源消息段的 src，本地流写入临时文件后以 file URL 引用
+func satoriResourceSrc(file *message.FileElement, fallbackURL string) string {
+	if file == nil {
+		return fallbackURL
+	}
+	if file.Stream != nil {
+		fileName := file.File
+		if fileName == "" {
+			fileName = "temp"
+		}
+		temp, err := os.CreateTemp("", fileName)
+		if err != nil {
+			return ""
+		}
+		defer func(temp *os.File) {
+			_ = temp.Close()
+		}(temp)
+		if _, err = io.Copy(temp, file.Stream); err != nil {
+			return ""
+		}
+		return "file:///" + temp.Name()
+	}
+	if fallbackURL != "" {
+		return fallbackURL
+	}
+	return file.URL
+}
+
+// encodeElements 将消息段编码为 satori 消息元素，不支持的消息段以文本形式发出
+func (pa *PlatformAdapterSatori) encodeElements(elems []message.IMessageElement) string {
 	var msg strings.Builder
+	writeResource := func(tag string, file *message.FileElement, fallbackURL string, fallback string) {
+		src := satoriResourceSrc(file, fallbackURL)
+		if src == "" {
+			msg.WriteString(satori.ContentEscape(fallback))
+			return
+		}
+		node := &satori.Element{
+			Type:  tag,
+			Attrs: satori.Dict{"src": src},
+		}
+		if file != nil && file.File != "" {
+			node.Attrs["title"] = file.File
+		}
+		msg.WriteString(node.ToString())
+	}
 	for _, elem := range elems {
 		switch e := elem.(type) {
 		case *message.TextElement:
@@ -659,56 +752,23 @@ func (pa *PlatformAdapterSatori) encodeMessage(content string) string {
 			if e.Target == "all" {
 				msg.WriteString(`<at type="all"/>`)
 			} else {
-				_, _ = fmt.Fprintf(&msg, `<at id="%s"/>`, e.Target)
+				_, _ = fmt.Fprintf(&msg, `<at id="%s"/>`, UserIDExtract(e.Target))
 			}
-		case *message.FileElement:
+		case *message.ReplyElement:
 			node := &satori.Element{
-				// Type:  "file",
-				Attrs: make(satori.Dict),
-			}
-			if e.File != "" {
-				node.Attrs["title"] = e.File
-			}
-			if e.Stream != nil {
-				fileName := e.File
-				if fileName == "" {
-					fileName = "temp"
-				}
-				temp, _ := os.CreateTemp("", fileName)
-				_, err := io.Copy(temp, e.Stream)
-				if err != nil {
-					continue
-				}
-				node.Attrs["src"] = "file:///" + temp.Name()
-			} else if e.URL != "" {
-				node.Attrs["src"] = e.URL
-			}
-			// cc 0.2.2 发送 file QQ 会直接爆炸
-			// msg.WriteString(node.ToString())
-		case *message.ImageElement:
-			file := e.File
-			node := &satori.Element{
-				Type:  "img",
-				Attrs: make(satori.Dict),
-			}
-			if file.File != "" {
-				node.Attrs["title"] = file.File
-			}
-			if file.Stream != nil {
-				fileName := file.File
-				if fileName == "" {
-					fileName = "temp"
-				}
-				temp, _ := os.CreateTemp("", fileName)
-				_, err := io.Copy(temp, file.Stream)
-				if err != nil {
-					continue
-				}
-				node.Attrs["src"] = "file:///" + temp.Name()
-			} else if e.File.URL != "" {
-				node.Attrs["src"] = e.File.URL
+				Type:  "quote",
+				Attrs: satori.Dict{"id": e.ReplySeq},
 			}
 			msg.WriteString(node.ToString())
+		case *message.ImageElement:
+			writeResource("img", e.File, e.URL, message.FallbackText(e))
+		case *message.RecordElement:
+			writeResource("audio", e.File, "", message.FallbackText(e))
+		case *message.FileElement:
+			// cc 0.2.2 发送 file QQ 会直接爆炸，这里只发出文件名
+			msg.WriteString(satori.ContentEscape(message.FallbackText(e)))
+		default:
+			msg.WriteString(satori.ContentEscape(message.FallbackText(e)))
 		}
 	}
 	result := msg.String()
@@ -809,7 +869,7 @@ func (pa *PlatformAdapterSatori) guildRequestHandle(e *SatoriEvent) {
 
 	eid := e.ID.String()
 	// 邀请人在黑名单上
-	banInfo, ok := d.Config.BanList.GetByID(uid)
+	banInfo, ok := d.Config.BanList.GetByIDLinked(uid)
 	if ok {
 		if banInfo.Rank == BanRankBanned && d.Config.BanList.BanBehaviorRefuseInvite {
 			pa.sendGuildRequestResult(eid, false, "黑名单")
@@ -870,7 +930,7 @@ func (pa *PlatformAdapterSatori) friendRequestHandle(e *SatoriEvent) {
 
 	eid := e.ID.String()
 	// 申请人在黑名单上
-	banInfo, ok := d.Config.BanList.GetByID(uid)
+	banInfo, ok := d.Config.BanList.GetByIDLinked(uid)
 	if ok {
 		if banInfo.Rank == BanRankBanned && d.Config.BanList.BanBehaviorRefuseInvite {
 			pa.sendGuildRequestResult(eid, false, "为被禁止用户，准备自动拒绝")
@@ -681,6 +681,12 @@ func (pa *PlatformAdapterSealChat) SetGroupCardName(mctx *MsgContext, text strin
 	})
 }
 
+func (pa *PlatformAdapterSealChat) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		RichElements: richElements(message.Text, message.At, message.Image, message.Reply),
+	}
+}
+
 func (pa *PlatformAdapterSealChat) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterSealChat) RecallMessage(_ *MsgContext, _ string) {}
@@ -743,7 +743,7 @@ func (pa *PlatformAdapterSealChat) getCharIdFromRequest(d *Dice, dataMap map[str
 			return "", "", fmt.Errorf("character not found: %s", id)
 		}
 		// 安全检查：验证归属用户和类型
-		if item.OwnerId != formattedUserID || item.AttrsType != service.AttrsTypeCharacter {
+		if item.OwnerId != d.AttrsManager.UIDConvert(formattedUserID) || item.AttrsType != service.AttrsTypeCharacter {
 			return "", "", errors.New("character not owned by user or invalid type")
 		}
 		return id, item.Name, nil
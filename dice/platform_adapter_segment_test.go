@@ -0,0 +1,58 @@
+//nolint:testpackage
+package dice
+
+import (
+	"encoding/json"
+	"testing"
+
+	"sealdice-core/message"
+)
+
+func TestAdapterSegmentEncoding(t *testing.T) {
+	elems := []message.IMessageElement{
+		&message.ReplyElement{ReplySeq: "42"},
+		&message.AtElement{Target: "QQ:10001"},
+		&message.TextElement{Content: " a<b"},
+		&message.FaceElement{FaceID: "1"},
+		&message.ImageElement{URL: "https://example.com/a.png"},
+	}
+
+	if got := (&PlatformAdapterSatori{}).encodeElements(elems); got != `<quote id="42"></quote><at id="10001"/> a&lt;b[表情:1]<img src="https://example.com/a.png"></img>` {
+		t.Errorf("unexpected satori content %q", got)
+	}
+	if got := encodeSlackElements(elems); got != "<@10001> a&lt;b[表情:1][图片:https://example.com/a.png]" {
+		t.Errorf("unexpected slack text %q", got)
+	}
+
+	segs := (&PlatformAdapterWalleQ{}).ElementsToMessageSegment(elems)
+	if len(segs) != 4 || segs[0].Type != "reply" || segs[1].Data.UserID != "10001" ||
+		segs[2].Type != "text" || segs[2].Data.Text != " a<b[表情:1]" || segs[3].Data.URL != "https://example.com/a.png" {
+		t.Errorf("unexpected walleq segments %+v", segs)
+	}
+}
+
+func TestEndPointCapabilities(t *testing.T) {
+	ep := &EndPointInfo{EndPointInfoBase: EndPointInfoBase{ID: "1", Platform: "QQ"}, Adapter: &PlatformAdapterMilky{}}
+	caps := ep.Capabilities()
+	if !caps.ForwardMessage || !caps.SupportsElement(message.Poke) || caps.SupportsElement(message.File) {
+		t.Fatalf("unexpected milky capabilities %+v", caps)
+	}
+	if caps := (&EndPointInfo{}).Capabilities(); caps.QuitGroup || caps.SupportsElement(message.Text) {
+		t.Fatal("endpoint without adapter should report nothing")
+	}
+
+	data, err := json.Marshal(ep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		ID           string               `json:"id"`
+		Capabilities PlatformCapabilities `json:"capabilities"`
+	}
+	if err = json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.ID != "1" || !v.Capabilities.ForwardMessage || len(v.Capabilities.RichElements) == 0 {
+		t.Fatalf("capabilities should be serialized with the endpoint, got %s", data)
+	}
+}
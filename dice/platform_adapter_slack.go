@@ -211,9 +211,49 @@ func (pa *PlatformAdapterSlack) QuitGroup(ctx *MsgContext, id string) {
 }
 
 func (pa *PlatformAdapterSlack) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	pa.send(ctx, ExtractSlackChannelID(groupID), encodeSlackElements(msg), flag)
+	pa.EndPoint.Session.OnMessageSend(ctx, &Message{
+		MessageType: "group",
+		Platform:    "SLACK",
+		Segment:     msg,
+		GroupID:     groupID,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+	}, flag)
 }
 
 func (pa *PlatformAdapterSlack) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	pa.send(ctx, ExtractSlackUserID(userID), encodeSlackElements(msg), flag)
+	pa.EndPoint.Session.OnMessageSend(ctx, &Message{
+		MessageType: "private",
+		Platform:    "SLACK",
+		Segment:     msg,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+	}, flag)
+}
+
+// encodeSlackElements 将消息段编码为 Slack 的 mrkdwn 文本，@ 使用原生的提及语法，其余非文本消息段以文本形式发出
+func encodeSlackElements(elems []message.IMessageElement) string {
+	escaper := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	var sb strings.Builder
+	for _, elem := range elems {
+		switch e := elem.(type) {
+		case *message.AtElement:
+			if e.Target == "all" {
+				sb.WriteString("<!channel>")
+			} else {
+				_, _ = fmt.Fprintf(&sb, "<@%s>", UserIDExtract(e.Target))
+			}
+		default:
+			sb.WriteString(escaper.Replace(message.FallbackText(e)))
+		}
+	}
+	return sb.String()
 }
 
 func (pa *PlatformAdapterSlack) SendToPerson(ctx *MsgContext, userID string, text string, flag string) {
@@ -259,6 +299,13 @@ func (pa *PlatformAdapterSlack) GetGroupInfoAsync(groupId string) {
 	// TODO
 }
 
+func (pa *PlatformAdapterSlack) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		Markdown:     true,
+		RichElements: richElements(message.Text, message.At),
+	}
+}
+
 func (pa *PlatformAdapterSlack) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterSlack) RecallMessage(_ *MsgContext, _ string) {}
@@ -398,9 +398,38 @@ func (pa *PlatformAdapterTelegram) SetEnable(enable bool) {
 }
 
 func (pa *PlatformAdapterTelegram) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	resp, err := pa.sendElementsToChatRaw(ExtractTelegramGroupID(groupID), msg, true)
+	if err != nil {
+		return
+	}
+	pa.EndPoint.Session.OnMessageSend(ctx, &Message{
+		Platform:    "TG",
+		MessageType: "group",
+		Segment:     msg,
+		GroupID:     groupID,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: resp.MessageID,
+	}, flag)
 }
 
 func (pa *PlatformAdapterTelegram) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	resp, err := pa.sendElementsToChatRaw(ExtractTelegramUserID(userID), msg, true)
+	if err != nil {
+		return
+	}
+	pa.EndPoint.Session.OnMessageSend(ctx, &Message{
+		Platform:    "TG",
+		MessageType: "private",
+		Segment:     msg,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: resp.MessageID,
+	}, flag)
 }
 
 func (pa *PlatformAdapterTelegram) SendToPerson(ctx *MsgContext, userID string, text string, flag string) {
@@ -507,6 +536,15 @@ func (pa *PlatformAdapterTelegram) SendFileToGroup(ctx *MsgContext, uid string,
 	}, flag)
 }
 
+func (pa *PlatformAdapterTelegram) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		QuitGroup:        true,
+		SendFile:         true,
+		MaxMessageLength: 4096,
+		RichElements:     richElements(message.Text, message.At, message.Image, message.File, message.Record, message.Reply),
+	}
+}
+
 func (pa *PlatformAdapterTelegram) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterTelegram) RecallMessage(_ *MsgContext, _ string) {}
@@ -526,51 +564,106 @@ func (r *RequestFileDataImpl) SendData() string {
 	return r.File
 }
 func (pa *PlatformAdapterTelegram) SendToChatRaw(uid string, text string) (*tgbotapi.Message, error) {
+	return pa.sendElementsToChatRaw(uid, message.ConvertStringMessage(text), false)
+}
+
+// telegramRequestFile 将资源消息段转为可发送的文件，本地流优先，其次是网络地址
+func telegramRequestFile(f *message.FileElement, fallbackURL string) tgbotapi.RequestFileData {
+	if f != nil && f.Stream != nil {
+		return &RequestFileDataImpl{File: f.File, Reader: f.Stream}
+	}
+	u := fallbackURL
+	if u == "" && f != nil {
+		u = f.URL
+	}
+	if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		return tgbotapi.FileURL(u)
+	}
+	return nil
+}
+
+// sendElementsToChatRaw 发送消息段，图片、文件、语音会带上此前的文本作为说明分开发送。
+// withFiles 为 false 时文件和语音只以文本形式发出
+func (pa *PlatformAdapterTelegram) sendElementsToChatRaw(uid string, elem []message.IMessageElement, withFiles bool) (*tgbotapi.Message, error) {
 	bot := pa.IntentSession
 	id, _ := strconv.ParseInt(uid, 10, 64)
-	elem := message.ConvertStringMessage(text)
 	msg := tgbotapi.NewMessage(id, "")
+	var last *tgbotapi.Message
+	// 实体的偏移量和长度以 UTF-16 码元计
+	textLen := func() int {
+		return len(utf16.Encode([]rune(msg.Text)))
+	}
+	sendMedia := func(media tgbotapi.Chattable) error {
+		resp, err := bot.Send(media)
+		if err != nil {
+			return err
+		}
+		last = &resp
+		replyTo := msg.ReplyToMessageID
+		msg = tgbotapi.NewMessage(id, "")
+		msg.ReplyToMessageID = replyTo
+		return nil
+	}
 	var err error
 	for _, element := range elem {
 		switch e := element.(type) {
 		case *message.TextElement:
 			msg.Text += e.Content
 		case *message.AtElement:
-			leng := len(msg.Text)
-			uid, _ := strconv.ParseInt(e.Target, 10, 64)
+			target := ExtractTelegramUserID(e.Target)
+			uid, errParse := strconv.ParseInt(target, 10, 64)
+			if errParse != nil {
+				msg.Text += message.FallbackText(e) + " "
+				break
+			}
 			user := &tgbotapi.User{ID: uid}
-			data := fmt.Sprintf("@%s ", e.Target)
+			data := fmt.Sprintf("@%s ", target)
+			entity := tgbotapi.MessageEntity{Type: "text_mention", Offset: textLen(), Length: len(utf16.Encode([]rune(data))), User: user}
 			msg.Text += data
-			entity := tgbotapi.MessageEntity{Type: "text_mention", Offset: leng, Length: len(data), User: user}
 			msg.Entities = append(msg.Entities, entity)
-			// 安全性问题禁用
-		// case *message.FileElement:
-		//	if msg.Text != "" {
-		//		_, err = bot.Send(msg)
-		//	}
-		//	if err != nil {
-		//		pa.EndPoint.Session.Parent.Logger.Errorf("向Telegram聊天#%d发送消息时出错:%s", id, err)
-		//		return
-		//	}
-		//	msg = tgbotapi.NewMessage(id, "")
-		//	data := &RequestFileDataImpl{File: e.File, Reader: e.Stream}
-		//	f := tgbotapi.NewDocument(id, data)
-		//	_, err = bot.Send(f)
 		case *message.ImageElement:
-			fi := e.File
-			data := &RequestFileDataImpl{File: fi.File, Reader: fi.Stream}
+			data := telegramRequestFile(e.File, e.URL)
+			if data == nil {
+				msg.Text += message.FallbackText(e)
+				break
+			}
 			f := tgbotapi.NewPhoto(id, data)
-			if msg.Text != "" {
-				f.Caption = msg.Text
-				f.CaptionEntities = msg.Entities
+			f.Caption = msg.Text
+			f.CaptionEntities = msg.Entities
+			f.ReplyToMessageID = msg.ReplyToMessageID
+			if _, ok := data.(*RequestFileDataImpl); ok {
+				f.Thumb = data
 			}
-			msg = tgbotapi.NewMessage(id, "")
-			f.Thumb = data
-			_, err = bot.Send(f)
-			if err != nil {
-				pa.EndPoint.Session.Parent.Logger.Errorf("向Telegram聊天#%d发送消息时出错:%s", id, err)
-				return nil, err
+			err = sendMedia(f)
+		// 安全性问题，文本中的文件 CQ 码不发送，只有直接构造的消息段才允许发送文件
+		case *message.FileElement:
+			var data tgbotapi.RequestFileData
+			if withFiles {
+				data = telegramRequestFile(e, "")
 			}
+			if data == nil {
+				msg.Text += message.FallbackText(e)
+				break
+			}
+			f := tgbotapi.NewDocument(id, data)
+			f.Caption = msg.Text
+			f.CaptionEntities = msg.Entities
+			f.ReplyToMessageID = msg.ReplyToMessageID
+			err = sendMedia(f)
+		case *message.RecordElement:
+			var data tgbotapi.RequestFileData
+			if withFiles {
+				data = telegramRequestFile(e.File, "")
+			}
+			if data == nil {
+				msg.Text += message.FallbackText(e)
+				break
+			}
+			f := tgbotapi.NewAudio(id, data)
+			f.Caption = msg.Text
+			f.CaptionEntities = msg.Entities
+			f.ReplyToMessageID = msg.ReplyToMessageID
+			err = sendMedia(f)
 		case *message.TTSElement:
 			msg.Text += e.Content
 		case *message.ReplyElement:
@@ -580,6 +673,8 @@ func (pa *PlatformAdapterTelegram) SendToChatRaw(uid string, text string) (*tgbo
 				break
 			}
 			msg.ReplyToMessageID = int(parseInt)
+		default:
+			msg.Text += message.FallbackText(e)
 		}
 		if err != nil {
 			pa.EndPoint.Session.Parent.Logger.Errorf("向Telegram聊天#%d发送消息时出错:%s", id, err)
@@ -593,6 +688,10 @@ func (pa *PlatformAdapterTelegram) SendToChatRaw(uid string, text string) (*tgbo
 		}
 		return &resp, err
 	}
+	// 最后一段是图片等媒体时，文本已随媒体发出
+	if last != nil {
+		return last, nil
+	}
 	return nil, errors.New("empty message")
 }
 
@@ -405,7 +405,7 @@ func (pa *PlatformAdapterWalleQ) Serve() int {
 				if event.UserID == event.Self.UserID {
 					skip := false
 					skipReason := ""
-					banInfo, ok := ctx.Dice.Config.BanList.GetByID(opUID)
+					banInfo, ok := ctx.Dice.Config.BanList.GetByIDLinked(opUID)
 					if ok {
 						if banInfo.Rank == 30 {
 							skip = true
@@ -511,7 +511,7 @@ func (pa *PlatformAdapterWalleQ) Serve() int {
 				// 检查黑名单
 				extra := ""
 				uid := msg.Sender.UserID
-				banInfo, ok := ctx.Dice.Config.BanList.GetByID(uid)
+				banInfo, ok := ctx.Dice.Config.BanList.GetByIDLinked(uid)
 				if ok {
 					if banInfo.Rank == BanRankBanned && ctx.Dice.Config.BanList.BanBehaviorRefuseInvite {
 						if willAccept {
@@ -565,7 +565,7 @@ func (pa *PlatformAdapterWalleQ) Serve() int {
 				// tempInviteMap2[msg.GroupId] = uid
 
 				// 邀请人在黑名单上
-				banInfo, ok := ctx.Dice.Config.BanList.GetByID(uid)
+				banInfo, ok := ctx.Dice.Config.BanList.GetByIDLinked(uid)
 				if ok {
 					if banInfo.Rank == BanRankBanned && ctx.Dice.Config.BanList.BanBehaviorRefuseInvite {
 						pa.SetGroupAddRequest(req.RequestID, event.GroupID, false)
@@ -644,7 +644,7 @@ func (pa *PlatformAdapterWalleQ) Serve() int {
 
 					// 处理被强制拉群的情况
 					uid := groupInfo.InviteUserID
-					banInfo, ok := ctx.Dice.Config.BanList.GetByID(uid)
+					banInfo, ok := ctx.Dice.Config.BanList.GetByIDLinked(uid)
 					if ok {
 						if banInfo.Rank == BanRankBanned && ctx.Dice.Config.BanList.BanBehaviorRefuseInvite {
 							// 如果是被ban之后拉群，判定为强制拉群
@@ -799,9 +799,56 @@ func (pa *PlatformAdapterWalleQ) GetGroupInfoAsync(id string) {
 }
 
 func (pa *PlatformAdapterWalleQ) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	rawID, idType := pa.mustExtractID(groupID)
+	if idType != QQUidGroup {
+		return
+	}
+
+	if groupInfo, ok := ctx.Session.ServiceAtNew.Load(groupID); ok {
+		msgToSend := &Message{
+			Platform:    "QQ",
+			Segment:     msg,
+			MessageType: "group",
+			GroupID:     groupID,
+			Sender: SenderBase{
+				UserID:   pa.EndPoint.UserID,
+				Nickname: pa.EndPoint.Nickname,
+			},
+		}
+		groupInfo.TriggerExtHook(ctx.Dice, func(ext *ExtInfo) func() {
+			if ext.OnMessageSend == nil {
+				return nil
+			}
+			return func() { ext.OnMessageSend(ctx, msgToSend, flag) }
+		})
+	}
+
+	pa.sendMessageSegments(pa.ElementsToMessageSegment(msg), "group", rawID, "")
 }
 
 func (pa *PlatformAdapterWalleQ) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	rawID, idType := pa.mustExtractID(userID)
+	if idType != QQUidPerson {
+		return
+	}
+
+	for _, i := range ctx.Dice.ExtList {
+		if i.OnMessageSend != nil {
+			i.callWithJsCheck(ctx.Dice, func() {
+				i.OnMessageSend(ctx, &Message{
+					Platform:    "QQ",
+					Segment:     msg,
+					MessageType: "private",
+					Sender: SenderBase{
+						UserID:   pa.EndPoint.UserID,
+						Nickname: pa.EndPoint.Nickname,
+					},
+				}, flag)
+			})
+		}
+	}
+
+	pa.sendMessageSegments(pa.ElementsToMessageSegment(msg), "private", rawID, "")
 }
 
 func (pa *PlatformAdapterWalleQ) SendToPerson(ctx *MsgContext, userID string, text string, flag string) {
@@ -947,6 +994,16 @@ func (pa *PlatformAdapterWalleQ) MemberKick(groupID string, userID string) {
 	socketSendText(pa.Socket, string(a))
 }
 
+func (pa *PlatformAdapterWalleQ) Capabilities() PlatformCapabilities {
+	return PlatformCapabilities{
+		MemberBan:    true,
+		MemberKick:   true,
+		QuitGroup:    true,
+		DeleteFriend: true,
+		RichElements: richElements(message.Text, message.At, message.Image, message.Reply),
+	}
+}
+
 func (pa *PlatformAdapterWalleQ) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterWalleQ) RecallMessage(_ *MsgContext, _ string) {}
@@ -984,6 +1041,10 @@ func (pa *PlatformAdapterWalleQ) waitGroupMemberInfoEcho(echo string, beforeWait
 
 // SendMessage 原始的发消息 API
 func (pa *PlatformAdapterWalleQ) SendMessage(text string, ty string, id string, cid string) {
+	pa.sendMessageSegments(pa.TextToMessageSegment(text), ty, id, cid)
+}
+
+func (pa *PlatformAdapterWalleQ) sendMessageSegments(segments []MessageSegment, ty string, id string, cid string) {
 	type Params struct {
 		DetailType string           `json:"detail_type"`
 		GroupID    string           `json:"group_id,omitempty"`
@@ -1014,7 +1075,7 @@ func (pa *PlatformAdapterWalleQ) SendMessage(text string, ty string, id string,
 			UserID:     uid,
 			GuildID:    g2id,
 			ChannelID:  cid,
-			Message:    pa.TextToMessageSegment(text),
+			Message:    segments,
 		},
 	})
 	socketSendText(pa.Socket, string(a))
@@ -1253,6 +1314,49 @@ func (pa *PlatformAdapterWalleQ) TextToMessageSegment(text string) []MessageSegm
 	return m
 }
 
+// ElementsToMessageSegment 将消息段转为 OneBot 12 消息段，不支持的消息段以文本形式发出
+func (pa *PlatformAdapterWalleQ) ElementsToMessageSegment(elems []message.IMessageElement) []MessageSegment {
+	var m []MessageSegment
+	appendText := func(text string) {
+		if text == "" {
+			return
+		}
+		if n := len(m); n > 0 && m[n-1].Type == "text" {
+			m[n-1].Data.Text += text
+			return
+		}
+		m = append(m, MessageSegment{Type: "text", Data: MSData{Text: text}})
+	}
+	for _, elem := range elems {
+		switch e := elem.(type) {
+		case *message.TextElement:
+			appendText(e.Content)
+		case *message.AtElement:
+			if e.Target == "all" {
+				m = append(m, MessageSegment{Type: "mention_all", Data: MSData{}})
+			} else {
+				m = append(m, MessageSegment{Type: "mention", Data: MSData{UserID: UserIDExtract(e.Target)}})
+			}
+		case *message.ImageElement:
+			u := e.URL
+			if u == "" && e.File != nil {
+				u = e.File.URL
+			}
+			if u == "" {
+				appendText(message.FallbackText(e))
+				continue
+			}
+			m = append(m, MessageSegment{Type: "image", Data: MSData{URL: u}})
+		case *message.ReplyElement:
+			m = append(m, MessageSegment{Type: "reply", Data: MSData{MessageID: e.ReplySeq}})
+		default:
+			// walleq 依赖的 ricq 尚不支持发送文件和语音
+			appendText(message.FallbackText(e))
+		}
+	}
+	return m
+}
+
 func (event *EventWalleQBase) toMessageBase() *Message {
 	msg := new(Message)
 	msg.Time = int64(event.Time)
@@ -111,7 +111,9 @@ func (s *Sandbox) CheckFileReadPermission(path string) error {
 		return err
 	}
 
-	if !s.matchPathPatterns(relPath, s.Permissions.FileRead) {
+	// 包自己的用户数据目录总是可读
+	allowedPaths := append([]string{UserDataDir, UserDataDir + "/*"}, s.Permissions.FileRead...)
+	if !s.matchPathPatterns(relPath, allowedPaths) {
 		return &PermissionError{
 			PackageID:  s.PackageID,
 			Permission: "file_read",
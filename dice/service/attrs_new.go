@@ -24,14 +24,6 @@ const (
 
 // 注: 角色表有用sheet也有用sheets的，这里数据结构中使用sheet
 
-// TOOD: 下面这个表记得添加 unique 索引
-
-// PlatformMappingModel 虚拟ID - 平台用户ID 映射表
-type PlatformMappingModel struct {
-	Id       string `gorm:"column:id"         json:"id"`       // 虚拟ID，格式为 U:nanoid 意为 User / Uniform / Universal
-	IMUserID string `gorm:"column:im_user_id" json:"IMUserID"` // IM平台的用户ID
-}
-
 func AttrsGetById(operator engine2.DatabaseOperator, id string) (*model.AttributesItemModel, error) {
 	// 这里必须使用model.AttributesItemModel结构体，如果你定义一个只有ID属性的结构体去接收，居然能接收到值，这样就会豹错
 	db := operator.GetDataDB(constant.READ)
@@ -51,3 +51,125 @@ func BanItemList(operator engine2.DatabaseOperator, callback func(id string, ban
 	}
 	return nil // 操作成功，返回 nil
 }
+
+// BanEventAppend 写入一条黑名单事件
+func BanEventAppend(operator engine2.DatabaseOperator, event *model.BanEvent) error {
+	db := operator.GetDataDB(constant.WRITE)
+	return db.Create(event).Error
+}
+
+// QueryBanEvent 是黑名单事件分页查询的参数
+type QueryBanEvent struct {
+	PageNum  int    `query:"pageNum"`  // 当前页码
+	PageSize int    `query:"pageSize"` // 每页条数
+	TargetID string `query:"targetId"` // 用户或群组ID
+	Action   string `query:"action"`   // 事件类型
+}
+
+// BanEventGetPage 分页查询黑名单事件，按时间倒序
+func BanEventGetPage(operator engine2.DatabaseOperator, params QueryBanEvent) (int64, []model.BanEvent, error) {
+	db := operator.GetDataDB(constant.READ)
+	var total int64
+	var events []model.BanEvent
+
+	query := db.Model(&model.BanEvent{})
+	if params.TargetID != "" {
+		query = query.Where("target_id = ?", params.TargetID)
+	}
+	if params.Action != "" {
+		query = query.Where("action = ?", params.Action)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+	if err := query.
+		Order("created_at DESC, id DESC").
+		Limit(params.PageSize).
+		Offset((params.PageNum - 1) * params.PageSize).
+		Find(&events).
+		Error; err != nil {
+		return 0, nil, err
+	}
+	return total, events, nil
+}
+
+// BanAppealCreate 写入一条申诉，ID 回填到 appeal 中
+func BanAppealCreate(operator engine2.DatabaseOperator, appeal *model.BanAppeal) error {
+	db := operator.GetDataDB(constant.WRITE)
+	return db.Create(appeal).Error
+}
+
+// BanAppealGet 按 ID 读取申诉，不存在时返回 nil
+func BanAppealGet(operator engine2.DatabaseOperator, id uint64) (*model.BanAppeal, error) {
+	db := operator.GetDataDB(constant.READ)
+	var appeals []model.BanAppeal
+	if err := db.Where("id = ?", id).Limit(1).Find(&appeals).Error; err != nil {
+		return nil, err
+	}
+	if len(appeals) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+	return &appeals[0], nil
+}
+
+// BanAppealLatest 读取对象最近的一条申诉，不存在时返回 nil
+func BanAppealLatest(operator engine2.DatabaseOperator, targetID string) (*model.BanAppeal, error) {
+	db := operator.GetDataDB(constant.READ)
+	var appeals []model.BanAppeal
+	if err := db.Where("target_id = ?", targetID).Order("id DESC").Limit(1).Find(&appeals).Error; err != nil {
+		return nil, err
+	}
+	if len(appeals) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+	return &appeals[0], nil
+}
+
+// QueryBanAppeal 是申诉分页查询的参数
+type QueryBanAppeal struct {
+	PageNum  int    `query:"pageNum"`
+	PageSize int    `query:"pageSize"`
+	TargetID string `query:"targetId"`
+	Status   string `query:"status"`
+}
+
+// BanAppealGetPage 分页查询申诉，按时间倒序
+func BanAppealGetPage(operator engine2.DatabaseOperator, params QueryBanAppeal) (int64, []model.BanAppeal, error) {
+	db := operator.GetDataDB(constant.READ)
+	var total int64
+	var appeals []model.BanAppeal
+
+	query := db.Model(&model.BanAppeal{})
+	if params.TargetID != "" {
+		query = query.Where("target_id = ?", params.TargetID)
+	}
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+	if err := query.
+		Order("id DESC").
+		Limit(params.PageSize).
+		Offset((params.PageNum - 1) * params.PageSize).
+		Find(&appeals).
+		Error; err != nil {
+		return 0, nil, err
+	}
+	return total, appeals, nil
+}
+
+// BanAppealResolve 处理一条待处理的申诉，返回是否确实更新了记录
+func BanAppealResolve(operator engine2.DatabaseOperator, id uint64, status string, handledBy string, reply string, handledAt int64) (bool, error) {
+	db := operator.GetDataDB(constant.WRITE)
+	result := db.Model(&model.BanAppeal{}).
+		Where("id = ? AND status = ?", id, model.BanAppealStatusPending).
+		Updates(map[string]any{
+			"status":     status,
+			"handled_by": handledBy,
+			"handled_at": handledAt,
+			"reply":      reply,
+		})
+	return result.RowsAffected > 0, result.Error
+}
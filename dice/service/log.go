@@ -552,6 +552,18 @@ func LogDelete(operator engine2.DatabaseOperator, groupID string, logName string
 	return err
 }
 
+// LogItemIDs 返回日志中全部消息的ID
+func LogItemIDs(operator engine2.DatabaseOperator, groupID string, logName string) ([]uint64, error) {
+	db := operator.GetLogDB(constant.READ)
+	logID, err := getIDByGroupIDAndName(db, groupID, logName)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint64
+	err = db.Model(&model.LogOneItem{}).Where("log_id = ?", logID).Pluck("id", &ids).Error
+	return ids, err
+}
+
 // LogAppend 向指定的log中添加一条信息
 func LogAppend(operator engine2.DatabaseOperator, groupID string, logName string, logItem *model.LogOneItem) bool {
 	db := operator.GetLogDB(constant.WRITE)
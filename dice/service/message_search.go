@@ -0,0 +1,280 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	engine2 "sealdice-core/utils/dboperator/engine"
+)
+
+const (
+	MessageSourceLog    = "log"
+	MessageSourceCensor = "censor"
+
+	// 正则需要逐行匹配，单次搜索最多扫描的行数
+	messageRegexScanLimit = 200000
+	messageRegexBatchSize = 2000
+)
+
+var ErrMessageSourceUnknown = errors.New("未知的消息来源，可选 log 或 censor")
+
+// 关键词按字面匹配，转义 LIKE 的通配符。
+// 转义符用 ! 而不是反斜杠，MySQL 的字符串字面量里反斜杠本身也要转义
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// QueryMessageSearch 跨群消息搜索的条件，留空的条件不生效
+type QueryMessageSearch struct {
+	PageNum   int    `query:"pageNum"`
+	PageSize  int    `query:"pageSize"`
+	Source    string `query:"source"`    // log（跑团日志，默认）或 censor（拦截记录）
+	UserID    string `query:"userId"`    // 平台用户ID或统一ID
+	GroupID   string `query:"groupId"`   // 群号，精确匹配
+	TimeBegin int64  `query:"timeBegin"` // 秒级时间戳
+	TimeEnd   int64  `query:"timeEnd"`
+	Keyword   string `query:"keyword"` // 消息内容包含
+	Regex     string `query:"regex"`   // 消息内容正则
+	FullText  bool   `query:"fullText"`
+}
+
+// MessageSearchItem 搜索结果中的一条消息
+type MessageSearchItem struct {
+	Source  string `json:"source"`
+	ID      uint64 `json:"id"`
+	LogID   uint64 `json:"logId,omitempty"`
+	LogName string `json:"logName,omitempty"`
+	GroupID string `json:"groupId"`
+	UserID  string `json:"userId"`
+	// 日志消息中记录的统一ID
+	UniformID string `json:"uniformId,omitempty"`
+	Nickname  string `json:"nickname,omitempty"`
+	Message   string `json:"message"`
+	Time      int64  `json:"time"`
+	IsDice    bool   `json:"isDice,omitempty"`
+	Level     int    `json:"level,omitempty"` // 拦截记录的敏感级别
+}
+
+// MessageSearchResult 分页结果。使用正则时只扫描前 messageRegexScanLimit 行，Truncated 表示还有未扫描的数据
+type MessageSearchResult struct {
+	Total     int64                `json:"total"`
+	Items     []*MessageSearchItem `json:"items"`
+	Truncated bool                 `json:"truncated"`
+}
+
+type messageRow struct {
+	ID        uint64
+	LogID     uint64
+	GroupID   string
+	UserID    string
+	UniformID string
+	Nickname  string
+	Message   string
+	Time      int64
+	IsDice    bool
+	Level     int
+}
+
+func (r *messageRow) item(source string) *MessageSearchItem {
+	return &MessageSearchItem{
+		Source:    source,
+		ID:        r.ID,
+		LogID:     r.LogID,
+		GroupID:   r.GroupID,
+		UserID:    r.UserID,
+		UniformID: r.UniformID,
+		Nickname:  r.Nickname,
+		Message:   r.Message,
+		Time:      r.Time,
+		IsDice:    r.IsDice,
+		Level:     r.Level,
+	}
+}
+
+const (
+	logMessageColumns    = "id, log_id, group_id, im_userid AS user_id, user_uniform_id AS uniform_id, nickname, message, time, is_dice"
+	censorMessageColumns = "id, group_id, user_id, content AS message, created_at AS time, highest_level AS level"
+)
+
+// messageSearchQuery 按来源构造带过滤条件的查询，返回查询和映射到 messageRow 的列
+func messageSearchQuery(operator engine2.DatabaseOperator, q *QueryMessageSearch) (*gorm.DB, string, string, error) {
+	var query *gorm.DB
+	var columns, userCond, timeCol, contentCol string
+	switch q.Source {
+	case "", MessageSourceLog:
+		query = operator.GetLogDB(constant.READ).Model(&model.LogOneItem{})
+		columns = logMessageColumns
+		userCond = "im_userid = ? OR user_uniform_id = ?"
+		timeCol, contentCol = "time", "message"
+	case MessageSourceCensor:
+		query = operator.GetCensorDB(constant.READ).Model(&model.CensorLog{})
+		columns = censorMessageColumns
+		userCond = "user_id = ? OR user_id = ?"
+		timeCol, contentCol = "created_at", "content"
+	default:
+		return nil, "", "", ErrMessageSourceUnknown
+	}
+
+	if q.UserID != "" {
+		query = query.Where(userCond, q.UserID, q.UserID)
+	}
+	if q.GroupID != "" {
+		query = query.Where("group_id = ?", q.GroupID)
+	}
+	if q.TimeBegin > 0 {
+		query = query.Where(timeCol+" >= ?", q.TimeBegin)
+	}
+	if q.TimeEnd > 0 {
+		query = query.Where(timeCol+" <= ?", q.TimeEnd)
+	}
+	if q.Keyword != "" {
+		query = query.Where(contentCol+" LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(q.Keyword)+"%")
+	}
+	return query, columns, timeCol + " DESC, id DESC", nil
+}
+
+// MessageSearch 在跑团日志或拦截记录中搜索消息，按时间倒序分页
+func MessageSearch(operator engine2.DatabaseOperator, q QueryMessageSearch) (*MessageSearchResult, error) {
+	var re *regexp.Regexp
+	if q.Regex != "" {
+		var err error
+		if re, err = regexp.Compile(q.Regex); err != nil {
+			return nil, err
+		}
+	}
+	query, columns, order, err := messageSearchQuery(operator, &q)
+	if err != nil {
+		return nil, err
+	}
+	source := q.Source
+	if source == "" {
+		source = MessageSourceLog
+	}
+
+	result := &MessageSearchResult{Items: []*MessageSearchItem{}}
+	offset := (q.PageNum - 1) * q.PageSize
+	if re == nil {
+		if err = query.Session(&gorm.Session{}).Count(&result.Total).Error; err != nil {
+			return nil, err
+		}
+		var rows []*messageRow
+		err = query.Select(columns).Order(order).Limit(q.PageSize).Offset(offset).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			result.Items = append(result.Items, r.item(source))
+		}
+	} else {
+		// 数据库之间的正则语法不统一，先用其他条件缩小范围，再逐批在内存中匹配
+		scanned := 0
+		for scanned < messageRegexScanLimit {
+			var rows []*messageRow
+			err = query.Session(&gorm.Session{}).Select(columns).Order(order).
+				Limit(messageRegexBatchSize).Offset(scanned).Scan(&rows).Error
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range rows {
+				if !re.MatchString(r.Message) {
+					continue
+				}
+				if result.Total >= int64(offset) && len(result.Items) < q.PageSize {
+					result.Items = append(result.Items, r.item(source))
+				}
+				result.Total++
+			}
+			scanned += len(rows)
+			if len(rows) < messageRegexBatchSize {
+				break
+			}
+		}
+		result.Truncated = scanned >= messageRegexScanLimit
+	}
+
+	if source == MessageSourceLog {
+		if err = fillMessageLogNames(operator, result.Items); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// MessageGetByIDs 按ID读取日志消息，顺序与 ids 一致，用于全文索引的结果
+func MessageGetByIDs(operator engine2.DatabaseOperator, ids []uint64) ([]*MessageSearchItem, error) {
+	items := []*MessageSearchItem{}
+	if len(ids) == 0 {
+		return items, nil
+	}
+	var rows []*messageRow
+	err := operator.GetLogDB(constant.READ).Model(&model.LogOneItem{}).
+		Select(logMessageColumns).
+		Where("id IN ?", ids).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint64]*messageRow, len(rows))
+	for _, r := range rows {
+		byID[r.ID] = r
+	}
+	for _, id := range ids {
+		// 索引可能落后于删除操作，找不到的直接跳过
+		if r, ok := byID[id]; ok {
+			items = append(items, r.item(MessageSourceLog))
+		}
+	}
+	return items, fillMessageLogNames(operator, items)
+}
+
+// MessageListAfter 按ID升序读取 afterID 之后的日志消息，用于增量建立全文索引
+func MessageListAfter(operator engine2.DatabaseOperator, afterID uint64, limit int) ([]*MessageSearchItem, error) {
+	var rows []*messageRow
+	err := operator.GetLogDB(constant.READ).Model(&model.LogOneItem{}).
+		Select(logMessageColumns).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*MessageSearchItem, 0, len(rows))
+	for _, r := range rows {
+		items = append(items, r.item(MessageSourceLog))
+	}
+	return items, nil
+}
+
+func fillMessageLogNames(operator engine2.DatabaseOperator, items []*MessageSearchItem) error {
+	var logIDs []uint64
+	seen := map[uint64]bool{}
+	for _, item := range items {
+		if item.LogID != 0 && !seen[item.LogID] {
+			seen[item.LogID] = true
+			logIDs = append(logIDs, item.LogID)
+		}
+	}
+	if len(logIDs) == 0 {
+		return nil
+	}
+	var logs []model.LogInfo
+	err := operator.GetLogDB(constant.READ).Model(&model.LogInfo{}).
+		Select("id, name").
+		Where("id IN ?", logIDs).
+		Find(&logs).Error
+	if err != nil {
+		return err
+	}
+	names := make(map[uint64]string, len(logs))
+	for _, l := range logs {
+		names[l.ID] = l.Name
+	}
+	for _, item := range items {
+		item.LogName = names[item.LogID]
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package service_test
+
+import (
+	"testing"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func newMessageSearchTestOperator(t *testing.T) *logInfoTestOperator {
+	t.Helper()
+	db := newLogInfoTestDB(t)
+	if err := db.AutoMigrate(&model.CensorLog{}); err != nil {
+		t.Fatalf("migrate censor_log: %v", err)
+	}
+	if err := db.Create(&[]model.LogInfo{{ID: 1, Name: "log-a", GroupID: "QQ-Group:1"}, {ID: 2, Name: "log-b", GroupID: "QQ-Group:2"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	items := []model.LogOneItem{
+		{ID: 1, LogID: 1, GroupID: "QQ-Group:1", IMUserID: "QQ:10", UniformID: "UI:1", Time: 100, Message: ".ra 侦查"},
+		{ID: 2, LogID: 1, GroupID: "QQ-Group:1", IMUserID: "QQ:11", Time: 200, Message: "门后有人"},
+		{ID: 3, LogID: 2, GroupID: "QQ-Group:2", IMUserID: "QQ:10", UniformID: "UI:1", Time: 300, Message: ".ra 聆听"},
+		{ID: 4, LogID: 2, GroupID: "QQ-Group:2", IMUserID: "QQ:12", Time: 400, Message: "门开了"},
+	}
+	if err := db.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&model.CensorLog{ID: 1, UserID: "QQ:10", GroupID: "QQ-Group:1", Content: "违规内容", HighestLevel: 3, CreatedAt: 150}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &logInfoTestOperator{db: db, dbType: constant.SQLITE}
+}
+
+func messageIDs(items []*service.MessageSearchItem) []uint64 {
+	ids := make([]uint64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestMessageSearch(t *testing.T) {
+	op := newMessageSearchTestOperator(t)
+
+	tests := []struct {
+		name  string
+		query service.QueryMessageSearch
+		want  []uint64
+		total int64
+	}{
+		{"user by uniform id", service.QueryMessageSearch{UserID: "UI:1"}, []uint64{3, 1}, 2},
+		{"group and keyword", service.QueryMessageSearch{GroupID: "QQ-Group:2", Keyword: "门"}, []uint64{4}, 1},
+		{"time range", service.QueryMessageSearch{TimeBegin: 200, TimeEnd: 300}, []uint64{3, 2}, 2},
+		{"regex", service.QueryMessageSearch{Regex: `^\.ra\s`}, []uint64{3, 1}, 2},
+		{"paging", service.QueryMessageSearch{PageNum: 2, PageSize: 3}, []uint64{1}, 4},
+		{"regex paging", service.QueryMessageSearch{Regex: "门|ra", PageNum: 2, PageSize: 3}, []uint64{1}, 4},
+		{"literal wildcards", service.QueryMessageSearch{Keyword: "%_"}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			if q.PageNum == 0 {
+				q.PageNum, q.PageSize = 1, 20
+			}
+			got, err := service.MessageSearch(op, q)
+			if err != nil {
+				t.Fatalf("MessageSearch() error = %v", err)
+			}
+			if ids := messageIDs(got.Items); got.Total != tt.total || len(ids) != len(tt.want) || (len(ids) > 0 && ids[0] != tt.want[0]) {
+				t.Fatalf("MessageSearch() = %v (total %d), want %v (total %d)", ids, got.Total, tt.want, tt.total)
+			}
+		})
+	}
+
+	got, err := service.MessageSearch(op, service.QueryMessageSearch{Source: service.MessageSourceCensor, UserID: "QQ:10", PageNum: 1, PageSize: 20})
+	if err != nil {
+		t.Fatalf("MessageSearch(censor) error = %v", err)
+	}
+	if len(got.Items) != 1 || got.Items[0].Message != "违规内容" || got.Items[0].Level != 3 || got.Items[0].Time != 150 {
+		t.Fatalf("MessageSearch(censor) = %+v", got.Items)
+	}
+
+	if _, err = service.MessageSearch(op, service.QueryMessageSearch{Source: "foo", PageNum: 1, PageSize: 20}); err == nil {
+		t.Fatal("unknown source should fail")
+	}
+}
+
+func TestMessageGetByIDsKeepsOrder(t *testing.T) {
+	op := newMessageSearchTestOperator(t)
+
+	items, err := service.MessageGetByIDs(op, []uint64{4, 99, 1})
+	if err != nil {
+		t.Fatalf("MessageGetByIDs() error = %v", err)
+	}
+	if ids := messageIDs(items); len(ids) != 2 || ids[0] != 4 || ids[1] != 1 {
+		t.Fatalf("MessageGetByIDs() = %v, want [4 1]", ids)
+	}
+	if items[0].LogName != "log-b" || items[1].LogName != "log-a" {
+		t.Fatalf("log names = %q, %q", items[0].LogName, items[1].LogName)
+	}
+}
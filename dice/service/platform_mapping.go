@@ -0,0 +1,148 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	engine2 "sealdice-core/utils/dboperator/engine"
+)
+
+// PlatformMappingList 列出全部账号关联
+func PlatformMappingList(operator engine2.DatabaseOperator) ([]model.PlatformMapping, error) {
+	db := operator.GetDataDB(constant.READ)
+	var items []model.PlatformMapping
+	if err := db.Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// PlatformMappingSave 将平台用户ID关联到统一ID，已存在时覆盖
+func PlatformMappingSave(operator engine2.DatabaseOperator, imUserID string, id string) error {
+	return platformMappingSave(operator.GetDataDB(constant.WRITE), imUserID, id)
+}
+
+func platformMappingSave(db *gorm.DB, imUserID string, id string) error {
+	item := model.PlatformMapping{IMUserID: imUserID, Id: id, CreatedAt: time.Now().Unix()}
+	return db.Where("im_user_id = ?", imUserID).
+		Assign(map[string]any{"id": id, "created_at": item.CreatedAt}).
+		FirstOrCreate(&item).Error
+}
+
+// PlatformMappingDelete 解除平台用户ID的关联
+func PlatformMappingDelete(operator engine2.DatabaseOperator, imUserID string) error {
+	db := operator.GetDataDB(constant.WRITE)
+	return db.Where("im_user_id = ?", imUserID).Delete(&model.PlatformMapping{}).Error
+}
+
+// AttrsMergeUserResult 账号合并时变动的数据
+type AttrsMergeUserResult struct {
+	MovedIds   []string          // 被移走的群内默认卡旧ID
+	RenamedIds map[string]string // 因重名被改名的角色卡ID -> 新名字
+	Characters int               // 转移的角色卡数量
+}
+
+// PlatformMappingLink 在同一事务中将 userIds 依次关联到 identity：转移各自的角色卡并写入映射，任一步失败时全部回滚。
+// 返回值与 userIds 一一对应
+func PlatformMappingLink(operator engine2.DatabaseOperator, userIds []string, identity string, rename func(name string, suffix int) string) ([]*AttrsMergeUserResult, error) {
+	var results []*AttrsMergeUserResult
+	db := operator.GetDataDB(constant.WRITE)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, userId := range userIds {
+			result, err := attrsMergeUser(tx, userId, identity, rename)
+			if err != nil {
+				return err
+			}
+			if err = platformMappingSave(tx, userId, identity); err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// attrsMergeUser 将 userId 名下的角色卡和群内默认卡转移到 toId 名下。
+// 角色卡重名时用 rename(原名, 序号) 生成新名字；toId 在同一群已有默认卡时，原卡保持不动。
+func attrsMergeUser(db *gorm.DB, userId string, toId string, rename func(name string, suffix int) string) (*AttrsMergeUserResult, error) {
+	result := &AttrsMergeUserResult{RenamedIds: map[string]string{}}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 1. 角色卡：改归属，重名的追加序号
+		var used []model.AttributesItemModel
+		if err := tx.Select("id, name").Where("attrs_type = ? AND owner_id = ?", AttrsTypeCharacter, toId).Find(&used).Error; err != nil {
+			return err
+		}
+		usedNames := map[string]struct{}{}
+		for _, item := range used {
+			usedNames[item.Name] = struct{}{}
+		}
+
+		var chars []model.AttributesItemModel
+		if err := tx.Select("id, name, created_at").Where("attrs_type = ? AND owner_id = ?", AttrsTypeCharacter, userId).Find(&chars).Error; err != nil {
+			return err
+		}
+		sort.Slice(chars, func(i, j int) bool {
+			if chars[i].CreatedAt != chars[j].CreatedAt {
+				return chars[i].CreatedAt < chars[j].CreatedAt
+			}
+			return chars[i].Id < chars[j].Id
+		})
+		for _, item := range chars {
+			updates := map[string]any{"owner_id": toId}
+			if _, exists := usedNames[item.Name]; exists {
+				for suffix := 2; ; suffix++ {
+					newName := rename(item.Name, suffix)
+					if _, exists = usedNames[newName]; !exists {
+						updates["name"] = newName
+						result.RenamedIds[item.Id] = newName
+						item.Name = newName
+						break
+					}
+				}
+			}
+			usedNames[item.Name] = struct{}{}
+			if err := tx.Model(&model.AttributesItemModel{}).Where("id = ?", item.Id).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		result.Characters = len(chars)
+
+		// 2. 群内默认卡：id 为 群ID-用户ID，绑卡时创建的行没有 attrs_type。LIKE 中的通配符可能多匹配，逐个校验后缀
+		var rows []model.AttributesItemModel
+		if err := tx.Select("id").Where("COALESCE(attrs_type, '') IN ? AND id LIKE ?", []string{AttrsTypeGroupUser, ""}, "%-"+userId).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			groupId, ok := strings.CutSuffix(row.Id, "-"+userId)
+			if !ok || groupId == "" {
+				continue
+			}
+			newId := groupId + "-" + toId
+			var count int64
+			if err := tx.Model(&model.AttributesItemModel{}).Where("id = ?", newId).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+			if err := tx.Model(&model.AttributesItemModel{}).Where("id = ?", row.Id).Update("id", newId).Error; err != nil {
+				return err
+			}
+			result.MovedIds = append(result.MovedIds, row.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	engine2 "sealdice-core/utils/dboperator/engine"
+)
+
+// GroupScheduleList 列出日程提醒，groupID 为空时列出全部
+func GroupScheduleList(operator engine2.DatabaseOperator, groupID string) ([]*model.GroupSchedule, error) {
+	db := operator.GetDataDB(constant.READ)
+	var items []*model.GroupSchedule
+	query := db.Model(&model.GroupSchedule{})
+	if groupID != "" {
+		query = query.Where("group_id = ?", groupID)
+	}
+	if err := query.Order("id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GroupScheduleCreate 新建日程提醒，成功后 item.ID 被填充
+func GroupScheduleCreate(operator engine2.DatabaseOperator, item *model.GroupSchedule) error {
+	db := operator.GetDataDB(constant.WRITE)
+	return db.Create(item).Error
+}
+
+// GroupScheduleUpdate 更新日程提醒的指定字段
+func GroupScheduleUpdate(operator engine2.DatabaseOperator, id uint64, updates map[string]any) error {
+	db := operator.GetDataDB(constant.WRITE)
+	return db.Model(&model.GroupSchedule{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// GroupScheduleDelete 删除日程提醒
+func GroupScheduleDelete(operator engine2.DatabaseOperator, id uint64) error {
+	db := operator.GetDataDB(constant.WRITE)
+	return db.Where("id = ?", id).Delete(&model.GroupSchedule{}).Error
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	engine2 "sealdice-core/utils/dboperator/engine"
+)
+
+// UIAuditAppend 写入一条 WebUI 操作记录
+func UIAuditAppend(operator engine2.DatabaseOperator, item *model.UIAuditLog) error {
+	db := operator.GetDataDB(constant.WRITE)
+	return db.Create(item).Error
+}
+
+// QueryUIAudit 是操作记录分页查询的参数
+type QueryUIAudit struct {
+	PageNum  int    `query:"pageNum"`  // 当前页码
+	PageSize int    `query:"pageSize"` // 每页条数
+	User     string `query:"user"`     // 用户名
+	Path     string `query:"path"`     // 路由前缀
+	From     int64  `query:"from"`     // 开始时间
+	To       int64  `query:"to"`       // 结束时间
+}
+
+// UIAuditGetPage 分页查询操作记录，按时间倒序
+func UIAuditGetPage(operator engine2.DatabaseOperator, params QueryUIAudit) (int64, []model.UIAuditLog, error) {
+	db := operator.GetDataDB(constant.READ)
+	var total int64
+	var items []model.UIAuditLog
+
+	query := db.Model(&model.UIAuditLog{})
+	if params.User != "" {
+		query = query.Where("user_name = ?", params.User)
+	}
+	if params.Path != "" {
+		query = query.Where("path LIKE ?", params.Path+"%")
+	}
+	if params.From > 0 {
+		query = query.Where("created_at >= ?", params.From)
+	}
+	if params.To > 0 {
+		query = query.Where("created_at <= ?", params.To)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+	if err := query.
+		Order("created_at DESC, id DESC").
+		Limit(params.PageSize).
+		Offset((params.PageNum - 1) * params.PageSize).
+		Find(&items).
+		Error; err != nil {
+		return 0, nil, err
+	}
+	return total, items, nil
+}
@@ -0,0 +1,214 @@
+package storylog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pilagod/gorm-cursor-paginator/v2/paginator"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/dboperator/engine"
+)
+
+// 默认导出格式，保持与旧版 .log export 相同的纯文本
+const ExportFormatDefault = "txt"
+
+// 本地导出的超时时间，与旧版 GetLogTxt 一致
+const exportTimeout = 10 * time.Second
+
+var (
+	ErrExportFormatUnknown = errors.New("未知的日志导出格式")
+	ErrExportEmpty         = errors.New("此log不存在，或条目数为空，名字是否正确？")
+	ErrExportTimeout       = errors.New("日志导出超时（10秒限制），请尝试减少数据量或联系管理员")
+)
+
+// ExportMeta 导出时传给格式化器的日志信息
+type ExportMeta struct {
+	GroupID    string
+	LogName    string
+	ExportedAt time.Time
+	// Lines 已写出的行数，仅在 End 时有效
+	Lines int
+}
+
+// LogExporter 将日志逐行渲染为某种格式。每次导出都会创建新的实例，因此实现可以持有状态。
+type LogExporter interface {
+	Begin(w io.Writer, meta *ExportMeta) error
+	Write(w io.Writer, line *model.LogOneItemParquet) error
+	End(w io.Writer, meta *ExportMeta) error
+}
+
+// LogExporterInfo 描述一种已注册的导出格式
+type LogExporterInfo struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases"`
+	Ext         string   `json:"ext"`
+	ContentType string   `json:"contentType"`
+	Description string   `json:"description"`
+
+	New func() LogExporter `json:"-"`
+}
+
+var logExporters = struct {
+	sync.RWMutex
+	items   map[string]*LogExporterInfo
+	aliases map[string]string
+}{
+	items:   map[string]*LogExporterInfo{},
+	aliases: map[string]string{},
+}
+
+// RegisterLogExporter 注册一种导出格式，同名格式会被覆盖
+func RegisterLogExporter(info *LogExporterInfo) {
+	if info == nil || info.Name == "" || info.New == nil {
+		return
+	}
+	logExporters.Lock()
+	defer logExporters.Unlock()
+	name := strings.ToLower(info.Name)
+	logExporters.items[name] = info
+	for _, alias := range info.Aliases {
+		logExporters.aliases[strings.ToLower(alias)] = name
+	}
+}
+
+// GetLogExporter 按名字或别名查找导出格式，空字符串返回默认格式
+func GetLogExporter(format string) (*LogExporterInfo, bool) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = ExportFormatDefault
+	}
+	logExporters.RLock()
+	defer logExporters.RUnlock()
+	if name, ok := logExporters.aliases[format]; ok {
+		format = name
+	}
+	info, ok := logExporters.items[format]
+	return info, ok
+}
+
+// LogExporterList 返回全部已注册的导出格式，按名字排序
+func LogExporterList() []*LogExporterInfo {
+	logExporters.RLock()
+	defer logExporters.RUnlock()
+	ret := make([]*LogExporterInfo, 0, len(logExporters.items))
+	for _, info := range logExporters.items {
+		ret = append(ret, info)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// LogExporterNames 返回全部格式名，用于帮助文本与报错提示
+func LogExporterNames() []string {
+	list := LogExporterList()
+	names := make([]string, 0, len(list))
+	for _, info := range list {
+		names = append(names, info.Name)
+	}
+	return names
+}
+
+type ExportEnv struct {
+	Db      engine.DatabaseOperator
+	GroupID string
+	LogName string
+	Format  string
+}
+
+// exportLineFetcher 按游标分批读取日志行
+type exportLineFetcher func(cursor paginator.Cursor) ([]model.LogOneItemParquet, paginator.Cursor, error)
+
+// ExportLog 将日志以指定格式写入 w，返回写出的行数。
+// 全程不需要网络，读取方式与 GetLogTxtAndParquetFile 相同。
+func ExportLog(ctx context.Context, env ExportEnv, w io.Writer) (int, error) {
+	info, ok := GetLogExporter(env.Format)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s，可用格式: %s", ErrExportFormatUnknown, env.Format, strings.Join(LogExporterNames(), "/"))
+	}
+	fetch := func(cursor paginator.Cursor) ([]model.LogOneItemParquet, paginator.Cursor, error) {
+		return service.LogGetExportCursorLines(env.Db, env.GroupID, env.LogName, cursor)
+	}
+	meta := &ExportMeta{
+		GroupID:    env.GroupID,
+		LogName:    env.LogName,
+		ExportedAt: time.Now(),
+	}
+	return exportLines(ctx, info.New(), meta, fetch, w)
+}
+
+func exportLines(ctx context.Context, exporter LogExporter, meta *ExportMeta, fetch exportLineFetcher, w io.Writer) (int, error) {
+	if err := exporter.Begin(w, meta); err != nil {
+		return 0, fmt.Errorf("写入日志导出头部失败: %w", err)
+	}
+	counter := 0
+	currentCursor := paginator.Cursor{}
+	for {
+		if ctx.Err() != nil {
+			return counter, ErrExportTimeout
+		}
+		lines, cursor, err := fetch(currentCursor)
+		if err != nil {
+			return counter, err
+		}
+		for i := range lines {
+			if err = exporter.Write(w, &lines[i]); err != nil {
+				return counter, fmt.Errorf("写入日志导出内容失败: %w", err)
+			}
+			counter++
+		}
+		if cursor.After == nil {
+			break
+		}
+		currentCursor.After = cursor.After
+	}
+	if counter == 0 {
+		return 0, ErrExportEmpty
+	}
+	meta.Lines = counter
+	if err := exporter.End(w, meta); err != nil {
+		return counter, fmt.Errorf("写入日志导出尾部失败: %w", err)
+	}
+	return counter, nil
+}
+
+// ExportLogToTempFile 将日志导出到临时文件，返回文件路径。调用方负责删除该文件。
+func ExportLogToTempFile(env ExportEnv, fileNamePrefix string) (string, string, error) {
+	info, ok := GetLogExporter(env.Format)
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s，可用格式: %s", ErrExportFormatUnknown, env.Format, strings.Join(LogExporterNames(), "/"))
+	}
+	env.Format = info.Name
+
+	tempPattern, notice := buildTempPatternWithExt(fileNamePrefix, info.Ext)
+	tempLog, err := os.CreateTemp("", tempPattern)
+	if err != nil {
+		return "", notice, errors.New("log导出出现未知错误")
+	}
+	defer func() {
+		_ = tempLog.Close()
+		if err != nil {
+			_ = os.Remove(tempLog.Name()) //nolint:gosec
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	if _, err = ExportLog(ctx, env, tempLog); err != nil {
+		return "", notice, err
+	}
+	if err = tempLog.Sync(); err != nil {
+		return "", notice, fmt.Errorf("写入日志导出临时文件失败: %w", err)
+	}
+	return tempLog.Name(), notice, nil
+}
@@ -0,0 +1,311 @@
+package storylog
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"sealdice-core/model"
+)
+
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+func init() {
+	RegisterLogExporter(&LogExporterInfo{
+		Name:        "txt",
+		Aliases:     []string{"text", "plain"},
+		Ext:         "txt",
+		ContentType: "text/plain; charset=utf-8",
+		Description: "纯文本，与旧版导出一致",
+		New:         func() LogExporter { return &txtExporter{} },
+	})
+	RegisterLogExporter(&LogExporterInfo{
+		Name:        "html",
+		Aliases:     []string{"htm"},
+		Ext:         "html",
+		ContentType: "text/html; charset=utf-8",
+		Description: "带发言人配色的单文件网页，可离线打开",
+		New:         func() LogExporter { return newHTMLExporter() },
+	})
+	RegisterLogExporter(&LogExporterInfo{
+		Name:        "md",
+		Aliases:     []string{"markdown"},
+		Ext:         "md",
+		ContentType: "text/markdown; charset=utf-8",
+		Description: "Markdown 文档",
+		New:         func() LogExporter { return &markdownExporter{} },
+	})
+	RegisterLogExporter(&LogExporterInfo{
+		Name:        "jsonl",
+		Aliases:     []string{"ndjson"},
+		Ext:         "jsonl",
+		ContentType: "application/x-ndjson; charset=utf-8",
+		Description: "每行一条 JSON 记录",
+		New:         func() LogExporter { return &jsonlExporter{} },
+	})
+	RegisterLogExporter(&LogExporterInfo{
+		Name:        "fvtt",
+		Aliases:     []string{"foundry"},
+		Ext:         "json",
+		ContentType: "application/json; charset=utf-8",
+		Description: "Foundry VTT 聊天消息数组，可用 ChatMessage.createDocuments 导入",
+		New:         func() LogExporter { return &fvttExporter{} },
+	})
+}
+
+func formatExportTime(ts int64) string {
+	return time.Unix(ts, 0).Format(exportTimeLayout)
+}
+
+// commandInfoRaw 将数据库中的 command_info 字符串转为合法的 JSON，无效时返回 nil
+func commandInfoRaw(s string) json.RawMessage {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "null" || !json.Valid([]byte(s)) {
+		return nil
+	}
+	return json.RawMessage(s)
+}
+
+// txtExporter 纯文本，格式与 GetLogTxt 保持一致
+type txtExporter struct{}
+
+func (*txtExporter) Begin(io.Writer, *ExportMeta) error { return nil }
+
+func (*txtExporter) Write(w io.Writer, line *model.LogOneItemParquet) error {
+	_, err := fmt.Fprintf(w, "%s(%v) %s\n%s\n\n", line.Nickname, line.IMUserID, formatExportTime(line.Time), line.Message)
+	return err
+}
+
+func (*txtExporter) End(io.Writer, *ExportMeta) error { return nil }
+
+// htmlExporter 单文件网页，样式全部内联，不引用任何外部资源
+type htmlExporter struct {
+	colors map[string]string
+}
+
+// 发言人配色，按首次出现顺序分配
+var exportUserColors = []string{
+	"#c0392b", "#2471a3", "#1e8449", "#b9770e", "#7d3c98", "#148f77",
+	"#a04000", "#2e4053", "#b03a2e", "#1f618d", "#6c3483", "#117a65",
+}
+
+const exportDiceColor = "#7f8c8d"
+
+func newHTMLExporter() *htmlExporter {
+	return &htmlExporter{colors: map[string]string{}}
+}
+
+func (e *htmlExporter) userColor(line *model.LogOneItemParquet) string {
+	if line.IsDice {
+		return exportDiceColor
+	}
+	key := line.IMUserID
+	if key == "" {
+		key = line.Nickname
+	}
+	if c, ok := e.colors[key]; ok {
+		return c
+	}
+	c := exportUserColors[len(e.colors)%len(exportUserColors)]
+	e.colors[key] = c
+	return c
+}
+
+const htmlExportHead = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="generator" content="SealDice">
+<title>%s</title>
+<style>
+body{margin:0;background:#f5f5f0;color:#222;font-family:"Noto Serif SC","Source Han Serif SC","Songti SC",serif;line-height:1.7}
+main{max-width:860px;margin:0 auto;padding:24px 16px 48px}
+header{border-bottom:1px solid #ccc;margin-bottom:16px}
+header h1{margin:0 0 4px;font-size:1.6em}
+header p{margin:0 0 12px;color:#666;font-size:.9em}
+.line{margin:6px 0;padding:2px 0}
+.line .nick{font-weight:bold}
+.line .uid,.line .time{color:#999;font-size:.8em;margin-left:6px}
+.line .msg{white-space:pre-wrap;word-break:break-word}
+.line.dice .msg{font-style:italic}
+footer{margin-top:24px;color:#999;font-size:.8em;text-align:center}
+@media print{body{background:#fff}}
+</style>
+</head>
+<body>
+<main>
+<header><h1>%s</h1><p>%s · 导出于 %s</p></header>
+`
+
+func (e *htmlExporter) Begin(w io.Writer, meta *ExportMeta) error {
+	title := html.EscapeString(meta.LogName)
+	_, err := fmt.Fprintf(w, htmlExportHead,
+		title, title,
+		html.EscapeString(meta.GroupID),
+		meta.ExportedAt.Format(exportTimeLayout),
+	)
+	return err
+}
+
+func (e *htmlExporter) Write(w io.Writer, line *model.LogOneItemParquet) error {
+	class := "line"
+	if line.IsDice {
+		class += " dice"
+	}
+	_, err := fmt.Fprintf(w,
+		"<div class=\"%s\" style=\"color:%s\"><span class=\"nick\">%s</span><span class=\"uid\">(%s)</span><span class=\"time\">%s</span><div class=\"msg\">%s</div></div>\n",
+		class,
+		e.userColor(line),
+		html.EscapeString(line.Nickname),
+		html.EscapeString(line.IMUserID),
+		formatExportTime(line.Time),
+		html.EscapeString(line.Message),
+	)
+	return err
+}
+
+func (e *htmlExporter) End(w io.Writer, meta *ExportMeta) error {
+	_, err := fmt.Fprintf(w, "<footer>共 %d 条记录 · SealDice</footer>\n</main>\n</body>\n</html>\n", meta.Lines)
+	return err
+}
+
+// markdownExporter Markdown 文档，骰子的发言以引用块呈现
+type markdownExporter struct{}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`,
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+func (*markdownExporter) Begin(w io.Writer, meta *ExportMeta) error {
+	_, err := fmt.Fprintf(w, "# %s\n\n> %s · 导出于 %s\n\n",
+		markdownEscaper.Replace(meta.LogName),
+		markdownEscaper.Replace(meta.GroupID),
+		meta.ExportedAt.Format(exportTimeLayout),
+	)
+	return err
+}
+
+func (*markdownExporter) Write(w io.Writer, line *model.LogOneItemParquet) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**%s** (%s) `%s`\n\n",
+		markdownEscaper.Replace(line.Nickname),
+		markdownEscaper.Replace(line.IMUserID),
+		formatExportTime(line.Time),
+	)
+	for _, text := range strings.Split(line.Message, "\n") {
+		text = markdownEscaper.Replace(text)
+		if line.IsDice {
+			sb.WriteString("> ")
+		}
+		// 行尾两个空格表示换行
+		sb.WriteString(text)
+		sb.WriteString("  \n")
+	}
+	sb.WriteString("\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (*markdownExporter) End(w io.Writer, meta *ExportMeta) error {
+	_, err := fmt.Fprintf(w, "---\n\n共 %d 条记录\n", meta.Lines)
+	return err
+}
+
+// jsonlExporter 每行一条记录，字段与海豹标准 Log 一致
+type jsonlExporter struct{}
+
+type jsonlExportLine struct {
+	ID          uint64          `json:"id"`
+	Nickname    string          `json:"nickname"`
+	IMUserID    string          `json:"IMUserId"`
+	Time        int64           `json:"time"`
+	Message     string          `json:"message"`
+	IsDice      bool            `json:"isDice"`
+	CommandID   int64           `json:"commandId"`
+	CommandInfo json.RawMessage `json:"commandInfo"`
+	UniformID   string          `json:"uniformId"`
+}
+
+func (*jsonlExporter) Begin(io.Writer, *ExportMeta) error { return nil }
+
+func (*jsonlExporter) Write(w io.Writer, line *model.LogOneItemParquet) error {
+	data, err := json.Marshal(jsonlExportLine{
+		ID:          line.ID,
+		Nickname:    line.Nickname,
+		IMUserID:    line.IMUserID,
+		Time:        line.Time,
+		Message:     line.Message,
+		IsDice:      line.IsDice,
+		CommandID:   line.CommandID,
+		CommandInfo: commandInfoRaw(line.CommandInfoStr),
+		UniformID:   line.UniformID,
+	})
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
+
+func (*jsonlExporter) End(io.Writer, *ExportMeta) error { return nil }
+
+// fvttExporter Foundry VTT ChatMessage 数据数组
+type fvttExporter struct {
+	wrote bool
+}
+
+type fvttSpeaker struct {
+	Alias string `json:"alias"`
+}
+
+type fvttChatMessage struct {
+	Speaker   fvttSpeaker               `json:"speaker"`
+	Content   string                    `json:"content"`
+	Timestamp int64                     `json:"timestamp"`
+	Flags     map[string]map[string]any `json:"flags"`
+}
+
+func (*fvttExporter) Begin(w io.Writer, _ *ExportMeta) error {
+	_, err := io.WriteString(w, "[\n")
+	return err
+}
+
+func (e *fvttExporter) Write(w io.Writer, line *model.LogOneItemParquet) error {
+	content := strings.ReplaceAll(html.EscapeString(line.Message), "\n", "<br>")
+	flags := map[string]any{
+		"id":       line.ID,
+		"imUserId": line.IMUserID,
+		"isDice":   line.IsDice,
+	}
+	if info := commandInfoRaw(line.CommandInfoStr); info != nil {
+		flags["commandInfo"] = info
+	}
+	data, err := json.Marshal(fvttChatMessage{
+		Speaker:   fvttSpeaker{Alias: line.Nickname},
+		Content:   "<p>" + content + "</p>",
+		Timestamp: line.Time * 1000,
+		Flags:     map[string]map[string]any{"sealdice": flags},
+	})
+	if err != nil {
+		return err
+	}
+	if e.wrote {
+		if _, err = io.WriteString(w, ",\n"); err != nil {
+			return err
+		}
+	}
+	e.wrote = true
+	_, err = w.Write(data)
+	return err
+}
+
+func (*fvttExporter) End(w io.Writer, _ *ExportMeta) error {
+	_, err := io.WriteString(w, "\n]\n")
+	return err
+}
@@ -0,0 +1,165 @@
+//nolint:testpackage
+package storylog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pilagod/gorm-cursor-paginator/v2/paginator"
+
+	"sealdice-core/model"
+)
+
+func testExportLines() []model.LogOneItemParquet {
+	return []model.LogOneItemParquet{
+		{ID: 1, Nickname: "Alice", IMUserID: "QQ:1", Time: 1700000000, Message: "<b>hi</b>\nsecond line"},
+		{ID: 2, Nickname: "Bob", IMUserID: "QQ:2", Time: 1700000010, Message: "*roll*"},
+		{ID: 3, Nickname: "海豹", IMUserID: "QQ:3", Time: 1700000020, Message: "Alice 掷骰 D100=42", IsDice: true, CommandInfoStr: `{"cmd":"roll"}`},
+		{ID: 4, Nickname: "Alice", IMUserID: "QQ:1", Time: 1700000030, Message: "again"},
+	}
+}
+
+// pagedFetcher 以每页 size 条模拟游标分页
+func pagedFetcher(lines []model.LogOneItemParquet, size int) exportLineFetcher {
+	return func(cursor paginator.Cursor) ([]model.LogOneItemParquet, paginator.Cursor, error) {
+		start := 0
+		if cursor.After != nil {
+			start, _ = strconv.Atoi(*cursor.After)
+		}
+		end := start + size
+		if end >= len(lines) {
+			return lines[start:], paginator.Cursor{}, nil
+		}
+		after := strconv.Itoa(end)
+		return lines[start:end], paginator.Cursor{After: &after}, nil
+	}
+}
+
+func runTestExport(t *testing.T, format string) string {
+	t.Helper()
+	info, ok := GetLogExporter(format)
+	if !ok {
+		t.Fatalf("format %q not registered", format)
+	}
+	meta := &ExportMeta{GroupID: "QQ-Group:1", LogName: "test<log>", ExportedAt: time.Unix(1700000000, 0)}
+	var buf bytes.Buffer
+	n, err := exportLines(context.Background(), info.New(), meta, pagedFetcher(testExportLines(), 3), &buf)
+	if err != nil {
+		t.Fatalf("export %s: %v", format, err)
+	}
+	if n != 4 {
+		t.Fatalf("export %s wrote %d lines, want 4", format, n)
+	}
+	return buf.String()
+}
+
+func TestLogExporterBuiltinsRegistered(t *testing.T) {
+	for _, name := range []string{"txt", "html", "md", "markdown", "jsonl", "fvtt", "foundry", ""} {
+		if _, ok := GetLogExporter(name); !ok {
+			t.Errorf("format %q not found", name)
+		}
+	}
+	if _, ok := GetLogExporter("docx"); ok {
+		t.Error("unexpected format docx")
+	}
+}
+
+func TestLogExportTxtMatchesLegacy(t *testing.T) {
+	out := runTestExport(t, "txt")
+	first := "Alice(QQ:1) " + time.Unix(1700000000, 0).Format(exportTimeLayout) + "\n<b>hi</b>\nsecond line\n\n"
+	if !strings.HasPrefix(out, first) {
+		t.Fatalf("unexpected txt output: %q", out)
+	}
+}
+
+func TestLogExportHTMLEscapesAndColors(t *testing.T) {
+	out := runTestExport(t, "html")
+	if strings.Contains(out, "<b>hi</b>") || strings.Contains(out, "test<log>") {
+		t.Fatal("html output is not escaped")
+	}
+	if strings.Contains(out, "http://") || strings.Contains(out, "https://") {
+		t.Fatal("html output references external resources")
+	}
+	aliceColor := `style="color:` + exportUserColors[0] + `"`
+	if strings.Count(out, aliceColor) != 2 {
+		t.Fatalf("expected Alice's two lines to share color %s", exportUserColors[0])
+	}
+	if !strings.Contains(out, `class="line dice" style="color:`+exportDiceColor) {
+		t.Fatal("dice line is not styled")
+	}
+	if !strings.HasSuffix(strings.TrimSpace(out), "</html>") {
+		t.Fatal("html output is not closed")
+	}
+}
+
+func TestLogExportMarkdown(t *testing.T) {
+	out := runTestExport(t, "md")
+	if !strings.HasPrefix(out, "# test\\<log\\>\n") {
+		t.Fatalf("unexpected markdown title: %q", strings.SplitN(out, "\n", 2)[0])
+	}
+	if !strings.Contains(out, `\*roll\*`) {
+		t.Fatal("markdown output is not escaped")
+	}
+	if !strings.Contains(out, "> Alice 掷骰 D100=42") {
+		t.Fatal("dice line is not quoted")
+	}
+}
+
+func TestLogExportJSONL(t *testing.T) {
+	out := runTestExport(t, "jsonl")
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	count := 0
+	for scanner.Scan() {
+		var line jsonlExportLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid json line %q: %v", scanner.Text(), err)
+		}
+		if line.ID == 3 && string(line.CommandInfo) != `{"cmd":"roll"}` {
+			t.Fatalf("unexpected commandInfo %s", line.CommandInfo)
+		}
+		count++
+	}
+	if count != 4 {
+		t.Fatalf("got %d jsonl lines, want 4", count)
+	}
+}
+
+func TestLogExportFVTT(t *testing.T) {
+	out := runTestExport(t, "fvtt")
+	var messages []fvttChatMessage
+	if err := json.Unmarshal([]byte(out), &messages); err != nil {
+		t.Fatalf("invalid fvtt json: %v", err)
+	}
+	if len(messages) != 4 {
+		t.Fatalf("got %d messages, want 4", len(messages))
+	}
+	if messages[0].Speaker.Alias != "Alice" || messages[0].Timestamp != 1700000000000 {
+		t.Fatalf("unexpected first message %+v", messages[0])
+	}
+	if messages[0].Content != "<p>&lt;b&gt;hi&lt;/b&gt;<br>second line</p>" {
+		t.Fatalf("unexpected content %q", messages[0].Content)
+	}
+}
+
+func TestLogExportEmpty(t *testing.T) {
+	info, _ := GetLogExporter("html")
+	var buf bytes.Buffer
+	_, err := exportLines(context.Background(), info.New(), &ExportMeta{}, pagedFetcher(nil, 3), &buf)
+	if !errors.Is(err, ErrExportEmpty) {
+		t.Fatalf("expected ErrExportEmpty, got %v", err)
+	}
+}
+
+func TestBuildTempPatternWithExt(t *testing.T) {
+	pattern, notice := buildTempPatternWithExt("log", "html")
+	if notice != "" || pattern != "log-*.html" {
+		t.Fatalf("unexpected pattern %q notice %q", pattern, notice)
+	}
+}
@@ -20,6 +20,15 @@ const (
 var invalidFilenameCharsRe = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]`)
 
 func buildLogBackupFilename(groupID, logName string, now time.Time) (string, string) {
+	return buildExportFilename(groupID, logName, "zip", now)
+}
+
+// BuildExportFilename 生成导出文件名，日志名不适合作为文件名时改用哈希
+func BuildExportFilename(groupID, logName, ext string, now time.Time) (string, string) {
+	return buildExportFilename(groupID, logName, ext, now)
+}
+
+func buildExportFilename(groupID, logName, ext string, now time.Time) (string, string) {
 	groupPart, ok := sanitizeFilenameComponent(groupID)
 	if !ok {
 		groupPart = "group"
@@ -29,35 +38,40 @@ func buildLogBackupFilename(groupID, logName string, now time.Time) (string, str
 	logPart, logOK := sanitizeFilenameComponent(logName)
 	timestamp := now.Format("060102150405")
 	if logOK {
-		name := fmt.Sprintf("%s_%s.%s.zip", groupPart, logPart, timestamp)
+		name := fmt.Sprintf("%s_%s.%s.%s", groupPart, logPart, timestamp, ext)
 		if len([]byte(name)) <= maxExportFilenameBytes {
 			return name, ""
 		}
 	}
 
 	hashPart := hashHex(logName)
-	name := fmt.Sprintf("%s_%s.%s.zip", groupPart, hashPart, timestamp)
+	name := fmt.Sprintf("%s_%s.%s.%s", groupPart, hashPart, timestamp, ext)
 	if len([]byte(name)) <= maxExportFilenameBytes {
 		return name, fileNameFallbackNotice
 	}
 
-	return fmt.Sprintf("log_%s.%s.zip", hashPart, timestamp), fileNameFallbackNotice
+	return fmt.Sprintf("log_%s.%s.%s", hashPart, timestamp, ext), fileNameFallbackNotice
 }
 
 func buildTempPattern(prefix string) (string, string) {
+	return buildTempPatternWithExt(prefix, "txt")
+}
+
+func buildTempPatternWithExt(prefix string, ext string) (string, string) {
+	suffix := "-*." + ext
 	cleanPrefix, ok := sanitizeFilenameComponent(prefix)
 	if ok {
-		if len([]byte(cleanPrefix+"-*.txt")) <= maxTempPatternBytes {
-			pattern := cleanPrefix + "-*.txt"
+		if len([]byte(cleanPrefix+suffix)) <= maxTempPatternBytes {
+			pattern := cleanPrefix + suffix
 			return pattern, ""
 		}
 	}
 
-	pattern := "log-export-" + hashHex(prefix) + "-*.txt"
+	pattern := "log-export-" + hashHex(prefix) + suffix
 	if len([]byte(pattern)) > maxTempPatternBytes {
 		pattern = trimUTF8ByBytes(pattern, maxTempPatternBytes)
 		if !strings.Contains(pattern, "*") {
-			pattern = "log-" + hashHex(prefix)[:8] + "-*.txt"
+			pattern = "log-" + hashHex(prefix)[:8] + suffix
 		}
 	}
 	return pattern, fileNameFallbackNotice
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	logStoreFile     = "system.jsonl"
+	logStoreMaxSize  = 20 // MB
+	logStoreBackups  = 10
+	logStoreMaxAge   = 30 // 天
+	logQueryMaxLimit = 5000
+)
+
+// DefaultLogStore 由 InitLogger 创建，未初始化时为 nil
+var DefaultLogStore *LogStore
+
+// LogStore 以 JSON Lines 格式保存系统日志并按大小轮转，可按时间、模块和文字检索
+type LogStore struct {
+	dir string
+	w   *lumberjack.Logger
+}
+
+func NewLogStore(dir string) *LogStore {
+	return &LogStore{
+		dir: dir,
+		w: &lumberjack.Logger{
+			Filename:   filepath.Join(dir, logStoreFile),
+			MaxSize:    logStoreMaxSize,
+			MaxBackups: logStoreBackups,
+			MaxAge:     logStoreMaxAge,
+		},
+	}
+}
+
+// Write 写入一行 JSON 编码的日志
+func (s *LogStore) Write(p []byte) (int, error) {
+	return s.w.Write(p)
+}
+
+func (s *LogStore) Sync() error {
+	return nil
+}
+
+func (s *LogStore) Close() error {
+	return s.w.Close()
+}
+
+// LogQuery 日志检索条件，From/To 为零值时不限制
+type LogQuery struct {
+	From   time.Time
+	To     time.Time
+	Filter LogFilter
+	Limit  int
+}
+
+// LogQueryResult 检索结果按时间正序排列，超出 Limit 时只保留最新的部分
+type LogQueryResult struct {
+	Items []*LogItem `json:"items"`
+	More  bool       `json:"more"` // 还有更早的匹配结果，可缩小时间范围后再查
+}
+
+// files 返回日志文件，旧的轮转文件在前，当前文件在最后
+func (s *LogStore) files() ([]string, error) {
+	ext := filepath.Ext(logStoreFile)
+	prefix := strings.TrimSuffix(logStoreFile, ext) + "-"
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var backups []string
+	hasCurrent := false
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case name == logStoreFile:
+			hasCurrent = true
+		case strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ext):
+			// 轮转文件名中的时间戳可以直接按字符串排序
+			backups = append(backups, name)
+		}
+	}
+	sort.Strings(backups)
+	if hasCurrent {
+		backups = append(backups, logStoreFile)
+	}
+	for i, name := range backups {
+		backups[i] = filepath.Join(s.dir, name)
+	}
+	return backups, nil
+}
+
+func (s *LogStore) Query(q LogQuery) (*LogQueryResult, error) {
+	if q.Limit <= 0 || q.Limit > logQueryMaxLimit {
+		q.Limit = logQueryMaxLimit
+	}
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LogQueryResult{Items: []*LogItem{}}
+	for _, fn := range files {
+		// 轮转文件最后修改的时间早于查询范围时，其中不会有需要的日志
+		if !q.From.IsZero() {
+			if st, statErr := os.Stat(fn); statErr == nil && st.ModTime().Before(q.From) {
+				continue
+			}
+		}
+		if err = s.scan(fn, &q, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *LogStore) scan(fn string, q *LogQuery, result *LogQueryResult) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// 查询过程中发生了轮转
+			return nil
+		}
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	from := float64(q.From.UnixMilli()) / 1000
+	to := float64(q.To.UnixMilli()) / 1000
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		item, ok := parseLogLine(scanner.Bytes())
+		if !ok {
+			continue
+		}
+		if !q.From.IsZero() && item.TS < from {
+			continue
+		}
+		if !q.To.IsZero() && item.TS > to {
+			continue
+		}
+		if !q.Filter.Match(item) {
+			continue
+		}
+		result.Items = append(result.Items, item)
+		if len(result.Items) > q.Limit {
+			// 只保留最新的 Limit 条，攒够一倍再整体移动
+			if len(result.Items) >= 2*q.Limit {
+				result.Items = append(result.Items[:0], result.Items[len(result.Items)-q.Limit:]...)
+			}
+			result.More = true
+		}
+	}
+	if len(result.Items) > q.Limit {
+		result.Items = append(result.Items[:0], result.Items[len(result.Items)-q.Limit:]...)
+	}
+	return scanner.Err()
+}
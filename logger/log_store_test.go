@@ -0,0 +1,106 @@
+//nolint:testpackage // These tests need access to unexported routing cores.
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newTestLogger(t *testing.T) (*zap.SugaredLogger, *UIWriter, *LogStore) {
+	t.Helper()
+	dir := t.TempDir()
+	ui := NewUIWriter()
+	store := NewLogStore(filepath.Join(dir, "logs"))
+	t.Cleanup(func() { _ = store.Close() })
+	core := newLoggerCore(zapcore.InfoLevel, ui, store, &testWriteSyncer{}, dir)
+	return zap.New(core).Sugar(), ui, store
+}
+
+func TestUIWriterSubscribers(t *testing.T) {
+	log, ui, _ := newTestLogger(t)
+	log.Named(LogKeyMain).Info("before")
+
+	warnOnly, _ := ParseLogFilter("warn", "", "")
+	adapterOnly, _ := ParseLogFilter("", LogKeyAdapter, "")
+	a, history := ui.Subscribe(warnOnly, 0, 10)
+	defer ui.Unsubscribe(a)
+	b, _ := ui.Subscribe(adapterOnly, 0, 10)
+	defer ui.Unsubscribe(b)
+	if len(history) != 0 {
+		t.Fatalf("history = %v, want none matching warn", history)
+	}
+
+	log.Named(LogKeyAdapter).Info("connected")
+	log.Named(LogKeyMain).Warn("disk almost full")
+
+	// 两个订阅者各自收到自己过滤条件下的日志，互不影响
+	if item := <-a.C; item.Msg != "disk almost full" {
+		t.Fatalf("warn subscriber got %q", item.Msg)
+	}
+	if item := <-b.C; item.Msg != "connected" || item.Module != LogKeyAdapter {
+		t.Fatalf("adapter subscriber got %+v", item)
+	}
+	select {
+	case item := <-b.C:
+		t.Fatalf("adapter subscriber got unexpected %q", item.Msg)
+	default:
+	}
+
+	// 按序号续传
+	items := ui.Snapshot()
+	_, history = ui.Subscribe(LogFilter{MinLevel: zapcore.DebugLevel}, items[0].ID, 10)
+	if len(history) != 2 || history[0].Msg != "connected" {
+		t.Fatalf("resume history = %v", history)
+	}
+	if len(ui.Snapshot()) != 3 {
+		t.Fatal("reading must not drain the recent log")
+	}
+}
+
+func TestLogStoreQuery(t *testing.T) {
+	log, _, store := newTestLogger(t)
+	log.Named(LogKeyMain).Info("dice started")
+	log.Named(LogKeyAdapter).Warn("QQ connection lost")
+	log.Named(LogKeyDatabaseQuery).Info("select 1")
+	log.Named(LogKeyDatabase).Error("database locked")
+
+	all, err := store.Query(LogQuery{Filter: LogFilter{MinLevel: zapcore.DebugLevel}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// database.query 不写入
+	if len(all.Items) != 3 || all.More {
+		t.Fatalf("Query() = %+v", all.Items)
+	}
+
+	filter, _ := ParseLogFilter("", "adapter,database", "")
+	got, _ := store.Query(LogQuery{Filter: filter, Limit: 1})
+	if len(got.Items) != 1 || got.Items[0].Msg != "database locked" || !got.More {
+		t.Fatalf("Query(module, limit) = %+v more=%v", got.Items, got.More)
+	}
+
+	filter, _ = ParseLogFilter("", "", "connection")
+	got, _ = store.Query(LogQuery{Filter: filter})
+	if len(got.Items) != 1 || got.Items[0].Level != "warn" {
+		t.Fatalf("Query(text) = %+v", got.Items)
+	}
+
+	got, _ = store.Query(LogQuery{From: time.Now().Add(time.Hour), Filter: filter})
+	if len(got.Items) != 0 {
+		t.Fatalf("Query(future) = %+v", got.Items)
+	}
+
+	// 轮转后的旧文件也会被检索
+	_ = store.Close()
+	data, _ := os.ReadFile(filepath.Join(store.dir, logStoreFile))
+	_ = os.WriteFile(filepath.Join(store.dir, "system-2020-01-01T00-00-00.000.jsonl"), data, 0o644)
+	got, _ = store.Query(LogQuery{Filter: filter})
+	if len(got.Items) != 2 {
+		t.Fatalf("Query() over rotated files = %d items", len(got.Items))
+	}
+}
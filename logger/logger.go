@@ -23,7 +23,8 @@ const (
 var DefaultSealLogger = NewGormLogger(zap.NewNop())
 
 func InitLogger(level zapcore.Level, ui *UIWriter) *zap.SugaredLogger {
-	core := newLoggerCore(level, ui, zapcore.AddSync(os.Stdout), "data")
+	DefaultLogStore = NewLogStore(filepath.Join("data", "logs"))
+	core := newLoggerCore(level, ui, DefaultLogStore, zapcore.AddSync(os.Stdout), "data")
 	logger := zap.New(core, zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel))
 
 	DefaultSealLogger = NewGormLogger(logger)
@@ -32,12 +33,13 @@ func InitLogger(level zapcore.Level, ui *UIWriter) *zap.SugaredLogger {
 	return logger.Sugar()
 }
 
-func newLoggerCore(level zapcore.Level, ui *UIWriter, consoleSink zapcore.WriteSyncer, rootDir string) zapcore.Core {
+func newLoggerCore(level zapcore.Level, ui *UIWriter, store *LogStore, consoleSink zapcore.WriteSyncer, rootDir string) zapcore.Core {
 	consoleEncoder := newEncoder(true)
 	jsonEncoder := newEncoder(false)
 
 	consoleWriter := zapcore.NewCore(consoleEncoder, consoleSink, level)
 	uiWriter := zapcore.NewCore(jsonEncoder, zapcore.AddSync(ui), level)
+	storeWriter := zapcore.NewCore(jsonEncoder, store, level)
 
 	levelConfig := map[string]zapcore.Level{
 		LogKeyMain:          level,
@@ -51,6 +53,7 @@ func newLoggerCore(level zapcore.Level, ui *UIWriter, consoleSink zapcore.WriteS
 		newDynamicFileCore(rootDir, consoleEncoder, levelConfig, level),
 		newVisibilityCore(consoleWriter, level),
 		newVisibilityCore(uiWriter, level),
+		newVisibilityCore(storeWriter, level),
 	)
 }
 
@@ -3,6 +3,9 @@ package logger
 import (
 	"encoding/json"
 	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap/zapcore"
@@ -11,19 +14,91 @@ import (
 const (
 	logLimitDefault   = 100
 	timeFormatISO8601 = "2006-01-02T15:04:05.000Z0700"
+
+	// 订阅者的缓冲区大小，读取跟不上时丢弃新日志而不是阻塞写日志的一方
+	subscriberBuffer = 256
 )
 
 type LogItem struct {
+	ID     uint64  `json:"id,omitempty"` // 本次运行内递增的序号，用于断线后续传
 	Level  string  `json:"level"`
 	Module string  `json:"module"`
 	TS     float64 `json:"ts"`
 	Caller string  `json:"caller"`
 	Msg    string  `json:"msg"`
+
+	level zapcore.Level
+}
+
+// LogFilter 日志过滤条件，由 ParseLogFilter 构造
+type LogFilter struct {
+	MinLevel zapcore.Level // 最低级别
+	Modules  []string      // 模块名，同时匹配其子模块，如 database 包含 database.query
+	Text     string        // 消息中包含的文字，不区分大小写
+}
+
+// ParseLogFilter 从接口参数构造过滤条件，modules 以逗号分隔
+func ParseLogFilter(level, modules, text string) (LogFilter, error) {
+	f := LogFilter{MinLevel: zapcore.DebugLevel, Text: text}
+	if level != "" {
+		lvl, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return f, err
+		}
+		f.MinLevel = lvl
+	}
+	for _, m := range strings.Split(modules, ",") {
+		if m = strings.ToLower(strings.TrimSpace(m)); m != "" {
+			f.Modules = append(f.Modules, m)
+		}
+	}
+	return f, nil
+}
+
+func (f *LogFilter) Match(item *LogItem) bool {
+	if item.level < f.MinLevel {
+		return false
+	}
+	if len(f.Modules) > 0 {
+		module := strings.ToLower(item.Module)
+		ok := false
+		for _, m := range f.Modules {
+			if module == m || strings.HasPrefix(module, m+".") {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if f.Text != "" && !strings.Contains(strings.ToLower(item.Msg), strings.ToLower(f.Text)) {
+		return false
+	}
+	return true
+}
+
+// LogSubscription 实时日志的一个订阅者
+type LogSubscription struct {
+	C       <-chan *LogItem
+	ch      chan *LogItem
+	filter  LogFilter
+	dropped atomic.Uint64
+}
+
+// Dropped 因读取过慢被丢弃的日志条数
+func (s *LogSubscription) Dropped() uint64 {
+	return s.dropped.Load()
 }
 
+// UIWriter 保存最近的日志供 UI 展示，并把新日志推送给所有订阅者
 type UIWriter struct {
 	LogLimit int
 	Items    []*LogItem
+
+	mu     sync.RWMutex
+	nextID uint64
+	subs   map[*LogSubscription]struct{}
 }
 
 var _ io.Writer = (*UIWriter)(nil)
@@ -35,29 +110,94 @@ func NewUIWriter() *UIWriter {
 	}
 }
 
-func (l *UIWriter) Write(p []byte) (int, error) {
+// parseLogLine 解析 JSON 编码器输出的一行日志
+func parseLogLine(p []byte) (*LogItem, bool) {
 	var a struct {
 		Level  zapcore.Level `json:"level"`
 		Module string        `json:"module"`
 		Time   string        `json:"time"`
+		Caller string        `json:"caller"`
 		Msg    string        `json:"msg"`
 	}
-	err := json.Unmarshal(p, &a)
-	if err == nil {
-		ts, _ := time.Parse(timeFormatISO8601, a.Time)
-		l.Items = append(l.Items, &LogItem{
-			Level:  a.Level.String(),
-			Module: a.Module,
-			TS:     float64(ts.Unix()),
-			Caller: "",
-			Msg:    a.Msg,
-		})
-		if l.LogLimit == 0 {
-			l.LogLimit = logLimitDefault
+	if err := json.Unmarshal(p, &a); err != nil {
+		return nil, false
+	}
+	ts, _ := time.Parse(timeFormatISO8601, a.Time)
+	return &LogItem{
+		Level:  a.Level.String(),
+		Module: a.Module,
+		TS:     float64(ts.UnixMilli()) / 1000,
+		Caller: a.Caller,
+		Msg:    a.Msg,
+		level:  a.Level,
+	}, true
+}
+
+func (l *UIWriter) Write(p []byte) (int, error) {
+	item, ok := parseLogLine(p)
+	if !ok {
+		return len(p), nil
+	}
+	// 最近日志的展示保持原样，不带调用位置
+	item.Caller = ""
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.nextID++
+	item.ID = l.nextID
+	l.Items = append(l.Items, item)
+	if l.LogLimit == 0 {
+		l.LogLimit = logLimitDefault
+	}
+	if len(l.Items) > l.LogLimit {
+		l.Items = l.Items[len(l.Items)-l.LogLimit:]
+	}
+	for s := range l.subs {
+		if !s.filter.Match(item) {
+			continue
 		}
-		if len(l.Items) > l.LogLimit {
-			l.Items = l.Items[1:]
+		select {
+		case s.ch <- item:
+		default:
+			s.dropped.Add(1)
 		}
 	}
 	return len(p), nil
 }
+
+// Snapshot 返回当前保存的最近日志的副本
+func (l *UIWriter) Snapshot() []*LogItem {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]*LogItem(nil), l.Items...)
+}
+
+// Subscribe 订阅实时日志。afterID 大于 0 时先补发内存中序号更大的日志，
+// 否则补发最近 backlog 条。使用完毕后须调用 Unsubscribe
+func (l *UIWriter) Subscribe(filter LogFilter, afterID uint64, backlog int) (*LogSubscription, []*LogItem) {
+	ch := make(chan *LogItem, subscriberBuffer)
+	s := &LogSubscription{C: ch, ch: ch, filter: filter}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var history []*LogItem
+	for _, item := range l.Items {
+		if item.ID > afterID && filter.Match(item) {
+			history = append(history, item)
+		}
+	}
+	if afterID == 0 && len(history) > backlog {
+		history = history[len(history)-backlog:]
+	}
+	if l.subs == nil {
+		l.subs = map[*LogSubscription]struct{}{}
+	}
+	l.subs[s] = struct{}{}
+	return s, history
+}
+
+func (l *UIWriter) Unsubscribe(s *LogSubscription) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.subs, s)
+}
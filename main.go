@@ -2,6 +2,7 @@ package main
 
 // _ "net/http/pprof"
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -28,10 +29,12 @@ import (
 	"sealdice-core/dice"
 	"sealdice-core/dice/service"
 	"sealdice-core/logger"
+	"sealdice-core/migrate/dbtransfer"
 	v2 "sealdice-core/migrate/v2"
 	"sealdice-core/static"
 	"sealdice-core/utils/crypto"
 	"sealdice-core/utils/dboperator"
+	operator "sealdice-core/utils/dboperator/engine"
 	"sealdice-core/utils/oschecker"
 	"sealdice-core/utils/paniclog"
 )
@@ -95,6 +98,9 @@ func cleanupCreate(diceManager *dice.DiceManager) func() {
 
 		for _, i := range diceManager.Dice {
 			d := i
+			if d.MessageIndex != nil {
+				d.MessageIndex.Close()
+			}
 			d.DBOperator.Close()
 		}
 
@@ -138,6 +144,62 @@ func deleteOldWrongFile() {
 	_ = os.Remove("./data/helpdoc/DND/子职列表大全.xlsx")
 }
 
+// migrateDatabase 将当前数据库迁移到另一个数据库，Ctrl+C 中断后重新执行会继续
+func migrateDatabase(source operator.DatabaseOperator, dbType string, dsn string, batch int) {
+	log := logger.M()
+	if dbType == "" {
+		log.Error("请通过 --migrate-db-type 指定目标数据库类型")
+		return
+	}
+	defer source.Close()
+	// 保证源库的表结构是最新的，与目标库一致
+	if err := v2.InitUpgrader(source); err != nil {
+		log.Errorf("源数据库升级失败，无法迁移: %v", err)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := dbtransfer.Run(ctx, source, dbtransfer.Options{
+		TargetType: dbType,
+		TargetDSN:  dsn,
+		BatchSize:  batch,
+		Logf:       log.Infof,
+	})
+	if err != nil {
+		log.Errorf("数据库迁移失败，重新执行相同命令可从中断处继续: %v", err)
+		return
+	}
+	log.Infof("数据库迁移完成，请将 DB_TYPE 改为 %s 并设置对应的连接信息后重新启动", dbType)
+}
+
+// restoreBackup 执行 --restore-backup，失败时返回 false 并停止启动
+func restoreBackup(fn string, only string) bool {
+	log := logger.M()
+	sel, err := dice.ParseBackupRestoreSelection(only)
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	info, err := dice.InspectBackup(fn)
+	if err != nil {
+		log.Errorf("无法读取备份 %s: %v", fn, err)
+		return false
+	}
+	log.Infof("开始从备份 %s 恢复数据，备份版本 %s", info.Name, info.Version)
+	result, err := dice.RestoreBackup(fn, sel)
+	if err != nil {
+		if result != nil && result.Snapshot != "" {
+			log.Errorf("恢复失败: %v，恢复前的文件已备份至 %s", err, result.Snapshot)
+		} else {
+			log.Errorf("恢复失败: %v", err)
+		}
+		return false
+	}
+	log.Infof("已恢复 %d 个文件，恢复前的文件已备份至 %s", len(result.Files), result.Snapshot)
+	return true
+}
+
 func fixTimezone() {
 	out, err := exec.Command("/system/bin/getprop", "persist.sys.timezone").Output()
 	if err != nil {
@@ -173,6 +235,11 @@ func main() {
 		ContainerMode          bool   `description:"容器模式，该模式下禁用内置客户端"                                                long:"container-mode"`
 		MutexProfileRate       int    `description:"对互斥锁竞用的采样速率，小于等于0=关闭，1=所有，其他N=N分之1采样率" long:"mutex-profile" default:"5"`
 		BlockProfileRate       int    `description:"对阻塞事件的采样速率，小于等于0=关闭，1=所有，其他N=每N纳秒1次采样" long:"block-profile" default:"5000"`
+		MigrateDBTo            string `description:"将当前数据库的全部数据迁移到目标数据库，sqlite 填数据目录，其余填DSN，中断后重新执行可继续" long:"migrate-db-to"`
+		MigrateDBType          string `choice:"sqlite" choice:"mysql" choice:"postgres" description:"迁移目标的数据库类型" long:"migrate-db-type"`
+		MigrateDBBatch         int    `default:"1000" description:"迁移数据库时每批复制的行数" long:"migrate-db-batch"`
+		RestoreBackup          string `description:"启动前从备份文件恢复数据，恢复前会自动备份将被覆盖的文件" long:"restore-backup"`
+		RestoreOnly            string `description:"只恢复指定部分，逗号分隔: global,js,decks,helpdoc,censor,names,images,dice 或 dice:<骰子名>，默认全部" long:"restore-only"`
 	}
 
 	// 读取命令行传参
@@ -262,6 +329,15 @@ func main() {
 
 	_ = os.MkdirAll("./data", 0o755)
 
+	// 恢复备份会替换数据库文件，必须在打开数据库之前进行
+	if opts.RestoreBackup != "" {
+		if !restoreBackup(opts.RestoreBackup, opts.RestoreOnly) {
+			return
+		}
+	} else {
+		dice.ApplyPendingRestore()
+	}
+
 	// 提早初始化是为了读取ServiceName
 
 	// diceManager初始化数据库
@@ -270,6 +346,10 @@ func main() {
 		log.Errorf("Failed to init database: %v", err)
 		return
 	}
+	if opts.MigrateDBTo != "" {
+		migrateDatabase(operator, opts.MigrateDBType, opts.MigrateDBTo, opts.MigrateDBBatch)
+		return
+	}
 	diceManager := &dice.DiceManager{
 		Operator: operator,
 	}
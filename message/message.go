@@ -15,6 +15,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/bytedance/sonic"
@@ -76,6 +77,22 @@ func EscapeCQParam(v string) string {
 	return safeV
 }
 
+// EscapeCQText 转义纯文本中的 & [ ]，使其不会被当作 CQ 码解析。
+// OneBot v11 的纯文本只还原这三种，逗号只在参数值中转义，否则会原样显示为 &#44;
+func EscapeCQText(v string) string {
+	safeV := strings.ReplaceAll(v, "&", "&amp;")
+	safeV = strings.ReplaceAll(safeV, "[", "&#91;")
+	safeV = strings.ReplaceAll(safeV, "]", "&#93;")
+	return safeV
+}
+
+var cqTextUnescaper = strings.NewReplacer("&#91;", "[", "&#93;", "]", "&amp;", "&")
+
+// UnescapeCQText 还原 EscapeCQText 的转义，不处理 &#44;
+func UnescapeCQText(v string) string {
+	return cqTextUnescaper.Replace(v)
+}
+
 // UnescapeCQParam 还原 EscapeCQParam 的转义，用于解析 CQ 码参数值。
 // 还原顺序需与转义顺序相反：其它占位符先还原，最后才还原 &amp;，避免二次解码。
 func UnescapeCQParam(v string) string {
@@ -119,6 +136,27 @@ const (
 	Default = -1               // 一个兜底的情况，兜底所有不认识的类型
 )
 
+var elementTypeNames = map[ElementType]string{
+	Text:    "text",
+	At:      "at",
+	File:    "file",
+	Image:   "image",
+	TTS:     "tts",
+	Reply:   "reply",
+	Record:  "record",
+	Face:    "face",
+	Poke:    "poke",
+	Default: "default",
+}
+
+// String 返回消息段类型名，与 CQ 码中的类型名一致
+func (t ElementType) String() string {
+	if name, ok := elementTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
 const maxFileSize = 1024 * 1024 * 50 // 50MB
 
 // ElementFactory 创建 IMessageElement 实例的工厂函数
@@ -675,8 +713,9 @@ func SealCodeToCqCode(text string) string {
 
 // convertConfig ConvertStringMessage的配置
 type convertConfig struct {
-	logger  *zap.SugaredLogger
-	onError func(err error, cqType string, cqArgs map[string]string)
+	logger       *zap.SugaredLogger
+	onError      func(err error, cqType string, cqArgs map[string]string)
+	unescapeText bool
 }
 
 // ConvertOption ConvertStringMessage的选项函数
@@ -696,6 +735,11 @@ func WithOnError(fn func(err error, cqType string, cqArgs map[string]string)) Co
 	return func(c *convertConfig) { c.onError = fn }
 }
 
+// WithCQTextUnescape 还原纯文本中的 CQ 转义，仅用于解析 ToCQCode 生成的消息
+func WithCQTextUnescape() ConvertOption {
+	return func(c *convertConfig) { c.unescapeText = true }
+}
+
 func ConvertStringMessage(raw string, opts ...ConvertOption) (r []IMessageElement) {
 	cfg := &convertConfig{
 		// 默认使用全局logger，确保控制台+前端日志可见
@@ -796,7 +840,11 @@ func ConvertStringMessage(raw string, opts ...ConvertOption) (r []IMessageElemen
 			i++
 		}
 		if i > 0 {
-			r = append(r, newText(text[:i]))
+			if cfg.unescapeText {
+				r = append(r, newText(UnescapeCQText(text[:i])))
+			} else {
+				r = append(r, newText(text[:i]))
+			}
 		}
 
 		if i+4 > len(text) {
@@ -847,3 +895,118 @@ func ConvertStringMessage(raw string, opts ...ConvertOption) (r []IMessageElemen
 	}
 	return r
 }
+
+// resourceURL 取资源消息段中可直接引用的地址
+func resourceURL(u string, f *FileElement) string {
+	if u != "" || f == nil {
+		return u
+	}
+	if f.URL != "" {
+		return f.URL
+	}
+	return f.File
+}
+
+// FallbackText 返回消息段在平台不支持该类型时的文本形式，回复等不可见的消息段返回空串
+func FallbackText(e IMessageElement) string {
+	switch v := e.(type) {
+	case *TextElement:
+		return v.Content
+	case *TTSElement:
+		return v.Content
+	case *AtElement:
+		if v.Target == "all" {
+			return "@全体成员"
+		}
+		return "@" + v.Target
+	case *ImageElement:
+		if u := resourceURL(v.URL, v.File); strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+			return "[图片:" + u + "]"
+		}
+		return "[图片]"
+	case *FileElement:
+		if v.File != "" {
+			return "[文件:" + path.Base(filepath.ToSlash(v.File)) + "]"
+		}
+		return "[文件]"
+	case *RecordElement:
+		return "[语音]"
+	case *FaceElement:
+		return "[表情:" + v.FaceID + "]"
+	case *PokeElement:
+		return "[戳一戳]"
+	case *DefaultElement:
+		return "[" + v.RawType + "]"
+	}
+	return ""
+}
+
+// ElementsToText 将消息段整体转为纯文本
+func ElementsToText(elems []IMessageElement) string {
+	var sb strings.Builder
+	for _, e := range elems {
+		sb.WriteString(FallbackText(e))
+	}
+	return sb.String()
+}
+
+// DegradeElements 将 supported 以外的消息段替换为文本，用于只支持部分消息段的平台
+func DegradeElements(elems []IMessageElement, supported ...ElementType) []IMessageElement {
+	ret := make([]IMessageElement, 0, len(elems))
+	for _, e := range elems {
+		if slices.Contains(supported, e.Type()) {
+			ret = append(ret, e)
+			continue
+		}
+		if text := FallbackText(e); text != "" {
+			ret = append(ret, newText(text))
+		}
+	}
+	return ret
+}
+
+// ToCQCode 将消息段还原为 CQ 码文本，是 ConvertStringMessage 的逆过程，供以文本为输入的发送流程使用
+func ToCQCode(elems []IMessageElement) string {
+	var sb strings.Builder
+	writeCQ := func(t string, args map[string]string) {
+		sb.WriteString((&CQCommand{Type: t, Args: args}).Compile())
+	}
+	for _, e := range elems {
+		switch v := e.(type) {
+		case *TextElement:
+			sb.WriteString(EscapeCQText(v.Content))
+		case *AtElement:
+			writeCQ("at", map[string]string{"qq": v.Target})
+		case *TTSElement:
+			writeCQ("tts", map[string]string{"text": v.Content})
+		case *ReplyElement:
+			writeCQ("reply", map[string]string{"id": v.ReplySeq})
+		case *FaceElement:
+			writeCQ("face", map[string]string{"id": v.FaceID})
+		case *PokeElement:
+			writeCQ("poke", map[string]string{"qq": v.Target})
+		case *ImageElement:
+			if u := resourceURL(v.URL, v.File); u != "" {
+				writeCQ("image", map[string]string{"file": u})
+			}
+		case *RecordElement:
+			if u := resourceURL("", v.File); u != "" {
+				writeCQ("record", map[string]string{"file": u})
+			}
+		case *FileElement:
+			if u := resourceURL("", v); u != "" {
+				writeCQ("file", map[string]string{"file": u})
+			}
+		case *DefaultElement:
+			args := map[string]string{}
+			var data map[string]any
+			if len(v.Data) > 0 && sonic.Unmarshal(v.Data, &data) == nil {
+				for k, val := range data {
+					args[k] = fmt.Sprint(val)
+				}
+			}
+			writeCQ(v.RawType, args)
+		}
+	}
+	return sb.String()
+}
@@ -321,3 +321,60 @@ func TestConvertStringMessageLeavesNonLocalVideoReferencesUnchanged(t *testing.T
 		t.Fatalf("video with URL converted to %#v", data)
 	}
 }
+
+func TestDegradeAndCQCodeRoundTrip(t *testing.T) {
+	elems := []message.IMessageElement{
+		&message.ReplyElement{ReplySeq: "12"},
+		&message.AtElement{Target: "all"},
+		&message.TextElement{Content: " 你好"},
+		&message.FaceElement{FaceID: "178"},
+		&message.ImageElement{URL: "https://example.com/a.png?x=1&y=2"},
+		&message.FileElement{File: "dir/note.txt"},
+	}
+
+	degraded := message.DegradeElements(elems, message.Text, message.At, message.Reply)
+	if got := message.ElementsToText(degraded); got != "@全体成员 你好[表情:178][图片:https://example.com/a.png?x=1&y=2][文件:note.txt]" {
+		t.Fatalf("unexpected degraded text %q", got)
+	}
+	if degraded[0].Type() != message.Reply || degraded[1].Type() != message.At {
+		t.Fatal("supported elements should be kept as is")
+	}
+
+	code := message.ToCQCode(elems[:4])
+	if code != "[CQ:reply,id=12][CQ:at,qq=all] 你好[CQ:face,id=178]" {
+		t.Fatalf("unexpected cq code %q", code)
+	}
+	back := message.ConvertStringMessage(code)
+	if len(back) != 4 || back[1].(*message.AtElement).Target != "all" || back[3].(*message.FaceElement).FaceID != "178" {
+		t.Fatalf("cq code should convert back, got %#v", back)
+	}
+}
+
+func TestCQCodeEscapesText(t *testing.T) {
+	// 复述用户输入时，文本中的 CQ 码不能变成真正的消息段
+	input := "你说: [CQ:at,qq=all] & [CQ:image,file=http://x/a.png]"
+	elems := []message.IMessageElement{
+		&message.TextElement{Content: input},
+		&message.AtElement{Target: "10001"},
+	}
+	code := message.ToCQCode(elems)
+	if strings.Count(code, "[CQ:") != 1 {
+		t.Fatalf("text should be escaped, got %q", code)
+	}
+	back := message.ConvertStringMessage(code, message.WithCQTextUnescape())
+	if len(back) != 2 || back[0].(*message.TextElement).Content != input || back[1].(*message.AtElement).Target != "10001" {
+		t.Fatalf("cq code should convert back, got %#v", back)
+	}
+}
+
+func TestConvertStringMessageKeepsEntities(t *testing.T) {
+	// 普通文本不是 CQ 转义的，不应被还原
+	input := "a &#44; b &#91;x&#93; &amp;"
+	back := message.ConvertStringMessage(input)
+	if len(back) != 1 || back[0].(*message.TextElement).Content != input {
+		t.Fatalf("plain text should be unchanged, got %#v", back)
+	}
+	if got := message.UnescapeCQText(input); got != "a &#44; b [x] &" {
+		t.Fatalf("UnescapeCQText() = %q", got)
+	}
+}
@@ -0,0 +1,275 @@
+// Package dbtransfer 在 SQLite、MySQL 和 PostgreSQL 之间迁移海豹的全部数据。
+// 数据按主键顺序分批复制并记录进度，中断后再次执行会从上次的位置继续。
+package dbtransfer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	v2 "sealdice-core/migrate/v2"
+	"sealdice-core/utils/constant"
+	"sealdice-core/utils/dboperator"
+	operator "sealdice-core/utils/dboperator/engine"
+)
+
+var (
+	ErrTargetNotEmpty = errors.New("目标数据库中已有数据，为避免混入旧数据，请使用空数据库")
+	ErrCountMismatch  = errors.New("迁移后行数不一致")
+)
+
+const (
+	defaultBatchSize    = 1000
+	defaultProgressFile = "./data/db-transfer-progress.json"
+)
+
+// Options 迁移参数
+type Options struct {
+	TargetType   string // sqlite、mysql 或 postgres
+	TargetDSN    string // sqlite 为数据目录
+	BatchSize    int
+	ProgressFile string
+	Logf         func(format string, args ...any)
+}
+
+// tableProgress 单张表的迁移进度
+type tableProgress struct {
+	Cursor string `json:"cursor"` // 已复制的最后一行主键
+	Copied int64  `json:"copied"`
+	Done   bool   `json:"done"`
+}
+
+type progress struct {
+	Target string                    `json:"target"` // 目标库的指纹，目标变化时进度作废
+	Tables map[string]*tableProgress `json:"tables"`
+}
+
+func targetFingerprint(dbType, dsn string) string {
+	sum := sha256.Sum256([]byte(dbType + "\x00" + dsn))
+	return hex.EncodeToString(sum[:])
+}
+
+func loadProgress(path, target string) (*progress, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return &progress{Target: target, Tables: map[string]*tableProgress{}}, false
+	}
+	var p progress
+	if err = json.Unmarshal(data, &p); err != nil || p.Target != target || p.Tables == nil {
+		return &progress{Target: target, Tables: map[string]*tableProgress{}}, false
+	}
+	return &p, true
+}
+
+func (p *progress) save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Run 把 source 中的数据复制到目标数据库并校验
+func Run(ctx context.Context, source operator.DatabaseOperator, opts Options) error {
+	logf := opts.Logf
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.ProgressFile == "" {
+		opts.ProgressFile = defaultProgressFile
+	}
+
+	target, err := dboperator.NewEngine(ctx, opts.TargetType, opts.TargetDSN)
+	if err != nil {
+		return fmt.Errorf("连接目标数据库失败: %w", err)
+	}
+	defer target.Close()
+
+	// 目标库的表结构由正常的升级流程创建，和直接在该数据库上启动海豹时一致
+	if err = v2.InitUpgrader(target); err != nil {
+		return fmt.Errorf("初始化目标数据库表结构失败: %w", err)
+	}
+
+	prog, resumed := loadProgress(opts.ProgressFile, targetFingerprint(opts.TargetType, opts.TargetDSN))
+	if resumed {
+		logf("发现上次未完成的迁移，将继续进行")
+	} else if err = checkTargetEmpty(target); err != nil {
+		return err
+	}
+	warnUnknownTables(source, logf)
+
+	for _, t := range transferTables {
+		if err = copyTable(ctx, source, target, t, prog, opts, logf); err != nil {
+			return fmt.Errorf("迁移 %s 失败: %w", t.name, err)
+		}
+	}
+
+	if target.Type() == constant.POSTGRESQL {
+		if err = resetSequences(target); err != nil {
+			return fmt.Errorf("重置自增序列失败: %w", err)
+		}
+	}
+	if err = verify(source, target, logf); err != nil {
+		return err
+	}
+	_ = os.Remove(opts.ProgressFile)
+	logf("数据库迁移完成")
+	return nil
+}
+
+func copyTable(ctx context.Context, source, target operator.DatabaseOperator, t tableSpec, prog *progress, opts Options, logf func(string, ...any)) error {
+	src, dst := t.kind.of(source), t.kind.of(target)
+	if !src.Migrator().HasTable(t.name) {
+		logf("源数据库中没有表 %s，跳过", t.name)
+		return nil
+	}
+	p := prog.Tables[t.name]
+	if p == nil {
+		p = &tableProgress{}
+		prog.Tables[t.name] = p
+	}
+	if p.Done {
+		logf("%s 已迁移 %d 行，跳过", t.name, p.Copied)
+		return nil
+	}
+
+	var total int64
+	if err := src.Table(t.name).Count(&total).Error; err != nil {
+		return err
+	}
+	logf("开始迁移 %s，共 %d 行", t.name, total)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		cursor, n, err := t.copyBatch(src, dst, p.Cursor, opts.BatchSize)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		p.Cursor = cursor
+		p.Copied += int64(n)
+		if err = prog.save(opts.ProgressFile); err != nil {
+			return err
+		}
+		logf("%s: %d/%d", t.name, p.Copied, total)
+	}
+	p.Done = true
+	return prog.save(opts.ProgressFile)
+}
+
+// checkTargetEmpty 新开始的迁移要求目标表为空
+func checkTargetEmpty(target operator.DatabaseOperator) error {
+	for _, t := range transferTables {
+		db := t.kind.of(target)
+		if !db.Migrator().HasTable(t.name) {
+			continue
+		}
+		var n int64
+		if err := db.Table(t.name).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("%w: %s 中有 %d 行", ErrTargetNotEmpty, t.name, n)
+		}
+	}
+	return nil
+}
+
+// warnUnknownTables 提示源库中存在但不在迁移列表中的表
+func warnUnknownTables(source operator.DatabaseOperator, logf func(string, ...any)) {
+	known := map[string]bool{}
+	for _, t := range transferTables {
+		known[t.name] = true
+	}
+	seen := map[string]bool{}
+	for _, kind := range []dbKind{kindData, kindLog, kindCensor} {
+		tables, err := kind.of(source).Migrator().GetTables()
+		if err != nil {
+			continue
+		}
+		for _, name := range tables {
+			if known[name] || skippedTables[name] || seen[name] {
+				continue
+			}
+			seen[name] = true
+			logf("表 %s 不在迁移范围内，将不会被复制", name)
+		}
+	}
+}
+
+// resetSequences 显式写入主键后，PostgreSQL 的序列不会前进，需要手动对齐到最大值
+func resetSequences(target operator.DatabaseOperator) error {
+	for _, t := range transferTables {
+		if !t.numeric {
+			continue
+		}
+		db := t.kind.of(target)
+		sql := fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%[1]s', '%[2]s'), COALESCE((SELECT MAX(%[2]s) FROM %[1]s), 0) + 1, false)",
+			t.name, t.key)
+		if err := db.Exec(sql).Error; err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// verify 比较每张表的行数，并确认 log_items 与 logs 的对应关系没有变化
+func verify(source, target operator.DatabaseOperator, logf func(string, ...any)) error {
+	var mismatched []string
+	for _, t := range transferTables {
+		src, dst := t.kind.of(source), t.kind.of(target)
+		if !src.Migrator().HasTable(t.name) {
+			continue
+		}
+		var a, b int64
+		if err := src.Table(t.name).Count(&a).Error; err != nil {
+			return err
+		}
+		if err := dst.Table(t.name).Count(&b).Error; err != nil {
+			return err
+		}
+		if a != b {
+			mismatched = append(mismatched, fmt.Sprintf("%s(源 %d, 目标 %d)", t.name, a, b))
+			continue
+		}
+		logf("校验 %s: %d 行", t.name, a)
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("%w: %v", ErrCountMismatch, mismatched)
+	}
+
+	if !kindLog.of(source).Migrator().HasTable("log_items") {
+		return nil
+	}
+	const orphanSQL = "SELECT COUNT(1) FROM log_items WHERE NOT EXISTS (SELECT 1 FROM logs WHERE logs.id = log_items.log_id)"
+	var a, b int64
+	if err := kindLog.of(source).Raw(orphanSQL).Scan(&a).Error; err != nil {
+		return err
+	}
+	if err := kindLog.of(target).Raw(orphanSQL).Scan(&b).Error; err != nil {
+		return err
+	}
+	if a != b {
+		return fmt.Errorf("%w: 找不到所属日志的 log_items 源 %d 行, 目标 %d 行", ErrCountMismatch, a, b)
+	}
+	return nil
+}
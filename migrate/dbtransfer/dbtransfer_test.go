@@ -0,0 +1,104 @@
+package dbtransfer //nolint:testpackage
+
+import (
+	"path/filepath"
+	"testing"
+
+	v2 "sealdice-core/migrate/v2"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	"sealdice-core/utils/dboperator"
+	operator "sealdice-core/utils/dboperator/engine"
+)
+
+func newTestEngine(t *testing.T, dir string) operator.DatabaseOperator {
+	t.Helper()
+	op, err := dboperator.NewEngine(t.Context(), constant.SQLITE, dir)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	if err = v2.InitUpgrader(op); err != nil {
+		t.Fatalf("InitUpgrader() error = %v", err)
+	}
+	return op
+}
+
+func TestRunResumesAndKeepsIDs(t *testing.T) {
+	source := newTestEngine(t, t.TempDir())
+	defer source.Close()
+
+	dataDB := source.GetDataDB(constant.WRITE)
+	logDB := source.GetLogDB(constant.WRITE)
+	updatedAt := int64(4)
+	// updated_at 为空的行也要原样复制
+	groups := []groupInfoRow{{ID: "QQ-Group:1", CreatedAt: 1}, {ID: "QQ-Group:2", CreatedAt: 2, UpdatedAt: &updatedAt}, {ID: "QQ-Group:3", CreatedAt: 3}}
+	if err := dataDB.Create(&groups).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := dataDB.Create(&model.UIAuditLog{ID: 7, User: "mod", Path: "/sd-api/banconfig/map_add_one"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := logDB.Create(&[]model.LogInfo{{ID: 5, Name: "a", GroupID: "QQ-Group:1"}, {ID: 9, Name: "b", GroupID: "QQ-Group:2"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	items := []model.LogOneItem{
+		{ID: 3, LogID: 5, Message: "1"},
+		{ID: 4, LogID: 9, Message: "2"},
+		{ID: 10, LogID: 5, Message: "3", CommandInfo: map[string]any{"cmd": "roll"}},
+	}
+	if err := logDB.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟中断：group_info 的第一行已写入目标库，但进度只记录到它之前
+	targetDir := t.TempDir()
+	pre := newTestEngine(t, targetDir)
+	if err := pre.GetDataDB(constant.WRITE).Create(&groupInfoRow{ID: "QQ-Group:1", CreatedAt: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	pre.Close()
+	progressFile := filepath.Join(t.TempDir(), "progress.json")
+	prog := &progress{Target: targetFingerprint(constant.SQLITE, targetDir), Tables: map[string]*tableProgress{}}
+	if err := prog.save(progressFile); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Run(t.Context(), source, Options{
+		TargetType:   constant.SQLITE,
+		TargetDSN:    targetDir,
+		BatchSize:    2,
+		ProgressFile: progressFile,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	target := newTestEngine(t, targetDir)
+	defer target.Close()
+	var gotGroups []groupInfoRow
+	target.GetDataDB(constant.READ).Order("id").Find(&gotGroups)
+	if len(gotGroups) != 3 || gotGroups[0].UpdatedAt != nil || gotGroups[1].UpdatedAt == nil || *gotGroups[1].UpdatedAt != updatedAt {
+		t.Fatalf("group_info rows = %+v", gotGroups)
+	}
+	var audits []model.UIAuditLog
+	target.GetDataDB(constant.READ).Find(&audits)
+	if len(audits) != 1 || audits[0].ID != 7 || audits[0].User != "mod" {
+		t.Fatalf("ui_audit_logs rows = %+v", audits)
+	}
+	var got []model.LogOneItem
+	if err = target.GetLogDB(constant.READ).Order("id").Find(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].ID != 3 || got[1].LogID != 9 || got[2].ID != 10 || got[2].LogID != 5 {
+		t.Fatalf("log_items not copied with their ids: %+v", got)
+	}
+	if got[2].CommandInfoStr != items[2].CommandInfoStr {
+		t.Fatalf("command_info = %q, want %q", got[2].CommandInfoStr, items[2].CommandInfoStr)
+	}
+
+	// 新开始的迁移拒绝写入已有数据的目标库
+	err = Run(t.Context(), source, Options{TargetType: constant.SQLITE, TargetDSN: targetDir, ProgressFile: progressFile})
+	if err == nil {
+		t.Fatal("migrating into a non-empty target should fail")
+	}
+}
@@ -0,0 +1,121 @@
+package dbtransfer
+
+import (
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+)
+
+// dbKind 数据表所在的库，SQLite 下是三个独立文件，其他数据库共用一个库
+type dbKind int
+
+const (
+	kindData dbKind = iota
+	kindLog
+	kindCensor
+)
+
+func (k dbKind) of(op operator.DatabaseOperator) *gorm.DB {
+	switch k {
+	case kindLog:
+		return op.GetLogDB(constant.WRITE)
+	case kindCensor:
+		return op.GetCensorDB(constant.WRITE)
+	default:
+		return op.GetDataDB(constant.WRITE)
+	}
+}
+
+// tableSpec 一张待迁移的表，按主键顺序分批复制
+type tableSpec struct {
+	name    string
+	kind    dbKind
+	key     string
+	numeric bool // 主键为自增整数，迁移到 PostgreSQL 后需要重置序列
+	// copyBatch 复制主键大于 cursor 的至多 limit 行，返回本批最后一行的主键
+	copyBatch func(src, dst *gorm.DB, cursor string, limit int) (string, int, error)
+}
+
+func newTable[T any](name string, kind dbKind, key string, numeric bool, keyOf func(*T) string) tableSpec {
+	return tableSpec{
+		name:    name,
+		kind:    kind,
+		key:     key,
+		numeric: numeric,
+		copyBatch: func(src, dst *gorm.DB, cursor string, limit int) (string, int, error) {
+			var rows []T
+			// 跳过钩子，原样复制数据库中的值
+			q := src.Session(&gorm.Session{SkipHooks: true}).Order(key).Limit(limit)
+			if cursor != "" {
+				if numeric {
+					n, err := strconv.ParseUint(cursor, 10, 64)
+					if err != nil {
+						return cursor, 0, err
+					}
+					q = q.Where(key+" > ?", n)
+				} else {
+					q = q.Where(key+" > ?", cursor)
+				}
+			}
+			if err := q.Find(&rows).Error; err != nil {
+				return cursor, 0, err
+			}
+			if len(rows) == 0 {
+				return cursor, 0, nil
+			}
+			// 中断后重跑时，上一批可能已写入但进度未保存，已存在的行直接跳过
+			err := dst.Session(&gorm.Session{SkipHooks: true}).
+				Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&rows).Error
+			if err != nil {
+				return cursor, 0, err
+			}
+			return keyOf(&rows[len(rows)-1]), len(rows), nil
+		},
+	}
+}
+
+// groupInfoRow 对应 group_info 表。model.GroupInfo 的 updated_at 为指针，
+// 为空时 GORM 的自动时间戳会写入失败，这里关闭自动时间戳以原样复制
+type groupInfoRow struct {
+	ID        string `gorm:"column:id;primaryKey"`
+	CreatedAt int64  `gorm:"column:created_at;autoCreateTime:false"`
+	UpdatedAt *int64 `gorm:"column:updated_at;autoUpdateTime:false"`
+	Data      []byte `gorm:"column:data"`
+}
+
+func (*groupInfoRow) TableName() string {
+	return "group_info"
+}
+
+func uintKey(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
+
+// transferTables 迁移的表及其顺序。logs 必须先于 log_items 复制
+var transferTables = []tableSpec{
+	newTable("group_info", kindData, "id", false, func(m *groupInfoRow) string { return m.ID }),
+	newTable("group_player_info", kindData, "id", true, func(m *model.GroupPlayerInfoBase) string { return uintKey(uint64(m.ID)) }),
+	newTable("ban_info", kindData, "id", false, func(m *model.BanInfo) string { return m.ID }),
+	newTable("endpoint_info", kindData, "user_id", false, func(m *model.EndpointInfo) string { return m.UserID }),
+	newTable("attrs", kindData, "id", false, func(m *model.AttributesItemModel) string { return m.Id }),
+	newTable("ban_events", kindData, "id", true, func(m *model.BanEvent) string { return uintKey(m.ID) }),
+	newTable("ban_appeals", kindData, "id", true, func(m *model.BanAppeal) string { return uintKey(m.ID) }),
+	newTable("platform_mappings", kindData, "im_user_id", false, func(m *model.PlatformMapping) string { return m.IMUserID }),
+	newTable("group_schedules", kindData, "id", true, func(m *model.GroupSchedule) string { return uintKey(m.ID) }),
+	newTable("ui_audit_logs", kindData, "id", true, func(m *model.UIAuditLog) string { return uintKey(m.ID) }),
+	newTable("logs", kindLog, "id", true, func(m *model.LogInfo) string { return uintKey(m.ID) }),
+	newTable("log_items", kindLog, "id", true, func(m *model.LogOneItem) string { return uintKey(m.ID) }),
+	newTable("censor_log", kindCensor, "id", true, func(m *model.CensorLog) string { return uintKey(m.ID) }),
+}
+
+// 不需要迁移的表：升级记录由目标库自己的升级流程生成
+var skippedTables = map[string]bool{
+	"upgrade_records": true,
+	"sqlite_sequence": true,
+}
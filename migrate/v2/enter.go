@@ -11,6 +11,7 @@ import (
 	v151 "sealdice-core/migrate/v2/v151"
 	v160 "sealdice-core/migrate/v2/v160"
 	v161 "sealdice-core/migrate/v2/v161"
+	v170 "sealdice-core/migrate/v2/v170"
 	"sealdice-core/utils/constant"
 	operator "sealdice-core/utils/dboperator/engine"
 	upgrade "sealdice-core/utils/upgrader"
@@ -44,6 +45,11 @@ func InitUpgrader(operator operator.DatabaseOperator) error {
 	// v161注册
 	mgr.Register(v161.V161NoticeIDsMigration)
 	mgr.Register(v161.V161LogUpdatedAtRepairMigration)
+	// v170注册
+	mgr.Register(v170.V170BanEventMigration)
+	mgr.Register(v170.V170PlatformMappingMigration)
+	mgr.Register(v170.V170GroupScheduleMigration)
+	mgr.Register(v170.V170UIAuditMigration)
 	err := mgr.ApplyAll()
 	if err != nil {
 		return err
@@ -0,0 +1,96 @@
+package v170
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+// legacyBanItem ban_info.data 中与历史记录相关的字段
+type legacyBanItem struct {
+	ID      string   `json:"ID"`
+	Name    string   `json:"name"`
+	Rank    int      `json:"rank"`
+	Times   []int64  `json:"times"`
+	Reasons []string `json:"reasons"`
+	Places  []string `json:"places"`
+}
+
+// V170BanEventMigrate 建立 ban_events / ban_appeals 表，并将 ban_info 中旧的原因列表导入为事件记录。
+// 仅在 ban_events 表首次创建时导入，重复执行不会产生重复记录。
+func V170BanEventMigrate(dboperator operator.DatabaseOperator, logf func(string)) error {
+	db := dboperator.GetDataDB(constant.WRITE)
+	existed := db.Migrator().HasTable(&model.BanEvent{})
+	if err := db.AutoMigrate(&model.BanEvent{}, &model.BanAppeal{}); err != nil {
+		return err
+	}
+	if existed {
+		logf("数据修复 - ban_events 表已存在，跳过旧记录导入")
+		return nil
+	}
+	if !db.Migrator().HasTable(&model.BanInfo{}) {
+		return nil
+	}
+
+	count := 0
+	var rows []model.BanInfo
+	err := db.Select("id, data").FindInBatches(&rows, 200, func(tx *gorm.DB, _ int) error {
+		var events []model.BanEvent
+		for _, row := range rows {
+			var item legacyBanItem
+			if json.Unmarshal(row.Data, &item) != nil {
+				continue
+			}
+			for index, reason := range item.Reasons {
+				event := model.BanEvent{
+					TargetID:   row.ID,
+					TargetName: item.Name,
+					Action:     model.BanEventActionImport,
+					Handler:    "legacy",
+					Reason:     reason,
+					RankAfter:  item.Rank,
+				}
+				if index < len(item.Places) {
+					event.Place = item.Places[index]
+				}
+				if index < len(item.Times) {
+					event.CreatedAt = item.Times[index]
+				}
+				events = append(events, event)
+			}
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		count += len(events)
+		return db.CreateInBatches(events, 200).Error
+	}).Error
+	if err != nil {
+		return err
+	}
+	logf(fmt.Sprintf("数据修复 - 已从 ban_info 导入 %d 条黑名单记录", count))
+	return nil
+}
+
+var V170BanEventMigration = upgrade.Upgrade{
+	ID: "013_V170BanEventMigration",
+	Description: `
+# 升级说明
+新建黑名单事件(ban_events)与申诉(ban_appeals)表，并导入黑名单中已有的原因记录
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		logf("[INFO] V170黑名单记录迁移开始")
+		err := V170BanEventMigrate(dbOperator, logf)
+		if err != nil {
+			return err
+		}
+		logf("[INFO] V170黑名单记录迁移处置完毕")
+		return nil
+	},
+}
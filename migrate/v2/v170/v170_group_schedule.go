@@ -0,0 +1,25 @@
+package v170
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+var V170GroupScheduleMigration = upgrade.Upgrade{
+	ID: "015_V170GroupScheduleMigration",
+	Description: `
+# 升级说明
+新建群日程提醒(group_schedules)表
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		logf("[INFO] V170日程提醒表迁移开始")
+		db := dbOperator.GetDataDB(constant.WRITE)
+		if err := db.AutoMigrate(&model.GroupSchedule{}); err != nil {
+			return err
+		}
+		logf("[INFO] V170日程提醒表迁移处置完毕")
+		return nil
+	},
+}
@@ -0,0 +1,25 @@
+package v170
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+var V170PlatformMappingMigration = upgrade.Upgrade{
+	ID: "014_V170PlatformMappingMigration",
+	Description: `
+# 升级说明
+新建跨平台账号关联(platform_mappings)表
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		logf("[INFO] V170账号关联表迁移开始")
+		db := dbOperator.GetDataDB(constant.WRITE)
+		if err := db.AutoMigrate(&model.PlatformMapping{}); err != nil {
+			return err
+		}
+		logf("[INFO] V170账号关联表迁移处置完毕")
+		return nil
+	},
+}
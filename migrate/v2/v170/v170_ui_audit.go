@@ -0,0 +1,25 @@
+package v170
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+var V170UIAuditMigration = upgrade.Upgrade{
+	ID: "016_V170UIAuditMigration",
+	Description: `
+# 升级说明
+新建 WebUI 操作记录(ui_audit_logs)表
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		logf("[INFO] V170操作记录表迁移开始")
+		db := dbOperator.GetDataDB(constant.WRITE)
+		if err := db.AutoMigrate(&model.UIAuditLog{}); err != nil {
+			return err
+		}
+		logf("[INFO] V170操作记录表迁移处置完毕")
+		return nil
+	},
+}
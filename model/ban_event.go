@@ -0,0 +1,59 @@
+package model
+
+// 黑名单事件类型
+const (
+	BanEventActionScore  = "score"  // 加分，等级未变化
+	BanEventActionWarn   = "warn"   // 进入警告
+	BanEventActionBan    = "ban"    // 进入黑名单
+	BanEventActionTrust  = "trust"  // 加入信任
+	BanEventActionUnban  = "unban"  // 手动解除
+	BanEventActionExpire = "expire" // 到期自动解除
+	BanEventActionDelete = "delete" // 从名单中删除
+	BanEventActionImport = "import" // 从旧版记录导入
+)
+
+// 申诉状态
+const (
+	BanAppealStatusPending  = "pending"
+	BanAppealStatusAccepted = "accepted"
+	BanAppealStatusRejected = "rejected"
+)
+
+// BanEvent 黑名单事件，每次加分、拉黑、信任、解除都会记录一行，用于审计
+type BanEvent struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement;column:id"               json:"id"`
+	TargetID   string `gorm:"index:idx_ban_event_target_id;column:target_id"   json:"targetId"`
+	TargetName string `gorm:"column:target_name"                               json:"targetName"`
+	Action     string `gorm:"index:idx_ban_event_action;column:action"         json:"action"`
+	Handler    string `gorm:"column:handler"                                   json:"handler"`  // 触发来源，如 group_kicked、censor、command
+	Operator   string `gorm:"column:operator"                                  json:"operator"` // 操作者，自动触发时为空
+	Place      string `gorm:"column:place"                                     json:"place"`
+	Reason     string `gorm:"column:reason"                                    json:"reason"`
+	Score      int64  `gorm:"column:score"                                     json:"score"`
+	RankBefore int    `gorm:"column:rank_before"                               json:"rankBefore"`
+	RankAfter  int    `gorm:"column:rank_after"                                json:"rankAfter"`
+	ExpireAt   int64  `gorm:"column:expire_at"                                 json:"expireAt"` // 0 为永久
+	CreatedAt  int64  `gorm:"index:idx_ban_event_created_at;column:created_at" json:"createdAt"`
+}
+
+func (*BanEvent) TableName() string {
+	return "ban_events"
+}
+
+// BanAppeal 黑名单申诉
+type BanAppeal struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement;column:id"             json:"id"`
+	TargetID   string `gorm:"index:idx_ban_appeal_target_id;column:target_id" json:"targetId"`
+	TargetName string `gorm:"column:target_name"                             json:"targetName"`
+	Place      string `gorm:"column:place"                                   json:"place"` // 提交申诉的位置
+	Content    string `gorm:"column:content"                                 json:"content"`
+	Status     string `gorm:"index:idx_ban_appeal_status;column:status"      json:"status"`
+	HandledBy  string `gorm:"column:handled_by"                              json:"handledBy"`
+	HandledAt  int64  `gorm:"column:handled_at"                              json:"handledAt"`
+	Reply      string `gorm:"column:reply"                                   json:"reply"`
+	CreatedAt  int64  `gorm:"column:created_at"                              json:"createdAt"`
+}
+
+func (*BanAppeal) TableName() string {
+	return "ban_appeals"
+}
@@ -0,0 +1,23 @@
+package model
+
+// GroupSchedule 群日程提醒
+type GroupSchedule struct {
+	ID          uint64 `gorm:"primaryKey;autoIncrement;column:id"                json:"id"`
+	GroupID     string `gorm:"index:idx_group_schedule_group_id;column:group_id" json:"groupId"`
+	EndpointID  string `gorm:"column:endpoint_id"                                json:"endpointId"` // 创建时所在的骰子账号，发送时优先使用
+	CreatorID   string `gorm:"column:creator_id"                                 json:"creatorId"`
+	CreatorName string `gorm:"column:creator_name"                               json:"creatorName"`
+	Spec        string `gorm:"column:spec"                                       json:"spec"`        // 用户输入的时间描述
+	CronExpr    string `gorm:"column:cron_expr"                                  json:"cronExpr"`    // 周期任务的 cron 表达式，一次性任务为空
+	RunAt       int64  `gorm:"column:run_at"                                     json:"runAt"`       // 一次性任务的活动时间
+	LeadMinutes int64  `gorm:"column:lead_minutes"                               json:"leadMinutes"` // 提前多少分钟提醒
+	Team        string `gorm:"column:team"                                       json:"team"`        // 提醒时@的队伍，来自 .team
+	Content     string `gorm:"column:content"                                    json:"content"`
+	Paused      bool   `gorm:"column:paused"                                     json:"paused"`
+	LastRunAt   int64  `gorm:"column:last_run_at"                                json:"lastRunAt"`
+	CreatedAt   int64  `gorm:"column:created_at"                                 json:"createdAt"`
+}
+
+func (*GroupSchedule) TableName() string {
+	return "group_schedules"
+}
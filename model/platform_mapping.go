@@ -0,0 +1,12 @@
+package model
+
+// PlatformMapping 统一ID - 平台用户ID 映射表，用于跨平台账号关联
+type PlatformMapping struct {
+	IMUserID  string `gorm:"primaryKey;column:im_user_id"            json:"imUserId"`  // IM平台的用户ID，一个账号只能关联到一个统一ID
+	Id        string `gorm:"index:idx_platform_mapping_id;column:id" json:"id"`        // 统一ID，格式为 U:nanoid 意为 User / Uniform / Universal
+	CreatedAt int64  `gorm:"column:created_at"                       json:"createdAt"` // 关联时间
+}
+
+func (*PlatformMapping) TableName() string {
+	return "platform_mappings"
+}
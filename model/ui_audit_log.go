@@ -0,0 +1,19 @@
+package model
+
+// UIAuditLog WebUI 与 API 的写操作记录，用于追查谁在何时改了什么
+type UIAuditLog struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement;column:id"                  json:"id"`
+	User      string `gorm:"index:idx_ui_audit_user;column:user_name"            json:"user"`
+	TokenID   string `gorm:"column:token_id"                                     json:"tokenId"` // 使用的令牌，便于吊销
+	Method    string `gorm:"column:method"                                       json:"method"`
+	Path      string `gorm:"index:idx_ui_audit_path;column:path"                 json:"path"` // 路由，如 /sd-api/banconfig/map_add_one
+	Query     string `gorm:"column:query"                                        json:"query"`
+	Detail    string `gorm:"column:detail"                                       json:"detail"` // 请求体摘要，敏感字段已隐去
+	Status    int    `gorm:"column:status"                                       json:"status"`
+	IP        string `gorm:"column:ip"                                           json:"ip"`
+	CreatedAt int64  `gorm:"index:idx_ui_audit_created_at;column:created_at"     json:"createdAt"`
+}
+
+func (*UIAuditLog) TableName() string {
+	return "ui_audit_logs"
+}
@@ -2,6 +2,7 @@ package dboperator
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"sync"
 
@@ -58,6 +59,26 @@ func GetDatabaseOperator() (operator.DatabaseOperator, error) {
 	return getEngine()
 }
 
+// NewEngine 创建一个独立于全局引擎的数据库连接，用于数据库迁移等场景。
+// dbType 为 sqlite 时 dsn 是数据目录，其余为数据库连接串
+func NewEngine(ctx context.Context, dbType string, dsn string) (operator.DatabaseOperator, error) {
+	var e operator.DatabaseOperator
+	switch dbType {
+	case constant.SQLITE:
+		e = &sqlite.SQLiteEngine{DataDir: dsn}
+	case constant.MYSQL:
+		e = &mysql.MYSQLEngine{DSN: dsn}
+	case constant.POSTGRESQL:
+		e = &pgsql.PGSQLEngine{DSN: dsn}
+	default:
+		return nil, fmt.Errorf("不支持的数据库类型: %s", dbType)
+	}
+	if err := e.Init(ctx); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
 // DBCheck 检查数据库状态
 func DBCheck() {
 	log := zap.S().Named(logger.LogKeyDatabase)
@@ -55,7 +55,10 @@ func (s *MYSQLEngine) Init(ctx context.Context) error {
 		return errors.New("ctx is missing")
 	}
 	s.ctx = ctx
-	s.DSN = os.Getenv("DB_DSN")
+	// 未预先指定 DSN 时从环境变量读取
+	if s.DSN == "" {
+		s.DSN = os.Getenv("DB_DSN")
+	}
 	if s.DSN == "" {
 		return errors.New("DB_DSN is missing")
 	}
@@ -103,7 +103,10 @@ func (s *SQLiteEngine) Init(ctx context.Context) error {
 		return errors.New("ctx is missing")
 	}
 	s.ctx = ctx
-	s.DataDir = os.Getenv("DATADIR")
+	// 未预先指定目录时从环境变量读取
+	if s.DataDir == "" {
+		s.DataDir = os.Getenv("DATADIR")
+	}
 	if s.DataDir == "" {
 		log.Debug("未能发现SQLITE定义位置，使用默认data地址")
 		s.DataDir = defaultDataDir
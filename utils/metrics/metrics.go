@@ -0,0 +1,332 @@
+// Package metrics 实现了一个很小的指标注册表，按 Prometheus 文本格式输出。
+// 只包含本项目用到的计数器、仪表和直方图，不依赖官方的 client_golang。
+package metrics
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefBuckets 默认的耗时分桶，单位为秒
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(b *strings.Builder)
+}
+
+// Registry 指标注册表，按注册顺序输出
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo 以 Prometheus 文本格式 (0.0.4) 输出所有指标
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	var b strings.Builder
+	for _, c := range collectors {
+		c.write(&b)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d *desc) writeHeader(b *strings.Builder) {
+	b.WriteString("# HELP ")
+	b.WriteString(d.name)
+	b.WriteByte(' ')
+	b.WriteString(strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(d.help))
+	b.WriteString("\n# TYPE ")
+	b.WriteString(d.name)
+	b.WriteByte(' ')
+	b.WriteString(d.typ)
+	b.WriteByte('\n')
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// writeSample 输出一行样本，extra 为附加的标签（如直方图的 le）
+func writeSample(b *strings.Builder, name string, labels, values []string, extra string, value float64) {
+	b.WriteString(name)
+	if len(labels) > 0 || extra != "" {
+		b.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(l)
+			b.WriteString(`="`)
+			b.WriteString(labelValueEscaper.Replace(values[i]))
+			b.WriteByte('"')
+		}
+		if extra != "" {
+			if len(labels) > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(extra)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// 标签值以 \xff 连接作为 map 的键，标签值中不会出现该字节
+const labelSep = "\xff"
+
+type series[T any] struct {
+	mu sync.RWMutex
+	m  map[string]*T
+}
+
+func (s *series[T]) get(values []string, newFn func() *T) *T {
+	key := strings.Join(values, labelSep)
+	s.mu.RLock()
+	v, ok := s.m[key]
+	s.mu.RUnlock()
+	if ok {
+		return v
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok = s.m[key]; ok {
+		return v
+	}
+	if s.m == nil {
+		s.m = map[string]*T{}
+	}
+	v = newFn()
+	s.m[key] = v
+	return v
+}
+
+// each 按标签值排序遍历，保证输出稳定
+func (s *series[T]) each(fn func(values []string, v *T)) {
+	s.mu.RLock()
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+	items := make(map[string]*T, len(s.m))
+	for k, v := range s.m {
+		items[k] = v
+	}
+	s.mu.RUnlock()
+	sort.Strings(keys)
+	for _, k := range keys {
+		fn(strings.Split(k, labelSep), items[k])
+	}
+}
+
+func (s *series[T]) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m = nil
+}
+
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) add(v float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+// Counter 单调递增的计数器
+type Counter struct {
+	v atomicFloat
+}
+
+func (c *Counter) Inc()          { c.v.add(1) }
+func (c *Counter) Add(v float64) { c.v.add(v) }
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	desc
+	s series[Counter]
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name: name, help: help, typ: "counter", labels: labels}}
+	r.register(c)
+	return c
+}
+
+// With 按标签值取得计数器，标签值的数量须与声明一致
+func (c *CounterVec) With(values ...string) *Counter {
+	return c.s.get(values, func() *Counter { return &Counter{} })
+}
+
+func (c *CounterVec) write(b *strings.Builder) {
+	c.writeHeader(b)
+	c.s.each(func(values []string, v *Counter) {
+		writeSample(b, c.name, c.labels, values, "", v.v.load())
+	})
+}
+
+// Gauge 可增可减的数值
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+func (g *Gauge) Set(v float64) { g.bits.Store(math.Float64bits(v)) }
+
+// GaugeVec 带标签的仪表
+type GaugeVec struct {
+	desc
+	s series[Gauge]
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{desc: desc{name: name, help: help, typ: "gauge", labels: labels}}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeVec) With(values ...string) *Gauge {
+	return g.s.get(values, func() *Gauge { return &Gauge{} })
+}
+
+// Reset 清空所有序列，用于账号被删除等标签值不再存在的情况
+func (g *GaugeVec) Reset() {
+	g.s.reset()
+}
+
+func (g *GaugeVec) write(b *strings.Builder) {
+	g.writeHeader(b)
+	g.s.each(func(values []string, v *Gauge) {
+		writeSample(b, g.name, g.labels, values, "", math.Float64frombits(v.bits.Load()))
+	})
+}
+
+// Histogram 直方图，记录观测值的分布
+type Histogram struct {
+	buckets []float64
+	counts  []atomic.Uint64
+	count   atomic.Uint64
+	sum     atomicFloat
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	if i < len(h.counts) {
+		h.counts[i].Add(1)
+	}
+	h.count.Add(1)
+	h.sum.add(v)
+}
+
+// ObserveSince 记录从 start 到现在经过的秒数
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	desc
+	buckets []float64
+	s       series[Histogram]
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{desc: desc{name: name, help: help, typ: "histogram", labels: labels}, buckets: buckets}
+	r.register(h)
+	return h
+}
+
+func (h *HistogramVec) With(values ...string) *Histogram {
+	return h.s.get(values, func() *Histogram {
+		return &Histogram{buckets: h.buckets, counts: make([]atomic.Uint64, len(h.buckets))}
+	})
+}
+
+func (h *HistogramVec) write(b *strings.Builder) {
+	h.writeHeader(b)
+	h.s.each(func(values []string, v *Histogram) {
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			cumulative += v.counts[i].Load()
+			writeSample(b, h.name+"_bucket", h.labels, values, `le="`+formatFloat(upper)+`"`, float64(cumulative))
+		}
+		count := v.count.Load()
+		writeSample(b, h.name+"_bucket", h.labels, values, `le="+Inf"`, float64(count))
+		writeSample(b, h.name+"_sum", h.labels, values, "", v.sum.load())
+		writeSample(b, h.name+"_count", h.labels, values, "", float64(count))
+	})
+}
+
+// FuncVec 在输出时才取值的指标，适合直接读取已有的统计数据
+type FuncVec struct {
+	desc
+	fn func(emit func(value float64, values ...string))
+}
+
+// NewGaugeFunc 注册一个输出时调用 fn 取值的仪表
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, fn func(emit func(value float64, values ...string))) {
+	r.register(&FuncVec{desc: desc{name: name, help: help, typ: "gauge", labels: labels}, fn: fn})
+}
+
+// NewCounterFunc 注册一个输出时调用 fn 取值的计数器
+func (r *Registry) NewCounterFunc(name, help string, labels []string, fn func(emit func(value float64, values ...string))) {
+	r.register(&FuncVec{desc: desc{name: name, help: help, typ: "counter", labels: labels}, fn: fn})
+}
+
+func (f *FuncVec) write(b *strings.Builder) {
+	f.writeHeader(b)
+	f.fn(func(value float64, values ...string) {
+		writeSample(b, f.name, f.labels, values, "", value)
+	})
+}
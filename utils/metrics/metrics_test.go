@@ -0,0 +1,59 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"sealdice-core/utils/metrics"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := metrics.NewRegistry()
+	msgs := r.NewCounterVec("test_messages_total", "Messages received.", "endpoint")
+	state := r.NewGaugeVec("test_state", "State.", "endpoint")
+	latency := r.NewHistogramVec("test_duration_seconds", "Duration.", []float64{0.1, 1})
+	r.NewGaugeFunc("test_func", "Func.", []string{"name"}, func(emit func(float64, ...string)) {
+		emit(3, `a"b`)
+	})
+
+	msgs.With("ep2").Inc()
+	msgs.With("ep1").Add(2)
+	msgs.With("ep2").Inc()
+	state.With("ep1").Set(1)
+	latency.With().Observe(0.05)
+	latency.With().Observe(0.5)
+	latency.With().Observe(5)
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_messages_total Messages received.
+# TYPE test_messages_total counter
+test_messages_total{endpoint="ep1"} 2
+test_messages_total{endpoint="ep2"} 2
+# HELP test_state State.
+# TYPE test_state gauge
+test_state{endpoint="ep1"} 1
+# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.1"} 1
+test_duration_seconds_bucket{le="1"} 2
+test_duration_seconds_bucket{le="+Inf"} 3
+test_duration_seconds_sum 5.55
+test_duration_seconds_count 3
+# HELP test_func Func.
+# TYPE test_func gauge
+test_func{name="a\"b"} 3
+`
+	if b.String() != want {
+		t.Fatalf("output mismatch:\n%s", b.String())
+	}
+
+	state.Reset()
+	b.Reset()
+	_, _ = r.WriteTo(&b)
+	if strings.Contains(b.String(), `test_state{`) {
+		t.Fatal("Reset() should drop all series")
+	}
+}
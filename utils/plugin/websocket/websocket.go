@@ -68,6 +68,8 @@ type (
 	WebSocket struct {
 		rt   *goja.Runtime
 		loop *eventloop.EventLoop
+		// checkURL 连接前的检查，返回错误时构造函数抛出异常
+		checkURL func(url string) error
 	}
 	WebSocketManager struct {
 		connections []*WebSocketConnection
@@ -229,6 +231,11 @@ func (ws *WebSocket) NewWebSocketConnection(call goja.FunctionCall) goja.Value {
 	}
 
 	url := args[0].String()
+	if ws.checkURL != nil {
+		if err := ws.checkURL(url); err != nil {
+			panic(rt.NewGoError(err))
+		}
+	}
 	var options *webSocketOptions
 
 	// 第二个参数是 protocols (可选)
@@ -617,6 +624,13 @@ func Enable(rt *goja.Runtime, loop *eventloop.EventLoop) {
 	_ = rt.Set("WebSocket", instance.Exports())
 }
 
+// NewConstructor 创建一个独立的 WebSocket 构造函数，checkURL 不为空时每次连接前都会检查目标地址
+func NewConstructor(rt *goja.Runtime, loop *eventloop.EventLoop, checkURL func(url string) error) goja.Value {
+	instance := New().NewInstance(rt, loop)
+	instance.checkURL = checkURL
+	return instance.Exports()
+}
+
 // --- 事件监听工具方法 ---
 
 func (conn *WebSocketConnection) addEventListener(eventType string, listener goja.Value) {